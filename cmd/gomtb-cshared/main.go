@@ -0,0 +1,226 @@
+// Command gomtb-cshared builds package mtb as a C shared library, so that tools written in
+// other languages, such as the Eclipse based IDE and the Python scripts around ModusToolbox,
+// can use it instead of parsing the manifests themselves. Build it with
+//
+//	go build -buildmode=c-shared -o libgomtb.so ./cmd/gomtb-cshared
+//
+// which also writes libgomtb.h. The C ABI is stable: functions are only added, and
+// gomtb_abi_version is raised when they are. Every function returning char* returns a JSON
+// document allocated by the library, {"error": "..."} on failure, that the caller releases
+// with gomtb_free. Catalogs are referred to by handles, which stay valid until gomtb_close:
+//
+//	char *gomtb_ingest(char *url, char *options);             // {"handle": 1, "boards": 2, ...}
+//	char *gomtb_ingest_bundle(char *path, char *options);     // same, from a bundle file
+//	char *gomtb_query(long long handle, char *expr);          // see mtb.Catalog.Query
+//	char *gomtb_match_apps(long long handle, char *board, char *version);
+//	char *gomtb_resolve(long long handle, char *board, char *version);
+//	char *gomtb_export(long long handle);                     // see mtb.Catalog.Export
+//	void gomtb_close(long long handle);
+//	void gomtb_free(char *json);
+//	int gomtb_abi_version(void);
+//
+// options is a JSON object, or NULL: {"cacheDir": "...", "ttlSeconds": 3600, "offline": true}.
+// From Python:
+//
+//	lib = ctypes.CDLL("./libgomtb.so")
+//	lib.gomtb_ingest.restype = ctypes.c_void_p
+//	p = lib.gomtb_ingest(b"", None)
+//	catalog = json.loads(ctypes.string_at(p))
+//	lib.gomtb_free(ctypes.c_void_p(p))
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/haneefdm/gomtb-manifest/mtb"
+)
+
+// abiVersion is raised whenever functions are added
+const abiVersion = 1
+
+var (
+	catalogsMu sync.Mutex
+	catalogs   = map[int64]*mtb.Catalog{}
+	lastHandle int64
+)
+
+// options are the ingestion options callers pass as JSON
+type options struct {
+	CacheDir   string `json:"cacheDir"`
+	TTLSeconds int    `json:"ttlSeconds"`
+	Offline    bool   `json:"offline"`
+}
+
+func main() {} // required by -buildmode=c-shared
+
+// result turns a value or an error into a JSON C string the caller frees with gomtb_free
+func result(v any, err error) *C.char {
+	if err != nil {
+		v = map[string]string{"error": err.Error()}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return C.CString(string(data))
+}
+
+// goString converts a C string that may be NULL
+func goString(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	return C.GoString(s)
+}
+
+// parseOptions turns the JSON options of a call into Ingest options
+func parseOptions(s *C.char) ([]mtb.Option, error) {
+	opts := &options{}
+	if text := goString(s); text != "" {
+		if err := json.Unmarshal([]byte(text), opts); err != nil {
+			return nil, fmt.Errorf("bad options: %v", err)
+		}
+	}
+	ret := []mtb.Option{}
+	if opts.CacheDir != "" {
+		ret = append(ret, mtb.WithCacheDir(opts.CacheDir))
+	}
+	if opts.TTLSeconds > 0 {
+		ret = append(ret, mtb.WithTTL(time.Duration(opts.TTLSeconds)*time.Second))
+	}
+	if opts.Offline {
+		ret = append(ret, mtb.WithOffline())
+	}
+	return ret, nil
+}
+
+// opened registers a catalog and describes it
+func opened(cat *mtb.Catalog, err error) *C.char {
+	if err != nil {
+		return result(nil, err)
+	}
+	catalogsMu.Lock()
+	lastHandle++
+	handle := lastHandle
+	catalogs[handle] = cat
+	catalogsMu.Unlock()
+	return result(map[string]any{
+		"handle":     handle,
+		"boards":     len(cat.Boards()),
+		"apps":       len(cat.Apps()),
+		"middleware": len(cat.Middleware()),
+		"failures":   cat.Failures(),
+	}, nil)
+}
+
+// catalog returns the catalog of a handle
+func catalog(handle C.longlong) (*mtb.Catalog, error) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	cat, ok := catalogs[int64(handle)]
+	if !ok {
+		return nil, fmt.Errorf("no catalog with handle %d", int64(handle))
+	}
+	return cat, nil
+}
+
+//export gomtb_abi_version
+func gomtb_abi_version() C.int {
+	return abiVersion
+}
+
+//export gomtb_ingest
+func gomtb_ingest(url *C.char, opts *C.char) *C.char {
+	ingestOpts, err := parseOptions(opts)
+	if err != nil {
+		return result(nil, err)
+	}
+	return opened(mtb.Ingest(goString(url), ingestOpts...))
+}
+
+//export gomtb_ingest_bundle
+func gomtb_ingest_bundle(path *C.char, opts *C.char) *C.char {
+	ingestOpts, err := parseOptions(opts)
+	if err != nil {
+		return result(nil, err)
+	}
+	f, err := os.Open(goString(path))
+	if err != nil {
+		return result(nil, err)
+	}
+	defer func() { _ = f.Close() }()
+	return opened(mtb.IngestBundle(f, ingestOpts...))
+}
+
+//export gomtb_query
+func gomtb_query(handle C.longlong, expr *C.char) *C.char {
+	cat, err := catalog(handle)
+	if err != nil {
+		return result(nil, err)
+	}
+	return result(cat.Query(goString(expr)))
+}
+
+//export gomtb_match_apps
+func gomtb_match_apps(handle C.longlong, board *C.char, version *C.char) *C.char {
+	cat, err := catalog(handle)
+	if err != nil {
+		return result(nil, err)
+	}
+	res, err := cat.Resolve(goString(board), goString(version))
+	if err != nil {
+		return result(nil, err)
+	}
+	apps := []mtb.App{}
+	for _, id := range res.Apps {
+		if a, ok := cat.App(id); ok {
+			apps = append(apps, a)
+		}
+	}
+	return result(apps, nil)
+}
+
+//export gomtb_resolve
+func gomtb_resolve(handle C.longlong, board *C.char, version *C.char) *C.char {
+	cat, err := catalog(handle)
+	if err != nil {
+		return result(nil, err)
+	}
+	return result(cat.Resolve(goString(board), goString(version)))
+}
+
+//export gomtb_export
+func gomtb_export(handle C.longlong) *C.char {
+	cat, err := catalog(handle)
+	if err != nil {
+		return result(nil, err)
+	}
+	var buf bytes.Buffer
+	if err := cat.Export(&buf); err != nil {
+		return result(nil, err)
+	}
+	return C.CString(buf.String())
+}
+
+//export gomtb_close
+func gomtb_close(handle C.longlong) {
+	catalogsMu.Lock()
+	delete(catalogs, int64(handle))
+	catalogsMu.Unlock()
+}
+
+//export gomtb_free
+func gomtb_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// archiveCommand groups the sub-commands that read manifest trees from git history
+type archiveCommand struct {
+	Import      archiveImportCommand      `command:"import" description:"Store the trees of the history as snapshots"`
+	Appearances archiveAppearancesCommand `command:"appearances" description:"Tell when boards, apps and middleware first and last appeared"`
+}
+
+// archiveOptions are the options common to the archive sub-commands
+type archiveOptions struct {
+	Repo  []string `long:"repo" required:"yes" description:"Local clone serving manifest URLs under a prefix, as URL=DIR or URL=DIR@REF (repeatable)"`
+	Since string   `long:"since" description:"Only commits made on or after this date, e.g. 2023-01-01"`
+	Until string   `long:"until" description:"Only commits made on or before this date"`
+	Every string   `long:"every" description:"Keep at most one commit per period, e.g. 7d or 24h"`
+}
+
+// archive returns the archive of the --url super manifest and the points selected by the options
+func (o *archiveOptions) archive() (*mtbmanifest.ManifestArchive, []*mtbmanifest.ArchivePoint, error) {
+	repos := []*mtbmanifest.ArchiveRepo{}
+	for _, s := range o.Repo {
+		repo, err := mtbmanifest.ParseArchiveRepo(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		repos = append(repos, repo)
+	}
+	superURL := options.URL
+	if superURL == "" {
+		superURL = mtbmanifest.SuperManifestURL
+	}
+	a, err := mtbmanifest.NewManifestArchive(superURL, repos, newGitFetcher())
+	if err != nil {
+		return nil, nil, err
+	}
+	var since, until time.Time
+	if o.Since != "" {
+		if since, err = time.ParseInLocation("2006-01-02", o.Since, time.Local); err != nil {
+			if since, err = time.Parse(time.RFC3339, o.Since); err != nil {
+				return nil, nil, fmt.Errorf("invalid --since %q, expected a date such as 2023-01-01", o.Since)
+			}
+		}
+	}
+	if o.Until != "" {
+		if until, err = parseAsOf(o.Until); err != nil {
+			return nil, nil, fmt.Errorf("invalid --until %q, expected a date such as 2024-06-01", o.Until)
+		}
+	}
+	var every time.Duration
+	if o.Every != "" {
+		if every, err = mtbmanifest.ParseTTL(o.Every); err != nil {
+			return nil, nil, fmt.Errorf("invalid --every %q: %w", o.Every, err)
+		}
+	}
+	points, err := a.Points(since, until, every)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Infof("Reading %d commits of the manifest history\n", len(points))
+	return a, points, nil
+}
+
+type archiveImportCommand struct {
+	archiveOptions
+}
+
+func (c *archiveImportCommand) Execute(args []string) error {
+	a, points, err := c.archive()
+	if err != nil {
+		return err
+	}
+	saved, err := a.SaveSnapshots(mtbmanifest.NewSnapshotStore(""), points)
+	if err != nil {
+		return err
+	}
+	for _, index := range saved {
+		fmt.Printf("%s  %s  %d files\n", shortID(index.ID()), index.Created.Local().Format("2006-01-02 15:04"),
+			len(index.Entries))
+	}
+	fmt.Printf("Stored %d snapshots from %d commits\n", len(saved), len(points))
+	return nil
+}
+
+type archiveAppearancesCommand struct {
+	archiveOptions
+	JSON bool `long:"json" description:"Print the appearances as JSON"`
+	Args struct {
+		IDs []string `positional-arg-name:"ID" description:"Only these boards, apps or middleware (default: all)"`
+	} `positional-args:"yes"`
+}
+
+func (c *archiveAppearancesCommand) Execute(args []string) error {
+	a, points, err := c.archive()
+	if err != nil {
+		return err
+	}
+	seen, err := a.Appearances(points)
+	if err != nil {
+		return err
+	}
+	if len(c.Args.IDs) > 0 {
+		wanted := map[string]bool{}
+		for _, id := range c.Args.IDs {
+			wanted[strings.ToLower(id)] = true
+		}
+		filtered := []*mtbmanifest.ItemAppearance{}
+		for _, s := range seen {
+			if wanted[strings.ToLower(s.ID)] {
+				filtered = append(filtered, s)
+			}
+		}
+		seen = filtered
+	}
+	if c.JSON {
+		data, err := json.MarshalIndent(seen, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	for _, s := range seen {
+		removed := ""
+		if s.Removed {
+			removed = "  (removed)"
+		}
+		fmt.Printf("%-10s %-40s %s .. %s%s\n", s.Kind, s.ID, s.FirstSeen.Local().Format("2006-01-02"),
+			s.LastSeen.Local().Format("2006-01-02"), removed)
+	}
+	return nil
+}
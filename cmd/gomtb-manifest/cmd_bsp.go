@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type packageBSPCommand struct {
+	Version string `long:"version" description:"Version commit of the BSP, e.g. latest-v4.X (default: the newest listed)"`
+	DryRun  bool   `long:"dry-run" description:"Only list what would be placed where, without fetching"`
+	JSON    bool   `long:"json" description:"Print the package as JSON"`
+	Args    struct {
+		ID  string `positional-arg-name:"BOARD_ID" required:"yes"`
+		Dir string `positional-arg-name:"DIR" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *packageBSPCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if _, ok := superManifest.GetBoard(c.Args.ID); !ok {
+		return notFoundError(superManifest, c.Args.ID)
+	}
+	var pkg *mtbmanifest.BSPPackage
+	if c.DryRun {
+		pkg, err = mtbmanifest.ResolveBSP(superManifest, c.Args.ID, c.Version)
+	} else {
+		pkg, err = mtbmanifest.PackageBSP(superManifest, newGitFetcher(), c.Args.ID, c.Version, c.Args.Dir)
+	}
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(pkg, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	for _, asset := range pkg.Assets {
+		fmt.Printf("%-40s %-20s %s\n", asset.ID, asset.Ref, asset.Path)
+	}
+	for _, spec := range pkg.Missing {
+		logger.Warningf("Not placed, not in the manifests: %s\n", spec)
+	}
+	for _, conflict := range pkg.Conflicts {
+		logger.Warningf("Version conflict: %s\n", conflict)
+	}
+	if !c.DryRun {
+		fmt.Printf("Packaged %s %s in %s\n", pkg.Board, pkg.Version, c.Args.Dir)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// bundleCommand groups the sub-commands that create and install offline bundles
+type bundleCommand struct {
+	Create  bundleCreateCommand  `command:"create" description:"Package the manifest tree into an offline bundle (.tar.gz)"`
+	Install bundleInstallCommand `command:"install" description:"Install an offline bundle into the manifest cache"`
+	Apply   bundleApplyCommand   `command:"apply" description:"Combine a base bundle and a delta into a new full bundle"`
+}
+
+type bundleCreateCommand struct {
+	Base string `long:"base" description:"Only include what changed since this earlier bundle (creates a delta)"`
+	Args struct {
+		File string `positional-arg-name:"FILE" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *bundleCreateCommand) Execute(args []string) error {
+	superManifest, err := loadLiveSuperManifest()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(c.Args.File)
+	if err != nil {
+		return err
+	}
+	var index *mtbmanifest.BundleIndex
+	if c.Base != "" {
+		var base *mtbmanifest.BundleIndex
+		if base, err = readBundleIndex(c.Base); err == nil {
+			index, err = mtbmanifest.CreateDeltaBundle(f, superManifest, nil, base)
+		}
+	} else {
+		index, err = mtbmanifest.CreateBundle(f, superManifest, nil)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(c.Args.File)
+		return err
+	}
+	changed := 0
+	for _, e := range index.Entries {
+		if !e.InBase {
+			changed++
+		}
+	}
+	fmt.Printf("Wrote %d of %d files to %s\n", changed, len(index.Entries), c.Args.File)
+	return nil
+}
+
+// readBundleIndex reads the index of a bundle file
+func readBundleIndex(file string) (*mtbmanifest.BundleIndex, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	index, _, err := mtbmanifest.ReadBundle(f)
+	return index, err
+}
+
+type bundleInstallCommand struct {
+	DryRun bool `long:"dry-run" description:"List the cache entries that would be added or overwritten, without installing"`
+	Args   struct {
+		File string `positional-arg-name:"FILE" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *bundleInstallCommand) Execute(args []string) error {
+	f, err := os.Open(c.Args.File)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	if c.DryRun {
+		_, changes, err := mtbmanifest.PlanInstallBundle(f, nil)
+		if err != nil {
+			return err
+		}
+		printDryRun(changes)
+		return nil
+	}
+	index, err := mtbmanifest.InstallBundle(f, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Installed %d files from a bundle created %s\n", len(index.Entries), index.Created.Format("2006-01-02 15:04 MST"))
+	for _, u := range index.RootURLs {
+		if u != mtbmanifest.SuperManifestURL {
+			fmt.Printf("Use --url %s to load it\n", u)
+		}
+	}
+	return nil
+}
+
+type bundleApplyCommand struct {
+	Args struct {
+		Base  string `positional-arg-name:"BASE" required:"yes"`
+		Delta string `positional-arg-name:"DELTA" required:"yes"`
+		Out   string `positional-arg-name:"OUT" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *bundleApplyCommand) Execute(args []string) error {
+	base, err := os.Open(c.Args.Base)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = base.Close() }()
+	delta, err := os.Open(c.Args.Delta)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = delta.Close() }()
+	out, err := os.Create(c.Args.Out)
+	if err != nil {
+		return err
+	}
+	index, err := mtbmanifest.ApplyDelta(base, delta, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(c.Args.Out)
+		return err
+	}
+	fmt.Printf("Wrote %d files to %s\n", len(index.Entries), c.Args.Out)
+	return nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// cacheCommand groups the sub-commands that move the manifest cache between machines
+type cacheCommand struct {
+	Export  cacheExportCommand  `command:"export" description:"Write the whole manifest cache to a file"`
+	Import  cacheImportCommand  `command:"import" description:"Load a file written by 'cache export' into the manifest cache"`
+	Migrate cacheMigrateCommand `command:"migrate" description:"Convert cache files written by old versions to the current format"`
+	Clear   cacheClearCommand   `command:"clear" description:"Delete every manifest cache entry, or only the stale ones"`
+	GC      cacheGCCommand      `command:"gc" description:"Delete the cache entries no configured channel refers to"`
+}
+
+// printDryRun lists what an operation would change, instead of doing it
+func printDryRun(changes []*mtbmanifest.CacheChange) {
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+	fmt.Printf("Dry run: %d entries or files would change; nothing was changed\n", len(changes))
+}
+
+type cacheExportCommand struct {
+	Args struct {
+		File string `positional-arg-name:"FILE" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *cacheExportCommand) Execute(args []string) error {
+	cache := mtbmanifest.NewManifestDefaultCache()
+	defer cache.Close()
+	f, err := os.Create(c.Args.File)
+	if err != nil {
+		return err
+	}
+	index, err := cache.Export(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(c.Args.File)
+		return err
+	}
+	fmt.Printf("Exported %d cache entries to %s\n", len(index.Entries), c.Args.File)
+	return nil
+}
+
+type cacheImportCommand struct {
+	DryRun bool `long:"dry-run" description:"List the entries that would be added or overwritten, without importing"`
+	Args   struct {
+		File string `positional-arg-name:"FILE" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *cacheImportCommand) Execute(args []string) error {
+	cache := mtbmanifest.NewManifestDefaultCache()
+	defer cache.Close()
+	f, err := os.Open(c.Args.File)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	if c.DryRun {
+		_, changes, err := cache.PlanImport(f)
+		if err != nil {
+			return err
+		}
+		printDryRun(changes)
+		return nil
+	}
+	index, err := cache.Import(f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d cache entries\n", len(index.Entries))
+	return nil
+}
+
+type cacheMigrateCommand struct {
+	Prune  bool `long:"prune" description:"Delete old-format files that no manifest of the tree refers to"`
+	DryRun bool `long:"dry-run" description:"List the files that would be converted or deleted, without changing them"`
+}
+
+func (c *cacheMigrateCommand) Execute(args []string) error {
+	cache := mtbmanifest.NewManifestDefaultCache()
+	defer cache.Close()
+	store, ok := cache.Store().(*mtbmanifest.FileStore)
+	if !ok {
+		return fmt.Errorf("only file caches have old-format files")
+	}
+	if c.DryRun {
+		return c.dryRun(store)
+	}
+	// Reading the tree offline converts every file it refers to
+	if _, err := mtbmanifest.NewSuperManifestFromURL(options.URL, mtbmanifest.WithFetcher(
+		mtbmanifest.NewManifestFetcher(mtbmanifest.WithCache(cache))), mtbmanifest.WithOffline()); err != nil {
+		if c.Prune {
+			return fmt.Errorf("not pruning, the manifest tree could not be read from the cache: %v", err)
+		}
+		logger.Warningf("Could not read the manifest tree from the cache: %v\n", err)
+	}
+	if c.Prune {
+		n, err := store.RemoveLegacyFiles()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d unreferenced old-format files from %s\n", n, store.Dir())
+		return nil
+	}
+	files, err := store.LegacyFiles()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d old-format files left in %s", len(files), store.Dir())
+	if len(files) > 0 {
+		fmt.Print(" (no manifest refers to them; remove them with --prune)")
+	}
+	fmt.Println()
+	return nil
+}
+
+// dryRun reads the tree without converting anything, to tell which files it refers to
+func (c *cacheMigrateCommand) dryRun(store *mtbmanifest.FileStore) error {
+	cache := mtbmanifest.NewManifestCache(mtbmanifest.WithStore(store.ReadOnly()), mtbmanifest.WithNoBackgroundRefresh())
+	defer cache.Close()
+	referenced := []string{}
+	tree, err := mtbmanifest.NewSuperManifestFromURL(options.URL, mtbmanifest.WithFetcher(
+		mtbmanifest.NewManifestFetcher(mtbmanifest.WithCache(cache))), mtbmanifest.WithOffline())
+	if err != nil {
+		if c.Prune {
+			return fmt.Errorf("not pruning, the manifest tree could not be read from the cache: %v", err)
+		}
+		logger.Warningf("Could not read the manifest tree from the cache: %v\n", err)
+	} else {
+		referenced = tree.ManifestURLs()
+	}
+	changes, err := store.PlanMigrate(referenced, c.Prune)
+	if err != nil {
+		return err
+	}
+	printDryRun(changes)
+	return nil
+}
+
+type cacheClearCommand struct {
+	Stale  bool `long:"stale" description:"Only delete the entries older than their TTL"`
+	DryRun bool `long:"dry-run" description:"List the entries that would be deleted, without deleting them"`
+}
+
+func (c *cacheClearCommand) Execute(args []string) error {
+	cache := mtbmanifest.NewManifestDefaultCache()
+	defer cache.Close()
+	plan := cache.PlanClear
+	if c.Stale {
+		plan = cache.PlanClearStale
+	}
+	changes, err := plan()
+	if err != nil {
+		return err
+	}
+	if c.DryRun {
+		printDryRun(changes)
+		return nil
+	}
+	if c.Stale {
+		err = cache.ClearStale()
+	} else {
+		err = cache.Clear()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d cache entries\n", len(changes))
+	return nil
+}
+
+type cacheGCCommand struct {
+	DryRun bool `long:"dry-run" description:"List the entries that would be deleted, without deleting them"`
+}
+
+func (c *cacheGCCommand) Execute(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	channels, err := cfg.channelSet()
+	if err != nil {
+		return err
+	}
+	cache := mtbmanifest.NewManifestDefaultCache()
+	defer cache.Close()
+	gc := channels.GC
+	if c.DryRun {
+		gc = channels.PlanGC
+	}
+	plan, err := gc(cache.Store())
+	if err != nil {
+		return err
+	}
+	changes, size := []*mtbmanifest.CacheChange{}, 0
+	for _, g := range plan {
+		switch {
+		case g.Skipped != "":
+			fmt.Printf("%s: skipped, %s\n", g.Cache, g.Skipped)
+		case g.Removed:
+			fmt.Printf("%s: channel no longer configured, %d entries\n", g.Cache, len(g.Changes))
+		default:
+			fmt.Printf("%s: %s refer to %d URLs, %d entries orphaned\n", g.Cache, strings.Join(g.Channels, ", "),
+				g.Referenced, len(g.Changes))
+		}
+		changes = append(changes, g.Changes...)
+		size += g.Size()
+	}
+	if c.DryRun {
+		printDryRun(changes)
+		return nil
+	}
+	fmt.Printf("Deleted %d cache entries, %d bytes\n", len(changes), size)
+	return nil
+}
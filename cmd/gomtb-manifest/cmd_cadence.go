@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type cadenceCommand struct {
+	Kind       []string `short:"k" long:"kind" choice:"board" choice:"app" choice:"middleware" description:"Report items of this kind (repeatable, default: middleware)"`
+	StaleAfter string   `long:"stale-after" default:"365d" description:"How long without a release makes an item stale"`
+	StaleOnly  bool     `long:"stale-only" description:"Only report the stale items"`
+	Format     string   `long:"format" choice:"text" choice:"json" choice:"markdown" default:"text" description:"Print the report as text, JSON or Markdown"`
+	Output     string   `short:"o" long:"output" description:"Write the report to this file instead of standard output"`
+}
+
+func (c *cadenceCommand) Execute(args []string) error {
+	staleAfter, err := mtbmanifest.ParseTTL(c.StaleAfter)
+	if err != nil {
+		return fmt.Errorf("invalid --stale-after %q: %w", c.StaleAfter, err)
+	}
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	rootURLs := []string{}
+	if options.URL != "" {
+		rootURLs = append(rootURLs, options.URL)
+	}
+	history, err := mtbmanifest.ReleaseHistoryFromSnapshots(mtbmanifest.NewSnapshotStore(""), rootURLs...)
+	if err != nil {
+		return fmt.Errorf("%w; store snapshots with 'snapshot create' or 'archive import'", err)
+	}
+	history.Add(time.Now(), superManifest)
+	opts := &mtbmanifest.StalenessOptions{StaleAfter: staleAfter}
+	for _, k := range c.Kind {
+		opts.Kinds = append(opts.Kinds, mtbmanifest.ItemKind(k))
+	}
+	report := mtbmanifest.ReleaseCadences(superManifest, history, opts)
+	if c.StaleOnly {
+		report.Items = report.Stale()
+	}
+
+	var w io.Writer = os.Stdout
+	if c.Output != "" {
+		f, err := os.Create(c.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch c.Format {
+	case "json":
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(jsonData))
+		return err
+	case "markdown":
+		return report.WriteMarkdown(w)
+	}
+	fmt.Fprintf(w, "Releases seen since %s, stale after %.0f days\n", report.Since.Local().Format("2006-01-02"),
+		staleAfter.Hours()/24)
+	for _, r := range report.Items {
+		mark := " "
+		if r.Stale {
+			mark = "!"
+		}
+		interval := "-"
+		if r.MeanInterval > 0 {
+			interval = fmt.Sprintf("%.0fd", r.MeanInterval.Hours()/24)
+		}
+		age := fmt.Sprintf("%.0fd", r.Age.Hours()/24)
+		if r.AgeAtLeast {
+			age = ">" + age
+		}
+		fmt.Fprintf(w, "%s %-40s %3d releases  every %-6s  last %s  age %s\n", mark, r.ID, r.Releases, interval,
+			r.LastRelease.Local().Format("2006-01-02"), age)
+	}
+	return nil
+}
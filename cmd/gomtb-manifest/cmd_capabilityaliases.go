@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type capabilityAliasesCommand struct {
+	Board string `long:"board" description:"Match code examples and middleware against this board and report the alias hits"`
+	JSON  bool   `long:"json" description:"Print the aliases and hits as JSON"`
+}
+
+func (c *capabilityAliasesCommand) Execute(args []string) error {
+	aliases := mtbmanifest.CapabilityAliases()
+	hits := []*mtbmanifest.CapabilityAliasHit{}
+	if c.Board != "" {
+		sm, err := loadSuperManifest()
+		if err != nil {
+			return err
+		}
+		board, ok := sm.GetBoard(c.Board)
+		if !ok {
+			return notFoundError(sm, c.Board, mtbmanifest.ItemKindBoard)
+		}
+		mtbmanifest.ResetCapabilityAliasHits()
+		mtbmanifest.FindCodeExamplesForBoard(sm, board)
+		mtbmanifest.FindMiddlewareForBoard(sm, board)
+		hits = mtbmanifest.CapabilityAliasHits()
+	}
+
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(map[string]any{"aliases": aliases, "hits": hits}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	oldTokens := make([]string, 0, len(aliases))
+	for oldToken := range aliases {
+		oldTokens = append(oldTokens, oldToken)
+	}
+	sort.Strings(oldTokens)
+	for _, oldToken := range oldTokens {
+		fmt.Printf("%-24s -> %s\n", oldToken, aliases[oldToken])
+	}
+	if c.Board != "" {
+		if len(hits) == 0 {
+			fmt.Printf("\nNo requirement needed an alias to match %s\n", c.Board)
+		} else {
+			fmt.Printf("\nRequirements met through aliases for %s:\n", c.Board)
+			for _, hit := range hits {
+				fmt.Printf("  %s\n", hit)
+			}
+		}
+	}
+	return nil
+}
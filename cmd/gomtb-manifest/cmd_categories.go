@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type categoriesCommand struct {
+	Taxonomy string   `short:"t" long:"taxonomy" description:"JSON file with category aliases and parents"`
+	Kinds    []string `short:"k" long:"kind" choice:"board" choice:"app" choice:"middleware" description:"Only include items of this kind (repeatable)"`
+	Args     struct {
+		Category string `positional-arg-name:"CATEGORY" description:"List the items in this category instead of the category tree"`
+	} `positional-args:"yes"`
+}
+
+func (c *categoriesCommand) Execute(args []string) error {
+	var config *mtbmanifest.TaxonomyConfig
+	if c.Taxonomy != "" {
+		data, err := os.ReadFile(c.Taxonomy)
+		if err != nil {
+			return fmt.Errorf("failed to read taxonomy config: %v", err)
+		}
+		config, err = mtbmanifest.ReadTaxonomyConfig(data)
+		if err != nil {
+			return fmt.Errorf("%w taxonomy config %s: %v", errParseFile, c.Taxonomy, err)
+		}
+	}
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+
+	kinds := []mtbmanifest.ItemKind{}
+	for _, kind := range c.Kinds {
+		kinds = append(kinds, mtbmanifest.ItemKind(kind))
+	}
+	taxonomy := mtbmanifest.NewTaxonomy(superManifest, config)
+	if c.Args.Category != "" {
+		if _, ok := taxonomy.GetCategory(c.Args.Category); !ok {
+			return fmt.Errorf("category %q %w", c.Args.Category, errNotFound)
+		}
+		fmt.Printf("%s\n", strings.Join(taxonomy.Path(c.Args.Category), " > "))
+		for _, item := range taxonomy.GetItemsByCategory(c.Args.Category, kinds...) {
+			fmt.Printf("    %-10s %s\n", item.Kind, item.ID)
+		}
+		return nil
+	}
+
+	var printNode func(node *mtbmanifest.CategoryNode, depth int)
+	printNode = func(node *mtbmanifest.CategoryNode, depth int) {
+		count := node.TotalCount(kinds...)
+		if count == 0 {
+			return
+		}
+		fmt.Printf("%s%s (%d)\n", strings.Repeat("    ", depth), node.Name, count)
+		for _, child := range node.Children {
+			printNode(child, depth+1)
+		}
+	}
+	for _, root := range taxonomy.Roots() {
+		printNode(root, 0)
+	}
+	return nil
+}
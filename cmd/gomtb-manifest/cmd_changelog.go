@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type changelogCommand struct {
+	JSON bool `long:"json" description:"Print the report as JSON"`
+	Args struct {
+		ID   string `positional-arg-name:"ID" required:"yes"`
+		From string `positional-arg-name:"FROM" required:"yes"`
+		To   string `positional-arg-name:"TO"`
+	} `positional-args:"yes"`
+}
+
+func (c *changelogCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if lookupItem(superManifest, c.Args.ID, nil) == nil {
+		return notFoundError(superManifest, c.Args.ID)
+	}
+	report, err := mtbmanifest.Changelog(superManifest, c.Args.ID, c.Args.From, c.Args.To, newGitFetcher())
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	fmt.Printf("%s: %s -> %s (from %s)\n", report.ID, report.From, report.To, report.File)
+	if len(report.Notes) == 0 {
+		fmt.Println("No release notes entries were added")
+		return nil
+	}
+	for _, note := range report.Notes {
+		fmt.Printf("\n== %s ==\n%s\n", note.Heading, note.Body)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type channelsCommand struct {
+	JSON bool `long:"json" description:"Print the channels as JSON"`
+}
+
+func (c *channelsCommand) Execute(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	channels, err := cfg.channelSet()
+	if err != nil {
+		return err
+	}
+	type channelInfo struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description,omitempty"`
+		URLs        []string `json:"urls"`
+		CacheDir    string   `json:"cacheDir"`
+	}
+	infos := []channelInfo{}
+	for _, ch := range channels.Channels() {
+		infos = append(infos, channelInfo{Name: ch.Name, Description: ch.Description, URLs: ch.URLs,
+			CacheDir: channels.CacheDir(ch.Name)})
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	for _, info := range infos {
+		if info.Description != "" {
+			fmt.Printf("%s: %s (cache %s)\n", info.Name, info.Description, info.CacheDir)
+		} else {
+			fmt.Printf("%s (cache %s)\n", info.Name, info.CacheDir)
+		}
+		for _, u := range info.URLs {
+			fmt.Printf("  %s\n", u)
+		}
+	}
+	return nil
+}
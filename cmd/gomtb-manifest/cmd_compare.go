@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type compareCommand struct {
+	JSON bool `long:"json" description:"Print the comparison as JSON"`
+	Args struct {
+		BoardA string `positional-arg-name:"BOARD_A" required:"1"`
+		BoardB string `positional-arg-name:"BOARD_B" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func (c *compareCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	boardA, ok := superManifest.GetBoard(c.Args.BoardA)
+	if !ok {
+		return notFoundError(superManifest, c.Args.BoardA, mtbmanifest.ItemKindBoard)
+	}
+	boardB, ok := superManifest.GetBoard(c.Args.BoardB)
+	if !ok {
+		return notFoundError(superManifest, c.Args.BoardB, mtbmanifest.ItemKindBoard)
+	}
+
+	cmp := mtbmanifest.CompareBoards(superManifest, boardA, boardB)
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(cmp, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Printf("A: %s (%s)\n", cmp.BoardA, cmp.NameA)
+	fmt.Printf("B: %s (%s)\n", cmp.BoardB, cmp.NameB)
+	printDiff("MCU", &cmp.MCU, true)
+	printDiff("Radio", &cmp.Radio, true)
+	printDiff("Capabilities", &cmp.Capabilities, true)
+	printDiff("Versions", &cmp.Versions, true)
+	printDiff("Dependencies", &cmp.Dependencies, true)
+	// The compatible lists are long; only show what is unique to each board
+	printDiff("Middleware", &cmp.Middleware, false)
+	printDiff("Code examples", &cmp.Apps, false)
+	return nil
+}
+
+func printDiff(title string, diff *mtbmanifest.StringSetDiff, showCommon bool) {
+	fmt.Printf("\n%s:\n", title)
+	if diff.IsSame() && len(diff.Common) == 0 {
+		fmt.Printf("    (none)\n")
+		return
+	}
+	if showCommon {
+		fmt.Printf("    both:   %s\n", joinOrDash(diff.Common))
+	} else {
+		fmt.Printf("    both:   %d in common\n", len(diff.Common))
+	}
+	fmt.Printf("    only A: %s\n", joinOrDash(diff.OnlyA))
+	fmt.Printf("    only B: %s\n", joinOrDash(diff.OnlyB))
+}
+
+func joinOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, " ")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type createCommand struct {
+	Version string   `long:"version" description:"Version commit of the code example, e.g. latest-v4.X (default: the newest listed)"`
+	BSP     string   `short:"b" long:"bsp" description:"Board the project targets (default: the example's own)"`
+	Name    string   `long:"name" description:"Application name (default: the name of DIR)"`
+	Vars    []string `long:"var" description:"Value of another template marker, e.g. --var ASSET_REPO=../mtb_shared (repeatable)"`
+	Args    struct {
+		ID  string `positional-arg-name:"APP_ID" required:"yes"`
+		Dir string `positional-arg-name:"DIR" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *createCommand) Execute(args []string) error {
+	settings := &mtbmanifest.ProjectSettings{AppName: c.Name, BSP: c.BSP, Vars: map[string]string{}}
+	for _, v := range c.Vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid --var %q, expected NAME=VALUE", v)
+		}
+		settings.Vars[name] = value
+	}
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if _, ok := superManifest.GetApp(c.Args.ID); !ok {
+		return notFoundError(superManifest, c.Args.ID)
+	}
+	result, err := mtbmanifest.CreateProject(superManifest, newGitFetcher(), c.Args.ID, c.Version, c.Args.Dir, settings)
+	if err != nil {
+		return err
+	}
+	fmt.Print(result)
+	fmt.Printf("Created %s from %s\n", c.Args.Dir, c.Args.ID)
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type daemonCommand struct {
+	Socket  string        `long:"socket" description:"Unix socket to listen on (default: ~/.modustoolbox/mtbmcp/gomtb-manifest.sock)"`
+	Refresh time.Duration `long:"refresh-every" description:"Refresh the manifests this often, e.g. 1h; by default only on a refresh request"`
+}
+
+func (c *daemonCommand) Execute(args []string) error {
+	superManifest, err := loadLiveSuperManifest()
+	if err != nil {
+		return err
+	}
+	live, err := mtbmanifest.NewLiveSuperManifest(superManifest)
+	if err != nil {
+		return err
+	}
+	socket := c.Socket
+	if socket == "" {
+		socket = mtbmanifest.DefaultDaemonSocket()
+	}
+	l, err := mtbmanifest.ListenDaemon(socket)
+	if err != nil {
+		return err
+	}
+	d := mtbmanifest.NewDaemon(live)
+	d.RefreshInterval = c.Refresh
+	d.OnRefresh = func(changes *mtbmanifest.ChangeSet, err error) {
+		if err != nil {
+			logger.Warningf("Refresh failed: %v\n", err)
+			return
+		}
+		if !changes.Empty() {
+			fmt.Printf("%s refreshed\n", time.Now().Format(time.TimeOnly))
+			printChangeSet(changes)
+		}
+	}
+	fmt.Printf("Serving %d boards, %d apps, %d middleware on %s\n", len(superManifest.GetBoardIDs()),
+		len(superManifest.GetAppIDs()), len(superManifest.GetMiddlewareIDs()), socket)
+	err = d.Serve(shutdownCtx, l)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type deprecationsCommand struct {
+	Args struct {
+		Binary string `positional-arg-name:"BINARY" description:"Go program to check; all deprecated symbols are listed if omitted"`
+	} `positional-args:"yes"`
+}
+
+func (c *deprecationsCommand) Execute(args []string) error {
+	deprecations := mtbmanifest.Deprecations
+	if c.Args.Binary != "" {
+		var err error
+		if deprecations, err = mtbmanifest.DeprecatedSymbolsIn(c.Args.Binary); err != nil {
+			return err
+		}
+	}
+	return mtbmanifest.WriteMigrationGuide(os.Stdout, deprecations)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type diagnoseCommand struct {
+	Output string `short:"o" long:"output" description:"Zip file to write (default: gomtb-manifest-diagnostics-<time>.zip)"`
+}
+
+func (c *diagnoseCommand) Execute(args []string) error {
+	// Record what goes wrong while ingesting below
+	recorder := mtbmanifest.NewRecordingLogger(logger, 200)
+	mtbmanifest.SetLogger(recorder)
+	defer mtbmanifest.SetLogger(logger)
+
+	d := mtbmanifest.NewDiagnostics()
+	cache := mtbmanifest.NewManifestDefaultCache()
+	if stats, err := cache.Stats(); err != nil {
+		d.CacheError = err.Error()
+	} else {
+		d.Cache = stats
+	}
+	cache.Close()
+	if _, err := loadSuperManifest(); err != nil {
+		d.IngestError = err.Error()
+	}
+	d.Ingest = lastIngestReport.Load()
+	d.Errors = recorder.Records()
+	if data, err := os.ReadFile(configPath()); err == nil {
+		d.Config = data
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	output := c.Output
+	if output == "" {
+		output = fmt.Sprintf("gomtb-manifest-diagnostics-%s.zip", d.GeneratedAt.Format("20060102-150405"))
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := d.WriteZip(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s (%d errors recorded) at %s\n", output, len(d.Errors), d.GeneratedAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type doctorCommand struct {
+	Fix  bool `long:"fix" description:"Delete corrupt cache entries and files left by interrupted writes"`
+	JSON bool `long:"json" description:"Print the checks as JSON"`
+}
+
+// doctorMarks are shown in front of the checks of each status
+var doctorMarks = map[mtbmanifest.CheckStatus]string{
+	mtbmanifest.CheckOK:      "[ok]  ",
+	mtbmanifest.CheckWarning: "[warn]",
+	mtbmanifest.CheckFailed:  "[FAIL]",
+	mtbmanifest.CheckSkipped: "[skip]",
+}
+
+// doctorAreas are the headings of the areas checked
+var doctorAreas = map[string]string{"proxy": "Proxy", "network": "Network", "cache": "Cache",
+	"modustoolbox": "ModusToolbox"}
+
+func (c *doctorCommand) Execute(args []string) error {
+	opts := &mtbmanifest.DoctorOptions{Offline: options.Offline, Repair: c.Fix, Timeout: options.Timeout}
+	if options.URL != "" {
+		opts.URLs = []string{options.URL}
+	}
+	checks := mtbmanifest.Doctor(shutdownCtx, opts)
+	failed := 0
+	for _, check := range checks {
+		if check.Status == mtbmanifest.CheckFailed {
+			failed++
+		}
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		area := ""
+		for _, check := range checks {
+			if check.Area != area {
+				area = check.Area
+				fmt.Println(doctorAreas[area])
+			}
+			fmt.Printf("  %s %s: %s\n", doctorMarks[check.Status], check.Name, check.Detail)
+			if check.Fix != "" {
+				fmt.Printf("         fix: %s\n", check.Fix)
+			}
+		}
+	}
+	if failed > 0 {
+		return validationErrorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type fetchCommand struct {
+	Version string `long:"version" description:"Version commit to fetch, e.g. latest-v4.X (default: the newest listed)"`
+	SHA     string `long:"sha" description:"Fail unless the version resolves to this commit SHA"`
+	Lock    string `long:"lock" description:"Fetch the version and commit recorded for the item in this lockfile"`
+	Args    struct {
+		ID  string `positional-arg-name:"ID" required:"yes"`
+		Dir string `positional-arg-name:"DIR" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *fetchCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if lookupItem(superManifest, c.Args.ID, nil) == nil {
+		return notFoundError(superManifest, c.Args.ID)
+	}
+	var src *mtbmanifest.GitSource
+	if c.Lock != "" {
+		lf, err := readLockfile(c.Lock)
+		if err != nil {
+			return err
+		}
+		entry, ok := lf.GetEntry(c.Args.ID)
+		if !ok {
+			return fmt.Errorf("%s %w in %s", c.Args.ID, errNotFound, c.Lock)
+		}
+		src = entry.Source()
+	} else {
+		if src, err = mtbmanifest.AssetSource(superManifest, c.Args.ID, c.Version); err != nil {
+			return err
+		}
+		src.SHA = c.SHA
+	}
+	sha, err := newGitFetcher().Fetch(src, c.Args.Dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fetched %s (%s) into %s\n", src, sha, c.Args.Dir)
+	return nil
+}
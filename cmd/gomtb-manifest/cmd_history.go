@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type historyCommand struct {
+	Since string `long:"since" description:"Only show versions newer than this one, e.g. 3.0.0"`
+	JSON  bool   `long:"json" description:"Print the history as JSON"`
+	Args  struct {
+		ID string `positional-arg-name:"ID" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *historyCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if lookupItem(superManifest, c.Args.ID, nil) == nil {
+		return notFoundError(superManifest, c.Args.ID)
+	}
+	timeline, err := mtbmanifest.VersionTimeline(superManifest, c.Args.ID)
+	if err != nil {
+		return err
+	}
+	if c.Since != "" {
+		if timeline.Entries, err = timeline.VersionsSince(c.Since); err != nil {
+			return err
+		}
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(timeline, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	fmt.Printf("%s %s: %d versions\n", timeline.Kind, timeline.ID, len(timeline.Entries))
+	for _, e := range timeline.Entries {
+		fmt.Printf("  %-25s %-30s %s\n", e.Commit, e.Num, e.FlowVersion)
+	}
+	counts := timeline.CountsByMajor()
+	majors := make([]int, 0, len(counts))
+	for major := range counts {
+		majors = append(majors, major)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(majors)))
+	for _, major := range majors {
+		fmt.Printf("  %d.x: %d\n", major, counts[major])
+	}
+	return nil
+}
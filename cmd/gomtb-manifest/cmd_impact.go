@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type impactCommand struct {
+	Format string `long:"format" choice:"text" choice:"json" choice:"csv" default:"text" description:"Print the impacted items as text, JSON or CSV"`
+	Output string `short:"o" long:"output" description:"Write the report to this file instead of standard output"`
+	Args   struct {
+		ID    string `positional-arg-name:"LIBRARY_ID" required:"yes"`
+		Range string `positional-arg-name:"RANGE" description:"Versions of the library, e.g. '>=4.0 <5.0' or 4.X (default: all)"`
+	} `positional-args:"yes"`
+}
+
+func (c *impactCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if _, ok := superManifest.GetMiddleware(c.Args.ID); !ok {
+		return notFoundError(superManifest, c.Args.ID)
+	}
+	report, err := mtbmanifest.ImpactOfChange(superManifest, c.Args.ID, c.Args.Range)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if c.Output != "" {
+		f, err := os.Create(c.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch c.Format {
+	case "json":
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(jsonData))
+		return err
+	case "csv":
+		return report.WriteCSV(w)
+	}
+	if report.Len() == 0 {
+		fmt.Fprintf(w, "Nothing depends on %s %s\n", c.Args.ID, c.Args.Range)
+		return nil
+	}
+	for _, ch := range report.Channels {
+		fmt.Fprintf(w, "%s (%d)\n", ch.Channel, len(ch.Items))
+		for _, item := range ch.Items {
+			via := "directly"
+			if len(item.Via) > 0 {
+				via = "via " + strings.Join(item.Via, " > ")
+			}
+			fmt.Fprintf(w, "  %-10s %-40s %-20s needs %s %s\n", item.Kind, item.ID, item.Version, item.LibraryVersion, via)
+		}
+	}
+	return nil
+}
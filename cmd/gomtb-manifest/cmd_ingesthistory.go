@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type ingestHistoryCommand struct {
+	Since time.Duration `long:"since" description:"Only look at ingestions this recent, e.g. 720h"`
+	Runs  bool          `long:"runs" description:"List every ingestion, not just the summary"`
+	JSON  bool          `long:"json" description:"Print the trends as JSON"`
+}
+
+func (c *ingestHistoryCommand) Execute(args []string) error {
+	history := mtbmanifest.NewIngestHistory("")
+	var since time.Time
+	if c.Since > 0 {
+		since = time.Now().Add(-c.Since)
+	}
+	reports, err := history.List(since)
+	if err != nil {
+		return err
+	}
+	trends := mtbmanifest.AnalyzeIngestReports(reports)
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(trends, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	if len(trends.Runs) == 0 {
+		fmt.Printf("No ingest reports in %s\n", history.Dir())
+		return nil
+	}
+	first, last := trends.Runs[0], trends.Runs[len(trends.Runs)-1]
+	fmt.Printf("%d ingestions from %s to %s, %v on average\n", len(trends.Runs),
+		first.StartedAt.Local().Format(time.DateTime), last.StartedAt.Local().Format(time.DateTime),
+		trends.AverageDuration.Round(time.Millisecond))
+	fmt.Printf("Boards %d (%+d), apps %d (%+d), middleware %d (%+d)\n", last.Boards, trends.BoardsGrowth,
+		last.Apps, trends.AppsGrowth, last.Middleware, trends.MiddlewareGrowth)
+	if c.Runs {
+		fmt.Println("\nIngestions:")
+		for _, run := range trends.Runs {
+			fmt.Printf("  %s %8v %5d boards %5d apps %5d middleware %3d of %d manifests failed\n",
+				run.StartedAt.Local().Format(time.DateTime), run.Duration.Round(time.Millisecond),
+				run.Boards, run.Apps, run.Middleware, run.Failed, run.Manifests)
+		}
+	}
+	if len(trends.Failing) == 0 {
+		fmt.Println("No manifest failed to load")
+		return nil
+	}
+	fmt.Println("\nFailing hosts:")
+	for _, h := range trends.Hosts {
+		fmt.Printf("  %-40s %d of %d reads failed\n", h.Host, h.Failures, h.Reads)
+	}
+	fmt.Println("\nFailing manifests:")
+	for _, f := range trends.Failing {
+		fmt.Printf("  %s\n    %d of %d runs failed, last %s: %s\n", f.URL, f.Failures, f.Runs,
+			f.LastFail.Local().Format(time.DateTime), f.LastError)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type checkLinksCommand struct {
+	All         bool `short:"a" long:"all" description:"Show every checked link, not just the broken ones"`
+	Manifests   bool `long:"manifests" description:"Also check the manifest files themselves"`
+	Concurrency int  `long:"concurrency" default:"8" description:"Requests in flight at once"`
+	Rate        int  `long:"rate" default:"10" description:"Requests started per second"`
+	JSON        bool `long:"json" description:"Print the results as JSON"`
+	BoardDocs   bool `long:"board-docs" description:"Show the title and availability of each board's landing and documentation pages instead"`
+}
+
+func (c *checkLinksCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if c.BoardDocs {
+		return c.showBoardDocs(superManifest)
+	}
+	statuses := mtbmanifest.CheckURIs(superManifest, linkCheckOptions(&mtbmanifest.LinkCheckOptions{
+		MaxConcurrent:    c.Concurrency,
+		RatePerSecond:    c.Rate,
+		IncludeManifests: c.Manifests,
+	}))
+	if !c.All {
+		problems := []*mtbmanifest.LinkStatus{}
+		for _, s := range statuses {
+			if s.Problem != mtbmanifest.LinkOK {
+				problems = append(problems, s)
+			}
+		}
+		statuses = problems
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	if len(statuses) == 0 {
+		fmt.Println("All links are healthy")
+		return nil
+	}
+	for _, s := range statuses {
+		problem := string(s.Problem)
+		if problem == "" {
+			problem = "ok"
+		}
+		detail := ""
+		switch {
+		case s.Error != "":
+			detail = s.Error
+		case s.FinalURL != "":
+			detail = "-> " + s.FinalURL
+		}
+		fmt.Printf("%-8s %3d %s %s\n    used by: %s\n", problem, s.StatusCode, s.URL, detail, strings.Join(s.Referrers, ", "))
+	}
+	return nil
+}
+
+// showBoardDocs prints the page metadata of every board, or only of the boards whose
+// documentation is gone without --all
+func (c *checkLinksCommand) showBoardDocs(superManifest mtbmanifest.SuperManifestIF) error {
+	pageOpts := []mtbmanifest.PageMetadataOption{}
+	if t := networkTimeouts(); !t.IsZero() {
+		if t.Request <= 0 {
+			t.Request = 15 * time.Second
+		}
+		pageOpts = append(pageOpts, mtbmanifest.WithPageMetadataClient(t.Client()))
+	}
+	infos := mtbmanifest.NewPageMetadataFetcher(pageOpts...).BoardInfos(superManifest, maxConcurrent(c.Concurrency))
+	if !c.All {
+		infos = mtbmanifest.BoardsWithDeadDocs(infos)
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	if len(infos) == 0 {
+		fmt.Println("All board documentation is available")
+		return nil
+	}
+	describe := func(meta *mtbmanifest.PageMetadata) string {
+		switch {
+		case meta == nil:
+			return "none"
+		case !meta.Available:
+			return fmt.Sprintf("%s (%s %d %s)", meta.URL, meta.Problem, meta.StatusCode, meta.Error)
+		case meta.FinalURL != "":
+			return fmt.Sprintf("%q %s -> %s", meta.Title, meta.URL, meta.FinalURL)
+		}
+		return fmt.Sprintf("%q %s", meta.Title, meta.URL)
+	}
+	for _, info := range infos {
+		fmt.Printf("%s\n    board: %s\n    docs:  %s\n", info.ID, describe(info.BoardURI), describe(info.Documentation))
+	}
+	return nil
+}
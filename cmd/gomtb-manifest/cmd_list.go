@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// listFlags are the filtering, sorting and paging options shared by the list-* commands
+type listFlags struct {
+	Sort       string `short:"s" long:"sort" choice:"id" choice:"name" choice:"category" choice:"version" choice:"difficulty" description:"Sort key (default: manifest order); difficulty sorts apps from beginner to advanced"`
+	Descending bool   `long:"desc" description:"Sort in descending order"`
+	Offset     int    `long:"offset" description:"Skip this many items"`
+	Limit      int    `short:"n" long:"limit" description:"Show at most this many items (0 for all)"`
+	Cursor     string `long:"cursor" description:"Continue from the cursor printed at the end of a previous page"`
+	Category   string `short:"c" long:"category" description:"Only list items in this category"`
+	Query      string `short:"q" long:"query" description:"Only list items whose ID or name contains this text"`
+	Filter     string `short:"f" long:"filter" description:"Only list items matching a filter expression, e.g. 'chip=CYW43* capability=wifi'"`
+	Preset     string `short:"p" long:"preset" description:"Only list items matching a filter saved with 'query save'"`
+}
+
+func (f *listFlags) listOptions() (*mtbmanifest.ListOptions, error) {
+	// A preset and an explicit filter combine: both must match
+	expr := f.Filter
+	if f.Preset != "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		preset, err := cfg.getPreset(f.Preset)
+		if err != nil {
+			return nil, err
+		}
+		expr = strings.TrimSpace(preset + " " + expr)
+	}
+	filter, err := mtbmanifest.ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &mtbmanifest.ListOptions{
+		SortBy:     mtbmanifest.SortKey(f.Sort),
+		Descending: f.Descending,
+		Offset:     f.Offset,
+		Limit:      f.Limit,
+		Cursor:     f.Cursor,
+		Category:   f.Category,
+		Query:      f.Query,
+		Filter:     filter,
+	}, nil
+}
+
+// printPageFooter tells the user how to get the next page, if there is one
+func printPageFooter(shown, offset, total int, nextCursor string) {
+	if nextCursor != "" {
+		fmt.Printf("-- %d-%d of %d, next page: --cursor %s\n", offset+1, offset+shown, total, nextCursor)
+	}
+}
+
+type listBoardsCommand struct {
+	listFlags
+	ByFamily    bool   `long:"by-family" description:"Group the boards by chip family (CAT1A, CAT2, ...); filter with family=cat1a"`
+	Radio       string `long:"radio" description:"Only list boards with this radio chip, e.g. CYW43439 or CYW43*"`
+	CheckRadios bool   `long:"check-radios" description:"List boards whose wifi/bt capabilities do not match their radio chips instead"`
+	Obsolete    bool   `long:"include-obsolete" description:"Also list obsolete boards; filter with lifecycle=nrnd to list only those"`
+	Lifecycle   bool   `long:"lifecycle" description:"Count the boards by lifecycle status and list those not active instead"`
+}
+
+func (c *listBoardsCommand) Execute(args []string) error {
+	opts, err := c.listOptions()
+	if err != nil {
+		return err
+	}
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if c.CheckRadios {
+		for _, issue := range mtbmanifest.CheckRadios(superManifest) {
+			fmt.Println(issue)
+		}
+		return nil
+	}
+	if c.Lifecycle {
+		groups := mtbmanifest.BoardsByLifecycle(superManifest)
+		for _, l := range mtbmanifest.Lifecycles() {
+			fmt.Printf("%-10s %d\n", l, len(groups[l]))
+		}
+		for _, l := range mtbmanifest.Lifecycles()[1:] {
+			for _, board := range groups[l] {
+				fmt.Printf("  %-38s %-9s %s\n", board.ID, l, board.Name)
+			}
+		}
+		return nil
+	}
+	opts.IncludeObsolete = c.Obsolete
+	if c.Radio != "" {
+		radio, err := mtbmanifest.ParseFilter("radio=" + strconv.Quote(c.Radio))
+		if err != nil {
+			return err
+		}
+		opts.Filter.Terms = append(opts.Filter.Terms, radio.Terms...)
+	}
+	if c.ByFamily {
+		return c.listByFamily(superManifest, opts)
+	}
+	page, err := superManifest.ListBoards(opts)
+	if err != nil {
+		return err
+	}
+	for _, board := range page.Items {
+		if l := board.Lifecycle(); l != mtbmanifest.LifecycleActive {
+			fmt.Printf("%-40s %s [%s]\n", board.ID, board.Name, l)
+		} else {
+			fmt.Printf("%-40s %s\n", board.ID, board.Name)
+		}
+	}
+	printPageFooter(len(page.Items), page.Offset, page.Total, page.NextCursor)
+	return nil
+}
+
+// listByFamily prints the boards matching opts under a heading per chip family
+func (c *listBoardsCommand) listByFamily(superManifest mtbmanifest.SuperManifestIF, opts *mtbmanifest.ListOptions) error {
+	opts.Offset, opts.Limit, opts.Cursor = 0, 0, ""
+	page, err := superManifest.ListBoards(opts)
+	if err != nil {
+		return err
+	}
+	groups := map[string][]*mtbmanifest.Board{}
+	for _, board := range page.Items {
+		groups[board.Family()] = append(groups[board.Family()], board)
+	}
+	for _, group := range mtbmanifest.GroupBoardsByFamily(superManifest) {
+		boards := groups[group.Family]
+		if len(boards) == 0 {
+			continue
+		}
+		family := group.Family
+		if family == mtbmanifest.FamilyUnknown {
+			family = "Unknown family"
+		}
+		fmt.Printf("%s (%d)\n", family, len(boards))
+		for _, board := range boards {
+			fmt.Printf("  %-38s %s\n", board.ID, board.Name)
+		}
+	}
+	return nil
+}
+
+type listAppsCommand struct {
+	listFlags
+	Keywords     []string `long:"keyword" description:"Only list apps carrying this keyword (repeatable, all must match)"`
+	KeywordStats bool     `long:"keyword-stats" description:"List keywords with the number of apps carrying them instead of apps"`
+	Related      string   `long:"related" description:"List keywords that appear together with this keyword"`
+}
+
+func (c *listAppsCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+
+	if c.KeywordStats || c.Related != "" {
+		keywordIndex := superManifest.GetKeywordIndex()
+		var counts []mtbmanifest.KeywordCount
+		if c.Related != "" {
+			counts = keywordIndex.CoOccurrences(c.Related)
+		} else {
+			counts = keywordIndex.Keywords()
+		}
+		for _, kc := range counts {
+			fmt.Printf("%6d  %s\n", kc.Count, kc.Keyword)
+		}
+		return nil
+	}
+
+	opts, err := c.listOptions()
+	if err != nil {
+		return err
+	}
+	opts.Keywords = c.Keywords
+	page, err := superManifest.ListApps(opts)
+	if err != nil {
+		return err
+	}
+	for _, app := range page.Items {
+		fmt.Printf("%-50s %s\n", app.ID, app.Name)
+	}
+	printPageFooter(len(page.Items), page.Offset, page.Total, page.NextCursor)
+	return nil
+}
+
+type listMiddlewareCommand struct {
+	listFlags
+}
+
+func (c *listMiddlewareCommand) Execute(args []string) error {
+	opts, err := c.listOptions()
+	if err != nil {
+		return err
+	}
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	page, err := superManifest.ListMiddleware(opts)
+	if err != nil {
+		return err
+	}
+	for _, mw := range page.Items {
+		fmt.Printf("%-40s %s\n", mw.ID, mw.Name)
+	}
+	printPageFooter(len(page.Items), page.Offset, page.Total, page.NextCursor)
+	return nil
+}
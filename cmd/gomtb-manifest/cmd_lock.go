@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// lockCommand groups the sub-commands that record and verify asset commit SHAs
+type lockCommand struct {
+	Create lockCreateCommand `command:"create" description:"Resolve asset versions to commit SHAs and write a lockfile"`
+	Verify lockVerifyCommand `command:"verify" description:"Check that every ref in a lockfile still resolves to its recorded commit"`
+}
+
+type lockCreateCommand struct {
+	Args struct {
+		File  string   `positional-arg-name:"FILE" required:"yes"`
+		Specs []string `positional-arg-name:"ID[@VERSION]" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *lockCreateCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	lf, err := mtbmanifest.LockAssets(superManifest, c.Args.Specs, newGitFetcher())
+	if err != nil {
+		return err
+	}
+	if err := writeLockfile(c.Args.File, lf); err != nil {
+		return err
+	}
+	for _, e := range lf.Entries {
+		fmt.Printf("%-45s %-18s %s\n", e.ID, e.Ref, e.SHA)
+	}
+	for _, issue := range mtbmanifest.LockfileLifecycleIssues(superManifest, lf) {
+		logger.Warningf("%s\n", issue)
+	}
+	return nil
+}
+
+func writeLockfile(file string, lf *mtbmanifest.Lockfile) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	err = mtbmanifest.WriteLockfile(f, lf)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readLockfile reads a lockfile from disk
+func readLockfile(file string) (*mtbmanifest.Lockfile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return mtbmanifest.ReadLockfile(f)
+}
+
+type lockVerifyCommand struct {
+	Lifecycle bool `long:"lifecycle" description:"Also warn about locked boards that are NRND or obsolete; this ingests the manifest"`
+	Args      struct {
+		File string `positional-arg-name:"FILE" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *lockVerifyCommand) Execute(args []string) error {
+	lf, err := readLockfile(c.Args.File)
+	if err != nil {
+		return err
+	}
+	moved := 0
+	for _, v := range mtbmanifest.VerifyLockfile(lf, newGitFetcher()) {
+		state := "ok"
+		switch {
+		case v.Error != "":
+			state = "error: " + v.Error
+		case v.Moved:
+			state = "MOVED to " + v.CurrentSHA
+			moved++
+		}
+		fmt.Printf("%-45s %-18s %s\n", v.Entry.ID, v.Entry.Ref, state)
+	}
+	if c.Lifecycle {
+		superManifest, err := loadSuperManifest()
+		if err != nil {
+			return err
+		}
+		for _, issue := range mtbmanifest.LockfileLifecycleIssues(superManifest, lf) {
+			logger.Warningf("%s\n", issue)
+		}
+	}
+	if moved > 0 {
+		return validationErrorf("%d of %d refs no longer point at their locked commit", moved, len(lf.Entries))
+	}
+	return nil
+}
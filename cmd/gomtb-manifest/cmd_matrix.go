@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type matrixCommand struct {
+	Middleware bool   `short:"m" long:"middleware" description:"Also match the middleware, not only the code examples"`
+	Flow       string `long:"flow" choice:"mtb1" choice:"mtb2" choice:"btsdk" description:"Only match versions for this build flow"`
+	Tools      string `long:"tools-version" description:"Only match versions these tools can build, e.g. 3.2.0"`
+	Workers    int    `long:"workers" description:"Number of board versions matched at once (default: number of CPUs)"`
+	Format     string `long:"format" choice:"text" choice:"json" choice:"csv" choice:"sql" default:"text" description:"Print the matrix as text, JSON, CSV or an SQL script for sqlite3"`
+	Output     string `short:"o" long:"output" description:"Write the matrix to this file instead of standard output"`
+}
+
+func (c *matrixCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	matrix := mtbmanifest.BuildCompatibilityMatrix(superManifest, &mtbmanifest.MatrixOptions{
+		Workers: c.Workers, Middleware: c.Middleware, ToolsVersion: c.Tools, Flow: mtbmanifest.ParseFlow(c.Flow)})
+	var w io.Writer = os.Stdout
+	if c.Output != "" {
+		f, err := os.Create(c.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch c.Format {
+	case "json":
+		jsonData, err := json.MarshalIndent(matrix, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(jsonData))
+		return err
+	case "csv":
+		return matrix.WriteCSV(w)
+	case "sql":
+		return matrix.WriteSQL(w)
+	}
+	for _, cell := range matrix.Cells {
+		board := cell.Board
+		if cell.BoardVersion != "" {
+			board += " " + cell.BoardVersion
+		}
+		fmt.Fprintf(w, "%-45s %-10s %-45s %s\n", board, cell.Kind, cell.ID, strings.Join(cell.Versions, ", "))
+	}
+	return nil
+}
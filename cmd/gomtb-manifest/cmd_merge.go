@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type mergeCommand struct {
+	Into   string `long:"into" description:"Channel to add the super manifest to (default: the first --channel, or prod)"`
+	DryRun bool   `long:"dry-run" description:"Only show what merging would add, shadow and conflict with; change nothing"`
+	JSON   bool   `long:"json" description:"Print the preview as JSON"`
+	Args   struct {
+		URL string `positional-arg-name:"URL" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *mergeCommand) Execute(args []string) error {
+	into := c.Into
+	if into == "" {
+		into = mtbmanifest.DefaultChannel
+		if len(options.Channel) > 0 {
+			into = options.Channel[0]
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	channels, err := cfg.channelSet()
+	if err != nil {
+		return err
+	}
+	ch, ok := channels.Channel(into)
+	if !ok {
+		return fmt.Errorf("channel %s %w", into, errNotFound)
+	}
+	if slices.Contains(ch.URLs, c.Args.URL) {
+		return fmt.Errorf("channel %s already merges %s", into, c.Args.URL)
+	}
+
+	current, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	ingestOpts, err := ingestOptions()
+	if err != nil {
+		return err
+	}
+	incoming, err := mtbmanifest.NewSuperManifestFromURL(c.Args.URL, ingestOpts...)
+	if err != nil {
+		return err
+	}
+	preview := mtbmanifest.PreviewMerge(current, incoming)
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(preview, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		printMergePreview(preview)
+	}
+	if c.DryRun {
+		if !c.JSON {
+			fmt.Printf("Dry run: channel %s was not changed\n", into)
+		}
+		return nil
+	}
+
+	merged := &mtbmanifest.Channel{Name: into, URLs: append(append([]string{}, ch.URLs...), c.Args.URL),
+		Description: ch.Description}
+	replaced := false
+	for i, existing := range cfg.Channels {
+		if existing.Name == into {
+			cfg.Channels[i], replaced = merged, true
+		}
+	}
+	if !replaced {
+		cfg.Channels = append(cfg.Channels, merged)
+	}
+	if err := saveConfig(cfg); err != nil {
+		return err
+	}
+	if !c.JSON {
+		fmt.Printf("Channel %s now merges %s; read it with --channel %s\n", into, c.Args.URL, into)
+	}
+	return nil
+}
+
+// printMergePreview prints what a merge would do
+func printMergePreview(p *mtbmanifest.MergePreview) {
+	counts := func(c mtbmanifest.ItemCounts) string {
+		return fmt.Sprintf("%d boards, %d apps, %d middleware", c.Boards, c.Apps, c.Middleware)
+	}
+	fmt.Printf("Before:   %s\n", counts(p.Before))
+	fmt.Printf("Incoming: %s\n", counts(p.Incoming))
+	fmt.Printf("After:    %s\n", counts(p.After))
+	if p.VersionMismatch != "" {
+		fmt.Printf("Super manifest versions differ: %s\n", p.VersionMismatch)
+	}
+	if len(p.Added) > 0 {
+		fmt.Printf("\nAdded (%d):\n", len(p.Added))
+		for _, a := range p.Added {
+			fmt.Printf("  %-10s %s\n", a.Kind, a.ID)
+		}
+	}
+	if len(p.Shadowed) > 0 {
+		fmt.Printf("\nShadowed by the incoming tree (%d, %d differ):\n", len(p.Shadowed), len(p.Differing()))
+		for _, s := range p.Shadowed {
+			if !s.Differs {
+				fmt.Printf("  %-10s %s (identical)\n", s.Kind, s.ID)
+				continue
+			}
+			fmt.Printf("  %-10s %s", s.Kind, s.ID)
+			if len(s.AddedVersions) > 0 {
+				fmt.Printf(" +%v", s.AddedVersions)
+			}
+			if len(s.RemovedVersions) > 0 {
+				fmt.Printf(" -%v", s.RemovedVersions)
+			}
+			fmt.Println()
+		}
+	}
+	if len(p.Conflicts) > 0 {
+		fmt.Printf("\nConflicting dependency and capability manifests (%d):\n", len(p.Conflicts))
+		for _, u := range p.Conflicts {
+			fmt.Printf("  %s\n", u)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type mirrorCommand struct {
+	BaseURL string `long:"base-url" required:"yes" description:"URL the directory will be served at, e.g. https://mirror.example.com/mtb"`
+	Args    struct {
+		Dir string `positional-arg-name:"DIR" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *mirrorCommand) Execute(args []string) error {
+	superManifest, err := loadLiveSuperManifest()
+	if err != nil {
+		return err
+	}
+	report, err := mtbmanifest.PublishMirror(superManifest, nil, c.Args.Dir, c.BaseURL)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d files to %s\n", len(report.Files), c.Args.Dir)
+	for _, u := range report.SuperManifestURLs {
+		fmt.Printf("Use --url %s to load from the mirror\n", u)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// policyCommand groups the sub-commands about organizational policies
+type policyCommand struct {
+	Check policyCheckCommand `command:"check" description:"Report policy violations; fails when any of them blocks"`
+}
+
+type policyCheckCommand struct {
+	Policy   string `long:"policy" description:"Policy file; default the policy of the config file"`
+	Lockfile string `long:"lockfile" description:"Check the entries of this lockfile"`
+	JSON     bool   `long:"json" description:"Print the violations as JSON"`
+	Args     struct {
+		Specs []string `positional-arg-name:"ID[@VERSION]"`
+	} `positional-args:"yes"`
+}
+
+func (c *policyCheckCommand) Execute(args []string) error {
+	policy, err := loadPolicy(c.Policy)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return errors.New("no policy: give --policy or set \"policy\" in the config file")
+	}
+	violations := []*mtbmanifest.PolicyViolation{}
+	if c.Lockfile != "" {
+		lf, err := readLockfile(c.Lockfile)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, policy.CheckLockfile(lf)...)
+	}
+	if len(c.Args.Specs) > 0 || c.Lockfile == "" {
+		superManifest, err := loadSuperManifest()
+		if err != nil {
+			return err
+		}
+		if len(c.Args.Specs) == 0 {
+			violations = policy.CheckTree(superManifest)
+		}
+		for _, spec := range c.Args.Specs {
+			id, version, _ := strings.Cut(spec, "@")
+			if lookupItem(superManifest, id, nil) == nil {
+				return notFoundError(superManifest, id)
+			}
+			found, err := policy.CheckItem(superManifest, id, version)
+			if err != nil {
+				return err
+			}
+			violations = append(violations, found...)
+		}
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(violations, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+	} else if len(violations) == 0 {
+		fmt.Println("No policy violations")
+	} else {
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+	}
+	blocking := 0
+	for _, v := range violations {
+		if v.Blocking {
+			blocking++
+		}
+	}
+	if blocking > 0 {
+		return validationErrorf("%d of %d policy violations block", blocking, len(violations))
+	}
+	return nil
+}
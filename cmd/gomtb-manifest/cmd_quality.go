@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type qualityCommand struct {
+	CheckLinks bool `long:"check-links" description:"Also request every link and grade the dead ones (slow)"`
+	Limit      int  `long:"limit" default:"10" description:"Issues to show per manifest, 0 for all"`
+	JSON       bool `long:"json" description:"Print the scorecard as JSON"`
+}
+
+func (c *qualityCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	opts := &mtbmanifest.ScorecardOptions{}
+	if c.CheckLinks {
+		opts.Links = mtbmanifest.CheckURIs(superManifest, linkCheckOptions(&mtbmanifest.LinkCheckOptions{}))
+	}
+	card := mtbmanifest.ScoreManifests(superManifest, opts)
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(card, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	for _, s := range card.Sources {
+		fmt.Printf("%s %3d  %s (%s, %d items)\n", s.Grade, s.Score, s.URL, s.Kind, s.Items)
+		kinds := make([]string, 0, len(s.Counts))
+		for kind := range s.Counts {
+			kinds = append(kinds, string(kind))
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Printf("      %-20s %d\n", kind, s.Counts[mtbmanifest.QualityIssueKind(kind)])
+		}
+		for i, issue := range s.Issues {
+			if c.Limit > 0 && i == c.Limit {
+				fmt.Printf("      ... %d more\n", len(s.Issues)-c.Limit)
+				break
+			}
+			fmt.Printf("      - %s\n", issue)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// queryCommand groups the sub-commands that manage saved filter presets
+type queryCommand struct {
+	Save   querySaveCommand   `command:"save" description:"Save a filter expression under a name"`
+	List   queryListCommand   `command:"list" description:"List saved filter presets"`
+	Delete queryDeleteCommand `command:"delete" description:"Delete a saved filter preset"`
+}
+
+type querySaveCommand struct {
+	Args struct {
+		Name string   `positional-arg-name:"NAME" required:"yes"`
+		Expr []string `positional-arg-name:"EXPR" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *querySaveCommand) Execute(args []string) error {
+	expr := strings.Join(c.Args.Expr, " ")
+	if _, err := mtbmanifest.ParseFilter(expr); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Presets == nil {
+		cfg.Presets = make(map[string]string)
+	}
+	cfg.Presets[c.Args.Name] = expr
+	if err := saveConfig(cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Saved preset %q: %s\n", c.Args.Name, expr)
+	return nil
+}
+
+type queryListCommand struct{}
+
+func (c *queryListCommand) Execute(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(cfg.Presets))
+	for name := range cfg.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-20s %s\n", name, cfg.Presets[name])
+	}
+	return nil
+}
+
+type queryDeleteCommand struct {
+	Args struct {
+		Name string `positional-arg-name:"NAME" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *queryDeleteCommand) Execute(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.getPreset(c.Args.Name); err != nil {
+		return err
+	}
+	delete(cfg.Presets, c.Args.Name)
+	return saveConfig(cfg)
+}
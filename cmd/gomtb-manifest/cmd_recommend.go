@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type recommendCommand struct {
+	Board    string  `short:"b" long:"board" description:"Only recommend middleware compatible with this board"`
+	Limit    int     `short:"n" long:"limit" default:"10" description:"Maximum number of recommendations"`
+	MinScore float64 `long:"min-score" default:"0.25" description:"Leave out middleware required together with the selection less often than this (0 to 1)"`
+	JSON     bool    `long:"json" description:"Print the recommendations as JSON"`
+	Args     struct {
+		Selected []string `positional-arg-name:"MIDDLEWARE_ID" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func (c *recommendCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	if c.Board != "" {
+		if _, ok := superManifest.GetBoard(c.Board); !ok {
+			return notFoundError(superManifest, c.Board)
+		}
+	}
+	for _, id := range c.Args.Selected {
+		if _, ok := superManifest.GetMiddleware(id); !ok {
+			return notFoundError(superManifest, id)
+		}
+	}
+	recs, err := mtbmanifest.Recommend(superManifest, c.Board, c.Args.Selected,
+		&mtbmanifest.RecommendOptions{Limit: c.Limit, MinScore: c.MinScore})
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(recs, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	if len(recs) == 0 {
+		fmt.Println("Nothing to recommend")
+		return nil
+	}
+	for _, r := range recs {
+		why := "required by " + strings.Join(r.RequiredBy, ", ")
+		if len(r.RequiredBy) == 0 {
+			why = "often used with " + strings.Join(r.CoRequiredWith, ", ")
+		}
+		fmt.Printf("%-40s %4.2f  %s\n", r.ID, r.Score, why)
+	}
+	return nil
+}
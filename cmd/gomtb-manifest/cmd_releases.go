@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type checkReleasesCommand struct {
+	Kind []string `short:"k" long:"kind" choice:"board" choice:"app" choice:"middleware" description:"Only check items of this kind (repeatable)"`
+	All  bool     `short:"a" long:"all" description:"Show every checked item, not just the ones that lag behind"`
+	JSON bool     `long:"json" description:"Print the results as JSON"`
+	Args struct {
+		IDs []string `positional-arg-name:"ID"`
+	} `positional-args:"yes"`
+}
+
+func (c *checkReleasesCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	opts := &mtbmanifest.ReleaseCheckOptions{IDs: c.Args.IDs, Fetcher: newGitFetcher()}
+	for _, k := range c.Kind {
+		opts.Kinds = append(opts.Kinds, mtbmanifest.ItemKind(k))
+	}
+	statuses := mtbmanifest.CheckReleases(superManifest, opts)
+	if !c.All {
+		lagging := []*mtbmanifest.ReleaseStatus{}
+		for _, s := range statuses {
+			if s.Behind {
+				lagging = append(lagging, s)
+			}
+		}
+		statuses = lagging
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	if len(statuses) == 0 {
+		fmt.Println("No manifest entry lags behind its repository")
+		return nil
+	}
+	for _, s := range statuses {
+		state := "ok"
+		switch {
+		case s.Error != "":
+			state = "error: " + s.Error
+		case s.Behind:
+			state = "BEHIND"
+		}
+		fmt.Printf("%-10s %-45s %-18s %-18s %s\n", s.Kind, s.ID, s.ManifestNewest, s.LatestTag, state)
+	}
+	return nil
+}
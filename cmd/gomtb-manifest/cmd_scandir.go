@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type scanDirCommand struct {
+	JSON     bool          `long:"json" description:"Print the files found and the counts as JSON"`
+	Format   string        `long:"format" choice:"text" choice:"github" choice:"sarif" default:"text" description:"Report the problems found as text, as GitHub Actions annotations, or as SARIF"`
+	Output   string        `short:"o" long:"output" description:"Write the GitHub annotations or SARIF log to this file instead of standard output"`
+	Watch    bool          `long:"watch" description:"Keep watching the directory and report what each edit changes"`
+	Interval time.Duration `long:"interval" default:"500ms" description:"How often to look at the files with --watch"`
+	Args     struct {
+		Dir string `positional-arg-name:"DIR" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *scanDirCommand) Execute(args []string) error {
+	ingestOpts, err := ingestOptions()
+	if err != nil {
+		return err
+	}
+	if c.Watch {
+		return c.watch(ingestOpts)
+	}
+	scan, err := mtbmanifest.ScanDir(c.Args.Dir, ingestOpts...)
+	if err != nil {
+		return err
+	}
+	findings := scan.Findings()
+	out := io.Writer(os.Stdout)
+	if c.Output != "" && (c.Format == "github" || c.Format == "sarif") {
+		f, err := os.Create(c.Output)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+	switch {
+	case c.JSON:
+		jsonData, err := json.MarshalIndent(scan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+	case c.Format == "github":
+		if err := mtbmanifest.WriteGitHubAnnotations(out, findings); err != nil {
+			return err
+		}
+	case c.Format == "sarif":
+		if err := mtbmanifest.WriteSARIF(out, findings); err != nil {
+			return err
+		}
+	default:
+		c.printScan(scan, findings)
+	}
+	if mtbmanifest.HasErrors(findings) {
+		return validationErrorf("%d manifest files do not parse", len(scan.Failed()))
+	}
+	return nil
+}
+
+// printScan prints the files found, the counts and the findings
+func (c *scanDirCommand) printScan(scan *mtbmanifest.DirScan, findings []*mtbmanifest.LintFinding) {
+	if len(scan.Files) == 0 {
+		fmt.Printf("No manifest files in %s\n", c.Args.Dir)
+		return
+	}
+	for _, f := range scan.Files {
+		switch {
+		case f.Error != "":
+			fmt.Printf("%-14s %s (does not parse)\n", f.Kind, f.Path)
+		case f.Format != 0:
+			fmt.Printf("%-14s %s (fv%d, %d items)\n", f.Kind, f.Path, f.Format, f.Items)
+		default:
+			fmt.Printf("%-14s %s (%d items)\n", f.Kind, f.Path, f.Items)
+		}
+	}
+	fmt.Println()
+	for _, kind := range mtbmanifest.ManifestKinds() {
+		if n := scan.Counts[kind]; n > 0 {
+			fmt.Printf("%d %s files\n", n, kind)
+		}
+	}
+	if failed := scan.Failed(); len(failed) > 0 {
+		fmt.Printf("%d files did not parse\n", len(failed))
+	}
+	if scan.Tree != nil {
+		fmt.Printf("\nTree: %d boards, %d apps, %d middleware\n", len(scan.Tree.GetBoardIDs()),
+			len(scan.Tree.GetAppIDs()), len(scan.Tree.GetMiddlewareIDs()))
+	}
+	if len(findings) > 0 {
+		fmt.Printf("\nFindings (%d):\n", len(findings))
+		for _, f := range findings {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+}
+
+// watch reports the item changes of every edit to the directory until interrupted
+func (c *scanDirCommand) watch(ingestOpts []mtbmanifest.IngestOption) error {
+	w, err := mtbmanifest.NewDirWatcher(c.Args.Dir, ingestOpts...)
+	if err != nil {
+		return err
+	}
+	tree := w.Live().Tree()
+	fmt.Printf("Watching %s: %d boards, %d apps, %d middleware\n", c.Args.Dir, len(tree.GetBoardIDs()),
+		len(tree.GetAppIDs()), len(tree.GetMiddlewareIDs()))
+	err = w.Run(shutdownCtx, c.Interval, func(reload *mtbmanifest.DirReload) {
+		if c.JSON {
+			jsonData, err := json.Marshal(reload)
+			if err == nil {
+				fmt.Println(string(jsonData))
+			}
+			return
+		}
+		fmt.Printf("%s changed %v\n", time.Now().Format(time.TimeOnly), reload.Files)
+		if reload.Err != nil {
+			fmt.Printf("  kept the previous tree: %v\n", reload.Err)
+			return
+		}
+		printChangeSet(reload.Changes)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// printChangeSet prints the items a reload added, removed and changed
+func printChangeSet(changes *mtbmanifest.ChangeSet) {
+	if changes.Empty() {
+		fmt.Println("  no item changed")
+		return
+	}
+	for _, c := range changes.Added {
+		fmt.Printf("  + %-10s %s\n", c.Kind, c.ID)
+	}
+	for _, c := range changes.Removed {
+		fmt.Printf("  - %-10s %s\n", c.Kind, c.ID)
+	}
+	for _, c := range changes.Changed {
+		fmt.Printf("  ~ %-10s %s", c.Kind, c.ID)
+		if len(c.AddedVersions) > 0 {
+			fmt.Printf(" +%v", c.AddedVersions)
+		}
+		if len(c.RemovedVersions) > 0 {
+			fmt.Printf(" -%v", c.RemovedVersions)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type searchCommand struct {
+	Kinds    []string `short:"k" long:"kind" choice:"board" choice:"app" choice:"middleware" description:"Only search items of this kind (repeatable)"`
+	Limit    int      `short:"n" long:"limit" default:"20" description:"Maximum number of results (0 for all)"`
+	MatchAll bool     `long:"all" description:"Require every query term to match"`
+	Long     bool     `short:"l" long:"long" description:"Also show a short excerpt of each description"`
+	Readmes  bool     `long:"readmes" description:"Also search the READMEs of the middleware repositories (downloaded once a week)"`
+	Args     struct {
+		Query []string `positional-arg-name:"QUERY" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func (c *searchCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+
+	if c.Readmes {
+		readmes := newReadmeFetcher().Readmes(superManifest, maxConcurrent(4))
+		logger.Debugf("Fetched %d of %d middleware READMEs\n", len(readmes), len(superManifest.GetMiddlewareIDs()))
+	}
+	opts := &mtbmanifest.SearchOptions{
+		Limit:    c.Limit,
+		MatchAll: c.MatchAll,
+	}
+	for _, kind := range c.Kinds {
+		opts.Kinds = append(opts.Kinds, mtbmanifest.ItemKind(kind))
+	}
+	query := strings.Join(c.Args.Query, " ")
+	results := superManifest.Search(query, opts)
+	if len(results) == 0 {
+		fmt.Printf("No matches for %q\n", query)
+		return nil
+	}
+	for _, r := range results {
+		fmt.Printf("%-10s %-45s %7.2f  %s\n", r.Kind, r.ID, r.Score, r.Name)
+		if c.Long {
+			if excerpt := itemExcerpt(r.Item, 100); excerpt != "" {
+				fmt.Printf("%10s %s\n", "", excerpt)
+			}
+		}
+	}
+	return nil
+}
+
+// itemExcerpt returns a one line description excerpt of a board, app or middleware item
+func itemExcerpt(item any, maxLen int) string {
+	switch v := item.(type) {
+	case *mtbmanifest.Board:
+		return v.Excerpt(maxLen)
+	case *mtbmanifest.App:
+		return v.Excerpt(maxLen)
+	case *mtbmanifest.MiddlewareItem:
+		if excerpt := v.Excerpt(maxLen); excerpt != "" || v.Readme() == nil {
+			return excerpt
+		}
+		return mtbmanifest.Excerpt(v.Readme().Snippet, maxLen)
+	}
+	return ""
+}
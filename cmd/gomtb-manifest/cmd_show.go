@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type showCommand struct {
+	Kind string `short:"k" long:"kind" choice:"board" choice:"app" choice:"middleware" description:"Only look for items of this kind"`
+	Args struct {
+		ID string `positional-arg-name:"ID" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func (c *showCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+
+	kinds := []mtbmanifest.ItemKind{}
+	if c.Kind != "" {
+		kinds = append(kinds, mtbmanifest.ItemKind(c.Kind))
+	}
+	item := lookupItem(superManifest, c.Args.ID, kinds)
+	if item == nil {
+		return notFoundError(superManifest, c.Args.ID, kinds...)
+	}
+	jsonData, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+// lookupItem finds a board, app or middleware item by exact ID. Returns nil if not found.
+func lookupItem(sm mtbmanifest.SuperManifestIF, id string, kinds []mtbmanifest.ItemKind) any {
+	wanted := func(kind mtbmanifest.ItemKind) bool {
+		if len(kinds) == 0 {
+			return true
+		}
+		for _, k := range kinds {
+			if k == kind {
+				return true
+			}
+		}
+		return false
+	}
+	if wanted(mtbmanifest.ItemKindBoard) {
+		if board, ok := sm.GetBoard(id); ok {
+			return board
+		}
+	}
+	if wanted(mtbmanifest.ItemKindApp) {
+		if app, ok := sm.GetApp(id); ok {
+			return app
+		}
+	}
+	if wanted(mtbmanifest.ItemKindMiddleware) {
+		if mw, ok := sm.GetMiddleware(id); ok {
+			return mw
+		}
+	}
+	return nil
+}
+
+// notFoundError builds a "not found" error that includes did-you-mean suggestions
+func notFoundError(sm mtbmanifest.SuperManifestIF, id string, kinds ...mtbmanifest.ItemKind) error {
+	suggestions := sm.FindClosest(id, kinds...)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("%s %w", id, errNotFound)
+	}
+	ids := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		ids = append(ids, s.ID)
+	}
+	return fmt.Errorf("%s %w, did you mean %s?", id, errNotFound, strings.Join(ids, " or "))
+}
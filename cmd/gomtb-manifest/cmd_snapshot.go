@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// snapshotCommand groups the sub-commands that manage stored snapshots
+type snapshotCommand struct {
+	Create snapshotCreateCommand `command:"create" description:"Save the current manifest tree as a snapshot"`
+	List   snapshotListCommand   `command:"list" description:"List stored snapshots"`
+	Use    snapshotUseCommand    `command:"use" description:"Pin ingestion to a snapshot, or unpin with --none"`
+}
+
+// shortID is how snapshot IDs are shown; any unique prefix is accepted
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+type snapshotCreateCommand struct {
+	DryRun bool `long:"dry-run" description:"Tell which snapshot file would be written, without writing it"`
+}
+
+func (c *snapshotCreateCommand) Execute(args []string) error {
+	superManifest, err := loadLiveSuperManifest()
+	if err != nil {
+		return err
+	}
+	if c.DryRun {
+		index, change, err := mtbmanifest.NewSnapshotStore("").PlanCreate(superManifest, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Snapshot %s with %d files\n", shortID(index.ID()), len(index.Entries))
+		printDryRun([]*mtbmanifest.CacheChange{change})
+		return nil
+	}
+	index, err := mtbmanifest.NewSnapshotStore("").Create(superManifest, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created snapshot %s with %d files\n", shortID(index.ID()), len(index.Entries))
+	return nil
+}
+
+type snapshotListCommand struct {
+	JSON bool `long:"json" description:"Print the full snapshot metadata as JSON"`
+}
+
+func (c *snapshotListCommand) Execute(args []string) error {
+	list, err := mtbmanifest.NewSnapshotStore("").List()
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	for _, index := range list {
+		mark := " "
+		if cfg.Snapshot == index.ID() {
+			mark = "*"
+		}
+		fmt.Printf("%s %s  %s  %4d files  %s\n", mark, shortID(index.ID()),
+			index.Created.Local().Format("2006-01-02 15:04"), len(index.Entries), strings.Join(index.RootURLs, " "))
+	}
+	return nil
+}
+
+type snapshotUseCommand struct {
+	None   bool `long:"none" description:"Unpin and go back to the live manifests"`
+	DryRun bool `long:"dry-run" description:"Tell what the pin would change, without saving it"`
+	Args   struct {
+		ID string `positional-arg-name:"ID"`
+	} `positional-args:"yes"`
+}
+
+func (c *snapshotUseCommand) Execute(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	previous := cfg.Snapshot
+	switch {
+	case c.None:
+		cfg.Snapshot = ""
+	case c.Args.ID == "":
+		return fmt.Errorf("give a snapshot ID or --none")
+	default:
+		if cfg.Snapshot, err = mtbmanifest.NewSnapshotStore("").Find(c.Args.ID); err != nil {
+			return err
+		}
+	}
+	if c.DryRun {
+		fmt.Printf("Dry run: ingestion would be pinned to %s instead of %s; nothing was changed\n",
+			describePin(cfg.Snapshot), describePin(previous))
+		return nil
+	}
+	if err := saveConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.Snapshot == "" {
+		fmt.Println("Using the live manifests")
+	} else {
+		fmt.Printf("Pinned to snapshot %s\n", shortID(cfg.Snapshot))
+	}
+	return nil
+}
+
+// describePin names what ingestion is pinned to
+func describePin(id string) string {
+	if id == "" {
+		return "the live manifests"
+	}
+	return "snapshot " + shortID(id)
+}
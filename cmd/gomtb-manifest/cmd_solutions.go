@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type solutionsCommand struct {
+	Limit    int    `short:"n" long:"limit" default:"10" description:"Maximum number of solutions"`
+	Partial  bool   `long:"partial" description:"Also show boards that satisfy only some of the wanted capabilities"`
+	Flow     string `long:"flow" choice:"mtb1" choice:"mtb2" choice:"btsdk" description:"Only propose boards, examples and middleware for this build flow"`
+	Tools    string `long:"tools-version" description:"Only propose examples and middleware these tools can build, e.g. 3.2.0"`
+	Policy   string `long:"policy" description:"Leave out what this policy file blocks; default the policy of the config file"`
+	Obsolete bool   `long:"include-obsolete" description:"Also propose obsolete boards"`
+	JSON     bool   `long:"json" description:"Print the solutions as JSON"`
+	Args     struct {
+		Wanted []string `positional-arg-name:"CAPABILITY" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func (c *solutionsCommand) Execute(args []string) error {
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	policy, err := loadPolicy(c.Policy)
+	if err != nil {
+		return err
+	}
+	solutions := mtbmanifest.FindSolutions(superManifest, strings.Join(c.Args.Wanted, " "),
+		&mtbmanifest.SolutionOptions{Limit: c.Limit, AllowPartial: c.Partial, Flow: mtbmanifest.ParseFlow(c.Flow),
+			ToolsVersion: c.Tools, Policy: policy, IncludeObsolete: c.Obsolete})
+	if c.JSON {
+		jsonData, err := json.MarshalIndent(solutions, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+	if len(solutions) == 0 {
+		fmt.Println("No board satisfies all of the wanted capabilities (try --partial)")
+		return nil
+	}
+
+	for i, s := range solutions {
+		fmt.Printf("%d. %s (%s)\n", i+1, s.BoardID, s.Board.Name)
+		fmt.Printf("    Board provides: %s\n", joinOrDash(s.FromBoard))
+		if s.Lifecycle != "" {
+			fmt.Printf("    Lifecycle:      %s\n", s.Lifecycle)
+		}
+		for _, mw := range s.Middleware {
+			fmt.Printf("    Middleware:     %s %s (%s)\n", mw.ID, mw.Version, strings.Join(mw.Provides, ", "))
+		}
+		if len(s.Missing) > 0 {
+			fmt.Printf("    Missing:        %s\n", strings.Join(s.Missing, ", "))
+		}
+		fmt.Printf("    Code examples:  %s\n", joinOrDash(s.AppIDs))
+	}
+	return nil
+}
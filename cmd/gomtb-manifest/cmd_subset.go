@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+type subsetCommand struct {
+	Filter  string `short:"f" long:"filter" required:"yes" description:"Boards to keep, as a filter expression, e.g. 'category=Kit family=PSoC4'"`
+	BaseURL string `long:"base-url" required:"yes" description:"URL the directory will be served at, e.g. https://edu.example.com/mtb"`
+	Args    struct {
+		Dir string `positional-arg-name:"DIR" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *subsetCommand) Execute(args []string) error {
+	filter, err := mtbmanifest.ParseFilter(c.Filter)
+	if err != nil {
+		return err
+	}
+	superManifest, err := loadSuperManifest()
+	if err != nil {
+		return err
+	}
+	report, err := mtbmanifest.ExportSubset(superManifest, filter, c.Args.Dir, c.BaseURL)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d boards, %d code examples and %d middleware in %d files to %s\n",
+		report.Boards, report.Apps, report.Middleware, len(report.Files), c.Args.Dir)
+	for _, file := range report.Files {
+		fmt.Printf("  %-14s %s\n", file.Kind, file.Path)
+	}
+	fmt.Printf("Use --url %s to load the subset\n", report.SuperManifestURL)
+	return nil
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+	"github.com/jessevdk/go-flags"
+)
+
+// addCommands registers all sub-commands with the parser. Without a sub-command, the
+// program runs its original demo behavior.
+func addCommands(parser *flags.Parser) {
+	_, _ = parser.AddCommand("search", "Full-text search over boards, apps and middleware",
+		"Search names, IDs, descriptions, keywords and categories. Results are ranked best match first.",
+		&searchCommand{})
+	_, _ = parser.AddCommand("show", "Show a board, app or middleware item as JSON",
+		"Look up an item by its exact ID and print it. Suggests close matches if the ID is not found.",
+		&showCommand{})
+	_, _ = parser.AddCommand("categories", "Show the category tree or the items in a category",
+		"Categories of boards, apps and middleware, optionally cleaned up and grouped by a taxonomy config.",
+		&categoriesCommand{})
+	_, _ = parser.AddCommand("list-boards", "List boards",
+		"List boards, optionally filtered, sorted and paged.",
+		&listBoardsCommand{})
+	_, _ = parser.AddCommand("list-apps", "List code examples",
+		"List code example apps, optionally filtered by keyword, or show keyword statistics.",
+		&listAppsCommand{})
+	_, _ = parser.AddCommand("list-middleware", "List middleware",
+		"List middleware items, optionally filtered, sorted and paged.",
+		&listMiddlewareCommand{})
+	_, _ = parser.AddCommand("compare", "Compare two boards side by side",
+		"Compare chips, capabilities, versions, dependencies and compatible middleware/examples of two boards.",
+		&compareCommand{})
+	_, _ = parser.AddCommand("solutions", "Propose board, code example and middleware bundles",
+		"Given capabilities such as 'ble display freertos', propose boards, code examples and middleware that provide them.",
+		&solutionsCommand{})
+	_, _ = parser.AddCommand("recommend", "Suggest middleware to go with a selection",
+		"Given selected middleware IDs, suggest the libraries they require and those most often required together with them in the dependency manifests, optionally only those compatible with a board.",
+		&recommendCommand{})
+	_, _ = parser.AddCommand("impact", "List the BSPs and libraries a library change affects",
+		"Follow the dependencies of every version of every BSP and library and list those that need LIBRARY_ID at a version in RANGE, grouped by channel. Export as CSV with --format csv.",
+		&impactCommand{})
+	_, _ = parser.AddCommand("matrix", "Tell which code examples and middleware run on which boards",
+		"Match every version of every board against every version of every code example, and of every middleware with --middleware, in parallel. Export as CSV with --format csv, or load into SQLite with --format sql.",
+		&matrixCommand{})
+	_, _ = parser.AddCommand("bundle", "Create or install offline bundles",
+		"Package the whole manifest tree into one file, and install it into the cache on a machine without network access.",
+		&bundleCommand{})
+	_, _ = parser.AddCommand("cache", "Export or import the manifest cache",
+		"Save the manifest cache to a file and restore it elsewhere, e.g. as a CI build artifact.",
+		&cacheCommand{})
+	_, _ = parser.AddCommand("query", "Manage saved filter presets",
+		"Save, list and delete named filter expressions for use with --preset on the list-* commands.",
+		&queryCommand{})
+	_, _ = parser.AddCommand("mirror", "Publish the manifest tree as a static mirror",
+		"Write every manifest into a directory laid out for static hosting, with the super manifest rewritten to point at the mirror's base URL.",
+		&mirrorCommand{})
+	_, _ = parser.AddCommand("subset", "Publish the part of the manifest tree for some boards",
+		"Write the manifests again with only the boards matching a filter, the code examples and middleware that work with them and the libraries they depend on, laid out for static hosting like a mirror, e.g. to publish a channel of education kits only.",
+		&subsetCommand{})
+	_, _ = parser.AddCommand("daemon", "Answer queries from IDE integrations over a local socket",
+		"Keep the manifest tree in memory and answer JSON requests, one per line, on a Unix socket: ping, list, get, search, compatible, recommend and refresh. See mtbmanifest.Daemon for the protocol.",
+		&daemonCommand{})
+	_, _ = parser.AddCommand("snapshot", "Create, list and pin manifest snapshots",
+		"Save the manifest tree as a snapshot and pin ingestion to it, so runs weeks apart see identical data.",
+		&snapshotCommand{})
+	_, _ = parser.AddCommand("archive", "Read manifest trees from the git history of manifest repositories",
+		"Given local clones of the repositories the manifests are published from, store the tree at each commit as a snapshot for --as-of, or tell when every board, app and middleware first and last appeared.",
+		&archiveCommand{})
+	_, _ = parser.AddCommand("fetch", "Fetch the sources of a board, app or middleware version",
+		"Get the files of an item's git repository at one of its versions, optionally pinned to a commit SHA. Repositories are cached as bare clones.",
+		&fetchCommand{})
+	_, _ = parser.AddCommand("create", "Create a project from a code example",
+		"Fetch a code example and fill in its template markers and Makefile for the given application name and BSP, as project-creator does.",
+		&createCommand{})
+	_, _ = parser.AddCommand("package-bsp", "Assemble a deployable BSP folder",
+		"Fetch the BSP of a board version and the libraries it needs into DIR, in the layout library-manager produces: bsps/TARGET_<board> with .mtb files for its dependencies, and mtb_shared/<id>/<version>. What was placed where is recorded in bsp-package.json.",
+		&packageBSPCommand{})
+	_, _ = parser.AddCommand("changelog", "Show what changed between two versions of an item",
+		"Read the release notes of a board, app or middleware at two versions and show the entries added in between. TO defaults to the newest version.",
+		&changelogCommand{})
+	_, _ = parser.AddCommand("history", "Show the release history of an item",
+		"List the versions of a board, app or middleware in release order, with the number of releases of each major version.",
+		&historyCommand{})
+	_, _ = parser.AddCommand("cadence", "Report release cadence and stale items",
+		"Date every release by the first stored snapshot that lists it, and report for each item the mean time between releases and the age of its last release, flagging those without a release in --stale-after.",
+		&cadenceCommand{})
+	_, _ = parser.AddCommand("check-releases", "Find manifest entries that lag behind their repository",
+		"Compare the newest version each board, app or middleware lists with the newest release tag of its repository.",
+		&checkReleasesCommand{})
+	_, _ = parser.AddCommand("lock", "Record and verify asset commit SHAs",
+		"Resolve asset versions such as latest-v4.X to commit SHAs in a lockfile, and later detect tags that have been moved.",
+		&lockCommand{})
+	_, _ = parser.AddCommand("policy", "Check assets against an organizational policy",
+		"Evaluate the denied IDs, allowed hosts and licenses and minimum versions of a policy file against a lockfile, some items or the whole manifest tree.",
+		&policyCommand{})
+	_, _ = parser.AddCommand("check-links", "Report dead links, redirects and TLS problems",
+		"Request every uri, board_uri and documentation_url in the manifest tree, rate limited, and report the ones that are broken or moved.",
+		&checkLinksCommand{})
+	_, _ = parser.AddCommand("doctor", "Check the environment and the cache, and suggest fixes",
+		"Check connectivity to the manifest hosts, the proxy configuration, that the cache directory is writable and its entries readable, and where ModusToolbox is installed. Each problem comes with what to do about it; --fix repairs the cache.",
+		&doctorCommand{})
+	_, _ = parser.AddCommand("diagnose", "Write a diagnostics bundle for support tickets",
+		"Ingest the manifests and zip up the environment, cache statistics, the ingest report, recorded errors and the configuration, with credentials redacted.",
+		&diagnoseCommand{})
+	_, _ = parser.AddCommand("ingest-history", "Show trends of past ingestions",
+		"Summarize the ingest reports saved in the manifest cache: how the number of boards, apps and middleware changed, and which manifest URLs and hosts keep failing.",
+		&ingestHistoryCommand{})
+	_, _ = parser.AddCommand("quality", "Grade the data quality of each manifest",
+		"Score every board, app and middleware manifest on missing descriptions, unparsable versions, unknown capability tokens, unknown tags and, with --check-links, dead links. Worst manifest first.",
+		&qualityCommand{})
+	_, _ = parser.AddCommand("channels", "List the super manifest channels",
+		"List prod and the channels of the config, such as early-access ones, with their super manifest URLs and cache directories. Select channels with --channel.",
+		&channelsCommand{})
+	_, _ = parser.AddCommand("merge", "Merge another super manifest into a channel",
+		"Ingest another super manifest and show what merging it would add, which IDs it would shadow and which dependency or capability manifests conflict; then add it to a channel of the config. With --dry-run nothing is changed.",
+		&mergeCommand{})
+	_, _ = parser.AddCommand("deprecations", "List deprecated library symbols a program still uses",
+		"Write a Markdown migration guide for the deprecated mtbmanifest symbols referenced by a Go binary, or for all of them.",
+		&deprecationsCommand{})
+	_, _ = parser.AddCommand("capability-aliases", "List capability token aliases and where they are needed",
+		"List the renamed capability tokens matching treats as equal, built-in and from the config. With --board, match the code examples and middleware against the board and report which requirements were met only through an alias.",
+		&capabilityAliasesCommand{})
+	_, _ = parser.AddCommand("scan-dir", "Find and check the manifest files in a local directory",
+		"Find the manifest files under a directory, such as a checkout of a manifest repository, by their content; parse each, count them by kind and assemble a tree from them. URLs of a super manifest are resolved to the file whose relative path ends the URL. Without a super manifest, the tree holds every board, app and middleware file. Problems are reported at their file and line; --format github writes them as GitHub Actions annotations and --format sarif as a SARIF log. Fails when a file does not parse. With --watch, keep watching the directory and report the items each edit adds, removes or changes.",
+		&scanDirCommand{})
+}
+
+// applyGlobalOptions applies options that are common to all commands
+func applyGlobalOptions() error {
+	mtbmanifest.EnableXMLUnmarshalVerification(true)
+	mtbmanifest.EnableDeterministicMode(options.Deterministic)
+	mtbmanifest.EnableCaseInsensitiveIDs(options.IgnoreIDCase)
+	if options.CacheStore != "" {
+		store, err := objectStoreFromURL(options.CacheStore)
+		if err != nil {
+			return err
+		}
+		mtbmanifest.SetDefaultCacheStore(store)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	mtbmanifest.SetMiddlewareSupersessions(cfg.Supersessions)
+	mtbmanifest.SetBoardLifecycles(cfg.BoardLifecycles)
+	mtbmanifest.SetCategoryDifficulties(cfg.CategoryDifficulties)
+	if cfg.Scoring != nil {
+		mtbmanifest.SetDefaultScorer(cfg.Scoring)
+	}
+	if err := mtbmanifest.SetCapabilityAliases(cfg.CapabilityAliases); err != nil {
+		return fmt.Errorf("config %s: %v", configPath(), err)
+	}
+	if err := mtbmanifest.SetCapabilityConditions(cfg.CapabilityConditions); err != nil {
+		return fmt.Errorf("config %s: %v", configPath(), err)
+	}
+	rules := []*mtbmanifest.TTLRule{}
+	for _, spec := range append(options.TTL, cfg.TTLRules...) {
+		rule, err := mtbmanifest.ParseTTLRule(spec)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) > 0 {
+		mtbmanifest.SetDefaultTTLPolicy(mtbmanifest.NewTTLRulesPolicy(rules...))
+	}
+
+	cacheOpts := []mtbmanifest.CacheOption{}
+	if len(cfg.Credentials) > 0 {
+		cacheOpts = append(cacheOpts, mtbmanifest.WithAuth(cfg.Credentials.AuthFunc()))
+	}
+	if options.ClientCert != "" || options.ClientKey != "" {
+		cert, err := mtbmanifest.LoadClientCertificate(options.ClientCert, options.ClientKey)
+		if err != nil {
+			return err
+		}
+		cacheOpts = append(cacheOpts, mtbmanifest.WithClientCertificate(cert))
+	}
+	if len(options.CACert) > 0 {
+		pool, err := mtbmanifest.LoadCertPool(options.CACert...)
+		if err != nil {
+			return err
+		}
+		cacheOpts = append(cacheOpts, mtbmanifest.WithRootCAs(pool))
+	}
+	if len(options.Pin) > 0 {
+		if options.Insecure {
+			return fmt.Errorf("--pin cannot be used with --insecure: pins need verified certificates")
+		}
+		pins := mtbmanifest.CertificatePins{}
+		for _, s := range options.Pin {
+			host, pin, err := mtbmanifest.ParseCertificatePin(s)
+			if err != nil {
+				return err
+			}
+			hosts := []string{host}
+			if host == "default" {
+				hosts = mtbmanifest.DefaultPinnedHosts
+			}
+			for _, h := range hosts {
+				pins[h] = append(pins[h], pin)
+			}
+		}
+		cacheOpts = append(cacheOpts, mtbmanifest.WithCertificatePins(pins))
+	}
+	if len(options.VerifyKey) > 0 {
+		keys, err := mtbmanifest.LoadPublicKeys(options.VerifyKey...)
+		if err != nil {
+			return err
+		}
+		cacheOpts = append(cacheOpts, mtbmanifest.WithSignatureVerification(keys...))
+	}
+	if options.MaxStale != "" {
+		maxStale, err := mtbmanifest.ParseTTL(options.MaxStale)
+		if err != nil {
+			return fmt.Errorf("--max-stale: %v", err)
+		}
+		cacheOpts = append(cacheOpts, mtbmanifest.WithMaxStale(maxStale))
+	}
+	if options.Insecure {
+		cacheOpts = append(cacheOpts, mtbmanifest.WithInsecureSkipVerify())
+	}
+	if !options.AllowAnyHost {
+		cacheOpts = append(cacheOpts, mtbmanifest.WithAllowList(allowList(cfg)))
+	}
+	if t := networkTimeouts(); !t.IsZero() {
+		cacheOpts = append(cacheOpts, mtbmanifest.WithTimeouts(t))
+	}
+	if options.MaxBandwidth != "" {
+		rate, err := mtbmanifest.ParseBandwidth(options.MaxBandwidth)
+		if err != nil {
+			return fmt.Errorf("--max-bandwidth: %v", err)
+		}
+		cacheOpts = append(cacheOpts, mtbmanifest.WithMaxBandwidth(rate))
+	}
+	if options.Throttle {
+		options.MaxConcurrent = 1
+		options.MaxPerHost = 1
+		cacheOpts = append(cacheOpts, mtbmanifest.WithNoBackgroundRefresh())
+	}
+	if options.MaxPerHost > 0 {
+		cacheOpts = append(cacheOpts, mtbmanifest.WithMaxPerHost(options.MaxPerHost))
+	}
+	if options.RateLimitWait != 0 {
+		cacheOpts = append(cacheOpts, mtbmanifest.WithRateLimitWait(options.RateLimitWait))
+	}
+	mtbmanifest.SetDefaultCacheOptions(cacheOpts...)
+	return nil
+}
+
+// networkTimeouts returns the limits set by --connect-timeout and --timeout
+func networkTimeouts() mtbmanifest.Timeouts {
+	return mtbmanifest.Timeouts{Connect: options.ConnectTimeout, Request: options.Timeout}
+}
+
+// newGitFetcher returns a git fetcher that keeps to the network timeouts
+func newGitFetcher() *mtbmanifest.GitFetcher {
+	if t := networkTimeouts(); !t.IsZero() {
+		return mtbmanifest.NewGitFetcher(mtbmanifest.WithGitTimeouts(t))
+	}
+	return mtbmanifest.NewGitFetcher()
+}
+
+// newReadmeFetcher returns a README fetcher that keeps to the network timeouts
+func newReadmeFetcher() *mtbmanifest.ReadmeFetcher {
+	if t := networkTimeouts(); !t.IsZero() {
+		return mtbmanifest.NewReadmeFetcher(mtbmanifest.WithReadmeClient(t.Client()))
+	}
+	return mtbmanifest.NewReadmeFetcher()
+}
+
+// maxConcurrent returns n, or less with --max-concurrent; n of 0 or less means the default of 8
+func maxConcurrent(n int) int {
+	if n <= 0 {
+		n = 8
+	}
+	if options.MaxConcurrent > 0 {
+		n = min(n, options.MaxConcurrent)
+	}
+	return n
+}
+
+// linkCheckOptions applies the network limits to the options of a link check
+func linkCheckOptions(opts *mtbmanifest.LinkCheckOptions) *mtbmanifest.LinkCheckOptions {
+	opts.MaxConcurrent = maxConcurrent(opts.MaxConcurrent)
+	if t := networkTimeouts(); !t.IsZero() {
+		opts.Timeout = t.Request
+		opts.Client = t.Client()
+	}
+	return opts
+}
+
+// allowList returns the hosts the CLI may fetch from: the Infineon hosts, the host of --url,
+// the hosts given credentials, those of the configured channels and those of --allow-host and
+// the config
+func allowList(cfg *Config) *mtbmanifest.AllowList {
+	hosts := append(append([]string{}, options.AllowHost...), cfg.AllowHosts...)
+	for host := range cfg.Credentials {
+		hosts = append(hosts, host)
+	}
+	for _, ch := range cfg.Channels {
+		for _, u := range ch.URLs {
+			if u, err := url.Parse(u); err == nil && u.Host != "" {
+				hosts = append(hosts, u.Hostname())
+			}
+		}
+	}
+	al := mtbmanifest.DefaultAllowList.WithHosts(hosts...)
+	if u, err := url.Parse(options.URL); err == nil && u.Host != "" {
+		al = al.WithHosts(u.Hostname())
+		if u.Scheme == "http" {
+			al.Schemes = append(al.Schemes, "http")
+		}
+	}
+	return al
+}
+
+// objectStoreFromURL creates an object store for s3://bucket/prefix. The endpoint, region and
+// credentials come from the standard AWS environment variables.
+func objectStoreFromURL(storeURL string) (*mtbmanifest.ObjectStore, error) {
+	u, err := url.Parse(storeURL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid cache store %q, expected s3://bucket/prefix", storeURL)
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL")
+	region := os.Getenv("AWS_REGION")
+	if endpoint == "" {
+		endpoint = "https://s3.amazonaws.com"
+		if region != "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var client *http.Client
+	if t := networkTimeouts(); !t.IsZero() {
+		client = t.Client()
+	}
+	return mtbmanifest.NewObjectStore(mtbmanifest.ObjectStoreConfig{
+		Endpoint:  endpoint,
+		Bucket:    u.Host,
+		Prefix:    prefix,
+		Region:    region,
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		Client:    client,
+	}), nil
+}
+
+// loadSuperManifest ingests the super manifest tree selected by the global options, or the
+// pinned snapshot if there is one (see 'snapshot use')
+func loadSuperManifest() (mtbmanifest.SuperManifestIF, error) {
+	if options.AsOf != "" {
+		return loadAsOf()
+	}
+	id := options.Snapshot
+	if id == "" && options.URL == "" && options.Ref == "" && len(options.Channel) == 0 {
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		id = cfg.Snapshot
+	}
+	if id == "" {
+		return loadLiveSuperManifest()
+	}
+	timer := NewTimer()
+	superManifest, err := mtbmanifest.NewSnapshotStore("").Load(id)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Loaded snapshot %s in %d ms\n", superManifest.PinnedSnapshot(), timer.ElapsedMs())
+	return superManifest, nil
+}
+
+// loadAsOf loads the stored snapshot of --as-of, of the --url super manifest if given
+func loadAsOf() (mtbmanifest.SuperManifestIF, error) {
+	if options.Snapshot != "" || options.Ref != "" || len(options.Channel) > 0 {
+		return nil, fmt.Errorf("--as-of cannot be combined with --snapshot, --ref or --channel")
+	}
+	asOf, err := parseAsOf(options.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	rootURLs := []string{}
+	if options.URL != "" {
+		rootURLs = append(rootURLs, options.URL)
+	}
+	store := mtbmanifest.NewSnapshotStore("")
+	index, err := store.FindAsOf(asOf, rootURLs...)
+	if err != nil {
+		return nil, err
+	}
+	superManifest, err := store.Load(index.ID())
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Loaded snapshot %s of %s as of %s\n", shortID(index.ID()),
+		index.Created.Local().Format("2006-01-02 15:04"), asOf.Format("2006-01-02 15:04"))
+	return superManifest, nil
+}
+
+// parseAsOf parses a date, meaning the end of that day in local time, or an RFC 3339 time
+func parseAsOf(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --as-of %q, expected a date such as 2024-06-01 or a time such as 2024-06-01T12:00:00Z", s)
+	}
+	return t, nil
+}
+
+// loadLiveSuperManifest ingests the super manifest tree through the manifest cache, ignoring
+// any pinned snapshot. Commands that package the cache contents need this.
+func loadLiveSuperManifest() (mtbmanifest.SuperManifestIF, error) {
+	if len(options.Channel) > 0 {
+		return loadChannels()
+	}
+	urlStr := options.URL
+	if options.Ref != "" {
+		if urlStr == "" {
+			urlStr = mtbmanifest.SuperManifestURL
+		}
+		var err error
+		if urlStr, err = mtbmanifest.PinURLToRef(urlStr, options.Ref); err != nil {
+			return nil, err
+		}
+	}
+	ingestOpts, err := ingestOptions()
+	if err != nil {
+		return nil, err
+	}
+	timer := NewTimer()
+	superManifest, err := mtbmanifest.NewSuperManifestFromURL(urlStr, ingestOpts...)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Finished ingesting super manifest in %d ms\n", timer.ElapsedMs())
+	warnIfPartial(superManifest)
+	logRateLimits(superManifest.IngestReport())
+	return superManifest, nil
+}
+
+// lastIngestReport is the report of the latest ingestion with ingestOptions, which failed ones
+// return no tree to carry
+var lastIngestReport atomic.Pointer[mtbmanifest.IngestReport]
+
+// ingestOptions returns the ingest options of --offline, --manifest-format and --fail-on
+func ingestOptions() ([]mtbmanifest.IngestOption, error) {
+	ingestOpts := []mtbmanifest.IngestOption{mtbmanifest.WithReportFunc(lastIngestReport.Store)}
+	if options.Offline {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithOffline())
+	}
+	if options.MaxConcurrent > 0 {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithConcurrency(options.MaxConcurrent))
+	}
+	if options.Resume {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithResume())
+	}
+	ingestOpts = append(ingestOpts, mtbmanifest.WithContext(shutdownCtx))
+	switch options.Format {
+	case "":
+	case "auto":
+		ingestOpts = append(ingestOpts, mtbmanifest.WithFormatNegotiation(nil))
+	default:
+		format, err := mtbmanifest.ParseManifestFormat(options.Format)
+		if err != nil {
+			return nil, fmt.Errorf("--manifest-format: %v", err)
+		}
+		ingestOpts = append(ingestOpts, mtbmanifest.WithFormat(format))
+	}
+	codes := []mtbmanifest.WarningCode{}
+	for _, s := range options.FailOn {
+		code, err := mtbmanifest.ParseWarningCode(s)
+		if err != nil {
+			return nil, fmt.Errorf("--fail-on: %v", err)
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) > 0 {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithFailOn(codes...))
+	}
+	return ingestOpts, nil
+}
+
+// loadChannels ingests the channels of --channel, merged if there are several
+func loadChannels() (mtbmanifest.SuperManifestIF, error) {
+	if options.Ref != "" {
+		return nil, fmt.Errorf("--ref cannot be combined with --channel")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	ingestOpts, err := ingestOptions()
+	if err != nil {
+		return nil, err
+	}
+	channels, err := cfg.channelSet(ingestOpts...)
+	if err != nil {
+		return nil, err
+	}
+	timer := NewTimer()
+	var superManifest mtbmanifest.SuperManifestIF
+	if len(options.Channel) == 1 {
+		superManifest, err = channels.Load(options.Channel[0])
+	} else {
+		superManifest, err = channels.Union(options.Channel...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Finished ingesting channels %s in %d ms\n", strings.Join(options.Channel, ", "), timer.ElapsedMs())
+	warnIfPartial(superManifest)
+	return superManifest, nil
+}
+
+// warnIfPartial tells the user when results may be missing because manifests failed to load
+func warnIfPartial(sm mtbmanifest.SuperManifestIF) {
+	for _, w := range sm.Completeness().Warnings() {
+		logger.Warningf("%s\n", w)
+	}
+}
+
+// logRateLimits tells what is left of the request quotas of the hosts an ingestion talked to,
+// and warns about the hosts that turned requests away
+func logRateLimits(report *mtbmanifest.IngestReport) {
+	if report == nil {
+		return
+	}
+	for _, h := range report.RateLimits {
+		if h.Limited > 0 {
+			logger.Warningf("%s rate limited %d requests; waited %s for it\n", h.Host, h.Limited, h.Waited.Round(time.Second))
+		}
+		if h.Remaining >= 0 {
+			logger.Infof("%s: %d of %d requests left, renewed at %s\n", h.Host, h.Remaining, h.Limit,
+				h.Reset.Local().Format("15:04"))
+		}
+	}
+}
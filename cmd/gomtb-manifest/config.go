@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// Config is the user's CLI configuration, kept as JSON next to the manifest cache
+type Config struct {
+	// Presets are named filter expressions (see mtbmanifest.ParseFilter), e.g.
+	// "wifi-kits": "chip=CYW43* capability=wifi"
+	Presets map[string]string `json:"presets,omitempty"`
+	// Snapshot is the ID of the stored snapshot ingestion is pinned to (see 'snapshot use')
+	Snapshot string `json:"snapshot,omitempty"`
+	// TTLRules give manifest kinds or URL patterns their own cache TTL (see
+	// mtbmanifest.ParseTTLRule), e.g. ["super=30d", "apps=1d"]. --ttl rules come first.
+	TTLRules []string `json:"ttlRules,omitempty"`
+	// Credentials authenticate to private manifest servers, keyed by host name or glob, e.g.
+	// "manifests.corp.example.com": {"tokenEnv": "MANIFEST_TOKEN"}
+	Credentials mtbmanifest.HostCredentials `json:"credentials,omitempty"`
+	// AllowHosts are fetched from in addition to the Infineon hosts (see --allow-host)
+	AllowHosts []string `json:"allowHosts,omitempty"`
+	// Supersessions name the middleware replacing deprecated middleware, as old ID -> new ID
+	// (see mtbmanifest.SetMiddlewareSupersessions)
+	Supersessions map[string]string `json:"supersessions,omitempty"`
+	// CapabilityAliases map renamed capability tokens to their new names, as old -> new, in
+	// addition to the built-in ones (see mtbmanifest.SetCapabilityAliases)
+	CapabilityAliases map[string]string `json:"capabilityAliases,omitempty"`
+	// CapabilityConditions tell which tools versions and flows capability tokens count with,
+	// e.g. "secure_sockets_v2": {"minToolsVersion": "3.2.0"} (see
+	// mtbmanifest.SetCapabilityConditions)
+	CapabilityConditions map[string]*mtbmanifest.CapabilityCondition `json:"capabilityConditions,omitempty"`
+	// BoardLifecycles are the statuses of boards, active, nrnd or obsolete, by ID; they
+	// override what the manifest says (see mtbmanifest.SetBoardLifecycles)
+	BoardLifecycles map[string]mtbmanifest.Lifecycle `json:"boardLifecycles,omitempty"`
+	// CategoryDifficulties set the difficulty of code example categories, e.g. "Audio":
+	// "advanced" (see mtbmanifest.SetCategoryDifficulties)
+	CategoryDifficulties map[string]mtbmanifest.Difficulty `json:"categoryDifficulties,omitempty"`
+	// Scoring tunes the ranking of search and recommendations: field weights, e.g. "readme":
+	// 0, and category boosts, e.g. "Wi-Fi": 1.5 (see mtbmanifest.WeightedScorer)
+	Scoring *mtbmanifest.WeightedScorer `json:"scoring,omitempty"`
+	// Policy is the file of the organizational policy (see mtbmanifest.Policy) 'policy check'
+	// and 'solutions' apply, unless --policy names another
+	Policy string `json:"policy,omitempty"`
+	// Channels are super manifest channels besides the known ones ("prod", "lts", ...), e.g.
+	// early-access ones, or replace known ones of the same name (see --channel and
+	// mtbmanifest.ChannelSet)
+	Channels []*mtbmanifest.Channel `json:"channels,omitempty"`
+}
+
+// configPath returns the config file selected by --config, or the default location
+func configPath() string {
+	if options.Config != "" {
+		return options.Config
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".modustoolbox", "mtbmcp", "gomtb-manifest.json")
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+func loadConfig() (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(configPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%w config file %s: %v", errParseFile, configPath(), err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config file, creating its directory if needed
+func saveConfig(cfg *Config) error {
+	file := configPath()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// getPreset returns the filter expression saved under name
+func (cfg *Config) getPreset(name string) (string, error) {
+	expr, ok := cfg.Presets[name]
+	if !ok {
+		return "", fmt.Errorf("preset %q %w in %s", name, errNotFound, configPath())
+	}
+	return expr, nil
+}
+
+// loadPolicy reads the policy file, or the one the config names if file is empty. It returns
+// nil when there is neither.
+func loadPolicy(file string) (*mtbmanifest.Policy, error) {
+	if file == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		if file = cfg.Policy; file == "" {
+			return nil, nil
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := mtbmanifest.ReadPolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w policy file %s: %v", errParseFile, file, err)
+	}
+	return policy, nil
+}
+
+// channelSet returns the channels of the config, with "prod" reading --url if given
+func (cfg *Config) channelSet(opts ...mtbmanifest.IngestOption) (*mtbmanifest.ChannelSet, error) {
+	channels := []*mtbmanifest.Channel{}
+	if options.URL != "" {
+		channels = append(channels, &mtbmanifest.Channel{Name: mtbmanifest.DefaultChannel, URLs: []string{options.URL}})
+	}
+	for _, ch := range cfg.Channels {
+		if options.URL == "" || ch.Name != mtbmanifest.DefaultChannel {
+			channels = append(channels, ch)
+		}
+	}
+	return mtbmanifest.NewChannelSet(channels, "", opts...)
+}
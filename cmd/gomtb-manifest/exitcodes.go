@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// Exit codes, so that wrapper scripts can tell failures apart:
+//
+//	0  success
+//	1  any other failure
+//	2  invalid command line
+//	3  network: a manifest could not be downloaded, or is not cached with --offline
+//	4  parse: a manifest, or a file given on the command line, is not valid XML or JSON
+//	5  validation: the command found problems, e.g. 'lock verify', 'policy check',
+//	   'scan-dir', 'doctor' or --fail-on
+//	6  not found: no board, app, middleware item or category of that name
+//	130 interrupted by Ctrl-C or SIGTERM
+//
+// With --json-errors a failure also prints {"error": ..., "kind": ..., "exitCode": ...} as
+// the last line on standard error, in place of the log line.
+const (
+	exitOK         = 0
+	exitFailure    = 1
+	exitUsage      = 2
+	exitNetwork    = 3
+	exitParse      = 4
+	exitValidation = 5
+	exitNotFound   = 6
+
+	exitInterrupted = 130
+)
+
+// exitKinds name the exit codes in --json-errors output
+var exitKinds = map[int]string{
+	exitFailure:    "failure",
+	exitUsage:      "usage",
+	exitNetwork:    "network",
+	exitParse:      "parse",
+	exitValidation: "validation",
+	exitNotFound:   "not-found",
+
+	exitInterrupted: "interrupted",
+}
+
+// errNotFound is wrapped by the errors of names that match nothing, errParseFile by those of
+// files given to the command that do not parse
+var (
+	errNotFound  = errors.New("not found")
+	errParseFile = errors.New("failed to parse")
+)
+
+// validationError is the error of a command that ran and found problems
+type validationError struct {
+	msg string
+}
+
+func (e *validationError) Error() string {
+	return e.msg
+}
+
+// validationErrorf returns a validationError
+func validationErrorf(format string, args ...any) error {
+	return &validationError{msg: fmt.Sprintf(format, args...)}
+}
+
+// exitCodeOf returns the exit code for the error of a command
+func exitCodeOf(err error) int {
+	var validation *validationError
+	var warnings *mtbmanifest.IngestWarningsError
+	var netErr net.Error
+	var xmlErr *xml.SyntaxError
+	var jsonErr *json.SyntaxError
+	var rateLimit *mtbmanifest.RateLimitError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled) && shutdownCtx.Err() != nil:
+		return exitInterrupted
+	case errors.As(err, &validation):
+		return exitValidation
+	case errors.As(err, &warnings):
+		return ingestWarningsExitCode(warnings)
+	case errors.Is(err, errNotFound):
+		return exitNotFound
+	case errors.Is(err, mtbmanifest.ErrParseFailed), errors.Is(err, errParseFile), errors.As(err, &xmlErr),
+		errors.As(err, &jsonErr):
+		return exitParse
+	case errors.Is(err, mtbmanifest.ErrFetchFailed), errors.Is(err, mtbmanifest.ErrOffline), errors.As(err, &netErr),
+		errors.As(err, &rateLimit):
+		return exitNetwork
+	}
+	return exitFailure
+}
+
+// ingestWarningsExitCode returns the exit code for warnings --fail-on did not accept: network
+// or parse when all are of that class, validation otherwise
+func ingestWarningsExitCode(e *mtbmanifest.IngestWarningsError) int {
+	code := -1
+	for _, w := range e.Warnings {
+		c := exitValidation
+		switch w.Code {
+		case mtbmanifest.WarnFetchFailed, mtbmanifest.WarnStaleData, mtbmanifest.WarnRateLimited:
+			c = exitNetwork
+		case mtbmanifest.WarnParseFailed:
+			c = exitParse
+		}
+		if code != -1 && c != code {
+			return exitValidation
+		}
+		code = c
+	}
+	if code == -1 {
+		return exitValidation
+	}
+	return code
+}
+
+// exitWithError reports the error of a command, as a log line or with --json-errors as a JSON
+// object, and exits with its code
+func exitWithError(err error, code int) {
+	if options.JSONErrors {
+		jsonData, _ := json.Marshal(map[string]any{"error": err.Error(), "kind": exitKinds[code], "exitCode": code})
+		fmt.Fprintln(os.Stderr, string(jsonData))
+	} else {
+		logger.Errorf("%v\n", err)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// shutdownCtx is done once the program is interrupted (Ctrl-C, SIGINT) or asked to stop
+// (SIGTERM). Ingestion and other long-running work stop with it, cancelling their requests.
+var shutdownCtx = context.Background()
+
+// handleSignals makes SIGINT and SIGTERM end shutdownCtx. A second signal kills the program
+// as usual, should the shutdown hang.
+func handleSignals() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	shutdownCtx = ctx
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+}
+
+// shutdown stops what the library runs in the background: caches stop refreshing, without
+// leaving an entry half written, and temporary files of killed runs are removed. After an
+// interruption it tells what the ingestion completed.
+func shutdown() {
+	mtbmanifest.CloseCaches()
+	if options.CacheStore == "" {
+		_, _ = mtbmanifest.NewFileStore(mtbmanifest.DefaultCacheDir()).RemoveTempFiles()
+	}
+	if shutdownCtx.Err() == nil {
+		return
+	}
+	report := lastIngestReport.Load()
+	if report == nil {
+		return
+	}
+	if interrupted, _ := mtbmanifest.NewIngestHistory("").Interrupted(report.URL); interrupted == nil {
+		return // the ingestion had ended
+	}
+	failed := len(report.Failed())
+	logger.Warningf("Interrupted after reading %d manifests of %s (%d failed); run again with --resume to fetch only the rest\n",
+		len(report.Manifests)-failed, report.URL, failed)
+}
@@ -0,0 +1,119 @@
+//go:build js && wasm
+
+// Command gomtb-wasm exposes package mtb to JavaScript, for web pages such as a kit selector
+// that fetch the manifest files themselves. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o gomtb.wasm ./cmd/gomtb-wasm
+//
+// and load it with the wasm_exec.js of the Go distribution. It sets globalThis.gomtb to an
+// object with these functions, all returning JSON text, {"error": "..."} on failure:
+//
+//	gomtb.load(files, rootURL)      // files: {url: manifest text}; rootURL: the super manifest
+//	gomtb.query(expr)               // see mtb.Catalog.Query
+//	gomtb.resolve(boardID, version) // see mtb.Catalog.Resolve
+//	gomtb.export()                  // see mtb.Catalog.Export
+//
+// load answers with the numbers of boards, apps and middleware and the manifests missing from
+// files, so that the page can fetch those and load again.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"syscall/js"
+
+	"github.com/haneefdm/gomtb-manifest/mtb"
+)
+
+// catalog is the catalog of the latest successful load
+var catalog *mtb.Catalog
+
+func main() {
+	js.Global().Set("gomtb", js.ValueOf(map[string]any{
+		"load":    js.FuncOf(load),
+		"query":   js.FuncOf(query),
+		"resolve": js.FuncOf(resolve),
+		"export":  js.FuncOf(export),
+	}))
+	select {} // keep the functions alive
+}
+
+// result turns a value or an error into the JSON text handed to JavaScript
+func result(v any, err error) any {
+	if err != nil {
+		v = map[string]string{"error": err.Error()}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return string(data)
+}
+
+// arg returns argument i as a string, or "" if it is missing or not a string
+func arg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
+
+func load(this js.Value, args []js.Value) any {
+	if len(args) == 0 || args[0].Type() != js.TypeObject {
+		return result(nil, fmt.Errorf("load needs an object of manifest files keyed by URL"))
+	}
+	files := map[string][]byte{}
+	keys := js.Global().Get("Object").Call("keys", args[0])
+	for i := 0; i < keys.Length(); i++ {
+		u := keys.Index(i).String()
+		files[u] = []byte(args[0].Get(u).String())
+	}
+	cat, err := mtb.Ingest(arg(args, 1), mtb.WithContents(files))
+	if err != nil {
+		return result(nil, err)
+	}
+	catalog = cat
+	return result(map[string]any{
+		"boards":     len(cat.Boards()),
+		"apps":       len(cat.Apps()),
+		"middleware": len(cat.Middleware()),
+		"failures":   cat.Failures(),
+	}, nil)
+}
+
+// loaded returns the catalog, or an error if nothing was loaded yet
+func loaded() (*mtb.Catalog, error) {
+	if catalog == nil {
+		return nil, fmt.Errorf("no manifests loaded, call gomtb.load first")
+	}
+	return catalog, nil
+}
+
+func query(this js.Value, args []js.Value) any {
+	cat, err := loaded()
+	if err != nil {
+		return result(nil, err)
+	}
+	return result(cat.Query(arg(args, 0)))
+}
+
+func resolve(this js.Value, args []js.Value) any {
+	cat, err := loaded()
+	if err != nil {
+		return result(nil, err)
+	}
+	return result(cat.Resolve(arg(args, 0), arg(args, 1)))
+}
+
+func export(this js.Value, args []js.Value) any {
+	cat, err := loaded()
+	if err != nil {
+		return result(nil, err)
+	}
+	var buf bytes.Buffer
+	if err := cat.Export(&buf); err != nil {
+		return result(nil, err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,200 @@
+package mtb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// Catalog is an ingested set of manifests
+type Catalog struct {
+	sm      mtbmanifest.SuperManifestIF
+	images  *mtbmanifest.BoardImageResolver
+	readmes *mtbmanifest.ReadmeFetcher
+	tr      mtbmanifest.Translator
+	langs   []string
+	policy  *mtbmanifest.Policy
+}
+
+// Boards returns every board, in manifest order
+func (c *Catalog) Boards() []Board {
+	ret := []Board{}
+	for _, b := range c.sm.Boards() {
+		ret = append(ret, newBoard(b))
+	}
+	return ret
+}
+
+// Apps returns every code example, in manifest order
+func (c *Catalog) Apps() []App {
+	ret := []App{}
+	for _, a := range c.sm.Apps() {
+		ret = append(ret, newApp(a))
+	}
+	return ret
+}
+
+// Middleware returns every middleware library, in manifest order
+func (c *Catalog) Middleware() []Middleware {
+	ret := []Middleware{}
+	for _, mw := range c.sm.Middleware() {
+		ret = append(ret, newMiddleware(mw))
+	}
+	return ret
+}
+
+// Board looks up a board by ID
+func (c *Catalog) Board(id string) (Board, bool) {
+	if b, ok := c.sm.GetBoard(id); ok {
+		return newBoard(b), true
+	}
+	return Board{}, false
+}
+
+// App looks up a code example by ID
+func (c *Catalog) App(id string) (App, bool) {
+	if a, ok := c.sm.GetApp(id); ok {
+		return newApp(a), true
+	}
+	return App{}, false
+}
+
+// MiddlewareByID looks up a middleware library by ID
+func (c *Catalog) MiddlewareByID(id string) (Middleware, bool) {
+	if mw, ok := c.sm.GetMiddleware(id); ok {
+		return newMiddleware(mw), true
+	}
+	return Middleware{}, false
+}
+
+// Failures returns the manifest files Ingest could not read; their items are missing
+func (c *Catalog) Failures() []Failure {
+	ret := []Failure{}
+	if report := c.sm.IngestReport(); report != nil {
+		for _, m := range report.Failed() {
+			ret = append(ret, Failure{URL: m.URL, Error: m.Error})
+		}
+	}
+	return ret
+}
+
+// QueryResult holds the items a query matched, in manifest order
+type QueryResult struct {
+	Boards     []Board      `json:"boards"`
+	Apps       []App        `json:"apps"`
+	Middleware []Middleware `json:"middleware"`
+}
+
+// Query returns the items matching a filter expression: space separated key=pattern terms
+// that must all match, with case-insensitive glob patterns. The keys are id, name, category,
+// chip, mcu, radio, capability and keyword, e.g. "chip=CYW43* capability=wifi". An empty
+// expression matches everything.
+func (c *Catalog) Query(expr string) (*QueryResult, error) {
+	f, err := mtbmanifest.ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	ret := &QueryResult{Boards: []Board{}, Apps: []App{}, Middleware: []Middleware{}}
+	for _, b := range c.sm.Boards() {
+		if f.MatchBoard(b) {
+			ret.Boards = append(ret.Boards, newBoard(b))
+		}
+	}
+	for _, a := range c.sm.Apps() {
+		if f.MatchApp(a) {
+			ret.Apps = append(ret.Apps, newApp(a))
+		}
+	}
+	for _, mw := range c.sm.Middleware() {
+		if f.MatchMiddleware(mw) {
+			ret.Middleware = append(ret.Middleware, newMiddleware(mw))
+		}
+	}
+	return ret, nil
+}
+
+// Resolution is what a board version needs and what it can run
+type Resolution struct {
+	Board   string `json:"board"`
+	Version string `json:"version"`
+	// Dependencies are the libraries the board support package needs. It is empty when the
+	// manifests list none for this version.
+	Dependencies []Dependency `json:"dependencies"`
+	// Middleware and Apps are the IDs of the libraries and code examples whose capability
+	// requirements the board meets
+	Middleware []string `json:"middleware"`
+	Apps       []string `json:"apps"`
+	// Violations are the ways the board version and its dependencies break the policy of the
+	// catalog, if it has one (see WithPolicy)
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Resolve works out the dependencies of a board version, given by commit (e.g.
+// "latest-v4.X"), and the middleware and code examples compatible with that version. An empty
+// version means the first, usually latest, listed.
+func (c *Catalog) Resolve(boardID, version string) (*Resolution, error) {
+	b, ok := c.sm.GetBoard(boardID)
+	if !ok {
+		return nil, fmt.Errorf("board %s not found", boardID)
+	}
+	if version == "" {
+		if b.Versions == nil || len(b.Versions.Versions) == 0 {
+			return nil, fmt.Errorf("board %s has no versions", b.ID)
+		}
+		version = b.Versions.Versions[0].Commit
+	} else if !slices.Contains(b.VersionCommits(), version) {
+		return nil, fmt.Errorf("board %s has no version %s", b.ID, version)
+	}
+
+	ret := &Resolution{Board: b.ID, Version: version, Dependencies: []Dependency{},
+		Middleware: []string{}, Apps: []string{}}
+	if c.policy != nil {
+		violations := c.policy.Check(mtbmanifest.ItemKindBoard, b.ID, b.BoardURI, version)
+		if mtbmanifest.HasBlockingViolations(violations) {
+			return nil, fmt.Errorf("board %s version %s is blocked by policy: %s", b.ID, version, violations[0])
+		}
+		ret.Violations = append(ret.Violations, newViolations(violations)...)
+	}
+	if b.Dependencies != nil {
+		for _, v := range b.Dependencies.Versions {
+			if v.Commit != version {
+				continue
+			}
+			for _, dep := range v.Dependees {
+				ret.Dependencies = append(ret.Dependencies, Dependency{ID: dep.ID, Commit: dep.Commit})
+				if c.policy != nil {
+					uri := "" // libraries missing from the middleware manifests have no known host
+					if mw, ok := c.sm.GetMiddleware(dep.ID); ok {
+						uri = mw.URI
+					}
+					ret.Violations = append(ret.Violations, newViolations(
+						c.policy.Check(mtbmanifest.ItemKindMiddleware, dep.ID, uri, dep.Commit))...)
+				}
+			}
+		}
+	}
+	for _, mw := range mtbmanifest.FindMiddlewareForBoardVersion(c.sm, b, version) {
+		if c.allows(mtbmanifest.ItemKindMiddleware, mw.ID, mw.URI, mw.VersionCommits()) {
+			ret.Middleware = append(ret.Middleware, mw.ID)
+		}
+	}
+	for _, a := range mtbmanifest.FindCodeExamplesForBoardVersion(c.sm, b, version) {
+		if c.allows(mtbmanifest.ItemKindApp, a.ID, a.URI, a.VersionCommits()) {
+			ret.Apps = append(ret.Apps, a.ID)
+		}
+	}
+	return ret, nil
+}
+
+// Export writes the whole catalog as an indented JSON object with boards, apps and middleware
+// arrays, using the field names of Board, App and Middleware. Boards carry their picture
+// when the catalog was ingested WithBoardImages, libraries a README snippet when it was
+// ingested WithReadmes, and items their translations when it was ingested WithTranslations. Items a policy blocks are left out (see WithPolicy).
+func (c *Catalog) Export(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.export())
+}
@@ -0,0 +1,177 @@
+package mtb
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"slices"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// allows reports whether the policy of the catalog, if any, allows the newest of the versions
+// of an item
+func (c *Catalog) allows(kind mtbmanifest.ItemKind, id, uri string, commits []string) bool {
+	if c.policy == nil {
+		return true
+	}
+	newest := ""
+	if v := mtbmanifest.NewestVersion(commits); v != nil {
+		newest = v.Raw
+	} else if len(commits) > 0 {
+		newest = commits[0]
+	}
+	return c.policy.Allows(kind, id, uri, newest)
+}
+
+// export returns every item the policy of the catalog allows, with what the catalog adds to
+// exports
+func (c *Catalog) export() *QueryResult {
+	ret := &QueryResult{Boards: c.exportBoards(), Apps: c.Apps(), Middleware: c.exportMiddleware()}
+	if c.policy != nil {
+		ret.Boards = slices.DeleteFunc(ret.Boards, func(b Board) bool {
+			return !c.allows(mtbmanifest.ItemKindBoard, b.ID, b.URI, versionCommits(b.Versions))
+		})
+		ret.Apps = slices.DeleteFunc(ret.Apps, func(a App) bool {
+			return !c.allows(mtbmanifest.ItemKindApp, a.ID, a.URI, versionCommits(a.Versions))
+		})
+		ret.Middleware = slices.DeleteFunc(ret.Middleware, func(mw Middleware) bool {
+			return !c.allows(mtbmanifest.ItemKindMiddleware, mw.ID, mw.URI, versionCommits(mw.Versions))
+		})
+	}
+	for i, b := range ret.Boards {
+		ret.Boards[i].Translations = c.translations(b.ID, b.Name, b.Description, b.Category)
+	}
+	for i, a := range ret.Apps {
+		ret.Apps[i].Translations = c.translations(a.ID, a.Name, a.Description, a.Category)
+	}
+	for i, mw := range ret.Middleware {
+		ret.Middleware[i].Translations = c.translations(mw.ID, mw.Name, mw.Description, mw.Category)
+	}
+	return ret
+}
+
+// translations returns the text of an item in the languages of the catalog that have any, or
+// nil
+func (c *Catalog) translations(id, name, description, category string) map[string]Translation {
+	if c.tr == nil {
+		return nil
+	}
+	var ret map[string]Translation
+	for _, lang := range c.langs {
+		t := mtbmanifest.Translate(c.tr, lang, id, name, description, category)
+		if !t.Translated {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string]Translation)
+		}
+		ret[lang] = Translation{Name: t.Name, Description: t.Description, Category: t.Category}
+	}
+	return ret
+}
+
+// exportBoards returns every board with its picture, if the catalog has an image resolver
+func (c *Catalog) exportBoards() []Board {
+	boards := c.Boards()
+	if c.images == nil {
+		return boards
+	}
+	byID := make(map[string]int, len(boards))
+	for i, b := range boards {
+		byID[b.ID] = i
+	}
+	for _, img := range c.images.Images(c.sm, 0) {
+		if i, ok := byID[img.BoardID]; ok {
+			boards[i].ImageURL = img.URL
+			boards[i].Thumbnail = "data:image/png;base64," + base64.StdEncoding.EncodeToString(img.Thumbnail)
+		}
+	}
+	return boards
+}
+
+// exportMiddleware returns every library with a snippet of its README, if the catalog has a
+// README fetcher
+func (c *Catalog) exportMiddleware() []Middleware {
+	middleware := c.Middleware()
+	if c.readmes == nil {
+		return middleware
+	}
+	byID := make(map[string]int, len(middleware))
+	for i, mw := range middleware {
+		byID[mw.ID] = i
+	}
+	for _, readme := range c.readmes.Readmes(c.sm, 0) {
+		if i, ok := byID[readme.MiddlewareID]; ok {
+			middleware[i].ReadmeURL = readme.URL
+			middleware[i].ReadmeSnippet = readme.Snippet
+		}
+	}
+	return middleware
+}
+
+var htmlTemplate = template.Must(template.New("catalog").Funcs(template.FuncMap{
+	// Thumbnails are data: URLs made by exportBoards, which html/template would otherwise reject
+	"dataURL": func(s string) template.URL { return template.URL(s) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ModusToolbox catalog</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; vertical-align: top; }
+img { max-width: 160px; max-height: 160px; }
+</style>
+</head>
+<body>
+<h1>Boards ({{len .Boards}})</h1>
+<table>
+<tr><th></th><th>ID</th><th>Name</th><th>Family</th><th>Capabilities</th></tr>
+{{- range .Boards}}
+<tr>
+<td>{{if .Thumbnail}}<a href="{{.ImageURL}}"><img src="{{dataURL .Thumbnail}}" alt="{{.Name}}"></a>{{end}}</td>
+<td>{{if .DocumentationURL}}<a href="{{.DocumentationURL}}">{{.ID}}</a>{{else}}{{.ID}}{{end}}</td>
+<td>{{.Name}}{{template "translated" .Translations}}{{if .Summary}}<br><small>{{.Summary}}</small>{{end}}</td>
+<td>{{.Family}}</td>
+<td>{{range $i, $c := .Capabilities}}{{if $i}} {{end}}{{$c}}{{end}}</td>
+</tr>
+{{- end}}
+</table>
+<h1>Code examples ({{len .Apps}})</h1>
+<table>
+<tr><th>ID</th><th>Name</th><th>Category</th><th>Requires</th></tr>
+{{- range .Apps}}
+<tr><td><a href="{{.URI}}">{{.ID}}</a></td><td>{{.Name}}{{template "translated" .Translations}}</td><td>{{.Category}}</td><td>{{.Requires}}</td></tr>
+{{- end}}
+</table>
+<h1>Middleware ({{len .Middleware}})</h1>
+<table>
+<tr><th>ID</th><th>Name</th><th>Category</th><th>Requires</th></tr>
+{{- range .Middleware}}
+<tr><td><a href="{{.URI}}">{{.ID}}</a></td><td>{{.Name}}{{template "translated" .Translations}}{{if .ReadmeSnippet}}<br><small>{{.ReadmeSnippet}} <a href="{{.ReadmeURL}}">README</a></small>{{end}}</td><td>{{.Category}}</td><td>{{.Requires}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+{{define "translated"}}{{range $lang, $t := .}}<br><span lang="{{$lang}}">{{$t.Name}}</span>{{end}}{{end}}
+`))
+
+// ExportHTML writes the whole catalog as a self-contained web page with a table each of
+// boards, code examples and middleware. Board thumbnails are embedded when the catalog was
+// ingested WithBoardImages, README snippets of libraries when it was ingested WithReadmes,
+// and translated names are shown under the English ones when it was ingested
+// WithTranslations.
+func (c *Catalog) ExportHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, c.export())
+}
+
+// versionCommits returns the commits of versions
+func versionCommits(versions []Version) []string {
+	ret := []string{}
+	for _, v := range versions {
+		ret = append(ret, v.Commit)
+	}
+	return ret
+}
@@ -0,0 +1,190 @@
+// Package mtb is the supported API for reading the ModusToolbox manifests: the boards, code
+// examples and middleware Infineon publishes, how they relate, and what a board needs.
+//
+// It is a small, stable layer over package mtbmanifest. mtbmanifest mirrors the XML files,
+// including their quirks (XMLName plumbing, Surprises fields that catch unknown tags, the
+// internal lookup maps), and keeps changing as the manifests and the tools do. This package
+// only exposes plain values and promises to keep them compatible:
+//
+//   - Ingest reads the manifests into a Catalog, IngestBundle reads them from a bundle file
+//   - Catalog.Query selects boards, apps and middleware with a filter expression
+//   - Catalog.Resolve works out what a board version needs and what it can run
+//   - Catalog.Export writes the catalog as JSON, Catalog.ExportHTML as a web page
+//
+// A typical use:
+//
+//	cat, err := mtb.Ingest("", mtb.WithCacheDir(dir))
+//	if err != nil {
+//		return err
+//	}
+//	res, err := cat.Query("chip=CY8C6* capability=wifi")
+//	...
+//
+// Programs that need more than this can use mtbmanifest directly, at the price of following
+// its changes.
+package mtb
+
+import (
+	"io"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// DefaultURL is the super manifest Ingest reads when given an empty URL
+const DefaultURL = mtbmanifest.SuperManifestURL
+
+// Option configures Ingest
+type Option func(*config)
+
+type config struct {
+	cacheDir string
+	ttl      time.Duration
+	offline  bool
+	progress func(done, total int, url string)
+	images   *mtbmanifest.BoardImageResolver
+	readmes  *mtbmanifest.ReadmeFetcher
+	tr       mtbmanifest.Translator
+	langs    []string
+	policy   *mtbmanifest.Policy
+	contents map[string][]byte
+}
+
+// WithCacheDir keeps downloaded manifests in dir instead of the user's cache directory
+func WithCacheDir(dir string) Option {
+	return func(cfg *config) {
+		cfg.cacheDir = dir
+	}
+}
+
+// WithTTL sets how long downloaded manifests are used before they are fetched again
+func WithTTL(ttl time.Duration) Option {
+	return func(cfg *config) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithOffline uses only manifests already in the cache, however old, and never touches the
+// network
+func WithOffline() Option {
+	return func(cfg *config) {
+		cfg.offline = true
+	}
+}
+
+// WithProgress calls fn each time a manifest file has been read: done of total files, the
+// latest being url
+func WithProgress(fn func(done, total int, url string)) Option {
+	return func(cfg *config) {
+		cfg.progress = fn
+	}
+}
+
+// WithBoardImages adds board pictures found by r to exports (see Catalog.Export). Without it
+// exports have none, and nothing but the manifests is downloaded.
+func WithBoardImages(r *mtbmanifest.BoardImageResolver) Option {
+	return func(cfg *config) {
+		cfg.images = r
+	}
+}
+
+// WithReadmes adds a snippet of the README of each library, fetched by r, to exports (see
+// Catalog.Export). Without it exports have none.
+func WithReadmes(r *mtbmanifest.ReadmeFetcher) Option {
+	return func(cfg *config) {
+		cfg.readmes = r
+	}
+}
+
+// WithTranslations adds the translations t has in each of langs, e.g. "ja" and "zh-CN", to
+// the items in exports. The English text stays as it is.
+func WithTranslations(t mtbmanifest.Translator, langs ...string) Option {
+	return func(cfg *config) {
+		cfg.tr, cfg.langs = t, langs
+	}
+}
+
+// WithPolicy applies an organizational policy: Resolve refuses board versions it blocks and
+// leaves out the middleware and code examples it blocks, and exports leave out the items whose
+// newest version it blocks
+func WithPolicy(p *mtbmanifest.Policy) Option {
+	return func(cfg *config) {
+		cfg.policy = p
+	}
+}
+
+// WithContents reads the manifests from contents, the files keyed by URL, instead of
+// downloading them: nothing touches the network or the file system, as in a browser that
+// fetched the files itself. Manifests missing from contents are reported by Catalog.Failures.
+func WithContents(contents map[string][]byte) Option {
+	return func(cfg *config) {
+		cfg.contents = contents
+	}
+}
+
+// Ingest reads the super manifest at url, DefaultURL if empty, and every manifest it lists.
+// Manifests that cannot be read are left out and reported by Catalog.Failures; an error is
+// returned only when the super manifest itself cannot be read.
+func Ingest(url string, opts ...Option) (*Catalog, error) {
+	cfg := newConfig(opts)
+	var sm mtbmanifest.SuperManifestIF
+	var err error
+	if cfg.contents != nil {
+		if url == "" {
+			url = DefaultURL
+		}
+		sm, err = mtbmanifest.NewSuperManifestFromContents(cfg.contents, []string{url}, cfg.ingestOptions()...)
+	} else {
+		sm, err = mtbmanifest.NewSuperManifestFromURL(url, cfg.ingestOptions()...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cfg.catalog(sm), nil
+}
+
+// IngestBundle reads the manifests from a bundle, as written by "gomtb-manifest bundle create"
+// or kept by "gomtb-manifest snapshot", without touching the network or the cache. The super
+// manifests the bundle was made from are merged. Options that choose a cache are ignored.
+func IngestBundle(r io.Reader, opts ...Option) (*Catalog, error) {
+	index, contents, err := mtbmanifest.ReadBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg := newConfig(opts)
+	sm, err := mtbmanifest.NewSuperManifestFromContents(contents, index.RootURLs, cfg.ingestOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.catalog(sm), nil
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// ingestOptions are the mtbmanifest options the config stands for
+func (cfg *config) ingestOptions() []mtbmanifest.IngestOption {
+	ingestOpts := []mtbmanifest.IngestOption{}
+	if cfg.cacheDir != "" {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithCacheDir(cfg.cacheDir))
+	}
+	if cfg.ttl > 0 {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithTTL(cfg.ttl))
+	}
+	if cfg.offline {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithOffline())
+	}
+	if cfg.progress != nil {
+		ingestOpts = append(ingestOpts, mtbmanifest.WithProgress(cfg.progress))
+	}
+	return ingestOpts
+}
+
+func (cfg *config) catalog(sm mtbmanifest.SuperManifestIF) *Catalog {
+	return &Catalog{sm: sm, images: cfg.images, readmes: cfg.readmes, tr: cfg.tr, langs: cfg.langs, policy: cfg.policy}
+}
@@ -0,0 +1,396 @@
+package mtb
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+const (
+	testSuperXML = `<super-manifest version="2.0">
+  <board-manifest-list><board-manifest dependency-url="{{URL}}/deps.xml"><uri>{{URL}}/boards.xml</uri></board-manifest></board-manifest-list>
+  <app-manifest-list><app-manifest><uri>{{URL}}/apps.xml</uri></app-manifest></app-manifest-list>
+  <middleware-manifest-list><middleware-manifest><uri>{{URL}}/middleware.xml</uri></middleware-manifest></middleware-manifest-list>
+</super-manifest>`
+	testBoardsXML = `<boards>
+  <board>
+    <id>CY8CKIT-062S2-43012</id><category>Kit</category>
+    <chips><mcu>CY8C624ABZI-S2D44</mcu><radio>CYW43012C0WKWBG</radio></chips>
+    <name>PSoC 62S2 Wi-Fi BT Pioneer Kit</name>
+    <prov_capabilities>psoc6 hal led wifi</prov_capabilities>
+    <versions>
+      <version flow_version="2.0"><num>Latest 4.X release</num><commit>latest-v4.X</commit></version>
+      <version flow_version="2.0"><num>4.1.0 release</num><commit>release-v4.1.0</commit></version>
+    </versions>
+  </board>
+  <board>
+    <id>CY8CKIT-149</id><category>Kit</category>
+    <chips><mcu>CY8C4147AZI-S475</mcu></chips>
+    <name>PSoC 4100S Plus Prototyping Kit</name>
+    <prov_capabilities>psoc4 hal led capsense</prov_capabilities>
+    <versions><version flow_version="2.0"><num>Latest 3.X release</num><commit>latest-v3.X</commit></version></versions>
+  </board>
+</boards>`
+	testDepsXML = `<dependencies version="2.0">
+  <depender><id>CY8CKIT-062S2-43012</id><versions>
+    <version><commit>latest-v4.X</commit><dependees>
+      <dependee><id>mtb-pdl-cat1</id><commit>latest-v3.X</commit></dependee>
+      <dependee><id>core-lib</id><commit>latest-v1.X</commit></dependee>
+    </dependees></version>
+  </versions></depender>
+</dependencies>`
+	testAppsXML = `<apps version="2.0">
+  <app keywords="led,starter" req_capabilities_v2="hal led">
+    <name>Hello World</name><id>mtb-example-hal-hello-world</id><uri>https://github.com/Infineon/mtb-example-hal-hello-world</uri>
+    <versions><version flow_version="2.0" tools_min_version="3.0.0"><num>Latest 4.X release</num><commit>latest-v4.X</commit></version></versions>
+  </app>
+  <app keywords="wifi,tcp" req_capabilities_v2="hal wifi">
+    <name>Wi-Fi TCP Client</name><id>mtb-example-wifi-tcp-client</id><uri>https://github.com/Infineon/mtb-example-wifi-tcp-client</uri>
+    <versions><version flow_version="2.0"><num>Latest 3.X release</num><commit>latest-v3.X</commit></version></versions>
+  </app>
+</apps>`
+	testMiddlewareXML = `<middleware>
+  <middleware req_capabilities_v2="wifi">
+    <n>Wi-Fi Connection Manager</n><id>wifi-connection-manager</id><uri>https://github.com/Infineon/wifi-connection-manager</uri>
+    <versions><version><num>Latest 3.X release</num><commit>latest-v3.X</commit></version></versions>
+  </middleware>
+</middleware>`
+)
+
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	files := map[string]string{
+		"/super.xml":      testSuperXML,
+		"/boards.xml":     testBoardsXML,
+		"/deps.xml":       testDepsXML,
+		"/apps.xml":       testAppsXML,
+		"/middleware.xml": testMiddlewareXML,
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(strings.ReplaceAll(data, "{{URL}}", srv.URL)))
+	}))
+	t.Cleanup(srv.Close)
+
+	cat, err := Ingest(srv.URL+"/super.xml", WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to ingest: %v", err)
+	}
+	if failures := cat.Failures(); len(failures) != 0 {
+		t.Fatalf("unexpected failures %v", failures)
+	}
+	return cat
+}
+
+func TestCatalog(t *testing.T) {
+	cat := newTestCatalog(t)
+	if len(cat.Boards()) != 2 || len(cat.Apps()) != 2 || len(cat.Middleware()) != 1 {
+		t.Fatalf("unexpected catalog: %d boards, %d apps, %d middleware",
+			len(cat.Boards()), len(cat.Apps()), len(cat.Middleware()))
+	}
+	b, ok := cat.Board("CY8CKIT-062S2-43012")
+	if !ok || len(b.Versions) != 2 || b.Radios[0] != "CYW43012C0WKWBG" || len(b.Capabilities) != 4 {
+		t.Errorf("unexpected board %+v", b)
+	}
+	if a, ok := cat.App("mtb-example-wifi-tcp-client"); !ok || a.Requires != "hal AND wifi" || len(a.Keywords) != 2 {
+		t.Errorf("unexpected app %+v", a)
+	}
+
+	res, err := cat.Query("capability=wifi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Boards) != 1 || len(res.Apps) != 1 || len(res.Middleware) != 1 {
+		t.Errorf("unexpected query result %+v", res)
+	}
+	if _, err := cat.Query("colour=red"); err == nil {
+		t.Error("expected an unknown filter key to fail")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cat := newTestCatalog(t)
+	r, err := cat.Resolve("CY8CKIT-062S2-43012", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "latest-v4.X" || len(r.Dependencies) != 2 || r.Dependencies[0] != (Dependency{"mtb-pdl-cat1", "latest-v3.X"}) {
+		t.Errorf("unexpected dependencies %+v", r)
+	}
+	if len(r.Apps) != 2 || len(r.Middleware) != 1 {
+		t.Errorf("expected both apps and the middleware to be compatible, got %+v", r)
+	}
+
+	r, err = cat.Resolve("CY8CKIT-149", "latest-v3.X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Dependencies) != 0 || len(r.Apps) != 1 || len(r.Middleware) != 0 {
+		t.Errorf("unexpected resolution %+v", r)
+	}
+	if _, err := cat.Resolve("CY8CKIT-149", "latest-v9.X"); err == nil {
+		t.Error("expected an unknown version to fail")
+	}
+	if _, err := cat.Resolve("NO-SUCH-KIT", ""); err == nil {
+		t.Error("expected an unknown board to fail")
+	}
+}
+
+func TestExport(t *testing.T) {
+	cat := newTestCatalog(t)
+	var buf bytes.Buffer
+	if err := cat.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var exported QueryResult
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Boards) != 2 || exported.Middleware[0].ID != "wifi-connection-manager" {
+		t.Errorf("unexpected export %+v", exported)
+	}
+	if p := exported.Middleware[0].Provenance; p == nil || !strings.HasSuffix(p.ManifestURL, "/middleware.xml") ||
+		!strings.HasSuffix(p.SuperManifestURL, "/super.xml") || p.SHA256 == "" {
+		t.Errorf("unexpected provenance %+v", p)
+	}
+	for _, internal := range []string{"Surprises", "LostAttrs", "XMLName"} {
+		if strings.Contains(buf.String(), internal) {
+			t.Errorf("export leaks %s", internal)
+		}
+	}
+}
+
+func TestExportBoardImages(t *testing.T) {
+	cat := newTestCatalog(t)
+	var pic bytes.Buffer
+	if err := png.Encode(&pic, image.NewGray(image.Rect(0, 0, 400, 200))); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(pic.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	cat.images = mtbmanifest.NewBoardImageResolver(
+		mtbmanifest.WithBoardImageMap(map[string]string{"CY8CKIT-149": srv.URL + "/board.png"}),
+		mtbmanifest.WithImageConventions(),
+		mtbmanifest.WithImageStore(mtbmanifest.NewMemoryStore()))
+
+	var buf bytes.Buffer
+	if err := cat.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var exported QueryResult
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range exported.Boards {
+		hasImage := b.ImageURL != "" && strings.HasPrefix(b.Thumbnail, "data:image/png;base64,")
+		if hasImage != (b.ID == "CY8CKIT-149") {
+			t.Errorf("board %s: image %q thumbnail %.30q", b.ID, b.ImageURL, b.Thumbnail)
+		}
+	}
+
+	buf.Reset()
+	if err := cat.ExportHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{`<img src="data:image/png;base64,`, "CY8CKIT-062S2-43012", "wifi-connection-manager"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+}
+
+// toServer sends every request to a test server, whatever its host
+type toServer string
+
+func (srv toServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", strings.TrimPrefix(string(srv), "http://")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestExportReadmes(t *testing.T) {
+	cat := newTestCatalog(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Infineon/wifi-connection-manager/latest-v3.X/README.md" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("# WCM\n\nThe Wi-Fi Connection Manager keeps a <b>connection</b> up across roaming.\n"))
+	}))
+	t.Cleanup(srv.Close)
+	cat.readmes = mtbmanifest.NewReadmeFetcher(mtbmanifest.WithReadmeStore(mtbmanifest.NewMemoryStore()),
+		mtbmanifest.WithReadmeClient(&http.Client{Transport: toServer(srv.URL)}))
+
+	var buf bytes.Buffer
+	if err := cat.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var exported QueryResult
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	const snippet = "The Wi-Fi Connection Manager keeps a connection up across roaming."
+	if mw := exported.Middleware[0]; mw.ReadmeSnippet != snippet || mw.ReadmeURL == "" {
+		t.Errorf("expected the README snippet, got %q at %q", mw.ReadmeSnippet, mw.ReadmeURL)
+	}
+
+	buf.Reset()
+	if err := cat.ExportHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), snippet) {
+		t.Error("page lacks the README snippet")
+	}
+}
+
+func TestExportTranslations(t *testing.T) {
+	cat := newTestCatalog(t)
+	bundle, err := mtbmanifest.ReadTranslationBundle([]byte(`{"ja": {"items": {"wifi-connection-manager": {"name": "Wi-Fi 接続マネージャ"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat.tr, cat.langs = bundle, []string{"ja", "zh"}
+	var buf bytes.Buffer
+	if err := cat.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var exported QueryResult
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	mw := exported.Middleware[0]
+	if mw.Name != "Wi-Fi Connection Manager" {
+		t.Errorf("the English name was replaced")
+	}
+	if len(mw.Translations) != 1 || mw.Translations["ja"].Name != "Wi-Fi 接続マネージャ" {
+		t.Errorf("unexpected translations %+v", mw.Translations)
+	}
+	if exported.Boards[0].Translations != nil {
+		t.Errorf("unexpected board translations %+v", exported.Boards[0].Translations)
+	}
+	buf.Reset()
+	if err := cat.ExportHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<span lang="ja">Wi-Fi 接続マネージャ</span>`) {
+		t.Error("page lacks the translated name")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	cat := newTestCatalog(t)
+	policy, err := mtbmanifest.ReadPolicy([]byte(`{"deniedIds": ["wifi-*", "CY8CKIT-149"], "minVersions": {"mtb-pdl-cat1": "4.0.0"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat.policy = policy
+	r, err := cat.Resolve("CY8CKIT-062S2-43012", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Middleware) != 0 || len(r.Apps) != 2 {
+		t.Errorf("expected the denied middleware to be left out, got %+v", r)
+	}
+	if len(r.Violations) != 1 || r.Violations[0].ID != "mtb-pdl-cat1" || !r.Violations[0].Blocking {
+		t.Errorf("expected the old PDL to be reported, got %+v", r.Violations)
+	}
+	if _, err := cat.Resolve("CY8CKIT-149", ""); err == nil || !strings.Contains(err.Error(), "policy") {
+		t.Errorf("expected the denied board to fail, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := cat.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var exported QueryResult
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Boards) != 1 || len(exported.Middleware) != 0 || len(exported.Apps) != 2 {
+		t.Errorf("unexpected export %d boards, %d apps, %d middleware", len(exported.Boards), len(exported.Apps), len(exported.Middleware))
+	}
+}
+
+// testContents returns the test manifests at example.com
+func testContents() map[string][]byte {
+	const base = "https://example.com"
+	contents := map[string][]byte{}
+	for path, data := range map[string]string{
+		"/super.xml":      testSuperXML,
+		"/boards.xml":     testBoardsXML,
+		"/deps.xml":       testDepsXML,
+		"/apps.xml":       testAppsXML,
+		"/middleware.xml": testMiddlewareXML,
+	} {
+		contents[base+path] = []byte(strings.ReplaceAll(data, "{{URL}}", base))
+	}
+	return contents
+}
+
+func TestIngestContents(t *testing.T) {
+	contents := testContents()
+	delete(contents, "https://example.com/middleware.xml")
+	cat, err := Ingest("https://example.com/super.xml", WithContents(contents))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cat.Boards()) != 2 || len(cat.Apps()) != 2 || len(cat.Middleware()) != 0 {
+		t.Fatalf("unexpected catalog %d boards, %d apps, %d middleware", len(cat.Boards()), len(cat.Apps()),
+			len(cat.Middleware()))
+	}
+	if failures := cat.Failures(); len(failures) != 1 || failures[0].URL != "https://example.com/middleware.xml" {
+		t.Fatalf("expected the missing middleware manifest to be a failure, got %v", failures)
+	}
+}
+
+func TestIngestBundle(t *testing.T) {
+	cat, err := Ingest("https://example.com/super.xml", WithContents(testContents()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := mtbmanifest.NewMemoryStore()
+	for u, data := range testContents() {
+		if err := store.Put(u, data, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache := mtbmanifest.NewManifestCache(mtbmanifest.WithStore(store))
+	defer cache.Close()
+	var buf bytes.Buffer
+	if _, err := mtbmanifest.CreateBundle(&buf, cat.sm, cache); err != nil {
+		t.Fatal(err)
+	}
+
+	fromBundle, err := IngestBundle(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := fromBundle.Resolve("CY8CKIT-062S2-43012", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Dependencies) != 2 || !slices.Contains(res.Apps, "mtb-example-wifi-tcp-client") {
+		t.Fatalf("unexpected resolution %+v", res)
+	}
+	if _, err := IngestBundle(strings.NewReader("not a bundle")); err == nil {
+		t.Fatal("expected an error for a bad bundle")
+	}
+}
@@ -0,0 +1,228 @@
+package mtb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/haneefdm/gomtb-manifest/mtbmanifest"
+)
+
+// The types below are copies of what the manifests say, detached from the tree they came
+// from: changing them changes nothing in the Catalog. New fields may be added; existing ones
+// keep their names and meaning.
+
+// Board is a kit or board support package
+type Board struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Category         string   `json:"category"`
+	Summary          string   `json:"summary,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	URI              string   `json:"uri"`
+	DocumentationURL string   `json:"documentationUrl,omitempty"`
+	MCUs             []string `json:"mcus"`
+	Radios           []string `json:"radios,omitempty"`
+	Capabilities     []string `json:"capabilities"`
+	// Family is the chip family, e.g. CAT1A, or empty when it cannot be told
+	Family   string    `json:"family,omitempty"`
+	Versions []Version `json:"versions"`
+	// ImageURL and Thumbnail, a data: URL of a small PNG, are only set in exports of a
+	// catalog ingested WithBoardImages
+	ImageURL  string `json:"imageUrl,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+	// Translations are only set in exports, see WithTranslations
+	Translations map[string]Translation `json:"translations,omitempty"`
+	// Provenance is where the item was read from, nil if not known
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// App is a code example
+type App struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Category    string   `json:"category,omitempty"`
+	Description string   `json:"description,omitempty"`
+	URI         string   `json:"uri"`
+	Keywords    []string `json:"keywords,omitempty"`
+	// Requires is the capability requirement, e.g. "hal AND (psoc6 OR t2gbe)"
+	Requires string    `json:"requires,omitempty"`
+	Versions []Version `json:"versions"`
+	// Projects are the per-core projects of a multi-core example, empty for single-project ones
+	Projects []Project `json:"projects,omitempty"`
+	// Translations are only set in exports, see WithTranslations
+	Translations map[string]Translation `json:"translations,omitempty"`
+	// Provenance is where the item was read from, nil if not known
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Project is one project of a multi-core code example
+type Project struct {
+	Name     string `json:"name"`
+	Core     string `json:"core,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Requires string `json:"requires,omitempty"`
+}
+
+// Middleware is a library
+type Middleware struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+	URI         string `json:"uri"`
+	Type        string `json:"type,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty"`
+	// Requires is the capability requirement, like App.Requires
+	Requires string    `json:"requires,omitempty"`
+	Versions []Version `json:"versions"`
+	// ReadmeURL and ReadmeSnippet, the first paragraph of the README, are only set in exports
+	// of a catalog ingested WithReadmes
+	ReadmeURL     string `json:"readmeUrl,omitempty"`
+	ReadmeSnippet string `json:"readmeSnippet,omitempty"`
+	// Translations are only set in exports, see WithTranslations
+	Translations map[string]Translation `json:"translations,omitempty"`
+	// Provenance is where the item was read from, nil if not known
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// Version is one release of a board, app or middleware, newest first in Versions
+type Version struct {
+	Num             string `json:"num"`
+	Commit          string `json:"commit"`
+	FlowVersion     string `json:"flowVersion,omitempty"`
+	ToolsMinVersion string `json:"toolsMinVersion,omitempty"`
+	// RefKind is the kind of git ref Commit is: tag, floating, branch or sha
+	RefKind string `json:"refKind,omitempty"`
+}
+
+// Translation is the name, description and category of an item in another language. Fields
+// without a translation hold the English text.
+type Translation struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category,omitempty"`
+}
+
+// Provenance says which manifest an item was read from and when that was downloaded
+type Provenance struct {
+	SuperManifestURL string    `json:"superManifestUrl"`
+	ManifestURL      string    `json:"manifestUrl"`
+	FetchedAt        time.Time `json:"fetchedAt,omitempty"`
+	SHA256           string    `json:"sha256"`
+	Channel          string    `json:"channel,omitempty"`
+}
+
+func newProvenance(p *mtbmanifest.Provenance) *Provenance {
+	if p == nil {
+		return nil
+	}
+	return &Provenance{SuperManifestURL: p.SuperManifestURL, ManifestURL: p.ManifestURL, FetchedAt: p.FetchedAt,
+		SHA256: p.SHA256, Channel: p.Channel}
+}
+
+// Dependency is a library a board version needs, at a given commit (tag or branch)
+type Dependency struct {
+	ID     string `json:"id"`
+	Commit string `json:"commit"`
+}
+
+// Violation is one way an item breaks the policy of a catalog
+type Violation struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Blocking is clear for violations the policy only flags
+	Blocking bool `json:"blocking"`
+}
+
+func newViolations(violations []*mtbmanifest.PolicyViolation) []Violation {
+	ret := []Violation{}
+	for _, v := range violations {
+		ret = append(ret, Violation{ID: v.ID, Version: v.Version, Rule: string(v.Rule), Message: v.Message, Blocking: v.Blocking})
+	}
+	return ret
+}
+
+// Failure is a manifest file that could not be read or parsed during Ingest
+type Failure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+func newBoard(b *mtbmanifest.Board) Board {
+	ret := Board{
+		ID:               b.ID,
+		Name:             b.Name,
+		Category:         b.Category,
+		Summary:          b.Summary,
+		Description:      b.Description,
+		URI:              b.BoardURI,
+		DocumentationURL: b.DocumentationURL,
+		MCUs:             append([]string{}, b.Chips.MCU...),
+		Radios:           append([]string(nil), b.Chips.Radio...),
+		Capabilities:     strings.Fields(b.ProvCapabilities),
+		Family:           b.Family(),
+		Versions:         []Version{},
+		Provenance:       newProvenance(b.Provenance()),
+	}
+	if b.Versions != nil {
+		for _, v := range b.Versions.Versions {
+			ret.Versions = append(ret.Versions, Version{Num: v.Num, Commit: v.Commit, FlowVersion: v.FlowVersion,
+				RefKind: string(v.GetRef().Kind)})
+		}
+	}
+	return ret
+}
+
+func newApp(a *mtbmanifest.App) App {
+	ret := App{
+		ID:          a.ID,
+		Name:        a.Name,
+		Category:    a.Category,
+		Description: a.Description,
+		URI:         a.URI,
+		Keywords:    a.GetKeywords(),
+		Versions:    []Version{},
+		Provenance:  newProvenance(a.Provenance()),
+	}
+	if req := a.GetCapabilities(); len(req.Groups) > 0 {
+		ret.Requires = req.String()
+	}
+	for _, v := range a.Versions.Version {
+		ret.Versions = append(ret.Versions, Version{Num: v.Num, Commit: v.Commit, FlowVersion: v.FlowVersion,
+			ToolsMinVersion: v.ToolsMinVersion, RefKind: string(v.GetRef().Kind)})
+	}
+	for _, p := range a.ProjectsFor(nil) {
+		project := Project{Name: p.Name, Core: p.Core, Path: p.Path}
+		if req := p.GetCapabilities(); len(req.Groups) > 0 {
+			project.Requires = req.String()
+		}
+		ret.Projects = append(ret.Projects, project)
+	}
+	return ret
+}
+
+func newMiddleware(mw *mtbmanifest.MiddlewareItem) Middleware {
+	ret := Middleware{
+		ID:          mw.ID,
+		Name:        mw.Name,
+		Category:    mw.Category,
+		Description: mw.Description,
+		URI:         mw.URI,
+		Type:        mw.Type,
+		Hidden:      strings.EqualFold(mw.Hidden, "true"),
+		Versions:    []Version{},
+		Provenance:  newProvenance(mw.Provenance()),
+	}
+	if req := mw.GetCapabilities(); len(req.Groups) > 0 {
+		ret.Requires = req.String()
+	}
+	if mw.Versions != nil {
+		for _, v := range mw.Versions.Version {
+			ret.Versions = append(ret.Versions, Version{Num: v.Num, Commit: v.Commit, FlowVersion: v.FlowVersion,
+				ToolsMinVersion: v.ToolsMinVersion, RefKind: string(v.GetRef().Kind)})
+		}
+	}
+	return ret
+}
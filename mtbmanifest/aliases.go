@@ -0,0 +1,217 @@
+package mtbmanifest
+
+import (
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Duplicate content
+// ////////////////////////////////////////////////////////////////////////
+
+// Mirrors serve byte-identical manifests under different URLs, and merged super manifests
+// often list the same ones. Parsing each copy costs memory and lists every item several times.
+// During an ingestion each distinct content is parsed once; afterwards, and again when trees
+// are merged, a board, app or middleware manifest whose content equals an earlier one of its
+// kind becomes an alias: AliasOf names the earlier manifest, which alone holds the items.
+// Dependency and capability manifests with identical content share one parsed copy, and merging
+// trees only warns about a URL both of them read when its content differs.
+
+// contentMemo parses each distinct content once during an ingestion; safe for concurrent use
+type contentMemo struct {
+	mu      sync.Mutex
+	entries map[string]*memoEntry
+}
+
+type memoEntry struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+func newContentMemo() *contentMemo {
+	return &contentMemo{entries: make(map[string]*memoEntry)}
+}
+
+// parseOnce returns what parsing data gives, calling parseFunc only for the first data with its
+// content. first reports whether this call parsed it.
+func parseOnce[T any](m *contentMemo, data []byte, err error, parseFunc func([]byte) (*T, error)) (ret *T, first bool, retErr error) {
+	if err != nil {
+		ret, err = UnmarshalManifest(data, err, parseFunc)
+		return ret, true, err
+	}
+	key := hashContent(data)
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &memoEntry{}
+		m.entries[key] = entry
+	}
+	m.mu.Unlock()
+	entry.once.Do(func() {
+		first = true
+		entry.value, entry.err = UnmarshalManifest(data, nil, parseFunc)
+	})
+	if entry.err != nil {
+		return nil, first, entry.err
+	}
+	return entry.value.(*T), first, nil
+}
+
+// aliasable is a board, app or middleware manifest
+type aliasable interface {
+	comparable
+	// hash is the SHA-256 of the content read, "" if not known
+	hash() string
+	loaded() bool
+	// takeContent moves the parsed content of another manifest of the kind to this one
+	takeContent(from any)
+	// aliasTo drops the parsed content, which the given manifest holds, and reports whether
+	// the manifest was not an alias of it yet
+	aliasTo(urlStr string) bool
+	// shallowCopy returns a copy of the manifest, sharing its content
+	shallowCopy() any
+}
+
+// appendManifests appends the manifests of another tree to a list. Those whose content is
+// already in the list are copied, so that aliasing them leaves the other tree alone.
+func appendManifests[M aliasable](list, others []M) []M {
+	seen := make(map[string]bool)
+	for _, m := range list {
+		seen[m.hash()] = true
+	}
+	for _, m := range others {
+		h := m.hash()
+		if h != "" && seen[h] {
+			m = m.shallowCopy().(M)
+		}
+		seen[h] = true
+		list = append(list, m)
+	}
+	return list
+}
+
+// aliasDuplicates turns the manifests whose content equals an earlier one's in the list into
+// aliases of it. The earliest manifest of each content ends up holding the parsed content,
+// wherever it was parsed.
+func aliasDuplicates[M aliasable](list []M, logger LoggerIF, uri func(M) string) {
+	first := make(map[string]M)
+	for _, m := range list {
+		if h := m.hash(); h != "" {
+			if _, ok := first[h]; !ok {
+				first[h] = m
+			}
+		}
+	}
+	for _, m := range list {
+		h := m.hash()
+		primary, ok := first[h]
+		if h == "" || !ok || primary == m {
+			continue
+		}
+		if !primary.loaded() && m.loaded() {
+			primary.takeContent(m)
+		}
+		if m.aliasTo(uri(primary)) {
+			logger.Infof("%s has the same content as %s; its items are listed there\n", uri(m), uri(primary))
+		}
+	}
+}
+
+// aliasDuplicates turns the board, app and middleware manifests of the tree whose content
+// equals an earlier one's into aliases
+func (sm *SuperManifest) aliasDuplicates() {
+	if sm.BoardManifestList != nil {
+		aliasDuplicates(sm.BoardManifestList.BoardManifest, sm.log(), func(m *BoardManifest) string { return m.URI })
+	}
+	if sm.AppManifestList != nil {
+		aliasDuplicates(sm.AppManifestList.AppManifest, sm.log(), func(m *AppManifest) string { return m.URI })
+	}
+	if sm.MiddlewareManifestList != nil {
+		aliasDuplicates(sm.MiddlewareManifestList.MiddlewareManifest, sm.log(),
+			func(m *MiddlewareManifest) string { return m.URI })
+	}
+}
+
+func (bm *BoardManifest) hash() string {
+	if bm.provenance == nil {
+		return ""
+	}
+	return bm.provenance.SHA256
+}
+
+func (bm *BoardManifest) loaded() bool { return bm.Boards != nil }
+
+func (bm *BoardManifest) takeContent(from any) {
+	other := from.(*BoardManifest)
+	bm.Boards, other.Boards = other.Boards, nil
+	for _, b := range bm.Boards.Boards {
+		b.Origin = bm
+	}
+}
+
+func (bm *BoardManifest) aliasTo(urlStr string) bool {
+	changed := bm.AliasOf != urlStr
+	bm.Boards, bm.AliasOf = nil, urlStr
+	return changed
+}
+
+func (bm *BoardManifest) shallowCopy() any {
+	ret := *bm
+	return &ret
+}
+
+func (am *AppManifest) hash() string {
+	if am.provenance == nil {
+		return ""
+	}
+	return am.provenance.SHA256
+}
+
+func (am *AppManifest) loaded() bool { return am.Apps != nil }
+
+func (am *AppManifest) takeContent(from any) {
+	other := from.(*AppManifest)
+	am.Apps, other.Apps = other.Apps, nil
+	for _, a := range am.Apps.App {
+		a.Origin = am
+	}
+}
+
+func (am *AppManifest) aliasTo(urlStr string) bool {
+	changed := am.AliasOf != urlStr
+	am.Apps, am.AliasOf = nil, urlStr
+	return changed
+}
+
+func (am *AppManifest) shallowCopy() any {
+	ret := *am
+	return &ret
+}
+
+func (mm *MiddlewareManifest) hash() string {
+	if mm.provenance == nil {
+		return ""
+	}
+	return mm.provenance.SHA256
+}
+
+func (mm *MiddlewareManifest) loaded() bool { return mm.Middlewares != nil }
+
+func (mm *MiddlewareManifest) takeContent(from any) {
+	other := from.(*MiddlewareManifest)
+	mm.Middlewares, other.Middlewares = other.Middlewares, nil
+	for _, mw := range mm.Middlewares.Middlewares {
+		mw.Origin = mm
+	}
+}
+
+func (mm *MiddlewareManifest) aliasTo(urlStr string) bool {
+	changed := mm.AliasOf != urlStr
+	mm.Middlewares, mm.AliasOf = nil, urlStr
+	return changed
+}
+
+func (mm *MiddlewareManifest) shallowCopy() any {
+	ret := *mm
+	return &ret
+}
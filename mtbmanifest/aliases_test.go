@@ -0,0 +1,88 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+const testAliasDepsXML = `<dependencies version="2.0">
+  <depender><id>CY8CKIT-062S2-43012</id><versions>
+    <version><commit>latest-v4.X</commit><dependees><dependee><id>core-lib</id><commit>latest-v1.X</commit></dependee></dependees></version>
+  </versions></depender>
+</dependencies>`
+
+// testMirrorSuperXML lists the board manifest and its dependencies at a mirror as well
+const testMirrorSuperXML = `<super-manifest version="2.0">
+  <board-manifest-list>
+    <board-manifest dependency-url="https://example.com/deps.xml"><uri>https://example.com/boards.xml</uri></board-manifest>
+    <board-manifest dependency-url="https://mirror.example.com/deps.xml"><uri>https://mirror.example.com/boards.xml</uri></board-manifest>
+  </board-manifest-list>
+  <app-manifest-list><app-manifest><uri>https://example.com/apps.xml</uri></app-manifest></app-manifest-list>
+  <middleware-manifest-list></middleware-manifest-list>
+</super-manifest>`
+
+func TestDuplicateContent(t *testing.T) {
+	contents := map[string][]byte{
+		"https://example.com/super.xml":         []byte(testMirrorSuperXML),
+		"https://mirror.example.com/super.xml":  []byte(testMirrorSuperXML),
+		"https://example.com/boards.xml":        []byte(testBoardsXML),
+		"https://mirror.example.com/boards.xml": []byte(testBoardsXML),
+		"https://example.com/deps.xml":          []byte(testAliasDepsXML),
+		"https://mirror.example.com/deps.xml":   []byte(testAliasDepsXML),
+		"https://example.com/apps.xml":          []byte(testAppsXML),
+	}
+	log := &messageLogger{}
+	sm, err := superManifestFromContents(contents, []string{"https://example.com/super.xml"}, []IngestOption{WithLogger(log)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifests := sm.BoardManifestList.BoardManifest
+	if manifests[0].Boards == nil || manifests[1].Boards != nil || manifests[1].AliasOf != "https://example.com/boards.xml" {
+		t.Fatalf("expected the mirror to be an alias of the first board manifest, got %+v", manifests[1])
+	}
+	if n := len(sm.Boards()); n != 2 {
+		t.Fatalf("expected each board once, got %d", n)
+	}
+	b, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	if b.Origin != manifests[0] || b.Dependencies == nil {
+		t.Fatalf("expected the board to come from the first manifest with its dependencies, got %+v", b)
+	}
+	if sm.dependenciesMap["https://example.com/deps.xml"] != sm.dependenciesMap["https://mirror.example.com/deps.xml"] {
+		t.Error("expected identical dependency manifests to be parsed once")
+	}
+	if c := sm.Completeness(); len(c.Failed) != 0 || len(c.Skipped) != 0 {
+		t.Errorf("expected an alias to count as loaded, got %+v", c)
+	}
+
+	// Merging the mirror's tree adds no items and no warnings about the URLs read twice
+	mirror, err := superManifestFromContents(contents, []string{"https://mirror.example.com/super.xml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.AddSuperManifest(mirror)
+	if n := len(sm.Boards()); n != 2 {
+		t.Fatalf("expected each board once after merging, got %d", n)
+	}
+	if n := len(sm.Apps()); n != 3 {
+		t.Fatalf("expected each app once after merging, got %d", n)
+	}
+	if n := log.count("duplicate"); n != 0 {
+		t.Errorf("expected no duplicate URL warnings, got %v", log.messages)
+	}
+	if mirror.BoardManifestList.BoardManifest[0].Boards == nil || len(mirror.Boards()) != 2 {
+		t.Error("expected merging to leave the merged tree alone")
+	}
+
+	// The same URL with different content is still reported
+	contents["https://example.com/deps.xml"] = []byte(`<dependencies version="2.0"></dependencies>`)
+	changed, err := superManifestFromContents(contents, []string{"https://example.com/super.xml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.AddSuperManifest(changed)
+	if n := log.count("duplicate-url: https://example.com/deps.xml"); n != 1 {
+		t.Errorf("expected a warning about the changed dependencies, got %v", log.messages)
+	}
+	if w := sm.Warnings(WarnDuplicateURL); len(w) != 1 {
+		t.Errorf("expected the warning to be recorded, got %v", w)
+	}
+}
@@ -0,0 +1,114 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Fetch allowlists
+// ////////////////////////////////////////////////////////////////////////
+
+// Every URL the cache fetches comes from a manifest, so a tampered manifest entry could make
+// the tool download, and cache under a trusted name, whatever it points at. With WithAllowList
+// the cache contacts only listed schemes and hosts, including for every redirect, and keeps
+// only responses of the listed content types.
+
+// ErrNotAllowed is returned for URLs and responses an AllowList refuses
+var ErrNotAllowed = errors.New("not allowed")
+
+// AllowList is what the cache may fetch
+type AllowList struct {
+	// Schemes are the URL schemes that may be fetched, e.g. "https"
+	Schemes []string `json:"schemes,omitempty"`
+	// Hosts are host names or globs such as "*.infineon.com"
+	Hosts []string `json:"hosts,omitempty"`
+	// ContentTypes are the media types a response may have; empty allows any
+	ContentTypes []string `json:"contentTypes,omitempty"`
+}
+
+// DefaultAllowList covers the hosts the Infineon manifests are served from
+var DefaultAllowList = AllowList{
+	Schemes: []string{"https"},
+	Hosts:   []string{"github.com", "raw.githubusercontent.com", "infineon.com", "*.infineon.com"},
+	ContentTypes: []string{"text/plain", "text/xml", "application/xml", "application/json",
+		"application/octet-stream"},
+}
+
+// WithHosts returns a copy of the allowlist that also allows hosts
+func (al AllowList) WithHosts(hosts ...string) *AllowList {
+	al.Schemes = append([]string{}, al.Schemes...)
+	al.Hosts = append(append([]string{}, al.Hosts...), hosts...)
+	return &al
+}
+
+// CheckURL returns an error matching ErrNotAllowed unless the URL may be fetched
+func (al *AllowList) CheckURL(urlStr string) error {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return err
+	}
+	if !containsFold(al.Schemes, u.Scheme) {
+		return fmt.Errorf("scheme of %s: %w", urlStr, ErrNotAllowed)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range al.Hosts {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s: %w", host, ErrNotAllowed)
+}
+
+// checkContentType returns an error matching ErrNotAllowed unless the response may be kept
+func (al *AllowList) checkContentType(resp *http.Response) error {
+	if len(al.ContentTypes) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !containsFold(al.ContentTypes, mediaType) {
+		return fmt.Errorf("content type '%s' of %s: %w", resp.Header.Get("Content-Type"),
+			resp.Request.URL, ErrNotAllowed)
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAllowList restricts what the cache fetches. A nil allowlist means DefaultAllowList.
+func WithAllowList(al *AllowList) CacheOption {
+	return func(c *ManifestCache) {
+		if al == nil {
+			al = &DefaultAllowList
+		}
+		c.allowList = al
+	}
+}
+
+// checkRedirect refuses redirects that leave the allowlist
+func (al *AllowList) checkRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if err := al.CheckURL(req.URL.String()); err != nil {
+			return fmt.Errorf("redirect to %w", err)
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllowList(t *testing.T) {
+	evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<evil/>"))
+	}))
+	defer evil.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect.xml":
+			http.Redirect(w, r, evil.URL+"/x.xml", http.StatusFound)
+		case "/page.xml":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html/>"))
+		default:
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			_, _ = w.Write([]byte("<super-manifest/>"))
+		}
+	}))
+	defer server.Close()
+	// Both servers listen on 127.0.0.1; tell them apart by name
+	local := "http://localhost" + server.URL[len("http://127.0.0.1"):]
+
+	al := DefaultAllowList.WithHosts("localhost")
+	al.Schemes = append(al.Schemes, "http")
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour), WithAllowList(al))
+	defer cache.Close()
+
+	if _, err := cache.Get(local + "/super.xml"); err != nil {
+		t.Errorf("expected an allowed host to be fetched: %v", err)
+	}
+	if _, err := cache.Get(server.URL + "/super.xml"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected a host off the list to be refused, got %v", err)
+	}
+	if _, err := cache.Get(local + "/redirect.xml"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected a redirect off the list to be refused, got %v", err)
+	}
+	if _, err := cache.Get(local + "/page.xml"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected an HTML response to be refused, got %v", err)
+	}
+
+	for urlStr, want := range map[string]bool{
+		SuperManifestURL: true,
+		"https://raw.githubusercontent.com/Infineon/mtb-bsp-manifest/v2.X/x.xml": true,
+		"https://downloads.infineon.com/x.xml":                                   true,
+		"http://github.com/Infineon/x.xml":                                       false,
+		"https://github.com.evil.example/x.xml":                                  false,
+	} {
+		if err := DefaultAllowList.CheckURL(urlStr); (err == nil) != want {
+			t.Errorf("CheckURL(%s) = %v, want allowed %v", urlStr, err, want)
+		}
+	}
+	if len(DefaultAllowList.Hosts) != 4 {
+		t.Error("WithHosts must not modify the list it copies")
+	}
+}
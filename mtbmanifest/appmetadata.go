@@ -0,0 +1,290 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// App difficulty and size
+// ////////////////////////////////////////////////////////////////////////
+
+// Someone new to ModusToolbox wants the blinky and hello world examples first, not a
+// dual-core machine learning demo, yet the manifest says nothing about how hard or how large an
+// example is. Difficulty is inferred from the category (see WithCategoryDifficulties and
+// SetCategoryDifficulties), refined by keywords and the number of core projects. Size is
+// measured by cloning the example (see MeasureAppSize), or else estimated from the same hints.
+// Both are in an app's JSON, listings can sort by difficulty, and search ranks beginner
+// examples above advanced ones.
+
+// Difficulty is how much experience an example expects
+type Difficulty string
+
+const (
+	DifficultyBeginner     Difficulty = "beginner"
+	DifficultyIntermediate Difficulty = "intermediate"
+	DifficultyAdvanced     Difficulty = "advanced"
+)
+
+// rank orders difficulties from beginner up
+func (d Difficulty) rank() int {
+	switch d {
+	case DifficultyBeginner:
+		return 0
+	case DifficultyAdvanced:
+		return 2
+	}
+	return 1
+}
+
+// ParseDifficulty parses the name of a difficulty
+func ParseDifficulty(s string) (Difficulty, error) {
+	switch d := Difficulty(strings.ToLower(strings.TrimSpace(s))); d {
+	case DifficultyBeginner, DifficultyIntermediate, DifficultyAdvanced:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown difficulty %q, expected beginner, intermediate or advanced", s)
+}
+
+// UnmarshalJSON accepts what ParseDifficulty does
+func (d *Difficulty) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDifficulty(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// defaultCategoryDifficulties are the difficulties of the categories of the code example
+// manifest, by lower case category. Categories not listed are intermediate.
+var defaultCategoryDifficulties = map[string]Difficulty{
+	"getting started":  DifficultyBeginner,
+	"peripherals":      DifficultyBeginner,
+	"device firmware":  DifficultyIntermediate,
+	"sensing":          DifficultyIntermediate,
+	"wi-fi":            DifficultyIntermediate,
+	"bluetooth":        DifficultyIntermediate,
+	"usb":              DifficultyIntermediate,
+	"graphics":         DifficultyIntermediate,
+	"audio":            DifficultyIntermediate,
+	"low power":        DifficultyAdvanced,
+	"security":         DifficultyAdvanced,
+	"machine learning": DifficultyAdvanced,
+	"motor control":    DifficultyAdvanced,
+	"multi-core":       DifficultyAdvanced,
+}
+
+var (
+	categoryDifficultiesMu sync.RWMutex
+	// categoryDifficulties are the defaults with those of SetCategoryDifficulties on top
+	categoryDifficulties = defaultCategoryDifficulties
+)
+
+// SetCategoryDifficulties sets the difficulty of app categories, case-insensitively, on top of
+// the built-in ones. Pass nil for the built-in ones only. Trees ingested
+// WithCategoryDifficulties use their own instead.
+func SetCategoryDifficulties(difficulties map[string]Difficulty) {
+	m := difficultyTable(difficulties)
+	categoryDifficultiesMu.Lock()
+	defer categoryDifficultiesMu.Unlock()
+	categoryDifficulties = m
+}
+
+// WithCategoryDifficulties sets the difficulty of the app categories of this tree,
+// case-insensitively, on top of the built-in ones, in place of those of
+// SetCategoryDifficulties
+func WithCategoryDifficulties(difficulties map[string]Difficulty) IngestOption {
+	m := difficultyTable(difficulties)
+	return func(cfg *ingestConfig) {
+		cfg.categoryDifficulties = m
+	}
+}
+
+// difficultyTable returns the built-in difficulties with difficulties on top, by lower case
+// category
+func difficultyTable(difficulties map[string]Difficulty) map[string]Difficulty {
+	m := make(map[string]Difficulty, len(defaultCategoryDifficulties)+len(difficulties))
+	for category, d := range defaultCategoryDifficulties {
+		m[category] = d
+	}
+	for category, d := range difficulties {
+		m[strings.ToLower(category)] = d
+	}
+	return m
+}
+
+// difficultyTableOf returns the difficulties that apply to an app: those of the ingestion of
+// its manifest, or else those of SetCategoryDifficulties
+func difficultyTableOf(a *App) map[string]Difficulty {
+	if a.Origin != nil && a.Origin.ingestCfg != nil && a.Origin.ingestCfg.categoryDifficulties != nil {
+		return a.Origin.ingestCfg.categoryDifficulties
+	}
+	categoryDifficultiesMu.RLock()
+	defer categoryDifficultiesMu.RUnlock()
+	return categoryDifficulties
+}
+
+// Keywords that say outright who an example is for
+var (
+	beginnerKeywords = []string{"getting started", "starter", "hello world", "beginner", "blinky"}
+	advancedKeywords = []string{"advanced", "expert"}
+)
+
+// Difficulty returns the inferred difficulty of the app: that of its category, unless its
+// keywords say otherwise; an app of several core projects is at least intermediate
+func (a *App) Difficulty() Difficulty {
+	d, ok := difficultyTableOf(a)[strings.ToLower(strings.TrimSpace(a.Category))]
+	if !ok {
+		d = DifficultyIntermediate
+	}
+	for _, keyword := range a.GetKeywords() {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		switch {
+		case slices.Contains(beginnerKeywords, keyword):
+			d = DifficultyBeginner
+		case slices.Contains(advancedKeywords, keyword):
+			return DifficultyAdvanced
+		}
+	}
+	if len(a.ProjectsFor(nil)) > 1 && d == DifficultyBeginner {
+		d = DifficultyIntermediate
+	}
+	return d
+}
+
+// SizeClass is a rough size of an example
+type SizeClass string
+
+const (
+	SizeSmall  SizeClass = "small"
+	SizeMedium SizeClass = "medium"
+	SizeLarge  SizeClass = "large"
+)
+
+// Sizes of the checked-out sources, without .git, above which an example is medium or large
+const (
+	sizeMediumBytes = 1 << 20
+	sizeLargeBytes  = 10 << 20
+)
+
+// AppSize is the size of an example, measured or estimated
+type AppSize struct {
+	Class SizeClass `json:"class"`
+	// Files and Bytes are those of the checked-out sources, when measured
+	Files int   `json:"files,omitempty"`
+	Bytes int64 `json:"bytes,omitempty"`
+	// Measured is set when the sources were cloned and counted
+	Measured bool `json:"measured"`
+}
+
+// sizeClassOf returns the class of sources of the given size
+func sizeClassOf(bytes int64) SizeClass {
+	switch {
+	case bytes >= sizeLargeBytes:
+		return SizeLarge
+	case bytes >= sizeMediumBytes:
+		return SizeMedium
+	}
+	return SizeSmall
+}
+
+var (
+	appSizesMu sync.RWMutex
+	// appSizes are the measured sizes, by idKey of app ID
+	appSizes = map[string]*AppSize{}
+)
+
+// SetAppSize records the measured size of an app, e.g. one saved from an earlier
+// MeasureAppSize; nil forgets it
+func SetAppSize(id string, size *AppSize) {
+	appSizesMu.Lock()
+	defer appSizesMu.Unlock()
+	if size == nil {
+		delete(appSizes, idKey(id))
+	} else {
+		appSizes[idKey(id)] = size
+	}
+}
+
+// Size returns the measured size of the app, or else an estimate: large for several core
+// projects, small for a beginner example, medium otherwise
+func (a *App) Size() *AppSize {
+	appSizesMu.RLock()
+	size, ok := appSizes[idKey(a.ID)]
+	appSizesMu.RUnlock()
+	if ok {
+		return size
+	}
+	switch {
+	case len(a.ProjectsFor(nil)) > 1:
+		return &AppSize{Class: SizeLarge}
+	case a.Difficulty() == DifficultyBeginner:
+		return &AppSize{Class: SizeSmall}
+	}
+	return &AppSize{Class: SizeMedium}
+}
+
+// MeasureAppSize clones a version of an app (the newest if commit is empty) into a temporary
+// directory, counts its files and bytes, and records the result for Size. A nil fetcher means
+// NewGitFetcher().
+func MeasureAppSize(sm SuperManifestIF, g *GitFetcher, id, commit string) (*AppSize, error) {
+	app, ok := sm.GetApp(id)
+	if !ok {
+		return nil, fmt.Errorf("app %s not found", id)
+	}
+	if commit == "" {
+		if commit = newestCommit(app.VersionCommits()); commit == "" {
+			return nil, fmt.Errorf("app %s lists no versions", id)
+		}
+	}
+	src, err := AssetSource(sm, app.ID, commit)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		g = NewGitFetcher()
+	}
+	dir, err := os.MkdirTemp("", "mtb-app-size-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	if _, err := g.Fetch(src, dir); err != nil {
+		return nil, err
+	}
+	size := &AppSize{Measured: true}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size.Files++
+			size.Bytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	size.Class = sizeClassOf(size.Bytes)
+	SetAppSize(app.ID, size)
+	return size, nil
+}
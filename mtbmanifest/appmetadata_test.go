@@ -0,0 +1,91 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAppDifficulty(t *testing.T) {
+	defer SetCategoryDifficulties(nil)
+	sm := newTestSuperManifest(t)
+	hello, _ := sm.GetApp("mtb-example-hal-hello-world")
+	tcp, _ := sm.GetApp("mtb-example-wifi-tcp-client")
+	if hello.Difficulty() != DifficultyBeginner || tcp.Difficulty() != DifficultyIntermediate {
+		t.Fatalf("unexpected difficulties %s, %s", hello.Difficulty(), tcp.Difficulty())
+	}
+	SetCategoryDifficulties(map[string]Difficulty{"WI-FI": DifficultyAdvanced})
+	if tcp.Difficulty() != DifficultyAdvanced {
+		t.Errorf("expected the configured category difficulty, got %s", tcp.Difficulty())
+	}
+	if (&App{Category: "Security", Keywords: "starter"}).Difficulty() != DifficultyBeginner {
+		t.Error("expected a beginner keyword to win over the category")
+	}
+
+	page, err := sm.ListApps(&ListOptions{SortBy: SortByDifficulty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Items[0] != hello || page.Items[len(page.Items)-1] != tcp {
+		t.Errorf("expected beginner examples first and advanced ones last, got %s ... %s",
+			page.Items[0].ID, page.Items[len(page.Items)-1].ID)
+	}
+	filter, _ := ParseFilter("difficulty=beginner")
+	if page, _ := sm.ListApps(&ListOptions{Filter: filter}); len(page.Items) != 1 || page.Items[0] != hello {
+		t.Errorf("unexpected beginner apps %+v", page.Items)
+	}
+
+	// Of equally good hits the beginner example comes first
+	results := NewSearchIndex(sm).Search("mtb", &SearchOptions{Kinds: []ItemKind{ItemKindApp}})
+	if len(results) < 2 || results[0].ID != hello.ID {
+		t.Errorf("expected the beginner example first, got %+v", results)
+	}
+
+	data, err := json.Marshal(hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"difficulty":"beginner","size":{"class":"small","measured":false}`) {
+		t.Errorf("expected the difficulty and size in the JSON, got %s", data)
+	}
+}
+
+func TestCategoryDifficultiesPerTree(t *testing.T) {
+	defer SetCategoryDifficulties(nil)
+	srv := newTestTreeServer(t)
+	ingest := func(opts ...IngestOption) *App {
+		sm, err := NewSuperManifestFromURL(srv.URL+"/super.xml",
+			append(opts, WithCacheDir(t.TempDir()), WithIngestHistory(nil))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, _ := sm.GetApp("mtb-example-wifi-tcp-client")
+		return a
+	}
+	prod := ingest()
+	training := ingest(WithCategoryDifficulties(map[string]Difficulty{"Wi-Fi": DifficultyBeginner}))
+	SetCategoryDifficulties(map[string]Difficulty{"wi-fi": DifficultyAdvanced})
+	if d := prod.Difficulty(); d != DifficultyAdvanced {
+		t.Errorf("expected the process-wide difficulties for the production tree, got %s", d)
+	}
+	if d := training.Difficulty(); d != DifficultyBeginner {
+		t.Errorf("expected the difficulties of the training tree, got %s", d)
+	}
+}
+
+func TestAppSize(t *testing.T) {
+	defer SetAppSize("mtb-example-hal-hello-world", nil)
+	sm := newTestSuperManifest(t)
+	hello, _ := sm.GetApp("mtb-example-hal-hello-world")
+	SetAppSize(hello.ID, &AppSize{Class: sizeClassOf(12 << 20), Files: 300, Bytes: 12 << 20, Measured: true})
+	if size := hello.Size(); size.Class != SizeLarge || !size.Measured {
+		t.Errorf("expected the measured size, got %+v", size)
+	}
+	if size := (&App{ID: "x", Projects: &AppProjects{Projects: []*AppProject{{}, {}}}}).Size(); size.Class != SizeLarge || size.Measured {
+		t.Errorf("expected a multi-core app to be estimated large, got %+v", size)
+	}
+	var d Difficulty
+	if err := json.Unmarshal([]byte(`"Expert"`), &d); err == nil {
+		t.Error("expected an unknown difficulty to be rejected")
+	}
+}
@@ -0,0 +1,174 @@
+package mtbmanifest
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Authentication for private manifest hosting
+// ////////////////////////////////////////////////////////////////////////
+
+// Manifests served from internal servers usually need credentials. An AuthFunc adds them to
+// every request the cache sends; HostCredentials picks them per host so one configuration can
+// cover several servers. Client certificates (mTLS) are set on the transport instead, with
+// WithClientCertificate. Go's HTTP client drops the Authorization header when a redirect leaves
+// the original host, so credentials do not leak to other servers.
+
+// AuthFunc adds credentials to an outgoing request
+type AuthFunc func(req *http.Request)
+
+// BearerToken returns an AuthFunc that sends "Authorization: Bearer <token>"
+func BearerToken(token string) AuthFunc {
+	return func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// BasicAuth returns an AuthFunc that sends HTTP basic authentication
+func BasicAuth(username, password string) AuthFunc {
+	return func(req *http.Request) {
+		req.SetBasicAuth(username, password)
+	}
+}
+
+// Credential is how to authenticate to one host. Secrets can be given directly or named by an
+// environment variable so they stay out of config files.
+type Credential struct {
+	Token       string `json:"token,omitempty"`
+	TokenEnv    string `json:"tokenEnv,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+}
+
+// authFunc returns the AuthFunc for the credential, or nil if it holds nothing usable
+func (c *Credential) authFunc() AuthFunc {
+	token := c.Token
+	if c.TokenEnv != "" {
+		token = os.Getenv(c.TokenEnv)
+	}
+	if token != "" {
+		return BearerToken(token)
+	}
+	password := c.Password
+	if c.PasswordEnv != "" {
+		password = os.Getenv(c.PasswordEnv)
+	}
+	if c.Username != "" {
+		return BasicAuth(c.Username, password)
+	}
+	return nil
+}
+
+// HostCredentials maps host names to credentials. A key may be a glob such as
+// "*.corp.example.com"; an exact host name wins over a glob.
+type HostCredentials map[string]*Credential
+
+// AuthFunc returns an AuthFunc that adds the credential of the request's host, if any
+func (hc HostCredentials) AuthFunc() AuthFunc {
+	return func(req *http.Request) {
+		if cred := hc.lookup(req.URL.Hostname()); cred != nil {
+			if fn := cred.authFunc(); fn != nil {
+				fn(req)
+			}
+		}
+	}
+}
+
+func (hc HostCredentials) lookup(host string) *Credential {
+	host = strings.ToLower(host)
+	if cred, ok := hc[host]; ok {
+		return cred
+	}
+	patterns := make([]string, 0, len(hc))
+	for pattern := range hc {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return hc[pattern]
+		}
+	}
+	return nil
+}
+
+// WithAuth adds credentials to every request the cache sends
+func WithAuth(fn AuthFunc) CacheOption {
+	return func(c *ManifestCache) {
+		c.auth = fn
+	}
+}
+
+// WithHTTPClient sets the client the cache fetches with. The default is http.DefaultClient, or a
+// client with its own transport when TLS options such as WithClientCertificate or WithTimeouts
+// are given; those options do not apply to a client set here.
+func WithHTTPClient(client *http.Client) CacheOption {
+	return func(c *ManifestCache) {
+		c.client = client
+	}
+}
+
+// WithClientCertificate makes the cache present a client certificate (mTLS)
+func WithClientCertificate(cert tls.Certificate) CacheOption {
+	return func(c *ManifestCache) {
+		c.tlsConfig().Certificates = append(c.tlsConfig().Certificates, cert)
+	}
+}
+
+// LoadClientCertificate reads a PEM certificate and key for WithClientCertificate
+func LoadClientCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	return cert, nil
+}
+
+// defaultCacheOptions are applied to every cache the library creates for itself
+var defaultCacheOptions []CacheOption
+
+// SetDefaultCacheOptions sets options applied to default caches, e.g. WithAuth for a private
+// manifest server. Replaces any previously set options.
+func SetDefaultCacheOptions(opts ...CacheOption) {
+	defaultCacheOptions = opts
+}
+
+// tlsConfig returns the TLS settings of the cache's transport, creating them on first use
+func (c *ManifestCache) tlsConfig() *tls.Config {
+	if c.tls == nil {
+		c.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return c.tls
+}
+
+// httpClient returns the client to fetch with
+func (c *ManifestCache) httpClient() *http.Client {
+	c.clientOnce.Do(func() {
+		switch {
+		case c.client != nil:
+		case c.tls == nil && c.timeouts.IsZero():
+			c.client = http.DefaultClient
+		default:
+			if c.tls != nil && c.tls.InsecureSkipVerify {
+				c.log().Warningf("Warning: TLS certificate verification is disabled for manifest downloads\n")
+			}
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = c.tls
+			c.client = &http.Client{}
+			c.timeouts.apply(c.client, transport)
+		}
+		if c.allowList != nil {
+			client := *c.client
+			client.CheckRedirect = c.allowList.checkRedirect(client.CheckRedirect)
+			c.client = &client
+		}
+	})
+	return c.client
+}
@@ -0,0 +1,51 @@
+package mtbmanifest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("<super-manifest/>"))
+	}))
+	defer server.Close()
+
+	anonymous := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer anonymous.Close()
+	if _, err := anonymous.Get(server.URL + "/super.xml"); err == nil {
+		t.Error("expected an anonymous fetch to be refused")
+	}
+
+	t.Setenv("MTB_TEST_TOKEN", "s3cret")
+	creds := HostCredentials{
+		"127.0.0.1":           {TokenEnv: "MTB_TEST_TOKEN"},
+		"*.corp.example.com":  {Username: "alice", Password: "pw"},
+		"other.example.com":   {Token: "unused"},
+		"empty.example.com":   {},
+		"*.other.example.com": {Token: "glob"},
+	}
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour), WithAuth(creds.AuthFunc()))
+	defer cache.Close()
+	data, err := cache.Get(server.URL + "/super.xml")
+	if err != nil || string(data) != "<super-manifest/>" {
+		t.Errorf("expected an authenticated fetch, got %q, %v", data, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://mfst.corp.example.com/x.xml", nil)
+	creds.AuthFunc()(req)
+	if user, pw, ok := req.BasicAuth(); !ok || user != "alice" || pw != "pw" {
+		t.Errorf("expected basic auth from the glob entry, got %q %q %v", user, pw, ok)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "https://empty.example.com/x.xml", nil)
+	creds.AuthFunc()(req)
+	if req.Header.Get("Authorization") != "" {
+		t.Error("an empty credential should add nothing")
+	}
+}
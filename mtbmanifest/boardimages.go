@@ -0,0 +1,292 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // decoders for image.Decode
+	_ "image/jpeg" // decoders for image.Decode
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Board images
+// ////////////////////////////////////////////////////////////////////////
+
+// The manifests do not say what a board looks like, but catalog UIs want a picture. A
+// BoardImageResolver finds one from an explicit board ID -> URL map (see ReadBoardImageMap),
+// or else at conventional paths of the board's GitHub repository, such as
+// docs/html/board.png at its newest version. Images and their thumbnails are kept in a
+// CacheStore, so a catalog export does not download them again.
+
+// DefaultImageConventions are the repository paths a board image is looked for at
+var DefaultImageConventions = []string{"docs/html/board.png", "docs/html/board.jpg", "images/board.png"}
+
+// BoardImage is the picture of a board
+type BoardImage struct {
+	BoardID string `json:"boardId"`
+	URL     string `json:"url"`
+	// Thumbnail is a PNG no larger than the thumbnail size on either side
+	Thumbnail []byte `json:"-"`
+}
+
+// BoardImageResolver finds, downloads and caches board images
+type BoardImageResolver struct {
+	images      map[string]string
+	conventions []string
+	store       CacheStore
+	client      *http.Client
+	thumbSize   int
+	rawBase     string
+
+	mu      sync.Mutex
+	missing map[string]bool // URLs known not to exist, for this resolver's lifetime
+}
+
+// BoardImageOption configures a BoardImageResolver
+type BoardImageOption func(*BoardImageResolver)
+
+// WithBoardImageMap gives the image URL of boards by ID. These win over the conventions.
+func WithBoardImageMap(images map[string]string) BoardImageOption {
+	return func(r *BoardImageResolver) {
+		for id, u := range images {
+			r.images[idKey(id)] = u
+		}
+	}
+}
+
+// WithImageConventions sets the repository paths images are looked for at. Default
+// DefaultImageConventions; none disables the lookup.
+func WithImageConventions(paths ...string) BoardImageOption {
+	return func(r *BoardImageResolver) {
+		r.conventions = paths
+	}
+}
+
+// WithImageStore sets where images and thumbnails are cached. Default files in
+// ~/.modustoolbox/mtbmcp/images.
+func WithImageStore(store CacheStore) BoardImageOption {
+	return func(r *BoardImageResolver) {
+		r.store = store
+	}
+}
+
+// WithImageClient sets the client images are downloaded with. Default a client with a 30s
+// timeout.
+func WithImageClient(client *http.Client) BoardImageOption {
+	return func(r *BoardImageResolver) {
+		r.client = client
+	}
+}
+
+// WithThumbnailSize sets the largest side of thumbnails, in pixels. Default 160.
+func WithThumbnailSize(size int) BoardImageOption {
+	return func(r *BoardImageResolver) {
+		r.thumbSize = size
+	}
+}
+
+// NewBoardImageResolver creates a resolver with the given options
+func NewBoardImageResolver(opts ...BoardImageOption) *BoardImageResolver {
+	home, _ := os.UserHomeDir()
+	r := &BoardImageResolver{
+		images:      make(map[string]string),
+		conventions: DefaultImageConventions,
+		store:       NewFileStore(filepath.Join(home, ".modustoolbox", "mtbmcp", "images")),
+		client:      &http.Client{Timeout: 30 * time.Second},
+		thumbSize:   160,
+		rawBase:     "https://raw.githubusercontent.com",
+		missing:     make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// ReadBoardImageMap parses a JSON object mapping board IDs to image URLs
+func ReadBoardImageMap(jsonData []byte) (map[string]string, error) {
+	images := map[string]string{}
+	if err := json.Unmarshal(jsonData, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// candidateURLs returns the URLs the image of a board may be at, best first
+func (r *BoardImageResolver) candidateURLs(b *Board) []string {
+	if u, ok := r.images[idKey(b.ID)]; ok {
+		return []string{u}
+	}
+	ownerRepo, ok := githubRepo(b.BoardURI)
+	if !ok {
+		return nil
+	}
+	ref := newestCommit(b.VersionCommits())
+	if ref == "" {
+		ref = "master"
+	}
+	ret := []string{}
+	for _, p := range r.conventions {
+		ret = append(ret, r.rawBase+"/"+ownerRepo+"/"+ref+"/"+p)
+	}
+	return ret
+}
+
+// download returns the content at urlStr from the store, or from the network and then stores
+// it
+func (r *BoardImageResolver) download(urlStr string) ([]byte, error) {
+	if data, err := r.store.Get(urlStr); err == nil {
+		return data, nil
+	}
+	r.mu.Lock()
+	missing := r.missing[urlStr]
+	r.mu.Unlock()
+	if missing {
+		return nil, fmt.Errorf("%s: not found", urlStr)
+	}
+	resp, err := r.client.Get(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound {
+			r.mu.Lock()
+			r.missing[urlStr] = true
+			r.mu.Unlock()
+		}
+		return nil, fmt.Errorf("%s: http status %d", urlStr, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.store.Put(urlStr, data, time.Time{})
+	return data, nil
+}
+
+// Image returns the picture of a board with its thumbnail, or an error if none was found
+func (r *BoardImageResolver) Image(b *Board) (*BoardImage, error) {
+	var lastErr error = fmt.Errorf("no image source for board %s", b.ID)
+	for _, u := range r.candidateURLs(b) {
+		data, err := r.download(u)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		thumb, err := r.thumbnail(u, data)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", u, err)
+			continue
+		}
+		return &BoardImage{BoardID: b.ID, URL: u, Thumbnail: thumb}, nil
+	}
+	return nil, lastErr
+}
+
+// Images returns the pictures of the boards of the tree that have one, in board ID order.
+// Up to maxConcurrent boards are looked up at once; 0 means 8.
+func (r *BoardImageResolver) Images(sm SuperManifestIF, maxConcurrent int) []*BoardImage {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 8
+	}
+	ids := sm.GetBoardIDs()
+	found := make([]*BoardImage, len(ids))
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, maxConcurrent)
+	for i, id := range ids {
+		b, ok := sm.GetBoard(id)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+			found[i], _ = r.Image(b)
+		}()
+	}
+	wg.Wait()
+	ret := []*BoardImage{}
+	for _, img := range found {
+		if img != nil {
+			ret = append(ret, img)
+		}
+	}
+	return ret
+}
+
+// thumbnail returns the cached thumbnail of the image at urlStr, making it from data if needed
+func (r *BoardImageResolver) thumbnail(urlStr string, data []byte) ([]byte, error) {
+	// Stores key on the URL path, so the size goes there rather than in a fragment
+	key := urlStr + ".thumbnail-" + strconv.Itoa(r.thumbSize) + ".png"
+	if thumb, err := r.store.Get(key); err == nil {
+		return thumb, nil
+	}
+	thumb, err := makeThumbnail(data, r.thumbSize)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.store.Put(key, thumb, time.Time{})
+	return thumb, nil
+}
+
+// makeThumbnail scales an image down to at most size pixels on either side, averaging the
+// pixels each thumbnail pixel covers, and encodes it as PNG
+func makeThumbnail(data []byte, size int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return nil, fmt.Errorf("empty image")
+	}
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, max(1, h*size/w)
+		} else {
+			tw, th = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := bounds.Min.Y+ty*h/th, bounds.Min.Y+max((ty+1)*h/th, ty*h/th+1)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := bounds.Min.X+tx*w/tw, bounds.Min.X+max((tx+1)*w/tw, tx*w/tw+1)
+			var sr, sg, sb, sa, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, b, a := src.At(x, y).RGBA()
+					sr, sg, sb, sa, n = sr+uint64(r), sg+uint64(g), sb+uint64(b), sa+uint64(a), n+1
+				}
+			}
+			i := dst.PixOffset(tx, ty)
+			if sa == 0 {
+				continue
+			}
+			// RGBA returns premultiplied 16-bit values; NRGBA wants straight 8-bit ones
+			dst.Pix[i+0] = uint8(sr * 0xff / sa)
+			dst.Pix[i+1] = uint8(sg * 0xff / sa)
+			dst.Pix[i+2] = uint8(sb * 0xff / sa)
+			dst.Pix[i+3] = uint8(sa / n >> 8)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,82 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 10, B: 10, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBoardImages(t *testing.T) {
+	var requests atomic.Int32
+	board := testPNG(t, 400, 200)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Infineon/TARGET_CY8CKIT-062S2-43012/latest-v4.X/docs/html/board.jpg", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(board)
+	})
+	mux.HandleFunc("/kit149.png", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(testPNG(t, 50, 100))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sm := newTestSuperManifest(t)
+	store := NewMemoryStore()
+	r := NewBoardImageResolver(WithImageStore(store), WithThumbnailSize(40),
+		WithBoardImageMap(map[string]string{"CY8CKIT-149": server.URL + "/kit149.png"}))
+	r.rawBase = server.URL
+
+	images := r.Images(sm, 0)
+	if len(images) != 2 {
+		t.Fatalf("expected two images, got %v", images)
+	}
+	if !strings.HasSuffix(images[0].URL, "/latest-v4.X/docs/html/board.jpg") {
+		t.Errorf("expected the conventional path at the newest version, got %s", images[0].URL)
+	}
+	for i, size := range [][2]int{{40, 20}, {20, 40}} {
+		thumb, err := png.Decode(bytes.NewReader(images[i].Thumbnail))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := thumb.Bounds(); b.Dx() != size[0] || b.Dy() != size[1] {
+			t.Errorf("%s: expected a %v thumbnail, got %v", images[i].BoardID, size, b)
+		}
+		if c := color.NRGBAModel.Convert(thumb.At(0, 0)).(color.NRGBA); c.R != 200 || c.A != 255 {
+			t.Errorf("unexpected thumbnail color %v", c)
+		}
+	}
+
+	// The images and thumbnails come from the store the second time
+	before := requests.Load()
+	b, _ := sm.GetBoard("CY8CKIT-149")
+	if _, err := r.Image(b); err != nil || requests.Load() != before {
+		t.Errorf("expected a cached image, got %v after %d requests", err, requests.Load()-before)
+	}
+	b.BoardURI = "https://gitlab.example.com/kits/unknown"
+	b.ID = "UNKNOWN-KIT"
+	if _, err := r.Image(b); err == nil {
+		t.Error("expected no image for a board without a source")
+	}
+}
@@ -0,0 +1,219 @@
+package mtbmanifest
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Board documentation metadata
+// ////////////////////////////////////////////////////////////////////////
+
+// The board_uri and documentation_url of a board point at landing pages whose title, final
+// location and availability are worth showing next to the board, e.g. in a catalog export.
+// PageMetadataFetcher reads them and keeps the result in a CacheStore for a while, so that a
+// catalog of a few hundred boards does not request every page on every run.
+
+// PageMetadata is what the page behind a link says about itself
+type PageMetadata struct {
+	URL string `json:"url"`
+	// Title is the HTML title of the page, if it has one
+	Title      string `json:"title,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	// FinalURL is where redirects ended up, when that differs from URL
+	FinalURL string `json:"finalUrl,omitempty"`
+	// Available is set when the page answered with a success status
+	Available bool        `json:"available"`
+	Problem   LinkProblem `json:"problem,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	CheckedAt time.Time   `json:"checkedAt"`
+}
+
+// BoardInfo is a board with the metadata of its landing pages
+type BoardInfo struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Summary  string `json:"summary"`
+	// BoardURI and Documentation are nil when the board does not have the link
+	BoardURI      *PageMetadata `json:"boardUri,omitempty"`
+	Documentation *PageMetadata `json:"documentation,omitempty"`
+	// DocsGone is set when the documentation page answers with an error status
+	DocsGone bool `json:"docsGone"`
+}
+
+// PageMetadataFetcher reads and caches page metadata
+type PageMetadataFetcher struct {
+	store  CacheStore
+	client *http.Client
+	ttl    time.Duration
+	// maxBody is how much of a page is read looking for its title
+	maxBody int64
+}
+
+// PageMetadataOption configures a PageMetadataFetcher
+type PageMetadataOption func(*PageMetadataFetcher)
+
+// WithPageMetadataStore sets where metadata is cached. Default files in
+// ~/.modustoolbox/mtbmcp/pages.
+func WithPageMetadataStore(store CacheStore) PageMetadataOption {
+	return func(f *PageMetadataFetcher) {
+		f.store = store
+	}
+}
+
+// WithPageMetadataClient sets the client pages are requested with. Default a client with a
+// 15s timeout that follows redirects.
+func WithPageMetadataClient(client *http.Client) PageMetadataOption {
+	return func(f *PageMetadataFetcher) {
+		f.client = client
+	}
+}
+
+// WithPageMetadataTTL sets how long cached metadata is used before the page is requested
+// again. Default 7 days.
+func WithPageMetadataTTL(ttl time.Duration) PageMetadataOption {
+	return func(f *PageMetadataFetcher) {
+		f.ttl = ttl
+	}
+}
+
+// NewPageMetadataFetcher creates a fetcher with the given options
+func NewPageMetadataFetcher(opts ...PageMetadataOption) *PageMetadataFetcher {
+	home, _ := os.UserHomeDir()
+	f := &PageMetadataFetcher{
+		store:   NewFileStore(filepath.Join(home, ".modustoolbox", "mtbmcp", "pages")),
+		client:  &http.Client{Timeout: 15 * time.Second},
+		ttl:     7 * 24 * time.Hour,
+		maxBody: 64 * 1024,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// pageTitle returns the unescaped HTML title of a page with its whitespace collapsed
+func pageTitle(body []byte) string {
+	m := titleRegex.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+}
+
+// Get returns the metadata of the page at urlStr, from the cache when it is fresh enough.
+// Network and TLS failures are not cached, since they are often transient.
+func (f *PageMetadataFetcher) Get(urlStr string) *PageMetadata {
+	if info, err := f.store.Stat(urlStr); err == nil && time.Since(info.ModTime) < f.ttl {
+		if data, err := f.store.Get(urlStr); err == nil {
+			meta := &PageMetadata{}
+			if json.Unmarshal(data, meta) == nil {
+				return meta
+			}
+		}
+	}
+	meta := f.fetch(urlStr)
+	if meta.Problem != LinkError && meta.Problem != LinkTLS {
+		if data, err := json.Marshal(meta); err == nil {
+			_ = f.store.Put(urlStr, data, meta.CheckedAt)
+		}
+	}
+	return meta
+}
+
+// fetch requests the page and reads its title
+func (f *PageMetadataFetcher) fetch(urlStr string) *PageMetadata {
+	meta := &PageMetadata{URL: urlStr, CheckedAt: time.Now()}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, urlStr, nil)
+	if err != nil {
+		meta.Problem, meta.Error = LinkError, err.Error()
+		return meta
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		meta.Problem, meta.Error = classifyLinkError(err), err.Error()
+		return meta
+	}
+	defer resp.Body.Close()
+	meta.StatusCode = resp.StatusCode
+	if final := resp.Request.URL.String(); final != urlStr {
+		meta.FinalURL = final
+	}
+	switch {
+	case resp.StatusCode >= 400:
+		meta.Problem = LinkDead
+		return meta
+	case meta.FinalURL != "":
+		meta.Problem = LinkRedirect
+	}
+	meta.Available = true
+	if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, f.maxBody))
+		meta.Title = pageTitle(body)
+	}
+	return meta
+}
+
+// BoardInfo returns the board with the metadata of its board_uri and documentation_url
+func (f *PageMetadataFetcher) BoardInfo(b *Board) *BoardInfo {
+	info := &BoardInfo{ID: b.ID, Name: b.Name, Category: b.Category, Summary: b.Summary}
+	if b.BoardURI != "" {
+		info.BoardURI = f.Get(b.BoardURI)
+	}
+	if b.DocumentationURL != "" {
+		info.Documentation = f.Get(b.DocumentationURL)
+		info.DocsGone = info.Documentation.Problem == LinkDead
+	}
+	return info
+}
+
+// BoardInfos returns the BoardInfo of every board of the tree, in board ID order. Up to
+// maxConcurrent boards are looked up at once; 0 means 8.
+func (f *PageMetadataFetcher) BoardInfos(sm SuperManifestIF, maxConcurrent int) []*BoardInfo {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 8
+	}
+	boards := []*Board{}
+	for _, id := range sm.GetBoardIDs() {
+		if b, ok := sm.GetBoard(id); ok {
+			boards = append(boards, b)
+		}
+	}
+	ret := make([]*BoardInfo, len(boards))
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, maxConcurrent)
+	for i, b := range boards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+			ret[i] = f.BoardInfo(b)
+		}()
+	}
+	wg.Wait()
+	return ret
+}
+
+// BoardsWithDeadDocs returns the boards whose documentation page is gone
+func BoardsWithDeadDocs(infos []*BoardInfo) []*BoardInfo {
+	ret := []*BoardInfo{}
+	for _, info := range infos {
+		if info.DocsGone {
+			ret = append(ret, info)
+		}
+	}
+	return ret
+}
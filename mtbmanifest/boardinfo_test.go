@@ -0,0 +1,57 @@
+package mtbmanifest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBoardInfos(t *testing.T) {
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kit", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html><head><TITLE>\n  CY8CKIT-149 &amp; friends\n</TITLE></head></html>"))
+	})
+	mux.HandleFunc("/old-kit", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/kit", http.StatusMovedPermanently)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sm := newTestSuperManifest(t)
+	b1, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	b1.BoardURI = server.URL + "/old-kit"
+	b1.DocumentationURL = server.URL + "/gone"
+	b2, _ := sm.GetBoard("CY8CKIT-149")
+	b2.BoardURI = ""
+	b2.DocumentationURL = server.URL + "/kit"
+
+	store := NewMemoryStore()
+	f := NewPageMetadataFetcher(WithPageMetadataStore(store), WithPageMetadataTTL(time.Hour))
+	infos := f.BoardInfos(sm, 0)
+	if len(infos) != 2 || infos[0].ID != "CY8CKIT-062S2-43012" {
+		t.Fatalf("unexpected infos %+v", infos)
+	}
+	if m := infos[0].BoardURI; !m.Available || m.Problem != LinkRedirect || m.FinalURL != server.URL+"/kit" {
+		t.Errorf("unexpected redirect metadata %+v", m)
+	}
+	if !infos[0].DocsGone || infos[0].Documentation.StatusCode != http.StatusNotFound {
+		t.Errorf("expected dead docs, got %+v", infos[0].Documentation)
+	}
+	if infos[1].BoardURI != nil || infos[1].Documentation.Title != "CY8CKIT-149 & friends" {
+		t.Errorf("unexpected metadata %+v", infos[1])
+	}
+	if dead := BoardsWithDeadDocs(infos); len(dead) != 1 || dead[0].ID != "CY8CKIT-062S2-43012" {
+		t.Errorf("unexpected dead docs %+v", dead)
+	}
+
+	// The second lookup is served from the cache
+	before := requests.Load()
+	if m := f.Get(server.URL + "/kit"); m.Title != "CY8CKIT-149 & friends" || requests.Load() != before {
+		t.Errorf("expected a cached title, got %+v after %d requests", m, requests.Load()-before)
+	}
+}
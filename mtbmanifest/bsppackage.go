@@ -0,0 +1,191 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// BSP packaging
+// ////////////////////////////////////////////////////////////////////////
+
+// A BSP does not build on its own: the dependencies manifest lists the libraries each of its
+// versions needs (core-lib, the PDL, the build recipe, ...), and those libraries list their
+// own. library-manager puts the BSP in bsps/TARGET_<board>, every library in
+// mtb_shared/<id>/<version>, and a .mtb file per direct dependency in the deps directory of
+// the BSP, naming the repository, version and location of the library. PackageBSP does the
+// same for one board version and records what it placed where in bsp-package.json.
+
+// BSPPackageFile is the file PackageBSP records the package in, at the top of its directory
+const BSPPackageFile = "bsp-package.json"
+
+// PackagedAsset is one repository of a BSP package
+type PackagedAsset struct {
+	ID   string   `json:"id"`
+	Kind ItemKind `json:"kind"`
+	Repo string   `json:"repo"`
+	Ref  string   `json:"ref"`
+	// SHA is the commit Ref resolved to when fetched
+	SHA string `json:"sha,omitempty"`
+	// Path is where the files are, relative to the package directory
+	Path string `json:"path"`
+	// RequiredBy are the assets that need this one; empty for the BSP
+	RequiredBy []string `json:"requiredBy,omitempty"`
+}
+
+// BSPPackage describes the BSP of a board version and the libraries it needs
+type BSPPackage struct {
+	Board   string `json:"board"`
+	Version string `json:"version"`
+	// Assets are the BSP, then its libraries breadth first
+	Assets []*PackagedAsset `json:"assets"`
+	// Missing are required libraries that are not middleware of the tree, as ID@version
+	Missing []string `json:"missing,omitempty"`
+	// Conflicts are libraries required at more than one version; the version required first
+	// is the one placed
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// bspDir returns where library-manager puts the BSP of a board
+func bspDir(boardID string) string {
+	return path.Join("bsps", "TARGET_"+boardID)
+}
+
+// sharedLibDir returns where library-manager puts a version of a library
+func sharedLibDir(id, ref string) string {
+	return path.Join("mtb_shared", id, ref)
+}
+
+// dependerVersion returns the dependencies of one version of a depender, or nil
+func dependerVersion(d *Depender, commit string) *DependerVersion {
+	if d == nil {
+		return nil
+	}
+	for _, v := range d.Versions {
+		if v.Commit == commit {
+			return v
+		}
+	}
+	return nil
+}
+
+// ResolveBSP returns the BSP package of a board version, without fetching anything: the
+// BSP and every library it needs, directly or through other libraries. An empty version means
+// the newest listed.
+func ResolveBSP(sm SuperManifestIF, boardID, version string) (*BSPPackage, error) {
+	board, ok := sm.GetBoard(boardID)
+	if !ok {
+		return nil, fmt.Errorf("board %s not found", boardID)
+	}
+	src, err := AssetSource(sm, board.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &BSPPackage{Board: board.ID, Version: src.Ref, Assets: []*PackagedAsset{{
+		ID: board.ID, Kind: ItemKindBoard, Repo: src.Repo, Ref: src.Ref, Path: bspDir(board.ID),
+	}}}
+	placed := map[string]*PackagedAsset{idKey(board.ID): pkg.Assets[0]}
+	missing := map[string]bool{}
+	queue := []struct {
+		asset *PackagedAsset
+		deps  *Depender
+	}{{pkg.Assets[0], board.Dependencies}}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		v := dependerVersion(from.deps, from.asset.Ref)
+		if v == nil {
+			continue
+		}
+		for _, dep := range v.Dependees {
+			if asset, ok := placed[idKey(dep.ID)]; ok {
+				asset.RequiredBy = append(asset.RequiredBy, from.asset.ID)
+				if asset.Ref != dep.Commit && asset.Kind != ItemKindBoard {
+					pkg.Conflicts = append(pkg.Conflicts, fmt.Sprintf("%s: %s needs %s, %s is placed",
+						dep.ID, from.asset.ID, dep.Commit, asset.Ref))
+				}
+				continue
+			}
+			mw, ok := sm.GetMiddleware(dep.ID)
+			if !ok || mw.URI == "" {
+				if spec := dep.ID + "@" + dep.Commit; !missing[spec] {
+					missing[spec] = true
+					pkg.Missing = append(pkg.Missing, spec)
+				}
+				continue
+			}
+			asset := &PackagedAsset{ID: mw.ID, Kind: ItemKindMiddleware, Repo: mw.URI, Ref: dep.Commit,
+				Path: sharedLibDir(mw.ID, dep.Commit), RequiredBy: []string{from.asset.ID}}
+			placed[idKey(dep.ID)] = asset
+			pkg.Assets = append(pkg.Assets, asset)
+			queue = append(queue, struct {
+				asset *PackagedAsset
+				deps  *Depender
+			}{asset, mw.Dependencies})
+		}
+	}
+	return pkg, nil
+}
+
+// PackageBSP fetches the BSP of a board version and the libraries it needs into dir, which
+// must not exist or be empty, in the layout of library-manager: the BSP in
+// bsps/TARGET_<board> with a .mtb file per direct dependency in its deps directory, the
+// libraries in mtb_shared/<id>/<version>. The package, with the commit of every asset, is
+// written to bsp-package.json. An empty version means the newest listed.
+func PackageBSP(sm SuperManifestIF, g *GitFetcher, boardID, version, dir string) (*BSPPackage, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+	pkg, err := ResolveBSP(sm, boardID, version)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		g = NewGitFetcher()
+	}
+	for _, asset := range pkg.Assets {
+		sha, err := g.Fetch(&GitSource{Repo: asset.Repo, Ref: asset.Ref}, filepath.Join(dir, filepath.FromSlash(asset.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", asset.ID, err)
+		}
+		asset.SHA = sha
+	}
+	if err := writeMTBFiles(pkg, dir); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, BSPPackageFile), append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// writeMTBFiles writes a .mtb file for each direct dependency of the BSP into its deps
+// directory: URL#version#location, the location relative to $$ASSET_REPO$$ (mtb_shared)
+func writeMTBFiles(pkg *BSPPackage, dir string) error {
+	bsp := pkg.Assets[0]
+	depsDir := filepath.Join(dir, filepath.FromSlash(bsp.Path), "deps")
+	for _, asset := range pkg.Assets[1:] {
+		direct := false
+		for _, by := range asset.RequiredBy {
+			direct = direct || by == bsp.ID
+		}
+		if !direct {
+			continue
+		}
+		if err := os.MkdirAll(depsDir, 0o755); err != nil {
+			return err
+		}
+		line := fmt.Sprintf("%s#%s#$$ASSET_REPO$$/%s/%s\n", asset.Repo, asset.Ref, asset.ID, asset.Ref)
+		if err := os.WriteFile(filepath.Join(depsDir, asset.ID+".mtb"), []byte(line), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
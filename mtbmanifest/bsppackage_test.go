@@ -0,0 +1,79 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageBSP(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	board, _ := sm.GetBoard("CY8CKIT-149")
+	wcm, _ := sm.GetMiddleware("wifi-connection-manager")
+	freertos, _ := sm.GetMiddleware("freertos")
+	for _, asset := range []struct {
+		uri *string
+		tag string
+	}{{&board.BoardURI, "latest-v3.X"}, {&wcm.URI, "latest-v3.X"}, {&freertos.URI, "latest-v10.X"}} {
+		repo := newTestGitRepo(t)
+		runGit(t, repo, "tag", asset.tag)
+		*asset.uri = "file://" + repo
+	}
+	dependsOn := func(id, commit string, dependees ...*Dependee) *Depender {
+		return &Depender{ID: id, Versions: []*DependerVersion{{Commit: commit, Dependees: dependees}}}
+	}
+	board.Dependencies = dependsOn(board.ID, "latest-v3.X",
+		&Dependee{ID: "wifi-connection-manager", Commit: "latest-v3.X"},
+		&Dependee{ID: "core-lib", Commit: "latest-v1.X"})
+	wcm.Dependencies = dependsOn(wcm.ID, "latest-v3.X", &Dependee{ID: "freertos", Commit: "latest-v10.X"})
+	freertos.Dependencies = dependsOn(freertos.ID, "latest-v10.X",
+		&Dependee{ID: "wifi-connection-manager", Commit: "latest-v2.X"})
+
+	dir := filepath.Join(t.TempDir(), "bsp")
+	pkg, err := PackageBSP(sm, NewGitFetcher(WithGitCacheDir(t.TempDir())), "CY8CKIT-149", "", dir)
+	if err != nil {
+		t.Fatalf("PackageBSP failed: %v", err)
+	}
+	if pkg.Version != "latest-v3.X" || len(pkg.Assets) != 3 {
+		t.Fatalf("expected the BSP and 2 libraries, got %s with %+v", pkg.Version, pkg.Assets)
+	}
+	for i, want := range []string{"bsps/TARGET_CY8CKIT-149", "mtb_shared/wifi-connection-manager/latest-v3.X",
+		"mtb_shared/freertos/latest-v10.X"} {
+		if pkg.Assets[i].Path != want || pkg.Assets[i].SHA == "" {
+			t.Errorf("expected asset %d in %s, got %+v", i, want, pkg.Assets[i])
+		}
+		if _, err := os.Stat(filepath.Join(dir, want, "README.md")); err != nil {
+			t.Errorf("expected the files of %s: %v", want, err)
+		}
+	}
+	if len(pkg.Missing) != 1 || pkg.Missing[0] != "core-lib@latest-v1.X" {
+		t.Errorf("expected core-lib to be missing, got %v", pkg.Missing)
+	}
+	if len(pkg.Conflicts) != 1 {
+		t.Errorf("expected the wifi-connection-manager versions to conflict, got %v", pkg.Conflicts)
+	}
+
+	mtb, err := os.ReadFile(filepath.Join(dir, "bsps/TARGET_CY8CKIT-149/deps/wifi-connection-manager.mtb"))
+	if want := wcm.URI + "#latest-v3.X#$$ASSET_REPO$$/wifi-connection-manager/latest-v3.X\n"; err != nil || string(mtb) != want {
+		t.Errorf("expected %q, got %q, %v", want, mtb, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bsps/TARGET_CY8CKIT-149/deps/freertos.mtb")); err == nil {
+		t.Error("expected no .mtb file for an indirect dependency")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, BSPPackageFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved BSPPackage
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved.Assets) != 3 || saved.Assets[2].SHA != pkg.Assets[2].SHA {
+		t.Errorf("expected the package to be recorded, got %+v, %v", saved, err)
+	}
+
+	if _, err := PackageBSP(sm, nil, "CY8CKIT-149", "", dir); err == nil {
+		t.Error("expected an error for a directory that is not empty")
+	}
+	if _, err := ResolveBSP(sm, "CY8CKIT-149", "latest-v9.X"); err == nil {
+		t.Error("expected an error for an unlisted version")
+	}
+}
@@ -0,0 +1,154 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Bulk fetching of arbitrary manifests
+// ////////////////////////////////////////////////////////////////////////
+
+// Tools that check a handful of manifests of their own, e.g. a BSP's dependencies manifest
+// before it is published, should not have to wire FetchAllWithCb callbacks to the right ReadXxx
+// function. FetchAndParse takes URLs of any kind, fetches them through the cache and limiter of
+// a fetcher, and parses each by the kind declared for it, or else by its root element, or else
+// by its file name (see ManifestKindOf).
+
+// TypedURL is a manifest URL and its kind; KindUnknown has the kind told from the content
+type TypedURL struct {
+	URL  string       `json:"url"`
+	Kind ManifestKind `json:"kind"`
+}
+
+// ParsedManifest is the outcome of fetching and parsing one manifest. Manifest is a
+// *SuperManifest, *Boards, *Apps, *Middleware, *Dependencies or *BSPCapabilitiesManifest,
+// according to Kind; nil when Err is set.
+type ParsedManifest struct {
+	URL      string
+	Kind     ManifestKind
+	Manifest any
+	// Format is the format of board, app and middleware manifests (see DetectManifestFormat)
+	Format int
+	// Warnings are the surprises found while parsing
+	Warnings []*IngestWarning
+	Err      error
+}
+
+// FetchAndParse fetches and parses manifests of any kind, concurrently. Ingestion options
+// choose the fetcher, cache and logger as for NewSuperManifestFromURL. Every URL has an entry
+// in the result; the error joins the failures, in the order of urls. When ctx ends first the
+// URLs not fetched yet fail with its error.
+func FetchAndParse(ctx context.Context, urls []TypedURL, opts ...IngestOption) (map[string]ParsedManifest, error) {
+	cfg := newIngestConfig(opts)
+	fetcher, done := cfg.newFetcher()
+	defer done()
+	return fetcher.fetchAndParse(ctx, urls, cfg)
+}
+
+// FetchAndParse is FetchAndParse through this fetcher
+func (f *ManifestFetcher) FetchAndParse(ctx context.Context, urls []TypedURL) (map[string]ParsedManifest, error) {
+	return f.fetchAndParse(ctx, urls, newIngestConfig(nil))
+}
+
+func (f *ManifestFetcher) fetchAndParse(ctx context.Context, urls []TypedURL, cfg *ingestConfig) (map[string]ParsedManifest, error) {
+	results := make(map[string]ParsedManifest, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[string]bool, len(urls))
+	for _, tu := range urls {
+		if seen[tu.URL] {
+			continue
+		}
+		seen[tu.URL] = true
+		wg.Add(1)
+		go func(tu TypedURL) {
+			defer wg.Done()
+			var pm ParsedManifest
+			if data, err := f.get(ctx, tu.URL); err != nil && ctx.Err() != nil {
+				pm = ParsedManifest{URL: tu.URL, Kind: tu.Kind, Err: ctx.Err()}
+			} else {
+				pm = parseTypedManifest(tu, data, err, cfg)
+			}
+			mu.Lock()
+			results[tu.URL] = pm
+			mu.Unlock()
+		}(tu)
+	}
+	wg.Wait()
+
+	errs := []error{}
+	for _, tu := range urls {
+		if pm := results[tu.URL]; pm.Err != nil && seen[tu.URL] {
+			errs = append(errs, fmt.Errorf("%s: %w", tu.URL, pm.Err))
+			seen[tu.URL] = false // once per URL
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// parseTypedManifest parses fetched data by the kind of tu, or the kind it sniffs
+func parseTypedManifest(tu TypedURL, data []byte, err error, cfg *ingestConfig) ParsedManifest {
+	pm := ParsedManifest{URL: tu.URL, Kind: tu.Kind}
+	if err != nil {
+		pm.Err = fmt.Errorf("%w: %w", ErrFetchFailed, err)
+		return pm
+	}
+	if pm.Kind == KindUnknown {
+		pm.Kind = SniffManifestKind(tu.URL, data)
+	}
+	report := &IngestReport{}
+	if pm.Kind == KindUnknown {
+		pm.Err = fmt.Errorf("cannot tell what kind of manifest this is")
+	} else if m, err := parseAnyAs(pm.Kind, data, cfg, report, tu.URL); err != nil {
+		pm.Err = err
+	} else {
+		pm.Manifest = m.Manifest()
+	}
+	switch pm.Kind {
+	case KindBoards, KindApps, KindMiddleware:
+		pm.Format = DetectManifestFormat(tu.URL, data)
+	}
+	pm.Warnings = report.Warnings
+	return pm
+}
+
+// rootElementKinds are the manifest kinds by root element
+var rootElementKinds = map[string]ManifestKind{
+	"super-manifest": KindSuper,
+	"boards":         KindBoards,
+	"apps":           KindApps,
+	"middleware":     KindMiddleware,
+	"dependencies":   KindDependencies,
+}
+
+// SniffManifestKind tells the kind of a manifest from its content: the root element of XML,
+// or capabilities for a JSON object with a capabilities list. Content that says nothing falls
+// back to ManifestKindOf.
+func SniffManifestKind(urlStr string, data []byte) ManifestKind {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		if isCapabilitiesJSON(trimmed) {
+			return KindCapabilities
+		}
+		return ManifestKindOf(urlStr)
+	}
+	d := xml.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if kind, ok := rootElementKinds[start.Name.Local]; ok {
+				return kind
+			}
+			break
+		}
+	}
+	return ManifestKindOf(urlStr)
+}
@@ -0,0 +1,111 @@
+package mtbmanifest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newContentsFetcher returns a fetcher serving contents from memory, and nothing else
+func newContentsFetcher(t *testing.T, contents map[string][]byte) *ManifestFetcher {
+	t.Helper()
+	store := NewMemoryStore()
+	for urlStr, data := range contents {
+		if err := store.Put(urlStr, data, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache := NewManifestCache(WithStore(store), WithCacheTTL(100*365*24*time.Hour), WithNoBackgroundRefresh(),
+		WithCacheOnly())
+	t.Cleanup(cache.Close)
+	return NewManifestFetcher(WithCache(cache), WithMaxConcurrent(2))
+}
+
+func TestFetchAndParse(t *testing.T) {
+	f := newContentsFetcher(t, map[string][]byte{
+		"https://example.com/super.xml":         []byte(testSuperXML),
+		"https://example.com/kits.xml":          []byte(testBoardsXML),
+		"https://example.com/apps.xml":          []byte(testAppsXML),
+		"https://example.com/mw.xml":            []byte(testMiddlewareXML),
+		"https://example.com/caps.json":         []byte(`{"capabilities": []}`),
+		"https://example.com/not-a-manifest":    []byte(`<html></html>`),
+		"https://example.com/declared-apps.xml": []byte(testBoardsXML),
+	})
+	urls := []TypedURL{
+		{URL: "https://example.com/super.xml"},
+		{URL: "https://example.com/kits.xml"},
+		{URL: "https://example.com/apps.xml"},
+		{URL: "https://example.com/mw.xml"},
+		{URL: "https://example.com/caps.json"},
+		{URL: "https://example.com/not-a-manifest"},
+		{URL: "https://example.com/declared-apps.xml", Kind: KindBoards},
+		{URL: "https://example.com/missing.xml", Kind: KindApps},
+		{URL: "https://example.com/kits.xml"},
+	}
+	results, err := f.FetchAndParse(context.Background(), urls)
+	if len(results) != len(urls)-1 {
+		t.Fatalf("expected one result per distinct URL, got %d", len(results))
+	}
+	if err == nil || !strings.Contains(err.Error(), "not-a-manifest") || !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected the failures joined, got %v", err)
+	}
+
+	if sm, ok := results["https://example.com/super.xml"].Manifest.(*SuperManifest); !ok || sm.Version != "2.0" {
+		t.Errorf("expected a super manifest, got %+v", results["https://example.com/super.xml"])
+	}
+	if boards, ok := results["https://example.com/kits.xml"].Manifest.(*Boards); !ok || len(boards.Boards) != 2 {
+		t.Errorf("expected sniffed boards, got %+v", results["https://example.com/kits.xml"])
+	}
+	if pm := results["https://example.com/apps.xml"]; pm.Kind != KindApps || pm.Format != 2 {
+		t.Errorf("expected fv2 apps, got %+v", pm)
+	}
+	if _, ok := results["https://example.com/mw.xml"].Manifest.(*Middleware); !ok {
+		t.Errorf("expected middleware, got %+v", results["https://example.com/mw.xml"])
+	}
+	if _, ok := results["https://example.com/caps.json"].Manifest.(*BSPCapabilitiesManifest); !ok {
+		t.Errorf("expected capabilities, got %+v", results["https://example.com/caps.json"])
+	}
+	if pm := results["https://example.com/declared-apps.xml"]; pm.Kind != KindBoards || pm.Err != nil {
+		t.Errorf("expected the declared kind to win over the file name, got %+v", pm)
+	}
+	if pm := results["https://example.com/not-a-manifest"]; pm.Err == nil || pm.Manifest != nil {
+		t.Errorf("expected an unknown kind to fail, got %+v", pm)
+	}
+	if pm := results["https://example.com/missing.xml"]; !errors.Is(pm.Err, ErrOffline) {
+		t.Errorf("expected a fetch failure, got %+v", pm)
+	}
+}
+
+func TestFetchAndParseCanceled(t *testing.T) {
+	f := newContentsFetcher(t, map[string][]byte{"https://example.com/apps.xml": []byte(testAppsXML)})
+	for i := 0; i < cap(f.limiter); i++ {
+		f.limiter <- struct{}{} // every slot busy
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := f.FetchAndParse(ctx, []TypedURL{{URL: "https://example.com/apps.xml"}})
+	if !errors.Is(err, context.Canceled) || !errors.Is(results["https://example.com/apps.xml"].Err, context.Canceled) {
+		t.Errorf("expected the canceled context to fail the fetch, got %v", err)
+	}
+}
+
+func TestSniffManifestKind(t *testing.T) {
+	tests := []struct {
+		url  string
+		data string
+		want ManifestKind
+	}{
+		{"https://example.com/x.xml", `<?xml version="1.0"?><!-- c --><super-manifest version="2.0"/>`, KindSuper},
+		{"https://example.com/x.xml", `<dependencies/>`, KindDependencies},
+		{"https://example.com/x", ` {"capabilities": []}`, KindCapabilities},
+		{"https://example.com/mtb-ce-manifest.xml", `<html/>`, KindApps},
+		{"https://example.com/x.xml", `garbage`, KindUnknown},
+	}
+	for _, tt := range tests {
+		if got := SniffManifestKind(tt.url, []byte(tt.data)); got != tt.want {
+			t.Errorf("SniffManifestKind(%s, %s) = %q, want %q", tt.url, tt.data, got, tt.want)
+		}
+	}
+}
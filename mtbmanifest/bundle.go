@@ -0,0 +1,232 @@
+package mtbmanifest
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Offline bundles
+// ////////////////////////////////////////////////////////////////////////
+
+// An offline bundle packs every file of a manifest tree (super manifest, board/app/middleware
+// manifests, dependency and capability manifests) into a single .tar.gz so the tree can be
+// carried to an air-gapped machine. Installing the bundle there fills the manifest cache, after
+// which NewSuperManifestFromURL works without a network.
+//
+// Layout of the archive:
+//
+//	bundle.json        the BundleIndex
+//	files/<sha256>     content of each entry, named by its hash
+//
+// gzip is used rather than zstd to stay within the standard library; the cache itself already
+// uses gzip.
+
+// BundleFormatVersion is the version of the bundle layout written by CreateBundle
+const BundleFormatVersion = 1
+
+const bundleIndexName = "bundle.json"
+
+// BundleEntry is one file in an offline bundle
+type BundleEntry struct {
+	URL    string `json:"url"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+	// ModTime is when the entry was cached. Only set in cache exports.
+	ModTime *time.Time `json:"modTime,omitempty"`
+	// InBase marks an entry of a delta bundle that is unchanged from the base bundle, and
+	// whose content is therefore not in the archive
+	InBase bool `json:"inBase,omitempty"`
+}
+
+// BundleIndex describes the contents of an offline bundle
+type BundleIndex struct {
+	Format  int       `json:"format"`
+	Created time.Time `json:"created"`
+	// RootURLs are the super manifest URLs the bundle was made from
+	RootURLs []string       `json:"rootUrls"`
+	Entries  []*BundleEntry `json:"entries"`
+	// BaseID is the ID of the base bundle when this is a delta bundle (see CreateDeltaBundle)
+	BaseID string `json:"baseId,omitempty"`
+}
+
+// GetEntry returns the entry for a URL
+func (bi *BundleIndex) GetEntry(urlStr string) (*BundleEntry, bool) {
+	for _, e := range bi.Entries {
+		if e.URL == urlStr {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// hashContent returns the hex SHA-256 of content, as bundles, provenance and the parsing of
+// duplicate manifests identify it
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// GetSourceURLs returns the URLs of the super manifests this tree was ingested from
+func (sm *SuperManifest) GetSourceURLs() []string {
+	ret := make([]string, len(sm.SourceUrls))
+	copy(ret, sm.SourceUrls)
+	return ret
+}
+
+// ManifestURLs returns the URL of every file the manifest tree was built from, in the order
+// of ReferencedURLs. Each URL appears once.
+func (sm *SuperManifest) ManifestURLs() []string {
+	refs := sm.ReferencedURLs()
+	ret := make([]string, len(refs))
+	for i, ref := range refs {
+		ret[i] = ref.URL
+	}
+	return ret
+}
+
+// CreateBundle writes an offline bundle of the manifest tree to w. File contents come from the
+// cache, which already holds everything that was fetched while ingesting the tree. A nil cache
+// means the default cache.
+func CreateBundle(w io.Writer, sm SuperManifestIF, cache *ManifestCache) (*BundleIndex, error) {
+	if cache == nil {
+		cache = NewManifestDefaultCache()
+		defer cache.Close()
+	}
+	index := &BundleIndex{
+		Format:   BundleFormatVersion,
+		Created:  time.Now().UTC(),
+		RootURLs: sm.GetSourceURLs(),
+		Entries:  []*BundleEntry{},
+	}
+	contents := make(map[string][]byte)
+	for _, urlStr := range sm.ManifestURLs() {
+		data, err := cache.Get(urlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", urlStr, err)
+		}
+		entry := &BundleEntry{URL: urlStr, Size: len(data), SHA256: hashContent(data)}
+		index.Entries = append(index.Entries, entry)
+		contents[entry.SHA256] = data
+	}
+	if err := writeBundle(w, index, contents); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// writeBundle writes the index followed by one file per distinct content hash
+func writeBundle(w io.Writer, index *BundleIndex, contents map[string][]byte) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	writeFile := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: index.Created,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(bundleIndexName, indexData); err != nil {
+		return err
+	}
+	written := make(map[string]bool)
+	for _, entry := range index.Entries {
+		if entry.InBase || written[entry.SHA256] {
+			continue
+		}
+		written[entry.SHA256] = true
+		if err := writeFile(path.Join("files", entry.SHA256), contents[entry.SHA256]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// ReadBundle reads an offline bundle and returns its index and the content of each entry,
+// keyed by URL. Every entry is checked against its hash. For a delta bundle, the entries that
+// are unchanged from the base have no content.
+func ReadBundle(r io.Reader) (*BundleIndex, map[string][]byte, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a bundle: %v", err)
+	}
+	defer func() { _ = gzr.Close() }()
+
+	var index *BundleIndex
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("corrupt bundle: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("corrupt bundle: %v", err)
+		}
+		if hdr.Name == bundleIndexName {
+			index = &BundleIndex{}
+			if err := json.Unmarshal(data, index); err != nil {
+				return nil, nil, fmt.Errorf("corrupt bundle index: %v", err)
+			}
+		} else {
+			files[path.Base(hdr.Name)] = data
+		}
+	}
+	if index == nil {
+		return nil, nil, fmt.Errorf("not a bundle: %s missing", bundleIndexName)
+	}
+	if index.Format > BundleFormatVersion {
+		return nil, nil, fmt.Errorf("unsupported bundle format %d", index.Format)
+	}
+
+	contents := make(map[string][]byte, len(index.Entries))
+	for _, entry := range index.Entries {
+		if entry.InBase {
+			continue
+		}
+		data, ok := files[entry.SHA256]
+		if !ok {
+			return nil, nil, fmt.Errorf("bundle is missing the content of %s", entry.URL)
+		}
+		if hashContent(data) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("bundle content of %s does not match its hash", entry.URL)
+		}
+		contents[entry.URL] = data
+	}
+	return index, contents, nil
+}
+
+// InstallBundle reads an offline bundle and stores every entry in the cache, so the manifest
+// tree can be ingested without a network. A nil cache means the default cache.
+func InstallBundle(r io.Reader, cache *ManifestCache) (*BundleIndex, error) {
+	if cache == nil {
+		cache = NewManifestDefaultCache()
+		defer cache.Close()
+	}
+	return cache.Import(r)
+}
@@ -0,0 +1,114 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	sm.SourceUrls = []string{"https://example.com/super.xml"}
+	sm.BoardManifestList.BoardManifest[0].DependencyURL = "https://example.com/deps.xml"
+
+	source := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer source.Close()
+	urls := sm.ManifestURLs()
+	if len(urls) != 5 || urls[0] != "https://example.com/super.xml" || urls[4] != "https://example.com/deps.xml" {
+		t.Fatalf("unexpected manifest URLs %v", urls)
+	}
+	for _, u := range urls {
+		if err := source.writeCache(u, []byte("content of "+u)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	index, err := CreateBundle(&buf, sm, source)
+	if err != nil {
+		t.Fatalf("CreateBundle failed: %v", err)
+	}
+	if len(index.Entries) != 5 || index.RootURLs[0] != "https://example.com/super.xml" {
+		t.Errorf("unexpected index %+v", index)
+	}
+
+	target := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer target.Close()
+	if _, err := InstallBundle(bytes.NewReader(buf.Bytes()), target); err != nil {
+		t.Fatalf("InstallBundle failed: %v", err)
+	}
+	for _, u := range urls {
+		data, err := target.readCache(u)
+		if err != nil || string(data) != "content of "+u {
+			t.Errorf("expected %s to be installed, got %q, %v", u, data, err)
+		}
+	}
+
+	if _, err := InstallBundle(strings.NewReader("not a bundle"), target); err == nil {
+		t.Error("expected an error for a bad bundle")
+	}
+}
+
+func TestDeltaBundle(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	sm.SourceUrls = []string{"https://example.com/super.xml"}
+	cache := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	urls := sm.ManifestURLs()
+	for _, u := range urls {
+		_ = cache.writeCache(u, []byte("v1 of "+u))
+	}
+	var base bytes.Buffer
+	baseIndex, err := CreateBundle(&base, sm, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the apps manifest changes
+	_ = cache.writeCache("https://example.com/apps.xml", []byte("v2 of apps"))
+	var delta bytes.Buffer
+	deltaIndex, err := CreateDeltaBundle(&delta, sm, cache, baseIndex)
+	if err != nil {
+		t.Fatalf("CreateDeltaBundle failed: %v", err)
+	}
+	changed := 0
+	for _, e := range deltaIndex.Entries {
+		if !e.InBase {
+			changed++
+		}
+	}
+	if changed != 1 || delta.Len() >= base.Len() {
+		t.Errorf("expected a small delta with 1 changed entry, got %d (%d vs %d bytes)", changed, delta.Len(), base.Len())
+	}
+
+	var full bytes.Buffer
+	merged, err := ApplyDelta(bytes.NewReader(base.Bytes()), bytes.NewReader(delta.Bytes()), &full)
+	if err != nil {
+		t.Fatalf("ApplyDelta failed: %v", err)
+	}
+	_, contents, err := ReadBundle(&full)
+	if err != nil || merged.IsDelta() {
+		t.Fatalf("merged bundle is not a full bundle: %v", err)
+	}
+	if string(contents["https://example.com/apps.xml"]) != "v2 of apps" ||
+		string(contents["https://example.com/boards.xml"]) != "v1 of https://example.com/boards.xml" {
+		t.Error("merged bundle does not hold the expected contents")
+	}
+
+	// A delta installs on top of the base, but not on an empty cache
+	fresh := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer fresh.Close()
+	if _, err := fresh.Import(bytes.NewReader(delta.Bytes())); err == nil {
+		t.Error("expected the delta to need its base")
+	}
+	if _, err := fresh.Import(bytes.NewReader(base.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fresh.Import(bytes.NewReader(delta.Bytes())); err != nil {
+		t.Errorf("expected the delta to apply on top of its base: %v", err)
+	}
+	if data, _ := fresh.readCache("https://example.com/apps.xml"); string(data) != "v2 of apps" {
+		t.Errorf("expected the delta content, got %q", data)
+	}
+}
@@ -0,0 +1,115 @@
+package mtbmanifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// A delta bundle carries only the entries whose content changed since a base bundle. Its index
+// still lists every entry with its hash, marking unchanged ones InBase, so the full tree can be
+// rebuilt from the base plus the delta (ApplyDelta), or the delta installed on top of a cache
+// that already holds the base (ManifestCache.Import).
+
+// ID identifies the content of a bundle: a hash over the URL and content hash of every entry.
+// Two bundles of identical trees have the same ID, whenever they were made.
+func (bi *BundleIndex) ID() string {
+	lines := make([]string, 0, len(bi.Entries))
+	for _, e := range bi.Entries {
+		lines = append(lines, e.URL+" "+e.SHA256+"\n")
+	}
+	sort.Strings(lines)
+	h := sha256.New()
+	for _, line := range lines {
+		_, _ = io.WriteString(h, line)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// IsDelta reports whether this is the index of a delta bundle
+func (bi *BundleIndex) IsDelta() bool {
+	return bi.BaseID != ""
+}
+
+// CreateDeltaBundle is like CreateBundle, but leaves out the content of every entry that is
+// identical in the base bundle. A nil cache means the default cache.
+func CreateDeltaBundle(w io.Writer, sm SuperManifestIF, cache *ManifestCache, base *BundleIndex) (*BundleIndex, error) {
+	if base.IsDelta() {
+		return nil, fmt.Errorf("the base of a delta must be a full bundle")
+	}
+	if cache == nil {
+		cache = NewManifestDefaultCache()
+		defer cache.Close()
+	}
+	baseHashes := make(map[string]string, len(base.Entries))
+	for _, e := range base.Entries {
+		baseHashes[e.URL] = e.SHA256
+	}
+
+	index := &BundleIndex{
+		Format:   BundleFormatVersion,
+		Created:  time.Now().UTC(),
+		RootURLs: sm.GetSourceURLs(),
+		Entries:  []*BundleEntry{},
+		BaseID:   base.ID(),
+	}
+	contents := make(map[string][]byte)
+	for _, urlStr := range sm.ManifestURLs() {
+		data, err := cache.Get(urlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", urlStr, err)
+		}
+		entry := &BundleEntry{URL: urlStr, Size: len(data), SHA256: hashContent(data)}
+		entry.InBase = baseHashes[urlStr] == entry.SHA256
+		index.Entries = append(index.Entries, entry)
+		if !entry.InBase {
+			contents[entry.SHA256] = data
+		}
+	}
+	if err := writeBundle(w, index, contents); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// ApplyDelta reads a full base bundle and a delta made against it, and writes the full bundle
+// the delta describes to w. Entries dropped since the base are dropped.
+func ApplyDelta(base, delta io.Reader, w io.Writer) (*BundleIndex, error) {
+	baseIndex, baseContents, err := ReadBundle(base)
+	if err != nil {
+		return nil, fmt.Errorf("base bundle: %v", err)
+	}
+	deltaIndex, deltaContents, err := ReadBundle(delta)
+	if err != nil {
+		return nil, fmt.Errorf("delta bundle: %v", err)
+	}
+	if !deltaIndex.IsDelta() {
+		return nil, fmt.Errorf("not a delta bundle")
+	}
+	if baseIndex.IsDelta() || deltaIndex.BaseID != baseIndex.ID() {
+		return nil, fmt.Errorf("delta bundle was not made against this base bundle")
+	}
+
+	merged := &BundleIndex{
+		Format:   BundleFormatVersion,
+		Created:  deltaIndex.Created,
+		RootURLs: deltaIndex.RootURLs,
+		Entries:  make([]*BundleEntry, 0, len(deltaIndex.Entries)),
+	}
+	contents := make(map[string][]byte)
+	for _, e := range deltaIndex.Entries {
+		data := deltaContents[e.URL]
+		if e.InBase {
+			data = baseContents[e.URL]
+		}
+		merged.Entries = append(merged.Entries, &BundleEntry{URL: e.URL, Size: e.Size, SHA256: e.SHA256})
+		contents[e.SHA256] = data
+	}
+	if err := writeBundle(w, merged, contents); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
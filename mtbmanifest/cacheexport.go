@@ -0,0 +1,78 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Export writes every entry of the cache to w, in the offline bundle format (see bundle.go).
+// Unlike a bundle, the export keeps the time each entry was cached, so an imported cache ages
+// and refreshes exactly like the original. CI systems can persist the export as a build
+// artifact and Import it in the next run instead of re-downloading everything.
+func (c *ManifestCache) Export(w io.Writer) (*BundleIndex, error) {
+	index := &BundleIndex{
+		Format:   BundleFormatVersion,
+		Created:  time.Now().UTC(),
+		RootURLs: []string{},
+		Entries:  []*BundleEntry{},
+	}
+	contents := make(map[string][]byte)
+	infos, err := c.store.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		data, err := c.store.Get(info.URL)
+		if err != nil {
+			logger.Warningf("Skipping unreadable cache entry %s: %v\n", info.URL, err)
+			continue
+		}
+		modTime := info.ModTime.UTC()
+		entry := &BundleEntry{URL: info.URL, Size: len(data), SHA256: hashContent(data), ModTime: &modTime}
+		index.Entries = append(index.Entries, entry)
+		contents[entry.SHA256] = data
+	}
+	sort.Slice(index.Entries, func(i, j int) bool { return index.Entries[i].URL < index.Entries[j].URL })
+	if err := writeBundle(w, index, contents); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Import reads an export written by Export (or an offline bundle) and stores its entries in
+// the cache. Entries keep the time they were originally cached when the export has it.
+// Existing entries for the same URLs are replaced. A delta bundle can be imported when the
+// cache already holds its unchanged entries, i.e. the base bundle was installed before.
+// PlanImport tells what Import would change. A cache that verifies signatures (see
+// WithSignatureVerification) checks those of every entry first and imports nothing unless all
+// verify.
+func (c *ManifestCache) Import(r io.Reader) (*BundleIndex, error) {
+	index, contents, err := ReadBundle(r)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := c.planImport(index, contents)
+	if err != nil {
+		return nil, err
+	}
+	imported := make(map[string][]byte, len(changes))
+	for _, change := range changes {
+		imported[change.URL] = contents[change.URL]
+	}
+	if err := c.verifyEntries(c.ctx, imported); err != nil {
+		return nil, fmt.Errorf("refusing to import: %w", err)
+	}
+	for _, change := range changes {
+		entry, _ := index.GetEntry(change.URL)
+		modTime := time.Time{}
+		if entry.ModTime != nil {
+			modTime = *entry.ModTime
+		}
+		if err := c.store.Put(entry.URL, contents[entry.URL], modTime); err != nil {
+			return nil, fmt.Errorf("failed to import %s: %v", entry.URL, err)
+		}
+	}
+	return index, nil
+}
@@ -0,0 +1,45 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCacheExportImport(t *testing.T) {
+	source := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer source.Close()
+	urls := []string{"https://example.com/a.xml", "https://example.com/b.xml"}
+	old := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	for _, u := range urls {
+		if err := source.writeCache(u, bytes.Repeat([]byte(u), 1000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = source.Store().Put(urls[1], bytes.Repeat([]byte(urls[1]), 1000), old)
+
+	var buf bytes.Buffer
+	index, err := source.Export(&buf)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(index.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(index.Entries))
+	}
+
+	target := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer target.Close()
+	if _, err := target.Import(&buf); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	for _, u := range urls {
+		data, err := target.readCache(u)
+		if err != nil || !bytes.Equal(data, bytes.Repeat([]byte(u), 1000)) {
+			t.Errorf("expected %s to be imported: %v", u, err)
+		}
+	}
+	info, _ := target.Store().Stat(urls[1])
+	if !info.ModTime.Equal(old) {
+		t.Errorf("expected the cache time %v to be kept, got %v", old, info.ModTime)
+	}
+}
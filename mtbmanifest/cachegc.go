@@ -0,0 +1,142 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Cache garbage collection
+// ////////////////////////////////////////////////////////////////////////
+
+// The TTL of a cache entry decides when it is fetched again, never when it goes away: the
+// manifests of a channel that was removed, or that a super manifest stopped listing, stay in
+// the cache forever. Garbage collection reads the tree of every channel of a ChannelSet from
+// each cache, offline, and removes the entries none of those trees refers to (see
+// SuperManifest.ReferencedURLs). A cache no tree can be read from is left alone, as its
+// entries may belong to a tree the set does not know of, except for the directory of a
+// channel that is no longer in the set, which is emptied and removed.
+
+// CacheGC is what garbage collection removes from one cache
+type CacheGC struct {
+	// Cache is the directory of the cache, or the type of its store
+	Cache string `json:"cache"`
+	// Channels are the channels whose tree was read from the cache
+	Channels []string `json:"channels"`
+	// Referenced is the number of URLs those trees refer to
+	Referenced int `json:"referenced"`
+	// Removed is set for the directory of a channel no longer in the set
+	Removed bool `json:"removed,omitempty"`
+	// Skipped tells why the cache was left alone
+	Skipped string         `json:"skipped,omitempty"`
+	Changes []*CacheChange `json:"changes"`
+	store   CacheStore
+	dir     string
+}
+
+// Size returns the number of bytes the changes free
+func (g *CacheGC) Size() int {
+	n := 0
+	for _, change := range g.Changes {
+		n += change.Size
+	}
+	return n
+}
+
+// PlanGC returns what GC would remove, cache by cache: first the store given, nil meaning
+// files in the cache directory of the set, then the directories of the channels, by name
+func (cs *ChannelSet) PlanGC(store CacheStore) ([]*CacheGC, error) {
+	if store == nil {
+		store = NewFileStore(cs.cacheDir)
+	}
+	main, err := cs.planGC(store, false)
+	if err != nil {
+		return nil, err
+	}
+	ret := []*CacheGC{main}
+	dirEntries, err := os.ReadDir(filepath.Join(cs.cacheDir, channelsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	names := []string{}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			names = append(names, dirEntry.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, known := cs.Channel(name)
+		gc, err := cs.planGC(NewFileStore(cs.CacheDir(name)), !known)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, gc)
+	}
+	return ret, nil
+}
+
+// planGC plans the collection of one store. Everything goes from the store of a removed
+// channel that no tree is read from.
+func (cs *ChannelSet) planGC(store CacheStore, removed bool) (*CacheGC, error) {
+	gc := &CacheGC{Cache: fmt.Sprintf("%T", store), Channels: []string{}, Changes: []*CacheChange{}, store: store}
+	readStore := store
+	if fs, ok := store.(*FileStore); ok {
+		gc.Cache, gc.dir = fs.Dir(), fs.Dir()
+		readStore = fs.ReadOnly()
+	}
+	cache := NewManifestCache(WithStore(readStore), WithNoBackgroundRefresh())
+	defer cache.Close()
+	referenced := make(map[string]bool)
+	for _, ch := range cs.channels {
+		for _, urlStr := range ch.URLs {
+			if _, err := readStore.Stat(urlStr); err != nil {
+				continue // never read into this cache
+			}
+			sm, err := NewSuperManifestFromURL(urlStr, WithOffline(),
+				WithFetcher(NewManifestFetcher(WithCache(cache))))
+			if err != nil {
+				return nil, fmt.Errorf("cannot tell what %s refers to: %v", urlStr, err)
+			}
+			for _, ref := range sm.ReferencedURLs() {
+				referenced[ref.URL] = true
+			}
+			if len(gc.Channels) == 0 || gc.Channels[len(gc.Channels)-1] != ch.Name {
+				gc.Channels = append(gc.Channels, ch.Name)
+			}
+		}
+	}
+	gc.Referenced = len(referenced)
+	if len(referenced) == 0 && !removed {
+		gc.Skipped = "no tree of the channels is cached here"
+		return gc, nil
+	}
+	gc.Removed = removed && len(referenced) == 0
+	changes, err := cache.planRemove(func(entry *CacheEntryInfo) bool { return !referenced[entry.URL] })
+	if err != nil {
+		return nil, err
+	}
+	gc.Changes = changes
+	return gc, nil
+}
+
+// GC removes the cache entries no tree of the channels refers to (see PlanGC)
+func (cs *ChannelSet) GC(store CacheStore) ([]*CacheGC, error) {
+	plan, err := cs.PlanGC(store)
+	if err != nil {
+		return nil, err
+	}
+	for _, gc := range plan {
+		for _, change := range gc.Changes {
+			if err := gc.store.Delete(change.URL); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		if gc.Removed && gc.dir != "" {
+			_ = os.Remove(gc.dir) // only if nothing else is left in it
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,80 @@
+package mtbmanifest
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestChannelSetGC(t *testing.T) {
+	dir := t.TempDir()
+	cs, err := NewChannelSet(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := func(cacheDir string, files map[string]string) {
+		cache := NewManifestCache(WithDir(cacheDir), WithCacheTTL(time.Hour))
+		defer cache.Close()
+		for u, data := range files {
+			if err := cache.writeCache(u, []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	prodTree := map[string]string{
+		SuperManifestURL:                     testSuperXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+		"https://example.com/dropped.xml":    testBoardsXML,
+	}
+	seed(dir, prodTree)
+	seed(cs.CacheDir(DefaultChannel), prodTree)
+	seed(cs.CacheDir("gone"), map[string]string{"https://example.com/gone/super.xml": testSuperXML})
+
+	plan, err := cs.PlanGC(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 3 {
+		t.Fatalf("expected the main cache and two channel caches, got %d", len(plan))
+	}
+	main, gone, prod := plan[0], plan[1], plan[2]
+	if main.Cache != dir || len(main.Channels) != 1 || main.Channels[0] != DefaultChannel ||
+		len(main.Changes) != 1 || main.Changes[0].URL != "https://example.com/dropped.xml" {
+		t.Errorf("unexpected plan for the main cache %+v", main)
+	}
+	if !gone.Removed || len(gone.Changes) != 1 {
+		t.Errorf("expected the cache of the removed channel to be emptied, got %+v", gone)
+	}
+	if prod.Removed || len(prod.Changes) != 1 || prod.Size() == 0 {
+		t.Errorf("unexpected plan for the prod cache %+v", prod)
+	}
+	if _, err := NewFileStore(dir).Stat("https://example.com/dropped.xml"); err != nil {
+		t.Fatal("expected the plan to remove nothing")
+	}
+
+	if _, err := cs.GC(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(dir).Stat("https://example.com/dropped.xml"); err == nil {
+		t.Error("expected the unreferenced entry to be removed")
+	}
+	if _, err := NewFileStore(dir).Stat(SuperManifestURL); err != nil {
+		t.Error("expected the super manifest to stay")
+	}
+	if _, err := os.Stat(cs.CacheDir("gone")); !os.IsNotExist(err) {
+		t.Error("expected the directory of the removed channel to be removed")
+	}
+
+	// A cache no tree is read from is left alone
+	empty, err := NewChannelSet(nil, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed(empty.cacheDir, map[string]string{"https://example.com/other.xml": testBoardsXML})
+	plan, err = empty.PlanGC(nil)
+	if err != nil || len(plan) != 1 || plan[0].Skipped == "" || len(plan[0].Changes) != 0 {
+		t.Errorf("expected the cache to be skipped, got %+v, %v", plan, err)
+	}
+}
@@ -0,0 +1,332 @@
+package mtbmanifest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntryInfo describes one entry of a CacheStore
+type CacheEntryInfo struct {
+	URL string
+	// Size is the number of bytes the entry takes in the store, which may be compressed
+	Size int
+	// ModTime is when the entry was stored. The cache judges staleness by it.
+	ModTime time.Time
+}
+
+// CacheStore is where a ManifestCache keeps its entries, keyed by URL. The default is a
+// FileStore; a MemoryStore suits tests and short-lived servers. Implementations must be safe
+// for concurrent use. Get, Stat and Delete return an error matching fs.ErrNotExist for a URL
+// that is not in the store.
+type CacheStore interface {
+	// Get returns the content stored for a URL
+	Get(urlStr string) ([]byte, error)
+	// Put stores content for a URL. A zero modTime means now.
+	Put(urlStr string, data []byte, modTime time.Time) error
+	// Stat describes the entry for a URL without reading its content
+	Stat(urlStr string) (*CacheEntryInfo, error)
+	// List describes every entry, ordered by URL
+	List() ([]*CacheEntryInfo, error)
+	// Delete removes the entry for a URL
+	Delete(urlStr string) error
+}
+
+// defaultCacheStore, when set, backs every cache the library creates for itself, e.g. while
+// ingesting with NewSuperManifestFromURL. nil means a FileStore in the default directory.
+var defaultCacheStore CacheStore
+
+// SetDefaultCacheStore sets the store used by default caches. Pass nil to go back to files in
+// ~/.modustoolbox/mtbmcp/manifests.
+func SetDefaultCacheStore(store CacheStore) {
+	defaultCacheStore = store
+}
+
+// ////////////////////////////////////////////////////////////////////////
+// FileStore
+// ////////////////////////////////////////////////////////////////////////
+
+// FileStore keeps each entry in its own file in a directory. Files start with a small header
+// holding the URL (see CacheHeader), and large content is gzip compressed.
+type FileStore struct {
+	dir string
+	// readOnly stores refuse to write and leave legacy files as they are (see ReadOnly)
+	readOnly bool
+}
+
+// NewFileStore creates a store in dir. The directory is created on the first Put.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Dir returns the directory the store keeps its files in
+func (store *FileStore) Dir() string {
+	return store.dir
+}
+
+// ReadOnly returns a view of the store that reads the same files but never changes them:
+// legacy files are read without being converted, and Put and Delete fail. Dry runs ingest
+// through it.
+func (store *FileStore) ReadOnly() *FileStore {
+	return &FileStore{dir: store.dir, readOnly: true}
+}
+
+// errReadOnly is returned by the writes of a read-only store
+func (store *FileStore) errReadOnly(urlStr string) error {
+	return fmt.Errorf("%s: cache in %s is read-only", urlStr, store.dir)
+}
+
+func (store *FileStore) urlToFilename(urlStr string) string {
+	parsed, _ := url.Parse(urlStr)
+	name := parsed.Host + parsed.Path
+	name = strings.ReplaceAll(name, "/", "_")
+	name = strings.ReplaceAll(name, ":", "_")
+	name = strings.ReplaceAll(name, "?", "_")
+	return filepath.Join(store.dir, name)
+}
+
+func (store *FileStore) Get(urlStr string) ([]byte, error) {
+	return store.readFile(urlStr, logger)
+}
+
+// Files written by early versions hold just the content, without a header. They are
+// converted to the current format when read, keeping their age. Those that are never read
+// again are invisible to List; LegacyFiles finds them and RemoveLegacyFiles cleans them up.
+
+// migrateLegacyFile converts a headerless cache file to the current format, telling logger
+// how that went, and returns its content
+func (store *FileStore) migrateLegacyFile(urlStr, filename string, logger LoggerIF) ([]byte, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || store.readOnly {
+		return data, err
+	}
+	if err := store.Put(urlStr, data, info.ModTime()); err != nil {
+		logger.Warningf("Failed to migrate legacy cache file %s: %v\n", filename, err)
+	} else {
+		logger.Infof("Migrated legacy cache file %s\n", filename)
+	}
+	return data, nil
+}
+
+// legacyFileName matches the names early versions gave cache files: the host, with its port
+// if any, and the path of a manifest URL, joined by underscores (see urlToFilename), e.g.
+// github.com_Infineon_mtb-super-manifest_raw_v2.X_mtb-super-manifest-fv2.xml
+var legacyFileName = regexp.MustCompile(`^[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)+(_[0-9]+)?_.+\.(xml|json)$`)
+
+// isLegacyFile reports whether a file is a cache file of an early version: named as one and
+// lacking the cache header. Other files in the directory are not the store's to touch.
+func isLegacyFile(filename string) bool {
+	if !legacyFileName.MatchString(filepath.Base(filename)) {
+		return false
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	var header CacheHeader
+	return binary.Read(f, binary.BigEndian, &header) != nil || header.Magic != cacheMagic
+}
+
+// LegacyFiles returns the files of the store that are still in the headerless format
+func (store *FileStore) LegacyFiles() ([]string, error) {
+	dirEntries, err := os.ReadDir(store.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	ret := []string{}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || strings.HasSuffix(dirEntry.Name(), ".tmp") {
+			continue
+		}
+		if filename := filepath.Join(store.dir, dirEntry.Name()); isLegacyFile(filename) {
+			ret = append(ret, filename)
+		}
+	}
+	return ret, nil
+}
+
+// tempFiles returns the temporary files in the store: name.*.tmp as writeFile makes them, and
+// name.tmp as earlier versions did
+func (store *FileStore) tempFiles() ([]string, error) {
+	return filepath.Glob(filepath.Join(store.dir, "*.tmp"))
+}
+
+// RemoveTempFiles deletes the temporary files of writes that were cut short, by a process
+// that was killed, and reports how many there were. Files modified in the last minute may
+// belong to a write in progress and are kept.
+func (store *FileStore) RemoveTempFiles() (int, error) {
+	files, err := store.tempFiles()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, filename := range files {
+		if info, err := os.Stat(filename); err != nil || time.Since(info.ModTime()) < time.Minute {
+			continue
+		}
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RemoveLegacyFiles deletes the files LegacyFiles returns and reports how many there were
+func (store *FileStore) RemoveLegacyFiles() (int, error) {
+	files, err := store.LegacyFiles()
+	if err != nil {
+		return 0, err
+	}
+	for _, filename := range files {
+		if err := os.Remove(filename); err != nil {
+			return 0, err
+		}
+	}
+	return len(files), nil
+}
+
+func (store *FileStore) Put(urlStr string, data []byte, modTime time.Time) error {
+	if store.readOnly {
+		return store.errReadOnly(urlStr)
+	}
+	if err := store.writeFile(urlStr, data); err != nil {
+		return err
+	}
+	if !modTime.IsZero() {
+		return os.Chtimes(store.urlToFilename(urlStr), modTime, modTime)
+	}
+	return nil
+}
+
+func (store *FileStore) Stat(urlStr string) (*CacheEntryInfo, error) {
+	info, err := os.Stat(store.urlToFilename(urlStr))
+	if err != nil {
+		return nil, err
+	}
+	return &CacheEntryInfo{URL: urlStr, Size: int(info.Size()), ModTime: info.ModTime()}, nil
+}
+
+func (store *FileStore) List() ([]*CacheEntryInfo, error) {
+	dirEntries, err := os.ReadDir(store.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*CacheEntryInfo{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	ret := []*CacheEntryInfo{}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || strings.HasSuffix(dirEntry.Name(), ".tmp") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		urlStr, err := store.readUrlFromFile(filepath.Join(store.dir, dirEntry.Name()))
+		if err != nil || urlStr == "" {
+			continue // not one of ours, or corrupt
+		}
+		ret = append(ret, &CacheEntryInfo{URL: urlStr, Size: int(info.Size()), ModTime: info.ModTime()})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].URL < ret[j].URL })
+	return ret, nil
+}
+
+func (store *FileStore) Delete(urlStr string) error {
+	if store.readOnly {
+		return store.errReadOnly(urlStr)
+	}
+	return os.Remove(store.urlToFilename(urlStr))
+}
+
+// ////////////////////////////////////////////////////////////////////////
+// MemoryStore
+// ////////////////////////////////////////////////////////////////////////
+
+// MemoryStore keeps entries in memory. Nothing survives the process.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func notInStore(urlStr string) error {
+	return fmt.Errorf("%s: %w", urlStr, fs.ErrNotExist)
+}
+
+func (ms *MemoryStore) Get(urlStr string) ([]byte, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	entry, ok := ms.entries[urlStr]
+	if !ok {
+		return nil, notInStore(urlStr)
+	}
+	return append([]byte(nil), entry.data...), nil
+}
+
+func (ms *MemoryStore) Put(urlStr string, data []byte, modTime time.Time) error {
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.entries[urlStr] = &memoryEntry{data: append([]byte(nil), data...), modTime: modTime}
+	return nil
+}
+
+func (ms *MemoryStore) Stat(urlStr string) (*CacheEntryInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	entry, ok := ms.entries[urlStr]
+	if !ok {
+		return nil, notInStore(urlStr)
+	}
+	return &CacheEntryInfo{URL: urlStr, Size: len(entry.data), ModTime: entry.modTime}, nil
+}
+
+func (ms *MemoryStore) List() ([]*CacheEntryInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	ret := make([]*CacheEntryInfo, 0, len(ms.entries))
+	for urlStr, entry := range ms.entries {
+		ret = append(ret, &CacheEntryInfo{URL: urlStr, Size: len(entry.data), ModTime: entry.modTime})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].URL < ret[j].URL })
+	return ret, nil
+}
+
+func (ms *MemoryStore) Delete(urlStr string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.entries[urlStr]; !ok {
+		return notInStore(urlStr)
+	}
+	delete(ms.entries, urlStr)
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build js
+
+package mtbmanifest
+
+// In a browser there is no file system to keep the cache in, so caches without a store of
+// their own share one in memory, for the life of the page
+
+var jsCacheStore = NewMemoryStore()
+
+// platformCacheStore returns the store of caches created without one
+func platformCacheStore() CacheStore {
+	return jsCacheStore
+}
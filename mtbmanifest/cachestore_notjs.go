@@ -0,0 +1,9 @@
+//go:build !js
+
+package mtbmanifest
+
+// platformCacheStore returns the store of caches created without one: files in
+// DefaultCacheDir
+func platformCacheStore() CacheStore {
+	return NewFileStore(DefaultCacheDir())
+}
@@ -0,0 +1,192 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testCacheStore(t *testing.T, store CacheStore) {
+	t.Helper()
+	const a, b = "https://example.com/a.xml", "https://example.com/b.xml"
+	large := bytes.Repeat([]byte("<board/>"), 4096) // big enough to be compressed by FileStore
+
+	if _, err := store.Get(a); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing entry, got %v", err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := store.Put(a, large, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(b, []byte("small"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := store.Get(a); err != nil || !bytes.Equal(data, large) {
+		t.Errorf("Get returned different content: %v", err)
+	}
+	if info, err := store.Stat(a); err != nil || !info.ModTime.Equal(old) {
+		t.Errorf("expected ModTime %v, got %+v, %v", old, info, err)
+	}
+	if info, err := store.Stat(b); err != nil || time.Since(info.ModTime) > time.Minute {
+		t.Errorf("expected a zero modTime to mean now, got %+v, %v", info, err)
+	}
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].URL != a || list[1].URL != b {
+		t.Errorf("unexpected List result %+v, %v", list, err)
+	}
+
+	if err := store.Delete(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Stat(a); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the entry to be gone, got %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	testCacheStore(t, NewFileStore(t.TempDir()))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testCacheStore(t, NewMemoryStore())
+}
+
+func TestManifestCacheOnMemoryStore(t *testing.T) {
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	_ = cache.Store().Put("https://example.com/a.xml", []byte("cached"), time.Time{})
+	if data, err := cache.Get("https://example.com/a.xml"); err != nil || string(data) != "cached" {
+		t.Errorf("expected a cache hit, got %q, %v", data, err)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := cache.Store().List(); len(list) != 0 {
+		t.Errorf("expected Clear to empty the store, %d entries left", len(list))
+	}
+}
+
+func TestFileStoreLegacyMigration(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	const legacyURL, orphanURL = "https://example.com/boards.xml", "https://example.com/orphan.xml"
+	old := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	for _, u := range []string{legacyURL, orphanURL} {
+		filename := store.urlToFilename(u)
+		if err := os.WriteFile(filename, []byte("<boards/>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(filename, old, old)
+	}
+	// Files of others in the directory are not legacy cache files, whatever their content
+	others := []string{"gomtb-manifest.lock", "notes.txt", "my_notes.xml", ".DS_Store"}
+	for _, name := range others {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("mine"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if files, err := store.LegacyFiles(); err != nil || len(files) != 2 {
+		t.Fatalf("expected two legacy files, got %v, %v", files, err)
+	}
+
+	if data, err := store.Get(legacyURL); err != nil || string(data) != "<boards/>" {
+		t.Fatalf("expected the legacy content, got %q, %v", data, err)
+	}
+	if info, err := store.Stat(legacyURL); err != nil || !info.ModTime.Equal(old) {
+		t.Errorf("migration must keep the age of the entry, got %+v, %v", info, err)
+	}
+	if list, err := store.List(); err != nil || len(list) != 1 || list[0].URL != legacyURL {
+		t.Errorf("expected the migrated entry to be listed, got %v, %v", list, err)
+	}
+
+	if n, err := store.RemoveLegacyFiles(); err != nil || n != 1 {
+		t.Errorf("expected the orphan to be removed, got %d, %v", n, err)
+	}
+	if _, err := store.Get(orphanURL); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the orphan to be gone, got %v", err)
+	}
+	for _, name := range others {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be kept, got %v", name, err)
+		}
+	}
+}
+
+func TestLegacyMigrationLogsToCacheLogger(t *testing.T) {
+	dir := t.TempDir()
+	const legacyURL = "https://example.com/boards.xml"
+	if err := os.WriteFile(NewFileStore(dir).urlToFilename(legacyURL), []byte("<boards/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	log := &messageLogger{}
+	cache := NewManifestCache(WithDir(dir), WithCacheTTL(time.Hour), WithCacheLogger(log))
+	defer cache.Close()
+	if data, err := cache.Get(legacyURL); err != nil || string(data) != "<boards/>" {
+		t.Fatalf("expected the legacy content, got %q, %v", data, err)
+	}
+	if log.count("Migrated legacy cache file") != 1 {
+		t.Errorf("expected the cache logger to hear of the migration, got %q", log.messages)
+	}
+}
+
+func TestFileStoreRemoveTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	if err := store.Put("https://example.com/boards.xml", []byte("<boards/>"), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	// One left by an earlier version, one by a write in progress
+	left, writing := store.urlToFilename("https://example.com/apps.xml")+".tmp", store.urlToFilename("https://example.com/mw.xml")+".123456.tmp"
+	for _, filename := range []string{left, writing} {
+		if err := os.WriteFile(filename, []byte("<ap"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(left, old, old)
+	if n, err := store.RemoveTempFiles(); err != nil || n != 1 {
+		t.Fatalf("expected the old temporary file to be removed, got %d, %v", n, err)
+	}
+	if _, err := os.Stat(left); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %s to be gone, got %v", left, err)
+	}
+	if _, err := os.Stat(writing); err != nil {
+		t.Errorf("expected the recent temporary file to be kept, got %v", err)
+	}
+	if data, err := store.Get("https://example.com/boards.xml"); err != nil || string(data) != "<boards/>" {
+		t.Errorf("expected the entry to be kept, got %q, %v", data, err)
+	}
+}
+
+func TestFileStoreConcurrentPut(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	const urlStr = "https://example.com/boards.xml"
+	contents := map[string]bool{}
+	var wg sync.WaitGroup
+	for i := range 8 {
+		content := strings.Repeat(string(rune('a'+i)), 64<<10)
+		contents[content] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Put(urlStr, []byte(content), time.Time{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if data, err := store.Get(urlStr); err != nil || !contents[string(data)] {
+		t.Errorf("expected the content of one of the writes, got %d bytes, %v", len(data), err)
+	}
+	if temps, _ := store.tempFiles(); len(temps) != 0 {
+		t.Errorf("expected no temporary files left, got %v", temps)
+	}
+}
@@ -0,0 +1,259 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Release cadence and staleness
+// ////////////////////////////////////////////////////////////////////////
+
+// The manifests list versions but not when they were released. The stored snapshots know it
+// to within the time between two of them: a version was released between the last snapshot
+// without it and the first with it ('archive import' fills the store from the git history of
+// the manifest repositories). A ReleaseHistory records those first sightings; ReleaseCadences
+// turns them, with the version timelines, into the mean time between releases and the age of
+// the last release of every item, and flags the items that have not shipped in over a year.
+
+// ReleaseHistory tells when versions first appeared in a series of manifest trees
+type ReleaseHistory struct {
+	// Since is when the oldest tree was made. Versions already in it were released then or
+	// earlier, at a time not known.
+	Since     time.Time
+	firstSeen map[string]time.Time
+}
+
+// NewReleaseHistory creates an empty history; add trees to it with Add
+func NewReleaseHistory() *ReleaseHistory {
+	return &ReleaseHistory{firstSeen: map[string]time.Time{}}
+}
+
+// releaseKey identifies a version of an item across trees
+func releaseKey(kind ItemKind, id, commit string) string {
+	return string(kind) + "/" + idKey(id) + "@" + commit
+}
+
+// Add records the versions of a tree made at created. Trees are added oldest first.
+func (h *ReleaseHistory) Add(created time.Time, sm SuperManifestIF) {
+	if h.Since.IsZero() || created.Before(h.Since) {
+		h.Since = created
+	}
+	record := func(t *Timeline) {
+		for _, e := range t.Entries {
+			key := releaseKey(t.Kind, t.ID, e.Commit)
+			if seen, ok := h.firstSeen[key]; !ok || created.Before(seen) {
+				h.firstSeen[key] = created
+			}
+		}
+	}
+	for _, b := range sm.Boards() {
+		record(b.Timeline())
+	}
+	for _, a := range sm.Apps() {
+		record(a.Timeline())
+	}
+	for _, mw := range sm.Middleware() {
+		record(mw.Timeline())
+	}
+}
+
+// ReleasedAt returns when a version was first seen, if it was seen after the oldest tree
+func (h *ReleaseHistory) ReleasedAt(kind ItemKind, id, commit string) (time.Time, bool) {
+	seen, ok := h.firstSeen[releaseKey(kind, id, commit)]
+	if !ok || !seen.After(h.Since) {
+		return time.Time{}, false
+	}
+	return seen, true
+}
+
+// ReleaseHistoryFromSnapshots builds the history of the full snapshots of a store. With
+// rootURLs, only snapshots made from all of those super manifests are read.
+func ReleaseHistoryFromSnapshots(store *SnapshotStore, rootURLs ...string) (*ReleaseHistory, error) {
+	list, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	h := NewReleaseHistory()
+	for _, index := range list {
+		if index.IsDelta() || !containsAll(index.RootURLs, rootURLs) {
+			continue
+		}
+		sm, err := store.Load(index.ID())
+		if err != nil {
+			logger.Warningf("Skipping snapshot %s: %v\n", index.ID(), err)
+			continue
+		}
+		h.Add(index.Created, sm)
+	}
+	if len(h.firstSeen) == 0 {
+		return nil, fmt.Errorf("no snapshots to date releases from in %s", store.Dir())
+	}
+	return h, nil
+}
+
+// ReleaseCadence is the release rhythm of one item
+type ReleaseCadence struct {
+	Kind     ItemKind `json:"kind"`
+	ID       string   `json:"id"`
+	Category string   `json:"category,omitempty"`
+	// Releases is the number of numbered releases listed; refs that move, such as
+	// latest-v4.X, are not releases
+	Releases int `json:"releases"`
+	// Dated is how many of them have a known release time
+	Dated int `json:"dated"`
+	// MeanInterval is the mean time between dated releases, 0 with fewer than two
+	MeanInterval time.Duration `json:"meanInterval"`
+	// LastRelease is when the newest release came out. When it predates the history, it is
+	// the start of the history and AgeAtLeast is set.
+	LastRelease time.Time     `json:"lastRelease"`
+	Age         time.Duration `json:"age"`
+	AgeAtLeast  bool          `json:"ageAtLeast,omitempty"`
+	Stale       bool          `json:"stale"`
+}
+
+// StalenessOptions controls ReleaseCadences
+type StalenessOptions struct {
+	// Kinds are the kinds of items reported. Empty means middleware only.
+	Kinds []ItemKind
+	// StaleAfter is how long without a release makes an item stale. 0 means 365 days.
+	StaleAfter time.Duration
+	// Now is the time ages are measured at. Zero means time.Now().
+	Now time.Time
+}
+
+// StalenessReport is the result of ReleaseCadences
+type StalenessReport struct {
+	Generated  time.Time     `json:"generated"`
+	Since      time.Time     `json:"since"`
+	StaleAfter time.Duration `json:"staleAfter"`
+	// Items are the stale items first, each group oldest last release first
+	Items []*ReleaseCadence `json:"items"`
+}
+
+// Stale returns the stale items of the report
+func (r *StalenessReport) Stale() []*ReleaseCadence {
+	ret := []*ReleaseCadence{}
+	for _, c := range r.Items {
+		if c.Stale {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// ReleaseCadences measures the release cadence of every item of the tree, dating its
+// releases with the history
+func ReleaseCadences(sm SuperManifestIF, h *ReleaseHistory, opts *StalenessOptions) *StalenessReport {
+	if opts == nil {
+		opts = &StalenessOptions{}
+	}
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = []ItemKind{ItemKindMiddleware}
+	}
+	staleAfter, now := opts.StaleAfter, opts.Now
+	if staleAfter == 0 {
+		staleAfter = 365 * 24 * time.Hour
+	}
+	if now.IsZero() {
+		now = time.Now()
+	}
+	report := &StalenessReport{Generated: now, Since: h.Since, StaleAfter: staleAfter, Items: []*ReleaseCadence{}}
+	add := func(t *Timeline, category string) {
+		c := &ReleaseCadence{Kind: t.Kind, ID: t.ID, Category: category}
+		dates := []time.Time{}
+		for _, e := range t.Entries {
+			if e.Version == nil || e.Ref.Moves() {
+				continue
+			}
+			c.Releases++
+			if at, ok := h.ReleasedAt(t.Kind, t.ID, e.Commit); ok {
+				dates = append(dates, at)
+			}
+		}
+		if c.Releases == 0 {
+			return
+		}
+		c.Dated = len(dates)
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		if len(dates) > 1 {
+			c.MeanInterval = dates[len(dates)-1].Sub(dates[0]) / time.Duration(len(dates)-1)
+		}
+		if len(dates) > 0 {
+			c.LastRelease = dates[len(dates)-1]
+		} else {
+			c.LastRelease, c.AgeAtLeast = h.Since, true
+		}
+		c.Age = now.Sub(c.LastRelease)
+		c.Stale = c.Age > staleAfter
+		report.Items = append(report.Items, c)
+	}
+	for _, kind := range kinds {
+		switch kind {
+		case ItemKindBoard:
+			for _, b := range sm.Boards() {
+				add(b.Timeline(), b.Category)
+			}
+		case ItemKindApp:
+			for _, a := range sm.Apps() {
+				add(a.Timeline(), "")
+			}
+		case ItemKindMiddleware:
+			for _, mw := range sm.Middleware() {
+				add(mw.Timeline(), mw.Category)
+			}
+		}
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.Stale != b.Stale {
+			return a.Stale
+		}
+		if !a.LastRelease.Equal(b.LastRelease) {
+			return a.LastRelease.Before(b.LastRelease)
+		}
+		return a.ID < b.ID
+	})
+	return report
+}
+
+// days formats a duration as a number of days
+func days(d time.Duration) string {
+	return fmt.Sprintf("%.0f days", d.Hours()/24)
+}
+
+// WriteMarkdown writes the report as a Markdown staleness report: the stale items, then the
+// cadence of the others
+func (r *StalenessReport) WriteMarkdown(w io.Writer) error {
+	stale := r.Stale()
+	if _, err := fmt.Fprintf(w, "# Release staleness report\n\nGenerated %s from releases seen since %s. Items without a release in %s are stale.\n\n",
+		r.Generated.Format(time.DateOnly), r.Since.Format(time.DateOnly), days(r.StaleAfter)); err != nil {
+		return err
+	}
+	table := func(items []*ReleaseCadence) {
+		fmt.Fprintf(w, "| Item | Category | Releases | Mean interval | Last release | Age |\n|---|---|---|---|---|---|\n")
+		for _, c := range items {
+			interval, last, age := "-", c.LastRelease.Format(time.DateOnly), days(c.Age)
+			if c.MeanInterval > 0 {
+				interval = days(c.MeanInterval)
+			}
+			if c.AgeAtLeast {
+				last, age = "before "+last, "over "+age
+			}
+			fmt.Fprintf(w, "| %s | %s | %d (%d dated) | %s | %s | %s |\n", c.ID, c.Category, c.Releases, c.Dated,
+				interval, last, age)
+		}
+	}
+	fmt.Fprintf(w, "## Stale (%d)\n\n", len(stale))
+	if len(stale) == 0 {
+		fmt.Fprintf(w, "Every item has shipped recently.\n")
+	} else {
+		table(stale)
+	}
+	fmt.Fprintf(w, "\n## Active (%d)\n\n", len(r.Items)-len(stale))
+	table(r.Items[len(stale):])
+	return nil
+}
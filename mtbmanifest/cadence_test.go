@@ -0,0 +1,63 @@
+package mtbmanifest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReleaseCadences(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	wcm, _ := sm.GetMiddleware("wifi-connection-manager")
+	freertos, _ := sm.GetMiddleware("freertos")
+	setVersions := func(mw *MiddlewareItem, commits ...string) {
+		mw.Versions = &MWVersions{}
+		for _, c := range commits {
+			mw.Versions.Version = append(mw.Versions.Version, &MWVersion{Num: c, Commit: c})
+		}
+	}
+	jan, mar, sep := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	h := NewReleaseHistory()
+	setVersions(wcm, "release-v3.0.0", "latest-v3.X")
+	setVersions(freertos, "release-v10.5.0")
+	h.Add(jan, sm)
+	setVersions(wcm, "release-v3.0.0", "release-v3.1.0", "latest-v3.X")
+	h.Add(mar, sm)
+	setVersions(wcm, "release-v3.0.0", "release-v3.1.0", "release-v3.2.0", "latest-v3.X")
+	h.Add(sep, sm)
+
+	if _, ok := h.ReleasedAt(ItemKindMiddleware, "wifi-connection-manager", "release-v3.0.0"); ok {
+		t.Error("expected no date for a release older than the history")
+	}
+	if at, ok := h.ReleasedAt(ItemKindMiddleware, "wifi-connection-manager", "release-v3.1.0"); !ok || !at.Equal(mar) {
+		t.Errorf("expected release-v3.1.0 dated March, got %v", at)
+	}
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := ReleaseCadences(sm, h, &StalenessOptions{Now: now})
+	if len(report.Items) != 2 || report.Items[0].ID != "freertos" {
+		t.Fatalf("expected freertos first of 2 items, got %+v", report.Items)
+	}
+	stale, active := report.Items[0], report.Items[1]
+	if !stale.Stale || !stale.AgeAtLeast || !stale.LastRelease.Equal(jan) || stale.Releases != 1 {
+		t.Errorf("expected freertos stale since before January, got %+v", stale)
+	}
+	if active.Stale || active.Releases != 3 || active.Dated != 2 || active.MeanInterval != sep.Sub(mar) ||
+		!active.LastRelease.Equal(sep) || active.Age != now.Sub(sep) {
+		t.Errorf("expected the connection manager active, got %+v", active)
+	}
+	if n := len(ReleaseCadences(sm, h, &StalenessOptions{Now: now, StaleAfter: 200 * 24 * time.Hour}).Stale()); n != 2 {
+		t.Errorf("expected both stale after 200 days, got %d", n)
+	}
+
+	var md strings.Builder
+	if err := report.WriteMarkdown(&md); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Stale (1)", "| freertos |", "before 2024-01-01 | over 517 days", "| 184 days |"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("expected %q in the report:\n%s", want, md.String())
+		}
+	}
+}
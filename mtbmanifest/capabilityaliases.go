@@ -0,0 +1,154 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Capability token aliases
+// ////////////////////////////////////////////////////////////////////////
+
+// Capability tokens get renamed from one manifest generation to the next, and apps written
+// against the old names keep requiring them while BSPs publish the new ones. An alias table
+// maps old tokens to their new names; matching (see CapabilityRequirement.Matches) treats
+// tokens leading to the same name as equal, in either direction. The built-in table holds
+// the known renames, SetCapabilityAliases adds a program's own. Every requirement met only
+// through an alias is counted, so CapabilityAliasHits tells which manifests still use old
+// names.
+
+// builtinCapabilityAliases maps old capability tokens to their new names
+var builtinCapabilityAliases = map[string]string{
+	"psoc6": "mtb-psoc6",
+	"psoc4": "mtb-psoc4",
+}
+
+var (
+	capabilityAliasesMu sync.RWMutex
+	// capabilityAliases is the built-in table with the program's aliases on top
+	capabilityAliases = copyAliases(builtinCapabilityAliases)
+
+	aliasHitsMu sync.Mutex
+	aliasHits   = map[[2]string]int{}
+)
+
+func copyAliases(aliases map[string]string) map[string]string {
+	ret := make(map[string]string, len(aliases))
+	for oldToken, newToken := range aliases {
+		ret[oldToken] = newToken
+	}
+	return ret
+}
+
+// validCapabilityToken reports whether a token can be written in a capability string
+func validCapabilityToken(token string) bool {
+	return token != "" && !strings.ContainsAny(token, " \t\r\n[],")
+}
+
+// SetCapabilityAliases sets the aliases used besides the built-in ones, as old token -> new
+// token. An alias of a built-in old token replaces it. Pass nil to use the built-in ones only.
+func SetCapabilityAliases(aliases map[string]string) error {
+	m := copyAliases(builtinCapabilityAliases)
+	for oldToken, newToken := range aliases {
+		if !validCapabilityToken(oldToken) || !validCapabilityToken(newToken) || oldToken == newToken {
+			return fmt.Errorf("invalid capability alias %q -> %q", oldToken, newToken)
+		}
+		m[oldToken] = newToken
+	}
+	capabilityAliasesMu.Lock()
+	defer capabilityAliasesMu.Unlock()
+	capabilityAliases = m
+	return nil
+}
+
+// CapabilityAliases returns the aliases in use, built-in and set, as old token -> new token
+func CapabilityAliases() map[string]string {
+	capabilityAliasesMu.RLock()
+	defer capabilityAliasesMu.RUnlock()
+	return copyAliases(capabilityAliases)
+}
+
+// CanonicalCapability returns the newest name of a capability token, following renames
+func CanonicalCapability(token string) string {
+	capabilityAliasesMu.RLock()
+	defer capabilityAliasesMu.RUnlock()
+	return canonicalCapability(capabilityAliases, token)
+}
+
+func canonicalCapability(aliases map[string]string, token string) string {
+	seen := map[string]bool{token: true}
+	for {
+		next, ok := aliases[token]
+		if !ok || seen[next] {
+			return token
+		}
+		seen[next] = true
+		token = next
+	}
+}
+
+// aliasedCapability returns the available token that is an alias of token, or "" if none is
+func aliasedCapability(token string, availableCaps map[string]bool) string {
+	capabilityAliasesMu.RLock()
+	defer capabilityAliasesMu.RUnlock()
+	if len(capabilityAliases) == 0 {
+		return ""
+	}
+	canonical := canonicalCapability(capabilityAliases, token)
+	provided := ""
+	for available, ok := range availableCaps {
+		if ok && available != token && canonicalCapability(capabilityAliases, available) == canonical &&
+			(provided == "" || available < provided) {
+			provided = available
+		}
+	}
+	return provided
+}
+
+// CapabilityAliasHit counts the requirements of one token met by an alias of it
+type CapabilityAliasHit struct {
+	// Required is the token a requirement names, Provided the alias the capabilities had
+	Required string `json:"required"`
+	Provided string `json:"provided"`
+	Count    int    `json:"count"`
+}
+
+func (h *CapabilityAliasHit) String() string {
+	return fmt.Sprintf("%s matched %s (%d times)", h.Required, h.Provided, h.Count)
+}
+
+func recordAliasHit(required, provided string) {
+	aliasHitsMu.Lock()
+	defer aliasHitsMu.Unlock()
+	aliasHits[[2]string{required, provided}]++
+}
+
+// CapabilityAliasHits returns the requirements met through aliases since the last reset, most
+// frequent first
+func CapabilityAliasHits() []*CapabilityAliasHit {
+	aliasHitsMu.Lock()
+	defer aliasHitsMu.Unlock()
+	ret := make([]*CapabilityAliasHit, 0, len(aliasHits))
+	for k, n := range aliasHits {
+		ret = append(ret, &CapabilityAliasHit{Required: k[0], Provided: k[1], Count: n})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		if ret[i].Required != ret[j].Required {
+			return ret[i].Required < ret[j].Required
+		}
+		return ret[i].Provided < ret[j].Provided
+	})
+	return ret
+}
+
+// ResetCapabilityAliasHits forgets the alias hits counted so far
+func ResetCapabilityAliasHits() {
+	aliasHitsMu.Lock()
+	defer aliasHitsMu.Unlock()
+	aliasHits = map[[2]string]int{}
+}
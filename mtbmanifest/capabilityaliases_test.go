@@ -0,0 +1,53 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestCapabilityAliases(t *testing.T) {
+	defer func() { _ = SetCapabilityAliases(nil) }()
+	ResetCapabilityAliasHits()
+
+	req := ParseCapabilities("psoc6 [capsense_button,capsense] led")
+	if !req.Matches(map[string]bool{"mtb-psoc6": true, "capsense": true, "led": true}) {
+		t.Fatal("expected the built-in alias to match the new token")
+	}
+	if !req.Matches(map[string]bool{"psoc6": true, "capsense": true, "led": true}) {
+		t.Fatal("expected the old token to match itself")
+	}
+	hits := CapabilityAliasHits()
+	if len(hits) != 1 || hits[0].Required != "psoc6" || hits[0].Provided != "mtb-psoc6" || hits[0].Count != 1 {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+
+	// Program aliases, followed through several renames and in both directions
+	if err := SetCapabilityAliases(map[string]string{"led": "user_led", "user_led": "board_led"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := CanonicalCapability("led"); got != "board_led" {
+		t.Errorf("expected led to become board_led, got %s", got)
+	}
+	if !req.Matches(map[string]bool{"mtb-psoc6": true, "capsense": true, "board_led": true}) {
+		t.Error("expected led to match board_led")
+	}
+	if newer := ParseCapabilities("user_led"); !newer.Matches(map[string]bool{"led": true}) {
+		t.Error("expected a new token to match its old name")
+	}
+	if req.Matches(map[string]bool{"mtb-psoc4": true, "capsense": true, "led": true}) {
+		t.Error("expected unrelated tokens not to match")
+	}
+	if len(CapabilityAliasHits()) != 3 {
+		t.Errorf("unexpected hits %+v", CapabilityAliasHits())
+	}
+	ResetCapabilityAliasHits()
+	if len(CapabilityAliasHits()) != 0 {
+		t.Error("expected no hits after a reset")
+	}
+
+	if err := SetCapabilityAliases(map[string]string{"bad token": "x"}); err == nil {
+		t.Error("expected a token with a space to be rejected")
+	}
+	if _, ok := CapabilityAliases()["psoc4"]; !ok {
+		t.Error("expected a rejected table to leave the built-in aliases")
+	}
+}
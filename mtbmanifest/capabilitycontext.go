@@ -0,0 +1,236 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Capability matching context
+// ////////////////////////////////////////////////////////////////////////
+
+// Whether a board suits an asset does not only depend on the tokens the board provides: some
+// tokens only mean something to newer tools, or to one build flow, and a version of an asset
+// may need tools newer than the ones installed (tools_min_version) or another flow
+// (flow_version). A CapabilityContext holds the provided tokens together with the tools
+// version and flow they are used with; matching against it ignores the tokens whose condition
+// (see SetCapabilityConditions) the context does not meet, and the versions of apps and
+// middleware it cannot build. A context without a tools version or flow does not filter on
+// it, so matching a flat token set (see CapabilityRequirement.Matches) is unchanged.
+
+// CapabilityCondition tells when a provided capability token counts
+type CapabilityCondition struct {
+	// MinToolsVersion is the oldest tools version the token counts with, e.g. 3.2.0
+	MinToolsVersion string `json:"minToolsVersion,omitempty"`
+	// Flows are the flows the token counts with; empty means all of them
+	Flows []Flow `json:"flows,omitempty"`
+}
+
+var (
+	capabilityConditionsMu sync.RWMutex
+	capabilityConditions   = map[string]*CapabilityCondition{}
+)
+
+// SetCapabilityConditions sets the conditions under which capability tokens count, by token.
+// Pass nil to make every token count everywhere.
+func SetCapabilityConditions(conditions map[string]*CapabilityCondition) error {
+	m := make(map[string]*CapabilityCondition, len(conditions))
+	for token, cond := range conditions {
+		if !validCapabilityToken(token) || cond == nil {
+			return fmt.Errorf("invalid capability condition for %q", token)
+		}
+		if cond.MinToolsVersion != "" {
+			if _, err := ParseVersion(cond.MinToolsVersion); err != nil {
+				return fmt.Errorf("capability %s: invalid tools version %q", token, cond.MinToolsVersion)
+			}
+		}
+		for _, flow := range cond.Flows {
+			if ParseFlow(string(flow)) != flow {
+				return fmt.Errorf("capability %s: unknown flow %q", token, flow)
+			}
+		}
+		copied := *cond
+		m[token] = &copied
+	}
+	capabilityConditionsMu.Lock()
+	defer capabilityConditionsMu.Unlock()
+	capabilityConditions = m
+	return nil
+}
+
+// CapabilityConditions returns the conditions in use, by token
+func CapabilityConditions() map[string]*CapabilityCondition {
+	capabilityConditionsMu.RLock()
+	defer capabilityConditionsMu.RUnlock()
+	ret := make(map[string]*CapabilityCondition, len(capabilityConditions))
+	for token, cond := range capabilityConditions {
+		copied := *cond
+		ret[token] = &copied
+	}
+	return ret
+}
+
+// CapabilityContext is what capability requirements are matched against: the tokens a board
+// provides and the tools and flow they are used with
+type CapabilityContext struct {
+	Capabilities map[string]bool
+	// ToolsVersion is the version of the tools in use, e.g. 3.6.0; "" means any
+	ToolsVersion string
+	// Flow is the build flow in use; "" means any
+	Flow Flow
+}
+
+// NewCapabilityContext creates a context of the given tokens, for any tools and flow
+func NewCapabilityContext(caps []string) *CapabilityContext {
+	ctx := &CapabilityContext{Capabilities: make(map[string]bool, len(caps))}
+	for _, c := range caps {
+		ctx.Capabilities[c] = true
+	}
+	return ctx
+}
+
+// toolsAtLeast reports whether the tools of the context are at least version. An unknown
+// version on either side does not rule anything out.
+func (ctx *CapabilityContext) toolsAtLeast(version string) bool {
+	if ctx.ToolsVersion == "" || version == "" {
+		return true
+	}
+	have, err := ParseVersion(ctx.ToolsVersion)
+	if err != nil {
+		return true
+	}
+	need, err := ParseVersion(version)
+	if err != nil {
+		return true
+	}
+	return CompareStrict(have, need) >= 0
+}
+
+// meets reports whether the context meets a token condition
+func (ctx *CapabilityContext) meets(cond *CapabilityCondition) bool {
+	if !ctx.toolsAtLeast(cond.MinToolsVersion) {
+		return false
+	}
+	return ctx.Flow == "" || len(cond.Flows) == 0 || slices.Contains(cond.Flows, ctx.Flow)
+}
+
+// available returns the tokens of the context that count
+func (ctx *CapabilityContext) available() map[string]bool {
+	if ctx.ToolsVersion == "" && ctx.Flow == "" {
+		return ctx.Capabilities
+	}
+	capabilityConditionsMu.RLock()
+	defer capabilityConditionsMu.RUnlock()
+	if len(capabilityConditions) == 0 {
+		return ctx.Capabilities
+	}
+	ret := make(map[string]bool, len(ctx.Capabilities))
+	for token, ok := range ctx.Capabilities {
+		if cond, conditional := capabilityConditions[token]; ok && conditional && !ctx.meets(cond) {
+			continue
+		}
+		ret[token] = ok
+	}
+	return ret
+}
+
+// suitsVersion reports whether a version of an asset, with its flow_version and
+// tools_min_version, can be built in the context. The BTSDK flow is not told by flow_version,
+// so versions are not filtered by it.
+func (ctx *CapabilityContext) suitsVersion(flowVersion, toolsMinVersion string) bool {
+	if !ctx.toolsAtLeast(toolsMinVersion) {
+		return false
+	}
+	if ctx.Flow == "" || ctx.Flow == FlowBTSDK {
+		return true
+	}
+	return slices.Contains(flowsOfVersion(flowVersion), ctx.Flow)
+}
+
+// MatchesContext checks if the tokens that count in a context satisfy this requirement
+func (cr *CapabilityRequirement) MatchesContext(ctx *CapabilityContext) bool {
+	return cr.matches(ctx.available())
+}
+
+// MatchesVersionContext is MatchesVersion in a context: the version must also suit its tools
+// and flow
+func (a *App) MatchesVersionContext(v *CEVersion, ctx *CapabilityContext) bool {
+	if v != nil && !ctx.suitsVersion(v.FlowVersion, v.ToolsMinVersion) {
+		return false
+	}
+	available := ctx.available()
+	reqs := []CapabilityRequirement{a.GetCapabilities()}
+	if v != nil {
+		reqs = append(reqs, v.GetCapabilities())
+	}
+	for _, p := range a.ProjectsFor(v) {
+		reqs = append(reqs, p.GetCapabilities())
+	}
+	required := false
+	for _, req := range reqs {
+		if !req.matches(available) {
+			return false
+		}
+		required = required || len(req.Groups) > 0
+	}
+	return required
+}
+
+// MatchesContext reports whether any version of the app suits a context
+func (a *App) MatchesContext(ctx *CapabilityContext) bool {
+	if len(a.Versions.Version) == 0 {
+		return a.MatchesVersionContext(nil, ctx)
+	}
+	for _, v := range a.Versions.Version {
+		if a.MatchesVersionContext(v, ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesContext reports whether the middleware has a version that can be built in a context
+// and whether the context meets its requirements. Middleware that requires nothing matches.
+func (mw *MiddlewareItem) MatchesContext(ctx *CapabilityContext) bool {
+	if mw.Versions != nil && len(mw.Versions.Version) > 0 {
+		suits := false
+		for _, v := range mw.Versions.Version {
+			if ctx.suitsVersion(v.FlowVersion, v.ToolsMinVersion) {
+				suits = true
+				break
+			}
+		}
+		if !suits {
+			return false
+		}
+	}
+	req := mw.GetCapabilities()
+	return len(req.Groups) == 0 || req.MatchesContext(ctx)
+}
+
+// FindMiddlewareForContext returns the middleware that matches a context (see
+// MiddlewareItem.MatchesContext)
+func FindMiddlewareForContext(sm SuperManifestIF, ctx *CapabilityContext) []*MiddlewareItem {
+	result := make([]*MiddlewareItem, 0)
+	middlewareMap := sm.MiddlewareByID()
+	for _, id := range orderedKeys(middlewareMap) {
+		if mw := middlewareMap[id]; mw.MatchesContext(ctx) {
+			result = append(result, mw)
+		}
+	}
+	return result
+}
+
+// FindCodeExamplesForContext returns the code examples with a version that matches a context
+// (see App.MatchesContext)
+func FindCodeExamplesForContext(sm SuperManifestIF, ctx *CapabilityContext) []*App {
+	result := make([]*App, 0)
+	appMap := sm.AppsByID()
+	for _, id := range orderedKeys(appMap) {
+		if app := appMap[id]; app.MatchesContext(ctx) {
+			result = append(result, app)
+		}
+	}
+	return result
+}
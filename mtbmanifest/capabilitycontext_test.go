@@ -0,0 +1,76 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+const testContextAppsXML = `<apps version="2.0">
+  <app req_capabilities_v2="cat1a secure_sockets_v2">
+    <name>Secure Sockets</name>
+    <id>mtb-example-secure-sockets</id>
+    <uri>https://github.com/Infineon/mtb-example-secure-sockets</uri>
+    <description>TLS client.</description>
+    <versions>
+      <version flow_version="2.0" tools_min_version="3.2.0"><num>Latest 2.X release</num><commit>latest-v2.X</commit></version>
+      <version flow_version="1.0"><num>Latest 1.X release</num><commit>latest-v1.X</commit></version>
+    </versions>
+  </app>
+</apps>`
+
+func TestCapabilityContext(t *testing.T) {
+	defer func() { _ = SetCapabilityConditions(nil) }()
+	if err := SetCapabilityConditions(map[string]*CapabilityCondition{
+		"secure_sockets_v2": {MinToolsVersion: "3.1.0", Flows: []Flow{FlowMTB2}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := ParseCapabilities("cat1a secure_sockets_v2")
+	caps := []string{"cat1a", "secure_sockets_v2"}
+	if !req.Matches(NewCapabilityContext(caps).Capabilities) {
+		t.Error("a flat token set must match whatever the conditions")
+	}
+	ctx := NewCapabilityContext(caps)
+	ctx.ToolsVersion = "3.0.0"
+	if req.MatchesContext(ctx) {
+		t.Error("the token must not count with tools older than its condition")
+	}
+	ctx.ToolsVersion = "3.6.0"
+	if !req.MatchesContext(ctx) {
+		t.Error("the token must count with newer tools")
+	}
+	ctx.Flow = FlowMTB1
+	if req.MatchesContext(ctx) {
+		t.Error("the token must not count in another flow")
+	}
+
+	apps, err := ReadAppsManifest([]byte(testContextAppsXML))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	app := apps.App[0]
+	latest, old := app.Versions.Version[0], app.Versions.Version[1]
+	ctx = NewCapabilityContext(caps)
+	ctx.ToolsVersion = "3.1.0"
+	if app.MatchesVersionContext(latest, ctx) || !app.MatchesVersionContext(old, ctx) {
+		t.Error("only the version whose tools_min_version the tools meet must match")
+	}
+	ctx.Flow = FlowMTB2
+	if app.MatchesContext(ctx) {
+		t.Error("no version is for both tools 3.1.0 and the mtb2 flow")
+	}
+	ctx.ToolsVersion = ""
+	if !app.MatchesContext(ctx) {
+		t.Error("without a tools version, the mtb2 version must match")
+	}
+
+	if err := SetCapabilityConditions(map[string]*CapabilityCondition{"x": {MinToolsVersion: "new"}}); err == nil {
+		t.Error("expected an unparsable tools version to be rejected")
+	}
+	if err := SetCapabilityConditions(map[string]*CapabilityCondition{"x": {Flows: []Flow{"mtb9"}}}); err == nil {
+		t.Error("expected an unknown flow to be rejected")
+	}
+	if _, ok := CapabilityConditions()["secure_sockets_v2"]; !ok {
+		t.Error("expected a rejected table to leave the conditions in use")
+	}
+}
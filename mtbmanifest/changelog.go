@@ -0,0 +1,168 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Release notes aggregation
+// ////////////////////////////////////////////////////////////////////////
+
+// Asset repositories keep their release notes in RELEASE.md or CHANGELOG.md, either as one
+// heading per version ("### v4.1.0") or as a changelog table whose rows start with a version.
+// Changelog reads the notes at two versions of an item and reports the entries that are in the
+// newer notes but not in the older ones, which is what changed between the two.
+
+// ReleaseNotesFiles are the files looked for, in order
+var ReleaseNotesFiles = []string{"RELEASE.md", "CHANGELOG.md", "README.md"}
+
+// ReleaseNote is the entry for one version in a release notes file
+type ReleaseNote struct {
+	// Version is the version as written, e.g. "v4.1.0"
+	Version string `json:"version"`
+	// Heading is the heading or first table cell the entry was found under
+	Heading string `json:"heading"`
+	Body    string `json:"body"`
+}
+
+// ChangelogReport is what changed for an item between two versions
+type ChangelogReport struct {
+	ID   string `json:"id"`
+	Repo string `json:"repo"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	// File is the release notes file the entries come from
+	File string `json:"file"`
+	// Notes are the entries added since From, newest first as in the file
+	Notes []*ReleaseNote `json:"notes"`
+}
+
+var (
+	mdHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	noteVersionRe  = regexp.MustCompile(`(?i)\bv?\d+\.\d+(?:\.\d+)?(?:[-+.][0-9A-Za-z.]+)?\b`)
+)
+
+// ParseReleaseNotes splits a markdown release notes file into per-version entries. A heading
+// containing a version starts an entry that runs until the next heading of the same or a
+// higher level; a table row whose first cell is a version is an entry of its own.
+func ParseReleaseNotes(markdown string) []*ReleaseNote {
+	ret := []*ReleaseNote{}
+	var current *ReleaseNote
+	currentLevel := 0
+	var body []string
+	flush := func() {
+		if current != nil {
+			current.Body = strings.TrimSpace(strings.Join(body, "\n"))
+			ret = append(ret, current)
+		}
+		current, body = nil, nil
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		if m := mdHeadingRegex.FindStringSubmatch(line); m != nil {
+			level := len(m[1])
+			if current != nil && level > currentLevel {
+				body = append(body, line)
+				continue
+			}
+			flush()
+			if v := noteVersionRe.FindString(m[2]); v != "" {
+				current = &ReleaseNote{Version: v, Heading: m[2]}
+				currentLevel = level
+			}
+			continue
+		}
+		if cells := tableCells(line); len(cells) > 1 {
+			if v := noteVersionRe.FindString(cells[0]); v != "" && strings.TrimSpace(cells[0]) == v {
+				flush()
+				ret = append(ret, &ReleaseNote{Version: v, Heading: cells[0],
+					Body: strings.Join(cells[1:], " | ")})
+				continue
+			}
+		}
+		if current != nil {
+			body = append(body, line)
+		}
+	}
+	flush()
+	return ret
+}
+
+// tableCells returns the trimmed cells of a markdown table row, or nil
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "|") {
+		return nil
+	}
+	cells := strings.Split(strings.Trim(line, "|"), "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// readReleaseNotes returns the first release notes file found in a revision, and its name
+func readReleaseNotes(g *GitFetcher, src *GitSource) (string, string, error) {
+	for _, name := range ReleaseNotesFiles {
+		data, err := g.ReadFile(src, name)
+		if err == nil {
+			return name, string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", err
+		}
+	}
+	return "", "", fmt.Errorf("%s has no release notes (looked for %s)", src, strings.Join(ReleaseNotesFiles, ", "))
+}
+
+// Changelog reports what changed for a board, app or middleware between two of its versions
+// (version commits such as release-v3.0.0 or latest-v4.X). An empty to means the newest
+// listed version. A nil fetcher means NewGitFetcher().
+func Changelog(sm SuperManifestIF, id, from, to string, g *GitFetcher) (*ChangelogReport, error) {
+	if g == nil {
+		g = NewGitFetcher()
+	}
+	if from == "" {
+		return nil, fmt.Errorf("give the version to compare from")
+	}
+	fromSrc, err := AssetSource(sm, id, from)
+	if err != nil {
+		return nil, err
+	}
+	toSrc, err := AssetSource(sm, id, to)
+	if err != nil {
+		return nil, err
+	}
+	report := &ChangelogReport{ID: id, Repo: toSrc.Repo, From: fromSrc.Ref, To: toSrc.Ref, Notes: []*ReleaseNote{}}
+
+	file, newer, err := readReleaseNotes(g, toSrc)
+	if err != nil {
+		return nil, err
+	}
+	report.File = file
+	seen := make(map[string]bool)
+	if _, older, err := readReleaseNotes(g, fromSrc); err == nil {
+		for _, note := range ParseReleaseNotes(older) {
+			seen[strings.ToLower(note.Version)] = true
+		}
+	} else {
+		// Without older notes, fall back to comparing version numbers
+		logger.Warningf("No release notes at %s, comparing version numbers: %v\n", fromSrc, err)
+	}
+	fromVersion, _ := ParseVersion(fromSrc.Ref)
+	for _, note := range ParseReleaseNotes(newer) {
+		if seen[strings.ToLower(note.Version)] {
+			continue
+		}
+		if len(seen) == 0 && fromVersion != nil {
+			if v, err := ParseVersion(note.Version); err == nil && CompareStrict(v, fromVersion) <= 0 {
+				continue
+			}
+		}
+		report.Notes = append(report.Notes, note)
+	}
+	return report, nil
+}
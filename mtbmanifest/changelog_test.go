@@ -0,0 +1,74 @@
+package mtbmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testReleaseV1 = `# FreeRTOS
+
+## Changelog
+
+### v1.0.0
+* Initial release
+`
+
+const testReleaseV2 = `# FreeRTOS
+
+## Changelog
+
+### v1.1.0
+* Added tickless idle
+#### Known issues
+* None
+
+### v1.0.0
+* Initial release
+`
+
+func TestParseReleaseNotes(t *testing.T) {
+	notes := ParseReleaseNotes(testReleaseV2)
+	if len(notes) != 2 || notes[0].Version != "v1.1.0" || notes[1].Version != "v1.0.0" {
+		t.Fatalf("unexpected notes %+v", notes)
+	}
+	if notes[0].Body != "* Added tickless idle\n#### Known issues\n* None" {
+		t.Errorf("unexpected body %q", notes[0].Body)
+	}
+
+	table := "| Version | Changes |\n|---|---|\n| 2.1.0 | Added X |\n| 2.0.0 | Initial |\n"
+	notes = ParseReleaseNotes(table)
+	if len(notes) != 2 || notes[0].Version != "2.1.0" || notes[0].Body != "Added X" {
+		t.Errorf("unexpected table notes %+v", notes)
+	}
+}
+
+func TestChangelog(t *testing.T) {
+	repo := newTestGitRepo(t)
+	for tag, notes := range map[string]string{"release-v1.0.0": testReleaseV1, "release-v1.1.0": testReleaseV2} {
+		if err := os.WriteFile(filepath.Join(repo, "RELEASE.md"), []byte(notes), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, repo, "add", "RELEASE.md")
+		runGit(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", tag)
+		runGit(t, repo, "tag", tag)
+	}
+
+	sm := newTestSuperManifest(t)
+	mw, _ := sm.GetMiddleware("freertos")
+	mw.URI = "file://" + repo
+	mw.Versions.Version = append(mw.Versions.Version,
+		&MWVersion{Num: "1.1.0", Commit: "release-v1.1.0"}, &MWVersion{Num: "1.0.0", Commit: "release-v1.0.0"})
+
+	g := NewGitFetcher(WithGitCacheDir(t.TempDir()))
+	report, err := Changelog(sm, "freertos", "release-v1.0.0", "release-v1.1.0", g)
+	if err != nil {
+		t.Fatalf("Changelog failed: %v", err)
+	}
+	if report.File != "RELEASE.md" || len(report.Notes) != 1 || report.Notes[0].Version != "v1.1.0" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if _, err := Changelog(sm, "freertos", "", "release-v1.1.0", g); err == nil {
+		t.Error("expected an error without a from version")
+	}
+}
@@ -0,0 +1,224 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Channels
+// ////////////////////////////////////////////////////////////////////////
+
+// Besides the production super manifest there are early-access and internal ones, and users
+// tracking them used to merge trees by hand with AddSuperManifest, losing track of where an
+// item came from. A Channel names a set of super manifest URLs; a ChannelSet ingests each
+// channel into a cache directory of its own, so a channel's files never stand in for another's,
+// and sets the Channel field of every board, app and middleware item it ingests. Union merges
+// channels into one tree and records, for every ID, which channels list it.
+
+// DefaultChannel is the name of the channel of the official super manifest
+const DefaultChannel = "prod"
+
+// channelsDir is the directory, inside the cache directory, channel caches are kept in
+const channelsDir = "channels"
+
+var channelNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Channel is a named set of super manifests, e.g. "early-access"
+type Channel struct {
+	Name string `json:"name"`
+	// URLs are the super manifests of the channel, merged in order
+	URLs []string `json:"urls"`
+	// Description says what the channel is for
+	Description string `json:"description,omitempty"`
+}
+
+// ChannelSet ingests channels, each through its own cache, and keeps the trees it ingested
+type ChannelSet struct {
+	channels []*Channel
+	// defaults are the names of the channels Union merges when not told which: the
+	// caller's and the default channel
+	defaults []string
+	cacheDir string
+	opts     []IngestOption
+
+	mu    sync.Mutex
+	trees map[string]*SuperManifest
+}
+
+// withChannel sets the Channel field of the items ingested
+func withChannel(name string) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.channel = name
+	}
+}
+
+// ReadChannels parses a JSON array of channels, e.g.
+//
+//	[{"name": "ea", "urls": ["https://example.com/mtb-super-manifest-fv2-ea.xml"]}]
+func ReadChannels(jsonData []byte) ([]*Channel, error) {
+	channels := []*Channel{}
+	if err := json.Unmarshal(jsonData, &channels); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// NewChannelSet creates a set of channels whose caches are kept under
+// cacheDir/channels/NAME; an empty cacheDir means DefaultCacheDir(). The known channels (see
+// KnownChannels) are added unless channels has one of the same name, the DefaultChannel
+// first. opts apply to every ingestion, except that the cache directory is the channel's own;
+// with WithFetcher all channels share its cache.
+func NewChannelSet(channels []*Channel, cacheDir string, opts ...IngestOption) (*ChannelSet, error) {
+	if cacheDir == "" {
+		cacheDir = DefaultCacheDir()
+	}
+	cs := &ChannelSet{cacheDir: cacheDir, opts: opts, trees: make(map[string]*SuperManifest)}
+	seen := make(map[string]bool)
+	for _, ch := range channels {
+		if err := ch.validate(); err != nil {
+			return nil, err
+		}
+		if seen[ch.Name] {
+			return nil, fmt.Errorf("channel %s is defined twice", ch.Name)
+		}
+		seen[ch.Name] = true
+		cs.channels = append(cs.channels, ch)
+		cs.defaults = append(cs.defaults, ch.Name)
+	}
+	for _, ch := range KnownChannels() {
+		if seen[ch.Name] {
+			continue
+		}
+		if ch.Name == DefaultChannel {
+			cs.channels = append([]*Channel{ch}, cs.channels...)
+			cs.defaults = append([]string{ch.Name}, cs.defaults...)
+		} else {
+			cs.channels = append(cs.channels, ch)
+		}
+	}
+	return cs, nil
+}
+
+// Channels returns the channels of the set: the caller's, with the default channel first
+// unless defined by the caller, then the other known channels
+func (cs *ChannelSet) Channels() []*Channel {
+	return append([]*Channel{}, cs.channels...)
+}
+
+// Channel returns the channel with the given name
+func (cs *ChannelSet) Channel(name string) (*Channel, bool) {
+	for _, ch := range cs.channels {
+		if ch.Name == name {
+			return ch, true
+		}
+	}
+	return nil, false
+}
+
+// CacheDir returns the directory the manifests of a channel are cached in
+func (cs *ChannelSet) CacheDir(name string) string {
+	return filepath.Join(cs.cacheDir, channelsDir, name)
+}
+
+// Load ingests a channel, or returns the tree already ingested. Every item of the tree has its
+// Channel field set to the channel's name.
+func (cs *ChannelSet) Load(name string) (SuperManifestIF, error) {
+	tree, err := cs.load(name)
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (cs *ChannelSet) load(name string) (*SuperManifest, error) {
+	ch, ok := cs.Channel(name)
+	if !ok {
+		return nil, fmt.Errorf("no channel named %s", name)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if tree, ok := cs.trees[name]; ok {
+		return tree, nil
+	}
+	cfg := newIngestConfig(append(append([]IngestOption{}, cs.opts...), WithCacheDir(cs.CacheDir(name)),
+		withChannel(name)))
+	var tree *SuperManifest
+	for _, urlStr := range ch.URLs {
+		sm, err := newSuperManifest(urlStr, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %v", name, err)
+		}
+		if tree == nil {
+			tree = sm
+		} else {
+			tree.AddSuperManifest(sm)
+		}
+	}
+	cs.trees[name] = tree
+	return tree, nil
+}
+
+// ChannelUnion is the tree of several channels merged, in the order they were given to Union.
+// Where more than one channel lists an ID, lookups return the item of the last of them, so
+// early-access channels go after production; Boards, Apps and Middleware list the item of each.
+type ChannelUnion struct {
+	*SuperManifest
+	channels   []string
+	provenance map[ItemKind]map[string][]string
+}
+
+// Union ingests the named channels, if none are named those given to NewChannelSet and the
+// default channel, and merges them into one tree. The channels' own trees are not changed.
+func (cs *ChannelSet) Union(names ...string) (*ChannelUnion, error) {
+	if len(names) == 0 {
+		names = append(names, cs.defaults...)
+	}
+	u := &ChannelUnion{
+		SuperManifest: NewSuperManifest().(*SuperManifest),
+		channels:      names,
+		provenance: map[ItemKind]map[string][]string{
+			ItemKindBoard: {}, ItemKindApp: {}, ItemKindMiddleware: {},
+		},
+	}
+	for _, name := range names {
+		tree, err := cs.load(name)
+		if err != nil {
+			return nil, err
+		}
+		if u.Version == "" {
+			u.Version = tree.Version
+		}
+		u.AddSuperManifest(tree)
+		for kind, ids := range map[ItemKind][]string{ItemKindBoard: tree.GetBoardIDs(),
+			ItemKindApp: tree.GetAppIDs(), ItemKindMiddleware: tree.GetMiddlewareIDs()} {
+			for _, id := range ids {
+				u.addProvenance(kind, id, name)
+			}
+		}
+	}
+	return u, nil
+}
+
+// addProvenance records that a channel lists an item, once
+func (u *ChannelUnion) addProvenance(kind ItemKind, id, channel string) {
+	key := idKey(id)
+	list := u.provenance[kind][key]
+	if len(list) == 0 || list[len(list)-1] != channel {
+		u.provenance[kind][key] = append(list, channel)
+	}
+}
+
+// ChannelNames returns the channels merged into the union, in merge order
+func (u *ChannelUnion) ChannelNames() []string {
+	return append([]string{}, u.channels...)
+}
+
+// ChannelsOf returns the channels that list the item of the given kind and ID, in merge order.
+// The last one is the channel of the item lookups return.
+func (u *ChannelUnion) ChannelsOf(kind ItemKind, id string) []string {
+	return append([]string{}, u.provenance[kind][idKey(id)]...)
+}
@@ -0,0 +1,122 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChannelSet(t *testing.T) {
+	const eaURL = "https://example.com/ea/super.xml"
+	dir := t.TempDir()
+	cs, err := NewChannelSet([]*Channel{{Name: "ea", URLs: []string{eaURL}}}, dir, WithOffline())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := []string{cs.Channels()[0].Name, cs.Channels()[1].Name}; !slices.Equal(names, []string{DefaultChannel, "ea"}) {
+		t.Fatalf("unexpected channels %v", names)
+	}
+
+	// Each channel reads its own cache: the early-access boards are only in the ea cache
+	seed := func(channel string, files map[string]string) {
+		cache := NewManifestCache(WithDir(cs.CacheDir(channel)), WithCacheTTL(time.Hour))
+		defer cache.Close()
+		for u, data := range files {
+			if err := cache.writeCache(u, []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	seed(DefaultChannel, map[string]string{
+		SuperManifestURL:                     testSuperXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	})
+	seed("ea", map[string]string{
+		eaURL: `<super-manifest version="2.0">
+  <board-manifest-list><board-manifest><uri>https://example.com/ea/boards.xml</uri></board-manifest></board-manifest-list>
+  <app-manifest-list></app-manifest-list>
+  <middleware-manifest-list></middleware-manifest-list>
+</super-manifest>`,
+		"https://example.com/ea/boards.xml": `<boards>
+  <board><id>KIT-EA</id><name>Early Access Kit</name><board_uri>https://example.com/ea</board_uri><chips><mcu>X</mcu></chips></board>
+  <board><id>CY8CKIT-149</id><name>PSoC 4100S Plus Prototyping Kit (EA)</name><board_uri>https://example.com/149</board_uri><chips><mcu>X</mcu></chips></board>
+</boards>`,
+	})
+
+	prod, err := cs.Load(DefaultChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := prod.GetBoard("KIT-EA"); ok {
+		t.Fatal("expected the early-access board to stay out of the prod channel")
+	}
+	if b, _ := prod.GetBoard("CY8CKIT-149"); b == nil || b.Channel != DefaultChannel {
+		t.Fatalf("expected CY8CKIT-149 from the prod channel, got %+v", b)
+	}
+	if again, _ := cs.Load(DefaultChannel); again != prod {
+		t.Fatal("expected the channel to be ingested once")
+	}
+
+	union, err := cs.Union()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := union.GetBoard("KIT-EA"); b == nil || b.Channel != "ea" {
+		t.Fatalf("expected KIT-EA from the ea channel, got %+v", b)
+	}
+	if p := union.Boards()[len(union.Boards())-1].Provenance(); p.Channel != "ea" || p.SuperManifestURL != eaURL {
+		t.Fatalf("unexpected provenance %+v", p)
+	}
+	if b, _ := union.GetBoard("CY8CKIT-149"); b == nil || b.Channel != "ea" {
+		t.Fatalf("expected the later channel to win, got %+v", b)
+	}
+	if got := union.ChannelsOf(ItemKindBoard, "CY8CKIT-149"); !slices.Equal(got, []string{DefaultChannel, "ea"}) {
+		t.Fatalf("unexpected provenance %v", got)
+	}
+	if got := union.ChannelsOf(ItemKindMiddleware, "freertos"); !slices.Equal(got, []string{DefaultChannel}) {
+		t.Fatalf("unexpected provenance %v", got)
+	}
+	if b, _ := prod.GetBoard("CY8CKIT-149"); b.Channel != DefaultChannel {
+		t.Fatal("expected the union to leave the channel trees alone")
+	}
+
+	if _, err := cs.Load("internal"); err == nil {
+		t.Fatal("expected an error for an unknown channel")
+	}
+	if _, err := NewChannelSet([]*Channel{{Name: "../x", URLs: []string{eaURL}}}, dir); err == nil {
+		t.Fatal("expected an error for a bad channel name")
+	}
+}
+
+func TestKnownChannels(t *testing.T) {
+	cs, err := NewChannelSet([]*Channel{{Name: LTSChannel, URLs: []string{"https://example.com/lts.xml"}}}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch, ok := cs.Channel(LTSChannel); !ok || ch.URLs[0] != "https://example.com/lts.xml" {
+		t.Fatalf("expected the caller's lts channel to win, got %+v", ch)
+	}
+	if ch, ok := cs.Channel(WifiBTChannel); !ok || ch.URLs[0] != SuperManifestWifiBTURL {
+		t.Fatalf("expected the known wifi-bt channel, got %+v", ch)
+	}
+	if names := cs.defaults; !slices.Equal(names, []string{DefaultChannel, LTSChannel}) {
+		t.Errorf("expected Union to default to the caller's channels and prod, got %v", names)
+	}
+
+	if err := RegisterChannel(&Channel{Name: "beta", URLs: []string{"https://example.com/beta.xml"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		knownChannelsMu.Lock()
+		knownChannels = knownChannels[:len(knownChannels)-1]
+		knownChannelsMu.Unlock()
+	}()
+	if ch, ok := KnownChannel("beta"); !ok || ch.URLs[0] != "https://example.com/beta.xml" {
+		t.Fatalf("expected the registered channel, got %+v", ch)
+	}
+	if err := RegisterChannel(&Channel{Name: "no urls"}); err == nil {
+		t.Error("expected an error for a bad channel")
+	}
+}
@@ -0,0 +1,112 @@
+package mtbmanifest
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// ID and name normalization
+// ////////////////////////////////////////////////////////////////////////
+
+// Vendors do not always agree on how to capitalize an ID (CY8CKIT-062S2-43012 in one manifest,
+// cy8ckit-062s2-43012 in another). With case-insensitive IDs enabled, the lookup maps are keyed
+// by the case-folded ID, so GetBoard/GetApp/GetMiddleware find an item however the caller or
+// another manifest spells it. Items keep their original ID. The maps are built on first use,
+// so set this before ingesting.
+var doCaseInsensitiveIDs = false
+
+// EnableCaseInsensitiveIDs enables or disables case-insensitive ID lookups
+func EnableCaseInsensitiveIDs(enable bool) {
+	if enable {
+		logger.Infof("Case-Insensitive IDs Enabled\n")
+	}
+	doCaseInsensitiveIDs = enable
+}
+
+// IsCaseInsensitiveIDs reports whether IDs are looked up case-insensitively
+func IsCaseInsensitiveIDs() bool {
+	return doCaseInsensitiveIDs
+}
+
+// idKey returns the key an ID is stored and looked up under in the maps, according to the
+// current ID policy
+func idKey(id string) string {
+	if doCaseInsensitiveIDs {
+		return strings.ToLower(strings.TrimSpace(id))
+	}
+	return id
+}
+
+// SameID reports whether two IDs refer to the same item under the current ID policy
+func SameID(a, b string) bool {
+	return idKey(a) == idKey(b)
+}
+
+// CompareNames compares two display names or IDs the way a person would sort them: case is
+// ignored (for any script, not just ASCII) and runs of digits compare by value, so
+// "Kit 2" sorts before "Kit 10". Returns -1, 0 or +1. Names that only differ in case fall back
+// to a byte comparison so the order is still total.
+func CompareNames(a, b string) int {
+	if c := compareFolded(a, b); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func compareFolded(a, b string) int {
+	for a != "" && b != "" {
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if unicode.IsDigit(ra) && unicode.IsDigit(rb) {
+			na, nb := digitRun(a), digitRun(b)
+			if c := compareDigits(a[:na], b[:nb]); c != 0 {
+				return c
+			}
+			a, b = a[na:], b[nb:]
+			continue
+		}
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			if la < lb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+// digitRun returns the length in bytes of the leading run of digits in s
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsDigit(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// compareDigits compares two runs of digits by numeric value without overflowing
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
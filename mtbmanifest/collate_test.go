@@ -0,0 +1,41 @@
+package mtbmanifest
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCompareNames(t *testing.T) {
+	names := []string{"Kit 10", "kit 2", "Émulateur", "Kit 2", "alpha", "KIT 1"}
+	sort.SliceStable(names, func(i, j int) bool { return CompareNames(names[i], names[j]) < 0 })
+	expected := []string{"alpha", "KIT 1", "Kit 2", "kit 2", "Kit 10", "Émulateur"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+	// Equal by value but spelled differently; the order must still be total
+	if CompareNames("v007", "v7") == 0 {
+		t.Error("expected v007 and v7 to compare unequal")
+	}
+}
+
+func TestCaseInsensitiveIDs(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	if _, ok := sm.GetBoard("cy8ckit-149"); ok {
+		t.Error("IDs should be case-sensitive by default")
+	}
+
+	EnableCaseInsensitiveIDs(true)
+	defer EnableCaseInsensitiveIDs(false)
+	sm = newTestSuperManifest(t)
+	if board, ok := sm.GetBoard("cy8ckit-149"); !ok || board.ID != "CY8CKIT-149" {
+		t.Error("expected to find CY8CKIT-149 with a lower-case ID, keeping its original ID")
+	}
+	if _, ok := sm.GetMiddleware("FreeRTOS"); !ok {
+		t.Error("expected to find freertos as FreeRTOS")
+	}
+	if !SameID("FreeRTOS", "freertos") {
+		t.Error("SameID should ignore case")
+	}
+}
@@ -0,0 +1,113 @@
+package mtbmanifest
+
+import (
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Version commit refs
+// ////////////////////////////////////////////////////////////////////////
+
+// The commit of a version is whatever git ref the manifest author wrote: a release tag
+// (release-v3.4.0), a tag moved to every new release of a major version (latest-v4.X), a
+// branch (master), now and then a commit SHA. Whether the ref can move matters to lockfiles,
+// release cadences and resolvers alike, so it is told once, when the manifest is read: every
+// version carries the CommitRef of its commit in its Ref field.
+
+// CommitRefKind is the kind of git ref a version commit is
+type CommitRefKind string
+
+const (
+	CommitRefTag      CommitRefKind = "tag"      // a release tag, e.g. release-v3.4.0
+	CommitRefFloating CommitRefKind = "floating" // a tag moved to each new release, e.g. latest-v4.X
+	CommitRefBranch   CommitRefKind = "branch"   // a branch, or any name without a version
+	CommitRefSHA      CommitRefKind = "sha"      // a full commit SHA
+)
+
+// CommitRef is a version commit and the kind of ref it is
+type CommitRef struct {
+	Kind  CommitRefKind `json:"kind"`
+	Value string        `json:"value"`
+}
+
+// ParseCommitRef tells the kind of a version commit. A version number with an X, or a name
+// starting with "latest", is floating; any other version number is a release tag.
+func ParseCommitRef(commit string) CommitRef {
+	commit = strings.TrimSpace(commit)
+	ref := CommitRef{Kind: CommitRefBranch, Value: commit}
+	switch {
+	case IsCommitSHA(commit):
+		ref.Kind = CommitRefSHA
+	case strings.Contains(versionRegex.FindString(strings.ToUpper(commit)), "X"):
+		ref.Kind = CommitRefFloating
+	case versionRegex.MatchString(commit):
+		ref.Kind = CommitRefTag
+	case strings.HasPrefix(strings.ToLower(commit), "latest"):
+		ref.Kind = CommitRefFloating
+	}
+	return ref
+}
+
+// Moves reports whether the ref is expected to point at other commits over time, as
+// floating tags and branches are
+func (r CommitRef) Moves() bool {
+	return r.Kind == CommitRefFloating || r.Kind == CommitRefBranch
+}
+
+func (r CommitRef) String() string {
+	return r.Value
+}
+
+// commitRefOf returns ref if it was parsed from commit, or parses commit, for versions that
+// were not read from a manifest
+func commitRefOf(ref CommitRef, commit string) CommitRef {
+	if ref.Kind != "" && ref.Value == strings.TrimSpace(commit) {
+		return ref
+	}
+	return ParseCommitRef(commit)
+}
+
+// GetRef returns the kind of ref the commit of the version is
+func (v *BoardVersion) GetRef() CommitRef {
+	return commitRefOf(v.Ref, v.Commit)
+}
+
+// GetRef returns the kind of ref the commit of the version is
+func (v *CEVersion) GetRef() CommitRef {
+	return commitRefOf(v.Ref, v.Commit)
+}
+
+// GetRef returns the kind of ref the commit of the version is
+func (v *MWVersion) GetRef() CommitRef {
+	return commitRefOf(v.Ref, v.Commit)
+}
+
+func (b *Boards) parseCommitRefs() {
+	for _, board := range b.Boards {
+		if board.Versions == nil {
+			continue
+		}
+		for _, v := range board.Versions.Versions {
+			v.Ref = ParseCommitRef(v.Commit)
+		}
+	}
+}
+
+func (apps *Apps) parseCommitRefs() {
+	for _, a := range apps.App {
+		for _, v := range a.Versions.Version {
+			v.Ref = ParseCommitRef(v.Commit)
+		}
+	}
+}
+
+func (mw *Middleware) parseCommitRefs() {
+	for _, item := range mw.Middlewares {
+		if item.Versions == nil {
+			continue
+		}
+		for _, v := range item.Versions.Version {
+			v.Ref = ParseCommitRef(v.Commit)
+		}
+	}
+}
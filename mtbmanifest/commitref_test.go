@@ -0,0 +1,44 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestParseCommitRef(t *testing.T) {
+	tests := []struct {
+		commit string
+		kind   CommitRefKind
+		moves  bool
+	}{
+		{"release-v3.4.0", CommitRefTag, false},
+		{"v2.5", CommitRefTag, false},
+		{"latest-v4.X", CommitRefFloating, true},
+		{"latest-v4.1.x", CommitRefFloating, true},
+		{"latest", CommitRefFloating, true},
+		{"master", CommitRefBranch, true},
+		{"0123456789abcdef0123456789ABCDEF01234567", CommitRefSHA, false},
+	}
+	for _, tt := range tests {
+		ref := ParseCommitRef(tt.commit)
+		if ref.Kind != tt.kind || ref.Value != tt.commit || ref.Moves() != tt.moves {
+			t.Errorf("%s: expected %s (moves %v), got %+v", tt.commit, tt.kind, tt.moves, ref)
+		}
+	}
+}
+
+func TestCommitRefsAtIngest(t *testing.T) {
+	apps, err := ReadAppsManifest([]byte(testMultiCoreAppsXML))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	latest, old := apps.App[0].Versions.Version[0], apps.App[0].Versions.Version[1]
+	if latest.Ref.Kind != CommitRefFloating || old.Ref.Kind != CommitRefTag || old.Ref.Value != "release-v1.0.0" {
+		t.Errorf("expected the refs parsed when reading, got %+v and %+v", latest.Ref, old.Ref)
+	}
+
+	// Versions built by hand, or changed since, are parsed on demand
+	v := &MWVersion{Commit: "release-v1.2.0", Ref: latest.Ref}
+	if ref := v.GetRef(); ref.Kind != CommitRefTag || ref.Value != "release-v1.2.0" {
+		t.Errorf("expected the commit parsed again, got %+v", ref)
+	}
+}
@@ -0,0 +1,119 @@
+package mtbmanifest
+
+import (
+	"sort"
+	"strings"
+)
+
+// StringSetDiff is the result of comparing two sets of strings
+type StringSetDiff struct {
+	Common []string `json:"common"`
+	OnlyA  []string `json:"onlyA"`
+	OnlyB  []string `json:"onlyB"`
+}
+
+// IsSame reports whether both sets had the same members
+func (d *StringSetDiff) IsSame() bool {
+	return len(d.OnlyA) == 0 && len(d.OnlyB) == 0
+}
+
+// diffSets compares two lists as sets. Duplicates are ignored and each result list is sorted.
+func diffSets(a, b []string) StringSetDiff {
+	inA := make(map[string]bool, len(a))
+	inB := make(map[string]bool, len(b))
+	for _, s := range a {
+		inA[s] = true
+	}
+	for _, s := range b {
+		inB[s] = true
+	}
+	ret := StringSetDiff{Common: []string{}, OnlyA: []string{}, OnlyB: []string{}}
+	for s := range inA {
+		if inB[s] {
+			ret.Common = append(ret.Common, s)
+		} else {
+			ret.OnlyA = append(ret.OnlyA, s)
+		}
+	}
+	for s := range inB {
+		if !inA[s] {
+			ret.OnlyB = append(ret.OnlyB, s)
+		}
+	}
+	sort.Strings(ret.Common)
+	sort.Strings(ret.OnlyA)
+	sort.Strings(ret.OnlyB)
+	return ret
+}
+
+// BoardComparison is a side-by-side comparison of two boards
+type BoardComparison struct {
+	BoardA string `json:"boardA"`
+	BoardB string `json:"boardB"`
+	NameA  string `json:"nameA"`
+	NameB  string `json:"nameB"`
+
+	MCU          StringSetDiff `json:"mcu"`
+	Radio        StringSetDiff `json:"radio"`
+	Capabilities StringSetDiff `json:"capabilities"`
+	Versions     StringSetDiff `json:"versions"`
+	// Dependencies compares the libraries required by the newest version of each board's BSP
+	Dependencies StringSetDiff `json:"dependencies"`
+	// Middleware and Apps compare which middleware and code examples each board is compatible with
+	Middleware StringSetDiff `json:"middleware"`
+	Apps       StringSetDiff `json:"apps"`
+}
+
+// CompareBoards compares two boards: chips, provided capabilities, versions, BSP dependencies
+// and the middleware and code examples compatible with each.
+func CompareBoards(sm SuperManifestIF, a, b *Board) *BoardComparison {
+	ret := &BoardComparison{
+		BoardA:       a.ID,
+		BoardB:       b.ID,
+		NameA:        a.Name,
+		NameB:        b.Name,
+		MCU:          diffSets(a.Chips.MCU, b.Chips.MCU),
+		Radio:        diffSets(a.Chips.Radio, b.Chips.Radio),
+		Capabilities: diffSets(strings.Fields(a.ProvCapabilities), strings.Fields(b.ProvCapabilities)),
+		Versions:     diffSets(a.VersionCommits(), b.VersionCommits()),
+		Dependencies: diffSets(newestDependencyIDs(a.Dependencies), newestDependencyIDs(b.Dependencies)),
+	}
+
+	mwIDs := func(board *Board) []string {
+		ids := []string{}
+		for _, mw := range FindMiddlewareForBoard(sm, board) {
+			ids = append(ids, mw.ID)
+		}
+		return ids
+	}
+	appIDs := func(board *Board) []string {
+		ids := []string{}
+		for _, app := range FindCodeExamplesForBoard(sm, board) {
+			ids = append(ids, app.ID)
+		}
+		return ids
+	}
+	ret.Middleware = diffSets(mwIDs(a), mwIDs(b))
+	ret.Apps = diffSets(appIDs(a), appIDs(b))
+	return ret
+}
+
+// newestDependencyIDs returns the IDs of the libraries the newest version of a depender needs
+func newestDependencyIDs(depender *Depender) []string {
+	ret := []string{}
+	if depender == nil || len(depender.Versions) == 0 {
+		return ret
+	}
+	newest := depender.Versions[0]
+	newestVer, _ := ParseVersion(newest.Commit)
+	for _, v := range depender.Versions[1:] {
+		ver, err := ParseVersion(v.Commit)
+		if err == nil && CompareStrict(ver, newestVer) > 0 {
+			newest, newestVer = v, ver
+		}
+	}
+	for _, dep := range newest.Dependees {
+		ret = append(ret, dep.ID)
+	}
+	return ret
+}
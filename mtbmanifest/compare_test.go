@@ -0,0 +1,38 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestCompareBoards(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	a, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	b, _ := sm.GetBoard("CY8CKIT-149")
+	a.Dependencies = &Depender{ID: a.ID, Versions: []*DependerVersion{
+		{Commit: "release-v4.0.0", Dependees: []*Dependee{{ID: "core-lib"}}},
+		{Commit: "release-v4.1.0", Dependees: []*Dependee{{ID: "core-lib"}, {ID: "mtb-pdl-cat1"}}},
+	}}
+	b.Dependencies = &Depender{ID: b.ID, Versions: []*DependerVersion{
+		{Commit: "release-v3.0.0", Dependees: []*Dependee{{ID: "core-lib"}, {ID: "mtb-pdl-cat2"}}},
+	}}
+
+	cmp := CompareBoards(sm, a, b)
+	if len(cmp.Capabilities.Common) != 2 {
+		t.Errorf("expected hal and led in common, got %v", cmp.Capabilities.Common)
+	}
+	if len(cmp.Radio.OnlyA) != 1 || len(cmp.Radio.OnlyB) != 0 {
+		t.Errorf("unexpected radio diff: %+v", cmp.Radio)
+	}
+	if len(cmp.Dependencies.Common) != 1 || cmp.Dependencies.OnlyA[0] != "mtb-pdl-cat1" || cmp.Dependencies.OnlyB[0] != "mtb-pdl-cat2" {
+		t.Errorf("unexpected dependency diff: %+v", cmp.Dependencies)
+	}
+	if len(cmp.Apps.OnlyA) != 1 || cmp.Apps.OnlyA[0] != "mtb-example-wifi-tcp-client" {
+		t.Errorf("unexpected apps only on A: %v", cmp.Apps.OnlyA)
+	}
+	if len(cmp.Middleware.OnlyA) != 1 || cmp.Middleware.OnlyA[0] != "wifi-connection-manager" {
+		t.Errorf("unexpected middleware only on A: %v", cmp.Middleware.OnlyA)
+	}
+	if len(cmp.Middleware.Common) != 1 {
+		t.Errorf("expected freertos on both boards, got %v", cmp.Middleware.Common)
+	}
+}
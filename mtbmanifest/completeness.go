@@ -0,0 +1,134 @@
+package mtbmanifest
+
+import (
+	"fmt"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Completeness of a tree
+// ////////////////////////////////////////////////////////////////////////
+
+// A tree whose child manifests did not all load is still usable, it just has fewer items than
+// it should. Completeness tells how partial it is, so that a UI can say "apps list may be
+// incomplete (2 manifests failed)" instead of silently showing fewer results.
+
+// ManifestStatus is the state of one child manifest of a tree
+type ManifestStatus struct {
+	URL   string       `json:"url"`
+	Kind  ManifestKind `json:"kind"`
+	Error string       `json:"error,omitempty"`
+}
+
+// Completeness sorts the child manifests of a tree by whether they loaded
+type Completeness struct {
+	Loaded []*ManifestStatus `json:"loaded"`
+	Failed []*ManifestStatus `json:"failed"`
+	// Skipped manifests were not requested, see WithSections. They do not make a tree partial.
+	Skipped []*ManifestStatus `json:"skipped"`
+	// Partial is set when some manifests failed
+	Partial bool `json:"partial"`
+}
+
+// completenessSubjects name what a failed manifest of each kind leaves incomplete
+var completenessSubjects = map[ManifestKind]string{
+	KindBoards:       "boards list",
+	KindApps:         "apps list",
+	KindMiddleware:   "middleware list",
+	KindDependencies: "dependency data",
+	KindCapabilities: "capability data",
+}
+
+// FailedByKind counts the failed manifests of each kind
+func (c *Completeness) FailedByKind() map[ManifestKind]int {
+	ret := make(map[ManifestKind]int)
+	for _, m := range c.Failed {
+		ret[m.Kind]++
+	}
+	return ret
+}
+
+// Warnings returns one message per kind of manifest that failed, e.g. "apps list may be
+// incomplete (2 manifests failed)"
+func (c *Completeness) Warnings() []string {
+	ret := []string{}
+	counts := c.FailedByKind()
+	for _, kind := range manifestKinds {
+		n := counts[kind]
+		if n == 0 {
+			continue
+		}
+		noun := "manifests"
+		if n == 1 {
+			noun = "manifest"
+		}
+		ret = append(ret, fmt.Sprintf("%s may be incomplete (%d %s failed)", completenessSubjects[kind], n, noun))
+	}
+	return ret
+}
+
+// Completeness reports which child manifests of the tree loaded. Manifests that did not load
+// get their error from the ingest reports of the tree and of the trees merged into it.
+func (sm *SuperManifest) Completeness() *Completeness {
+	c := &Completeness{Loaded: []*ManifestStatus{}, Failed: []*ManifestStatus{}, Skipped: []*ManifestStatus{}}
+	records := make(map[string]*IngestedManifest)
+	hasReport := false
+	for _, r := range append([]*IngestReport{sm.ingestReport}, sm.mergedReports...) {
+		if r == nil {
+			continue
+		}
+		hasReport = true
+		for _, m := range r.Manifests {
+			records[m.URL] = m
+		}
+	}
+
+	seen := make(map[string]bool)
+	add := func(urlStr string, kind ManifestKind, loaded bool) {
+		if urlStr == "" || seen[urlStr] {
+			return
+		}
+		seen[urlStr] = true
+		status := &ManifestStatus{URL: urlStr, Kind: kind}
+		switch {
+		case loaded:
+			c.Loaded = append(c.Loaded, status)
+		case records[urlStr] != nil:
+			status.Error = records[urlStr].Error
+			c.Failed = append(c.Failed, status)
+		case hasReport:
+			// Every manifest an ingestion requests is in its report
+			c.Skipped = append(c.Skipped, status)
+		default:
+			status.Error = "not loaded"
+			c.Failed = append(c.Failed, status)
+		}
+	}
+	if sm.BoardManifestList != nil {
+		for _, bm := range sm.BoardManifestList.BoardManifest {
+			add(bm.URI, KindBoards, bm.Boards != nil || bm.AliasOf != "")
+		}
+	}
+	if sm.AppManifestList != nil {
+		for _, am := range sm.AppManifestList.AppManifest {
+			add(am.URI, KindApps, am.Apps != nil || am.AliasOf != "")
+		}
+	}
+	if sm.MiddlewareManifestList != nil {
+		for _, mm := range sm.MiddlewareManifestList.MiddlewareManifest {
+			add(mm.URI, KindMiddleware, mm.Middlewares != nil || mm.AliasOf != "")
+		}
+	}
+	if sm.BoardManifestList != nil {
+		for _, bm := range sm.BoardManifestList.BoardManifest {
+			add(bm.DependencyURL, KindDependencies, sm.dependenciesMap[bm.DependencyURL] != nil)
+			add(bm.CapabilityURL, KindCapabilities, sm.bspCapabilitiesMap[bm.CapabilityURL] != nil)
+		}
+	}
+	if sm.MiddlewareManifestList != nil {
+		for _, mm := range sm.MiddlewareManifestList.MiddlewareManifest {
+			add(mm.DependencyURL, KindDependencies, sm.dependenciesMap[mm.DependencyURL] != nil)
+		}
+	}
+	c.Partial = len(c.Failed) > 0
+	return c
+}
@@ -0,0 +1,58 @@
+package mtbmanifest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompleteness(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	superXML := strings.Replace(testSuperXML, "<board-manifest>",
+		`<board-manifest dependency-url="https://example.com/deps.xml">`, 1)
+	for u, data := range map[string]string{
+		superURL:                             superXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": "<middleware><broken",
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// deps.xml is not cached, so it fails offline like middleware.xml fails to parse
+	sm, err := NewSuperManifestFromURL(superURL, withIngestCache(cache), WithOffline())
+	if err != nil {
+		t.Fatalf("failed to ingest: %v", err)
+	}
+	c := sm.Completeness()
+	if !c.Partial || len(c.Loaded) != 2 || len(c.Failed) != 2 || len(c.Skipped) != 0 {
+		t.Fatalf("unexpected completeness %+v", c)
+	}
+	if c.Failed[0].Kind != KindMiddleware || c.Failed[0].Error == "" || c.Failed[1].Kind != KindDependencies {
+		t.Errorf("unexpected failures %+v, %+v", c.Failed[0], c.Failed[1])
+	}
+	warnings := c.Warnings()
+	if len(warnings) != 2 || warnings[0] != "middleware list may be incomplete (1 manifest failed)" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if len(sm.GetBoardIDs()) != 2 {
+		t.Error("the boards must load despite their dependencies failing")
+	}
+
+	// Sections left out on purpose do not make the tree partial
+	sm, err = NewSuperManifestFromURL(superURL, withIngestCache(cache), WithOffline(), WithSections(KindBoards, KindApps))
+	if err != nil {
+		t.Fatalf("failed to ingest: %v", err)
+	}
+	if c := sm.Completeness(); c.Partial || len(c.Loaded) != 2 || len(c.Skipped) != 2 {
+		t.Errorf("unexpected completeness %+v", c)
+	}
+
+	if c := newTestSuperManifest(t).Completeness(); c.Partial || len(c.Warnings()) != 0 {
+		t.Errorf("a fully built tree should be complete, got %+v", c)
+	}
+}
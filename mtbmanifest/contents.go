@@ -0,0 +1,58 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Pre-fetched manifests
+// ////////////////////////////////////////////////////////////////////////
+
+// Some programs get the manifest files by other means than this package, e.g. a web page that
+// downloads them with the browser's fetch and runs this package compiled to WebAssembly, where
+// there is neither a file system for the cache nor an HTTP client of ours. They hand the
+// contents over keyed by URL, and the tree is ingested from memory. Manifests missing from the
+// contents are reported as failures, like manifests that cannot be downloaded.
+
+// NewSuperManifestFromContents ingests the tree of the super manifests at rootURLs from
+// contents, the manifest files keyed by URL, without touching the network or the file system.
+// Several roots are merged in order. Options that choose a cache are ignored.
+func NewSuperManifestFromContents(contents map[string][]byte, rootURLs []string, opts ...IngestOption) (SuperManifestIF, error) {
+	sm, err := superManifestFromContents(contents, rootURLs, opts)
+	if err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// superManifestFromContents ingests a tree from contents through a cache that is never stale
+// and never fetches
+func superManifestFromContents(contents map[string][]byte, rootURLs []string, opts []IngestOption) (*SuperManifest, error) {
+	if len(rootURLs) == 0 {
+		return nil, fmt.Errorf("no super manifest URL")
+	}
+	store := NewMemoryStore()
+	for urlStr, data := range contents {
+		if err := store.Put(urlStr, data, time.Time{}); err != nil {
+			return nil, err
+		}
+	}
+	cache := NewManifestCache(WithStore(store), WithCacheTTL(100*365*24*time.Hour), WithNoBackgroundRefresh(),
+		WithCacheOnly())
+	defer cache.Close()
+	cfg := newIngestConfig(append(append([]IngestOption{}, opts...), WithFetcher(nil), withIngestCache(cache)))
+	var ret *SuperManifest
+	for _, rootURL := range rootURLs {
+		sm, err := newSuperManifest(rootURL, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if ret == nil {
+			ret = sm
+		} else {
+			ret.AddSuperManifest(sm)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,41 @@
+package mtbmanifest
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNewSuperManifestFromContents(t *testing.T) {
+	contents := map[string][]byte{
+		SuperManifestURL:                     []byte(testSuperXML),
+		"https://example.com/boards.xml":     []byte(testBoardsXML),
+		"https://example.com/middleware.xml": []byte(testMiddlewareXML),
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", home)
+	sm, err := NewSuperManifestFromContents(contents, []string{SuperManifestURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sm.GetBoard("CY8CKIT-149"); !ok {
+		t.Fatal("expected the boards of the contents")
+	}
+	if _, ok := sm.GetMiddleware("freertos"); !ok {
+		t.Fatal("expected the middleware of the contents")
+	}
+	if entries, _ := os.ReadDir(home); len(entries) != 0 {
+		t.Fatalf("expected nothing written to the file system, found %v", entries)
+	}
+	failed := sm.IngestReport().Failed()
+	if len(failed) != 1 || failed[0].URL != "https://example.com/apps.xml" {
+		t.Fatalf("expected the missing apps manifest to be reported, got %+v", failed)
+	}
+
+	if _, err := NewSuperManifestFromContents(contents, []string{"https://example.com/other.xml"}); err == nil {
+		t.Fatal("expected an error for a super manifest missing from the contents")
+	}
+	if _, err := NewSuperManifestFromContents(contents, nil); err == nil {
+		t.Fatal("expected an error without a super manifest URL")
+	}
+}
@@ -0,0 +1,312 @@
+package mtbmanifest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Daemon
+// ////////////////////////////////////////////////////////////////////////
+
+// An editor plugin that runs the CLI for every completion pays for loading the tree each time.
+// A Daemon keeps one tree warm in a LiveSuperManifest and answers queries over a local socket,
+// in milliseconds. The protocol is JSON, one object per line: a request names a method and
+// carries its params, and the response carries the id of the request and either a result or
+// an error. A connection may send any number of requests, answered in order; connections are
+// served at once. Unix sockets are also what Windows 10 and later offer for this, so the
+// daemon listens on one everywhere (see ListenDaemon).
+//
+// The methods are
+//
+//	ping                                       sizes and sources of the tree
+//	list       {"kind"}                        IDs of the boards, apps or middleware
+//	get        {"kind", "id"}                  a board, app or middleware item
+//	search     {"query", "kinds", "limit", "matchAll"}
+//	compatible {"board", "middleware"}         what runs on a board (see CompatibilityMatrix)
+//	recommend  {"board", "selected", "limit"}  middleware to add (see Recommend)
+//	refresh                                    refresh the tree now (see LiveSuperManifest)
+
+// DaemonRequest is a request to a Daemon
+type DaemonRequest struct {
+	// ID is echoed in the response; any JSON value
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// DaemonResponse is the answer to a DaemonRequest: a result, or an error
+type DaemonResponse struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Result any             `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Daemon answers queries about a tree over a socket
+type Daemon struct {
+	live *LiveSuperManifest
+	// RefreshInterval, when positive, refreshes the tree this often while the daemon serves
+	RefreshInterval time.Duration
+	// OnRefresh, when set, is called after each refresh, with what changed or why it failed
+	OnRefresh func(*ChangeSet, error)
+}
+
+// NewDaemon creates a daemon answering about the tree of live
+func NewDaemon(live *LiveSuperManifest) *Daemon {
+	return &Daemon{live: live}
+}
+
+// DefaultDaemonSocket returns the default path of the daemon socket,
+// ~/.modustoolbox/mtbmcp/gomtb-manifest.sock
+func DefaultDaemonSocket() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(home, ".modustoolbox", "mtbmcp", "gomtb-manifest.sock")
+}
+
+// ListenDaemon listens on a Unix socket at path, only for the current user. The socket file
+// of a daemon that is gone is replaced; that of a running one is an error.
+func ListenDaemon(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("a daemon is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return listenPrivate(path)
+}
+
+// Serve answers the connections of l until ctx ends, then closes l and the connections
+func (d *Daemon) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	if d.RefreshInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.refreshEvery(ctx)
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (d *Daemon) refreshEvery(ctx context.Context) {
+	ticker := time.NewTicker(d.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changes, err := d.live.Refresh(ctx)
+			if d.OnRefresh != nil {
+				d.OnRefresh(changes, err)
+			}
+		}
+	}
+}
+
+// serveConn answers the requests of one connection, in order, until it is closed
+func (d *Daemon) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		req := &DaemonRequest{}
+		var resp *DaemonResponse
+		if err := json.Unmarshal(scanner.Bytes(), req); err != nil {
+			resp = &DaemonResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else {
+			resp = d.Handle(ctx, req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+type daemonParams struct {
+	Kind       ItemKind   `json:"kind"`
+	ID         string     `json:"id"`
+	Query      string     `json:"query"`
+	Kinds      []ItemKind `json:"kinds"`
+	Limit      int        `json:"limit"`
+	MatchAll   bool       `json:"matchAll"`
+	Board      string     `json:"board"`
+	Middleware bool       `json:"middleware"`
+	Selected   []string   `json:"selected"`
+}
+
+// Handle answers one request, as Serve does, for programs that carry requests themselves
+func (d *Daemon) Handle(ctx context.Context, req *DaemonRequest) *DaemonResponse {
+	resp := &DaemonResponse{ID: req.ID}
+	p := &daemonParams{}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, p); err != nil {
+			resp.Error = fmt.Sprintf("invalid params: %v", err)
+			return resp
+		}
+	}
+	result, err := d.call(ctx, req.Method, p)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Result = result
+	}
+	return resp
+}
+
+func (d *Daemon) call(ctx context.Context, method string, p *daemonParams) (any, error) {
+	sm := d.live.Tree() // one tree for the whole request
+	switch method {
+	case "ping":
+		return map[string]any{"boards": len(sm.GetBoardIDs()), "apps": len(sm.GetAppIDs()),
+			"middleware": len(sm.GetMiddlewareIDs()), "sources": sm.GetSourceURLs()}, nil
+	case "list":
+		switch p.Kind {
+		case ItemKindBoard:
+			return sm.GetBoardIDs(), nil
+		case ItemKindApp:
+			return sm.GetAppIDs(), nil
+		case ItemKindMiddleware:
+			return sm.GetMiddlewareIDs(), nil
+		}
+		return nil, fmt.Errorf("unknown kind %q", p.Kind)
+	case "get":
+		var item any
+		var ok bool
+		switch p.Kind {
+		case ItemKindBoard:
+			item, ok = sm.GetBoard(p.ID)
+		case ItemKindApp:
+			item, ok = sm.GetApp(p.ID)
+		case ItemKindMiddleware:
+			item, ok = sm.GetMiddleware(p.ID)
+		default:
+			return nil, fmt.Errorf("unknown kind %q", p.Kind)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s %s not found", p.Kind, p.ID)
+		}
+		return item, nil
+	case "search":
+		return sm.Search(p.Query, &SearchOptions{Kinds: p.Kinds, Limit: p.Limit, MatchAll: p.MatchAll}), nil
+	case "compatible":
+		if _, ok := sm.GetBoard(p.Board); !ok {
+			return nil, fmt.Errorf("board %s not found", p.Board)
+		}
+		cells := []*MatrixCell{}
+		for _, cell := range BuildCompatibilityMatrix(sm, &MatrixOptions{Middleware: p.Middleware}).Cells {
+			if idKey(cell.Board) == idKey(p.Board) {
+				cells = append(cells, cell)
+			}
+		}
+		return cells, nil
+	case "recommend":
+		return Recommend(sm, p.Board, p.Selected, &RecommendOptions{Limit: p.Limit})
+	case "refresh":
+		return d.live.Refresh(ctx)
+	}
+	return nil, fmt.Errorf("unknown method %q", method)
+}
+
+// DaemonClient sends requests to a daemon over one connection. Calls may be made from several
+// goroutines; they are sent one at a time.
+type DaemonClient struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	scanner *bufio.Scanner
+	next    int
+}
+
+// DialDaemon connects to the daemon listening on the Unix socket at path
+func DialDaemon(path string) (*DaemonClient, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return &DaemonClient{conn: conn, scanner: scanner}, nil
+}
+
+// Call sends a request with params, nil for none, and decodes its result into result, unless
+// nil
+func (c *DaemonClient) Call(method string, params, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	req := map[string]any{"id": c.next, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		return err
+	}
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return err
+		}
+		return errors.New("the daemon closed the connection")
+	}
+	resp := &struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}{}
+	if err := json.Unmarshal(c.scanner.Bytes(), resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// Close closes the connection
+func (c *DaemonClient) Close() error {
+	return c.conn.Close()
+}
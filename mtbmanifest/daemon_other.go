@@ -0,0 +1,11 @@
+//go:build !unix
+
+package mtbmanifest
+
+import "net"
+
+// listenPrivate listens on a Unix socket at path. Windows has no mode bits for a socket; who
+// may connect follows the access control list of its directory.
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
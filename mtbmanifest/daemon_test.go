@@ -0,0 +1,99 @@
+package mtbmanifest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestDaemon(t *testing.T) {
+	live, err := NewLiveSuperManifest(newTestSuperManifest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Socket paths are short on some systems, so not under t.TempDir()
+	dir, err := os.MkdirTemp("", "mtbd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "d.sock")
+	l, err := ListenDaemon(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(socket); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+		t.Errorf("expected the socket to be private, got mode %v", fi.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the socket in its directory, got %d entries", len(entries))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- NewDaemon(live).Serve(ctx, l) }()
+
+	if _, err := ListenDaemon(socket); err == nil {
+		t.Error("expected a second daemon on the socket to be refused")
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := DialDaemon(socket)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer client.Close()
+			results := []*SearchResult{}
+			if err := client.Call("search", map[string]any{"query": "hello world"}, &results); err != nil ||
+				len(results) != 1 || results[0].ID != "mtb-example-hal-hello-world" {
+				t.Errorf("unexpected search results %+v, %v", results, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	client, err := DialDaemon(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ping := map[string]any{}
+	if err := client.Call("ping", nil, &ping); err != nil || ping["boards"] != float64(2) {
+		t.Errorf("unexpected ping %v, %v", ping, err)
+	}
+	board := &Board{}
+	if err := client.Call("get", map[string]any{"kind": "board", "id": "CY8CKIT-149"}, board); err != nil || board.Name != "PSoC 4100S Plus Prototyping Kit" {
+		t.Errorf("unexpected board %+v, %v", board, err)
+	}
+	cells := []*MatrixCell{}
+	if err := client.Call("compatible", map[string]any{"board": "CY8CKIT-149", "middleware": true}, &cells); err != nil || len(cells) != 3 {
+		t.Errorf("expected 2 examples and FreeRTOS, got %d cells, %v", len(cells), err)
+	}
+	if err := client.Call("get", map[string]any{"kind": "board", "id": "nope"}, nil); err == nil {
+		t.Error("expected an error for an unknown board")
+	}
+	if err := client.Call("frobnicate", nil, nil); err == nil {
+		t.Error("expected an error for an unknown method")
+	}
+	if err := client.Call("refresh", nil, nil); err == nil {
+		t.Error("expected a tree assembled in memory not to refresh")
+	}
+
+	cancel()
+	if err := <-served; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Serve to end with the context, got %v", err)
+	}
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the socket to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+//go:build unix
+
+package mtbmanifest
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// listenPrivate listens on a Unix socket at path that only the current user may connect to.
+// The socket is made in a new directory only the user can enter, made private there, and only
+// then linked at path, so no one else can connect to it at any time. Linking, unlike renaming,
+// fails if another daemon took path meanwhile.
+func listenPrivate(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".gomtb-manifest-")
+	if err != nil {
+		return nil, err
+	}
+	made := filepath.Join(dir, "sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: made, Net: "unix"})
+	if err != nil {
+		return nil, errors.Join(err, os.RemoveAll(dir))
+	}
+	l.SetUnlinkOnClose(false) // the socket is removed from path instead, see Close
+	if err := os.Chmod(made, 0o600); err != nil {
+		return nil, errors.Join(err, l.Close(), os.RemoveAll(dir))
+	}
+	if err := os.Link(made, path); err != nil {
+		return nil, errors.Join(err, l.Close(), os.RemoveAll(dir))
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, errors.Join(err, l.Close(), os.Remove(path))
+	}
+	return &linkedListener{UnixListener: l, path: path}, nil
+}
+
+// linkedListener listens on a socket that was linked at path
+type linkedListener struct {
+	*net.UnixListener
+	path   string
+	remove sync.Once
+}
+
+// Addr returns the address of the socket at path
+func (l *linkedListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close removes the socket, once, and stops listening. The socket goes first, so that it is
+// gone by the time Accept fails.
+func (l *linkedListener) Close() error {
+	var err error
+	l.remove.Do(func() {
+		if rmErr := os.Remove(l.path); !errors.Is(rmErr, fs.ErrNotExist) {
+			err = rmErr
+		}
+	})
+	return errors.Join(err, l.UnixListener.Close())
+}
@@ -0,0 +1,108 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Deprecation policy
+// ////////////////////////////////////////////////////////////////////////
+
+// A symbol that is replaced is not removed right away. It gets a "Deprecated:" paragraph in
+// its doc comment, an entry in Deprecations, and a call to warnDeprecated so that programs
+// still using it find out at run time: the first call in a process logs a warning, later ones
+// are silent. It is removed after it has been deprecated for at least two minor releases.
+// DeprecatedSymbolsIn and WriteMigrationGuide tell the owner of a binary what is left to
+// migrate.
+
+// Deprecation describes one deprecated symbol of this package
+type Deprecation struct {
+	// Symbol is the name as it appears in Go symbol tables, without the package path, e.g.
+	// "(*SuperManifest).GetBoardsMap"
+	Symbol      string `json:"symbol"`
+	Replacement string `json:"replacement"`
+	Reason      string `json:"reason"`
+}
+
+// Deprecations lists the deprecated symbols of this package
+var Deprecations = []*Deprecation{
+	{Symbol: "(*SuperManifest).GetBoardsMap", Replacement: "BoardsByID or Boards",
+		Reason: "returns the tree's own index, which callers must not modify"},
+	{Symbol: "(*SuperManifest).GetAppsMap", Replacement: "AppsByID or Apps",
+		Reason: "returns the tree's own index, which callers must not modify"},
+	{Symbol: "(*SuperManifest).GetMiddlewareMap", Replacement: "MiddlewareByID or Middleware",
+		Reason: "returns the tree's own index, which callers must not modify"},
+	{Symbol: "NewManifestCacheWithStore", Replacement: "NewManifestCache(WithStore(store), WithCacheTTL(ttl)) and Start",
+		Reason: "starts a goroutine in the constructor"},
+}
+
+// packagePath is the import path of this package, which prefixes its symbols in binaries
+var packagePath = reflect.TypeOf(SuperManifest{}).PkgPath()
+
+var (
+	doDeprecationWarnings = true
+	warnedDeprecations    sync.Map
+)
+
+// EnableDeprecationWarnings enables or disables the warnings logged when deprecated symbols
+// are used. They are on by default.
+func EnableDeprecationWarnings(enable bool) {
+	doDeprecationWarnings = enable
+}
+
+// warnDeprecated logs a warning the first time a deprecated symbol is used in the process
+func warnDeprecated(symbol string) {
+	if !doDeprecationWarnings {
+		return
+	}
+	if _, warned := warnedDeprecations.LoadOrStore(symbol, true); warned {
+		return
+	}
+	msg := fmt.Sprintf("%s is deprecated", symbol)
+	for _, d := range Deprecations {
+		if d.Symbol == symbol {
+			msg += fmt.Sprintf(", use %s instead", d.Replacement)
+			break
+		}
+	}
+	logger.Warningf("%s\n", msg)
+}
+
+// DeprecatedSymbolsIn returns the deprecated symbols a Go binary still contains. The linker
+// drops functions nothing calls, so what is left is referenced, although a method can also be
+// kept because a method of the same name is called through an interface.
+func DeprecatedSymbolsIn(binaryPath string) ([]*Deprecation, error) {
+	data, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	ret := []*Deprecation{}
+	for _, d := range Deprecations {
+		if bytes.Contains(data, []byte(packagePath+"."+d.Symbol)) {
+			ret = append(ret, d)
+		}
+	}
+	return ret, nil
+}
+
+// WriteMigrationGuide writes a Markdown guide to replacing the given deprecated symbols
+func WriteMigrationGuide(w io.Writer, deprecations []*Deprecation) error {
+	if _, err := fmt.Fprintf(w, "# Migration guide for %s\n\n", packagePath); err != nil {
+		return err
+	}
+	if len(deprecations) == 0 {
+		_, err := fmt.Fprintf(w, "Nothing to migrate: no deprecated symbols are used.\n")
+		return err
+	}
+	fmt.Fprintf(w, "| Deprecated | Use instead | Why |\n|---|---|---|\n")
+	for _, d := range deprecations {
+		fmt.Fprintf(w, "| `%s` | %s | %s |\n", d.Symbol, d.Replacement, d.Reason)
+	}
+	_, err := fmt.Fprintf(w, "\nDeprecated symbols are removed after at least two minor releases.\n")
+	return err
+}
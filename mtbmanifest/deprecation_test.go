@@ -0,0 +1,51 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	rec := NewRecordingLogger(nil, 10)
+	saved := logger
+	SetLogger(rec)
+	defer SetLogger(saved)
+	warnedDeprecations.Delete("(*SuperManifest).GetBoardsMap")
+
+	sm := newTestSuperManifest(t)
+	sm.GetBoard("CY8CKIT-149") // not deprecated, must not warn
+	if len(rec.Records()) != 0 {
+		t.Fatalf("unexpected warnings %v", rec.Records())
+	}
+	sm.GetBoardsMap()
+	sm.GetBoardsMap()
+	records := rec.Records()
+	if len(records) != 1 || !strings.Contains(records[0].Message, "use BoardsByID or Boards instead") {
+		t.Errorf("expected a single warning, got %v", records)
+	}
+}
+
+func TestMigrationGuide(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "prog")
+	content := "\x7fELF...github.com/haneefdm/gomtb-manifest/mtbmanifest.(*SuperManifest).GetAppsMap\x00..."
+	if err := os.WriteFile(binary, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	found, err := DeprecatedSymbolsIn(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Symbol != "(*SuperManifest).GetAppsMap" {
+		t.Fatalf("unexpected symbols %v", found)
+	}
+	var buf bytes.Buffer
+	if err := WriteMigrationGuide(&buf, found); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "| `(*SuperManifest).GetAppsMap` | AppsByID or Apps |") {
+		t.Errorf("unexpected guide:\n%s", buf.String())
+	}
+}
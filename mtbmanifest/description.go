@@ -0,0 +1,95 @@
+package mtbmanifest
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Descriptions in the manifests are CDATA blobs that often carry HTML. The helpers here turn
+// them into plain text that is safe to put in a table cell or a JSON string.
+
+var (
+	cdataRegex    = regexp.MustCompile(`<!\[CDATA\[|\]\]>`)
+	breakTagRegex = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6]|/tr)\s*/?\s*>`)
+	listTagRegex  = regexp.MustCompile(`(?i)<\s*li[^>]*>`)
+	anyTagRegex   = regexp.MustCompile(`<[^>]*>`)
+	spaceRegex    = regexp.MustCompile(`[ \t\r\f\v]+`)
+	newlinesRegex = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// PlainText strips CDATA markers and HTML tags from text, decodes HTML entities and collapses
+// whitespace. Block level tags (paragraphs, line breaks, list items) become line breaks.
+func PlainText(text string) string {
+	text = cdataRegex.ReplaceAllString(text, "")
+	text = breakTagRegex.ReplaceAllString(text, "\n")
+	text = listTagRegex.ReplaceAllString(text, "\n- ")
+	text = anyTagRegex.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	text = spaceRegex.ReplaceAllString(text, " ")
+	text = newlinesRegex.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
+
+// Excerpt returns at most maxLen characters (runes) of plain text on a single line. Longer text
+// is cut at the last word boundary that fits and gets a trailing ellipsis. maxLen <= 0 means
+// no limit.
+func Excerpt(text string, maxLen int) string {
+	text = strings.Join(strings.Fields(PlainText(text)), " ")
+	runes := []rune(text)
+	if maxLen <= 0 || len(runes) <= maxLen {
+		return text
+	}
+	if maxLen == 1 {
+		return "…"
+	}
+	cut := runes[:maxLen-1] // leave room for the ellipsis
+	if !unicode.IsSpace(runes[maxLen-1]) {
+		// Back up to the previous word boundary, unless the first word alone is too long
+		for i := len(cut) - 1; i > 0; i-- {
+			if unicode.IsSpace(cut[i]) {
+				cut = cut[:i]
+				break
+			}
+		}
+	}
+	return strings.TrimRightFunc(string(cut), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// PlainDescription returns the board description as plain text
+func (b *Board) PlainDescription() string {
+	return PlainText(b.Description)
+}
+
+// Excerpt returns the board description as a single line of at most maxLen characters.
+// Falls back to the summary when there is no description.
+func (b *Board) Excerpt(maxLen int) string {
+	if strings.TrimSpace(b.Description) == "" {
+		return Excerpt(b.Summary, maxLen)
+	}
+	return Excerpt(b.Description, maxLen)
+}
+
+// PlainDescription returns the app description as plain text
+func (a *App) PlainDescription() string {
+	return PlainText(a.Description)
+}
+
+// Excerpt returns the app description as a single line of at most maxLen characters
+func (a *App) Excerpt(maxLen int) string {
+	return Excerpt(a.Description, maxLen)
+}
+
+// PlainDescription returns the middleware description as plain text
+func (mw *MiddlewareItem) PlainDescription() string {
+	return PlainText(mw.Description)
+}
+
+// Excerpt returns the middleware description as a single line of at most maxLen characters
+func (mw *MiddlewareItem) Excerpt(maxLen int) string {
+	return Excerpt(mw.Description, maxLen)
+}
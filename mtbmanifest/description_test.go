@@ -0,0 +1,68 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "tags and entities",
+			input:    `<p>Blinks an <b>LED</b> &amp; prints &quot;Hello&quot;</p>`,
+			expected: `Blinks an LED & prints "Hello"`,
+		},
+		{
+			name:     "cdata markers",
+			input:    `<![CDATA[Plain text]]>`,
+			expected: `Plain text`,
+		},
+		{
+			name:     "breaks and lists",
+			input:    "First line<br/>Second line<ul><li>one</li><li>two</li></ul>",
+			expected: "First line\nSecond line\n- one\n- two",
+		},
+		{
+			name:     "whitespace",
+			input:    "  lots \t of\n\n   space&nbsp;here ",
+			expected: "lots of\nspace here",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(tt.input); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		maxLen   int
+		expected string
+	}{
+		{"short", "Hello world", 20, "Hello world"},
+		{"no limit", "Hello world", 0, "Hello world"},
+		{"word boundary", "Connects to a TCP server over Wi-Fi.", 20, "Connects to a TCP…"},
+		{"trailing punctuation", "One, two, three, four", 10, "One, two…"},
+		{"long first word", "Supercalifragilistic", 8, "Superca…"},
+		{"multi line", "<p>First</p><p>Second</p>", 0, "First Second"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Excerpt(tt.input, tt.maxLen)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if tt.maxLen > 0 && len([]rune(got)) > tt.maxLen {
+				t.Errorf("excerpt %q longer than %d", got, tt.maxLen)
+			}
+		})
+	}
+}
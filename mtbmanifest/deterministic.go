@@ -0,0 +1,42 @@
+package mtbmanifest
+
+import (
+	"sort"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Deterministic mode
+// ////////////////////////////////////////////////////////////////////////
+
+// When deterministic mode is on, everything that would otherwise depend on Go's randomized
+// map iteration or on goroutine scheduling (callback order, match results, merge warnings)
+// is forced into a stable order. Exports, reports and logs are then byte-identical across
+// runs given the same input, which is what golden-file tests need. It costs a few sorts and
+// serializes fetch callbacks, so it is off by default.
+var doDeterministic = false
+
+// EnableDeterministicMode enables or disables deterministic iteration order
+func EnableDeterministicMode(enable bool) {
+	if enable {
+		logger.Infof("Deterministic Mode Enabled\n")
+	}
+	doDeterministic = enable
+}
+
+// IsDeterministicMode reports whether deterministic iteration order is enabled
+func IsDeterministicMode() bool {
+	return doDeterministic
+}
+
+// orderedKeys returns the keys of a map. In deterministic mode they are sorted, otherwise
+// they come back in whatever order the map iteration produces.
+func orderedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	if doDeterministic {
+		sort.Strings(keys)
+	}
+	return keys
+}
@@ -0,0 +1,67 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDeterministicCallbackOrder(t *testing.T) {
+	EnableDeterministicMode(true)
+	defer EnableDeterministicMode(false)
+
+	cache := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+
+	urls := []*FetchUrlWithCb{}
+	order := []int{}
+	for i := 0; i < 20; i++ {
+		u := fmt.Sprintf("https://example.com/manifest-%02d.xml", i)
+		if err := cache.writeCache(u, []byte(u)); err != nil {
+			t.Fatalf("failed to seed cache: %v", err)
+		}
+		urls = append(urls, &FetchUrlWithCb{
+			Url: u, Index: i,
+			Callback: func(urlStr string, data []byte, err error, index int) {
+				if err != nil {
+					t.Errorf("unexpected error for %s: %v", urlStr, err)
+				}
+				if string(data) != urlStr {
+					t.Errorf("expected content %q, got %q", urlStr, string(data))
+				}
+				order = append(order, index)
+			},
+		})
+	}
+
+	fetcher := NewManifestFetcher(WithCache(cache), WithMaxConcurrent(8))
+	results := fetcher.FetchAllWithCb(urls)
+	if len(results) != len(urls) {
+		t.Fatalf("expected %d results, got %d", len(urls), len(results))
+	}
+	for i, index := range order {
+		if i != index {
+			t.Fatalf("callbacks out of order: %v", order)
+		}
+	}
+	if len(order) != len(urls) {
+		t.Errorf("expected %d callbacks, got %d", len(urls), len(order))
+	}
+}
+
+func TestOrderedKeys(t *testing.T) {
+	EnableDeterministicMode(true)
+	defer EnableDeterministicMode(false)
+
+	m := map[string]int{"zeta": 1, "alpha": 2, "mu": 3, "beta": 4}
+	keys := orderedKeys(m)
+	expected := []string{"alpha", "beta", "mu", "zeta"}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), len(keys))
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("key %d: expected %q, got %q", i, expected[i], keys[i])
+		}
+	}
+}
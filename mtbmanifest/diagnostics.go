@@ -0,0 +1,312 @@
+package mtbmanifest
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Diagnostics bundles
+// ////////////////////////////////////////////////////////////////////////
+
+// A Diagnostics bundle is what a support ticket needs to reproduce a problem: the environment,
+// the state of the cache, the last ingest report, recent errors and the configuration. It is
+// meant to be shared, so secrets are removed before anything is written: values of keys that
+// look like secrets, passwords in URLs and the values of secret-looking environment variables
+// become "[REDACTED]".
+
+// Redacted replaces a secret
+const Redacted = "[REDACTED]"
+
+// secretNameRegex matches key and variable names whose values are secrets. Names ending in
+// "Env" hold the name of a variable, not the secret, and are kept.
+var secretNameRegex = regexp.MustCompile(`(?i)(token|passw|secret|credential|auth|apikey|api_key|private|access_key|signature)`)
+
+func isSecretName(name string) bool {
+	return secretNameRegex.MatchString(name) && !strings.HasSuffix(name, "Env")
+}
+
+// RedactURL removes the password of a URL such as http://user:pw@proxy:8080 and the values of
+// secret-looking query parameters
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return s
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		redacted := false
+		for name := range q {
+			if isSecretName(name) {
+				q.Set(name, Redacted)
+				redacted = true
+			}
+		}
+		if redacted {
+			u.RawQuery = q.Encode()
+		}
+	}
+	if u.User == nil && !strings.Contains(u.RawQuery, url.QueryEscape(Redacted)) {
+		return s
+	}
+	return u.String()
+}
+
+// RedactJSON returns a copy of a JSON document with the values of secret-looking keys replaced
+// and passwords removed from the URLs in its strings
+func RedactJSON(data []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(redactValue(v), "", "  ")
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if _, isString := item.(string); isString && isSecretName(k) {
+				v[k] = Redacted
+			} else {
+				v[k] = redactValue(item)
+			}
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+		return v
+	case string:
+		return redactMessage(v)
+	}
+	return v
+}
+
+// LogRecord is one message kept by a RecordingLogger
+type LogRecord struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// RecordingLogger passes messages on to another logger and keeps the latest warnings and
+// errors for a diagnostics bundle. Install it with SetLogger.
+type RecordingLogger struct {
+	next    LoggerIF
+	max     int
+	mu      sync.Mutex
+	records []*LogRecord
+}
+
+// NewRecordingLogger returns a logger that keeps up to max warnings and errors. A nil next
+// discards the messages after recording them.
+func NewRecordingLogger(next LoggerIF, max int) *RecordingLogger {
+	return &RecordingLogger{next: next, max: max}
+}
+
+func (l *RecordingLogger) record(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, &LogRecord{Time: time.Now().UTC(), Level: level,
+		Message: strings.TrimSpace(fmt.Sprintf(format, args...))})
+	if len(l.records) > l.max {
+		l.records = l.records[len(l.records)-l.max:]
+	}
+}
+
+// Records returns the kept messages, oldest first
+func (l *RecordingLogger) Records() []*LogRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*LogRecord{}, l.records...)
+}
+
+func (l *RecordingLogger) Infof(format string, args ...interface{}) {
+	if l.next != nil {
+		l.next.Infof(format, args...)
+	}
+}
+
+func (l *RecordingLogger) Debugf(format string, args ...interface{}) {
+	if l.next != nil {
+		l.next.Debugf(format, args...)
+	}
+}
+
+func (l *RecordingLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+	if l.next != nil {
+		l.next.Errorf(format, args...)
+	}
+}
+
+func (l *RecordingLogger) Warningf(format string, args ...interface{}) {
+	l.record("warning", format, args...)
+	if l.next != nil {
+		l.next.Warningf(format, args...)
+	}
+}
+
+// CacheStats summarizes the entries of a cache
+type CacheStats struct {
+	Entries int                  `json:"entries"`
+	Bytes   int                  `json:"bytes"`
+	Stale   int                  `json:"stale"`
+	Oldest  time.Time            `json:"oldest,omitzero"`
+	Newest  time.Time            `json:"newest,omitzero"`
+	ByKind  map[ManifestKind]int `json:"byKind"`
+}
+
+// Stats summarizes the entries of the cache
+func (c *ManifestCache) Stats() (*CacheStats, error) {
+	entries, err := c.store.List()
+	if err != nil {
+		return nil, err
+	}
+	stats := &CacheStats{ByKind: make(map[ManifestKind]int)}
+	for _, e := range entries {
+		stats.Entries++
+		stats.Bytes += e.Size
+		stats.ByKind[ManifestKindOf(e.URL)]++
+		if time.Since(e.ModTime) >= c.TTL(e.URL) {
+			stats.Stale++
+		}
+		if stats.Oldest.IsZero() || e.ModTime.Before(stats.Oldest) {
+			stats.Oldest = e.ModTime
+		}
+		if e.ModTime.After(stats.Newest) {
+			stats.Newest = e.ModTime
+		}
+	}
+	return stats, nil
+}
+
+// EnvironmentInfo describes the machine and build the tool runs on
+type EnvironmentInfo struct {
+	GoVersion  string `json:"goVersion"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	NumCPU     int    `json:"numCpu"`
+	Executable string `json:"executable,omitempty"`
+	Module     string `json:"module,omitempty"`
+	// Variables are the environment variables that affect the tool, redacted
+	Variables map[string]string `json:"variables"`
+}
+
+// diagnosticEnvPrefixes select the environment variables worth reporting
+var diagnosticEnvPrefixes = []string{"MTB", "CY_", "AWS_", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "no_proxy", "SSL_CERT_", "GODEBUG"}
+
+// CollectEnvironment describes the current process
+func CollectEnvironment() *EnvironmentInfo {
+	env := &EnvironmentInfo{
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		Variables: make(map[string]string),
+	}
+	env.Executable, _ = os.Executable()
+	if info, ok := debug.ReadBuildInfo(); ok {
+		env.Module = info.Main.Path + " " + info.Main.Version
+	}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		for _, prefix := range diagnosticEnvPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				if isSecretName(name) {
+					value = Redacted
+				}
+				env.Variables[name] = RedactURL(value)
+				break
+			}
+		}
+	}
+	return env
+}
+
+// Diagnostics is the content of a diagnostics bundle. Everything but Environment is optional.
+type Diagnostics struct {
+	GeneratedAt time.Time        `json:"generatedAt"`
+	Environment *EnvironmentInfo `json:"environment"`
+	Cache       *CacheStats      `json:"cache,omitempty"`
+	CacheError  string           `json:"cacheError,omitempty"`
+	Ingest      *IngestReport    `json:"ingest,omitempty"`
+	IngestError string           `json:"ingestError,omitempty"`
+	Errors      []*LogRecord     `json:"errors"`
+	// Config is the tool's configuration file, redacted by WriteZip
+	Config json.RawMessage `json:"-"`
+}
+
+// NewDiagnostics returns diagnostics with the environment filled in
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{GeneratedAt: time.Now().UTC(), Environment: CollectEnvironment(), Errors: []*LogRecord{}}
+}
+
+// WriteZip writes the bundle as a zip archive: diagnostics.json with everything but the
+// configuration, errors.log, and config.json with secrets redacted
+func (d *Diagnostics) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	add := func(name string, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: d.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Error messages can quote URLs with credentials, so the whole document goes through redaction
+	if data, err = RedactJSON(data); err != nil {
+		return err
+	}
+	if err := add("diagnostics.json", append(data, '\n')); err != nil {
+		return err
+	}
+	var log strings.Builder
+	for _, r := range d.Errors {
+		fmt.Fprintf(&log, "%s [%s] %s\n", r.Time.Format(time.RFC3339), strings.ToUpper(r.Level), redactMessage(r.Message))
+	}
+	if err := add("errors.log", []byte(log.String())); err != nil {
+		return err
+	}
+	if len(d.Config) > 0 {
+		config, err := RedactJSON(d.Config)
+		if err != nil {
+			config = []byte(fmt.Sprintf("unreadable configuration: %v", err))
+		}
+		if err := add("config.json", append(config, '\n')); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// redactMessage redacts the URLs in a log message
+func redactMessage(msg string) string {
+	for _, f := range strings.Fields(msg) {
+		f = strings.Trim(f, "'\"(),")
+		if r := RedactURL(f); r != f {
+			msg = strings.ReplaceAll(msg, f, r)
+		}
+	}
+	return msg
+}
@@ -0,0 +1,171 @@
+package mtbmanifest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Watching a local manifest directory
+// ////////////////////////////////////////////////////////////////////////
+
+// Manifest authors editing a local checkout (see ScanDir) want a running viewer to follow
+// their edits. A DirWatcher polls the manifest files of the directory; when one is added,
+// removed or modified, the directory is scanned again and the new tree swapped into a
+// LiveSuperManifest, whose readers see the old tree or the new one, never a mix. A scan in
+// which a file does not parse leaves the current tree in place, so saving a half-edited file
+// does not empty the views. Polling needs nothing outside the standard library and works
+// the same on every file system, network shares included.
+
+// DefaultDirWatchInterval is how often Run looks at the files when given no interval
+const DefaultDirWatchInterval = 500 * time.Millisecond
+
+// DirReload is the outcome of a change to the files of a watched directory
+type DirReload struct {
+	// Files are the paths, relative to the directory, that were added, removed or modified
+	Files []string `json:"files"`
+	// Scan is the new scan of the directory
+	Scan *DirScan `json:"scan"`
+	// Changes are the item changes of the new tree; nil when the tree was kept
+	Changes *ChangeSet `json:"changes,omitempty"`
+	// Err is set when the tree was kept, because a file did not parse or no tree was assembled
+	Err error `json:"-"`
+}
+
+// fileStamp is what tells a modified file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// DirWatcher keeps a LiveSuperManifest in step with the manifest files of a directory
+type DirWatcher struct {
+	dir    string
+	abs    string
+	opts   []IngestOption
+	live   *LiveSuperManifest
+	mu     sync.Mutex // serializes checks
+	stamps map[string]fileStamp
+}
+
+// NewDirWatcher scans dir (see ScanDir) and holds the tree in a LiveSuperManifest. It fails
+// when no tree can be assembled from the directory.
+func NewDirWatcher(dir string, opts ...IngestOption) (*DirWatcher, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	w := &DirWatcher{dir: dir, abs: abs, opts: opts}
+	if w.stamps, err = w.stat(); err != nil {
+		return nil, err
+	}
+	scan, err := ScanDir(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if scan.Tree == nil {
+		return nil, fmt.Errorf("no manifest files in %s", dir)
+	}
+	if w.live, err = NewLiveSuperManifest(scan.Tree); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Live returns the live tree, which follows the directory
+func (w *DirWatcher) Live() *LiveSuperManifest {
+	return w.live
+}
+
+// stat returns the stamps of the manifest files of the directory, by path
+func (w *DirWatcher) stat() (map[string]fileStamp, error) {
+	paths, err := manifestFilePaths(w.abs)
+	if err != nil {
+		return nil, err
+	}
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // removed while walking; the next check sees it gone
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// Check looks at the files once and, when any changed since the last look, scans the
+// directory again and swaps in the new tree. Returns nil when nothing changed.
+func (w *DirWatcher) Check() (*DirReload, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stamps, err := w.stat()
+	if err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	for path, stamp := range stamps {
+		if old, ok := w.stamps[path]; !ok || !old.modTime.Equal(stamp.modTime) || old.size != stamp.size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range w.stamps {
+		if _, ok := stamps[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	w.stamps = stamps
+	reload := &DirReload{Files: make([]string, 0, len(changed))}
+	for _, path := range changed {
+		rel, _ := filepath.Rel(w.abs, path)
+		reload.Files = append(reload.Files, filepath.ToSlash(rel))
+	}
+	slices.Sort(reload.Files)
+
+	scan, err := ScanDir(w.dir, w.opts...)
+	reload.Scan = scan
+	switch {
+	case err != nil:
+		reload.Err = err
+	case len(scan.Failed()) > 0:
+		f := scan.Failed()[0]
+		reload.Err = fmt.Errorf("%s: %s", f.Path, f.Error)
+	case scan.Tree == nil:
+		reload.Err = fmt.Errorf("no manifest files in %s", w.dir)
+	default:
+		reload.Changes = w.live.replace(scan.Tree.(*SuperManifest))
+	}
+	return reload, nil
+}
+
+// Run checks the files every interval (DefaultDirWatchInterval if not positive) until ctx
+// ends, calling onReload after each change
+func (w *DirWatcher) Run(ctx context.Context, interval time.Duration, onReload func(*DirReload)) error {
+	if interval <= 0 {
+		interval = DefaultDirWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			reload, err := w.Check()
+			if err != nil {
+				return err
+			}
+			if reload != nil && onReload != nil {
+				onReload(reload)
+			}
+		}
+	}
+}
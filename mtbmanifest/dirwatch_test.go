@@ -0,0 +1,90 @@
+package mtbmanifest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirWatcher(t *testing.T) {
+	dir := t.TempDir()
+	writeScanFiles(t, dir, map[string]string{"boards.xml": testBoardsXML, "apps.xml": testAppsXML})
+	w, err := NewDirWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := w.Live().Tree()
+	if reload, err := w.Check(); reload != nil || err != nil {
+		t.Fatalf("expected no reload without changes, got %+v, %v", reload, err)
+	}
+
+	added := strings.Replace(testBoardsXML, "</boards>",
+		"<board><id>KIT-NEW</id><name>New Kit</name></board></boards>", 1)
+	writeScanFiles(t, dir, map[string]string{"boards.xml": added})
+	reload, err := w.Check()
+	if err != nil || reload == nil || reload.Err != nil {
+		t.Fatalf("expected a reload, got %+v, %v", reload, err)
+	}
+	if len(reload.Files) != 1 || reload.Files[0] != "boards.xml" {
+		t.Errorf("expected boards.xml to have changed, got %v", reload.Files)
+	}
+	if len(reload.Changes.Added) != 1 || reload.Changes.Added[0].ID != "KIT-NEW" {
+		t.Errorf("expected the new board to be added, got %+v", reload.Changes)
+	}
+	if _, ok := w.Live().Tree().GetBoard("KIT-NEW"); !ok {
+		t.Error("expected the live tree to have the new board")
+	}
+	if _, ok := before.GetBoard("KIT-NEW"); ok {
+		t.Error("expected the old tree to be left alone")
+	}
+
+	writeScanFiles(t, dir, map[string]string{"boards.xml": `<boards><board><id>KIT-`})
+	reload, err = w.Check()
+	if err != nil || reload == nil || reload.Err == nil || reload.Changes != nil {
+		t.Fatalf("expected a failed reload, got %+v, %v", reload, err)
+	}
+	if _, ok := w.Live().Tree().GetBoard("KIT-NEW"); !ok {
+		t.Error("expected a file that does not parse to leave the tree in place")
+	}
+}
+
+func TestDirWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	writeScanFiles(t, dir, map[string]string{"boards.xml": testBoardsXML})
+	w, err := NewDirWatcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan *DirReload, 10)
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, 10*time.Millisecond, func(r *DirReload) {
+			if r.Err == nil {
+				select {
+				case reloads <- r:
+				default:
+				}
+			}
+		})
+	}()
+	writeScanFiles(t, dir, map[string]string{"apps.xml": testAppsXML})
+	select {
+	case r := <-reloads:
+		if len(r.Changes.Added) != 3 {
+			t.Errorf("expected the apps to be added, got %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected a reload")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected Run to end with the context, got %v", err)
+	}
+
+	if _, err := NewDirWatcher(t.TempDir()); err == nil {
+		t.Error("expected an empty directory to be refused")
+	}
+}
@@ -0,0 +1,171 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Planned cache changes
+// ////////////////////////////////////////////////////////////////////////
+
+// Clearing the cache, installing a bundle over it or pruning its old files cannot be undone,
+// so each of these operations can first tell what it would do. The Plan functions return the
+// entries and files an operation would add, overwrite or remove, without changing anything;
+// the operations themselves act on exactly what their plan lists.
+
+// CacheChangeKind is what an operation does to one cache entry or file
+type CacheChangeKind string
+
+const (
+	ChangeAdd       CacheChangeKind = "add"       // the entry or file does not exist yet
+	ChangeOverwrite CacheChangeKind = "overwrite" // the entry or file is replaced
+	ChangeRemove    CacheChangeKind = "remove"    // the entry or file is deleted
+)
+
+// CacheChange is one entry or file an operation adds, overwrites or removes
+type CacheChange struct {
+	Kind CacheChangeKind `json:"kind"`
+	// URL is the cache entry changed, Path the file for changes not keyed by URL
+	URL  string `json:"url,omitempty"`
+	Path string `json:"path,omitempty"`
+	// Size is the number of bytes written, or removed
+	Size int `json:"size"`
+}
+
+func (c *CacheChange) String() string {
+	what := c.URL
+	if what == "" {
+		what = c.Path
+	}
+	return fmt.Sprintf("%-9s %s (%d bytes)", c.Kind, what, c.Size)
+}
+
+// PlanClear returns the entries Clear would remove
+func (c *ManifestCache) PlanClear() ([]*CacheChange, error) {
+	return c.planRemove(func(*CacheEntryInfo) bool { return true })
+}
+
+// PlanClearStale returns the entries ClearStale would remove: those older than their TTL
+func (c *ManifestCache) PlanClearStale() ([]*CacheChange, error) {
+	return c.planRemove(func(entry *CacheEntryInfo) bool {
+		return time.Since(entry.ModTime) > c.TTL(entry.URL)
+	})
+}
+
+func (c *ManifestCache) planRemove(remove func(*CacheEntryInfo) bool) ([]*CacheChange, error) {
+	entries, err := c.store.List()
+	if err != nil {
+		return nil, err
+	}
+	ret := []*CacheChange{}
+	for _, entry := range entries {
+		if remove(entry) {
+			ret = append(ret, &CacheChange{Kind: ChangeRemove, URL: entry.URL, Size: entry.Size})
+		}
+	}
+	return ret, nil
+}
+
+// PlanImport reads an export or bundle as Import does and returns the entries importing it
+// would add or overwrite. It fails where Import would, e.g. for a delta bundle that does not
+// apply.
+func (c *ManifestCache) PlanImport(r io.Reader) (*BundleIndex, []*CacheChange, error) {
+	index, contents, err := ReadBundle(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := c.planImport(index, contents)
+	if err != nil {
+		return nil, nil, err
+	}
+	return index, changes, nil
+}
+
+func (c *ManifestCache) planImport(index *BundleIndex, contents map[string][]byte) ([]*CacheChange, error) {
+	// Check a delta applies before changing anything
+	for _, entry := range index.Entries {
+		if !entry.InBase {
+			continue
+		}
+		if data, err := c.readCache(entry.URL); err != nil || hashContent(data) != entry.SHA256 {
+			return nil, fmt.Errorf("delta bundle does not apply: cache does not hold the base version of %s", entry.URL)
+		}
+	}
+	ret := []*CacheChange{}
+	for _, entry := range index.Entries {
+		if entry.InBase {
+			continue
+		}
+		kind := ChangeAdd
+		if _, err := c.store.Stat(entry.URL); err == nil {
+			kind = ChangeOverwrite
+		}
+		ret = append(ret, &CacheChange{Kind: kind, URL: entry.URL, Size: len(contents[entry.URL])})
+	}
+	return ret, nil
+}
+
+// PlanInstallBundle returns the entries InstallBundle would add to or overwrite in the cache.
+// A nil cache means the default cache.
+func PlanInstallBundle(r io.Reader, cache *ManifestCache) (*BundleIndex, []*CacheChange, error) {
+	if cache == nil {
+		cache = NewManifestDefaultCache()
+		defer cache.Close()
+	}
+	return cache.PlanImport(r)
+}
+
+// PlanMigrate returns what converting the legacy files of the store does: the files of the
+// URLs a tree refers to are overwritten in the current format, and, if prune is set, the
+// others are removed as RemoveLegacyFiles does
+func (store *FileStore) PlanMigrate(referenced []string, prune bool) ([]*CacheChange, error) {
+	files, err := store.LegacyFiles()
+	if err != nil {
+		return nil, err
+	}
+	converted := make(map[string]string)
+	for _, urlStr := range referenced {
+		converted[store.urlToFilename(urlStr)] = urlStr
+	}
+	ret := []*CacheChange{}
+	for _, filename := range files {
+		info, err := os.Stat(filename)
+		if err != nil {
+			continue
+		}
+		if urlStr, ok := converted[filename]; ok {
+			ret = append(ret, &CacheChange{Kind: ChangeOverwrite, URL: urlStr, Path: filename, Size: int(info.Size())})
+		} else if prune {
+			ret = append(ret, &CacheChange{Kind: ChangeRemove, Path: filename, Size: int(info.Size())})
+		}
+	}
+	return ret, nil
+}
+
+// PlanCreate returns the index of the snapshot Create would save for the tree, and the file it
+// would write. Saving a tree that is already stored overwrites its file with the same content.
+func (ss *SnapshotStore) PlanCreate(sm SuperManifestIF, cache *ManifestCache) (*BundleIndex, *CacheChange, error) {
+	counter := &countingWriter{}
+	index, err := CreateBundle(counter, sm, cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	change := &CacheChange{Kind: ChangeAdd, Path: ss.path(index.ID()), Size: counter.n}
+	if _, err := os.Stat(change.Path); err == nil {
+		change.Kind = ChangeOverwrite
+	}
+	return index, change, nil
+}
+
+// countingWriter discards what is written and counts the bytes
+type countingWriter struct {
+	n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
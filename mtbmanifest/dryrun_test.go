@@ -0,0 +1,127 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPlanClear(t *testing.T) {
+	store := NewMemoryStore()
+	_ = store.Put("https://example.com/fresh.xml", []byte("fresh"), time.Now())
+	_ = store.Put("https://example.com/stale.xml", []byte("stale"), time.Now().Add(-48*time.Hour))
+	cache := NewManifestCache(WithStore(store), WithCacheTTL(time.Hour))
+	defer cache.Close()
+
+	stale, err := cache.PlanClearStale()
+	if err != nil || len(stale) != 1 || stale[0].URL != "https://example.com/stale.xml" || stale[0].Kind != ChangeRemove {
+		t.Fatalf("unexpected stale plan %v, %v", stale, err)
+	}
+	all, err := cache.PlanClear()
+	if err != nil || len(all) != 2 {
+		t.Fatalf("unexpected plan %v, %v", all, err)
+	}
+	if list, _ := store.List(); len(list) != 2 {
+		t.Fatal("expected planning to leave the cache alone")
+	}
+	_ = cache.ClearStale()
+	if list, _ := store.List(); len(list) != 1 || list[0].URL != "https://example.com/fresh.xml" {
+		t.Fatalf("expected ClearStale to remove what was planned, left %v", list)
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	source := NewManifestCache(WithStore(NewMemoryStore()))
+	defer source.Close()
+	_ = source.store.Put("https://example.com/a.xml", []byte("new a"), time.Time{})
+	_ = source.store.Put("https://example.com/b.xml", []byte("b"), time.Time{})
+	var buf bytes.Buffer
+	if _, err := source.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	target := NewManifestCache(WithStore(NewMemoryStore()))
+	defer target.Close()
+	_ = target.store.Put("https://example.com/a.xml", []byte("old a"), time.Time{})
+	_, changes, err := target.PlanImport(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Kind != ChangeOverwrite || changes[1].Kind != ChangeAdd || changes[1].Size != 1 {
+		t.Fatalf("unexpected plan %v", changes)
+	}
+	if data, _ := target.store.Get("https://example.com/a.xml"); string(data) != "old a" {
+		t.Fatal("expected planning to leave the cache alone")
+	}
+}
+
+func TestPlanMigrate(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	const legacyURL, orphanURL = "https://example.com/boards.xml", "https://example.com/orphan.xml"
+	for _, u := range []string{legacyURL, orphanURL} {
+		if err := os.WriteFile(store.urlToFilename(u), []byte("<boards/>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reading through a read-only view leaves the legacy file as it is
+	if data, err := store.ReadOnly().Get(legacyURL); err != nil || string(data) != "<boards/>" {
+		t.Fatalf("expected the legacy content, got %q, %v", data, err)
+	}
+	if files, _ := store.LegacyFiles(); len(files) != 2 {
+		t.Fatal("expected the read-only view not to convert the file")
+	}
+	if err := store.ReadOnly().Put(legacyURL, nil, time.Time{}); err == nil {
+		t.Fatal("expected a read-only store to refuse writes")
+	}
+
+	changes, err := store.PlanMigrate([]string{legacyURL}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Kind != ChangeOverwrite || changes[0].URL != legacyURL ||
+		changes[1].Kind != ChangeRemove || changes[1].Path != store.urlToFilename(orphanURL) {
+		t.Fatalf("unexpected plan %v", changes)
+	}
+	if changes, _ := store.PlanMigrate([]string{legacyURL}, false); len(changes) != 1 {
+		t.Fatalf("expected nothing removed without pruning, got %v", changes)
+	}
+}
+
+func TestPlanCreateSnapshot(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	for u, data := range map[string]string{
+		superURL:                             testSuperXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm, err := newSuperManifestFromCache(superURL, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss := NewSnapshotStore(t.TempDir())
+	index, change, err := ss.PlanCreate(sm, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Kind != ChangeAdd || change.Path != ss.path(index.ID()) || change.Size == 0 {
+		t.Fatalf("unexpected plan %+v", change)
+	}
+	if list, _ := ss.List(); len(list) != 0 {
+		t.Fatal("expected planning to write nothing")
+	}
+	if _, err := ss.Create(sm, cache); err != nil {
+		t.Fatal(err)
+	}
+	if _, change, _ := ss.PlanCreate(sm, cache); change.Kind != ChangeOverwrite {
+		t.Fatalf("expected the existing snapshot to be overwritten, got %+v", change)
+	}
+}
@@ -0,0 +1,139 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Chip families
+// ////////////////////////////////////////////////////////////////////////
+
+// ModusToolbox groups devices into families (categories in its own terms) that share a HAL
+// and PDL: CAT1A is PSoC 6, CAT2 is PSoC 4, and so on. BSPs usually say which one they belong
+// to with a capability token such as "cat1a", but some only say "cat1", so the MCU part
+// number is consulted as well.
+
+// Chip families, as used in capability tokens but upper case
+const (
+	FamilyCAT1A   = "CAT1A" // PSoC 6
+	FamilyCAT1B   = "CAT1B" // CYW20829, PSoC Control C3
+	FamilyCAT1C   = "CAT1C" // XMC7000, TRAVEO T2G
+	FamilyCAT1D   = "CAT1D" // PSoC Edge
+	FamilyCAT2    = "CAT2"  // PSoC 4, PMG1
+	FamilyCAT3    = "CAT3"  // XMC1000, XMC4000
+	FamilyCAT4    = "CAT4"  // CYW43907
+	FamilyCAT5    = "CAT5"  // AIROC Bluetooth, CYW20xxx, CYW55xxx
+	FamilyUnknown = ""
+)
+
+// familyTokens maps capability tokens to families, most specific first
+var familyTokens = []struct {
+	token  string
+	family string
+}{
+	{"cat1a", FamilyCAT1A}, {"cat1b", FamilyCAT1B}, {"cat1c", FamilyCAT1C}, {"cat1d", FamilyCAT1D},
+	{"cat2", FamilyCAT2}, {"cat3", FamilyCAT3}, {"cat4", FamilyCAT4}, {"cat5", FamilyCAT5},
+	{"psoc6", FamilyCAT1A}, {"psoc4", FamilyCAT2},
+}
+
+// familyPrefixes maps MCU part number prefixes to families, longest first where they overlap
+var familyPrefixes = []struct {
+	prefix string
+	family string
+}{
+	{"CYW20829", FamilyCAT1B}, {"CYW89829", FamilyCAT1B}, {"PSC3", FamilyCAT1B},
+	{"CY8C6", FamilyCAT1A}, {"CYB06", FamilyCAT1A}, {"CYS06", FamilyCAT1A},
+	{"XMC7", FamilyCAT1C}, {"CYT", FamilyCAT1C},
+	{"PSE8", FamilyCAT1D},
+	{"CY8C4", FamilyCAT2}, {"CYPM", FamilyCAT2}, {"PMG1", FamilyCAT2},
+	{"XMC1", FamilyCAT3}, {"XMC4", FamilyCAT3},
+	{"CYW43907", FamilyCAT4}, {"CYW54907", FamilyCAT4},
+	{"CYW20", FamilyCAT5}, {"CYW30", FamilyCAT5}, {"CYW55", FamilyCAT5}, {"CYW89", FamilyCAT5},
+}
+
+// FamilyOfCapabilities returns the family named by a family capability token, or FamilyUnknown
+func FamilyOfCapabilities(tokens []string) string {
+	for _, ft := range familyTokens {
+		for _, t := range tokens {
+			if strings.EqualFold(t, ft.token) {
+				return ft.family
+			}
+		}
+	}
+	return FamilyUnknown
+}
+
+// FamilyOfMCU returns the family of an MCU part number such as CY8C624ABZI-S2D44, or
+// FamilyUnknown
+func FamilyOfMCU(mcu string) string {
+	mcu = strings.ToUpper(mcu)
+	for _, fp := range familyPrefixes {
+		if strings.HasPrefix(mcu, fp.prefix) {
+			return fp.family
+		}
+	}
+	return FamilyUnknown
+}
+
+// Family returns the chip family of the board, from its capability tokens or else from its
+// MCUs. Boards that cannot be placed return FamilyUnknown.
+func (b *Board) Family() string {
+	if family := FamilyOfCapabilities(strings.Fields(b.ProvCapabilities)); family != FamilyUnknown {
+		return family
+	}
+	for _, mcu := range b.Chips.MCU {
+		if family := FamilyOfMCU(mcu); family != FamilyUnknown {
+			return family
+		}
+	}
+	return FamilyUnknown
+}
+
+// FamilyGroup is the boards of one chip family
+type FamilyGroup struct {
+	Family string   `json:"family"`
+	Boards []*Board `json:"boards"`
+}
+
+// GroupBoardsByFamily returns the boards of the tree grouped by family, families in name
+// order with FamilyUnknown last and boards in manifest order
+func GroupBoardsByFamily(sm SuperManifestIF) []*FamilyGroup {
+	groups := make(map[string]*FamilyGroup)
+	for _, id := range sm.GetBoardIDs() {
+		b, ok := sm.GetBoard(id)
+		if !ok {
+			continue
+		}
+		family := b.Family()
+		if groups[family] == nil {
+			groups[family] = &FamilyGroup{Family: family, Boards: []*Board{}}
+		}
+		groups[family].Boards = append(groups[family].Boards, b)
+	}
+	ret := make([]*FamilyGroup, 0, len(groups))
+	for _, g := range groups {
+		ret = append(ret, g)
+	}
+	slices.SortFunc(ret, func(a, b *FamilyGroup) int {
+		switch {
+		case a.Family == FamilyUnknown:
+			return 1
+		case b.Family == FamilyUnknown:
+			return -1
+		}
+		return strings.Compare(a.Family, b.Family)
+	})
+	return ret
+}
+
+// BoardsInFamily returns the boards of the given family, e.g. "cat1a", in manifest order
+func BoardsInFamily(sm SuperManifestIF, family string) []*Board {
+	ret := []*Board{}
+	for _, id := range sm.GetBoardIDs() {
+		if b, ok := sm.GetBoard(id); ok && strings.EqualFold(b.Family(), family) {
+			ret = append(ret, b)
+		}
+	}
+	return ret
+}
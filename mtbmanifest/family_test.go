@@ -0,0 +1,46 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestBoardFamily(t *testing.T) {
+	tests := []struct {
+		caps   string
+		mcu    string
+		family string
+	}{
+		{"cat1 cat1a psoc6 hal", "CY8C624ABZI-S2D44", FamilyCAT1A},
+		{"cat1 hal", "CYW20829B0LKML", FamilyCAT1B},
+		{"hal", "XMC7200D-E272K8384", FamilyCAT1C},
+		{"", "cy8c4147azi-s475", FamilyCAT2},
+		{"cat3", "XMC4700-F144K2048", FamilyCAT3},
+		{"", "CYW20819A1KFBG", FamilyCAT5},
+		{"hal", "MYSTERY-1", FamilyUnknown},
+	}
+	for _, tt := range tests {
+		b := &Board{ProvCapabilities: tt.caps, Chips: Chips{MCU: []string{tt.mcu}}}
+		if got := b.Family(); got != tt.family {
+			t.Errorf("%q %s: expected %q, got %q", tt.caps, tt.mcu, tt.family, got)
+		}
+	}
+}
+
+func TestGroupBoardsByFamily(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	groups := GroupBoardsByFamily(sm)
+	if len(groups) != 2 || groups[0].Family != FamilyCAT1A || groups[1].Family != FamilyCAT2 {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+	if boards := BoardsInFamily(sm, "cat2"); len(boards) != 1 || boards[0].ID != "CY8CKIT-149" {
+		t.Errorf("unexpected CAT2 boards %v", boards)
+	}
+	filter, err := ParseFilter("family=cat1*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := sm.ListBoards(&ListOptions{Filter: filter})
+	if err != nil || page.Total != 1 || page.Items[0].ID != "CY8CKIT-062S2-43012" {
+		t.Errorf("unexpected filtered boards %v, %v", page, err)
+	}
+}
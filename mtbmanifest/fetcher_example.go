@@ -26,7 +26,8 @@ func ExampleNewManifestFetcher_customConcurrency() {
 // Perfect for testing or when you need a specific cache location
 func ExampleNewManifestFetcher_customCache() {
 	// Create a custom cache in a specific location with 7-day TTL
-	customCache := NewManifestCache("/tmp/my-test-cache", 7*24*time.Hour)
+	customCache := NewManifestCache(WithDir("/tmp/my-test-cache"), WithCacheTTL(7*24*time.Hour))
+	customCache.Start()
 
 	fetcher := NewManifestFetcher(WithCache(customCache))
 	_ = fetcher // Use the fetcher...
@@ -36,7 +37,8 @@ func ExampleNewManifestFetcher_customCache() {
 // Full control over both aspects
 func ExampleNewManifestFetcher_customBoth() {
 	// Create a custom cache
-	customCache := NewManifestCache("/var/cache/manifests", 30*24*time.Hour)
+	customCache := NewManifestCache(WithDir("/var/cache/manifests"), WithCacheTTL(30*24*time.Hour))
+	customCache.Start()
 
 	// Create fetcher with both custom cache and high concurrency
 	fetcher := NewManifestFetcher(
@@ -0,0 +1,218 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ItemFilter is a parsed filter expression such as "chip=CYW43* capability=wifi". The
+// expression is a space separated list of key=pattern terms, all of which must match.
+// Patterns are case-insensitive globs ('*', '?' and '[...]' as in path.Match).
+//
+// Supported keys:
+//
+//	id, name, category  any item
+//	chip                board MCU or radio part number
+//	mcu, radio          board MCU or radio part number only
+//	family              board chip family, e.g. cat1a (see Board.Family)
+//	connectivity        wifi or bt provided by a board chip (see Board.RadioCapabilities)
+//	flow                a build flow of any item: mtb1, mtb2 or btsdk (see Board.Flows)
+//	lifecycle           board status: active, nrnd or obsolete (see Board.Lifecycle)
+//	difficulty          app difficulty: beginner, intermediate or advanced (see App.Difficulty)
+//	capability          a token a board provides, or that an app/middleware requires
+//	keyword             an app keyword
+//
+// Keys that make no sense for an item kind (e.g. keyword on a board) never match it.
+type ItemFilter struct {
+	Terms []FilterTerm
+	expr  string
+}
+
+// FilterTerm is one key=pattern term of a filter expression
+type FilterTerm struct {
+	Key     string
+	Pattern string
+}
+
+var filterKeys = map[string]bool{
+	"id": true, "name": true, "category": true,
+	"chip": true, "mcu": true, "radio": true,
+	"capability": true, "keyword": true, "family": true,
+	"connectivity": true, "flow": true, "lifecycle": true,
+	"difficulty": true,
+}
+
+// ParseFilter parses a filter expression. An empty expression matches everything.
+// Values with spaces can be quoted: name="Pioneer Kit*"
+func ParseFilter(expr string) (*ItemFilter, error) {
+	f := &ItemFilter{expr: expr}
+	for _, term := range splitFilterTerms(expr) {
+		key, pattern, ok := strings.Cut(term, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid filter term %q, expected key=pattern", term)
+		}
+		key = strings.ToLower(key)
+		if !filterKeys[key] {
+			return nil, fmt.Errorf("unknown filter key %q", key)
+		}
+		pattern = strings.ToLower(strings.Trim(pattern, `"`))
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in filter term %q: %v", term, err)
+		}
+		f.Terms = append(f.Terms, FilterTerm{Key: key, Pattern: pattern})
+	}
+	return f, nil
+}
+
+// splitFilterTerms splits on spaces outside of double quotes
+func splitFilterTerms(expr string) []string {
+	ret := []string{}
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if current.Len() > 0 {
+				ret = append(ret, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		ret = append(ret, current.String())
+	}
+	return ret
+}
+
+// String returns the expression the filter was parsed from
+func (f *ItemFilter) String() string {
+	return f.expr
+}
+
+func globMatch(pattern, value string) bool {
+	ok, _ := path.Match(pattern, strings.ToLower(value))
+	return ok
+}
+
+func globMatchAny(pattern string, values []string) bool {
+	for _, v := range values {
+		if globMatch(pattern, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilityTokens flattens a capability requirement into the list of tokens it mentions
+func capabilityTokens(req CapabilityRequirement) []string {
+	ret := []string{}
+	for _, group := range req.Groups {
+		ret = append(ret, group...)
+	}
+	return ret
+}
+
+// MatchBoard reports whether the board satisfies every term of the filter
+func (f *ItemFilter) MatchBoard(b *Board) bool {
+	for _, t := range f.Terms {
+		var ok bool
+		switch t.Key {
+		case "id":
+			ok = globMatch(t.Pattern, b.ID)
+		case "name":
+			ok = globMatch(t.Pattern, b.Name)
+		case "category":
+			ok = globMatch(t.Pattern, b.Category)
+		case "chip":
+			ok = globMatchAny(t.Pattern, b.Chips.MCU) || globMatchAny(t.Pattern, b.Chips.Radio)
+		case "mcu":
+			ok = globMatchAny(t.Pattern, b.Chips.MCU)
+		case "radio":
+			ok = globMatchAny(t.Pattern, b.Chips.Radio)
+		case "capability":
+			ok = globMatchAny(t.Pattern, strings.Fields(b.ProvCapabilities))
+		case "family":
+			ok = globMatch(t.Pattern, b.Family())
+		case "connectivity":
+			ok = globMatchAny(t.Pattern, b.RadioCapabilities())
+		case "flow":
+			ok = globMatchAny(t.Pattern, flowNames(b.Flows()))
+		case "lifecycle":
+			ok = globMatch(t.Pattern, string(b.Lifecycle()))
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// hasKey reports whether the filter has a term with the key
+func (f *ItemFilter) hasKey(key string) bool {
+	for _, t := range f.Terms {
+		if t.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchApp reports whether the app satisfies every term of the filter
+func (f *ItemFilter) MatchApp(a *App) bool {
+	for _, t := range f.Terms {
+		var ok bool
+		switch t.Key {
+		case "id":
+			ok = globMatch(t.Pattern, a.ID)
+		case "name":
+			ok = globMatch(t.Pattern, a.Name)
+		case "category":
+			ok = globMatch(t.Pattern, a.Category)
+		case "keyword":
+			ok = globMatchAny(t.Pattern, a.GetKeywords())
+		case "capability":
+			tokens := capabilityTokens(a.GetCapabilities())
+			for _, v := range a.Versions.Version {
+				tokens = append(tokens, capabilityTokens(v.GetCapabilities())...)
+			}
+			ok = globMatchAny(t.Pattern, tokens)
+		case "flow":
+			ok = globMatchAny(t.Pattern, flowNames(a.Flows()))
+		case "difficulty":
+			ok = globMatch(t.Pattern, string(a.Difficulty()))
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchMiddleware reports whether the middleware item satisfies every term of the filter
+func (f *ItemFilter) MatchMiddleware(mw *MiddlewareItem) bool {
+	for _, t := range f.Terms {
+		var ok bool
+		switch t.Key {
+		case "id":
+			ok = globMatch(t.Pattern, mw.ID)
+		case "name":
+			ok = globMatch(t.Pattern, mw.Name)
+		case "category":
+			ok = globMatch(t.Pattern, mw.Category)
+		case "capability":
+			ok = globMatchAny(t.Pattern, capabilityTokens(mw.GetCapabilities()))
+		case "flow":
+			ok = globMatchAny(t.Pattern, flowNames(mw.Flows()))
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
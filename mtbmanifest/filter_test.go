@@ -0,0 +1,52 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter(`chip=CYW43* name="PSoC 62S2*"`)
+	if err != nil {
+		t.Fatalf("ParseFilter failed: %v", err)
+	}
+	if len(f.Terms) != 2 || f.Terms[0].Pattern != "cyw43*" || f.Terms[1].Pattern != "psoc 62s2*" {
+		t.Errorf("unexpected terms %+v", f.Terms)
+	}
+
+	for _, bad := range []string{"chip", "=x", "color=red", "id=[abc"} {
+		if _, err := ParseFilter(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestListWithFilter(t *testing.T) {
+	sm := newTestSuperManifest(t)
+
+	filter, _ := ParseFilter("chip=CYW43* capability=wifi")
+	boards, err := sm.ListBoards(&ListOptions{Filter: filter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if boards.Total != 1 || boards.Items[0].ID != "CY8CKIT-062S2-43012" {
+		t.Errorf("expected only the Wi-Fi kit, got %d boards", boards.Total)
+	}
+
+	filter, _ = ParseFilter("capability=capsense")
+	apps, _ := sm.ListApps(&ListOptions{Filter: filter})
+	if apps.Total != 1 || apps.Items[0].ID != "mtb-example-capsense-buttons-slider" {
+		t.Errorf("expected only the CAPSENSE app, got %d apps", apps.Total)
+	}
+
+	filter, _ = ParseFilter("keyword=wifi")
+	middleware, _ := sm.ListMiddleware(&ListOptions{Filter: filter})
+	if middleware.Total != 0 {
+		t.Errorf("keyword terms should never match middleware, got %d", middleware.Total)
+	}
+
+	filter, _ = ParseFilter("capability=wifi")
+	middleware, _ = sm.ListMiddleware(&ListOptions{Filter: filter})
+	if middleware.Total != 1 || middleware.Items[0].ID != "wifi-connection-manager" {
+		t.Errorf("expected only the connection manager, got %d", middleware.Total)
+	}
+}
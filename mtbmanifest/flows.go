@@ -0,0 +1,112 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Build flows
+// ////////////////////////////////////////////////////////////////////////
+
+// Not every asset in the manifests builds with the current ModusToolbox flow. The
+// flow_version of a version says which flow it is for: 1.x is the original flow, 2.x the one
+// based on dependency manifests. Boards of the Bluetooth SDK (BTSDK) are built by a flow of
+// their own, and are told apart by their default_location attribute or a btsdk capability.
+
+// Flow is a way of building projects
+type Flow string
+
+const (
+	FlowMTB1  Flow = "mtb1"  // flow_version 1.x, or none
+	FlowMTB2  Flow = "mtb2"  // flow_version 2.x and later
+	FlowBTSDK Flow = "btsdk" // Bluetooth SDK boards
+)
+
+// ParseFlow parses a flow name, case-insensitively. "" is returned for unknown names.
+func ParseFlow(s string) Flow {
+	switch f := Flow(strings.ToLower(strings.TrimSpace(s))); f {
+	case FlowMTB1, FlowMTB2, FlowBTSDK:
+		return f
+	}
+	return ""
+}
+
+// flowsOfVersion returns the flows a flow_version attribute names. It may list several,
+// separated by commas.
+func flowsOfVersion(flowVersion string) []Flow {
+	ret := []Flow{}
+	for _, fv := range strings.Split(flowVersion, ",") {
+		flow := FlowMTB1
+		if major, _, _ := strings.Cut(strings.TrimSpace(fv), "."); major != "" && major != "1" && major != "0" {
+			flow = FlowMTB2
+		}
+		if !slices.Contains(ret, flow) {
+			ret = append(ret, flow)
+		}
+	}
+	return ret
+}
+
+// flowsOfVersions returns the flows of a list of flow_version attributes, in order
+func flowsOfVersions(flowVersions []string) []Flow {
+	ret := []Flow{}
+	for _, fv := range flowVersions {
+		for _, flow := range flowsOfVersion(fv) {
+			if !slices.Contains(ret, flow) {
+				ret = append(ret, flow)
+			}
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// IsBTSDK reports whether the board belongs to the Bluetooth SDK
+func (b *Board) IsBTSDK() bool {
+	return b.DefaultLocation != "" || slices.Contains(strings.Fields(strings.ToLower(b.ProvCapabilities)), "btsdk")
+}
+
+// Flows returns the flows the versions of the board are for. BTSDK boards are only for
+// FlowBTSDK.
+func (b *Board) Flows() []Flow {
+	if b.IsBTSDK() {
+		return []Flow{FlowBTSDK}
+	}
+	fvs := []string{}
+	if b.Versions != nil {
+		for _, v := range b.Versions.Versions {
+			fvs = append(fvs, v.FlowVersion)
+		}
+	}
+	return flowsOfVersions(fvs)
+}
+
+// Flows returns the flows the versions of the app are for
+func (a *App) Flows() []Flow {
+	fvs := []string{}
+	for _, v := range a.Versions.Version {
+		fvs = append(fvs, v.FlowVersion)
+	}
+	return flowsOfVersions(fvs)
+}
+
+// Flows returns the flows the versions of the middleware are for
+func (mw *MiddlewareItem) Flows() []Flow {
+	fvs := []string{}
+	if mw.Versions != nil {
+		for _, v := range mw.Versions.Version {
+			fvs = append(fvs, v.FlowVersion)
+		}
+	}
+	return flowsOfVersions(fvs)
+}
+
+// flowNames returns the flows as strings, for filters
+func flowNames(flows []Flow) []string {
+	ret := make([]string, len(flows))
+	for i, f := range flows {
+		ret[i] = string(f)
+	}
+	return ret
+}
@@ -0,0 +1,41 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFlows(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	kit, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	if flows := kit.Flows(); !slices.Equal(flows, []Flow{FlowMTB2}) {
+		t.Errorf("unexpected flows %v", flows)
+	}
+	kit.Versions.Versions[1].FlowVersion = "1.0,2.0"
+	if flows := kit.Flows(); !slices.Equal(flows, []Flow{FlowMTB1, FlowMTB2}) {
+		t.Errorf("unexpected flows %v", flows)
+	}
+
+	btsdk, _ := sm.GetBoard("CY8CKIT-149")
+	btsdk.DefaultLocation = "btsdk"
+	if !btsdk.IsBTSDK() || !slices.Equal(btsdk.Flows(), []Flow{FlowBTSDK}) {
+		t.Errorf("expected a BTSDK board, got %v", btsdk.Flows())
+	}
+	filter, err := ParseFilter("flow=mtb*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := sm.ListBoards(&ListOptions{Filter: filter})
+	if page.Total != 1 || page.Items[0] != kit {
+		t.Errorf("expected only the MTB flow board, got %v", page.Items)
+	}
+	if ParseFlow("BTSDK") != FlowBTSDK || ParseFlow("make") != "" {
+		t.Error("unexpected ParseFlow results")
+	}
+
+	// BTSDK boards drop out of solutions for the MTB flow
+	btsdk.ProvCapabilities += " wifi"
+	if solutions := FindSolutions(sm, "wifi", &SolutionOptions{Flow: FlowMTB2}); len(solutions) != 1 || solutions[0].Board != kit {
+		t.Errorf("unexpected solutions %v", solutions)
+	}
+}
@@ -0,0 +1,134 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// fv1 and fv2 manifests
+// ////////////////////////////////////////////////////////////////////////
+
+// Vendors still publish board, app and middleware manifests in the fv1 format next to fv2 ones,
+// and a super manifest may list both. fv1 manifests have no version attribute on their root
+// element, say which capabilities an item requires as a plain space-separated list in
+// req_capabilities and req_capabilities_per_version, and are named without the -fv2 suffix;
+// fv2 ones add a bracketed syntax in the _v2 attributes. The parser accepts either layout.
+// Once parsed, an fv1 manifest is normalized into the fv2 model: its capability lists are
+// copied into the _v2 fields, where they mean the same, so code reading the tree never has to
+// tell the two apart. Format on the manifest says what was read.
+
+// fileFormatRe finds the format suffix of a manifest file name, e.g. -fv2
+var fileFormatRe = regexp.MustCompile(`-fv(\d+)\.(xml|json)$`)
+
+// DetectManifestFormat returns the format revision of a manifest, 1 or 2 (or newer), from the
+// version attribute of its root element, from the _v2 attributes it uses, or else from its
+// file name
+func DetectManifestFormat(urlStr string, data []byte) int {
+	if major := formatMajor(rootVersion(data)); major > 0 {
+		return major
+	}
+	if bytes.Contains(data, []byte(`_v2="`)) || bytes.Contains(data, []byte(`_v2='`)) {
+		return 2
+	}
+	if m := fileFormatRe.FindStringSubmatch(path.Base(urlStr)); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			return n
+		}
+	}
+	return 1
+}
+
+// rootVersion returns the version attribute of the root element of an XML document
+func rootVersion(data []byte) string {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "version" {
+					return attr.Value
+				}
+			}
+			return ""
+		}
+	}
+}
+
+// formatNormalizer is a manifest that can be brought into the fv2 model
+type formatNormalizer interface {
+	normalizeFormat(format int)
+}
+
+// normalizeManifest normalizes obj if it is a manifest read from urlStr
+func normalizeManifest(obj any, urlStr string, data []byte) {
+	if n, ok := obj.(formatNormalizer); ok {
+		n.normalizeFormat(DetectManifestFormat(urlStr, data))
+	}
+}
+
+// v2Capabilities returns the fv2 form of an fv1 capability list, which is the list itself
+func v2Capabilities(v2, v1 string) string {
+	if v2 != "" {
+		return v2
+	}
+	return strings.TrimSpace(v1)
+}
+
+func (b *Boards) normalizeFormat(format int) {
+	b.Format = format
+	b.parseCommitRefs()
+}
+
+func (apps *Apps) normalizeFormat(format int) {
+	apps.Format = format
+	apps.parseCommitRefs()
+	if format >= 2 {
+		return
+	}
+	for _, a := range apps.App {
+		a.ReqCapabilitiesV2 = v2Capabilities(a.ReqCapabilitiesV2, a.ReqCapabilities)
+		for _, v := range a.Versions.Version {
+			v.ReqCapabilitiesPerVersionV2 = v2Capabilities(v.ReqCapabilitiesPerVersionV2, v.ReqCapabilitiesPerVersion)
+		}
+	}
+}
+
+func (mw *Middleware) normalizeFormat(format int) {
+	mw.Format = format
+	mw.parseCommitRefs()
+	if format >= 2 {
+		return
+	}
+	for _, item := range mw.Middlewares {
+		item.ReqCapabilitiesV2 = v2Capabilities(item.ReqCapabilitiesV2, item.ReqCapabilities)
+	}
+}
+
+// UnmarshalXML reads a middleware item. Besides the usual <n> it accepts <name>, and the
+// required capabilities as an attribute as well as an element.
+func (mw *MiddlewareItem) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type PlainMiddlewareItem MiddlewareItem // without this method
+	alt := struct {
+		*PlainMiddlewareItem
+		LongName            string `xml:"name"`
+		ReqCapabilitiesAttr string `xml:"req_capabilities,attr"`
+	}{PlainMiddlewareItem: (*PlainMiddlewareItem)(mw)}
+	if err := d.DecodeElement(&alt, &start); err != nil {
+		return err
+	}
+	if mw.Name == "" {
+		mw.Name = alt.LongName
+	}
+	if mw.ReqCapabilities == "" {
+		mw.ReqCapabilities = strings.TrimSpace(alt.ReqCapabilitiesAttr)
+	}
+	return nil
+}
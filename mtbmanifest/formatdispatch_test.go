@@ -0,0 +1,66 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestDetectManifestFormat(t *testing.T) {
+	tests := []struct {
+		url  string
+		data string
+		want int
+	}{
+		{"https://example.com/mtb-ce-manifest-fv2.xml", `<apps version="2.0"><app/></apps>`, 2},
+		{"https://example.com/mtb-ce-manifest.xml", `<?xml version="1.0"?><apps><app req_capabilities_v2="hal"/></apps>`, 2},
+		{"https://example.com/mtb-mw-manifest-fv2.xml", `<middleware></middleware>`, 2},
+		{"https://example.com/mtb-mw-manifest.xml", `<middleware></middleware>`, 1},
+		{"", `<boards><board/></boards>`, 1},
+	}
+	for _, tt := range tests {
+		if got := DetectManifestFormat(tt.url, []byte(tt.data)); got != tt.want {
+			t.Errorf("DetectManifestFormat(%s, %s) = %d, want %d", tt.url, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestFV1Normalization(t *testing.T) {
+	apps, err := ReadAppsManifest([]byte(`<apps>
+  <app>
+    <n>Empty App</n>
+    <id>mtb-example-empty-app</id>
+    <uri>https://github.com/Infineon/mtb-example-empty-app</uri>
+    <req_capabilities>psoc6 led</req_capabilities>
+    <versions>
+      <version req_capabilities_per_version="bsp_gen1" tools_max_version="2.1.0"><num>1.0</num><commit>latest-v1.X</commit></version>
+    </versions>
+  </app>
+</apps>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := apps.App[0]
+	if apps.Format != 1 || a.Name != "Empty App" || a.ReqCapabilitiesV2 != "psoc6 led" ||
+		a.Versions.Version[0].ReqCapabilitiesPerVersionV2 != "bsp_gen1" {
+		t.Fatalf("unexpected fv1 app %+v in format %d", a, apps.Format)
+	}
+
+	mw, err := ReadMiddlewareManifest([]byte(`<middleware>
+  <middleware req_capabilities="wifi"><name>Wi-Fi Connection Manager</name><id>wcm</id></middleware>
+</middleware>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := mw.Middlewares[0]
+	if mw.Format != 1 || item.Name != "Wi-Fi Connection Manager" || item.ReqCapabilitiesV2 != "wifi" || len(item.Surprises) != 0 {
+		t.Fatalf("unexpected fv1 middleware %+v in format %d", item, mw.Format)
+	}
+
+	// fv2 manifests are left as they are
+	apps, err = ReadAppsManifest([]byte(testAppsXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apps.Format != 2 || apps.App[0].ReqCapabilities != "" {
+		t.Fatalf("unexpected fv2 apps in format %d", apps.Format)
+	}
+}
@@ -0,0 +1,317 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Manifest archive from git history
+// ////////////////////////////////////////////////////////////////////////
+
+// The manifests are published from git repositories, so their history goes back much further
+// than any snapshot we stored. A ManifestArchive reads the tree as it was at any time from
+// local clones of those repositories: each clone serves the manifest URLs under its URL
+// prefix, and a file is read at the last commit of the clone made at or before that time.
+// Points lists those commits; Walk ingests the tree at each of them, Appearances tells when
+// every board, app and middleware first and last appeared, and SaveSnapshots stores the trees
+// in a SnapshotStore, where --as-of and snapshot comparisons find them.
+
+// ArchiveRepo is a local clone of a repository manifests are published from
+type ArchiveRepo struct {
+	// URL is the prefix of the manifest URLs the repository serves, e.g.
+	// https://github.com/Infineon/mtb-super-manifest/raw/v2.X/
+	URL string `json:"url"`
+	// Dir is the local clone, bare or not
+	Dir string `json:"dir"`
+	// Ref is the branch or tag whose history is read; empty means HEAD
+	Ref string `json:"ref,omitempty"`
+
+	gitDir string
+}
+
+// ArchivePoint is a commit of one of the repositories of an archive
+type ArchivePoint struct {
+	Time    time.Time `json:"time"`
+	RepoURL string    `json:"repoUrl"`
+	Commit  string    `json:"commit"`
+	Subject string    `json:"subject"`
+}
+
+// ManifestArchive reads manifest trees from the git history of the repositories they are
+// published from
+type ManifestArchive struct {
+	superURL string
+	repos    []*ArchiveRepo
+	g        *GitFetcher
+}
+
+// NewManifestArchive creates an archive of the tree of the super manifest at superURL, read
+// from the given clones. The clone serving the longest prefix of a URL serves it; URLs no clone
+// serves are missing from the trees. A nil fetcher means NewGitFetcher().
+func NewManifestArchive(superURL string, repos []*ArchiveRepo, g *GitFetcher) (*ManifestArchive, error) {
+	if g == nil {
+		g = NewGitFetcher()
+	}
+	if g.gitBinary == "" {
+		return nil, fmt.Errorf("reading the history of manifest repositories needs git")
+	}
+	a := &ManifestArchive{superURL: superURL, g: g}
+	for _, repo := range repos {
+		out, err := g.git("", "-C", repo.Dir, "rev-parse", "--absolute-git-dir")
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a git repository: %w", repo.Dir, err)
+		}
+		r := *repo
+		r.gitDir = strings.TrimSpace(string(out))
+		if r.Ref == "" {
+			r.Ref = "HEAD"
+		}
+		a.repos = append(a.repos, &r)
+	}
+	sort.SliceStable(a.repos, func(i, j int) bool { return len(a.repos[i].URL) > len(a.repos[j].URL) })
+	if a.repoOf(superURL) == nil {
+		return nil, fmt.Errorf("no repository serves the super manifest %s", superURL)
+	}
+	return a, nil
+}
+
+// ParseArchiveRepo parses URL=DIR, or URL=DIR@REF, into an ArchiveRepo
+func ParseArchiveRepo(s string) (*ArchiveRepo, error) {
+	urlStr, dir, ok := strings.Cut(s, "=")
+	if !ok || urlStr == "" || dir == "" {
+		return nil, fmt.Errorf("invalid repository %q, expected URL=DIR or URL=DIR@REF", s)
+	}
+	repo := &ArchiveRepo{URL: urlStr, Dir: dir}
+	if i := strings.LastIndex(dir, "@"); i > 0 {
+		repo.Dir, repo.Ref = dir[:i], dir[i+1:]
+	}
+	return repo, nil
+}
+
+// repoOf returns the repository serving urlStr, or nil
+func (a *ManifestArchive) repoOf(urlStr string) *ArchiveRepo {
+	for _, repo := range a.repos {
+		if strings.HasPrefix(urlStr, repo.URL) {
+			return repo
+		}
+	}
+	return nil
+}
+
+// Points returns the commits of the repositories made between since and until, oldest first.
+// A zero since or until means no bound. With every, at most one commit per period is kept: the
+// last one of the period.
+func (a *ManifestArchive) Points(since, until time.Time, every time.Duration) ([]*ArchivePoint, error) {
+	points := []*ArchivePoint{}
+	for _, repo := range a.repos {
+		args := []string{"log", "--format=%H%x09%ct%x09%s"}
+		if !since.IsZero() {
+			args = append(args, "--since="+strconv.FormatInt(since.Unix(), 10))
+		}
+		if !until.IsZero() {
+			args = append(args, "--until="+strconv.FormatInt(until.Unix(), 10))
+		}
+		out, err := a.g.git(repo.gitDir, append(args, repo.Ref, "--")...)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) < 2 {
+				continue
+			}
+			secs, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			p := &ArchivePoint{Time: time.Unix(secs, 0).UTC(), RepoURL: repo.URL, Commit: fields[0]}
+			if len(fields) == 3 {
+				p.Subject = fields[2]
+			}
+			points = append(points, p)
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	if every <= 0 {
+		return points, nil
+	}
+	thinned := []*ArchivePoint{}
+	for _, p := range points {
+		period := p.Time.Truncate(every)
+		if n := len(thinned); n > 0 && thinned[n-1].Time.Truncate(every).Equal(period) {
+			thinned[n-1] = p
+		} else {
+			thinned = append(thinned, p)
+		}
+	}
+	return thinned, nil
+}
+
+// Contents returns the files of the tree as it was at t, keyed by URL. Files that did not exist
+// then, or that no repository serves, are left out; it is an error only for the super manifest.
+func (a *ManifestArchive) Contents(t time.Time) (map[string][]byte, error) {
+	revs := map[*ArchiveRepo]string{}
+	read := func(urlStr string) ([]byte, error) {
+		repo := a.repoOf(urlStr)
+		if repo == nil {
+			return nil, fmt.Errorf("no repository serves %s", urlStr)
+		}
+		rev, ok := revs[repo]
+		if !ok {
+			out, err := a.g.git(repo.gitDir, "rev-list", "-1", "--before="+strconv.FormatInt(t.Unix(), 10), repo.Ref, "--")
+			if err != nil {
+				return nil, err
+			}
+			rev = strings.TrimSpace(string(out))
+			revs[repo] = rev
+		}
+		if rev == "" {
+			return nil, fmt.Errorf("%s: %w at %s", urlStr, os.ErrNotExist, t.Format(time.RFC3339))
+		}
+		return a.g.git(repo.gitDir, "show", rev+":"+strings.TrimPrefix(urlStr, repo.URL))
+	}
+
+	super, err := read(a.superURL)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ReadSuperManifest(super)
+	if err != nil {
+		return nil, fmt.Errorf("%s at %s: %w", a.superURL, t.Format(time.RFC3339), err)
+	}
+	contents := map[string][]byte{a.superURL: super}
+	for _, urlStr := range parsed.ManifestURLs() {
+		if data, err := read(urlStr); err == nil {
+			contents[urlStr] = data
+		} else {
+			logger.Debugf("Not in the archive: %v\n", err)
+		}
+	}
+	return contents, nil
+}
+
+// At returns the tree as it was at t
+func (a *ManifestArchive) At(t time.Time) (SuperManifestIF, error) {
+	contents, err := a.Contents(t)
+	if err != nil {
+		return nil, err
+	}
+	return superManifestFromContents(contents, []string{a.superURL}, nil)
+}
+
+// Walk calls fn with the tree at each of the points, in order. Points where the super
+// manifest cannot be read are skipped. An error of fn stops the walk.
+func (a *ManifestArchive) Walk(points []*ArchivePoint, fn func(p *ArchivePoint, sm SuperManifestIF) error) error {
+	for _, p := range points {
+		sm, err := a.At(p.Time)
+		if err != nil {
+			logger.Debugf("Skipping %s of %s: %v\n", p.Commit, p.RepoURL, err)
+			continue
+		}
+		if err := fn(p, sm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ItemAppearance tells when an item was in the manifests
+type ItemAppearance struct {
+	Kind      ItemKind  `json:"kind"`
+	ID        string    `json:"id"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Removed is set when the item is not in the tree at the last point
+	Removed bool `json:"removed,omitempty"`
+}
+
+// Appearances walks the points and returns, for every board, app and middleware listed at any
+// of them, the first and last time it was, in the order they first appeared
+func (a *ManifestArchive) Appearances(points []*ArchivePoint) ([]*ItemAppearance, error) {
+	byKey := map[string]*ItemAppearance{}
+	ret := []*ItemAppearance{}
+	var last time.Time
+	err := a.Walk(points, func(p *ArchivePoint, sm SuperManifestIF) error {
+		last = p.Time
+		for kind, ids := range map[ItemKind][]string{ItemKindBoard: sm.GetBoardIDs(),
+			ItemKindApp: sm.GetAppIDs(), ItemKindMiddleware: sm.GetMiddlewareIDs()} {
+			for _, id := range ids {
+				key := string(kind) + "/" + idKey(id)
+				if seen, ok := byKey[key]; ok {
+					seen.LastSeen = p.Time
+					continue
+				}
+				seen := &ItemAppearance{Kind: kind, ID: id, FirstSeen: p.Time, LastSeen: p.Time}
+				byKey[key] = seen
+				ret = append(ret, seen)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, seen := range ret {
+		seen.Removed = seen.LastSeen.Before(last)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if !ret[i].FirstSeen.Equal(ret[j].FirstSeen) {
+			return ret[i].FirstSeen.Before(ret[j].FirstSeen)
+		}
+		if ret[i].Kind != ret[j].Kind {
+			return ret[i].Kind < ret[j].Kind
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret, nil
+}
+
+// SaveSnapshots saves the tree at each of the points in store, created at the time of the
+// point, and returns the indexes of the snapshots written. A tree already stored, at that
+// point or an earlier one, is not written again.
+func (a *ManifestArchive) SaveSnapshots(store *SnapshotStore, points []*ArchivePoint) ([]*BundleIndex, error) {
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		return nil, err
+	}
+	ret := []*BundleIndex{}
+	for _, p := range points {
+		contents, err := a.Contents(p.Time)
+		if err != nil {
+			logger.Debugf("Skipping %s of %s: %v\n", p.Commit, p.RepoURL, err)
+			continue
+		}
+		index := &BundleIndex{Format: BundleFormatVersion, Created: p.Time, RootURLs: []string{a.superURL},
+			Entries: []*BundleEntry{}}
+		byHash := map[string][]byte{}
+		urls := make([]string, 0, len(contents))
+		for urlStr := range contents {
+			urls = append(urls, urlStr)
+		}
+		sort.Strings(urls)
+		for _, urlStr := range urls {
+			data := contents[urlStr]
+			entry := &BundleEntry{URL: urlStr, Size: len(data), SHA256: hashContent(data)}
+			index.Entries = append(index.Entries, entry)
+			byHash[entry.SHA256] = data
+		}
+		file := store.path(index.ID())
+		if _, err := os.Stat(file); err == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := writeBundle(&buf, index, byHash); err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(file, buf.Bytes()); err != nil {
+			return nil, err
+		}
+		ret = append(ret, index)
+	}
+	return ret, nil
+}
@@ -0,0 +1,112 @@
+package mtbmanifest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// commitAt commits files to repo with the given author and committer date
+func commitAt(t *testing.T, repo string, when time.Time, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, repo, "add", "-A")
+	cmd := exec.Command("git", "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit", "--quiet", "-m", "update at "+when.Format(time.DateOnly))
+	cmd.Dir = repo
+	date := when.Format(time.RFC3339)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v: %s", err, out)
+	}
+}
+
+func TestManifestArchive(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	runGit(t, repo, "init", "--quiet")
+	split := strings.Index(testBoardsXML, "  <board>\n    <id>CY8CKIT-149")
+	kit062 := testBoardsXML[:split] + "</boards>"
+	kit149 := "<boards>\n" + testBoardsXML[split:]
+	jan, mar, jun := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	commitAt(t, repo, jan, map[string]string{"super.xml": testSuperXML, "boards.xml": kit149,
+		"apps.xml": testAppsXML, "middleware.xml": testMiddlewareXML})
+	commitAt(t, repo, mar, map[string]string{"boards.xml": testBoardsXML})
+	commitAt(t, repo, jun, map[string]string{"boards.xml": kit062})
+
+	a, err := NewManifestArchive("https://example.com/super.xml",
+		[]*ArchiveRepo{{URL: "https://example.com/", Dir: repo}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := a.Points(jan.Add(-time.Hour), time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 || !points[0].Time.Equal(jan) || !points[2].Time.Equal(jun) {
+		t.Fatalf("expected the 3 commits since January, got %+v", points)
+	}
+	if thinned, _ := a.Points(time.Time{}, time.Time{}, 365*24*time.Hour); len(thinned) != 1 || !thinned[0].Time.Equal(jun) {
+		t.Errorf("expected the last commit of the year, got %+v", thinned)
+	}
+
+	sm, err := a.At(mar.Add(24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.GetBoardIDs()) != 2 || len(sm.GetAppIDs()) != 3 {
+		t.Errorf("expected the tree of March, got %v", sm.GetBoardIDs())
+	}
+	if _, err := a.At(jan.Add(-time.Hour)); err == nil {
+		t.Error("expected an error before the first commit")
+	}
+
+	seen, err := a.Appearances(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]*ItemAppearance{}
+	for _, s := range seen {
+		found[s.ID] = s
+	}
+	if s := found["CY8CKIT-062S2-43012"]; s == nil || !s.FirstSeen.Equal(mar) || s.Removed {
+		t.Errorf("expected the 062S2 kit from March on, got %+v", s)
+	}
+	if s := found["CY8CKIT-149"]; s == nil || !s.FirstSeen.Equal(jan) || !s.LastSeen.Equal(mar) || !s.Removed {
+		t.Errorf("expected the 149 kit from January to March, got %+v", s)
+	}
+
+	store := NewSnapshotStore(t.TempDir())
+	saved, err := a.SaveSnapshots(store, points)
+	if err != nil || len(saved) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d, %v", len(saved), err)
+	}
+	if again, _ := a.SaveSnapshots(store, points); len(again) != 0 {
+		t.Errorf("expected stored trees not to be written again, got %d", len(again))
+	}
+	asOf, err := store.AsOf(time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(asOf.GetBoardIDs()) != 2 {
+		t.Errorf("expected the snapshot of March, got %v", asOf.GetBoardIDs())
+	}
+
+	if _, err := NewManifestArchive("https://other.example.com/super.xml",
+		[]*ArchiveRepo{{URL: "https://example.com/", Dir: repo}}, nil); err == nil {
+		t.Error("expected an error for a super manifest no repository serves")
+	}
+	if r, err := ParseArchiveRepo("https://example.com/=/src/manifests@v2.X"); err != nil || r.Dir != "/src/manifests" || r.Ref != "v2.X" {
+		t.Errorf("ParseArchiveRepo = %+v, %v", r, err)
+	}
+}
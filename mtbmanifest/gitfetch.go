@@ -0,0 +1,583 @@
+package mtbmanifest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Git-based fetching of asset sources
+// ////////////////////////////////////////////////////////////////////////
+
+// Board, app and middleware URIs point at git repositories and their versions name tags or
+// branches (latest-v4.X, release-v4.1.0). GitFetcher gets the files of one such revision into
+// a directory. With a git binary it keeps a bare repository per remote in a cache directory
+// and fetches single commits into it (depth 1), so asking for the same revision again costs
+// nothing and other revisions only transfer what is new. Without git, GitHub repositories are
+// downloaded as codeload tarballs instead.
+//
+// A source can be pinned to a commit SHA: the fetch fails if the ref no longer resolves to it.
+
+// GitSource names one revision of a git repository
+type GitSource struct {
+	// Repo is the repository URL, e.g. https://github.com/Infineon/freertos
+	Repo string `json:"repo"`
+	// Ref is a tag, branch or commit SHA, e.g. latest-v10.X
+	Ref string `json:"ref"`
+	// SHA, when set, is the commit Ref must resolve to
+	SHA string `json:"sha,omitempty"`
+}
+
+func (src *GitSource) String() string {
+	return src.Repo + "@" + src.Ref
+}
+
+// GitFetcher fetches revisions of git repositories
+type GitFetcher struct {
+	cacheDir     string
+	gitBinary    string
+	client       *http.Client
+	timeouts     Timeouts // see WithGitTimeouts
+	codeloadBase string
+	rawBase      string
+	apiBase      string
+}
+
+// GitFetcherOption configures a GitFetcher
+type GitFetcherOption func(*GitFetcher)
+
+// WithGitCacheDir sets where bare repositories are kept. Default
+// ~/.modustoolbox/mtbmcp/git.
+func WithGitCacheDir(dir string) GitFetcherOption {
+	return func(g *GitFetcher) {
+		g.cacheDir = dir
+	}
+}
+
+// WithGitBinary sets the git executable. Default "git" from PATH; "" disables git and always
+// downloads tarballs.
+func WithGitBinary(gitBinary string) GitFetcherOption {
+	return func(g *GitFetcher) {
+		g.gitBinary = gitBinary
+	}
+}
+
+// WithGitHTTPClient sets the client used to download tarballs. Default http.DefaultClient.
+func WithGitHTTPClient(client *http.Client) GitFetcherOption {
+	return func(g *GitFetcher) {
+		g.client = client
+	}
+}
+
+// NewGitFetcher creates a fetcher with the given options
+func NewGitFetcher(opts ...GitFetcherOption) *GitFetcher {
+	home, _ := os.UserHomeDir()
+	g := &GitFetcher{
+		cacheDir:     filepath.Join(home, ".modustoolbox", "mtbmcp", "git"),
+		gitBinary:    "git",
+		client:       http.DefaultClient,
+		codeloadBase: "https://codeload.github.com",
+		rawBase:      "https://raw.githubusercontent.com",
+		apiBase:      "https://api.github.com",
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+var shaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// IsCommitSHA reports whether s is a full hexadecimal commit SHA
+func IsCommitSHA(s string) bool {
+	return shaRegex.MatchString(strings.ToLower(s))
+}
+
+// ErrSHAMismatch is returned when a pinned source resolves to a different commit
+var ErrSHAMismatch = errors.New("ref does not resolve to the pinned commit")
+
+func checkPin(src *GitSource, sha string) error {
+	if src.SHA != "" && !strings.EqualFold(src.SHA, sha) {
+		return fmt.Errorf("%s: %w: expected %s, got %s", src, ErrSHAMismatch, src.SHA, sha)
+	}
+	return nil
+}
+
+// ErrUnsafeGitSource is returned for a repository or ref git could take for an option, or a
+// repository with another scheme than https, ssh or file. Both come from manifest data.
+var ErrUnsafeGitSource = errors.New("unsafe git source")
+
+// gitSchemes are the URL schemes git is allowed to use, see also GIT_ALLOW_PROTOCOL in git
+var gitSchemes = []string{"https", "ssh", "file"}
+
+// checkGitSource returns an error matching ErrUnsafeGitSource unless repo and refs are safe to
+// pass to git
+func checkGitSource(repo string, refs ...string) error {
+	u, err := url.Parse(repo)
+	if strings.HasPrefix(repo, "-") || err != nil || !slices.Contains(gitSchemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: repository %q", ErrUnsafeGitSource, repo)
+	}
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, "-") {
+			return fmt.Errorf("%w: ref %q", ErrUnsafeGitSource, ref)
+		}
+	}
+	return nil
+}
+
+func (g *GitFetcher) hasGit() bool {
+	if g.gitBinary == "" {
+		return false
+	}
+	_, err := exec.LookPath(g.gitBinary)
+	return err == nil
+}
+
+// Fetch writes the files of a revision into dir, which is created if needed, and returns the
+// commit SHA it resolved to
+func (g *GitFetcher) Fetch(src *GitSource, dir string) (string, error) {
+	if src.Repo == "" || src.Ref == "" {
+		return "", fmt.Errorf("git source needs a repository and a ref")
+	}
+	if err := checkGitSource(src.Repo, src.Ref, src.SHA); err != nil {
+		return "", err
+	}
+	if g.hasGit() {
+		return g.fetchWithGit(src, dir)
+	}
+	return g.fetchTarball(src, dir)
+}
+
+// ResolveSHA returns the commit SHA a ref currently points to in the remote repository.
+// Annotated tags are peeled to their commit.
+func (g *GitFetcher) ResolveSHA(repo, ref string) (string, error) {
+	if err := checkGitSource(repo, ref); err != nil {
+		return "", err
+	}
+	if IsCommitSHA(ref) {
+		return strings.ToLower(ref), nil
+	}
+	if !g.hasGit() {
+		return g.resolveSHAWithAPI(repo, ref)
+	}
+	out, err := g.git("", "ls-remote", "--", repo, ref, "refs/tags/"+ref+"^{}")
+	if err != nil {
+		return "", err
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+	for _, name := range []string{"refs/tags/" + ref + "^{}", "refs/tags/" + ref, "refs/heads/" + ref, ref} {
+		if sha, ok := refs[name]; ok {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("%s@%s: no such ref", repo, ref)
+}
+
+// resolveSHAWithAPI asks the GitHub API for the commit of a ref
+func (g *GitFetcher) resolveSHAWithAPI(repo, ref string) (string, error) {
+	ownerRepo, ok := githubRepo(repo)
+	if !ok {
+		return "", fmt.Errorf("resolving %s@%s needs git: not a GitHub repository", repo, ref)
+	}
+	req, err := http.NewRequest(http.MethodGet, g.apiBase+"/repos/"+ownerRepo+"/commits/"+url.PathEscape(ref), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github.sha")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("http get: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s@%s: http status %d", repo, ref, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	sha := strings.TrimSpace(string(data))
+	if !IsCommitSHA(sha) {
+		return "", fmt.Errorf("%s@%s: unexpected answer from the GitHub API", repo, ref)
+	}
+	return sha, nil
+}
+
+// git runs a git command, in gitDir if set, and returns its output
+func (g *GitFetcher) git(gitDir string, args ...string) ([]byte, error) {
+	if gitDir != "" {
+		args = append([]string{"--git-dir", gitDir}, args...)
+	}
+	cmd := exec.Command(g.gitBinary, args...)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL="+strings.Join(gitSchemes, ":")),
+		g.timeouts.gitEnv()...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// bareRepoPath returns where the bare repository for a remote is cached, e.g.
+// <cacheDir>/github.com/Infineon/freertos.git
+func (g *GitFetcher) bareRepoPath(repo string) (string, error) {
+	u, err := url.Parse(repo)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimSuffix(path.Clean("/"+u.Path), ".git")
+	host := u.Host
+	if host == "" {
+		host = "local"
+	}
+	return filepath.Join(g.cacheDir, host, filepath.FromSlash(p)+".git"), nil
+}
+
+func (g *GitFetcher) fetchWithGit(src *GitSource, dir string) (string, error) {
+	bare, sha, err := g.cachedCommit(src)
+	if err != nil {
+		return "", err
+	}
+	out, err := g.git(bare, "archive", "--format=tar", sha)
+	if err != nil {
+		return "", err
+	}
+	if _, err := extractTarball(bytes.NewReader(out), dir, 0); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// cachedCommit makes sure the commit of a source is in the bare repository cache and returns
+// the repository and the commit SHA, checked against the pin
+func (g *GitFetcher) cachedCommit(src *GitSource) (string, string, error) {
+	bare, err := g.bareRepoPath(src.Repo)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := os.Stat(bare); err != nil {
+		if _, err := g.git("", "init", "--bare", "--quiet", bare); err != nil {
+			return "", "", err
+		}
+	}
+
+	var sha string
+	if IsCommitSHA(src.Ref) {
+		// A commit never changes, so one already in the cache needs no network
+		sha = strings.ToLower(src.Ref)
+		if _, err := g.git(bare, "cat-file", "-e", sha+"^{commit}"); err != nil {
+			if _, err := g.git(bare, "fetch", "--quiet", "--depth", "1", "--", src.Repo, sha); err != nil {
+				return "", "", err
+			}
+		}
+	} else {
+		// Tags and branches can move; fetch, but fall back to the last known commit offline
+		localRef := "refs/mtb-cache/" + src.Ref
+		_, fetchErr := g.git(bare, "fetch", "--quiet", "--force", "--depth", "1", "--", src.Repo, "+"+src.Ref+":"+localRef)
+		out, err := g.git(bare, "rev-parse", "--verify", localRef+"^{commit}")
+		if err != nil {
+			if fetchErr != nil {
+				return "", "", fetchErr
+			}
+			return "", "", err
+		}
+		if fetchErr != nil {
+			logger.Warningf("Using cached %s, fetch failed: %v\n", src, fetchErr)
+		}
+		sha = strings.TrimSpace(string(out))
+	}
+	if err := checkPin(src, sha); err != nil {
+		return "", "", err
+	}
+	return bare, sha, nil
+}
+
+// ReadFile returns one file of a revision, e.g. its RELEASE.md. The error matches
+// fs.ErrNotExist when the revision has no such file.
+func (g *GitFetcher) ReadFile(src *GitSource, name string) ([]byte, error) {
+	if err := checkGitSource(src.Repo, src.Ref, src.SHA); err != nil {
+		return nil, err
+	}
+	if g.hasGit() {
+		bare, sha, err := g.cachedCommit(src)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := g.git(bare, "cat-file", "-e", sha+":"+name); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", src, name, fs.ErrNotExist)
+		}
+		return g.git(bare, "show", sha+":"+name)
+	}
+	ownerRepo, ok := githubRepo(src.Repo)
+	if !ok {
+		return nil, fmt.Errorf("cannot read %s from %s without git: not a GitHub repository", name, src)
+	}
+	if src.SHA != "" {
+		// Raw files do not tell their commit, so read the pinned commit itself
+		src = &GitSource{Repo: src.Repo, Ref: src.SHA}
+	}
+	resp, err := g.client.Get(g.rawBase + "/" + ownerRepo + "/" + url.PathEscape(src.Ref) + "/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("http get: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %s: %w", src, name, fs.ErrNotExist)
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading %s from %s: http status %d", name, src, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// githubRepo returns owner/repo for a GitHub repository URL
+func githubRepo(repo string) (string, bool) {
+	u, err := url.Parse(repo)
+	if err != nil || !strings.EqualFold(u.Host, "github.com") {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return "", false
+	}
+	return parts[0] + "/" + strings.TrimSuffix(parts[1], ".git"), true
+}
+
+// fetchTarball downloads a GitHub revision from codeload. The commit SHA comes from the
+// comment git archive leaves in the tarball.
+func (g *GitFetcher) fetchTarball(src *GitSource, dir string) (string, error) {
+	ownerRepo, ok := githubRepo(src.Repo)
+	if !ok {
+		return "", fmt.Errorf("cannot fetch %s without git: only GitHub repositories can be downloaded as tarballs", src)
+	}
+	resp, err := g.client.Get(g.codeloadBase + "/" + ownerRepo + "/tar.gz/" + url.PathEscape(src.Ref))
+	if err != nil {
+		return "", fmt.Errorf("http get: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: http status %d", src, resp.StatusCode)
+	}
+	gzr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("fetching %s: %v", src, err)
+	}
+	defer func() { _ = gzr.Close() }()
+
+	// Extract to a temporary directory first so a pin mismatch leaves nothing behind
+	tmp, err := os.MkdirTemp(filepath.Dir(filepath.Clean(dir)), ".gitfetch-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+	// GitHub tarballs hold everything under one <repo>-<ref> directory
+	sha, err := extractTarball(gzr, tmp, 1)
+	if err != nil {
+		return "", fmt.Errorf("fetching %s: %v", src, err)
+	}
+	if sha == "" && src.SHA != "" {
+		return "", fmt.Errorf("%s: cannot verify the pinned commit, the tarball does not name one", src)
+	}
+	if err := checkPin(src, sha); err != nil {
+		return "", err
+	}
+	if _, err := moveDirContents(tmp, dir); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// extractTarball writes the files of a tar stream into dir, dropping the first strip path
+// components of every name. It returns the commit SHA recorded by git archive, if any.
+func extractTarball(r io.Reader, dir string, strip int) (string, error) {
+	sha := ""
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			if c := strings.TrimSpace(hdr.PAXRecords["comment"]); IsCommitSHA(c) {
+				sha = c
+			}
+			continue
+		}
+		parts := strings.Split(path.Clean(hdr.Name), "/")
+		if len(parts) <= strip {
+			continue
+		}
+		name := path.Join(parts[strip:]...)
+		if !filepath.IsLocal(name) {
+			return "", fmt.Errorf("unsafe path %q in archive", hdr.Name)
+		}
+		if err := checkNoSymlinks(dir, path.Dir(name)); err != nil {
+			return "", err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return "", err
+			}
+			mode := os.FileMode(0o644)
+			if hdr.Mode&0o111 != 0 {
+				mode = 0o755
+			}
+			if fi, err := os.Lstat(target); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+				_ = os.Remove(target) // replaced, not written through
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return "", err
+			}
+		case tar.TypeSymlink:
+			if err := checkLinkTarget(name, hdr.Linkname); err != nil {
+				return "", err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return "", err
+			}
+			if fi, err := os.Lstat(target); err == nil && !fi.IsDir() {
+				_ = os.Remove(target)
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return "", err
+			}
+		}
+	}
+	return sha, nil
+}
+
+// checkNoSymlinks returns an error when a component of name, a local directory under dir, is
+// a symbolic link: nothing is written through a link, wherever it points
+func checkNoSymlinks(dir, name string) error {
+	if name == "." {
+		return nil
+	}
+	p := dir
+	for _, part := range strings.Split(name, "/") {
+		p = filepath.Join(p, part)
+		fi, err := os.Lstat(p)
+		if err != nil {
+			return nil // the rest does not exist yet
+		}
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("unsafe path %q in archive: it goes through a symbolic link", name)
+		}
+	}
+	return nil
+}
+
+// checkLinkTarget returns an error unless a symbolic link at name, a local path, stays in the
+// directory it is extracted to. The target must be relative and may only climb up with leading
+// ".." components: those resolve from the real directories of name, and what follows only
+// descends, through links that were checked the same way.
+func checkLinkTarget(name, linkname string) error {
+	target := filepath.ToSlash(linkname)
+	if target == "" || path.IsAbs(target) || filepath.IsAbs(linkname) || filepath.VolumeName(linkname) != "" {
+		return fmt.Errorf("unsafe symbolic link %q -> %q in archive", name, linkname)
+	}
+	climbing := true
+	for _, part := range strings.Split(target, "/") {
+		if part == ".." && !climbing {
+			return fmt.Errorf("unsafe symbolic link %q -> %q in archive", name, linkname)
+		}
+		climbing = climbing && (part == ".." || part == ".")
+	}
+	if !filepath.IsLocal(path.Join(path.Dir(name), target)) {
+		return fmt.Errorf("unsafe symbolic link %q -> %q in archive: it points outside", name, linkname)
+	}
+	return nil
+}
+
+// moveDirContents moves the contents of from into to, creating to if needed. Returns the number of
+// entries moved.
+func moveDirContents(from, to string) (int, error) {
+	if err := os.MkdirAll(to, 0o755); err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		target := filepath.Join(to, e.Name())
+		_ = os.RemoveAll(target)
+		if err := os.Rename(filepath.Join(from, e.Name()), target); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), nil
+}
+
+// AssetSource returns the git source of a board, app or middleware version. commit is one of
+// the item's version commits (e.g. latest-v4.X); empty means the newest listed version.
+func AssetSource(sm SuperManifestIF, id, commit string) (*GitSource, error) {
+	var repo string
+	var commits []string
+	if board, ok := sm.GetBoard(id); ok {
+		repo, commits = board.BoardURI, board.VersionCommits()
+	} else if app, ok := sm.GetApp(id); ok {
+		repo, commits = app.URI, app.VersionCommits()
+	} else if mw, ok := sm.GetMiddleware(id); ok {
+		repo, commits = mw.URI, mw.VersionCommits()
+	} else {
+		return nil, fmt.Errorf("%s not found", id)
+	}
+	if repo == "" {
+		return nil, fmt.Errorf("%s has no repository URI", id)
+	}
+	if commit == "" {
+		newest := NewestVersion(commits)
+		if newest == nil {
+			if len(commits) == 0 {
+				return nil, fmt.Errorf("%s lists no versions", id)
+			}
+			return &GitSource{Repo: repo, Ref: commits[0]}, nil
+		}
+		return &GitSource{Repo: repo, Ref: newest.Raw}, nil
+	}
+	for _, c := range commits {
+		if c == commit {
+			return &GitSource{Repo: repo, Ref: c}, nil
+		}
+	}
+	if ParseCommitRef(commit).Kind == CommitRefSHA {
+		return &GitSource{Repo: repo, Ref: commit}, nil
+	}
+	return nil, fmt.Errorf("%s has no version %s (versions: %s)", id, commit, strings.Join(commits, ", "))
+}
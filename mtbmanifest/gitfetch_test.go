@@ -0,0 +1,209 @@
+package mtbmanifest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestGitRepo creates a repository with one file, tagged v1.0.0, and returns its path
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "init"},
+	} {
+		runGit(t, repo, args...)
+	}
+	writeAndTag(t, repo, "v1 of readme", "v1.0.0")
+	return repo
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeAndTag(t *testing.T, repo, content, tag string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repo, "README.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", "README.md")
+	runGit(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", content)
+	runGit(t, repo, "tag", "-f", tag)
+}
+
+func TestGitFetcher(t *testing.T) {
+	repo := newTestGitRepo(t)
+	repoURL := "file://" + repo
+	sha1 := runGit(t, repo, "rev-parse", "v1.0.0")
+	g := NewGitFetcher(WithGitCacheDir(t.TempDir()))
+
+	if got, err := g.ResolveSHA(repoURL, "v1.0.0"); err != nil || got != sha1 {
+		t.Errorf("ResolveSHA = %s, %v; want %s", got, err, sha1)
+	}
+
+	dir := t.TempDir()
+	sha, err := g.Fetch(&GitSource{Repo: repoURL, Ref: "v1.0.0", SHA: sha1}, dir)
+	if err != nil || sha != sha1 {
+		t.Fatalf("Fetch = %s, %v; want %s", sha, err, sha1)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(data) != "v1 of readme" {
+		t.Errorf("unexpected README.md %q", data)
+	}
+
+	// Moving the tag breaks the pin, but the old commit is still available by SHA
+	writeAndTag(t, repo, "v2 of readme", "v1.0.0")
+	if _, err := g.Fetch(&GitSource{Repo: repoURL, Ref: "v1.0.0", SHA: sha1}, t.TempDir()); !errors.Is(err, ErrSHAMismatch) {
+		t.Errorf("expected a pin mismatch for a moved tag, got %v", err)
+	}
+	dir = t.TempDir()
+	if sha, err := g.Fetch(&GitSource{Repo: repoURL, Ref: sha1}, dir); err != nil || sha != sha1 {
+		t.Errorf("Fetch by SHA = %s, %v", sha, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(data) != "v1 of readme" {
+		t.Errorf("unexpected README.md %q for the pinned commit", data)
+	}
+}
+
+func TestGitFetcherTarball(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header",
+		PAXRecords: map[string]string{"comment": sha}})
+	_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "freertos-v1/", Mode: 0o755})
+	_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "freertos-v1/README.md", Mode: 0o644, Size: 5})
+	_, _ = tw.Write([]byte("hello"))
+	_ = tw.Close()
+	_ = gzw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Infineon/freertos/tar.gz/v1" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	g := NewGitFetcher(WithGitBinary(""))
+	g.codeloadBase = server.URL
+	dir := filepath.Join(t.TempDir(), "out")
+	got, err := g.Fetch(&GitSource{Repo: "https://github.com/Infineon/freertos", Ref: "v1", SHA: sha}, dir)
+	if err != nil || got != sha {
+		t.Fatalf("Fetch = %s, %v", got, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(data) != "hello" {
+		t.Errorf("unexpected README.md %q", data)
+	}
+	if _, err := g.Fetch(&GitSource{Repo: "https://github.com/Infineon/freertos", Ref: "v1", SHA: strings.Repeat("f", 40)}, t.TempDir()); !errors.Is(err, ErrSHAMismatch) {
+		t.Errorf("expected a pin mismatch, got %v", err)
+	}
+	if _, err := g.Fetch(&GitSource{Repo: "https://gitlab.com/a/b", Ref: "v1"}, t.TempDir()); err == nil {
+		t.Error("expected an error for a non-GitHub repository without git")
+	}
+}
+
+func TestAssetSource(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	src, err := AssetSource(sm, "CY8CKIT-062S2-43012", "")
+	if err != nil || src.Repo != "https://github.com/Infineon/TARGET_CY8CKIT-062S2-43012" || src.Ref != "latest-v4.X" {
+		t.Errorf("unexpected source %+v, %v", src, err)
+	}
+	if src, err := AssetSource(sm, "freertos", "latest-v10.X"); err != nil || src.Ref != "latest-v10.X" {
+		t.Errorf("unexpected source %+v, %v", src, err)
+	}
+	if _, err := AssetSource(sm, "freertos", "latest-v9.X"); err == nil {
+		t.Error("expected an error for an unlisted version")
+	}
+}
+
+func TestGitSourceOptionInjection(t *testing.T) {
+	g := NewGitFetcher(WithGitCacheDir(t.TempDir()))
+	for _, src := range []*GitSource{
+		{Repo: "--upload-pack=touch pwned", Ref: "v1"},
+		{Repo: "https://github.com/Infineon/freertos", Ref: "--upload-pack=touch pwned"},
+		{Repo: "ext::sh -c touch% pwned", Ref: "v1"},
+		{Repo: "git@github.com:Infineon/freertos.git", Ref: "v1"},
+	} {
+		if _, err := g.Fetch(src, t.TempDir()); !errors.Is(err, ErrUnsafeGitSource) {
+			t.Errorf("Fetch(%s) = %v, want ErrUnsafeGitSource", src, err)
+		}
+		if _, err := g.ResolveSHA(src.Repo, src.Ref); !errors.Is(err, ErrUnsafeGitSource) {
+			t.Errorf("ResolveSHA(%s) = %v, want ErrUnsafeGitSource", src, err)
+		}
+	}
+}
+
+// tarballOf returns a tar stream of the given headers, regular files holding their own name
+func tarballOf(t *testing.T, hdrs ...*tar.Header) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range hdrs {
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(hdr.Name))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			_, _ = tw.Write([]byte(hdr.Name))
+		}
+	}
+	_ = tw.Close()
+	return &buf
+}
+
+func TestExtractTarballSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	reg := func(name string) *tar.Header {
+		return &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644}
+	}
+	link := func(name, target string) *tar.Header {
+		return &tar.Header{Typeflag: tar.TypeSymlink, Name: name, Linkname: target}
+	}
+	for name, hdrs := range map[string][]*tar.Header{
+		"absolute target":       {link("a", outside), reg("a/x")},
+		"target outside":        {link("docs/a", "../../x")},
+		"climbing after a name": {link("b", "."), link("a", "b/..")},
+		"write through a link":  {link("a", "docs"), reg("a/x")},
+	} {
+		dir := t.TempDir()
+		if _, err := extractTarball(tarballOf(t, hdrs...), dir, 0); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+			t.Fatalf("%s: wrote outside the directory", name)
+		}
+	}
+
+	dir := t.TempDir()
+	if _, err := extractTarball(tarballOf(t, reg("README.md"), link("docs/readme", "../README.md")), dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "docs", "readme")); string(data) != "README.md" {
+		t.Errorf("unexpected docs/readme %q", data)
+	}
+}
@@ -0,0 +1,192 @@
+package mtbmanifest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testBoardsXML = `<boards>
+  <board>
+    <id>CY8CKIT-062S2-43012</id>
+    <category>Kit</category>
+    <board_uri>https://github.com/Infineon/TARGET_CY8CKIT-062S2-43012</board_uri>
+    <chips><mcu>CY8C624ABZI-S2D44</mcu><radio>CYW43012C0WKWBG</radio></chips>
+    <name>PSoC 62S2 Wi-Fi BT Pioneer Kit</name>
+    <summary>PSoC 6 kit with Wi-Fi and Bluetooth</summary>
+    <prov_capabilities>cat1 cat1a psoc6 hal led switch wifi bt flash_2048k</prov_capabilities>
+    <description><![CDATA[<p>The <strong>PSoC 62S2</strong> Wi-Fi BT Pioneer Kit.</p>]]></description>
+    <documentation_url>https://www.infineon.com/CY8CKIT-062S2-43012</documentation_url>
+    <versions>
+      <version flow_version="2.0"><num>Latest 4.X release</num><commit>latest-v4.X</commit></version>
+      <version flow_version="2.0"><num>4.1.0 release</num><commit>release-v4.1.0</commit></version>
+    </versions>
+  </board>
+  <board>
+    <id>CY8CKIT-149</id>
+    <category>Kit</category>
+    <board_uri>https://github.com/Infineon/TARGET_CY8CKIT-149</board_uri>
+    <chips><mcu>CY8C4147AZI-S475</mcu></chips>
+    <name>PSoC 4100S Plus Prototyping Kit</name>
+    <summary>PSoC 4 prototyping kit</summary>
+    <prov_capabilities>cat2 psoc4 hal led capsense</prov_capabilities>
+    <description>Low cost CAPSENSE prototyping kit.</description>
+    <documentation_url>https://www.infineon.com/CY8CKIT-149</documentation_url>
+    <versions>
+      <version flow_version="2.0"><num>Latest 3.X release</num><commit>latest-v3.X</commit></version>
+    </versions>
+  </board>
+</boards>`
+
+const testAppsXML = `<apps version="2.0">
+  <app keywords="led,starter,hello world" req_capabilities_v2="hal led">
+    <name>Hello World</name>
+    <id>mtb-example-hal-hello-world</id>
+    <category>Getting Started</category>
+    <uri>https://github.com/Infineon/mtb-example-hal-hello-world</uri>
+    <description><![CDATA[This example blinks an <i>LED</i> and prints Hello World over UART.]]></description>
+    <versions>
+      <version flow_version="2.0" tools_min_version="3.0.0"><num>Latest 4.X release</num><commit>latest-v4.X</commit></version>
+    </versions>
+  </app>
+  <app keywords="wifi,tcp,client" req_capabilities_v2="hal wifi">
+    <name>Wi-Fi TCP Client</name>
+    <id>mtb-example-wifi-tcp-client</id>
+    <category>Wi-Fi</category>
+    <uri>https://github.com/Infineon/mtb-example-wifi-tcp-client</uri>
+    <description>Connects to a TCP server over Wi-Fi.</description>
+    <versions>
+      <version flow_version="2.0" tools_min_version="3.0.0"><num>Latest 3.X release</num><commit>latest-v3.X</commit></version>
+    </versions>
+  </app>
+  <app keywords="capsense,buttons" req_capabilities_v2="capsense [psoc4,psoc6]">
+    <name>CAPSENSE Buttons and Slider</name>
+    <id>mtb-example-capsense-buttons-slider</id>
+    <category>Sensing</category>
+    <uri>https://github.com/Infineon/mtb-example-capsense-buttons-slider</uri>
+    <description>Demonstrates CAPSENSE buttons and a slider.</description>
+    <versions>
+      <version flow_version="2.0" tools_min_version="3.0.0"><num>Latest 2.X release</num><commit>latest-v2.X</commit></version>
+    </versions>
+  </app>
+</apps>`
+
+const testMiddlewareXML = `<middleware>
+  <middleware req_capabilities_v2="wifi">
+    <n>Wi-Fi Connection Manager</n>
+    <id>wifi-connection-manager</id>
+    <uri>https://github.com/Infineon/wifi-connection-manager</uri>
+    <desc>Wi-Fi Connection Manager (WCM) library</desc>
+    <category>Wi-Fi</category>
+    <versions>
+      <version flow_version="2.0"><num>Latest 3.X release</num><commit>latest-v3.X</commit><desc>Latest 3.X</desc></version>
+    </versions>
+  </middleware>
+  <middleware>
+    <n>FreeRTOS</n>
+    <id>freertos</id>
+    <uri>https://github.com/Infineon/freertos</uri>
+    <desc>FreeRTOS kernel for Infineon MCUs</desc>
+    <category>RTOS</category>
+    <versions>
+      <version flow_version="2.0"><num>Latest 10.X release</num><commit>latest-v10.X</commit><desc>Latest 10.X</desc></version>
+    </versions>
+  </middleware>
+</middleware>`
+
+// newTestSuperManifest assembles a small super manifest from the in-memory test manifests
+// above, without touching the network.
+func newTestSuperManifest(t *testing.T) *SuperManifest {
+	t.Helper()
+	boards, err := ReadBoardManifest([]byte(testBoardsXML))
+	if err != nil {
+		t.Fatalf("failed to parse boards: %v", err)
+	}
+	apps, err := ReadAppsManifest([]byte(testAppsXML))
+	if err != nil {
+		t.Fatalf("failed to parse apps: %v", err)
+	}
+	middleware, err := ReadMiddlewareManifest([]byte(testMiddlewareXML))
+	if err != nil {
+		t.Fatalf("failed to parse middleware: %v", err)
+	}
+
+	sm := NewSuperManifest().(*SuperManifest)
+	sm.BoardManifestList.BoardManifest = []*BoardManifest{
+		{URI: "https://example.com/boards.xml", Boards: boards},
+	}
+	sm.AppManifestList.AppManifest = []*AppManifest{
+		{URI: "https://example.com/apps.xml", Apps: apps},
+	}
+	sm.MiddlewareManifestList.MiddlewareManifest = []*MiddlewareManifest{
+		{URI: "https://example.com/middleware.xml", Middlewares: middleware},
+	}
+	return sm
+}
+
+// testTreeServer serves the test manifests over HTTP, with the example.com URLs in them
+// pointing at itself. Files can be replaced while it serves, and requests are counted.
+type testTreeServer struct {
+	*httptest.Server
+	// intercept, when set, sees each request first and may answer it itself
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+
+	mu    sync.Mutex
+	files map[string]string
+	hits  map[string]int
+}
+
+// newTestTreeServer serves the test manifests at /super.xml, /boards.xml, /apps.xml and
+// /middleware.xml until the test ends
+func newTestTreeServer(t *testing.T) *testTreeServer {
+	t.Helper()
+	s := &testTreeServer{
+		files: map[string]string{
+			"/super.xml":      testSuperXML,
+			"/boards.xml":     testBoardsXML,
+			"/apps.xml":       testAppsXML,
+			"/middleware.xml": testMiddlewareXML,
+		},
+		hits: map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.mu.Unlock()
+		if s.intercept != nil && s.intercept(w, r) {
+			return
+		}
+		data, ok := s.content(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// content returns the file served at path
+func (s *testTreeServer) content(path string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[path]
+	return strings.ReplaceAll(data, "https://example.com", s.URL), ok
+}
+
+// setFile replaces the file served at path
+func (s *testTreeServer) setFile(path, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = data
+}
+
+// hitCount returns the number of requests for path
+func (s *testTreeServer) hitCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
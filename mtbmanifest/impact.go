@@ -0,0 +1,239 @@
+package mtbmanifest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Impact of a library change
+// ////////////////////////////////////////////////////////////////////////
+
+// Before a breaking release of a library its maintainers ask who is affected. The dependency
+// manifests answer it: ImpactOfChange follows the dependencies of every version of every BSP
+// and library, through the libraries they need at the versions they need them, and reports
+// those that end up needing the library at a version of the given range, grouped by the
+// channel they were ingested from.
+
+// VersionRange is a set of constraints on versions, e.g. ">=4.0 <5.0". See ParseVersionRange.
+type VersionRange struct {
+	Raw         string
+	constraints []versionConstraint
+}
+
+type versionConstraint struct {
+	op string // one of = != < <= > >=
+	v  *SemanticVersion
+}
+
+// ParseVersionRange parses constraints separated by spaces or commas, each a version with an
+// optional operator: =, !=, <, <=, > or >=. Without an operator, or with =, an X matches any
+// number: "4.X" is every 4.x version. Ordering compares as CompareStrict does, so latest-v4.X
+// is above any 4.x release. An empty range, or "*", contains every version.
+func ParseVersionRange(s string) (*VersionRange, error) {
+	r := &VersionRange{Raw: strings.TrimSpace(s)}
+	if r.Raw == "" || r.Raw == "*" {
+		return r, nil
+	}
+	for _, field := range strings.FieldsFunc(r.Raw, func(c rune) bool { return c == ' ' || c == ',' }) {
+		op := "="
+		for _, candidate := range []string{">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(field, candidate) {
+				op, field = candidate, strings.TrimSpace(field[len(candidate):])
+				break
+			}
+		}
+		v, err := ParseVersion(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range %q: %w", s, err)
+		}
+		r.constraints = append(r.constraints, versionConstraint{op: op, v: v})
+	}
+	return r, nil
+}
+
+// Contains reports whether a version, e.g. the commit release-v4.1.0, meets every constraint.
+// Versions that do not parse are only in the empty range.
+func (r *VersionRange) Contains(version string) bool {
+	if len(r.constraints) == 0 {
+		return true
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	for _, c := range r.constraints {
+		cmp := CompareStrict(v, c.v)
+		ok := false
+		switch c.op {
+		case "=":
+			ok = v.Compare(c.v) == 0
+		case "!=":
+			ok = v.Compare(c.v) != 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ImpactedItem is a version of a BSP or library that needs the changed library
+type ImpactedItem struct {
+	Kind    ItemKind `json:"kind"`
+	ID      string   `json:"id"`
+	Version string   `json:"version"`
+	// LibraryVersion is the version of the changed library it needs
+	LibraryVersion string `json:"libraryVersion"`
+	// Via are the libraries, as ID@version, it needs the changed library through; empty when
+	// it depends on it directly
+	Via []string `json:"via,omitempty"`
+}
+
+// ChannelImpact are the impacted items of one channel
+type ChannelImpact struct {
+	Channel string          `json:"channel"`
+	Items   []*ImpactedItem `json:"items"`
+}
+
+// ImpactReport is the result of ImpactOfChange
+type ImpactReport struct {
+	Library string `json:"library"`
+	Range   string `json:"range,omitempty"`
+	// Channels are in name order, the default channel first
+	Channels []*ChannelImpact `json:"channels"`
+}
+
+// Len returns the number of impacted items of all channels
+func (r *ImpactReport) Len() int {
+	n := 0
+	for _, ch := range r.Channels {
+		n += len(ch.Items)
+	}
+	return n
+}
+
+// dependencyClosure calls found with the path of libraries taken for every library a version
+// of a depender needs, directly or through others, at the version it is needed. A library
+// already visited on the way is not followed again.
+func dependencyClosure(sm SuperManifestIF, v *DependerVersion, found func(dep *Dependee, via []string)) {
+	type step struct {
+		v   *DependerVersion
+		via []string
+	}
+	seen := map[string]bool{}
+	queue := []step{{v: v}}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, dep := range s.v.Dependees {
+			key := idKey(dep.ID) + "@" + dep.Commit
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			found(dep, s.via)
+			mw, ok := sm.GetMiddleware(dep.ID)
+			if !ok {
+				continue
+			}
+			if next := dependerVersion(mw.Dependencies, dep.Commit); next != nil {
+				via := append(append([]string{}, s.via...), dep.ID+"@"+dep.Commit)
+				queue = append(queue, step{v: next, via: via})
+			}
+		}
+	}
+}
+
+// ImpactOfChange returns every version of every BSP and library whose dependencies include
+// the library at a version in versionRange (see ParseVersionRange), grouped by channel. Items
+// ingested without a channel are in DefaultChannel.
+func ImpactOfChange(sm SuperManifestIF, libraryID, versionRange string) (*ImpactReport, error) {
+	r, err := ParseVersionRange(versionRange)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := sm.GetMiddleware(libraryID); !ok {
+		return nil, fmt.Errorf("middleware %s not found", libraryID)
+	}
+	byChannel := map[string][]*ImpactedItem{}
+	check := func(kind ItemKind, id, channel string, deps *Depender) {
+		if deps == nil {
+			return
+		}
+		if channel == "" {
+			channel = DefaultChannel
+		}
+		for _, v := range deps.Versions {
+			dependencyClosure(sm, v, func(dep *Dependee, via []string) {
+				if idKey(dep.ID) == idKey(libraryID) && r.Contains(dep.Commit) {
+					byChannel[channel] = append(byChannel[channel], &ImpactedItem{Kind: kind, ID: id,
+						Version: v.Commit, LibraryVersion: dep.Commit, Via: via})
+				}
+			})
+		}
+	}
+	for _, b := range sm.Boards() {
+		check(ItemKindBoard, b.ID, b.Channel, b.Dependencies)
+	}
+	for _, mw := range sm.Middleware() {
+		if idKey(mw.ID) != idKey(libraryID) {
+			check(ItemKindMiddleware, mw.ID, mw.Channel, mw.Dependencies)
+		}
+	}
+
+	report := &ImpactReport{Library: libraryID, Range: r.Raw, Channels: []*ChannelImpact{}}
+	for channel, items := range byChannel {
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i], items[j]
+			if a.Kind != b.Kind {
+				return a.Kind == ItemKindBoard // BSPs first
+			}
+			if a.ID != b.ID {
+				return a.ID < b.ID
+			}
+			return CompareStrict(parsedVersion(a.Version), parsedVersion(b.Version)) > 0
+		})
+		report.Channels = append(report.Channels, &ChannelImpact{Channel: channel, Items: items})
+	}
+	sort.Slice(report.Channels, func(i, j int) bool {
+		a, b := report.Channels[i].Channel, report.Channels[j].Channel
+		if (a == DefaultChannel) != (b == DefaultChannel) {
+			return a == DefaultChannel
+		}
+		return a < b
+	})
+	return report, nil
+}
+
+// parsedVersion returns the parsed version, or nil if it does not parse
+func parsedVersion(version string) *SemanticVersion {
+	v, _ := ParseVersion(version)
+	return v
+}
+
+// WriteCSV writes the report as CSV, one row per impacted item after a header row
+func (r *ImpactReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"channel", "kind", "id", "version", "library", "library_version", "direct", "via"})
+	for _, ch := range r.Channels {
+		for _, item := range ch.Items {
+			_ = cw.Write([]string{ch.Channel, string(item.Kind), item.ID, item.Version, r.Library,
+				item.LibraryVersion, strconv.FormatBool(len(item.Via) == 0), strings.Join(item.Via, " > ")})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
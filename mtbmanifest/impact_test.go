@@ -0,0 +1,87 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVersionRange(t *testing.T) {
+	for _, tc := range []struct {
+		rng, version string
+		want         bool
+	}{
+		{"", "anything", true},
+		{"4.X", "release-v4.1.0", true},
+		{"4.X", "latest-v4.X", true},
+		{"4.X", "release-v5.0.0", false},
+		{">=4.0 <5.0", "release-v4.2.1", true},
+		{">=4.0, <5.0", "latest-v4.X", true},
+		{">=4.0 <5.0", "release-v3.9.0", false},
+		{"<4.2", "latest-v4.X", false},
+		{"!=10.X", "latest-v9.X", true},
+		{">=1.0", "master", false},
+	} {
+		r, err := ParseVersionRange(tc.rng)
+		if err != nil {
+			t.Fatalf("ParseVersionRange(%q): %v", tc.rng, err)
+		}
+		if got := r.Contains(tc.version); got != tc.want {
+			t.Errorf("%q contains %q: got %v, want %v", tc.rng, tc.version, got, tc.want)
+		}
+	}
+	if _, err := ParseVersionRange(">=four"); err == nil {
+		t.Error("expected an error for a range without a version")
+	}
+}
+
+func TestImpactOfChange(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	board, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	wcm, _ := sm.GetMiddleware("wifi-connection-manager")
+	wcm.Channel = "early-access"
+	board.Dependencies = &Depender{ID: board.ID, Versions: []*DependerVersion{
+		{Commit: "latest-v4.X", Dependees: []*Dependee{{ID: "wifi-connection-manager", Commit: "latest-v3.X"}}},
+		{Commit: "release-v4.1.0", Dependees: []*Dependee{{ID: "freertos", Commit: "latest-v9.X"}}},
+	}}
+	wcm.Dependencies = &Depender{ID: wcm.ID, Versions: []*DependerVersion{
+		{Commit: "latest-v3.X", Dependees: []*Dependee{{ID: "freertos", Commit: "latest-v10.X"}}},
+	}}
+
+	report, err := ImpactOfChange(sm, "freertos", ">=10.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Channels) != 2 || report.Channels[0].Channel != DefaultChannel || report.Channels[1].Channel != "early-access" {
+		t.Fatalf("expected prod then early-access, got %+v", report.Channels)
+	}
+	if item := report.Channels[0].Items[0]; len(report.Channels[0].Items) != 1 || item.ID != board.ID ||
+		item.Version != "latest-v4.X" || item.LibraryVersion != "latest-v10.X" ||
+		len(item.Via) != 1 || item.Via[0] != "wifi-connection-manager@latest-v3.X" {
+		t.Errorf("expected the BSP through the connection manager, got %+v", report.Channels[0].Items)
+	}
+	if item := report.Channels[1].Items[0]; item.ID != "wifi-connection-manager" || len(item.Via) != 0 {
+		t.Errorf("expected the connection manager directly, got %+v", item)
+	}
+
+	if report, _ := ImpactOfChange(sm, "freertos", "9.X"); report.Len() != 1 || report.Channels[0].Items[0].Version != "release-v4.1.0" {
+		t.Errorf("expected only the 4.1.0 BSP for 9.X, got %+v", report.Channels)
+	}
+	report, _ = ImpactOfChange(sm, "freertos", "")
+	if report.Len() != 3 {
+		t.Errorf("expected every dependent for an empty range, got %d", report.Len())
+	}
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "channel,kind,id,version,library,library_version,direct,via" ||
+		lines[1] != "prod,board,CY8CKIT-062S2-43012,latest-v4.X,freertos,latest-v10.X,false,wifi-connection-manager@latest-v3.X" {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+
+	if _, err := ImpactOfChange(sm, "no-such-library", ""); err == nil {
+		t.Error("expected an error for an unknown library")
+	}
+}
@@ -0,0 +1,266 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Ingest history
+// ////////////////////////////////////////////////////////////////////////
+
+// One failed ingestion says little; the same manifest failing every other day, or every
+// manifest of one host failing, says a lot. Ingestions through a file cache save their
+// IngestReport in an ingest-reports directory next to the cached files, and
+// AnalyzeIngestReports summarizes the saved reports: how the tree grew and which URLs and
+// hosts keep failing.
+
+// ingestHistoryDir is the directory, inside a cache directory, reports are saved in
+const ingestHistoryDir = "ingest-reports"
+
+// MaxIngestReports is the number of reports an IngestHistory keeps; older ones are removed as
+// new ones are saved
+var MaxIngestReports = 500
+
+// IngestHistory keeps ingest reports as JSON files in a directory
+type IngestHistory struct {
+	dir string
+}
+
+// NewIngestHistory creates a history in dir. An empty dir means the ingest-reports directory
+// of the default cache directory. The directory is created when the first report is saved.
+func NewIngestHistory(dir string) *IngestHistory {
+	if dir == "" {
+		dir = filepath.Join(DefaultCacheDir(), ingestHistoryDir)
+	}
+	return &IngestHistory{dir: dir}
+}
+
+// WithIngestHistory saves the report of the ingestion in h. By default reports are saved in
+// the ingest-reports directory of the cache when the cache keeps files, and not at all
+// otherwise; nil turns saving off.
+func WithIngestHistory(h *IngestHistory) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.history, cfg.historySet = h, true
+	}
+}
+
+// ingestHistory returns where the report of an ingestion through cache is to be saved, or nil
+func (cfg *ingestConfig) ingestHistory(cache *ManifestCache) *IngestHistory {
+	if cfg.historySet {
+		return cfg.history
+	}
+	if store, ok := cache.Store().(*FileStore); ok {
+		return NewIngestHistory(filepath.Join(store.Dir(), ingestHistoryDir))
+	}
+	return nil
+}
+
+// Dir returns the directory the history keeps its reports in
+func (h *IngestHistory) Dir() string {
+	return h.dir
+}
+
+// files returns the report files, oldest first. Names sort by time.
+func (h *IngestHistory) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(h.dir, "*.json"))
+	sort.Strings(files)
+	return files, err
+}
+
+// Save adds a report to the history and removes the oldest reports beyond MaxIngestReports
+func (h *IngestHistory) Save(r *IngestReport) error {
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	name := r.StartedAt.UTC().Format("20060102T150405.000000000Z") + ".json"
+	if err := writeFileAtomic(filepath.Join(h.dir, name), data); err != nil {
+		return err
+	}
+	files, err := h.files()
+	if err != nil {
+		return err
+	}
+	for len(files) > MaxIngestReports {
+		_ = os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// List returns the saved reports, oldest first. Reports saved after since, if not zero, only.
+func (h *IngestHistory) List(since time.Time) ([]*IngestReport, error) {
+	files, err := h.files()
+	if err != nil {
+		return nil, err
+	}
+	ret := []*IngestReport{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue // pruned by another process
+		} else if err != nil {
+			return nil, err
+		}
+		r := &IngestReport{}
+		if err := json.Unmarshal(data, r); err != nil {
+			logger.Warningf("Skipping ingest report %s: %v\n", file, err)
+			continue
+		}
+		if since.IsZero() || r.StartedAt.After(since) {
+			ret = append(ret, r)
+		}
+	}
+	return ret, nil
+}
+
+// writeFileAtomic writes a file through a temporary file, so readers never see part of it
+func writeFileAtomic(filename string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
+
+// IngestRun is the summary of one ingestion
+type IngestRun struct {
+	StartedAt  time.Time     `json:"startedAt"`
+	Duration   time.Duration `json:"duration"`
+	Boards     int           `json:"boards"`
+	Apps       int           `json:"apps"`
+	Middleware int           `json:"middleware"`
+	Manifests  int           `json:"manifests"`
+	Failed     int           `json:"failed"`
+}
+
+// URLFailures counts the failures of one manifest URL across ingestions
+type URLFailures struct {
+	URL  string       `json:"url"`
+	Kind ManifestKind `json:"kind"`
+	// Runs is the number of ingestions that read the URL, Failures the number that failed to
+	Runs      int       `json:"runs"`
+	Failures  int       `json:"failures"`
+	LastError string    `json:"lastError,omitempty"`
+	LastFail  time.Time `json:"lastFail,omitempty"`
+}
+
+// HostFailures counts the failures of the manifests of one host across ingestions
+type HostFailures struct {
+	Host     string `json:"host"`
+	Reads    int    `json:"reads"`
+	Failures int    `json:"failures"`
+}
+
+// IngestTrends summarizes a series of ingestions
+type IngestTrends struct {
+	Runs []*IngestRun `json:"runs"`
+	// BoardsGrowth, AppsGrowth and MiddlewareGrowth are the change in the number of items from
+	// the first run to the last
+	BoardsGrowth     int `json:"boardsGrowth"`
+	AppsGrowth       int `json:"appsGrowth"`
+	MiddlewareGrowth int `json:"middlewareGrowth"`
+	// AverageDuration is the mean duration of the runs
+	AverageDuration time.Duration `json:"averageDuration"`
+	// Failing lists the URLs that failed at least once, most failures first
+	Failing []*URLFailures `json:"failing"`
+	// Hosts lists the hosts of the URLs that failed at least once, most failures first
+	Hosts []*HostFailures `json:"hosts"`
+}
+
+// AnalyzeIngestReports summarizes reports, which should be oldest first as IngestHistory.List
+// returns them
+func AnalyzeIngestReports(reports []*IngestReport) *IngestTrends {
+	trends := &IngestTrends{Runs: []*IngestRun{}, Failing: []*URLFailures{}, Hosts: []*HostFailures{}}
+	byURL := make(map[string]*URLFailures)
+	byHost := make(map[string]*HostFailures)
+	var total time.Duration
+	for _, r := range reports {
+		run := &IngestRun{StartedAt: r.StartedAt, Duration: r.Duration, Boards: r.Boards, Apps: r.Apps,
+			Middleware: r.Middleware, Manifests: len(r.Manifests)}
+		total += r.Duration
+		for _, m := range r.Manifests {
+			uf := byURL[m.URL]
+			if uf == nil {
+				uf = &URLFailures{URL: m.URL, Kind: m.Kind}
+				byURL[m.URL] = uf
+			}
+			host := hostOf(m.URL)
+			hf := byHost[host]
+			if hf == nil {
+				hf = &HostFailures{Host: host}
+				byHost[host] = hf
+			}
+			uf.Runs++
+			hf.Reads++
+			if m.Error != "" {
+				run.Failed++
+				uf.Failures++
+				uf.LastError, uf.LastFail = m.Error, r.StartedAt
+				hf.Failures++
+			}
+		}
+		trends.Runs = append(trends.Runs, run)
+	}
+	if n := len(trends.Runs); n > 0 {
+		first, last := trends.Runs[0], trends.Runs[n-1]
+		trends.BoardsGrowth = last.Boards - first.Boards
+		trends.AppsGrowth = last.Apps - first.Apps
+		trends.MiddlewareGrowth = last.Middleware - first.Middleware
+		trends.AverageDuration = total / time.Duration(n)
+	}
+	for _, uf := range byURL {
+		if uf.Failures > 0 {
+			trends.Failing = append(trends.Failing, uf)
+		}
+	}
+	sort.Slice(trends.Failing, func(i, j int) bool {
+		a, b := trends.Failing[i], trends.Failing[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.URL < b.URL
+	})
+	for _, hf := range byHost {
+		if hf.Failures > 0 {
+			trends.Hosts = append(trends.Hosts, hf)
+		}
+	}
+	sort.Slice(trends.Hosts, func(i, j int) bool {
+		a, b := trends.Hosts[i], trends.Hosts[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.Host < b.Host
+	})
+	return trends
+}
+
+// hostOf returns the host of a URL, or the URL itself if it has none
+func hostOf(urlStr string) string {
+	if u, err := url.Parse(urlStr); err == nil && u.Host != "" {
+		return strings.ToLower(u.Host)
+	}
+	return urlStr
+}
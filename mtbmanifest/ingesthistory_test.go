@@ -0,0 +1,73 @@
+package mtbmanifest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIngestHistory(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	dir := t.TempDir()
+	seed := NewManifestCache(WithDir(dir), WithCacheTTL(time.Hour))
+	defer seed.Close()
+	for u, data := range map[string]string{
+		superURL:                         testSuperXML,
+		"https://example.com/boards.xml": testBoardsXML,
+		"https://example.com/apps.xml":   testAppsXML,
+	} {
+		if err := seed.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first run cannot read the middleware manifest, the second can, the third is not saved
+	if _, err := NewSuperManifestFromURL(superURL, WithCacheDir(dir), WithOffline()); err != nil {
+		t.Fatal(err)
+	}
+	if err := seed.writeCache("https://example.com/middleware.xml", []byte(testMiddlewareXML)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSuperManifestFromURL(superURL, WithCacheDir(dir), WithOffline()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSuperManifestFromURL(superURL, WithCacheDir(dir), WithOffline(), WithIngestHistory(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	history := NewIngestHistory(filepath.Join(dir, ingestHistoryDir))
+	reports, err := history.List(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || !reports[0].StartedAt.Before(reports[1].StartedAt) {
+		t.Fatalf("expected two reports in order, got %d", len(reports))
+	}
+	if later, _ := history.List(reports[0].StartedAt); len(later) != 1 {
+		t.Errorf("expected one report after the first, got %d", len(later))
+	}
+
+	trends := AnalyzeIngestReports(reports)
+	if trends.BoardsGrowth != 0 || trends.AppsGrowth != 0 || trends.MiddlewareGrowth != 2 {
+		t.Errorf("unexpected growth %d %d %d", trends.BoardsGrowth, trends.AppsGrowth, trends.MiddlewareGrowth)
+	}
+	if len(trends.Failing) != 1 || trends.Failing[0].URL != "https://example.com/middleware.xml" ||
+		trends.Failing[0].Failures != 1 || trends.Failing[0].Runs != 2 {
+		t.Errorf("unexpected failing URLs %+v", trends.Failing)
+	}
+	if len(trends.Hosts) != 1 || trends.Hosts[0].Host != "example.com" || trends.Hosts[0].Reads != 8 {
+		t.Errorf("unexpected hosts %+v", trends.Hosts)
+	}
+	if trends.Runs[0].Failed != 1 || trends.Runs[1].Failed != 0 {
+		t.Errorf("unexpected runs %+v %+v", trends.Runs[0], trends.Runs[1])
+	}
+
+	defer func(max int) { MaxIngestReports = max }(MaxIngestReports)
+	MaxIngestReports = 1
+	if err := history.Save(newIngestReport(superURL)); err != nil {
+		t.Fatal(err)
+	}
+	if reports, _ := history.List(time.Time{}); len(reports) != 1 || reports[0].Boards != 0 {
+		t.Errorf("expected only the newest report to be kept, got %d", len(reports))
+	}
+}
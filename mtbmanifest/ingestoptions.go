@@ -0,0 +1,234 @@
+package mtbmanifest
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Ingestion options
+// ////////////////////////////////////////////////////////////////////////
+
+// The options of an ingestion stay with the tree it produces, so that several trees with
+// different sources, caches and loggers can live in one process, e.g. a server offering both
+// the production and a beta manifest channel. The package-level settings (SetLogger,
+// EnableXMLUnmarshalVerification, SetDefaultCacheOptions, ...) are only defaults, read when an
+// ingestion starts. Deterministic mode and case-insensitive IDs remain process-wide.
+
+// IngestOption configures NewSuperManifestFromURL
+type IngestOption func(*ingestConfig)
+
+// ProgressFunc is told about every manifest file ingested: done of total files are read,
+// the latest being urlStr. total includes the super manifest.
+type ProgressFunc func(done, total int, urlStr string)
+
+type ingestConfig struct {
+	fetcher  *ManifestFetcher
+	cache    *ManifestCache
+	cacheDir string
+	ttl      time.Duration
+	logger   LoggerIF
+	sections map[ManifestKind]bool
+	offline  bool
+	progress ProgressFunc
+	// onReport gets the report when the ingestion ends (see WithReportFunc)
+	onReport func(*IngestReport)
+	verify   bool
+	// concurrency limits the fetches in flight (see WithConcurrency)
+	concurrency int
+	// resume reads what an interrupted ingestion completed from the cache (see WithResume)
+	resume bool
+	// ctx interrupts the ingestion when done (see WithContext)
+	ctx context.Context
+	// readmes fetches the middleware READMEs after ingestion (see WithReadmes)
+	readmes *ReadmeFetcher
+	// snapshotFallback uses the embedded snapshot when offline (see WithSnapshotFallback)
+	snapshotFallback bool
+	// boardLifecycles replace the table of SetBoardLifecycles for this tree, unless nil
+	boardLifecycles map[string]Lifecycle
+	// supersessions replace the mapping of SetMiddlewareSupersessions for this tree, unless nil
+	supersessions map[string]string
+	// categoryDifficulties replace those of SetCategoryDifficulties for this tree, unless nil
+	categoryDifficulties map[string]Difficulty
+
+	dependencyProvider DependencyProvider
+	capabilityProvider CapabilityProvider
+
+	history    *IngestHistory
+	historySet bool
+
+	// channel labels the items ingested (see ChannelSet)
+	channel string
+	// failOn are the warning codes that fail the ingestion (see WithFailOn)
+	failOn []WarningCode
+
+	// format, urlTemplate and negotiator choose the super manifest when no URL is given (see
+	// manifestformat.go)
+	format      *ManifestFormat
+	urlTemplate string
+	negotiator  FormatNegotiator
+}
+
+// ErrOffline is returned for manifests that are not cached when ingesting offline
+var ErrOffline = errors.New("not cached and offline")
+
+// WithFetcher ingests through the given fetcher and its cache. It takes precedence over
+// WithCacheDir and WithTTL.
+func WithFetcher(f *ManifestFetcher) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.fetcher = f
+	}
+}
+
+// WithContext interrupts the ingestion when ctx is done: requests in flight are cancelled and
+// NewSuperManifestFromURL fails with an error matching ctx.Err(). The checkpoint of an
+// interrupted ingestion is kept for WithResume.
+func WithContext(ctx context.Context) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.ctx = ctx
+	}
+}
+
+// withIngestCache ingests through the given cache
+func withIngestCache(cache *ManifestCache) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.cache = cache
+	}
+}
+
+// WithCacheDir keeps the manifest cache in dir instead of the default store
+func WithCacheDir(dir string) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.cacheDir = dir
+	}
+}
+
+// WithTTL sets the cache TTL for manifests no TTL policy covers (see SetDefaultTTLPolicy)
+func WithTTL(ttl time.Duration) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithLogger sends the messages about this ingestion to l instead of the package logger
+func WithLogger(l LoggerIF) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.logger = l
+	}
+}
+
+// WithSections limits ingestion to some kinds of manifests, e.g. KindBoards and
+// KindCapabilities for a board picker. The super manifest is always read. Dependencies and
+// capabilities are only read for the boards and middleware that are.
+func WithSections(kinds ...ManifestKind) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.sections = make(map[ManifestKind]bool)
+		for _, k := range kinds {
+			cfg.sections[k] = true
+		}
+	}
+}
+
+// WithOffline uses only what is cached, however old, and never touches the network. Manifests
+// that are not cached fail with ErrOffline.
+func WithOffline() IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.offline = true
+	}
+}
+
+// WithProgress calls fn as manifest files are read
+func WithProgress(fn ProgressFunc) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.progress = fn
+	}
+}
+
+// WithVerification turns the check for unknown XML tags and attributes on or off for this
+// ingestion, overriding EnableXMLUnmarshalVerification
+func WithVerification(enable bool) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.verify = enable
+	}
+}
+
+func newIngestConfig(opts []IngestOption) *ingestConfig {
+	cfg := &ingestConfig{logger: logger, verify: doVerifyXMLUnmarshal, ctx: context.Background(),
+		snapshotFallback: true}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// parser returns a parse function for UnmarshalManifest that verifies as configured and
+// reports surprises to the ingestion's logger
+func parser[T any](cfg *ingestConfig, report *IngestReport, urlStr string) func([]byte) (*T, error) {
+	surprise := func(path string) {
+		report.warn(cfg.logger, WarnXMLSurprise, urlStr, "XML Unmarshal Surprise: %s", path)
+	}
+	return func(data []byte) (*T, error) {
+		var obj T
+		if err := unmarshalXML(data, &obj, cfg.verify, cfg.logger, surprise); err != nil {
+			return nil, err
+		}
+		normalizeManifest(&obj, urlStr, data)
+		return &obj, nil
+	}
+}
+
+// wants reports whether a kind of manifest is to be ingested
+func (cfg *ingestConfig) wants(kind ManifestKind) bool {
+	return cfg.sections == nil || cfg.sections[kind]
+}
+
+// newCache returns the cache to ingest through: that of the fetcher or the one given, or else
+// a new one, not started
+func (cfg *ingestConfig) newCache() *ManifestCache {
+	switch {
+	case cfg.fetcher != nil:
+		return cfg.fetcher.Cache()
+	case cfg.cache != nil:
+		return cfg.cache
+	case cfg.cacheDir != "":
+		return NewManifestCache(append(defaultCacheOptionList(), WithDir(cfg.cacheDir), WithCacheTTL(cfg.ttl),
+			WithCacheLogger(cfg.logger))...)
+	default:
+		return newManifestDefaultCache(WithCacheTTL(cfg.ttl), WithCacheLogger(cfg.logger))
+	}
+}
+
+// newFetcher returns the fetcher to ingest with, and what to call when done with it: a cache of
+// its own refreshes the stale entries the ingestion served, and stops once they are done
+func (cfg *ingestConfig) newFetcher() (*ManifestFetcher, func()) {
+	concurrency := runtime.NumCPU()
+	if cfg.concurrency > 0 {
+		concurrency = cfg.concurrency
+	}
+	if cfg.fetcher != nil {
+		concurrency = cap(cfg.fetcher.limiter)
+	}
+	cache := cfg.newCache()
+	done := func() {}
+	switch {
+	case cfg.offline:
+		cache = NewManifestCache(WithStore(cache.Store()), WithCacheTTL(cache.ttl), WithTTLPolicy(cache.ttlPolicy),
+			WithMaxStale(cache.maxStale), WithCacheOnly(), WithCacheLogger(cfg.logger))
+	case cfg.fetcher != nil:
+		return cfg.fetcher, done
+	case cfg.cache == nil:
+		cache.Start() // the cache is our own
+		done = cache.finish
+	}
+	return NewManifestFetcher(WithCache(cache), WithMaxConcurrent(concurrency)), done
+}
+
+// WithCacheOnly makes the cache serve only what it holds, however old, and never fetch.
+// Misses fail with ErrOffline.
+func WithCacheOnly() CacheOption {
+	return func(c *ManifestCache) {
+		c.cacheOnly = true
+	}
+}
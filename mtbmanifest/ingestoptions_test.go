@@ -0,0 +1,173 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIngestOptions(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	dir := t.TempDir()
+	seed := NewManifestCache(WithDir(dir), WithCacheTTL(time.Hour))
+	for u, data := range map[string]string{
+		superURL:                         testSuperXML,
+		"https://example.com/boards.xml": testBoardsXML,
+		"https://example.com/apps.xml":   testAppsXML,
+	} {
+		if err := seed.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed.Close()
+
+	type step struct {
+		done, total int
+	}
+	steps := []step{}
+	sm, err := NewSuperManifestFromURL(superURL, WithCacheDir(dir), WithOffline(),
+		WithSections(KindBoards), WithProgress(func(done, total int, urlStr string) {
+			steps = append(steps, step{done, total})
+		}))
+	if err != nil {
+		t.Fatalf("failed to ingest offline: %v", err)
+	}
+	if len(sm.GetBoardIDs()) != 2 || len(sm.GetAppIDs()) != 0 || len(sm.GetMiddlewareIDs()) != 0 {
+		t.Errorf("expected only boards, got %d boards, %d apps, %d middleware",
+			len(sm.GetBoardIDs()), len(sm.GetAppIDs()), len(sm.GetMiddlewareIDs()))
+	}
+	if len(steps) != 2 || steps[0] != (step{1, 2}) || steps[1] != (step{2, 2}) {
+		t.Errorf("unexpected progress %v", steps)
+	}
+
+	// The middleware manifest is not cached, and offline it must not be fetched
+	rec := NewRecordingLogger(nil, 10)
+	sm, err = NewSuperManifestFromURL(superURL, WithCacheDir(dir), WithOffline(), WithLogger(rec))
+	if err != nil {
+		t.Fatalf("failed to ingest offline: %v", err)
+	}
+	if len(sm.GetAppIDs()) != 3 || len(sm.GetMiddlewareIDs()) != 0 {
+		t.Errorf("expected apps but no middleware, got %d apps, %d middleware", len(sm.GetAppIDs()), len(sm.GetMiddlewareIDs()))
+	}
+	failed := sm.IngestReport().Failed()
+	if len(failed) != 1 || !strings.Contains(failed[0].Error, ErrOffline.Error()) {
+		t.Errorf("expected the middleware manifest to fail offline, got %+v", failed)
+	}
+	if records := rec.Records(); len(records) != 1 || !strings.Contains(records[0].Message, "middleware.xml") {
+		t.Errorf("expected the failure on the given logger, got %v", records)
+	}
+
+	if _, err := NewSuperManifestFromURL("https://example.com/other.xml", WithCacheDir(dir), WithOffline()); err == nil {
+		t.Error("expected an uncached super manifest to fail offline")
+	}
+}
+
+// messageLogger keeps every message, whatever its level
+type messageLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *messageLogger) add(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *messageLogger) Infof(format string, args ...interface{})    { l.add(format, args...) }
+func (l *messageLogger) Debugf(format string, args ...interface{})   { l.add(format, args...) }
+func (l *messageLogger) Errorf(format string, args ...interface{})   { l.add(format, args...) }
+func (l *messageLogger) Warningf(format string, args ...interface{}) { l.add(format, args...) }
+
+func (l *messageLogger) count(substr string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, m := range l.messages {
+		if strings.Contains(m, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestIngestIsolation(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	seed := func(superXML string) string {
+		dir := t.TempDir()
+		cache := NewManifestCache(WithDir(dir), WithCacheTTL(time.Hour))
+		defer cache.Close()
+		for u, data := range map[string]string{
+			superURL:                             superXML,
+			"https://example.com/boards.xml":     testBoardsXML,
+			"https://example.com/apps.xml":       testAppsXML,
+			"https://example.com/middleware.xml": testMiddlewareXML,
+		} {
+			if err := cache.writeCache(u, []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	prodDir := seed(testSuperXML)
+	betaDir := seed(strings.Replace(testSuperXML, `version="2.0"`, `version="2.1"`, 1))
+
+	prodLog, betaLog := &messageLogger{}, &messageLogger{}
+	prod, err := NewSuperManifestFromURL(superURL, WithCacheDir(prodDir), WithOffline(), WithLogger(prodLog),
+		WithVerification(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta, err := NewSuperManifestFromURL(superURL, WithCacheDir(betaDir), WithOffline(), WithLogger(betaLog),
+		WithVerification(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := prodLog.count("Begin Verification"); n != 0 {
+		t.Errorf("the production tree should not be verified, got %d verifications", n)
+	}
+	if n := betaLog.count("Begin Verification"); n != 4 {
+		t.Errorf("expected the four beta manifests to be verified on the beta logger, got %d", n)
+	}
+
+	// Merge warnings go to the logger of the tree merged into
+	prod.(*SuperManifest).AddSuperManifest(beta.(*SuperManifest))
+	if prodLog.count("different versions") != 1 || betaLog.count("different versions") != 0 {
+		t.Errorf("expected the merge warning on the production logger only")
+	}
+}
+
+func TestIngestPrivateCacheStops(t *testing.T) {
+	srv := newTestTreeServer(t)
+
+	// Everything cached is stale, so the ingestion queues refreshes in its own cache
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	for _, path := range []string{"/super.xml", "/boards.xml", "/apps.xml", "/middleware.xml"} {
+		data, _ := srv.content(path)
+		if err := store.Put(srv.URL+path, []byte(data), time.Now().Add(-2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	countStarted := func() int {
+		n := 0
+		startedCaches.Range(func(_, _ any) bool { n++; return true })
+		return n
+	}
+	before := countStarted()
+	if _, err := NewSuperManifestFromURL(srv.URL+"/super.xml", WithCacheDir(dir), WithTTL(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for countStarted() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := countStarted(); n != before {
+		t.Fatalf("expected the cache of the ingestion to stop, %d caches still run", n-before)
+	}
+	if info, err := store.Stat(srv.URL + "/boards.xml"); err != nil || time.Since(info.ModTime) > time.Hour {
+		t.Errorf("expected the stale entries to be refreshed before the cache stopped")
+	}
+}
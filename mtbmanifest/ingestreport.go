@@ -0,0 +1,98 @@
+package mtbmanifest
+
+import (
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Ingest reports
+// ////////////////////////////////////////////////////////////////////////
+
+// A failed board, app or middleware manifest does not fail the whole ingestion; its items are
+// just missing from the tree. The IngestReport records every manifest that was read and what
+// went wrong with it, so missing items can be explained after the fact.
+
+// IngestedManifest is the outcome of reading one manifest file
+type IngestedManifest struct {
+	URL   string       `json:"url"`
+	Kind  ManifestKind `json:"kind"`
+	Bytes int          `json:"bytes"`
+	Error string       `json:"error,omitempty"`
+	// Resumed is set when the manifest was read from the cache because an interrupted
+	// ingestion had already completed it (see WithResume)
+	Resumed bool `json:"resumed,omitempty"`
+}
+
+// IngestReport describes one ingestion of a super manifest tree
+type IngestReport struct {
+	URL       string        `json:"url"`
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`
+	// FromSnapshot is set when the embedded snapshot stood in for the network
+	FromSnapshot bool                `json:"fromSnapshot,omitempty"`
+	Manifests    []*IngestedManifest `json:"manifests"`
+	Boards       int                 `json:"boards"`
+	Apps         int                 `json:"apps"`
+	Middleware   int                 `json:"middleware"`
+	// Warnings are what went wrong, in the order noticed (see WarningCode)
+	Warnings []*IngestWarning `json:"warnings,omitempty"`
+	// ResumedFrom is when the interrupted ingestion this one resumed started
+	ResumedFrom time.Time `json:"resumedFrom,omitzero"`
+	// RateLimits are what the hosts said of their request quotas (see RateLimitError)
+	RateLimits []*HostRateLimit `json:"rateLimits,omitempty"`
+
+	mu sync.Mutex
+	// resumed are the URLs read from the cache on resuming; checkpoint, if set, saves the
+	// report after every manifest (see resume.go)
+	resumed      map[string]bool
+	checkpoint   func()
+	checkpointMu sync.Mutex
+}
+
+func newIngestReport(urlStr string) *IngestReport {
+	return &IngestReport{URL: urlStr, StartedAt: time.Now().UTC(), Manifests: []*IngestedManifest{}}
+}
+
+// record adds the outcome of one manifest, and a warning if it failed; safe to call from
+// fetch callbacks. Callers log the failure.
+func (r *IngestReport) record(urlStr string, kind ManifestKind, data []byte, err error) {
+	m := &IngestedManifest{URL: urlStr, Kind: kind, Bytes: len(data)}
+	r.mu.Lock()
+	m.Resumed = r.resumed[urlStr]
+	if err != nil {
+		m.Error = err.Error()
+		r.Warnings = append(r.Warnings, warningFor(urlStr, err))
+	}
+	r.Manifests = append(r.Manifests, m)
+	r.mu.Unlock()
+	if r.checkpoint != nil {
+		r.checkpoint()
+	}
+}
+
+// Failed returns the manifests that could not be read or parsed
+func (r *IngestReport) Failed() []*IngestedManifest {
+	ret := []*IngestedManifest{}
+	for _, m := range r.Manifests {
+		if m.Error != "" {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+// IngestReport returns the report of the ingestion that produced the tree, or nil for trees
+// that were not loaded from URLs
+func (sm *SuperManifest) IngestReport() *IngestReport {
+	return sm.ingestReport
+}
+
+// WithReportFunc calls fn with the report of the ingestion once it ends, whether it succeeded,
+// failed or was interrupted. A tree carries its own report (see SuperManifest.IngestReport);
+// this is for the ingestions that return none.
+func WithReportFunc(fn func(*IngestReport)) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.onReport = fn
+	}
+}
@@ -0,0 +1,203 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Ingest warnings
+// ////////////////////////////////////////////////////////////////////////
+
+// Ingesting a tree goes wrong in small ways all the time: a manifest that cannot be fetched,
+// content older than the cache accepts, a tag the parser does not know, two super manifests
+// that disagree. These used to be log lines only, which programs cannot act on. Every such
+// warning is now also recorded in the IngestReport with a WarningCode, so callers can list,
+// filter or count them, and WithFailOn turns the classes a caller cannot live with into an
+// ingestion error. The logger still gets every warning.
+
+// WarningCode names a class of ingest warnings
+type WarningCode string
+
+const (
+	WarnFetchFailed      WarningCode = "fetch-failed"      // a manifest could not be fetched
+	WarnParseFailed      WarningCode = "parse-failed"      // a manifest could not be parsed
+	WarnStaleData        WarningCode = "stale-data"        // a manifest was staler than the cache accepts (see WithMaxStale)
+	WarnXMLSurprise      WarningCode = "xml-surprise"      // a tag or attribute the parser does not know (see WithVerification)
+	WarnSnapshotFallback WarningCode = "snapshot-fallback" // the embedded snapshot stood in for the network
+	WarnHistoryNotSaved  WarningCode = "history-not-saved" // the ingest report could not be saved in the history
+	WarnVersionMismatch  WarningCode = "version-mismatch"  // super manifests of different versions were merged
+	WarnDuplicateURL     WarningCode = "duplicate-url"     // merged trees have different content under one URL
+	WarnNewerFormat      WarningCode = "newer-format"      // a super manifest is in a format newer than SupportedFormatMajor
+	WarnRateLimited      WarningCode = "rate-limited"      // a host rate limited the fetch of a manifest longer than the cache waits
+)
+
+// WarningCodes returns all warning codes
+func WarningCodes() []WarningCode {
+	return []WarningCode{WarnFetchFailed, WarnParseFailed, WarnStaleData, WarnXMLSurprise, WarnSnapshotFallback,
+		WarnHistoryNotSaved, WarnVersionMismatch, WarnDuplicateURL, WarnNewerFormat, WarnRateLimited}
+}
+
+// ParseWarningCode returns the warning code named s
+func ParseWarningCode(s string) (WarningCode, error) {
+	code := WarningCode(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains(WarningCodes(), code) {
+		names := []string{}
+		for _, c := range WarningCodes() {
+			names = append(names, string(c))
+		}
+		return "", fmt.Errorf("unknown warning code %q, expected one of %s", s, strings.Join(names, ", "))
+	}
+	return code, nil
+}
+
+// IngestWarning is one thing that went wrong while ingesting
+type IngestWarning struct {
+	Code WarningCode `json:"code"`
+	// URL is the manifest the warning is about, if any
+	URL     string `json:"url,omitempty"`
+	Message string `json:"message"`
+}
+
+func (w *IngestWarning) String() string {
+	if w.URL == "" {
+		return fmt.Sprintf("%s: %s", w.Code, w.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", w.Code, w.URL, w.Message)
+}
+
+// filterWarnings returns the warnings with one of the codes, all of them if none are given
+func filterWarnings(warnings []*IngestWarning, codes []WarningCode) []*IngestWarning {
+	ret := []*IngestWarning{}
+	for _, w := range warnings {
+		if len(codes) == 0 || slices.Contains(codes, w.Code) {
+			ret = append(ret, w)
+		}
+	}
+	return ret
+}
+
+// IngestWarningsError is returned by an ingestion that produced warnings of a code given to
+// WithFailOn
+type IngestWarningsError struct {
+	URL      string
+	Warnings []*IngestWarning
+}
+
+func (e *IngestWarningsError) Error() string {
+	lines := []string{}
+	for _, w := range e.Warnings {
+		lines = append(lines, w.String())
+	}
+	return fmt.Sprintf("ingesting %s: %d warnings not accepted: %s", e.URL, len(e.Warnings), strings.Join(lines, "; "))
+}
+
+// WithFailOn fails the ingestion when it produces warnings of any of the given codes. The
+// error is an IngestWarningsError listing them. Warnings from merging trees afterwards, e.g.
+// with AddSuperManifest, do not fail anything; see SuperManifest.Warnings.
+func WithFailOn(codes ...WarningCode) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.failOn = append(cfg.failOn, codes...)
+	}
+}
+
+// ErrFetchFailed and ErrParseFailed match, with errors.Is, the errors of manifests that could
+// not be fetched and of manifests fetched but not parsed
+var (
+	ErrFetchFailed = errors.New("failed to fetch manifest")
+	ErrParseFailed = errors.New("failed to parse manifest")
+)
+
+// manifestFetchError is the error of a manifest that could not be fetched, where the message
+// already says so
+type manifestFetchError struct {
+	err error
+}
+
+func (e *manifestFetchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *manifestFetchError) Unwrap() error {
+	return e.err
+}
+
+func (e *manifestFetchError) Is(target error) bool {
+	return target == ErrFetchFailed
+}
+
+// manifestParseError is the error of a manifest fetched but not parsed
+type manifestParseError struct {
+	err error
+}
+
+func (e *manifestParseError) Is(target error) bool {
+	return target == ErrParseFailed
+}
+
+func (e *manifestParseError) Error() string {
+	return fmt.Sprintf("failed to parse manifest: %v", e.err)
+}
+
+func (e *manifestParseError) Unwrap() error {
+	return e.err
+}
+
+// warningFor returns the warning for a manifest that failed to load
+func warningFor(urlStr string, err error) *IngestWarning {
+	var stale *StaleDataError
+	var parse *manifestParseError
+	var rateLimit *RateLimitError
+	code := WarnFetchFailed
+	switch {
+	case errors.As(err, &rateLimit):
+		code = WarnRateLimited
+	case errors.As(err, &stale):
+		code = WarnStaleData
+	case errors.As(err, &parse):
+		code = WarnParseFailed
+	}
+	return &IngestWarning{Code: code, URL: urlStr, Message: err.Error()}
+}
+
+// addWarning records a warning already logged
+func (r *IngestReport) addWarning(w *IngestWarning) {
+	r.mu.Lock()
+	r.Warnings = append(r.Warnings, w)
+	r.mu.Unlock()
+}
+
+// warn records a warning and logs it
+func (r *IngestReport) warn(logger LoggerIF, code WarningCode, urlStr, format string, args ...any) {
+	w := &IngestWarning{Code: code, URL: urlStr, Message: fmt.Sprintf(format, args...)}
+	logger.Warningf("%s\n", w)
+	r.addWarning(w)
+}
+
+// WarningsOf returns the warnings with one of the given codes, all of them if none are given
+func (r *IngestReport) WarningsOf(codes ...WarningCode) []*IngestWarning {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return filterWarnings(r.Warnings, codes)
+}
+
+// warn records a warning about merging trees and logs it
+func (sm *SuperManifest) warn(code WarningCode, urlStr, format string, args ...any) {
+	w := &IngestWarning{Code: code, URL: urlStr, Message: fmt.Sprintf(format, args...)}
+	sm.log().Warningf("%s\n", w)
+	sm.mergeWarnings = append(sm.mergeWarnings, w)
+}
+
+// Warnings returns the warnings of the ingestions that produced the tree and of merging trees
+// into it, with one of the given codes, all of them if none are given
+func (sm *SuperManifest) Warnings(codes ...WarningCode) []*IngestWarning {
+	ret := []*IngestWarning{}
+	for _, r := range append([]*IngestReport{sm.ingestReport}, sm.mergedReports...) {
+		if r != nil {
+			ret = append(ret, r.WarningsOf(codes...)...)
+		}
+	}
+	return append(ret, filterWarnings(sm.mergeWarnings, codes)...)
+}
@@ -0,0 +1,97 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIngestWarnings(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	superXML := strings.Replace(testSuperXML, "<board-manifest>",
+		`<board-manifest dependency-url="https://example.com/deps.xml">`, 1)
+	appsXML := strings.Replace(testAppsXML, "<name>Hello World</name>", "<name>Hello World</name><unknown-tag>x</unknown-tag>", 1)
+	for u, data := range map[string]string{
+		superURL:                             superXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       appsXML,
+		"https://example.com/middleware.xml": "<middleware><broken",
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// deps.xml is not cached, so it fails offline; middleware.xml fails to parse
+	log := &messageLogger{}
+	sm, err := NewSuperManifestFromURL(superURL, withIngestCache(cache), WithOffline(), WithVerification(true),
+		WithLogger(log))
+	if err != nil {
+		t.Fatalf("failed to ingest: %v", err)
+	}
+	tree := sm.(*SuperManifest)
+	got := map[WarningCode][]string{}
+	for _, w := range tree.Warnings() {
+		got[w.Code] = append(got[w.Code], w.URL)
+	}
+	if urls := got[WarnFetchFailed]; len(urls) != 1 || urls[0] != "https://example.com/deps.xml" {
+		t.Errorf("unexpected fetch failures %v", urls)
+	}
+	if urls := got[WarnParseFailed]; len(urls) != 1 || urls[0] != "https://example.com/middleware.xml" {
+		t.Errorf("unexpected parse failures %v", urls)
+	}
+	if urls := got[WarnXMLSurprise]; len(urls) == 0 || urls[0] != "https://example.com/apps.xml" {
+		t.Errorf("unexpected surprises %v", urls)
+	}
+	if log.count("xml-surprise: https://example.com/apps.xml") == 0 {
+		t.Error("expected the surprise to be logged")
+	}
+	if n := len(tree.Warnings(WarnParseFailed, WarnFetchFailed)); n != 2 {
+		t.Errorf("expected 2 warnings of the codes asked for, got %d", n)
+	}
+
+	_, err = NewSuperManifestFromURL(superURL, withIngestCache(cache), WithOffline(), WithLogger(log),
+		WithFailOn(WarnParseFailed))
+	var warnErr *IngestWarningsError
+	if !errors.As(err, &warnErr) || len(warnErr.Warnings) != 1 || warnErr.Warnings[0].Code != WarnParseFailed {
+		t.Fatalf("expected the parse failure to fail the ingestion, got %v", err)
+	}
+	if _, err := NewSuperManifestFromURL(superURL, withIngestCache(cache), WithOffline(), WithLogger(log),
+		WithFailOn(WarnStaleData)); err != nil {
+		t.Fatalf("expected no stale data, got %v", err)
+	}
+
+	// Merging records its own warnings
+	other := NewSuperManifest().(*SuperManifest)
+	other.Version = "9.9"
+	tree.AddSuperManifest(other)
+	if w := tree.Warnings(WarnVersionMismatch); len(w) != 1 {
+		t.Errorf("expected a version mismatch, got %v", w)
+	}
+
+	if _, err := ParseWarningCode("XML-Surprise"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseWarningCode("nope"); err == nil {
+		t.Error("expected an error for an unknown code")
+	}
+}
+
+func TestIngestErrorKinds(t *testing.T) {
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	if err := cache.writeCache("https://example.com/broken.xml", []byte("<super-manifest><broken")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewSuperManifestFromURL("https://example.com/missing.xml", withIngestCache(cache), WithOffline())
+	if !errors.Is(err, ErrFetchFailed) || !errors.Is(err, ErrOffline) || errors.Is(err, ErrParseFailed) {
+		t.Errorf("expected a fetch failure, got %v", err)
+	}
+	_, err = NewSuperManifestFromURL("https://example.com/broken.xml", withIngestCache(cache), WithOffline())
+	if !errors.Is(err, ErrParseFailed) || errors.Is(err, ErrFetchFailed) {
+		t.Errorf("expected a parse failure, got %v", err)
+	}
+}
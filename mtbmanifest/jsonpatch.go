@@ -0,0 +1,246 @@
+package mtbmanifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// JSON Patch update feeds
+// ////////////////////////////////////////////////////////////////////////
+
+// Services that copy the manifest data into a database of their own should not reload all of
+// it after every refresh. ItemDocument is the data as one JSON document, the items keyed by
+// kind and ID:
+//
+//	{"boards": {"CY8CKIT-062S2-43012": {...}}, "apps": {...}, "middleware": {...}}
+//
+// and JSONPatch turns the changes between two trees (see DiffTrees) into RFC 6902 operations on
+// it, one per item added, removed or changed. A PatchFeed numbers the patches of each channel's
+// refreshes, so consumers ask for what came after the last one they applied, and reload the
+// whole document when they have fallen too far behind.
+
+// PatchOperation is one RFC 6902 JSON Patch operation
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// patchSections are the members of the item document, by item kind
+var patchSections = map[ItemKind]string{
+	ItemKindBoard:      "boards",
+	ItemKindApp:        "apps",
+	ItemKindMiddleware: "middleware",
+}
+
+// PatchPath returns the JSON Pointer (RFC 6901) of an item in the item document
+func PatchPath(kind ItemKind, id string) string {
+	escaped := strings.NewReplacer("~", "~0", "/", "~1").Replace(id)
+	return "/" + patchSections[kind] + "/" + escaped
+}
+
+// ItemDocument returns the document JSON patches apply to: every board, app and middleware
+// item as JSON, by kind and ID. Where a tree lists an ID twice, the item lookups return is
+// the one in the document.
+func ItemDocument(sm SuperManifestIF) (map[string]map[string]json.RawMessage, error) {
+	doc := map[string]map[string]json.RawMessage{"boards": {}, "apps": {}, "middleware": {}}
+	var err error
+	add := func(section, id string, item any) {
+		if data, e := json.Marshal(item); e != nil {
+			err = e
+		} else {
+			doc[section][id] = data
+		}
+	}
+	for _, id := range sm.GetBoardIDs() {
+		b, _ := sm.GetBoard(id)
+		add("boards", id, b)
+	}
+	for _, id := range sm.GetAppIDs() {
+		a, _ := sm.GetApp(id)
+		add("apps", id, a)
+	}
+	for _, id := range sm.GetMiddlewareIDs() {
+		mw, _ := sm.GetMiddleware(id)
+		add("middleware", id, mw)
+	}
+	return doc, err
+}
+
+// JSONPatch returns the operations that turn the item document of one tree into that of
+// another: removals first, then additions and replacements, each in DiffTrees order
+func JSONPatch(from, to SuperManifestIF) ([]*PatchOperation, error) {
+	return patchOperations(to, DiffTrees(from, to))
+}
+
+// patchOperations returns the operations of a change set whose new items are in tree
+func patchOperations(tree SuperManifestIF, cs *ChangeSet) ([]*PatchOperation, error) {
+	ops := []*PatchOperation{}
+	for _, c := range cs.Removed {
+		ops = append(ops, &PatchOperation{Op: "remove", Path: PatchPath(c.Kind, c.ID)})
+	}
+	value := func(c *ItemChange) (json.RawMessage, error) {
+		var item any
+		switch c.Kind {
+		case ItemKindBoard:
+			item, _ = tree.GetBoard(c.ID)
+		case ItemKindApp:
+			item, _ = tree.GetApp(c.ID)
+		case ItemKindMiddleware:
+			item, _ = tree.GetMiddleware(c.ID)
+		}
+		return json.Marshal(item)
+	}
+	for _, group := range []struct {
+		op      string
+		changes []*ItemChange
+	}{{"add", cs.Added}, {"replace", cs.Changed}} {
+		for _, c := range group.changes {
+			data, err := value(c)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %v", c.Kind, c.ID, err)
+			}
+			ops = append(ops, &PatchOperation{Op: group.op, Path: PatchPath(c.Kind, c.ID), Value: data})
+		}
+	}
+	return ops, nil
+}
+
+// ApplyJSONPatch applies operations made by JSONPatch to an item document
+func ApplyJSONPatch(doc map[string]map[string]json.RawMessage, ops []*PatchOperation) error {
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for _, op := range ops {
+		parts := strings.SplitN(strings.TrimPrefix(op.Path, "/"), "/", 2)
+		if len(parts) != 2 || doc[parts[0]] == nil {
+			return fmt.Errorf("unsupported path %q", op.Path)
+		}
+		section, id := doc[parts[0]], unescape.Replace(parts[1])
+		_, exists := section[id]
+		switch op.Op {
+		case "add":
+			section[id] = op.Value
+		case "replace":
+			if !exists {
+				return fmt.Errorf("replace: no item at %s", op.Path)
+			}
+			section[id] = op.Value
+		case "remove":
+			if !exists {
+				return fmt.Errorf("remove: no item at %s", op.Path)
+			}
+			delete(section, id)
+		default:
+			return fmt.Errorf("unsupported operation %q", op.Op)
+		}
+	}
+	return nil
+}
+
+// ErrPatchGap is returned by PatchFeed.Since for a sequence number whose successors are no
+// longer kept; the consumer has to reload the whole item document
+var ErrPatchGap = errors.New("patches since this sequence number are no longer kept, reload the item document")
+
+// PatchDocument is the patch of one refresh of a channel
+type PatchDocument struct {
+	Channel string `json:"channel"`
+	// Seq numbers the patches of a channel from 1
+	Seq        int64             `json:"seq"`
+	Created    time.Time         `json:"created"`
+	Operations []*PatchOperation `json:"operations"`
+}
+
+// PatchFeed keeps the most recent patches of each channel
+type PatchFeed struct {
+	limit int
+
+	mu       sync.Mutex
+	channels map[string]*channelFeed
+}
+
+type channelFeed struct {
+	seq  int64
+	docs []*PatchDocument
+}
+
+// NewPatchFeed creates a feed keeping the last limit patches of each channel; limit <= 0
+// means 100
+func NewPatchFeed(limit int) *PatchFeed {
+	if limit <= 0 {
+		limit = 100
+	}
+	return &PatchFeed{limit: limit, channels: make(map[string]*channelFeed)}
+}
+
+// Publish adds the patch from one tree of a channel to the next. It returns nil, and adds
+// nothing, when no item changed.
+func (f *PatchFeed) Publish(channel string, from, to SuperManifestIF) (*PatchDocument, error) {
+	ops, err := JSONPatch(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return f.publish(channel, ops), nil
+}
+
+func (f *PatchFeed) publish(channel string, ops []*PatchOperation) *PatchDocument {
+	if len(ops) == 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	feed := f.channels[channel]
+	if feed == nil {
+		feed = &channelFeed{}
+		f.channels[channel] = feed
+	}
+	feed.seq++
+	doc := &PatchDocument{Channel: channel, Seq: feed.seq, Created: time.Now().UTC(), Operations: ops}
+	feed.docs = append(feed.docs, doc)
+	if len(feed.docs) > f.limit {
+		feed.docs = feed.docs[len(feed.docs)-f.limit:]
+	}
+	return doc
+}
+
+// Refresh refreshes a live tree of a channel and publishes what changed
+func (f *PatchFeed) Refresh(ctx context.Context, channel string, live *LiveSuperManifest) (*PatchDocument, error) {
+	fresh, changes, err := live.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := patchOperations(fresh, changes)
+	if err != nil {
+		return nil, err
+	}
+	return f.publish(channel, ops), nil
+}
+
+// Seq returns the sequence number of the latest patch of a channel, 0 if there is none
+func (f *PatchFeed) Seq(channel string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if feed := f.channels[channel]; feed != nil {
+		return feed.seq
+	}
+	return 0
+}
+
+// Since returns the patches of a channel after sequence number seq, oldest first. A consumer
+// that has applied none passes the Seq it read along with the item document.
+func (f *PatchFeed) Since(channel string, seq int64) ([]*PatchDocument, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	feed := f.channels[channel]
+	if feed == nil || seq >= feed.seq {
+		return []*PatchDocument{}, nil
+	}
+	if len(feed.docs) == 0 || feed.docs[0].Seq > seq+1 {
+		return nil, ErrPatchGap
+	}
+	return append([]*PatchDocument{}, feed.docs[seq+1-feed.docs[0].Seq:]...), nil
+}
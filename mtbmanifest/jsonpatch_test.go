@@ -0,0 +1,119 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// patchTestTrees returns the test tree and one with different boards
+func patchTestTrees(t *testing.T) (from, to *SuperManifest) {
+	t.Helper()
+	contents := map[string][]byte{
+		"https://example.com/super.xml":      []byte(testSuperXML),
+		"https://example.com/boards.xml":     []byte(testBoardsXML),
+		"https://example.com/apps.xml":       []byte(testAppsXML),
+		"https://example.com/middleware.xml": []byte(testMiddlewareXML),
+	}
+	from, err := superManifestFromContents(contents, []string{"https://example.com/super.xml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents["https://example.com/boards.xml"] = []byte(`<boards>
+  <board><id>KIT/NEW</id><name>New Kit</name><chips><mcu>X</mcu></chips></board>
+  <board><id>CY8CKIT-149</id><name>PSoC 4100S Plus Prototyping Kit</name><chips><mcu>X</mcu></chips>
+    <versions><version><num>9.0</num><commit>release-v9.0.0</commit></version></versions></board>
+</boards>`)
+	to, err = superManifestFromContents(contents, []string{"https://example.com/super.xml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return from, to
+}
+
+func TestJSONPatch(t *testing.T) {
+	from, to := patchTestTrees(t)
+	ops, err := JSONPatch(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) == 0 || ops[0].Op != "remove" {
+		t.Fatalf("expected removals first, got %+v", ops)
+	}
+	found := false
+	for _, op := range ops {
+		if op.Op == "add" && op.Path == "/boards/KIT~1NEW" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected an escaped add of KIT/NEW in %+v", ops)
+	}
+
+	doc, err := ItemDocument(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyJSONPatch(doc, ops); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ItemDocument(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unchanged items keep the provenance of the old fetch, so only the boards compare equal
+	if !reflect.DeepEqual(doc["boards"], want["boards"]) {
+		t.Error("expected the patched boards to equal the new tree's")
+	}
+	if !reflect.DeepEqual(slices.Sorted(maps.Keys(doc["apps"])), slices.Sorted(maps.Keys(want["apps"]))) {
+		t.Error("expected the patched apps to equal the new tree's")
+	}
+
+	// Patches survive a round trip through JSON
+	data, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `{"op":"remove","path":"/boards/`) {
+		t.Errorf("unexpected JSON %s", data)
+	}
+
+	if err := ApplyJSONPatch(doc, []*PatchOperation{{Op: "remove", Path: "/boards/NO-SUCH-KIT"}}); err == nil {
+		t.Error("expected removing a missing item to fail")
+	}
+}
+
+func TestPatchFeed(t *testing.T) {
+	a, b := patchTestTrees(t)
+	feed := NewPatchFeed(2)
+
+	if doc, err := feed.Publish("prod", a, a); err != nil || doc != nil {
+		t.Fatalf("expected no patch for an unchanged tree, got %+v, %v", doc, err)
+	}
+	for i := 0; i < 3; i++ {
+		from, to := SuperManifestIF(a), SuperManifestIF(b)
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		if _, err := feed.Publish("prod", from, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if feed.Seq("prod") != 3 || feed.Seq("lts") != 0 {
+		t.Fatalf("unexpected sequence numbers %d, %d", feed.Seq("prod"), feed.Seq("lts"))
+	}
+	docs, err := feed.Since("prod", 1)
+	if err != nil || len(docs) != 2 || docs[0].Seq != 2 {
+		t.Fatalf("unexpected patches %+v, %v", docs, err)
+	}
+	if docs, err := feed.Since("prod", 3); err != nil || len(docs) != 0 {
+		t.Errorf("expected nothing after the latest patch, got %+v, %v", docs, err)
+	}
+	if _, err := feed.Since("prod", 0); !errors.Is(err, ErrPatchGap) {
+		t.Errorf("expected ErrPatchGap, got %v", err)
+	}
+}
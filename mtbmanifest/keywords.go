@@ -0,0 +1,142 @@
+package mtbmanifest
+
+import (
+	"sort"
+	"strings"
+)
+
+// KeywordCount is a keyword and how many apps carry it
+type KeywordCount struct {
+	Keyword string `json:"keyword"`
+	Count   int    `json:"count"`
+}
+
+// KeywordIndex maps the keywords of v2 app manifests to the apps that carry them.
+// Keywords are matched case-insensitively.
+type KeywordIndex struct {
+	apps     map[string][]*App // normalized keyword -> apps, in manifest order
+	spelling map[string]string // normalized keyword -> first spelling seen
+	keywords map[*App][]string // app -> its normalized keywords
+}
+
+func normalizeKeyword(keyword string) string {
+	return strings.ToLower(strings.TrimSpace(keyword))
+}
+
+// NewKeywordIndex builds a keyword index over all apps in the super manifest
+func NewKeywordIndex(sm SuperManifestIF) *KeywordIndex {
+	ki := &KeywordIndex{
+		apps:     make(map[string][]*App),
+		spelling: make(map[string]string),
+		keywords: make(map[*App][]string),
+	}
+	for _, id := range sm.GetAppIDs() {
+		app, ok := sm.GetApp(id)
+		if !ok {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, keyword := range app.GetKeywords() {
+			norm := normalizeKeyword(keyword)
+			if seen[norm] {
+				continue
+			}
+			seen[norm] = true
+			if _, ok := ki.spelling[norm]; !ok {
+				ki.spelling[norm] = keyword
+			}
+			ki.apps[norm] = append(ki.apps[norm], app)
+			ki.keywords[app] = append(ki.keywords[app], norm)
+		}
+	}
+	return ki
+}
+
+// GetApps returns the apps carrying the keyword, in manifest order
+func (ki *KeywordIndex) GetApps(keyword string) []*App {
+	apps := ki.apps[normalizeKeyword(keyword)]
+	ret := make([]*App, len(apps))
+	copy(ret, apps)
+	return ret
+}
+
+// GetAppsWithAll returns the apps carrying every one of the keywords, in manifest order
+func (ki *KeywordIndex) GetAppsWithAll(keywords ...string) []*App {
+	if len(keywords) == 0 {
+		return []*App{}
+	}
+	ret := []*App{}
+	for _, app := range ki.apps[normalizeKeyword(keywords[0])] {
+		if ki.hasAll(app, keywords[1:]) {
+			ret = append(ret, app)
+		}
+	}
+	return ret
+}
+
+func (ki *KeywordIndex) hasAll(app *App, keywords []string) bool {
+	for _, keyword := range keywords {
+		norm := normalizeKeyword(keyword)
+		found := false
+		for _, k := range ki.keywords[app] {
+			if k == norm {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Keywords returns every keyword with the number of apps carrying it, most used first
+func (ki *KeywordIndex) Keywords() []KeywordCount {
+	ret := make([]KeywordCount, 0, len(ki.apps))
+	for norm, apps := range ki.apps {
+		ret = append(ret, KeywordCount{Keyword: ki.spelling[norm], Count: len(apps)})
+	}
+	sortKeywordCounts(ret)
+	return ret
+}
+
+// CoOccurrences returns the keywords that appear on the same apps as the given keyword, with
+// the number of apps they share, most frequent first
+func (ki *KeywordIndex) CoOccurrences(keyword string) []KeywordCount {
+	norm := normalizeKeyword(keyword)
+	counts := make(map[string]int)
+	for _, app := range ki.apps[norm] {
+		for _, other := range ki.keywords[app] {
+			if other != norm {
+				counts[other]++
+			}
+		}
+	}
+	ret := make([]KeywordCount, 0, len(counts))
+	for other, count := range counts {
+		ret = append(ret, KeywordCount{Keyword: ki.spelling[other], Count: count})
+	}
+	sortKeywordCounts(ret)
+	return ret
+}
+
+func sortKeywordCounts(counts []KeywordCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Keyword < counts[j].Keyword
+	})
+}
+
+// GetKeywordIndex returns the keyword index for this super manifest, building it if needed. It
+// is safe to call from several goroutines; the index is built once.
+func (sm *SuperManifest) GetKeywordIndex() *KeywordIndex {
+	return sm.keywordIndex.get(func() *KeywordIndex { return NewKeywordIndex(sm) })
+}
+
+// GetAppsByKeyword returns the apps carrying the keyword (case-insensitive), in manifest order
+func (sm *SuperManifest) GetAppsByKeyword(keyword string) []*App {
+	return sm.GetKeywordIndex().GetApps(keyword)
+}
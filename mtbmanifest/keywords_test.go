@@ -0,0 +1,33 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestKeywordIndex(t *testing.T) {
+	sm := newTestSuperManifest(t)
+
+	apps := sm.GetAppsByKeyword("LED")
+	if len(apps) != 1 || apps[0].ID != "mtb-example-hal-hello-world" {
+		t.Errorf("unexpected apps for LED: %v", apps)
+	}
+	if apps := sm.GetAppsByKeyword("no-such-keyword"); len(apps) != 0 {
+		t.Errorf("expected no apps, got %d", len(apps))
+	}
+
+	ki := sm.GetKeywordIndex()
+	if apps := ki.GetAppsWithAll("wifi", "tcp"); len(apps) != 1 {
+		t.Errorf("expected 1 app with wifi and tcp, got %d", len(apps))
+	}
+	if apps := ki.GetAppsWithAll("wifi", "led"); len(apps) != 0 {
+		t.Errorf("expected no apps with wifi and led, got %d", len(apps))
+	}
+
+	if n := len(ki.Keywords()); n != 8 {
+		t.Errorf("expected 8 distinct keywords, got %d", n)
+	}
+	related := ki.CoOccurrences("wifi")
+	if len(related) != 2 || related[0].Keyword != "client" || related[1].Keyword != "tcp" {
+		t.Errorf("unexpected co-occurrences for wifi: %v", related)
+	}
+}
@@ -0,0 +1,91 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Known channels
+// ////////////////////////////////////////////////////////////////////////
+
+// Infineon publishes more than one super manifest: the production one, long-term-support
+// variants that stay on older tools, and one for Wi-Fi and Bluetooth connectivity parts.
+// Users pasted their GitHub URLs around; the registry below names them, so a ChannelSet can
+// load "lts" without being told where it is. Programs and config files add their own with
+// RegisterChannel, or by giving NewChannelSet a channel of the same name, which wins.
+
+// Names of the channels registered by default
+const (
+	LTSChannel    = "lts"     // the long-term-support super manifest
+	WifiBTChannel = "wifi-bt" // the super manifest of Wi-Fi and Bluetooth connectivity parts
+	LegacyChannel = "fv1"     // the super manifest in the format of tools before ModusToolbox 3.0
+)
+
+// URLs of the official super manifests besides SuperManifestURL
+const (
+	SuperManifestLTSURL    = "https://github.com/Infineon/mtb-super-manifest/raw/v2.X/mtb-super-manifest-fv2-lts.xml"
+	SuperManifestWifiBTURL = "https://github.com/Infineon/mtb-super-manifest/raw/v2.X/mtb-super-manifest-fv2-wifi-bt.xml"
+	SuperManifestFV1URL    = "https://github.com/Infineon/mtb-super-manifest/raw/v2.X/mtb-super-manifest.xml"
+)
+
+var (
+	knownChannelsMu sync.Mutex
+	knownChannels   = []*Channel{
+		{Name: DefaultChannel, URLs: []string{SuperManifestURL}, Description: "The official ModusToolbox super manifest"},
+		{Name: LTSChannel, URLs: []string{SuperManifestLTSURL}, Description: "Long-term-support releases"},
+		{Name: WifiBTChannel, URLs: []string{SuperManifestWifiBTURL}, Description: "Wi-Fi and Bluetooth connectivity parts"},
+		{Name: LegacyChannel, URLs: []string{SuperManifestFV1URL}, Description: "The super manifest in the fv1 format of tools before 3.0"},
+	}
+)
+
+// KnownChannels returns the registered channels, the default channel first
+func KnownChannels() []*Channel {
+	knownChannelsMu.Lock()
+	defer knownChannelsMu.Unlock()
+	ret := []*Channel{}
+	for _, ch := range knownChannels {
+		c := *ch
+		ret = append(ret, &c)
+	}
+	return ret
+}
+
+// KnownChannel returns the registered channel with the given name
+func KnownChannel(name string) (*Channel, bool) {
+	for _, ch := range KnownChannels() {
+		if ch.Name == name {
+			return ch, true
+		}
+	}
+	return nil, false
+}
+
+// RegisterChannel adds a channel to the registry, or replaces the one of the same name
+func RegisterChannel(ch *Channel) error {
+	if err := ch.validate(); err != nil {
+		return err
+	}
+	c := *ch
+	knownChannelsMu.Lock()
+	defer knownChannelsMu.Unlock()
+	for i, known := range knownChannels {
+		if known.Name == ch.Name {
+			knownChannels[i] = &c
+			return nil
+		}
+	}
+	knownChannels = append(knownChannels, &c)
+	return nil
+}
+
+// validate checks the name and URLs of a channel
+func (ch *Channel) validate() error {
+	if !channelNameRe.MatchString(ch.Name) {
+		return fmt.Errorf("invalid channel name %q", ch.Name)
+	}
+	if len(ch.URLs) == 0 {
+		return fmt.Errorf("channel %s has no super manifest URLs", ch.Name)
+	}
+	return nil
+}
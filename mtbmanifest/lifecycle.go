@@ -0,0 +1,195 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Board lifecycle
+// ////////////////////////////////////////////////////////////////////////
+
+// Kits are sold for years and then phased out: first marked "not recommended for new designs"
+// (NRND), later obsolete, while the manifest keeps listing them. The manifest has no field for
+// this, so the status comes from a table the program sets for one tree or for all (see
+// WithBoardLifecycles and SetBoardLifecycles), from a <lifecycle> tag should a manifest carry
+// one, or else from what the name, summary and description say. Catalogs and FindSolutions
+// leave obsolete boards out unless asked, and LockfileLifecycleIssues tells which locked boards
+// are no longer active.
+
+// Lifecycle is the sales status of a board
+type Lifecycle string
+
+const (
+	LifecycleActive   Lifecycle = "active"
+	LifecycleNRND     Lifecycle = "nrnd" // not recommended for new designs
+	LifecycleObsolete Lifecycle = "obsolete"
+)
+
+// Lifecycles returns the lifecycle states, from active to obsolete
+func Lifecycles() []Lifecycle {
+	return []Lifecycle{LifecycleActive, LifecycleNRND, LifecycleObsolete}
+}
+
+// ParseLifecycle parses a lifecycle status. Besides the names of the states it accepts the
+// usual spellings: "not recommended for new designs", "EOL", "end of life", "discontinued".
+func ParseLifecycle(s string) (Lifecycle, error) {
+	switch strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(s, "-", " "))), " ") {
+	case "active", "production", "released":
+		return LifecycleActive, nil
+	case "nrnd", "not recommended for new designs":
+		return LifecycleNRND, nil
+	case "obsolete", "eol", "end of life", "discontinued":
+		return LifecycleObsolete, nil
+	}
+	return "", fmt.Errorf("unknown lifecycle status %q, expected active, nrnd or obsolete", s)
+}
+
+// UnmarshalJSON accepts what ParseLifecycle does
+func (l *Lifecycle) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseLifecycle(s)
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+var (
+	boardLifecyclesMu sync.RWMutex
+	// boardLifecycles maps the idKey of a board ID to its status
+	boardLifecycles = map[string]Lifecycle{}
+)
+
+// SetBoardLifecycles sets the status of boards by ID, e.g. from a product status export.
+// These take precedence over what the manifest says. Pass nil to rely on the manifest only.
+// Trees ingested WithBoardLifecycles use their own table instead.
+func SetBoardLifecycles(lifecycles map[string]Lifecycle) {
+	m := lifecycleTable(lifecycles)
+	boardLifecyclesMu.Lock()
+	defer boardLifecyclesMu.Unlock()
+	boardLifecycles = m
+}
+
+// WithBoardLifecycles sets the status of the boards of this tree by ID, in place of those of
+// SetBoardLifecycles, e.g. for a channel whose kits have a product status export of their own
+func WithBoardLifecycles(lifecycles map[string]Lifecycle) IngestOption {
+	m := lifecycleTable(lifecycles)
+	return func(cfg *ingestConfig) {
+		cfg.boardLifecycles = m
+	}
+}
+
+// lifecycleTable returns lifecycles keyed by the idKey of the board IDs
+func lifecycleTable(lifecycles map[string]Lifecycle) map[string]Lifecycle {
+	m := make(map[string]Lifecycle, len(lifecycles))
+	for id, l := range lifecycles {
+		m[idKey(id)] = l
+	}
+	return m
+}
+
+// lifecycleTableOf returns the table that applies to a board: that of the ingestion of its
+// manifest, or else that of SetBoardLifecycles
+func lifecycleTableOf(b *Board) map[string]Lifecycle {
+	if b.Origin != nil && b.Origin.ingestCfg != nil && b.Origin.ingestCfg.boardLifecycles != nil {
+		return b.Origin.ingestCfg.boardLifecycles
+	}
+	boardLifecyclesMu.RLock()
+	defer boardLifecyclesMu.RUnlock()
+	return boardLifecycles
+}
+
+// ReadBoardLifecycles reads a JSON object of board IDs and statuses, e.g.
+// {"CY8CKIT-062-WIFI-BT": "nrnd"}, for SetBoardLifecycles
+func ReadBoardLifecycles(jsonData []byte) (map[string]Lifecycle, error) {
+	ret := map[string]Lifecycle{}
+	if err := json.Unmarshal(jsonData, &ret); err != nil {
+		return nil, fmt.Errorf("invalid board lifecycles: %v", err)
+	}
+	return ret, nil
+}
+
+var (
+	obsoleteRegex = regexp.MustCompile(`(?i)\b(?:obsolete|discontinued|end[ -]of[ -]life|EOL)\b`)
+	nrndRegex     = regexp.MustCompile(`(?i)\b(?:NRND|not recommended for new designs)\b`)
+)
+
+// Lifecycle returns the status of the board: the one set with WithBoardLifecycles or
+// SetBoardLifecycles, else that of a <lifecycle> tag, else obsolete or NRND if its name,
+// summary or description says so, else active
+func (b *Board) Lifecycle() Lifecycle {
+	if l, ok := lifecycleTableOf(b)[idKey(b.ID)]; ok {
+		return l
+	}
+	for _, tag := range b.Surprises {
+		if tag.XMLName.Local == "lifecycle" {
+			if l, err := ParseLifecycle(tag.Body); err == nil {
+				return l
+			}
+		}
+	}
+	for _, text := range []string{b.Name, b.Summary, b.PlainDescription()} {
+		if obsoleteRegex.MatchString(text) {
+			return LifecycleObsolete
+		}
+	}
+	for _, text := range []string{b.Name, b.Summary, b.PlainDescription()} {
+		if nrndRegex.MatchString(text) {
+			return LifecycleNRND
+		}
+	}
+	return LifecycleActive
+}
+
+// BoardsByLifecycle groups the boards of a tree by status, each group in manifest order
+func BoardsByLifecycle(sm SuperManifestIF) map[Lifecycle][]*Board {
+	ret := map[Lifecycle][]*Board{}
+	for _, l := range Lifecycles() {
+		ret[l] = []*Board{}
+	}
+	for _, id := range distinctIDs(sm.GetBoardIDs()) {
+		if b, ok := sm.GetBoard(id); ok {
+			ret[b.Lifecycle()] = append(ret[b.Lifecycle()], b)
+		}
+	}
+	return ret
+}
+
+// LifecycleIssue is a locked board that is no longer active
+type LifecycleIssue struct {
+	Entry     *LockEntry `json:"entry"`
+	Lifecycle Lifecycle  `json:"lifecycle"`
+}
+
+func (issue *LifecycleIssue) String() string {
+	switch issue.Lifecycle {
+	case LifecycleNRND:
+		return fmt.Sprintf("%s: board is not recommended for new designs", issue.Entry.ID)
+	default:
+		return fmt.Sprintf("%s: board is %s", issue.Entry.ID, issue.Lifecycle)
+	}
+}
+
+// LockfileLifecycleIssues returns the boards of a lockfile that are NRND or obsolete in a tree
+func LockfileLifecycleIssues(sm SuperManifestIF, lf *Lockfile) []*LifecycleIssue {
+	ret := []*LifecycleIssue{}
+	for _, e := range lf.Entries {
+		if e.Kind != ItemKindBoard {
+			continue
+		}
+		if b, ok := sm.GetBoard(e.ID); ok {
+			if l := b.Lifecycle(); l != LifecycleActive {
+				ret = append(ret, &LifecycleIssue{Entry: e, Lifecycle: l})
+			}
+		}
+	}
+	return ret
+}
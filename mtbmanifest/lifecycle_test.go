@@ -0,0 +1,102 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestBoardLifecycle(t *testing.T) {
+	defer SetBoardLifecycles(nil)
+	sm := newTestSuperManifest(t)
+	kit149, _ := sm.GetBoard("CY8CKIT-149")
+	kit062, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	if kit149.Lifecycle() != LifecycleActive {
+		t.Fatalf("expected an active board, got %s", kit149.Lifecycle())
+	}
+	kit149.Description = "<p>This kit is <b>not recommended for new designs</b>.</p>"
+	if kit149.Lifecycle() != LifecycleNRND {
+		t.Fatalf("expected the description to make the board NRND, got %s", kit149.Lifecycle())
+	}
+	SetBoardLifecycles(map[string]Lifecycle{"CY8CKIT-062S2-43012": LifecycleObsolete})
+	if kit062.Lifecycle() != LifecycleObsolete {
+		t.Fatalf("expected the table to make the board obsolete, got %s", kit062.Lifecycle())
+	}
+
+	page, err := sm.ListBoards(nil)
+	if err != nil || len(page.Items) != 1 || page.Items[0] != kit149 {
+		t.Fatalf("expected obsolete boards to be left out by default, got %+v, %v", page, err)
+	}
+	filter, _ := ParseFilter("lifecycle=obsolete")
+	if page, _ := sm.ListBoards(&ListOptions{Filter: filter}); len(page.Items) != 1 || page.Items[0] != kit062 {
+		t.Errorf("expected the filter to list the obsolete board, got %+v", page.Items)
+	}
+	if page, _ := sm.ListBoards(&ListOptions{IncludeObsolete: true}); len(page.Items) != 2 {
+		t.Errorf("expected all boards, got %d", len(page.Items))
+	}
+
+	for _, s := range FindSolutions(sm, "led", nil) {
+		if s.Board == kit062 {
+			t.Error("expected no solution with the obsolete board")
+		} else if s.Lifecycle != LifecycleNRND {
+			t.Errorf("expected the solution to say the board is NRND, got %q", s.Lifecycle)
+		}
+	}
+	if len(FindSolutions(sm, "led", &SolutionOptions{IncludeObsolete: true})) != 2 {
+		t.Error("expected the obsolete board when asked for")
+	}
+
+	groups := BoardsByLifecycle(sm)
+	if len(groups[LifecycleActive]) != 0 || len(groups[LifecycleNRND]) != 1 || len(groups[LifecycleObsolete]) != 1 {
+		t.Errorf("unexpected groups %v", groups)
+	}
+
+	lf := &Lockfile{Entries: []*LockEntry{
+		{ID: "CY8CKIT-149", Kind: ItemKindBoard},
+		{ID: "mtb-example-hal-hello-world", Kind: ItemKindApp},
+	}}
+	issues := LockfileLifecycleIssues(sm, lf)
+	if len(issues) != 1 || issues[0].String() != "CY8CKIT-149: board is not recommended for new designs" {
+		t.Errorf("unexpected issues %v", issues)
+	}
+}
+
+func TestBoardLifecyclesPerTree(t *testing.T) {
+	defer SetBoardLifecycles(nil)
+	srv := newTestTreeServer(t)
+	ingest := func(opts ...IngestOption) *Board {
+		sm, err := NewSuperManifestFromURL(srv.URL+"/super.xml",
+			append(opts, WithCacheDir(t.TempDir()), WithIngestHistory(nil))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+		return b
+	}
+	prod := ingest()
+	beta := ingest(WithBoardLifecycles(map[string]Lifecycle{"CY8CKIT-062S2-43012": LifecycleNRND}))
+	SetBoardLifecycles(map[string]Lifecycle{"CY8CKIT-062S2-43012": LifecycleObsolete})
+	if l := prod.Lifecycle(); l != LifecycleObsolete {
+		t.Errorf("expected the process-wide table for the production tree, got %s", l)
+	}
+	if l := beta.Lifecycle(); l != LifecycleNRND {
+		t.Errorf("expected the table of the beta tree, got %s", l)
+	}
+}
+
+func TestLifecycleTagAndParsing(t *testing.T) {
+	boards, err := ReadBoardManifest([]byte(`<boards><board><id>KIT-OLD</id><name>Old Kit</name>
+  <lifecycle>End of Life</lifecycle></board></boards>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := boards.Boards[0].Lifecycle(); l != LifecycleObsolete {
+		t.Errorf("expected the lifecycle tag to be read, got %s", l)
+	}
+
+	table, err := ReadBoardLifecycles([]byte(`{"KIT-A": "NRND", "KIT-B": "discontinued"}`))
+	if err != nil || table["KIT-A"] != LifecycleNRND || table["KIT-B"] != LifecycleObsolete {
+		t.Errorf("unexpected table %v, %v", table, err)
+	}
+	if _, err := ReadBoardLifecycles([]byte(`{"KIT-A": "sold out"}`)); err == nil {
+		t.Error("expected an unknown status to be rejected")
+	}
+}
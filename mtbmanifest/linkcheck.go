@@ -0,0 +1,185 @@
+package mtbmanifest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// URI health checks
+// ////////////////////////////////////////////////////////////////////////
+
+// LinkProblem classifies what is wrong with a link
+type LinkProblem string
+
+const (
+	LinkOK       LinkProblem = ""
+	LinkDead     LinkProblem = "dead"     // the server answered with an error status
+	LinkRedirect LinkProblem = "redirect" // the link works but points elsewhere now
+	LinkTLS      LinkProblem = "tls"      // certificate or handshake failure
+	LinkError    LinkProblem = "error"    // DNS, connection or timeout failure
+)
+
+// LinkStatus is the result of checking one URL
+type LinkStatus struct {
+	URL string `json:"url"`
+	// Referrers are the items and manifests the URL appears in, e.g. "board CY8CKIT-149"
+	Referrers  []string    `json:"referrers"`
+	StatusCode int         `json:"statusCode,omitempty"`
+	FinalURL   string      `json:"finalUrl,omitempty"`
+	Problem    LinkProblem `json:"problem,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// LinkCheckOptions controls CheckURIs
+type LinkCheckOptions struct {
+	// MaxConcurrent is the number of requests in flight at once. Default 8.
+	MaxConcurrent int
+	// RatePerSecond caps how many requests are started per second. Default 10.
+	RatePerSecond int
+	// Timeout is the limit for one request. Default 15s.
+	Timeout time.Duration
+	// IncludeManifests also checks the manifest files themselves
+	IncludeManifests bool
+	// Client defaults to a client with Timeout that follows redirects
+	Client *http.Client
+}
+
+// collectLinks returns every URL of the tree with the places it appears in
+func collectLinks(sm SuperManifestIF, includeManifests bool) map[string][]string {
+	links := make(map[string][]string)
+	add := func(urlStr, referrer string) {
+		if urlStr != "" {
+			links[urlStr] = append(links[urlStr], referrer)
+		}
+	}
+	for _, id := range sm.GetBoardIDs() {
+		if b, ok := sm.GetBoard(id); ok {
+			add(b.BoardURI, "board "+b.ID)
+			add(b.DocumentationURL, "board "+b.ID)
+		}
+	}
+	for _, id := range sm.GetAppIDs() {
+		if a, ok := sm.GetApp(id); ok {
+			add(a.URI, "app "+a.ID)
+		}
+	}
+	for _, id := range sm.GetMiddlewareIDs() {
+		if mw, ok := sm.GetMiddleware(id); ok {
+			add(mw.URI, "middleware "+mw.ID)
+		}
+	}
+	if includeManifests {
+		for _, ref := range sm.ReferencedURLs() {
+			add(ref.URL, string(ref.Kind)+" manifest")
+		}
+	}
+	return links
+}
+
+// classifyLinkError tells certificate and handshake failures from other network errors
+func classifyLinkError(err error) LinkProblem {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+	var certVerification *tls.CertificateVerificationError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) || errors.As(err, &hostname) ||
+		errors.As(err, &recordHeader) || errors.As(err, &certVerification) {
+		return LinkTLS
+	}
+	return LinkError
+}
+
+// checkLink checks one URL with HEAD, falling back to GET for servers that do not allow HEAD
+func checkLink(ctx context.Context, client *http.Client, status *LinkStatus) {
+	var resp *http.Response
+	var err error
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, method, status.URL, nil); err != nil {
+			break
+		}
+		if resp, err = client.Do(req); err != nil {
+			break
+		}
+		_ = resp.Body.Close()
+		if method == http.MethodHead && (resp.StatusCode == http.StatusMethodNotAllowed ||
+			resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotImplemented) {
+			continue
+		}
+		break
+	}
+	if err != nil {
+		status.Problem = classifyLinkError(err)
+		status.Error = err.Error()
+		return
+	}
+	status.StatusCode = resp.StatusCode
+	if final := resp.Request.URL.String(); final != status.URL {
+		status.FinalURL = final
+	}
+	switch {
+	case resp.StatusCode >= 400:
+		status.Problem = LinkDead
+	case status.FinalURL != "":
+		status.Problem = LinkRedirect
+	}
+}
+
+// CheckURIs checks every uri, board_uri and documentation_url of the tree (and the manifest
+// files with IncludeManifests) and returns one status per distinct URL, ordered by URL.
+// Requests are spread out to respect RatePerSecond.
+func CheckURIs(sm SuperManifestIF, opts *LinkCheckOptions) []*LinkStatus {
+	if opts == nil {
+		opts = &LinkCheckOptions{}
+	}
+	maxConcurrent := opts.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = 8
+	}
+	rate := opts.RatePerSecond
+	if rate <= 0 {
+		rate = 10
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: timeout}
+	}
+
+	links := collectLinks(sm, opts.IncludeManifests)
+	ret := make([]*LinkStatus, 0, len(links))
+	for urlStr, referrers := range links {
+		ret = append(ret, &LinkStatus{URL: urlStr, Referrers: referrers})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].URL < ret[j].URL })
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, maxConcurrent)
+	for _, status := range ret {
+		<-ticker.C
+		limiter <- struct{}{}
+		wg.Add(1)
+		go func(status *LinkStatus) {
+			defer wg.Done()
+			defer func() { <-limiter }()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			checkLink(ctx, client, status)
+		}(status)
+	}
+	wg.Wait()
+	return ret
+}
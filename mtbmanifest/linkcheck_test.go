@@ -0,0 +1,67 @@
+package mtbmanifest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckURIs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(mux)
+	defer tlsServer.Close()
+
+	sm := newTestSuperManifest(t)
+	b1, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	b1.BoardURI = server.URL + "/ok"
+	b1.DocumentationURL = server.URL + "/gone"
+	b2, _ := sm.GetBoard("CY8CKIT-149")
+	b2.BoardURI = server.URL + "/moved"
+	b2.DocumentationURL = tlsServer.URL + "/ok"
+	for _, id := range sm.GetAppIDs() {
+		a, _ := sm.GetApp(id)
+		a.URI = server.URL + "/no-head"
+	}
+	for _, id := range sm.GetMiddlewareIDs() {
+		mw, _ := sm.GetMiddleware(id)
+		mw.URI = server.URL + "/ok"
+	}
+
+	statuses := CheckURIs(sm, &LinkCheckOptions{RatePerSecond: 1000})
+	byURL := make(map[string]*LinkStatus)
+	for _, s := range statuses {
+		byURL[s.URL] = s
+	}
+	if len(statuses) != 5 {
+		t.Fatalf("expected 5 distinct URLs, got %d", len(statuses))
+	}
+	tests := map[string]LinkProblem{
+		server.URL + "/ok":      LinkOK,
+		server.URL + "/gone":    LinkDead,
+		server.URL + "/moved":   LinkRedirect,
+		server.URL + "/no-head": LinkOK,
+		tlsServer.URL + "/ok":   LinkTLS,
+	}
+	for u, want := range tests {
+		if s := byURL[u]; s == nil || s.Problem != want {
+			t.Errorf("%s: expected problem %q, got %+v", u, want, s)
+		}
+	}
+	if s := byURL[server.URL+"/no-head"]; len(s.Referrers) != 3 {
+		t.Errorf("expected the three apps as referrers, got %v", s.Referrers)
+	}
+	if s := byURL[server.URL+"/moved"]; s.FinalURL != server.URL+"/ok" {
+		t.Errorf("expected the redirect target, got %q", s.FinalURL)
+	}
+}
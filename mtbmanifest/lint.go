@@ -0,0 +1,284 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Lint findings for CI
+// ////////////////////////////////////////////////////////////////////////
+
+// Pull requests to a manifest repository are best reviewed with the problems shown on the
+// offending lines. Findings turns what ScanDir found (files that do not parse, URLs with no
+// file, and the data quality issues of ScoreManifests) into findings located at a file and
+// line, and these are written as GitHub Actions workflow commands, which become annotations
+// on the pull request, or as SARIF 2.1.0 for code scanning tools. Lines are found by looking
+// for the item's <id> and the offending text in the file, so they point at the right item
+// even where the parser does not keep positions.
+
+// LintSeverity is how bad a finding is
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintNotice  LintSeverity = "notice"
+)
+
+// Rules of findings besides the QualityIssueKinds
+const (
+	LintRuleParseError    = "parse-error"    // a manifest file does not parse
+	LintRuleUnresolvedURL = "unresolved-url" // a super manifest references a URL with no file
+)
+
+// lintRules describe the rules, for SARIF
+var lintRules = map[string]string{
+	LintRuleParseError:              "The manifest file does not parse",
+	LintRuleUnresolvedURL:           "The super manifest references a URL with no file in the directory",
+	string(IssueMissingDescription): "The item has no description",
+	string(IssueBadVersion):         "A version commit has no version number",
+	string(IssueUnknownCapability):  "A capability token that no capabilities manifest defines",
+	string(IssueDeadLink):           "A link is dead or unreachable",
+	string(IssueSurprise):           "A tag or attribute the parser does not know",
+}
+
+// lintSeverities are the severities of the rules; missing ones are warnings
+var lintSeverities = map[string]LintSeverity{
+	LintRuleParseError:              LintError,
+	string(IssueMissingDescription): LintNotice,
+}
+
+// LintFinding is one problem at a place in a manifest file
+type LintFinding struct {
+	// File is the path of the file, the scanned directory joined with the path in it
+	File string `json:"file"`
+	// Line is 1-based; 0 when unknown
+	Line     int          `json:"line,omitempty"`
+	Severity LintSeverity `json:"severity"`
+	Rule     string       `json:"rule"`
+	Message  string       `json:"message"`
+}
+
+func (f *LintFinding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s [%s]", f.File, f.Severity, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s [%s]", f.File, f.Line, f.Severity, f.Message, f.Rule)
+}
+
+// HasErrors reports whether any finding is an error
+func HasErrors(findings []*LintFinding) bool {
+	return slices.ContainsFunc(findings, func(f *LintFinding) bool { return f.Severity == LintError })
+}
+
+// Findings returns the problems of the scanned files: parse errors, unresolved URLs and the
+// quality issues of the assembled tree, file by file in scan order
+func (s *DirScan) Findings() []*LintFinding {
+	byFile := map[*ScannedFile][]*LintFinding{}
+	add := func(f *ScannedFile, line int, rule, message string) {
+		severity, ok := lintSeverities[rule]
+		if !ok {
+			severity = LintWarning
+		}
+		byFile[f] = append(byFile[f], &LintFinding{File: filepath.ToSlash(filepath.Join(s.Dir, f.Path)),
+			Line: line, Severity: severity, Rule: rule, Message: message})
+	}
+
+	for _, f := range s.Files {
+		if f.err != nil {
+			add(f, errorLine(f.data, f.err), LintRuleParseError, f.Error)
+			continue
+		}
+		if f.Kind != KindSuper {
+			continue
+		}
+		for _, ref := range referencedBy(f.m.Super) {
+			if slices.Contains(s.Unresolved, ref) {
+				add(f, lineOf(f.data, 0, ref), LintRuleUnresolvedURL, fmt.Sprintf("no file in the directory for %s", ref))
+			}
+		}
+	}
+
+	if s.Tree != nil {
+		for _, source := range ScoreManifests(s.Tree, nil).Sources {
+			f := s.byURL[source.URL]
+			if f == nil {
+				continue
+			}
+			for _, issue := range source.Issues {
+				line := lineOf(f.data, 0, "<id>"+issue.ItemID+"</id>")
+				if detail := issueNeedle(issue); detail != "" && line > 0 {
+					if l := lineOf(f.data, lineOffset(f.data, line), detail); l > 0 {
+						line = l
+					}
+				}
+				add(f, line, string(issue.Kind), issue.String())
+			}
+		}
+	}
+
+	ret := []*LintFinding{}
+	for _, f := range s.Files {
+		findings := byFile[f]
+		slices.SortStableFunc(findings, func(a, b *LintFinding) int { return a.Line - b.Line })
+		ret = append(ret, findings...)
+	}
+	return ret
+}
+
+// issueNeedle returns the text of the file an issue is about, if it names one
+func issueNeedle(issue *QualityIssue) string {
+	switch issue.Kind {
+	case IssueBadVersion, IssueUnknownCapability:
+		return issue.Detail
+	case IssueDeadLink:
+		link, _, _ := strings.Cut(issue.Detail, " ")
+		return link
+	}
+	return ""
+}
+
+// lineOf returns the line of the first needle at or after offset, or 0
+func lineOf(data []byte, offset int, needle string) int {
+	i := bytes.Index(data[offset:], []byte(needle))
+	if i < 0 {
+		return 0
+	}
+	return bytes.Count(data[:offset+i], []byte("\n")) + 1
+}
+
+// lineOffset returns the offset of the start of a 1-based line
+func lineOffset(data []byte, line int) int {
+	offset := 0
+	for ; line > 1; line-- {
+		i := bytes.IndexByte(data[offset:], '\n')
+		if i < 0 {
+			return len(data)
+		}
+		offset += i + 1
+	}
+	return offset
+}
+
+// errorLine returns the line a parse error is at, or 0
+func errorLine(data []byte, err error) int {
+	var xmlErr *xml.SyntaxError
+	var jsonErr *json.SyntaxError
+	switch {
+	case errors.As(err, &xmlErr):
+		return xmlErr.Line
+	case errors.As(err, &jsonErr):
+		return bytes.Count(data[:min(int(jsonErr.Offset), len(data))], []byte("\n")) + 1
+	}
+	return 0
+}
+
+// WriteGitHubAnnotations writes findings as GitHub Actions workflow commands, which the
+// Actions runner turns into annotations on the lines of the pull request
+func WriteGitHubAnnotations(w io.Writer, findings []*LintFinding) error {
+	for _, f := range findings {
+		props := "file=" + escapeGitHubProperty(f.File)
+		if f.Line > 0 {
+			props += fmt.Sprintf(",line=%d", f.Line)
+		}
+		props += ",title=" + escapeGitHubProperty(f.Rule)
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", f.Severity, props, escapeGitHubData(f.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeGitHubData escapes the message of a workflow command
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// SARIF 2.1.0, as much of it as findings need
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+// WriteSARIF writes findings as a SARIF 2.1.0 log, e.g. for GitHub code scanning
+func WriteSARIF(w io.Writer, findings []*LintFinding) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{Name: "gomtb-manifest",
+			InformationURI: "https://github.com/haneefdm/gomtb-manifest", Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	for _, f := range findings {
+		if !slices.ContainsFunc(run.Tool.Driver.Rules, func(r sarifRule) bool { return r.ID == f.Rule }) {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules,
+				sarifRule{ID: f.Rule, ShortDescription: sarifMessage{Text: lintRules[f.Rule]}})
+		}
+		level := string(f.Severity)
+		if f.Severity == LintNotice {
+			level = "note"
+		}
+		loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.File}}
+		if f.Line > 0 {
+			loc.Region = &sarifRegion{StartLine: f.Line}
+		}
+		run.Results = append(run.Results, sarifResult{RuleID: f.Rule, Level: level, Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: loc}}})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: "2.1.0", Schema: "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{run}})
+}
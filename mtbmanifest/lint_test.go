@@ -0,0 +1,106 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lintBoardsXML = `<boards>
+  <board>
+    <id>KIT-A</id>
+    <name>Kit A</name>
+    <description>A kit.</description>
+    <versions>
+      <version><num>1.0.0 release</num><commit>release-v1.0.0</commit></version>
+      <version><num>nightly</num><commit>nightly</commit></version>
+    </versions>
+  </board>
+  <board>
+    <id>KIT-B</id>
+    <name>Kit B</name>
+  </board>
+</boards>
+`
+
+func TestDirScanFindings(t *testing.T) {
+	dir := t.TempDir()
+	writeScanFiles(t, dir, map[string]string{
+		"super-manifest.xml": strings.ReplaceAll(testSuperXML, "><", ">\n<"),
+		"boards.xml":         lintBoardsXML,
+		"apps.xml":           testAppsXML,
+		"draft/broken.xml":   "<boards>\n  <board>\n    <id>KIT-C</id>\n  </boardz>\n</boards>\n",
+	})
+	scan, err := ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := scan.Findings()
+	want := []string{
+		filepath.ToSlash(filepath.Join(dir, "boards.xml")) + ":8: warning: KIT-A: bad-version (nightly) [bad-version]",
+		filepath.ToSlash(filepath.Join(dir, "boards.xml")) + ":12: notice: KIT-B: missing-description [missing-description]",
+		filepath.ToSlash(filepath.Join(dir, "draft/broken.xml")) + ":4: error: ",
+		filepath.ToSlash(filepath.Join(dir, "super-manifest.xml")) + ":14: warning: no file in the directory for https://example.com/middleware.xml [unresolved-url]",
+	}
+	got := []string{}
+	for _, f := range findings {
+		if f.Rule != string(IssueUnknownCapability) && !strings.Contains(f.File, "apps.xml") {
+			got = append(got, f.String())
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d findings, got\n%s", len(want), strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("finding %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+	if !HasErrors(findings) {
+		t.Error("expected the parse error to count as an error")
+	}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteGitHubAnnotations(&buf, []*LintFinding{
+		{File: "boards.xml", Line: 12, Severity: LintNotice, Rule: "missing-description", Message: "KIT-B: 100% bare\nreally"},
+		{File: "a,b.xml", Severity: LintError, Rule: LintRuleParseError, Message: "bad"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "::notice file=boards.xml,line=12,title=missing-description::KIT-B: 100%25 bare%0Areally\n" +
+		"::error file=a%2Cb.xml,title=parse-error::bad\n"
+	if buf.String() != want {
+		t.Errorf("unexpected annotations\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSARIF(&buf, []*LintFinding{
+		{File: "boards.xml", Line: 12, Severity: LintNotice, Rule: "missing-description", Message: "KIT-B"},
+		{File: "boards.xml", Line: 14, Severity: LintNotice, Rule: "missing-description", Message: "KIT-C"},
+		{File: "broken.xml", Severity: LintError, Rule: LintRuleParseError, Message: "bad"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	if log.Version != "2.1.0" || len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 3 {
+		t.Fatalf("unexpected log %+v", log)
+	}
+	if r := run.Results[0]; r.Level != "note" || r.Locations[0].PhysicalLocation.Region.StartLine != 12 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if r := run.Results[2]; r.Level != "error" || r.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
@@ -0,0 +1,281 @@
+package mtbmanifest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortKey selects the order of List results. IDs, names and categories are compared with
+// CompareNames.
+type SortKey string
+
+const (
+	SortByManifest      SortKey = ""           // order of appearance in the manifests
+	SortByID            SortKey = "id"         // case-insensitive ID
+	SortByName          SortKey = "name"       // case-insensitive display name
+	SortByCategory      SortKey = "category"   // category, then name
+	SortByNewestVersion SortKey = "version"    // newest listed version, then ID
+	SortByDifficulty    SortKey = "difficulty" // apps from beginner to advanced, then name
+)
+
+// Manifests carry no release dates, so "newest" is judged by the version numbers in the
+// version commits (see CompareStrict).
+
+// ListOptions controls filtering, ordering and pagination of the List methods
+type ListOptions struct {
+	SortBy     SortKey
+	Descending bool
+
+	// Offset and Limit select a page. Limit <= 0 means everything from Offset on.
+	Offset int
+	Limit  int
+	// Cursor, when set, is the NextCursor of a previous page and takes precedence over Offset
+	Cursor string
+
+	// Category keeps only items in this category (case-insensitive)
+	Category string
+	// Query keeps only items whose ID or name contains this text (case-insensitive)
+	Query string
+	// Keywords keeps only apps carrying all of these keywords. Ignored for boards and middleware.
+	Keywords []string
+	// Filter keeps only items matching a filter expression (see ParseFilter)
+	Filter *ItemFilter
+	// IncludeObsolete also lists obsolete boards (see Board.Lifecycle). A filter with a
+	// lifecycle term lists them too.
+	IncludeObsolete bool
+}
+
+// skipsBoard reports whether the options leave a board out
+func (opts *ListOptions) skipsBoard(b *Board) bool {
+	if opts == nil {
+		return b.Lifecycle() == LifecycleObsolete
+	}
+	if opts.Filter != nil {
+		if !opts.Filter.MatchBoard(b) {
+			return true
+		}
+		if opts.Filter.hasKey("lifecycle") {
+			return false
+		}
+	}
+	return !opts.IncludeObsolete && b.Lifecycle() == LifecycleObsolete
+}
+
+// ListPage is one page of List results
+type ListPage[T any] struct {
+	Items []T `json:"items"`
+	// Total is the number of items that matched the filters, across all pages
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	// NextCursor can be passed as ListOptions.Cursor to get the next page. Empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+const cursorPrefix = "offset:"
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), cursorPrefix) {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return offset, nil
+}
+
+// listEntry carries the fields List sorts and filters on, whatever the item type
+type listEntry[T any] struct {
+	item     T
+	id       string
+	name     string
+	category string
+	newest   *SemanticVersion
+	// difficulty ranks apps from beginner up (see App.Difficulty)
+	difficulty int
+}
+
+func paginate[T any](entries []*listEntry[T], opts *ListOptions) (*ListPage[T], error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	filtered := entries[:0]
+	query := strings.ToLower(opts.Query)
+	for _, e := range entries {
+		if opts.Category != "" && !strings.EqualFold(e.category, opts.Category) {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(e.id), query) &&
+			!strings.Contains(strings.ToLower(e.name), query) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+
+	less := func(a, b *listEntry[T]) int {
+		switch opts.SortBy {
+		case SortByID:
+			return CompareNames(a.id, b.id)
+		case SortByName:
+			if c := CompareNames(a.name, b.name); c != 0 {
+				return c
+			}
+		case SortByCategory:
+			if c := CompareNames(a.category, b.category); c != 0 {
+				return c
+			}
+			if c := CompareNames(a.name, b.name); c != 0 {
+				return c
+			}
+		case SortByNewestVersion:
+			if c := CompareStrict(a.newest, b.newest); c != 0 {
+				return c
+			}
+		case SortByDifficulty:
+			if c := a.difficulty - b.difficulty; c != 0 {
+				return c
+			}
+			if c := CompareNames(a.name, b.name); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.id, b.id)
+	}
+	switch opts.SortBy {
+	case SortByManifest, SortByID, SortByName, SortByCategory, SortByNewestVersion, SortByDifficulty:
+	default:
+		return nil, fmt.Errorf("unknown sort key %q", opts.SortBy)
+	}
+	if opts.SortBy == SortByManifest {
+		// Manifest order has no key to compare, only positions to reverse
+		if opts.Descending {
+			slices.Reverse(filtered)
+		}
+	} else {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			if opts.Descending {
+				return less(filtered[j], filtered[i]) < 0
+			}
+			return less(filtered[i], filtered[j]) < 0
+		})
+	}
+
+	offset := opts.Offset
+	if opts.Cursor != "" {
+		var err error
+		if offset, err = decodeCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	offset = max(0, min(offset, len(filtered)))
+	end := len(filtered)
+	if opts.Limit > 0 {
+		end = min(offset+opts.Limit, len(filtered))
+	}
+
+	page := &ListPage[T]{
+		Items:  make([]T, 0, end-offset),
+		Total:  len(filtered),
+		Offset: offset,
+	}
+	for _, e := range filtered[offset:end] {
+		page.Items = append(page.Items, e.item)
+	}
+	if end < len(filtered) {
+		page.NextCursor = encodeCursor(end)
+	}
+	return page, nil
+}
+
+// VersionCommits returns the commit (tag/branch) of every listed version of the board
+func (b *Board) VersionCommits() []string {
+	ret := []string{}
+	if b.Versions != nil {
+		for _, v := range b.Versions.Versions {
+			ret = append(ret, v.Commit)
+		}
+	}
+	return ret
+}
+
+// VersionCommits returns the commit (tag/branch) of every listed version of the app
+func (a *App) VersionCommits() []string {
+	ret := []string{}
+	for _, v := range a.Versions.Version {
+		ret = append(ret, v.Commit)
+	}
+	return ret
+}
+
+// VersionCommits returns the commit (tag/branch) of every listed version of the middleware
+func (mw *MiddlewareItem) VersionCommits() []string {
+	ret := []string{}
+	if mw.Versions != nil {
+		for _, v := range mw.Versions.Version {
+			ret = append(ret, v.Commit)
+		}
+	}
+	return ret
+}
+
+// ListBoards returns a filtered, sorted page of boards
+func (sm *SuperManifest) ListBoards(opts *ListOptions) (*ListPage[*Board], error) {
+	entries := []*listEntry[*Board]{}
+	for _, id := range sm.GetBoardIDs() {
+		if b, ok := sm.GetBoard(id); ok && !opts.skipsBoard(b) {
+			entries = append(entries, &listEntry[*Board]{
+				item: b, id: b.ID, name: b.Name, category: b.Category,
+				newest: NewestVersion(b.VersionCommits()),
+			})
+		}
+	}
+	return paginate(entries, opts)
+}
+
+// ListApps returns a filtered, sorted page of code example apps
+func (sm *SuperManifest) ListApps(opts *ListOptions) (*ListPage[*App], error) {
+	var apps []*App
+	if opts != nil && len(opts.Keywords) > 0 {
+		apps = sm.GetKeywordIndex().GetAppsWithAll(opts.Keywords...)
+	} else {
+		for _, id := range sm.GetAppIDs() {
+			if a, ok := sm.GetApp(id); ok {
+				apps = append(apps, a)
+			}
+		}
+	}
+	entries := make([]*listEntry[*App], 0, len(apps))
+	for _, a := range apps {
+		if opts != nil && opts.Filter != nil && !opts.Filter.MatchApp(a) {
+			continue
+		}
+		entries = append(entries, &listEntry[*App]{
+			item: a, id: a.ID, name: a.Name, category: a.Category,
+			newest: NewestVersion(a.VersionCommits()), difficulty: a.Difficulty().rank(),
+		})
+	}
+	return paginate(entries, opts)
+}
+
+// ListMiddleware returns a filtered, sorted page of middleware items
+func (sm *SuperManifest) ListMiddleware(opts *ListOptions) (*ListPage[*MiddlewareItem], error) {
+	entries := []*listEntry[*MiddlewareItem]{}
+	for _, id := range sm.GetMiddlewareIDs() {
+		if mw, ok := sm.GetMiddleware(id); ok && (opts == nil || opts.Filter == nil || opts.Filter.MatchMiddleware(mw)) {
+			entries = append(entries, &listEntry[*MiddlewareItem]{
+				item: mw, id: mw.ID, name: mw.Name, category: mw.Category,
+				newest: NewestVersion(mw.VersionCommits()),
+			})
+		}
+	}
+	return paginate(entries, opts)
+}
@@ -0,0 +1,116 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestListApps(t *testing.T) {
+	sm := newTestSuperManifest(t)
+
+	page, err := sm.ListApps(&ListOptions{SortBy: SortByName})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"mtb-example-capsense-buttons-slider", "mtb-example-hal-hello-world", "mtb-example-wifi-tcp-client"}
+	if len(page.Items) != len(expected) {
+		t.Fatalf("expected %d apps, got %d", len(expected), len(page.Items))
+	}
+	for i := range expected {
+		if page.Items[i].ID != expected[i] {
+			t.Errorf("app %d: expected %q, got %q", i, expected[i], page.Items[i].ID)
+		}
+	}
+
+	page, err = sm.ListApps(&ListOptions{SortBy: SortByNewestVersion, Descending: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Items[0].ID != "mtb-example-hal-hello-world" {
+		t.Errorf("expected newest app first, got %q", page.Items[0].ID)
+	}
+
+	page, err = sm.ListApps(&ListOptions{Category: "wi-fi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Total != 1 || page.Items[0].ID != "mtb-example-wifi-tcp-client" {
+		t.Errorf("unexpected category filter result: %v", page.Items)
+	}
+
+	if _, err := sm.ListApps(&ListOptions{SortBy: "bogus"}); err == nil {
+		t.Error("expected error for unknown sort key")
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	sm := newTestSuperManifest(t)
+
+	opts := &ListOptions{SortBy: SortByID, Limit: 2}
+	page, err := sm.ListApps(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Items) != 2 || page.Total != 3 || page.NextCursor == "" {
+		t.Fatalf("unexpected first page: %d items, total %d, cursor %q", len(page.Items), page.Total, page.NextCursor)
+	}
+
+	opts.Cursor = page.NextCursor
+	page, err = sm.ListApps(opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Items) != 1 || page.Offset != 2 || page.NextCursor != "" {
+		t.Errorf("unexpected second page: %d items, offset %d, cursor %q", len(page.Items), page.Offset, page.NextCursor)
+	}
+
+	if _, err := sm.ListBoards(&ListOptions{Cursor: "not-a-cursor"}); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
+
+func TestListManifestOrderDescending(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	ids := func(opts *ListOptions) []string {
+		page, err := sm.ListApps(opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out := []string{}
+		for _, a := range page.Items {
+			out = append(out, a.ID)
+		}
+		return out
+	}
+	ascending := ids(&ListOptions{})
+	descending := ids(&ListOptions{Descending: true})
+	if len(ascending) < 2 {
+		t.Fatalf("expected several apps, got %v", ascending)
+	}
+	slices.Reverse(ascending)
+	if !slices.Equal(ascending, descending) {
+		t.Errorf("expected descending manifest order %v, got %v", ascending, descending)
+	}
+	if first := ids(&ListOptions{Descending: true, Limit: 1}); len(first) != 1 || first[0] != descending[0] {
+		t.Errorf("expected first page %v, got %v", descending[:1], first)
+	}
+}
+
+func TestCompareStrict(t *testing.T) {
+	parse := func(s string) *SemanticVersion {
+		v, err := ParseVersion(s)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", s, err)
+		}
+		return v
+	}
+	if CompareStrict(parse("latest-v4.X"), parse("release-v4.1.0")) <= 0 {
+		t.Error("expected latest-v4.X after release-v4.1.0")
+	}
+	if CompareStrict(parse("latest-v4.X"), parse("release-v5.0.0")) >= 0 {
+		t.Error("expected latest-v4.X before release-v5.0.0")
+	}
+	if newest := NewestVersion([]string{"release-v1.2.0", "garbage", "latest-v1.X", "release-v1.10.0"}); newest.Raw != "latest-v1.X" {
+		t.Errorf("unexpected newest version %v", newest)
+	}
+}
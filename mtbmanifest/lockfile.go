@@ -0,0 +1,150 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Asset lockfiles
+// ////////////////////////////////////////////////////////////////////////
+
+// Versions such as "latest-v4.X" are tags that are moved to each new release, and nothing
+// stops a release tag from being moved either. A lockfile records the commit SHA every asset
+// version resolved to, so a build can fetch exactly those commits (see GitSource.SHA) and a
+// later VerifyLockfile can tell whether a tag has been moved since, which for a release tag
+// is a supply chain red flag.
+
+// LockfileFormatVersion is the version of the lockfile layout written by WriteLockfile
+const LockfileFormatVersion = 1
+
+// LockEntry is the resolved commit of one asset version
+type LockEntry struct {
+	ID   string   `json:"id"`
+	Kind ItemKind `json:"kind"`
+	Repo string   `json:"repo"`
+	Ref  string   `json:"ref"`
+	// RefKind tells whether Ref is expected to move (see CommitRef.Moves)
+	RefKind    CommitRefKind `json:"refKind,omitempty"`
+	SHA        string        `json:"sha"`
+	ResolvedAt time.Time     `json:"resolvedAt"`
+}
+
+// Source returns the git source of the entry, pinned to its SHA
+func (e *LockEntry) Source() *GitSource {
+	return &GitSource{Repo: e.Repo, Ref: e.Ref, SHA: e.SHA}
+}
+
+// Lockfile is a set of resolved asset versions
+type Lockfile struct {
+	Format  int          `json:"format"`
+	Entries []*LockEntry `json:"entries"`
+}
+
+// GetEntry returns the entry for an item, case-insensitively if IDs are (see
+// EnableCaseInsensitiveIDs)
+func (lf *Lockfile) GetEntry(id string) (*LockEntry, bool) {
+	for _, e := range lf.Entries {
+		if SameID(e.ID, id) {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// ReadLockfile reads a lockfile written by WriteLockfile
+func ReadLockfile(r io.Reader) (*Lockfile, error) {
+	lf := &Lockfile{}
+	if err := json.NewDecoder(r).Decode(lf); err != nil {
+		return nil, fmt.Errorf("corrupt lockfile: %v", err)
+	}
+	if lf.Format > LockfileFormatVersion {
+		return nil, fmt.Errorf("unsupported lockfile format %d", lf.Format)
+	}
+	return lf, nil
+}
+
+// WriteLockfile writes a lockfile as indented JSON
+func WriteLockfile(w io.Writer, lf *Lockfile) error {
+	lf.Format = LockfileFormatVersion
+	data, err := json.MarshalIndent(lf, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// LockAssets resolves assets to commit SHAs. Each spec is an item ID, optionally followed by
+// @ and one of its version commits (e.g. freertos@latest-v10.X); without a version the newest
+// listed one is used. A nil fetcher means NewGitFetcher().
+func LockAssets(sm SuperManifestIF, specs []string, g *GitFetcher) (*Lockfile, error) {
+	if g == nil {
+		g = NewGitFetcher()
+	}
+	lf := &Lockfile{Format: LockfileFormatVersion, Entries: []*LockEntry{}}
+	for _, spec := range specs {
+		id, version, _ := strings.Cut(spec, "@")
+		src, err := AssetSource(sm, id, version)
+		if err != nil {
+			return nil, err
+		}
+		sha, err := g.ResolveSHA(src.Repo, src.Ref)
+		if err != nil {
+			return nil, err
+		}
+		lf.Entries = append(lf.Entries, &LockEntry{
+			ID:         id,
+			Kind:       assetKind(sm, id),
+			Repo:       src.Repo,
+			Ref:        src.Ref,
+			RefKind:    ParseCommitRef(src.Ref).Kind,
+			SHA:        sha,
+			ResolvedAt: time.Now().UTC(),
+		})
+	}
+	return lf, nil
+}
+
+func assetKind(sm SuperManifestIF, id string) ItemKind {
+	if _, ok := sm.GetBoard(id); ok {
+		return ItemKindBoard
+	}
+	if _, ok := sm.GetApp(id); ok {
+		return ItemKindApp
+	}
+	return ItemKindMiddleware
+}
+
+// LockVerification is the result of checking one lockfile entry
+type LockVerification struct {
+	Entry *LockEntry `json:"entry"`
+	// CurrentSHA is the commit the ref resolves to now
+	CurrentSHA string `json:"currentSha,omitempty"`
+	// Moved is set when the ref no longer resolves to the locked commit
+	Moved bool   `json:"moved"`
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyLockfile resolves every ref of a lockfile again and reports which ones moved. A nil
+// fetcher means NewGitFetcher().
+func VerifyLockfile(lf *Lockfile, g *GitFetcher) []*LockVerification {
+	if g == nil {
+		g = NewGitFetcher()
+	}
+	ret := make([]*LockVerification, 0, len(lf.Entries))
+	for _, e := range lf.Entries {
+		v := &LockVerification{Entry: e}
+		if sha, err := g.ResolveSHA(e.Repo, e.Ref); err != nil {
+			v.Error = err.Error()
+		} else {
+			v.CurrentSHA = sha
+			v.Moved = !strings.EqualFold(sha, e.SHA)
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
@@ -0,0 +1,66 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLockfile(t *testing.T) {
+	repo := newTestGitRepo(t)
+	runGit(t, repo, "tag", "latest-v10.X")
+	sha1 := runGit(t, repo, "rev-parse", "latest-v10.X")
+	sm := newTestSuperManifest(t)
+	mw, _ := sm.GetMiddleware("freertos")
+	mw.URI = "file://" + repo
+	g := NewGitFetcher(WithGitCacheDir(t.TempDir()))
+
+	lf, err := LockAssets(sm, []string{"freertos@latest-v10.X"}, g)
+	if err != nil {
+		t.Fatalf("LockAssets failed: %v", err)
+	}
+	if len(lf.Entries) != 1 || lf.Entries[0].SHA != sha1 || lf.Entries[0].Kind != ItemKindMiddleware {
+		t.Fatalf("unexpected lockfile %+v", lf.Entries[0])
+	}
+	var buf bytes.Buffer
+	if err := WriteLockfile(&buf, lf); err != nil {
+		t.Fatal(err)
+	}
+	lf, err = ReadLockfile(&buf)
+	if err != nil {
+		t.Fatalf("ReadLockfile failed: %v", err)
+	}
+	if e, ok := lf.GetEntry("freertos"); !ok || e.Source().SHA != sha1 {
+		t.Errorf("expected the freertos entry, got %+v", e)
+	}
+
+	if v := VerifyLockfile(lf, g); len(v) != 1 || v[0].Moved || v[0].Error != "" {
+		t.Errorf("expected the lock to verify, got %+v", v[0])
+	}
+	writeAndTag(t, repo, "a new release", "latest-v10.X")
+	if v := VerifyLockfile(lf, g); v[0].Moved != true || v[0].CurrentSHA == sha1 {
+		t.Errorf("expected the moved tag to be detected, got %+v", v[0])
+	}
+
+	if _, err := LockAssets(sm, []string{"freertos@latest-v9.X"}, g); err == nil {
+		t.Error("expected an error for an unlisted version")
+	}
+}
+
+func TestResolveSHAGitHubAPI(t *testing.T) {
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/Infineon/freertos/commits/latest-v10.X" || r.Header.Get("Accept") != "application/vnd.github.sha" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(sha))
+	}))
+	defer server.Close()
+	g := NewGitFetcher(WithGitBinary(""))
+	g.apiBase = server.URL
+	if got, err := g.ResolveSHA("https://github.com/Infineon/freertos", "latest-v10.X"); err != nil || got != sha {
+		t.Errorf("ResolveSHA = %s, %v", got, err)
+	}
+}
@@ -0,0 +1,157 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Manifest format revisions
+// ////////////////////////////////////////////////////////////////////////
+
+// Each revision of the manifest format lives on a branch of its own in the super manifest
+// repository: fv2 manifests on v2.X, and a future fv3 presumably on v3.X. SuperManifestURL
+// hard-codes the current one. A ManifestFormat names a revision and expands a URL template
+// into the URL of its super manifest, so programs can be pointed at another revision, or at a
+// mirror laid out the same way, without code changes. WithFormatNegotiation goes further:
+// it probes which revisions exist and lets a FormatNegotiator pick one, by default the newest
+// this library understands.
+
+// SuperManifestURLTemplate is where the super manifest of each format revision is published.
+// {branch}, {format} and {major} are replaced by those of a ManifestFormat.
+const SuperManifestURLTemplate = "https://github.com/Infineon/mtb-super-manifest/raw/{branch}/mtb-super-manifest-{format}.xml"
+
+// SupportedFormatMajor is the newest manifest format revision this library understands
+const SupportedFormatMajor = 2
+
+// formatLookahead is how many revisions past SupportedFormatMajor are probed for
+const formatLookahead = 2
+
+// ManifestFormat is a revision of the manifest format
+type ManifestFormat struct {
+	Major int `json:"major"`
+	// Branch is the branch of the super manifest repository, e.g. v2.X
+	Branch string `json:"branch"`
+	// Format is the suffix of the super manifest file name, e.g. fv2
+	Format string `json:"format"`
+}
+
+// FormatFor returns the format revision with the given major number, on the branch and with
+// the file name the repository uses for it
+func FormatFor(major int) ManifestFormat {
+	return ManifestFormat{Major: major, Branch: fmt.Sprintf("v%d.X", major), Format: fmt.Sprintf("fv%d", major)}
+}
+
+// DefaultFormat returns the format revision SuperManifestURL is in
+func DefaultFormat() ManifestFormat {
+	return FormatFor(SupportedFormatMajor)
+}
+
+// ParseManifestFormat parses a format revision given as its major number, its branch or its
+// name: "3", "v3.X" and "fv3" are the same
+func ParseManifestFormat(s string) (ManifestFormat, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(t, "f"), "v"), ".x")
+	major, err := strconv.Atoi(t)
+	if err != nil || major < 1 {
+		return ManifestFormat{}, fmt.Errorf("invalid manifest format %q, expected e.g. fv2, v2.X or 2", s)
+	}
+	return FormatFor(major), nil
+}
+
+func (f ManifestFormat) String() string {
+	return f.Format
+}
+
+// URL expands a URL template for the format; an empty template means SuperManifestURLTemplate
+func (f ManifestFormat) URL(template string) string {
+	if template == "" {
+		template = SuperManifestURLTemplate
+	}
+	return strings.NewReplacer("{branch}", f.Branch, "{format}", f.Format, "{major}", strconv.Itoa(f.Major)).
+		Replace(template)
+}
+
+// FormatNegotiator picks the format revision to ingest from those offered, ordered oldest
+// first. It is never called with none.
+type FormatNegotiator func(offered []ManifestFormat) (ManifestFormat, error)
+
+// NewestSupportedFormat is the default FormatNegotiator: it picks the newest revision offered
+// that this library understands
+func NewestSupportedFormat(offered []ManifestFormat) (ManifestFormat, error) {
+	for i := len(offered) - 1; i >= 0; i-- {
+		if offered[i].Major <= SupportedFormatMajor {
+			return offered[i], nil
+		}
+	}
+	return ManifestFormat{}, fmt.Errorf("no supported manifest format offered (newest supported is fv%d)", SupportedFormatMajor)
+}
+
+// DetectFormats returns the format revisions, from the default one up to a few newer ones,
+// whose super manifest the cache holds or can fetch from the URL template
+func (c *ManifestCache) DetectFormats(template string) []ManifestFormat {
+	ret := []ManifestFormat{}
+	for major := SupportedFormatMajor; major <= SupportedFormatMajor+formatLookahead; major++ {
+		f := FormatFor(major)
+		if _, err := c.Get(f.URL(template)); err == nil {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// WithFormat ingests the super manifest of the given format revision when no URL is given
+func WithFormat(f ManifestFormat) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.format = &f
+	}
+}
+
+// WithURLTemplate expands the given template instead of SuperManifestURLTemplate for the
+// super manifest when no URL is given (see WithFormat and WithFormatNegotiation)
+func WithURLTemplate(template string) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.urlTemplate = template
+	}
+}
+
+// WithFormatNegotiation probes which format revisions are published when no URL is given and
+// ingests the one n picks; a nil n means NewestSupportedFormat. When none can be found, e.g.
+// offline with nothing cached, the revision of WithFormat or the default one is ingested.
+func WithFormatNegotiation(n FormatNegotiator) IngestOption {
+	return func(cfg *ingestConfig) {
+		if n == nil {
+			n = NewestSupportedFormat
+		}
+		cfg.negotiator = n
+	}
+}
+
+// superManifestURL returns the URL of the super manifest to ingest when none is given
+func (cfg *ingestConfig) superManifestURL(cache *ManifestCache) (string, error) {
+	if cfg.format == nil && cfg.urlTemplate == "" && cfg.negotiator == nil {
+		return SuperManifestURL, nil
+	}
+	f := DefaultFormat()
+	if cfg.format != nil {
+		f = *cfg.format
+	}
+	if cfg.negotiator != nil {
+		if offered := cache.DetectFormats(cfg.urlTemplate); len(offered) > 0 {
+			picked, err := cfg.negotiator(offered)
+			if err != nil {
+				return "", err
+			}
+			f = picked
+		}
+	}
+	return f.URL(cfg.urlTemplate), nil
+}
+
+// formatMajor returns the major number of a super manifest version such as "2.0", or 0
+func formatMajor(version string) int {
+	major, _, _ := strings.Cut(strings.TrimSpace(version), ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
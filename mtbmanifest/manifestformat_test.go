@@ -0,0 +1,70 @@
+package mtbmanifest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestFormat(t *testing.T) {
+	if u := DefaultFormat().URL(""); u != SuperManifestURL {
+		t.Fatalf("expected the default format to expand to SuperManifestURL, got %s", u)
+	}
+	for _, s := range []string{"3", "v3.X", "fv3", " FV3 "} {
+		if f, err := ParseManifestFormat(s); err != nil || f != FormatFor(3) {
+			t.Errorf("ParseManifestFormat(%q) = %+v, %v", s, f, err)
+		}
+	}
+	if _, err := ParseManifestFormat("latest"); err == nil {
+		t.Error("expected an error for a bad format")
+	}
+
+	const template = "https://example.com/{branch}/super-{format}.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	for u, data := range map[string]string{
+		FormatFor(2).URL(template):           testSuperXML,
+		FormatFor(3).URL(template):           strings.Replace(testSuperXML, `version="2.0"`, `version="3.0"`, 1),
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if offered := cache.DetectFormats(template); len(offered) != 2 || offered[1].Format != "fv3" {
+		t.Fatalf("unexpected formats %v", offered)
+	}
+
+	// The default negotiator stays on the newest format understood
+	sm, err := NewSuperManifestFromURL("", withIngestCache(cache), WithOffline(), WithURLTemplate(template),
+		WithFormatNegotiation(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src := sm.(*SuperManifest).SourceUrls[0]; src != "https://example.com/v2.X/super-fv2.xml" {
+		t.Fatalf("expected fv2, got %s", src)
+	}
+
+	// A negotiator may opt into a newer one, which is reported
+	newest := func(offered []ManifestFormat) (ManifestFormat, error) { return offered[len(offered)-1], nil }
+	sm, err = NewSuperManifestFromURL("", withIngestCache(cache), WithOffline(), WithURLTemplate(template),
+		WithFormatNegotiation(newest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := sm.(*SuperManifest).Warnings(WarnNewerFormat); len(w) != 1 || w[0].URL != "https://example.com/v3.X/super-fv3.xml" {
+		t.Fatalf("expected a newer-format warning, got %v", w)
+	}
+
+	// Without negotiation the format is taken as given
+	sm, err = NewSuperManifestFromURL("", withIngestCache(cache), WithOffline(), WithURLTemplate(template),
+		WithFormat(FormatFor(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src := sm.(*SuperManifest).SourceUrls[0]; src != "https://example.com/v3.X/super-fv3.xml" {
+		t.Fatalf("expected fv3, got %s", src)
+	}
+}
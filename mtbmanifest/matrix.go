@@ -0,0 +1,367 @@
+package mtbmanifest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Compatibility matrix
+// ////////////////////////////////////////////////////////////////////////
+
+// Which code examples and libraries run on which boards is asked of every tree over and over,
+// one pair at a time. BuildCompatibilityMatrix answers it for all pairs at once: every
+// version of every board is matched against every version of every code example, and
+// optionally of every library, by a pool of workers, one board version each. A version of a
+// board provides its own capabilities (see Board.EffectiveCapabilities), so the matrix tells
+// them apart. The matrix of a tree is kept with the tree, per options, until the tree changes.
+// A refresh changes few items of a large tree, so it does not throw the kept matrices away:
+// Update matches again only the boards and the items the ChangeSet names, and the refreshed
+// tree keeps the updated matrices.
+
+// MatrixOptions are the options of BuildCompatibilityMatrix
+type MatrixOptions struct {
+	// Workers is the number of board versions matched at once; 0 or less means GOMAXPROCS
+	Workers int
+	// Middleware also matches the libraries, not only the code examples
+	Middleware bool
+	// ToolsVersion and Flow, when set, leave out the versions these tools or this flow cannot
+	// build (see CapabilityContext)
+	ToolsVersion string
+	Flow         Flow
+}
+
+// MatrixCell is a code example or library that runs on a version of a board
+type MatrixCell struct {
+	Board string `json:"board"`
+	// BoardVersion is the commit of the version of the board; "" for a board without versions
+	BoardVersion string       `json:"boardVersion"`
+	Kind         ManifestKind `json:"kind"`
+	ID           string       `json:"id"`
+	// Versions are the commits of the versions of the item that run on the board version; ""
+	// stands for an item without versions
+	Versions []string `json:"versions"`
+}
+
+// CompatibilityMatrix is every compatible pair of a board version and a code example or
+// library, by board, then board version, code examples before libraries, then ID
+type CompatibilityMatrix struct {
+	Options MatrixOptions `json:"options"`
+	Cells   []*MatrixCell `json:"cells"`
+	// compatible has the ID pairs of the cells, board first
+	compatible map[[2]string]bool
+}
+
+// matrixMu guards the matrices kept with trees
+var matrixMu sync.Mutex
+
+// BuildCompatibilityMatrix matches every board of a tree against every code example, and
+// against every library if asked. A nil opts is the zero MatrixOptions.
+func BuildCompatibilityMatrix(sm SuperManifestIF, opts *MatrixOptions) *CompatibilityMatrix {
+	key := MatrixOptions{}
+	if opts != nil {
+		key = *opts
+	}
+	workers := key.Workers
+	key.Workers = 0 // the result does not depend on it
+	tree, keep := sm.(*SuperManifest)
+	if keep {
+		matrixMu.Lock()
+		m, ok := tree.matrices[key]
+		matrixMu.Unlock()
+		if ok {
+			return m
+		}
+	}
+
+	jobs := matrixJobs(sm)
+	apps := sm.Apps()
+	var middleware []*MiddlewareItem
+	if key.Middleware {
+		middleware = sm.Middleware()
+	}
+	m := newMatrix(key, runMatrixJobs(jobs, workers, func(j matrixJob) []*MatrixCell {
+		return matchBoardVersion(j.board, j.version, apps, middleware, &key)
+	}))
+	if keep {
+		matrixMu.Lock()
+		if tree.matrices == nil {
+			tree.matrices = map[MatrixOptions]*CompatibilityMatrix{}
+		}
+		tree.matrices[key] = m
+		matrixMu.Unlock()
+	}
+	return m
+}
+
+// matrixJob is a version of a board to match; a nil version is a board without versions
+type matrixJob struct {
+	board   *Board
+	version *BoardVersion
+}
+
+// boardVersion returns the commit of the version of the job
+func (j matrixJob) boardVersion() string {
+	if j.version == nil {
+		return ""
+	}
+	return j.version.Commit
+}
+
+func matrixJobs(sm SuperManifestIF) []matrixJob {
+	jobs := []matrixJob{}
+	for _, b := range sm.Boards() {
+		if b.Versions == nil || len(b.Versions.Versions) == 0 {
+			jobs = append(jobs, matrixJob{board: b})
+			continue
+		}
+		for _, v := range b.Versions.Versions {
+			jobs = append(jobs, matrixJob{board: b, version: v})
+		}
+	}
+	return jobs
+}
+
+// runMatrixJobs runs match on the jobs with a pool of workers and returns the cells of each
+// job, in the order of the jobs
+func runMatrixJobs(jobs []matrixJob, workers int, match func(matrixJob) []*MatrixCell) [][]*MatrixCell {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	results := make([][]*MatrixCell, len(jobs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, max(len(jobs), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ix := range next {
+				results[ix] = match(jobs[ix])
+			}
+		}()
+	}
+	for ix := range jobs {
+		next <- ix
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+func newMatrix(opts MatrixOptions, results [][]*MatrixCell) *CompatibilityMatrix {
+	m := &CompatibilityMatrix{Options: opts, Cells: []*MatrixCell{}, compatible: map[[2]string]bool{}}
+	for _, cells := range results {
+		for _, cell := range cells {
+			m.Cells = append(m.Cells, cell)
+			m.compatible[[2]string{idKey(cell.Board), idKey(cell.ID)}] = true
+		}
+	}
+	return m
+}
+
+// matchBoardVersion returns the cells of one version of a board; a nil version is the board
+// itself
+func matchBoardVersion(b *Board, v *BoardVersion, apps []*App, middleware []*MiddlewareItem, opts *MatrixOptions) []*MatrixCell {
+	ctx := NewCapabilityContext(b.EffectiveCapabilities(v))
+	ctx.ToolsVersion, ctx.Flow = opts.ToolsVersion, opts.Flow
+	boardVersion := ""
+	if v != nil {
+		if !ctx.suitsVersion(v.FlowVersion, "") {
+			return nil
+		}
+		boardVersion = v.Commit
+	}
+	cells := []*MatrixCell{}
+	for _, a := range apps {
+		versions := []string{}
+		if len(a.Versions.Version) == 0 {
+			if a.MatchesVersionContext(nil, ctx) {
+				versions = append(versions, "")
+			}
+		}
+		for _, av := range a.Versions.Version {
+			if a.MatchesVersionContext(av, ctx) {
+				versions = append(versions, av.Commit)
+			}
+		}
+		if len(versions) > 0 {
+			cells = append(cells, &MatrixCell{Board: b.ID, BoardVersion: boardVersion, Kind: KindApps, ID: a.ID, Versions: versions})
+		}
+	}
+	for _, mw := range middleware {
+		if req := mw.GetCapabilities(); len(req.Groups) > 0 && !req.MatchesContext(ctx) {
+			continue
+		}
+		versions := []string{}
+		if mw.Versions == nil || len(mw.Versions.Version) == 0 {
+			versions = append(versions, "")
+		} else {
+			for _, mv := range mw.Versions.Version {
+				if ctx.suitsVersion(mv.FlowVersion, mv.ToolsMinVersion) {
+					versions = append(versions, mv.Commit)
+				}
+			}
+		}
+		if len(versions) > 0 {
+			cells = append(cells, &MatrixCell{Board: b.ID, BoardVersion: boardVersion, Kind: KindMiddleware, ID: mw.ID, Versions: versions})
+		}
+	}
+	return cells
+}
+
+// Update returns the matrix of sm, the tree this matrix was built from after the given
+// changes: the rows of the boards and the columns of the code examples and libraries that
+// changed are matched again, and the other cells are kept. The matrix itself is not changed.
+func (m *CompatibilityMatrix) Update(sm SuperManifestIF, changes *ChangeSet) *CompatibilityMatrix {
+	if changes == nil || changes.Empty() {
+		return m
+	}
+	dirtyBoards, dirtyItems := map[string]bool{}, map[string]bool{}
+	for _, list := range [][]*ItemChange{changes.Added, changes.Removed, changes.Changed} {
+		for _, change := range list {
+			if change.Kind == ItemKindBoard {
+				dirtyBoards[idKey(change.ID)] = true
+			} else {
+				dirtyItems[matrixItemKey(change.Kind, change.ID)] = true
+			}
+		}
+	}
+	key := m.Options
+	apps, dirtyApps := sm.Apps(), []*App{}
+	for _, a := range apps {
+		if dirtyItems[matrixItemKey(ItemKindApp, a.ID)] {
+			dirtyApps = append(dirtyApps, a)
+		}
+	}
+	var middleware, dirtyMiddleware []*MiddlewareItem
+	if key.Middleware {
+		middleware = sm.Middleware()
+		for _, mw := range middleware {
+			if dirtyItems[matrixItemKey(ItemKindMiddleware, mw.ID)] {
+				dirtyMiddleware = append(dirtyMiddleware, mw)
+			}
+		}
+	}
+
+	// The cells of the boards that did not change, by board version, then item
+	kept := map[[2]string]map[string]*MatrixCell{}
+	for _, cell := range m.Cells {
+		if dirtyBoards[idKey(cell.Board)] {
+			continue
+		}
+		row := [2]string{idKey(cell.Board), cell.BoardVersion}
+		if kept[row] == nil {
+			kept[row] = map[string]*MatrixCell{}
+		}
+		kept[row][cell.itemKey()] = cell
+	}
+	return newMatrix(key, runMatrixJobs(matrixJobs(sm), 0, func(j matrixJob) []*MatrixCell {
+		if dirtyBoards[idKey(j.board.ID)] {
+			return matchBoardVersion(j.board, j.version, apps, middleware, &key)
+		}
+		row := map[string]*MatrixCell{}
+		for k, cell := range kept[[2]string{idKey(j.board.ID), j.boardVersion()}] {
+			if !dirtyItems[k] {
+				row[k] = cell
+			}
+		}
+		for _, cell := range matchBoardVersion(j.board, j.version, dirtyApps, dirtyMiddleware, &key) {
+			row[cell.itemKey()] = cell
+		}
+		// In the order a build would give
+		cells := []*MatrixCell{}
+		for _, a := range apps {
+			if cell, ok := row[matrixItemKey(ItemKindApp, a.ID)]; ok {
+				cells = append(cells, cell)
+			}
+		}
+		for _, mw := range middleware {
+			if cell, ok := row[matrixItemKey(ItemKindMiddleware, mw.ID)]; ok {
+				cells = append(cells, cell)
+			}
+		}
+		return cells
+	}))
+}
+
+// matrixItemKey identifies a code example or library in Update
+func matrixItemKey(kind ItemKind, id string) string {
+	return string(kind) + "/" + idKey(id)
+}
+
+func (c *MatrixCell) itemKey() string {
+	if c.Kind == KindApps {
+		return matrixItemKey(ItemKindApp, c.ID)
+	}
+	return matrixItemKey(ItemKindMiddleware, c.ID)
+}
+
+// updateMatrices returns the matrices kept with a tree, updated for the tree it was refreshed
+// into
+func updateMatrices(from, to *SuperManifest, changes *ChangeSet) map[MatrixOptions]*CompatibilityMatrix {
+	matrixMu.Lock()
+	kept := make(map[MatrixOptions]*CompatibilityMatrix, len(from.matrices))
+	for key, m := range from.matrices {
+		kept[key] = m
+	}
+	matrixMu.Unlock()
+	if len(kept) == 0 {
+		return nil
+	}
+	for key, m := range kept {
+		kept[key] = m.Update(to, changes)
+	}
+	return kept
+}
+
+// Compatible reports whether a code example or library runs on some version of a board
+func (m *CompatibilityMatrix) Compatible(boardID, itemID string) bool {
+	return m.compatible[[2]string{idKey(boardID), idKey(itemID)}]
+}
+
+// WriteCSV writes the matrix as CSV, one row per version of an item on a board version, after
+// a header row
+func (m *CompatibilityMatrix) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"board", "board_version", "kind", "id", "version"})
+	for _, cell := range m.Cells {
+		for _, version := range cell.Versions {
+			_ = cw.Write([]string{cell.Board, cell.BoardVersion, string(cell.Kind), cell.ID, version})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteSQL writes the matrix as an SQL script that creates and fills a compatibility table
+// with the rows of WriteCSV, e.g. for sqlite3 matrix.db < matrix.sql
+func (m *CompatibilityMatrix) WriteSQL(w io.Writer) error {
+	if _, err := fmt.Fprint(w, "BEGIN TRANSACTION;\n"+
+		"DROP TABLE IF EXISTS compatibility;\n"+
+		"CREATE TABLE compatibility (board TEXT NOT NULL, board_version TEXT NOT NULL, "+
+		"kind TEXT NOT NULL, id TEXT NOT NULL, version TEXT NOT NULL);\n"); err != nil {
+		return err
+	}
+	for _, cell := range m.Cells {
+		for _, version := range cell.Versions {
+			if _, err := fmt.Fprintf(w, "INSERT INTO compatibility VALUES (%s, %s, %s, %s, %s);\n",
+				sqlQuote(cell.Board), sqlQuote(cell.BoardVersion), sqlQuote(string(cell.Kind)),
+				sqlQuote(cell.ID), sqlQuote(version)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprint(w, "CREATE INDEX compatibility_board ON compatibility (board);\n"+
+		"CREATE INDEX compatibility_id ON compatibility (id);\n"+
+		"COMMIT;\n")
+	return err
+}
+
+// sqlQuote quotes a string as an SQL literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,89 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuildCompatibilityMatrix(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	m := BuildCompatibilityMatrix(sm, &MatrixOptions{Workers: 2})
+	if len(m.Cells) != 6 {
+		t.Fatalf("expected 6 cells, got %d", len(m.Cells))
+	}
+	first := m.Cells[0]
+	if first.Board != "CY8CKIT-062S2-43012" || first.BoardVersion != "latest-v4.X" || first.Kind != KindApps ||
+		first.ID != "mtb-example-hal-hello-world" || len(first.Versions) != 1 {
+		t.Errorf("unexpected first cell %+v", first)
+	}
+	if !m.Compatible("CY8CKIT-062S2-43012", "mtb-example-wifi-tcp-client") || m.Compatible("CY8CKIT-149", "mtb-example-wifi-tcp-client") {
+		t.Error("expected the Wi-Fi example to run on the Wi-Fi kit only")
+	}
+	if again := BuildCompatibilityMatrix(sm, &MatrixOptions{Workers: 8}); again != m {
+		t.Error("expected the matrix to be kept with the tree")
+	}
+
+	withMiddleware := BuildCompatibilityMatrix(sm, &MatrixOptions{Middleware: true})
+	if len(withMiddleware.Cells) != 11 || !withMiddleware.Compatible("CY8CKIT-149", "freertos") ||
+		withMiddleware.Compatible("CY8CKIT-149", "wifi-connection-manager") {
+		t.Errorf("unexpected matrix with middleware, %d cells", len(withMiddleware.Cells))
+	}
+	if old := BuildCompatibilityMatrix(sm, &MatrixOptions{ToolsVersion: "2.4.0"}); len(old.Cells) != 0 {
+		t.Errorf("expected no example to build with old tools, got %d cells", len(old.Cells))
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 7 || lines[0] != "board,board_version,kind,id,version" {
+		t.Errorf("unexpected CSV %q", buf.String())
+	}
+	buf.Reset()
+	if err := m.WriteSQL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "INSERT INTO compatibility"); n != 6 {
+		t.Errorf("expected 6 rows, got %d", n)
+	}
+	if got := sqlQuote("it's"); got != "'it''s'" {
+		t.Errorf("unexpected quoting %s", got)
+	}
+}
+
+func TestCompatibilityMatrixUpdate(t *testing.T) {
+	before := newTestSuperManifest(t)
+	m := BuildCompatibilityMatrix(before, &MatrixOptions{Middleware: true})
+
+	// The PSoC 4 kit gains Wi-Fi and the TCP client needs less
+	after := newTestSuperManifest(t)
+	board, _ := after.GetBoard("CY8CKIT-149")
+	board.ProvCapabilities += " wifi"
+	app, _ := after.GetApp("mtb-example-wifi-tcp-client")
+	app.ReqCapabilitiesV2 = "wifi"
+	changes := DiffTrees(before, after)
+	if len(changes.Changed) != 2 {
+		t.Fatalf("expected a board and an app to change, got %+v", changes.Changed)
+	}
+
+	updated := m.Update(after, changes)
+	if updated == m || len(m.Cells) != 11 {
+		t.Error("expected the matrix to be left as it was")
+	}
+	var got, want bytes.Buffer
+	_ = updated.WriteCSV(&got)
+	_ = BuildCompatibilityMatrix(after, &MatrixOptions{Middleware: true}).WriteCSV(&want)
+	if got.String() != want.String() {
+		t.Errorf("expected the update to match a build:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+	if updated.Cells[0] != m.Cells[0] {
+		t.Error("expected the cells of unchanged pairs to be kept")
+	}
+	if !updated.Compatible("CY8CKIT-149", "wifi-connection-manager") {
+		t.Error("expected the Wi-Fi library to run on the PSoC 4 kit now")
+	}
+	if m.Update(after, &ChangeSet{}) != m {
+		t.Error("expected no changes to keep the matrix")
+	}
+}
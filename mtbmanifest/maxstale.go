@@ -0,0 +1,76 @@
+package mtbmanifest
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Bounded staleness
+// ////////////////////////////////////////////////////////////////////////
+
+// A stale entry is served while it is refreshed in the background, and kept when refreshing
+// fails, which is right for a flaky network but wrong for a manifest host that has been gone
+// for months. WithMaxStale bounds how long past its TTL an entry is served that way. Beyond
+// that, Get fetches the URL before answering, and only if that fails does it return the old
+// content, together with a StaleDataError saying how old it is.
+
+// StaleDataError is returned by ManifestCache.Get, next to the cached content, when the content
+// is staler than the cache accepts and could not be refreshed. Callers that can live with old
+// manifests can use the content anyway:
+//
+//	data, err := cache.Get(urlStr)
+//	var stale *StaleDataError
+//	if errors.As(err, &stale) {
+//		log.Printf("using %v old %s", stale.Age, urlStr)
+//		err = nil
+//	}
+type StaleDataError struct {
+	URL string
+	// Age is the time since the content was cached, Staleness the time since it expired
+	Age       time.Duration
+	Staleness time.Duration
+	// MaxStale is the staleness the cache accepts (see WithMaxStale)
+	MaxStale time.Duration
+	// Data is the cached content, also returned by Get
+	Data []byte
+	// Err is why the content could not be refreshed
+	Err error
+}
+
+func (e *StaleDataError) Error() string {
+	return fmt.Sprintf("%s: cached %v ago, %v past its TTL (at most %v accepted), and refreshing failed: %v",
+		e.URL, e.Age.Round(time.Second), e.Staleness.Round(time.Second), e.MaxStale, e.Err)
+}
+
+func (e *StaleDataError) Unwrap() error {
+	return e.Err
+}
+
+// WithMaxStale sets how long past their TTL entries may be served without refreshing them
+// first. Zero, the default, serves stale entries however old.
+func WithMaxStale(maxStale time.Duration) CacheOption {
+	return func(c *ManifestCache) {
+		c.maxStale = maxStale
+	}
+}
+
+// MaxStale returns the staleness the cache accepts, 0 for any (see WithMaxStale)
+func (c *ManifestCache) MaxStale() time.Duration {
+	return c.maxStale
+}
+
+// getTooStale answers Get for content that has been stale for longer than the cache accepts:
+// fresh content if it can be fetched, else the old content with a StaleDataError
+func (c *ManifestCache) getTooStale(ctx context.Context, urlStr string, data []byte, age, staleness time.Duration) ([]byte, error) {
+	err := fmt.Errorf("%s: %w", urlStr, ErrOffline)
+	if !c.cacheOnly {
+		var fresh []byte
+		if fresh, err = c.fetchAndCache(ctx, urlStr); err == nil {
+			return fresh, nil
+		}
+	}
+	c.log().Warningf("Serving %s cached %v ago: %v\n", urlStr, age.Round(time.Second), err)
+	return data, &StaleDataError{URL: urlStr, Age: age, Staleness: staleness, MaxStale: c.maxStale, Data: data, Err: err}
+}
@@ -0,0 +1,56 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxStale(t *testing.T) {
+	var up atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("<fresh/>"))
+	}))
+	defer srv.Close()
+	urlStr := srv.URL + "/boards.xml"
+
+	store := NewMemoryStore()
+	cache := NewManifestCache(WithStore(store), WithCacheTTL(time.Hour), WithMaxStale(24*time.Hour))
+	defer cache.Close()
+
+	// Stale, but within what is accepted: served as is
+	_ = store.Put(urlStr, []byte("<stale/>"), time.Now().Add(-2*time.Hour))
+	if data, err := cache.Get(urlStr); err != nil || string(data) != "<stale/>" {
+		t.Errorf("expected the stale entry, got %q, %v", data, err)
+	}
+
+	// Too stale and the server is down: the old content comes with a StaleDataError
+	_ = store.Put(urlStr, []byte("<ancient/>"), time.Now().Add(-72*time.Hour))
+	data, err := cache.Get(urlStr)
+	var stale *StaleDataError
+	if !errors.As(err, &stale) || string(data) != "<ancient/>" || string(stale.Data) != "<ancient/>" {
+		t.Fatalf("expected the ancient entry with a StaleDataError, got %q, %v", data, err)
+	}
+	if stale.Staleness < 70*time.Hour || stale.MaxStale != 24*time.Hour {
+		t.Errorf("unexpected staleness %v, max %v", stale.Staleness, stale.MaxStale)
+	}
+
+	// Offline, nothing is fetched
+	offline := NewManifestCache(WithStore(store), WithCacheTTL(time.Hour), WithMaxStale(24*time.Hour), WithCacheOnly())
+	if _, err := offline.Get(urlStr); !errors.Is(err, ErrOffline) || !errors.As(err, &stale) {
+		t.Errorf("expected a StaleDataError wrapping ErrOffline, got %v", err)
+	}
+
+	// Too stale and the server is up: fetched before answering
+	up.Store(true)
+	if data, err := cache.Get(urlStr); err != nil || string(data) != "<fresh/>" {
+		t.Errorf("expected fresh content, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,207 @@
+package mtbmanifest
+
+import (
+	"fmt"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Merge previews
+// ////////////////////////////////////////////////////////////////////////
+
+// AddSuperManifest cannot be undone, and what it does to a tree is not obvious: an item whose
+// ID the other tree also lists is shadowed, lookups returning the other tree's item from then
+// on, and dependency or capability manifests of the same URL are replaced. PreviewMerge says
+// all that before anything is merged: which items are new, which are shadowed and whether they
+// differ, which shared URLs have different content, and how the counts change.
+
+// ItemCounts are the numbers of distinct board, app and middleware IDs of a tree
+type ItemCounts struct {
+	Boards     int `json:"boards"`
+	Apps       int `json:"apps"`
+	Middleware int `json:"middleware"`
+}
+
+// ShadowedItem is an item both trees list; after the merge lookups return the incoming one
+type ShadowedItem struct {
+	Kind ItemKind `json:"kind"`
+	ID   string   `json:"id"`
+	// Differs is set when the two items differ; then the versions only one of them has are
+	// listed
+	Differs         bool     `json:"differs"`
+	AddedVersions   []string `json:"addedVersions,omitempty"`
+	RemovedVersions []string `json:"removedVersions,omitempty"`
+	// From and To are the manifests of the item that is shadowed and of the one that wins
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// MergePreview is what merging one tree into another would do
+type MergePreview struct {
+	Before   ItemCounts `json:"before"`
+	Incoming ItemCounts `json:"incoming"`
+	After    ItemCounts `json:"after"`
+	// Added are the items only the incoming tree lists
+	Added []*ItemChange `json:"added"`
+	// Shadowed are the items both trees list, those that differ first
+	Shadowed []*ShadowedItem `json:"shadowed"`
+	// Conflicts are the dependency and capability manifest URLs both trees read, with
+	// different content; the incoming content replaces the other
+	Conflicts []string `json:"conflicts,omitempty"`
+	// VersionMismatch describes super manifests of different versions, if they are
+	VersionMismatch string `json:"versionMismatch,omitempty"`
+}
+
+// Differing returns the shadowed items that differ from the ones shadowing them
+func (p *MergePreview) Differing() []*ShadowedItem {
+	ret := []*ShadowedItem{}
+	for _, s := range p.Shadowed {
+		if s.Differs {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// PreviewMerge reports what merging b into a with AddSuperManifest would do, without changing
+// either
+func PreviewMerge(a, b SuperManifestIF) *MergePreview {
+	p := &MergePreview{Added: []*ItemChange{}, Shadowed: []*ShadowedItem{}}
+	cs := DiffTrees(a, b)
+	seen := make(map[ItemKind]map[string]bool)
+	for _, c := range cs.Added {
+		if seen[c.Kind] == nil {
+			seen[c.Kind] = make(map[string]bool)
+		}
+		if key := idKey(c.ID); !seen[c.Kind][key] {
+			seen[c.Kind][key] = true
+			p.Added = append(p.Added, c)
+		}
+	}
+	changed := make(map[ItemKind]map[string]*ItemChange)
+	for _, c := range cs.Changed {
+		if changed[c.Kind] == nil {
+			changed[c.Kind] = make(map[string]*ItemChange)
+		}
+		changed[c.Kind][idKey(c.ID)] = c
+	}
+	shadow := func(kind ItemKind, id string, from, to *Provenance) {
+		s := &ShadowedItem{Kind: kind, ID: id}
+		if c := changed[kind][idKey(id)]; c != nil {
+			s.Differs, s.AddedVersions, s.RemovedVersions = true, c.AddedVersions, c.RemovedVersions
+		}
+		if from != nil {
+			s.From = from.ManifestURL
+		}
+		if to != nil {
+			s.To = to.ManifestURL
+		}
+		p.Shadowed = append(p.Shadowed, s)
+	}
+	for _, id := range distinctIDs(b.GetBoardIDs()) {
+		if old, ok := a.GetBoard(id); ok {
+			cur, _ := b.GetBoard(id)
+			shadow(ItemKindBoard, id, old.Provenance(), cur.Provenance())
+		}
+	}
+	for _, id := range distinctIDs(b.GetAppIDs()) {
+		if old, ok := a.GetApp(id); ok {
+			cur, _ := b.GetApp(id)
+			shadow(ItemKindApp, id, old.Provenance(), cur.Provenance())
+		}
+	}
+	for _, id := range distinctIDs(b.GetMiddlewareIDs()) {
+		if old, ok := a.GetMiddleware(id); ok {
+			cur, _ := b.GetMiddleware(id)
+			shadow(ItemKindMiddleware, id, old.Provenance(), cur.Provenance())
+		}
+	}
+	// Differing items first, each group in manifest order
+	differing, same := []*ShadowedItem{}, []*ShadowedItem{}
+	for _, s := range p.Shadowed {
+		if s.Differs {
+			differing = append(differing, s)
+		} else {
+			same = append(same, s)
+		}
+	}
+	p.Shadowed = append(differing, same...)
+
+	p.Before, p.Incoming = itemCounts(a), itemCounts(b)
+	p.After = ItemCounts{
+		Boards:     p.Before.Boards + countKind(p.Added, ItemKindBoard),
+		Apps:       p.Before.Apps + countKind(p.Added, ItemKindApp),
+		Middleware: p.Before.Middleware + countKind(p.Added, ItemKindMiddleware),
+	}
+
+	if smA, ok := a.(*SuperManifest); ok {
+		if smB, ok := b.(*SuperManifest); ok {
+			p.Conflicts = urlConflicts(smA, smB)
+			if smA.Version != smB.Version && smB.Version != "" {
+				p.VersionMismatch = fmt.Sprintf("%s vs %s", smA.Version, smB.Version)
+			}
+		}
+	}
+	return p
+}
+
+// urlConflicts returns the dependency and capability URLs of both trees with different
+// content, like AddSuperManifest warns about
+func urlConflicts(a, b *SuperManifest) []string {
+	ret := []string{}
+	for _, k := range orderedKeys(b.dependenciesMap) {
+		if _, exists := a.dependenciesMap[k]; exists && !sameContentHash(a, b, k) {
+			ret = append(ret, k)
+		}
+	}
+	for _, k := range orderedKeys(b.bspCapabilitiesMap) {
+		if _, exists := a.bspCapabilitiesMap[k]; exists && !sameContentHash(a, b, k) {
+			ret = append(ret, k)
+		}
+	}
+	return ret
+}
+
+// sameContentHash reports whether two trees read the same content from a URL
+func sameContentHash(a, b *SuperManifest, urlStr string) bool {
+	h := a.contentHashes[urlStr]
+	return h != "" && h == b.contentHashes[urlStr]
+}
+
+// itemCounts counts the distinct IDs of a tree
+func itemCounts(sm SuperManifestIF) ItemCounts {
+	return ItemCounts{
+		Boards:     len(distinctIDs(sm.GetBoardIDs())),
+		Apps:       len(distinctIDs(sm.GetAppIDs())),
+		Middleware: len(distinctIDs(sm.GetMiddlewareIDs())),
+	}
+}
+
+// distinctIDs returns the IDs, each once, in order; IDs are compared like lookups do
+func distinctIDs(ids []string) []string {
+	seen := make(map[string]bool)
+	ret := []string{}
+	for _, id := range ids {
+		if key := idKey(id); !seen[key] {
+			seen[key] = true
+			ret = append(ret, id)
+		}
+	}
+	return ret
+}
+
+// countKind counts the changes of one kind of item
+func countKind(changes []*ItemChange, kind ItemKind) int {
+	n := 0
+	for _, c := range changes {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// String summarizes the preview in a line
+func (p *MergePreview) String() string {
+	return fmt.Sprintf("%d added, %d shadowed (%d differ), %d conflicting URLs; after: %d boards, %d apps, %d middleware",
+		len(p.Added), len(p.Shadowed), len(p.Differing()), len(p.Conflicts), p.After.Boards, p.After.Apps, p.After.Middleware)
+}
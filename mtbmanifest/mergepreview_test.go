@@ -0,0 +1,67 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPreviewMerge(t *testing.T) {
+	base, err := superManifestFromContents(map[string][]byte{
+		"https://example.com/super.xml":      []byte(testSuperXML),
+		"https://example.com/boards.xml":     []byte(testBoardsXML),
+		"https://example.com/apps.xml":       []byte(testAppsXML),
+		"https://example.com/middleware.xml": []byte(testMiddlewareXML),
+	}, []string{"https://example.com/super.xml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incoming, err := superManifestFromContents(map[string][]byte{
+		"https://example.com/ea/super.xml": []byte(`<super-manifest version="2.0">
+  <board-manifest-list><board-manifest><uri>https://example.com/ea/boards.xml</uri></board-manifest></board-manifest-list>
+  <app-manifest-list><app-manifest><uri>https://example.com/apps.xml</uri></app-manifest></app-manifest-list>
+  <middleware-manifest-list></middleware-manifest-list>
+</super-manifest>`),
+		"https://example.com/ea/boards.xml": []byte(`<boards>
+  <board><id>KIT-EA</id><name>Early Access Kit</name><chips><mcu>X</mcu></chips></board>
+  <board><id>CY8CKIT-149</id><name>PSoC 4100S Plus Prototyping Kit (EA)</name><chips><mcu>X</mcu></chips>
+    <versions><version><num>9.0</num><commit>release-v9.0.0</commit></version></versions></board>
+</boards>`),
+		"https://example.com/apps.xml": []byte(testAppsXML),
+	}, []string{"https://example.com/ea/super.xml"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := len(base.GetBoardIDs())
+	p := PreviewMerge(base, incoming)
+	if len(base.GetBoardIDs()) != before {
+		t.Fatal("expected the preview to leave the tree alone")
+	}
+	if len(p.Added) != 1 || p.Added[0].ID != "KIT-EA" {
+		t.Fatalf("unexpected additions %+v", p.Added)
+	}
+	if p.After.Boards != p.Before.Boards+1 || p.After.Apps != p.Before.Apps {
+		t.Errorf("unexpected counts %+v -> %+v", p.Before, p.After)
+	}
+	differing := p.Differing()
+	if len(differing) != 1 || differing[0].ID != "CY8CKIT-149" || !slices.Equal(differing[0].AddedVersions, []string{"release-v9.0.0"}) ||
+		differing[0].To != "https://example.com/ea/boards.xml" {
+		t.Fatalf("unexpected differing items %+v", differing)
+	}
+	if p.Shadowed[0] != differing[0] || len(p.Shadowed) != 1+len(base.GetAppIDs()) {
+		t.Errorf("expected the identical apps to be listed after the differing board, got %d", len(p.Shadowed))
+	}
+	if !strings.HasPrefix(p.String(), "1 added, ") {
+		t.Errorf("unexpected summary %q", p.String())
+	}
+
+	// The preview agrees with the merge
+	base.AddSuperManifest(incoming)
+	if b, _ := base.GetBoard("CY8CKIT-149"); b.Name != "PSoC 4100S Plus Prototyping Kit (EA)" {
+		t.Errorf("expected the incoming board to shadow the other, got %s", b.Name)
+	}
+	if got := itemCounts(base); got != p.After {
+		t.Errorf("expected %+v after merging, got %+v", p.After, got)
+	}
+}
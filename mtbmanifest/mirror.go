@@ -0,0 +1,114 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A mirror is a directory of manifests laid out for static hosting. Every manifest is written
+// at host/path of its original URL, and the super manifests are rewritten so that the
+// manifests they point to are fetched from the mirror's base URL. Serving the directory at
+// that base URL gives an internal mirror; pass the mirror URL of the super manifest as the URL
+// to ingest.
+
+// MirrorFile is one manifest written to a mirror
+type MirrorFile struct {
+	URL       string       `json:"url"`
+	Kind      ManifestKind `json:"kind"`
+	Path      string       `json:"path"`
+	MirrorURL string       `json:"mirrorUrl"`
+}
+
+// MirrorReport describes a published mirror
+type MirrorReport struct {
+	Files []*MirrorFile `json:"files"`
+	// SuperManifestURLs are the mirror URLs of the rewritten super manifests
+	SuperManifestURLs []string `json:"superManifestUrls"`
+}
+
+// mirrorPath returns where a URL lives in the mirror, relative to its root, e.g.
+// github.com/Infineon/mtb-super-manifest/raw/v2.X/mtb-super-manifest-fv2.xml
+func mirrorPath(urlStr string) (string, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("cannot mirror %q: not an absolute URL", urlStr)
+	}
+	p := path.Clean("/" + u.Host + "/" + u.Path)
+	return strings.TrimPrefix(p, "/"), nil
+}
+
+// PublishMirror writes every manifest of the tree into dir and rewrites the super manifests to
+// point at baseURL, the URL dir will be served at. Contents come from the cache, which holds
+// everything fetched while ingesting. A nil cache means the default cache.
+func PublishMirror(sm SuperManifestIF, cache *ManifestCache, dir, baseURL string) (*MirrorReport, error) {
+	if cache == nil {
+		cache = NewManifestDefaultCache()
+		defer cache.Close()
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	report := &MirrorReport{Files: []*MirrorFile{}, SuperManifestURLs: []string{}}
+
+	// Map every original URL to its mirror URL first; the super manifests refer to the others
+	rewrites := make(map[string]string)
+	for _, ref := range sm.ReferencedURLs() {
+		rel, err := mirrorPath(ref.URL)
+		if err != nil {
+			return nil, err
+		}
+		file := &MirrorFile{URL: ref.URL, Kind: ref.Kind, Path: rel, MirrorURL: baseURL + "/" + rel}
+		report.Files = append(report.Files, file)
+		rewrites[ref.URL] = file.MirrorURL
+	}
+
+	for _, file := range report.Files {
+		data, err := cache.Get(file.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", file.URL, err)
+		}
+		if file.Kind == KindSuper {
+			data = rewriteURLs(data, rewrites)
+			report.SuperManifestURLs = append(report.SuperManifestURLs, file.MirrorURL)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// rewriteURLs replaces every occurrence of the URLs in an XML document, as written or
+// XML-escaped. Longer URLs are replaced first so one URL that is a prefix of another does
+// not break it.
+func rewriteURLs(data []byte, rewrites map[string]string) []byte {
+	from := make([]string, 0, len(rewrites))
+	for u := range rewrites {
+		from = append(from, u)
+	}
+	sort.Slice(from, func(i, j int) bool {
+		if len(from[i]) != len(from[j]) {
+			return len(from[i]) > len(from[j])
+		}
+		return from[i] < from[j]
+	})
+	escape := func(s string) []byte {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(s))
+		return buf.Bytes()
+	}
+	for _, u := range from {
+		data = bytes.ReplaceAll(data, escape(u), escape(rewrites[u]))
+		data = bytes.ReplaceAll(data, []byte(u), []byte(rewrites[u]))
+	}
+	return data
+}
@@ -0,0 +1,63 @@
+package mtbmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublishMirror(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	sm.SourceUrls = []string{"https://example.com/super.xml"}
+	sm.BoardManifestList.BoardManifest[0].DependencyURL = "https://example.com/deps.xml?raw=true"
+
+	cache := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	super := `<super-manifest><board-manifest-list>` +
+		`<board-manifest dependency-url="https://example.com/deps.xml?raw=true"><uri>https://example.com/boards.xml</uri></board-manifest>` +
+		`</board-manifest-list></super-manifest>`
+	for _, u := range sm.ManifestURLs() {
+		data := "content of " + u
+		if u == "https://example.com/super.xml" {
+			data = super
+		}
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	report, err := PublishMirror(sm, cache, dir, "https://mirror.local/mtb/")
+	if err != nil {
+		t.Fatalf("PublishMirror failed: %v", err)
+	}
+	if len(report.Files) != 5 {
+		t.Errorf("expected 5 files, got %d", len(report.Files))
+	}
+	if len(report.SuperManifestURLs) != 1 || report.SuperManifestURLs[0] != "https://mirror.local/mtb/example.com/super.xml" {
+		t.Errorf("unexpected super manifest URLs %v", report.SuperManifestURLs)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "example.com", "boards.xml"))
+	if err != nil || string(data) != "content of https://example.com/boards.xml" {
+		t.Errorf("boards.xml not mirrored as is: %q, %v", data, err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "example.com", "super.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewritten := string(data)
+	for _, want := range []string{
+		"<uri>https://mirror.local/mtb/example.com/boards.xml</uri>",
+		`dependency-url="https://mirror.local/mtb/example.com/deps.xml"`,
+	} {
+		if !strings.Contains(rewritten, want) {
+			t.Errorf("expected %s in rewritten super manifest:\n%s", want, rewritten)
+		}
+	}
+	if strings.Contains(rewritten, "https://example.com/") {
+		t.Errorf("original URL left in rewritten super manifest:\n%s", rewritten)
+	}
+}
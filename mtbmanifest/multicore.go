@@ -0,0 +1,73 @@
+package mtbmanifest
+
+// ////////////////////////////////////////////////////////////////////////
+// Multi-core applications
+// ////////////////////////////////////////////////////////////////////////
+
+// A multi-core code example is made of one project per core, e.g. proj_cm33_s, proj_cm33_ns
+// and proj_cm55 on PSoC Edge. The manifest lists them under <projects>, for the app or for one
+// of its versions, each with the core it runs on and what it requires of the board:
+//
+//	<projects>
+//	  <project name="proj_cm33" core="CM33" req_capabilities_v2="cat1d"/>
+//	  <project name="proj_cm55" core="CM55" req_capabilities_v2="cat1d [ml,npu]"/>
+//	</projects>
+//
+// A board suits such an app only if it meets the requirements of every project.
+
+// GetCapabilities returns the parsed capability requirements of a project
+// Prefers v2 format if available, falls back to v1
+func (p *AppProject) GetCapabilities() CapabilityRequirement {
+	if p.ReqCapabilitiesV2 != "" {
+		return ParseCapabilities(p.ReqCapabilitiesV2)
+	}
+	return ParseCapabilities(p.ReqCapabilities)
+}
+
+// ProjectsFor returns the projects of a version of the app: its own if it lists any, else
+// the app's. v may be nil for the app's. Single-project apps have none.
+func (a *App) ProjectsFor(v *CEVersion) []*AppProject {
+	if v != nil && v.Projects != nil && len(v.Projects.Projects) > 0 {
+		return v.Projects.Projects
+	}
+	if a.Projects != nil {
+		return a.Projects.Projects
+	}
+	return nil
+}
+
+// IsMultiCore reports whether the app, or any of its versions, has more than one project
+func (a *App) IsMultiCore() bool {
+	if len(a.ProjectsFor(nil)) > 1 {
+		return true
+	}
+	for _, v := range a.Versions.Version {
+		if len(a.ProjectsFor(v)) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Cores returns the cores the projects of a version of the app run on, in project order
+func (a *App) Cores(v *CEVersion) []string {
+	ret := []string{}
+	for _, p := range a.ProjectsFor(v) {
+		if p.Core != "" {
+			ret = append(ret, p.Core)
+		}
+	}
+	return ret
+}
+
+// MatchesVersion reports whether a board with the given capabilities suits a version of the
+// app: the app's, the version's and every project's requirements must be met. An app that
+// requires nothing at all is not offered for every board, so it does not match.
+func (a *App) MatchesVersion(v *CEVersion, boardCaps map[string]bool) bool {
+	return a.MatchesVersionContext(v, &CapabilityContext{Capabilities: boardCaps})
+}
+
+// Matches reports whether a board with the given capabilities suits any version of the app
+func (a *App) Matches(boardCaps map[string]bool) bool {
+	return a.MatchesContext(&CapabilityContext{Capabilities: boardCaps})
+}
@@ -0,0 +1,51 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+const testMultiCoreAppsXML = `<apps version="2.0">
+  <app keywords="ml,edge" req_capabilities_v2="cat1d">
+    <name>Edge ML Deploy</name>
+    <id>mtb-example-psoc-edge-ml-deploy</id>
+    <uri>https://github.com/Infineon/mtb-example-psoc-edge-ml-deploy</uri>
+    <description>Runs a model on the CM55 core.</description>
+    <projects>
+      <project name="proj_cm33_s" core="CM33" path="proj_cm33_s"/>
+      <project name="proj_cm55" core="CM55" req_capabilities_v2="[npu,ml]"/>
+    </projects>
+    <versions>
+      <version flow_version="2.0"><num>Latest 2.X release</num><commit>latest-v2.X</commit></version>
+      <version flow_version="2.0"><num>1.0.0 release</num><commit>release-v1.0.0</commit>
+        <projects><project name="proj_cm33" core="CM33"/></projects>
+      </version>
+    </versions>
+  </app>
+</apps>`
+
+func TestMultiCoreApps(t *testing.T) {
+	apps, err := ReadAppsManifest([]byte(testMultiCoreAppsXML))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	app := apps.App[0]
+	latest, old := app.Versions.Version[0], app.Versions.Version[1]
+	if !app.IsMultiCore() || len(app.ProjectsFor(latest)) != 2 || app.ProjectsFor(latest)[0].Path != "proj_cm33_s" {
+		t.Fatalf("unexpected projects %+v", app.Projects)
+	}
+	if cores := app.Cores(old); len(cores) != 1 || cores[0] != "CM33" {
+		t.Errorf("expected the version's own projects, got %v", cores)
+	}
+
+	withNPU := map[string]bool{"cat1d": true, "npu": true}
+	withoutNPU := map[string]bool{"cat1d": true}
+	if !app.MatchesVersion(latest, withNPU) || app.MatchesVersion(latest, withoutNPU) {
+		t.Error("the CM55 project requirements must be met for the latest version")
+	}
+	if !app.MatchesVersion(old, withoutNPU) || !app.Matches(withoutNPU) {
+		t.Error("the single-core 1.0.0 release does not need an NPU")
+	}
+	if app.Matches(map[string]bool{"npu": true}) {
+		t.Error("the app level requirements must still be met")
+	}
+}
@@ -4,15 +4,17 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto"
+	"crypto/tls"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -40,69 +42,258 @@ type ManifestFetcher struct {
 }
 
 type ManifestCache struct {
-	cacheDir string
-	ttl      time.Duration
-
-	// Background refresh tracking
+	store     CacheStore
+	ttl       time.Duration
+	ttlPolicy TTLPolicy
+
+	// Network access, see auth.go, tlsoptions.go, signature.go and allowlist.go
+	client     *http.Client
+	clientOnce sync.Once
+	auth       AuthFunc
+	tls        *tls.Config
+	timeouts   Timeouts // see WithTimeouts
+	verifyKeys []crypto.PublicKey
+	allowList  *AllowList
+	cacheOnly  bool
+	maxStale   time.Duration     // see WithMaxStale
+	perHost    *hostLimiter      // see WithMaxPerHost
+	bandwidth  *bandwidthLimiter // see WithMaxBandwidth
+	rateLimits *rateLimiter      // see ratelimit.go
+
+	// logger gets the messages of the cache and its fetchers; nil means the package logger
+	logger LoggerIF
+
+	// Background refresh, see Start
+	noRefresh    bool
 	ctx          context.Context
 	cancel       context.CancelFunc
 	refreshQueue chan string
 	refreshing   sync.Map // track URLs being refreshed
-	closeOnce    sync.Once
+	startOnce    sync.Once
+	running      atomic.Bool
+	finishing    chan struct{} // closed by finish
+	finishOnce   sync.Once
+	worker       sync.WaitGroup // the refresh worker, waited for by Close
 }
 
 const (
 	compressionThreshold = 10 * 1024 // 10KB
 	compressionFlag      = 0x01
+	cacheFormatVersion   = 1                   // version of CacheHeader written by FileStore
 	defaultTTL           = 15 * 24 * time.Hour // 15 days
 )
 
-func NewManifestCache(cacheDir string, ttl time.Duration) *ManifestCache {
-	if cacheDir == "" {
-		home, _ := os.UserHomeDir()
-		cacheDir = filepath.Join(home, ".modustoolbox", "mtbmcp", "manifests")
-	}
-	if ttl <= 0 {
-		ttl = defaultTTL
-	}
+// DefaultCacheDir returns where caches keep their files unless told otherwise:
+// ~/.modustoolbox/mtbmcp/manifests
+func DefaultCacheDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".modustoolbox", "mtbmcp", "manifests")
+}
 
+// NewManifestCache creates a cache. Without options it keeps its entries as files in
+// DefaultCacheDir, fresh for 15 days. No goroutine is started: stale entries are served as
+// they are until Start is called, and Close stops the refreshing again.
+func NewManifestCache(opts ...CacheOption) *ManifestCache {
 	ctx, cancel := context.WithCancel(context.Background())
 	c := &ManifestCache{
-		cacheDir:     cacheDir,
-		ttl:          ttl,
+		ttl:          defaultTTL,
 		ctx:          ctx,
 		cancel:       cancel,
 		refreshQueue: make(chan string, 100),
+		finishing:    make(chan struct{}),
+		rateLimits:   newRateLimiter(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.store == nil {
+		c.store = platformCacheStore()
+	}
+	return c
+}
 
-	// Start background refresh worker
-	go c.refreshWorker()
-
+// NewManifestCacheWithStore creates a started cache on top of any CacheStore
+//
+// Deprecated: use NewManifestCache(WithStore(store), WithCacheTTL(ttl)) and Start.
+func NewManifestCacheWithStore(store CacheStore, ttl time.Duration, opts ...CacheOption) *ManifestCache {
+	warnDeprecated("NewManifestCacheWithStore")
+	c := NewManifestCache(append([]CacheOption{WithStore(store), WithCacheTTL(ttl)}, opts...)...)
+	c.Start()
 	return c
 }
 
+// WithDir keeps the entries of the cache as files in dir. An empty dir means DefaultCacheDir.
+func WithDir(dir string) CacheOption {
+	return func(c *ManifestCache) {
+		if dir == "" {
+			dir = DefaultCacheDir()
+		}
+		c.store = NewFileStore(dir)
+	}
+}
+
+// WithStore keeps the entries of the cache in any CacheStore, e.g. a MemoryStore
+func WithStore(store CacheStore) CacheOption {
+	return func(c *ManifestCache) {
+		c.store = store
+	}
+}
+
+// WithCacheTTL sets how long entries stay fresh, for URLs no TTL policy covers. A TTL of zero
+// or less means the default of 15 days.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *ManifestCache) {
+		if ttl <= 0 {
+			ttl = defaultTTL
+		}
+		c.ttl = ttl
+	}
+}
+
+// WithNoBackgroundRefresh makes Start a no-op: stale entries are served as they are, and
+// refreshed only by fetching them explicitly. Suits caches that are only read.
+func WithNoBackgroundRefresh() CacheOption {
+	return func(c *ManifestCache) {
+		c.noRefresh = true
+	}
+}
+
+// Start starts refreshing stale entries in the background. It is safe to call more than once.
+// Call Close when done with the cache.
+func (c *ManifestCache) Start() {
+	if c.noRefresh || c.ctx.Err() != nil {
+		return
+	}
+	c.startOnce.Do(func() {
+		c.running.Store(true)
+		startedCaches.Store(c, true)
+		c.worker.Add(1)
+		go func() {
+			defer c.worker.Done()
+			c.refreshWorker()
+		}()
+	})
+}
+
+// NewManifestDefaultCache creates a started cache on the default store (see
+// SetDefaultCacheStore) with the default TTL policy and options (see SetDefaultTTLPolicy and
+// SetDefaultCacheOptions)
 func NewManifestDefaultCache() *ManifestCache {
-	return NewManifestCache("", 0)
+	c := newManifestDefaultCache()
+	c.Start()
+	return c
+}
+
+// newManifestDefaultCache creates a cache with the default store and options, followed by opts
+func newManifestDefaultCache(opts ...CacheOption) *ManifestCache {
+	defaults := defaultCacheOptionList()
+	if defaultCacheStore != nil {
+		defaults = append([]CacheOption{WithStore(defaultCacheStore)}, defaults...)
+	}
+	return NewManifestCache(append(defaults, opts...)...)
+}
+
+// defaultCacheOptionList returns the options of default caches
+func defaultCacheOptionList() []CacheOption {
+	opts := []CacheOption{}
+	if defaultTTLPolicy != nil {
+		opts = append(opts, WithTTLPolicy(defaultTTLPolicy))
+	}
+	return append(opts, defaultCacheOptions...)
+}
+
+// WithCacheLogger sends the messages of the cache, and of the fetchers using it, to l instead
+// of the package logger
+func WithCacheLogger(l LoggerIF) CacheOption {
+	return func(c *ManifestCache) {
+		c.logger = l
+	}
+}
+
+// log returns the logger of the cache
+func (c *ManifestCache) log() LoggerIF {
+	if c.logger != nil {
+		return c.logger
+	}
+	return logger
+}
+
+// Store returns the storage backend of the cache
+func (c *ManifestCache) Store() CacheStore {
+	return c.store
+}
+
+// readCache returns the cached content for a URL, fresh or not
+func (c *ManifestCache) readCache(urlStr string) ([]byte, error) {
+	if store, ok := c.store.(*FileStore); ok {
+		// Migrating a legacy file is news for the logger of the cache
+		return store.readFile(urlStr, c.log())
+	}
+	return c.store.Get(urlStr)
 }
 
-// Close gracefully shuts down the background refresh worker.
-// It's safe to call multiple times (idempotent).
+// writeCache stores content for a URL, cached as of now
+func (c *ManifestCache) writeCache(urlStr string, content []byte) error {
+	return c.store.Put(urlStr, content, time.Time{})
+}
+
+// Close stops the background refresh worker, if started. Once closed, a cache is not
+// refreshed again. It's safe to call multiple times (idempotent).
 // Should be called with defer in client code: defer cache.Close()
+//
+// A refresh in progress is cancelled and waited for, so that no entry is left half written.
 func (c *ManifestCache) Close() {
-	c.closeOnce.Do(func() {
-		c.cancel()            // Signal context cancellation
-		close(c.refreshQueue) // Close the queue channel
+	c.cancel()
+	c.running.Store(false)
+	c.worker.Wait()
+	startedCaches.Delete(c)
+}
+
+// finish stops the background refresh once the refreshes queued so far are done, instead of
+// cancelling them as Close does. For caches used for one job, e.g. an ingestion: what the job
+// served stale is still refreshed, and no goroutine outlives that.
+func (c *ManifestCache) finish() {
+	c.finishOnce.Do(func() {
+		c.running.Store(false) // nothing more is queued
+		close(c.finishing)
+	})
+}
+
+// startedCaches are the caches started and not closed yet, see CloseCaches
+var startedCaches sync.Map
+
+// CloseCaches closes every cache of the process that was started, including those ingestion
+// starts for itself, e.g. when shutting down
+func CloseCaches() {
+	startedCaches.Range(func(key, _ any) bool {
+		key.(*ManifestCache).Close()
+		return true
 	})
 }
 
+// Get returns the content of a URL, from the cache if it holds it (see GetContext)
 func (c *ManifestCache) Get(urlStr string) ([]byte, error) {
+	return c.GetContext(context.Background(), urlStr)
+}
+
+// GetContext returns the content of a URL: cached content right away, stale or not, queuing a
+// refresh of stale content; otherwise, or if too stale (see WithMaxStale), content fetched
+// until ctx is done
+func (c *ManifestCache) GetContext(ctx context.Context, urlStr string) ([]byte, error) {
 	data, err := c.readCache(urlStr)
 	if err == nil {
 		// Cache hit - check if stale
-		info, _ := os.Stat(c.urlToFilename(urlStr))
-		age := time.Since(info.ModTime())
+		age := time.Duration(0)
+		if info, err := c.store.Stat(urlStr); err == nil {
+			age = time.Since(info.ModTime)
+		}
 
-		if age >= c.ttl {
+		ttl := c.TTL(urlStr)
+		if c.maxStale > 0 && age-ttl > c.maxStale {
+			// Too stale to serve without trying to refresh it first
+			return c.getTooStale(ctx, urlStr, data, age, age-ttl)
+		}
+		if age >= ttl && !c.cacheOnly {
 			// Stale - queue for background refresh
 			c.queueRefresh(urlStr)
 		}
@@ -112,10 +303,16 @@ func (c *ManifestCache) Get(urlStr string) ([]byte, error) {
 	}
 
 	// Cache miss - must fetch synchronously
-	return c.fetchAndCache(urlStr)
+	if c.cacheOnly {
+		return nil, fmt.Errorf("%s: %w", urlStr, ErrOffline)
+	}
+	return c.fetchAndCache(ctx, urlStr)
 }
 
 func (c *ManifestCache) queueRefresh(urlStr string) {
+	if !c.running.Load() {
+		return // not started or closed
+	}
 	// Avoid duplicate refreshes
 	if _, alreadyQueued := c.refreshing.LoadOrStore(urlStr, true); alreadyQueued {
 		return
@@ -134,19 +331,8 @@ func (c *ManifestCache) refreshWorker() {
 	// Process refresh queue in background
 	for {
 		select {
-		case urlStr, ok := <-c.refreshQueue:
-			if !ok {
-				// Channel closed, exit gracefully
-				return
-			}
-			// Refresh this URL
-			_, err := c.fetchAndCache(urlStr)
-			if err != nil {
-				logger.Infof("Background refresh failed for %s: %v", urlStr, err)
-			}
-
-			// Mark as no longer refreshing
-			c.refreshing.Delete(urlStr)
+		case urlStr := <-c.refreshQueue:
+			c.refreshQueued(urlStr)
 
 			// Small delay to avoid hammering servers
 			time.Sleep(100 * time.Millisecond)
@@ -154,63 +340,117 @@ func (c *ManifestCache) refreshWorker() {
 		case <-c.ctx.Done():
 			// Context cancelled, exit gracefully
 			return
+
+		case <-c.finishing:
+			if len(c.refreshQueue) == 0 {
+				startedCaches.Delete(c)
+				return
+			}
+			// The rest of the queue is refreshed first
+			c.refreshQueued(<-c.refreshQueue)
 		}
 	}
 }
 
-func (c *ManifestCache) fetchAndCache(urlStr string) ([]byte, error) {
-	data, err := c.fetchFromNetwork(urlStr)
+// refreshQueued refreshes a URL taken from the refresh queue
+func (c *ManifestCache) refreshQueued(urlStr string) {
+	_, err := c.fetchAndCache(c.ctx, urlStr)
+	if err != nil {
+		c.log().Infof("Background refresh failed for %s: %v", urlStr, err)
+	}
+
+	// Mark as no longer refreshing
+	c.refreshing.Delete(urlStr)
+}
+
+func (c *ManifestCache) fetchAndCache(ctx context.Context, urlStr string) ([]byte, error) {
+	data, err := c.fetchFromNetwork(ctx, urlStr)
 	if err != nil {
 		return nil, err
 	}
 
 	err = c.writeCache(urlStr, data)
 	if err != nil {
-		logger.Warningf("Warning: failed to write cache for %s: %v", urlStr, err)
+		c.log().Warningf("Warning: failed to write cache for %s: %v", urlStr, err)
 	}
 	return data, nil
 }
 
-func (c *ManifestCache) fetchFromNetwork(urlStr string) ([]byte, error) {
-	resp, err := http.Get(urlStr)
+func (c *ManifestCache) fetchFromNetwork(ctx context.Context, urlStr string) ([]byte, error) {
+	data, err := c.httpGet(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.verifyKeys) > 0 {
+		if err := c.verifySignature(ctx, urlStr, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// httpGet downloads a URL with the cache's client and credentials
+func (c *ManifestCache) httpGet(ctx context.Context, urlStr string) ([]byte, error) {
+	if c.allowList != nil {
+		if err := c.allowList.CheckURL(urlStr); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.rateLimits.check(urlStr); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.auth != nil {
+		c.auth(req)
+	}
+	if c.perHost != nil {
+		defer c.perHost.acquire(urlStr)()
+	}
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http get: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if err := c.rateLimits.observe(urlStr, resp); err != nil {
+		return nil, err
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http status %d", resp.StatusCode)
+		return nil, &httpStatusError{StatusCode: resp.StatusCode}
+	}
+	if c.allowList != nil {
+		if err := c.allowList.checkContentType(resp); err != nil {
+			return nil, err
+		}
 	}
 
+	if c.bandwidth != nil {
+		return io.ReadAll(c.bandwidth.reader(req.Context(), resp.Body))
+	}
 	return io.ReadAll(resp.Body)
 }
 
-func (c *ManifestCache) urlToFilename(urlStr string) string {
-	parsed, _ := url.Parse(urlStr)
-	name := parsed.Host + parsed.Path
-	name = strings.ReplaceAll(name, "/", "_")
-	name = strings.ReplaceAll(name, ":", "_")
-	name = strings.ReplaceAll(name, "?", "_")
-	return filepath.Join(c.cacheDir, name)
+// httpStatusError is returned for responses other than 200 OK
+type httpStatusError struct {
+	StatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("http status %d", e.StatusCode)
 }
 
 func (c *ManifestCache) RefreshAllStale() {
-	entries, err := os.ReadDir(c.cacheDir)
+	entries, err := c.store.List()
 	if err != nil {
 		return
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		info, _ := entry.Info()
-		if time.Since(info.ModTime()) >= c.ttl {
-			oldUrl, err := c.readUrlFromCache(filepath.Join(c.cacheDir, entry.Name()))
-			if err == nil && oldUrl != "" {
-				c.queueRefresh(oldUrl)
-			}
+		if time.Since(entry.ModTime) >= c.TTL(entry.URL) {
+			c.queueRefresh(entry.URL)
 		}
 	}
 }
@@ -246,12 +486,12 @@ func WithMaxConcurrent(maxConcurrent int) FetcherOption {
 //	fetcher := NewManifestFetcher(WithMaxConcurrent(20))
 //
 //	// Custom cache and concurrency
-//	myCache := NewManifestCache("/my/cache", 7*24*time.Hour)
+//	myCache := NewManifestCache(WithDir("/my/cache"), WithCacheTTL(7*24*time.Hour))
+//	myCache.Start()
 //	fetcher := NewManifestFetcher(WithCache(myCache), WithMaxConcurrent(15))
 func NewManifestFetcher(opts ...FetcherOption) *ManifestFetcher {
 	// Set sensible defaults
 	f := &ManifestFetcher{
-		cache:   NewManifestDefaultCache(),
 		limiter: make(chan struct{}, 10), // Conservative default
 	}
 
@@ -259,6 +499,9 @@ func NewManifestFetcher(opts ...FetcherOption) *ManifestFetcher {
 	for _, opt := range opts {
 		opt(f)
 	}
+	if f.cache == nil {
+		f.cache = NewManifestDefaultCache()
+	}
 
 	return f
 }
@@ -281,8 +524,19 @@ type FetchUrlWithCb struct {
 // The return value is a map of URL to fetched data or any error encountered
 // If a Callback is provided, it is called for each URL when fetched and it will be
 // in its own goroutine. So, use callbacks with proper synchronization if needed.
-// The order of the callbacks can be different from the order of the input URLs.
+// The order of the callbacks can be different from the order of the input URLs, unless
+// deterministic mode is enabled. In that case, callbacks are deferred until all fetches are
+// done and then called one at a time, in the order of the input URLs.
 func (f *ManifestFetcher) FetchAllWithCb(urls []*FetchUrlWithCb) map[string]any {
+	return f.fetchAllWithCb(context.Background(), urls)
+}
+
+// fetchAllWithCb is FetchAllWithCb, giving up the fetches when ctx is done: those not started
+// fail with ctx.Err() and requests in flight are cancelled
+func (f *ManifestFetcher) fetchAllWithCb(ctx context.Context, urls []*FetchUrlWithCb) map[string]any {
+	if doDeterministic {
+		return f.fetchAllWithOrderedCb(ctx, urls)
+	}
 	results := map[string]any{}
 	var mu sync.Mutex
 	var wgFetches sync.WaitGroup
@@ -291,16 +545,14 @@ func (f *ManifestFetcher) FetchAllWithCb(urls []*FetchUrlWithCb) map[string]any
 	for ix, item := range urls {
 		wgFetches.Add(1)
 		go func(index int, item *FetchUrlWithCb) {
-			f.limiter <- struct{}{}        // Acquire
-			defer func() { <-f.limiter }() // Release
 			defer wgFetches.Done()
 			defer func() {
 				if r := recover(); r != nil {
-					logger.Errorf("Fetch URL '%s' paniced unexpectedly: %v", item.Url, r)
+					f.cache.log().Errorf("Fetch URL '%s' paniced unexpectedly: %v", item.Url, r)
 				}
 			}()
 
-			data, err := f.cache.Get(item.Url)
+			data, err := f.get(ctx, item.Url)
 			mu.Lock()
 			if err != nil {
 				results[item.Url] = err
@@ -314,7 +566,7 @@ func (f *ManifestFetcher) FetchAllWithCb(urls []*FetchUrlWithCb) map[string]any
 					defer wgCallbacks.Done()
 					defer func() {
 						if r := recover(); r != nil {
-							logger.Errorf("Fetch URL '%s' callback recovered from panic: %v", url, r)
+							f.cache.log().Errorf("Fetch URL '%s' callback recovered from panic: %v", url, r)
 						}
 					}()
 					item.Callback(url, data, err, index)
@@ -328,8 +580,79 @@ func (f *ManifestFetcher) FetchAllWithCb(urls []*FetchUrlWithCb) map[string]any
 	return results
 }
 
+// fetchAllWithOrderedCb is the deterministic flavor of FetchAllWithCb. Fetching is still
+// concurrent but the callbacks are called sequentially, in input order, from this goroutine.
+func (f *ManifestFetcher) fetchAllWithOrderedCb(ctx context.Context, urls []*FetchUrlWithCb) map[string]any {
+	results := f.fetchAll(ctx, uniqueUrls(urls))
+	for _, item := range urls {
+		if item.Callback == nil {
+			continue
+		}
+		var data []byte
+		var err error
+		switch v := results[item.Url].(type) {
+		case []byte:
+			data = v
+		case error:
+			err = v
+		default:
+			err = fmt.Errorf("no result for %s", item.Url)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					f.cache.log().Errorf("Fetch URL '%s' callback recovered from panic: %v", item.Url, r)
+				}
+			}()
+			item.Callback(item.Url, data, err, item.Index)
+		}()
+	}
+	return results
+}
+
+// get fetches a URL through the cache once the limiter has room, or fails when ctx is done first.
+// A URL its host rate limits is fetched again once the limit resets, without holding a slot
+// of the limiter meanwhile (see WithRateLimitWait).
+func (f *ManifestFetcher) get(ctx context.Context, urlStr string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		data, err := f.getOnce(ctx, urlStr)
+		var rl *RateLimitError
+		if !errors.As(err, &rl) || attempt == rateLimitRetries || !f.cache.waitForRateLimit(ctx, rl) {
+			return data, err
+		}
+	}
+}
+
+// getOnce fetches a URL through the cache once the limiter has room
+func (f *ManifestFetcher) getOnce(ctx context.Context, urlStr string) ([]byte, error) {
+	select {
+	case f.limiter <- struct{}{}: // Acquire
+		defer func() { <-f.limiter }() // Release
+		return f.cache.GetContext(ctx, urlStr)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func uniqueUrls(urls []*FetchUrlWithCb) []string {
+	seen := make(map[string]bool, len(urls))
+	ret := make([]string, 0, len(urls))
+	for _, item := range urls {
+		if !seen[item.Url] {
+			seen[item.Url] = true
+			ret = append(ret, item.Url)
+		}
+	}
+	return ret
+}
+
 // The return value is a map of URL to fetched data or any error encountered
 func (f *ManifestFetcher) FetchAll(urls []string) map[string]any {
+	return f.fetchAll(context.Background(), urls)
+}
+
+// fetchAll is FetchAll, giving up the fetches when ctx is done
+func (f *ManifestFetcher) fetchAll(ctx context.Context, urls []string) map[string]any {
 	results := map[string]any{}
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -339,10 +662,7 @@ func (f *ManifestFetcher) FetchAll(urls []string) map[string]any {
 		go func(u string) {
 			defer wg.Done()
 
-			f.limiter <- struct{}{}        // Acquire
-			defer func() { <-f.limiter }() // Release
-
-			data, err := f.cache.Get(u)
+			data, err := f.get(ctx, u)
 
 			mu.Lock()
 			if err != nil {
@@ -358,18 +678,25 @@ func (f *ManifestFetcher) FetchAll(urls []string) map[string]any {
 	return results
 }
 
-// Add to cache struct
+// Clear deletes every entry of the cache (see PlanClear)
 func (c *ManifestCache) Clear() error {
-	return os.RemoveAll(c.cacheDir)
+	changes, err := c.PlanClear()
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if err := c.store.Delete(change.URL); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// ClearStale deletes the entries older than their TTL (see PlanClearStale)
 func (c *ManifestCache) ClearStale() error {
-	entries, _ := os.ReadDir(c.cacheDir)
-	for _, entry := range entries {
-		info, _ := entry.Info()
-		if time.Since(info.ModTime()) > c.ttl {
-			_ = os.Remove(filepath.Join(c.cacheDir, entry.Name()))
-		}
+	changes, _ := c.PlanClearStale()
+	for _, change := range changes {
+		_ = c.store.Delete(change.URL)
 	}
 	return nil
 }
@@ -386,11 +713,13 @@ type CacheHeader struct {
 	URLSize  uint16
 }
 
+var cacheMagic = [2]byte{'M', 'C'}
+
 func validateHeader(header *CacheHeader, urlStr string) error {
-	if header.Magic != [2]byte{'M', 'C'} {
+	if header.Magic != cacheMagic {
 		return fmt.Errorf("invalid magic number")
 	}
-	if header.Version != 1 {
+	if header.Version != cacheFormatVersion {
 		return fmt.Errorf("unsupported version %d", header.Version)
 	}
 	urlBytes := []byte(urlStr)
@@ -400,12 +729,12 @@ func validateHeader(header *CacheHeader, urlStr string) error {
 	return nil
 }
 
-func (c *ManifestCache) writeCache(urlStr string, content []byte) error {
-	err := os.MkdirAll(c.cacheDir, 0o755)
+func (store *FileStore) writeFile(urlStr string, content []byte) error {
+	err := os.MkdirAll(store.dir, 0o755)
 	if err != nil {
 		return err
 	}
-	filename := c.urlToFilename(urlStr)
+	filename := store.urlToFilename(urlStr)
 	urlBytes := []byte(urlStr)
 
 	// Decide: compress or not?
@@ -438,23 +767,25 @@ func (c *ManifestCache) writeCache(urlStr string, content []byte) error {
 
 	// Build header
 	header := CacheHeader{
-		Magic:    [2]byte{'M', 'C'},
-		Version:  1,
+		Magic:    cacheMagic,
+		Version:  cacheFormatVersion,
 		Flags:    flags,
 		Checksum: simpleChecksum(urlBytes),
 		URLSize:  uint16(len(urlBytes)),
 	}
 
-	// Write atomically to temp file, then rename
-	tmpFile := filename + ".tmp"
-	f, err := os.Create(tmpFile)
+	// Write atomically to a temp file of our own, then rename. Concurrent writes of the same
+	// URL, from this process or another sharing the directory, each have their own.
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
 	if err != nil {
 		return err
 	}
+	tmpFile := f.Name()
 	closed := false
 	defer func() {
 		if !closed {
 			_ = f.Close()
+			_ = os.Remove(tmpFile) // a failed write leaves no partial entry behind
 		}
 	}()
 
@@ -474,11 +805,17 @@ func (c *ManifestCache) writeCache(urlStr string, content []byte) error {
 	_ = f.Close() // We have a defer close above. But needs to be closed before rename
 
 	// Atomic rename (even on Windows)
-	return os.Rename(tmpFile, filename)
+	if err := os.Rename(tmpFile, filename); err != nil {
+		_ = os.Remove(tmpFile)
+		return err
+	}
+	return nil
 }
 
-func (c *ManifestCache) readCache(urlStr string) ([]byte, error) {
-	filename := c.urlToFilename(urlStr)
+// readFile returns the content stored for a URL. Legacy files are migrated, and logger told
+// about it.
+func (store *FileStore) readFile(urlStr string, logger LoggerIF) ([]byte, error) {
+	filename := store.urlToFilename(urlStr)
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -487,8 +824,10 @@ func (c *ManifestCache) readCache(urlStr string) ([]byte, error) {
 
 	// Read and validate header
 	var header CacheHeader
-	if err := binary.Read(f, binary.BigEndian, &header); err != nil {
-		return nil, err
+	if err := binary.Read(f, binary.BigEndian, &header); err != nil || header.Magic != cacheMagic {
+		// Written before files had a header
+		_ = f.Close()
+		return store.migrateLegacyFile(urlStr, filename, logger)
 	}
 
 	// Read URL and validate
@@ -524,7 +863,7 @@ func (c *ManifestCache) readCache(urlStr string) ([]byte, error) {
 	return content, nil
 }
 
-func (c *ManifestCache) readUrlFromCache(filename string) (string, error) {
+func (store *FileStore) readUrlFromFile(filename string) (string, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return "", err
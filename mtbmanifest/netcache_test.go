@@ -0,0 +1,95 @@
+package mtbmanifest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestManifestCacheLifecycle(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("<fresh/>"))
+	}))
+	defer srv.Close()
+	urlStr := srv.URL + "/boards.xml"
+
+	if c := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(0)); c.TTL(urlStr) != defaultTTL {
+		t.Errorf("a TTL of zero should mean the default, got %v", c.TTL(urlStr))
+	}
+
+	store := NewMemoryStore()
+	_ = store.Put(urlStr, []byte("<stale/>"), time.Now().Add(-2*time.Hour))
+	cache := NewManifestCache(WithStore(store), WithCacheTTL(time.Hour))
+	if data, err := cache.Get(urlStr); err != nil || string(data) != "<stale/>" {
+		t.Fatalf("expected the stale entry, got %q, %v", data, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if hits.Load() != 0 {
+		t.Fatal("a cache that is not started must not refresh")
+	}
+
+	cache.Start()
+	cache.Start()
+	_, _ = cache.Get(urlStr)
+	deadline := time.Now().Add(2 * time.Second)
+	for hits.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected one background refresh, got %d", hits.Load())
+	}
+	cache.Close()
+	cache.Close()
+	_ = store.Put(urlStr, []byte("<stale/>"), time.Now().Add(-2*time.Hour))
+	_, _ = cache.Get(urlStr) // must not panic or refresh once closed
+	cache.Start()
+
+	quiet := NewManifestCache(WithStore(store), WithCacheTTL(time.Hour), WithNoBackgroundRefresh())
+	quiet.Start()
+	defer quiet.Close()
+	_, _ = quiet.Get(urlStr)
+	time.Sleep(50 * time.Millisecond)
+	if hits.Load() != 1 {
+		t.Errorf("expected no refresh after Close or with WithNoBackgroundRefresh, got %d hits", hits.Load())
+	}
+}
+
+func TestManifestCacheCloseCancelsRefresh(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-r.Context().Done() // answers only once the client gives up
+	}))
+	defer srv.Close()
+	urlStr := srv.URL + "/boards.xml"
+
+	store := NewMemoryStore()
+	_ = store.Put(urlStr, []byte("<stale/>"), time.Now().Add(-2*time.Hour))
+	cache := NewManifestCache(WithStore(store), WithCacheTTL(time.Hour))
+	cache.Start()
+	if _, ok := startedCaches.Load(cache); !ok {
+		t.Error("expected the started cache to be tracked")
+	}
+	_, _ = cache.Get(urlStr)
+	select {
+	case <-requested:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a background refresh")
+	}
+
+	start := time.Now()
+	CloseCaches()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the refresh in flight to be cancelled, Close took %v", elapsed)
+	}
+	if _, ok := startedCaches.Load(cache); ok {
+		t.Error("expected the closed cache to be forgotten")
+	}
+	if data, err := store.Get(urlStr); err != nil || string(data) != "<stale/>" {
+		t.Errorf("expected the entry to be untouched, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,290 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ObjectStore is a CacheStore kept in an S3-compatible object store (AWS S3, Google Cloud
+// Storage through its XML API and HMAC keys, MinIO, Ceph, ...), so many stateless service
+// instances can share one warm cache. It speaks the plain REST API with path-style URLs
+// (endpoint/bucket/key) and signs requests with AWS Signature Version 4. Without credentials,
+// requests are sent unsigned, which works for buckets that allow anonymous access.
+//
+// Each cache entry is one object, named Prefix + the escaped URL. The time an entry was put in
+// the cache is kept in the object's metadata and reported by Stat; List reports the time the
+// object was last written, which is the same unless Put was given an explicit modTime.
+type ObjectStore struct {
+	cfg ObjectStoreConfig
+}
+
+// ObjectStoreConfig configures an ObjectStore
+type ObjectStoreConfig struct {
+	// Endpoint is the base URL of the service, e.g. https://s3.us-west-2.amazonaws.com or
+	// https://storage.googleapis.com
+	Endpoint string
+	Bucket   string
+	// Prefix is prepended to every object name, e.g. "manifests/"
+	Prefix string
+	// Region is used for signing. Default "us-east-1"; use "auto" for Google Cloud Storage.
+	Region    string
+	AccessKey string
+	SecretKey string
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+const objectModTimeHeader = "X-Amz-Meta-Mtb-Modtime"
+
+// NewObjectStore creates an object store backend. Nothing is contacted until first use.
+func NewObjectStore(cfg ObjectStoreConfig) *ObjectStore {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &ObjectStore{cfg: cfg}
+}
+
+func (store *ObjectStore) objectName(urlStr string) string {
+	return store.cfg.Prefix + url.QueryEscape(urlStr)
+}
+
+// do sends a signed request for an object (or the bucket when name is "") and returns the
+// response. Status 404 becomes an error matching fs.ErrNotExist.
+func (store *ObjectStore) do(method, name string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	endpoint, err := url.Parse(store.cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object store endpoint: %v", err)
+	}
+	path := "/" + store.cfg.Bucket
+	if name != "" {
+		path += "/" + name
+	}
+	reqURL := &url.URL{
+		Scheme:   endpoint.Scheme,
+		Host:     endpoint.Host,
+		Path:     endpoint.Path + path,
+		RawPath:  endpoint.Path + awsURIEncode(path, false),
+		RawQuery: canonicalQuery(query),
+	}
+	req, err := http.NewRequest(method, reqURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	store.sign(req, body, time.Now().UTC())
+
+	resp, err := store.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, notInStore(name)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("object store %s %s: http status %d: %s", method, name, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (store *ObjectStore) Get(urlStr string) ([]byte, error) {
+	resp, err := store.do(http.MethodGet, store.objectName(urlStr), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return io.ReadAll(resp.Body)
+}
+
+func (store *ObjectStore) Put(urlStr string, data []byte, modTime time.Time) error {
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/xml")
+	header.Set(objectModTimeHeader, modTime.UTC().Format(time.RFC3339))
+	resp, err := store.do(http.MethodPut, store.objectName(urlStr), nil, data, header)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (store *ObjectStore) Stat(urlStr string) (*CacheEntryInfo, error) {
+	resp, err := store.do(http.MethodHead, store.objectName(urlStr), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	info := &CacheEntryInfo{URL: urlStr, Size: int(resp.ContentLength)}
+	if t, err := time.Parse(time.RFC3339, resp.Header.Get(objectModTimeHeader)); err == nil {
+		info.ModTime = t
+	} else if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+	return info, nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response the store needs
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int       `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (store *ObjectStore) List() ([]*CacheEntryInfo, error) {
+	ret := []*CacheEntryInfo{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {store.cfg.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := store.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("object store list: %v", err)
+		}
+		for _, obj := range result.Contents {
+			urlStr, err := url.QueryUnescape(strings.TrimPrefix(obj.Key, store.cfg.Prefix))
+			if err != nil {
+				continue // not one of ours
+			}
+			ret = append(ret, &CacheEntryInfo{URL: urlStr, Size: obj.Size, ModTime: obj.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].URL < ret[j].URL })
+	return ret, nil
+}
+
+func (store *ObjectStore) Delete(urlStr string) error {
+	// S3 answers 204 whether or not the object existed; Stat first to honor the contract
+	if _, err := store.Stat(urlStr); err != nil {
+		return err
+	}
+	resp, err := store.do(http.MethodDelete, store.objectName(urlStr), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// ////////////////////////////////////////////////////////////////////////
+// AWS Signature Version 4
+// ////////////////////////////////////////////////////////////////////////
+
+// awsURIEncode encodes everything but unreserved characters (and '/' unless encodeSlash), as
+// SigV4 requires
+func awsURIEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	parts := []string{}
+	for k, values := range query {
+		for _, v := range values {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = io.WriteString(h, data)
+	return h.Sum(nil)
+}
+
+// sign adds the SigV4 headers to a request. Unsigned when there are no credentials.
+func (store *ObjectStore) sign(req *http.Request, body []byte, now time.Time) {
+	if store.cfg.AccessKey == "" || store.cfg.SecretKey == "" {
+		return
+	}
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hashContent(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Sign host and every x-amz-* header
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + store.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashContent([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+store.cfg.SecretKey), date)
+	key = hmacSHA256(key, store.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.cfg.AccessKey, scope, signedHeaders, signature))
+}
@@ -0,0 +1,111 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Parsing manifests of any kind
+// ////////////////////////////////////////////////////////////////////////
+
+// A linter or converter handed a directory of manifest files should not need to know which
+// ReadXxx function each one wants, nor trust file names that were never meant to be parsed.
+// ParseAny tells the kind from the content alone (see SniffManifestKind) and returns the typed
+// manifest in an AnyManifest, of which exactly the field for Kind is set.
+
+// AnyManifest holds a manifest of any kind; the field named after Kind is set, the others nil
+type AnyManifest struct {
+	Kind         ManifestKind
+	Super        *SuperManifest
+	Boards       *Boards
+	Apps         *Apps
+	Middleware   *Middleware
+	Dependencies *Dependencies
+	Capabilities *BSPCapabilitiesManifest
+}
+
+// Manifest returns the manifest that is set, as an any, or nil
+func (m *AnyManifest) Manifest() any {
+	switch m.Kind {
+	case KindSuper:
+		return m.Super
+	case KindBoards:
+		return m.Boards
+	case KindApps:
+		return m.Apps
+	case KindMiddleware:
+		return m.Middleware
+	case KindDependencies:
+		return m.Dependencies
+	case KindCapabilities:
+		return m.Capabilities
+	}
+	return nil
+}
+
+// ParseAny parses a manifest of any kind, told by its root element or JSON shape. Content that
+// is no manifest, or does not parse as the kind it claims to be, is an error.
+func ParseAny(data []byte) (*AnyManifest, error) {
+	kind := SniffManifestKind("", data)
+	if kind == KindUnknown {
+		return nil, fmt.Errorf("cannot tell what kind of manifest this is")
+	}
+	return parseAnyAs(kind, data, newIngestConfig(nil), &IngestReport{}, "")
+}
+
+// ReadManifestFile reads and parses a manifest file of any kind. When the content does not tell
+// the kind, the file name does (see ManifestKindOf).
+func ReadManifestFile(filename string) (*AnyManifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	kind := SniffManifestKind(filename, data)
+	if kind == KindUnknown {
+		return nil, fmt.Errorf("%s: cannot tell what kind of manifest this is", filename)
+	}
+	m, err := parseAnyAs(kind, data, newIngestConfig(nil), &IngestReport{}, filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return m, nil
+}
+
+// parseAnyAs parses data as a manifest of the given kind, verifying it as ingestion does
+func parseAnyAs(kind ManifestKind, data []byte, cfg *ingestConfig, report *IngestReport, urlStr string) (*AnyManifest, error) {
+	m := &AnyManifest{Kind: kind}
+	var err error
+	switch kind {
+	case KindSuper:
+		m.Super, err = UnmarshalManifest(data, nil, parser[SuperManifest](cfg, report, urlStr))
+	case KindBoards:
+		m.Boards, err = UnmarshalManifest(data, nil, parser[Boards](cfg, report, urlStr))
+	case KindApps:
+		m.Apps, err = UnmarshalManifest(data, nil, parser[Apps](cfg, report, urlStr))
+	case KindMiddleware:
+		m.Middleware, err = UnmarshalManifest(data, nil, parser[Middleware](cfg, report, urlStr))
+	case KindDependencies:
+		m.Dependencies, err = UnmarshalManifest(data, nil, parser[Dependencies](cfg, report, urlStr))
+	case KindCapabilities:
+		m.Capabilities, err = UnmarshalManifest(data, nil, ReadBSPCapabilitiesManifest)
+	default:
+		return nil, fmt.Errorf("unknown manifest kind %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// isCapabilitiesJSON tells whether data is a JSON object with a capabilities list
+func isCapabilitiesJSON(data []byte) bool {
+	var shape struct {
+		Capabilities json.RawMessage `json:"capabilities"`
+	}
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return false
+	}
+	return len(shape.Capabilities) > 0 && shape.Capabilities[0] == '['
+}
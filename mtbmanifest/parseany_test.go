@@ -0,0 +1,53 @@
+package mtbmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAny(t *testing.T) {
+	tests := []struct {
+		data string
+		kind ManifestKind
+	}{
+		{testSuperXML, KindSuper},
+		{testBoardsXML, KindBoards},
+		{testAppsXML, KindApps},
+		{testMiddlewareXML, KindMiddleware},
+		{`<dependencies version="2.0"></dependencies>`, KindDependencies},
+		{`{"capabilities": [{"category": "Chip Families", "name": "PSoC 6", "token": "psoc6"}]}`, KindCapabilities},
+	}
+	for _, tt := range tests {
+		m, err := ParseAny([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.kind, err)
+		}
+		if m.Kind != tt.kind || m.Manifest() == nil {
+			t.Errorf("expected a %s manifest, got %+v", tt.kind, m)
+		}
+	}
+	if m, _ := ParseAny([]byte(testBoardsXML)); m.Apps != nil || len(m.Boards.Boards) != 2 {
+		t.Errorf("expected only the boards to be set, got %+v", m)
+	}
+
+	for _, data := range []string{`<html></html>`, `{"channels": []}`, `not a manifest`, `<apps><app>`} {
+		if m, err := ParseAny([]byte(data)); err == nil {
+			t.Errorf("expected %q to be rejected, got %+v", data, m)
+		}
+	}
+}
+
+func TestReadManifestFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "bsp-capabilities.json")
+	if err := os.WriteFile(file, []byte(`{"capabilities": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := ReadManifestFile(file); err != nil || m.Capabilities == nil {
+		t.Errorf("expected capabilities, got %+v, %v", m, err)
+	}
+	if _, err := ReadManifestFile(filepath.Join(dir, "missing.xml")); err == nil {
+		t.Error("expected a missing file to fail")
+	}
+}
@@ -0,0 +1,239 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Organizational policies
+// ////////////////////////////////////////////////////////////////////////
+
+// Companies often restrict what their projects may use: only some licenses, only code from
+// approved hosts, no libraries with known problems, nothing older than a given release. A
+// Policy states such rules; solutions and exports leave out what it blocks, and the
+// 'policy check' command fails a pipeline whose lockfile breaks it. Rules listed in
+// Policy.FlagOnly report violations without blocking anything.
+
+// PolicyRule names a kind of policy rule
+type PolicyRule string
+
+const (
+	RuleDenied     PolicyRule = "denied"      // the item is on Policy.DeniedIDs
+	RuleHost       PolicyRule = "host"        // the item's repository is not on Policy.AllowedHosts
+	RuleLicense    PolicyRule = "license"     // the item's license is not on Policy.AllowedLicenses
+	RuleMinVersion PolicyRule = "min-version" // the version is older than Policy.MinVersions says
+)
+
+var policyRules = []PolicyRule{RuleDenied, RuleHost, RuleLicense, RuleMinVersion}
+
+// Policy is a set of rules for the boards, apps and middleware projects may use. Empty
+// fields do not restrict anything.
+type Policy struct {
+	// DeniedIDs are item IDs or case-insensitive globs, e.g. "*-deprecated"
+	DeniedIDs []string `json:"deniedIds,omitempty"`
+	// AllowedHosts are the hosts, or globs such as "*.corp.example.com", repositories may be on
+	AllowedHosts []string `json:"allowedHosts,omitempty"`
+	// AllowedLicenses are SPDX identifiers such as "Apache-2.0". The manifests do not say what
+	// license an item has, so Licenses gives them by item ID; items without one are flagged
+	// but not blocked.
+	AllowedLicenses []string          `json:"allowedLicenses,omitempty"`
+	Licenses        map[string]string `json:"licenses,omitempty"`
+	// MinVersions gives the oldest version allowed of items, by ID, e.g. "freertos": "10.5.0"
+	MinVersions map[string]string `json:"minVersions,omitempty"`
+	// FlagOnly lists the rules whose violations are reported but do not block
+	FlagOnly []PolicyRule `json:"flagOnly,omitempty"`
+
+	once        sync.Once
+	compileErr  error
+	minVersions map[string]*SemanticVersion
+	licenses    map[string]string
+}
+
+// PolicyViolation is one way an item breaks a policy
+type PolicyViolation struct {
+	ID      string     `json:"id"`
+	Kind    ItemKind   `json:"kind,omitempty"`
+	Version string     `json:"version,omitempty"`
+	Rule    PolicyRule `json:"rule"`
+	Message string     `json:"message"`
+	// Blocking is clear for violations that are only flagged
+	Blocking bool `json:"blocking"`
+}
+
+func (v *PolicyViolation) String() string {
+	severity := "blocked"
+	if !v.Blocking {
+		severity = "flagged"
+	}
+	item := v.ID
+	if v.Version != "" {
+		item += "@" + v.Version
+	}
+	return fmt.Sprintf("%s: %s (%s, %s)", item, v.Message, v.Rule, severity)
+}
+
+// ReadPolicy parses a policy from JSON and checks its globs, versions and rule names
+func ReadPolicy(jsonData []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(jsonData, p); err != nil {
+		return nil, err
+	}
+	if err := p.compiled(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compiled compiles the policy once, for policies not made by ReadPolicy
+func (p *Policy) compiled() error {
+	p.once.Do(func() { p.compileErr = p.compile() })
+	return p.compileErr
+}
+
+// compile validates the policy and builds its lookup tables
+func (p *Policy) compile() error {
+	for _, pattern := range append(slices.Clone(p.DeniedIDs), p.AllowedHosts...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %v", pattern, err)
+		}
+	}
+	for _, rule := range p.FlagOnly {
+		if !slices.Contains(policyRules, rule) {
+			return fmt.Errorf("unknown policy rule %q", rule)
+		}
+	}
+	p.minVersions = make(map[string]*SemanticVersion, len(p.MinVersions))
+	for id, min := range p.MinVersions {
+		v, err := ParseVersion(min)
+		if err != nil {
+			return fmt.Errorf("minimum version of %s: %v", id, err)
+		}
+		p.minVersions[idKey(id)] = v
+	}
+	p.licenses = make(map[string]string, len(p.Licenses))
+	for id, license := range p.Licenses {
+		p.licenses[idKey(id)] = license
+	}
+	return nil
+}
+
+// blocks reports whether violations of rule block
+func (p *Policy) blocks(rule PolicyRule) bool {
+	return !slices.Contains(p.FlagOnly, rule)
+}
+
+// Check returns the violations of an item with the given repository URI, at version (a commit
+// such as release-v4.1.0). An empty version skips the minimum version rule.
+func (p *Policy) Check(kind ItemKind, id, uri, version string) []*PolicyViolation {
+	if err := p.compiled(); err != nil {
+		return []*PolicyViolation{{ID: id, Kind: kind, Version: version, Message: err.Error(), Blocking: true}}
+	}
+	ret := []*PolicyViolation{}
+	add := func(rule PolicyRule, blocking bool, format string, args ...any) {
+		ret = append(ret, &PolicyViolation{ID: id, Kind: kind, Version: version, Rule: rule,
+			Message: fmt.Sprintf(format, args...), Blocking: blocking && p.blocks(rule)})
+	}
+	for _, pattern := range p.DeniedIDs {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(id)); ok {
+			add(RuleDenied, true, "denied by %q", pattern)
+			break
+		}
+	}
+	if len(p.AllowedHosts) > 0 && uri != "" {
+		host := ""
+		if u, err := url.Parse(uri); err == nil {
+			host = strings.ToLower(u.Hostname())
+		}
+		allowed := false
+		for _, pattern := range p.AllowedHosts {
+			if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			add(RuleHost, true, "repository host %q is not allowed", host)
+		}
+	}
+	if len(p.AllowedLicenses) > 0 {
+		if license, ok := p.licenses[idKey(id)]; !ok {
+			add(RuleLicense, false, "license unknown")
+		} else if !containsFold(p.AllowedLicenses, license) {
+			add(RuleLicense, true, "license %s is not allowed", license)
+		}
+	}
+	if min, ok := p.minVersions[idKey(id)]; ok && version != "" {
+		if v, err := ParseVersion(version); err != nil {
+			add(RuleMinVersion, false, "cannot tell whether %s is at least %s", version, min.Raw)
+		} else if CompareStrict(v, min) < 0 {
+			add(RuleMinVersion, true, "older than the minimum %s", min.Raw)
+		}
+	}
+	return ret
+}
+
+// Allows reports whether an item at version breaks no blocking rule
+func (p *Policy) Allows(kind ItemKind, id, uri, version string) bool {
+	return !HasBlockingViolations(p.Check(kind, id, uri, version))
+}
+
+// HasBlockingViolations reports whether any of the violations blocks
+func HasBlockingViolations(violations []*PolicyViolation) bool {
+	return slices.ContainsFunc(violations, func(v *PolicyViolation) bool { return v.Blocking })
+}
+
+// itemSource returns the kind, repository URI and version commits of the board, app or
+// middleware with the given ID
+func itemSource(sm SuperManifestIF, id string) (ItemKind, string, []string, bool) {
+	if b, ok := sm.GetBoard(id); ok {
+		return ItemKindBoard, b.BoardURI, b.VersionCommits(), true
+	}
+	if a, ok := sm.GetApp(id); ok {
+		return ItemKindApp, a.URI, a.VersionCommits(), true
+	}
+	if mw, ok := sm.GetMiddleware(id); ok {
+		return ItemKindMiddleware, mw.URI, mw.VersionCommits(), true
+	}
+	return "", "", nil, false
+}
+
+// CheckItem returns the violations of the board, app or middleware with the given ID at
+// version. An empty version checks the newest listed.
+func (p *Policy) CheckItem(sm SuperManifestIF, id, version string) ([]*PolicyViolation, error) {
+	kind, uri, commits, ok := itemSource(sm, id)
+	if !ok {
+		return nil, fmt.Errorf("%s not found", id)
+	}
+	if version == "" {
+		version = newestCommit(commits)
+	}
+	return p.Check(kind, id, uri, version), nil
+}
+
+// CheckLockfile returns the violations of the entries of a lockfile
+func (p *Policy) CheckLockfile(lf *Lockfile) []*PolicyViolation {
+	ret := []*PolicyViolation{}
+	for _, e := range lf.Entries {
+		ret = append(ret, p.Check(e.Kind, e.ID, e.Repo, e.Ref)...)
+	}
+	return ret
+}
+
+// CheckTree returns the violations of every item of the tree at its newest version, in board,
+// app and middleware ID order
+func (p *Policy) CheckTree(sm SuperManifestIF) []*PolicyViolation {
+	ret := []*PolicyViolation{}
+	for _, ids := range [][]string{sm.GetBoardIDs(), sm.GetAppIDs(), sm.GetMiddlewareIDs()} {
+		for _, id := range ids {
+			violations, _ := p.CheckItem(sm, id, "")
+			ret = append(ret, violations...)
+		}
+	}
+	return ret
+}
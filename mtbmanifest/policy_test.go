@@ -0,0 +1,78 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	if _, err := ReadPolicy([]byte(`{"minVersions": {"freertos": "latest"}}`)); err == nil {
+		t.Error("expected a minimum version without a number to be refused")
+	}
+	if _, err := ReadPolicy([]byte(`{"flagOnly": ["nonsense"]}`)); err == nil {
+		t.Error("expected an unknown rule to be refused")
+	}
+
+	p, err := ReadPolicy([]byte(`{
+	  "deniedIds": ["mtb-example-*-tcp-*"],
+	  "allowedHosts": ["github.com"],
+	  "allowedLicenses": ["Apache-2.0"],
+	  "licenses": {"freertos": "MIT", "wifi-connection-manager": "Apache-2.0"},
+	  "minVersions": {"freertos": "10.5.0"},
+	  "flagOnly": ["license"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := func(violations []*PolicyViolation) map[PolicyRule]bool {
+		ret := map[PolicyRule]bool{}
+		for _, v := range violations {
+			ret[v.Rule] = v.Blocking
+		}
+		return ret
+	}
+
+	got := rules(p.Check(ItemKindMiddleware, "freertos", "https://github.com/Infineon/freertos", "release-v10.4.3"))
+	if blocking, ok := got[RuleMinVersion]; !ok || !blocking {
+		t.Errorf("expected 10.4.3 to be blocked as too old, got %v", got)
+	}
+	if blocking, ok := got[RuleLicense]; !ok || blocking {
+		t.Errorf("expected the MIT license to be flagged only, got %v", got)
+	}
+	if got := p.Check(ItemKindMiddleware, "freertos", "https://github.com/Infineon/freertos", "latest-v10.X"); HasBlockingViolations(got) {
+		t.Errorf("latest-v10.X covers 10.5.0, got %v", got)
+	}
+	if p.Allows(ItemKindMiddleware, "wifi-connection-manager", "https://gitlab.example.com/wcm", "") {
+		t.Error("expected a repository on another host to be blocked")
+	}
+	if p.Allows(ItemKindApp, "MTB-EXAMPLE-WIFI-TCP-CLIENT", "https://github.com/x", "") {
+		t.Error("expected the denied ID to be blocked whatever its case")
+	}
+
+	sm := newTestSuperManifest(t)
+	violations := p.CheckTree(sm)
+	denied := 0
+	for _, v := range violations {
+		if v.Rule == RuleDenied {
+			denied++
+			if v.ID != "mtb-example-wifi-tcp-client" || v.Kind != ItemKindApp || v.Version != "latest-v3.X" {
+				t.Errorf("unexpected violation %v", v)
+			}
+		}
+	}
+	if denied != 1 {
+		t.Errorf("expected one denied item, got %v", violations)
+	}
+	if _, err := p.CheckItem(sm, "no-such-item", ""); err == nil {
+		t.Error("expected an unknown item to fail")
+	}
+
+	lf := &Lockfile{Entries: []*LockEntry{{ID: "freertos", Kind: ItemKindMiddleware, Repo: "https://github.com/Infineon/freertos", Ref: "release-v10.0.0"}}}
+	if !HasBlockingViolations(p.CheckLockfile(lf)) {
+		t.Error("expected the locked freertos to be too old")
+	}
+
+	solutions := FindSolutions(sm, "wifi", &SolutionOptions{Policy: p})
+	if len(solutions) != 1 || len(solutions[0].AppIDs) != 0 {
+		t.Errorf("expected the denied example to be left out, got %+v", solutions)
+	}
+}
@@ -0,0 +1,215 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Project creation from code examples
+// ////////////////////////////////////////////////////////////////////////
+
+// A code example repository is a template: its files carry markers such as $$APPNAME$$ or
+// $$ASSET_REPO$$ and its Makefile names the BSP it was written for. Project-creator turns a
+// downloaded example into a project by filling in the markers and pointing APPNAME and TARGET
+// in the Makefile at the new project. CreateProject does the same.
+
+// templateMarkerRegex matches a template marker such as $$ASSET_REPO$$
+var templateMarkerRegex = regexp.MustCompile(`\$\$([A-Z][A-Z0-9_]*)\$\$`)
+
+// Files larger than this are not searched for markers
+const maxTemplateFileSize = 4 << 20
+
+// ProjectSettings are what a new project is made of
+type ProjectSettings struct {
+	// AppName names the project. Default the name of the project directory.
+	AppName string
+	// BSP is the board the project targets, e.g. CY8CKIT-062S2-43012. Empty keeps the
+	// example's default.
+	BSP string
+	// Vars give values to other markers, e.g. "ASSET_REPO": "../mtb_shared". Markers without
+	// a value are left in place for the build tools.
+	Vars map[string]string
+}
+
+// vars returns the value of every marker the settings fill in
+func (ps *ProjectSettings) vars() map[string]string {
+	ret := make(map[string]string)
+	for k, v := range ps.Vars {
+		ret[k] = v
+	}
+	if ps.AppName != "" {
+		ret["APPNAME"], ret["APP_NAME"] = ps.AppName, ps.AppName
+	}
+	if ps.BSP != "" {
+		ret["TARGET"], ret["BSP"] = ps.BSP, ps.BSP
+	}
+	return ret
+}
+
+// TemplateResult tells what filling in a template did
+type TemplateResult struct {
+	// Substituted maps each marker that was filled in to the files it was in
+	Substituted map[string][]string `json:"substituted"`
+	// Unresolved maps each marker without a value to the files it is in
+	Unresolved map[string][]string `json:"unresolved"`
+	// Makefile is the Makefile whose APPNAME and TARGET were set, if any
+	Makefile string `json:"makefile,omitempty"`
+}
+
+// walkTemplateFiles calls fn with the relative path and content of every text file in dir,
+// skipping .git and files that look binary
+func walkTemplateFiles(dir string, fn func(rel, path string, data []byte, mode fs.FileMode) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxTemplateFileSize {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		return fn(filepath.ToSlash(rel), path, data, info.Mode())
+	})
+}
+
+// FindTemplateVariables returns the markers used in the files of dir, each with the files
+// it appears in
+func FindTemplateVariables(dir string) (map[string][]string, error) {
+	ret := make(map[string][]string)
+	err := walkTemplateFiles(dir, func(rel, _ string, data []byte, _ fs.FileMode) error {
+		seen := make(map[string]bool)
+		for _, m := range templateMarkerRegex.FindAllSubmatch(data, -1) {
+			if name := string(m[1]); !seen[name] {
+				seen[name] = true
+				ret[name] = append(ret[name], rel)
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// makefileVarRegex matches the assignment of APPNAME or TARGET in a Makefile
+var makefileVarRegex = regexp.MustCompile(`(?m)^(APPNAME|TARGET)([ \t]*[?:]?=[ \t]*)[^\r\n]*`)
+
+// setMakefileVars points APPNAME and TARGET in a Makefile at the project
+func setMakefileVars(data []byte, ps *ProjectSettings) []byte {
+	return makefileVarRegex.ReplaceAllFunc(data, func(line []byte) []byte {
+		m := makefileVarRegex.FindSubmatch(line)
+		value := ps.AppName
+		if string(m[1]) == "TARGET" {
+			value = ps.BSP
+		}
+		if value == "" {
+			return line
+		}
+		return []byte(string(m[1]) + string(m[2]) + value)
+	})
+}
+
+// SubstituteTemplateVariables fills in the markers of the files in dir from the settings
+// and sets APPNAME and TARGET in the top-level Makefile
+func SubstituteTemplateVariables(dir string, ps *ProjectSettings) (*TemplateResult, error) {
+	vars := ps.vars()
+	result := &TemplateResult{Substituted: map[string][]string{}, Unresolved: map[string][]string{}}
+	err := walkTemplateFiles(dir, func(rel, path string, data []byte, mode fs.FileMode) error {
+		seen := make(map[string]bool)
+		out := templateMarkerRegex.ReplaceAllFunc(data, func(marker []byte) []byte {
+			name := string(marker[2 : len(marker)-2])
+			value, ok := vars[name]
+			if !seen[name] {
+				seen[name] = true
+				if ok {
+					result.Substituted[name] = append(result.Substituted[name], rel)
+				} else {
+					result.Unresolved[name] = append(result.Unresolved[name], rel)
+				}
+			}
+			if !ok {
+				return marker
+			}
+			return []byte(value)
+		})
+		if rel == "Makefile" {
+			out = setMakefileVars(out, ps)
+			result.Makefile = rel
+		}
+		if bytes.Equal(out, data) {
+			return nil
+		}
+		return os.WriteFile(path, out, mode.Perm())
+	})
+	return result, err
+}
+
+// CreateProject fetches a version of a code example into dir, which must not exist or be
+// empty, and turns it into a project with the given settings. An empty version means the
+// newest listed.
+func CreateProject(sm SuperManifestIF, g *GitFetcher, appID, version, dir string, ps *ProjectSettings) (*TemplateResult, error) {
+	if _, ok := sm.GetApp(appID); !ok {
+		return nil, fmt.Errorf("code example %s not found", appID)
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+	if ps.BSP != "" {
+		if _, ok := sm.GetBoard(ps.BSP); !ok {
+			return nil, fmt.Errorf("board %s not found", ps.BSP)
+		}
+	}
+	src, err := AssetSource(sm, appID, version)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := g.Fetch(src, dir); err != nil {
+		return nil, err
+	}
+	settings := *ps
+	if settings.AppName == "" {
+		abs, _ := filepath.Abs(dir)
+		settings.AppName = filepath.Base(abs)
+	}
+	return SubstituteTemplateVariables(dir, &settings)
+}
+
+// sortedMarkers returns the marker names of m in order
+func sortedMarkers(m map[string][]string) []string {
+	ret := make([]string, 0, len(m))
+	for name := range m {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// String lists the markers filled in and left, one per line
+func (r *TemplateResult) String() string {
+	var sb strings.Builder
+	for _, name := range sortedMarkers(r.Substituted) {
+		fmt.Fprintf(&sb, "set  $$%s$$ in %s\n", name, strings.Join(r.Substituted[name], ", "))
+	}
+	for _, name := range sortedMarkers(r.Unresolved) {
+		fmt.Fprintf(&sb, "left $$%s$$ in %s\n", name, strings.Join(r.Unresolved[name], ", "))
+	}
+	return sb.String()
+}
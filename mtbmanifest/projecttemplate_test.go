@@ -0,0 +1,90 @@
+package mtbmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubstituteTemplateVariables(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Makefile":         "APPNAME=mtb-example-hal-hello-world\nTARGET?=CY8CKIT-062S2-43012\nCORE=CM4\n",
+		"README.md":        "# $$APPNAME$$\nBuilt for $$TARGET$$.\n",
+		"deps/mtb-hal.mtb": "https://github.com/Infineon/mtb-hal-cat1#latest-v2.X#$$ASSET_REPO$$/mtb-hal-cat1/latest-v2\n",
+		".git/config":      "$$APPNAME$$",
+		"images/logo.bin":  "\x00$$APPNAME$$",
+		"source/main.c":    "/* no markers */\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := FindTemplateVariables(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 || len(found["APPNAME"]) != 1 || found["ASSET_REPO"][0] != "deps/mtb-hal.mtb" {
+		t.Fatalf("unexpected markers %v", found)
+	}
+
+	result, err := SubstituteTemplateVariables(dir, &ProjectSettings{AppName: "blinky", BSP: "CY8CKIT-149"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Substituted) != 2 || len(result.Unresolved["ASSET_REPO"]) != 1 || result.Makefile != "Makefile" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read("Makefile"); got != "APPNAME=blinky\nTARGET?=CY8CKIT-149\nCORE=CM4\n" {
+		t.Errorf("unexpected Makefile %q", got)
+	}
+	if got := read("README.md"); got != "# blinky\nBuilt for CY8CKIT-149.\n" {
+		t.Errorf("unexpected README %q", got)
+	}
+	if !strings.Contains(read("deps/mtb-hal.mtb"), "$$ASSET_REPO$$") || read(".git/config") != "$$APPNAME$$" {
+		t.Error("markers without a value and files under .git must be left alone")
+	}
+	if !strings.Contains(result.String(), "left $$ASSET_REPO$$ in deps/mtb-hal.mtb") {
+		t.Errorf("unexpected summary %q", result.String())
+	}
+}
+
+func TestCreateProject(t *testing.T) {
+	repo := newTestGitRepo(t)
+	if err := os.WriteFile(filepath.Join(repo, "Makefile"), []byte("APPNAME=$$APPNAME$$\nTARGET=CY8CKIT-062S2-43012\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", "Makefile")
+	runGit(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "makefile")
+	runGit(t, repo, "tag", "latest-v4.X")
+
+	sm := newTestSuperManifest(t)
+	app, _ := sm.GetApp("mtb-example-hal-hello-world")
+	app.URI = "file://" + repo
+	dir := filepath.Join(t.TempDir(), "blinky")
+	g := NewGitFetcher(WithGitCacheDir(t.TempDir()))
+	if _, err := CreateProject(sm, g, app.ID, "", dir, &ProjectSettings{BSP: "NO-SUCH-KIT"}); err == nil {
+		t.Error("expected an error for an unknown BSP")
+	}
+	if _, err := CreateProject(sm, g, app.ID, "", dir, &ProjectSettings{BSP: "CY8CKIT-149"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil || string(data) != "APPNAME=blinky\nTARGET=CY8CKIT-149\n" {
+		t.Errorf("unexpected Makefile %q, %v", data, err)
+	}
+}
@@ -0,0 +1,101 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Provenance
+// ////////////////////////////////////////////////////////////////////////
+
+// "Where did this entry come from, and when?" Each board, app and middleware manifest ingested
+// remembers the super manifest that listed it, its own URL, when its cached copy was fetched
+// and a hash of the content read; Provenance() on an item returns them, and the item's JSON
+// includes them. Trees not ingested from URLs, e.g. assembled by hand, have no provenance.
+
+// Provenance says where an item was read from
+type Provenance struct {
+	// SuperManifestURL is the super manifest that listed the item's manifest
+	SuperManifestURL string `json:"superManifestUrl"`
+	// ManifestURL is the board, app or middleware manifest the item is in
+	ManifestURL string `json:"manifestUrl"`
+	// FetchedAt is when the manifest was downloaded, which may be long before it was ingested
+	FetchedAt time.Time `json:"fetchedAt,omitempty"`
+	// SHA256 is the hex SHA-256 of the manifest content
+	SHA256 string `json:"sha256"`
+	// Channel is the channel the item was ingested from, if through a ChannelSet
+	Channel string `json:"channel,omitempty"`
+}
+
+// newProvenance describes a manifest read through cache for the super manifest at superURL
+func newProvenance(cache *ManifestCache, superURL, urlStr string, data []byte) *Provenance {
+	ret := &Provenance{SuperManifestURL: superURL, ManifestURL: urlStr, SHA256: hashContent(data)}
+	if info, err := cache.Store().Stat(urlStr); err == nil {
+		ret.FetchedAt = info.ModTime.UTC()
+	}
+	return ret
+}
+
+// withChannel returns p for an item of the given channel
+func (p *Provenance) withChannel(channel string) *Provenance {
+	if p == nil || channel == "" {
+		return p
+	}
+	ret := *p
+	ret.Channel = channel
+	return &ret
+}
+
+// Provenance returns where the board was read from, or nil if that is not known
+func (b *Board) Provenance() *Provenance {
+	if b.Origin == nil {
+		return nil
+	}
+	return b.Origin.provenance.withChannel(b.Channel)
+}
+
+// Provenance returns where the app was read from, or nil if that is not known
+func (a *App) Provenance() *Provenance {
+	if a.Origin == nil {
+		return nil
+	}
+	return a.Origin.provenance.withChannel(a.Channel)
+}
+
+// Provenance returns where the middleware item was read from, or nil if that is not known
+func (mw *MiddlewareItem) Provenance() *Provenance {
+	if mw.Origin == nil {
+		return nil
+	}
+	return mw.Origin.provenance.withChannel(mw.Channel)
+}
+
+// MarshalJSON adds the board's provenance to its fields
+func (b *Board) MarshalJSON() ([]byte, error) {
+	type PlainBoard Board // without this method
+	return json.Marshal(struct {
+		*PlainBoard
+		Provenance *Provenance `json:"provenance,omitempty"`
+	}{(*PlainBoard)(b), b.Provenance()})
+}
+
+// MarshalJSON adds the app's provenance, difficulty and size to its fields
+func (a *App) MarshalJSON() ([]byte, error) {
+	type PlainApp App // without this method
+	return json.Marshal(struct {
+		*PlainApp
+		Provenance *Provenance `json:"provenance,omitempty"`
+		Difficulty Difficulty  `json:"difficulty"`
+		Size       *AppSize    `json:"size"`
+	}{(*PlainApp)(a), a.Provenance(), a.Difficulty(), a.Size()})
+}
+
+// MarshalJSON adds the middleware item's provenance to its fields
+func (mw *MiddlewareItem) MarshalJSON() ([]byte, error) {
+	type PlainMiddlewareItem MiddlewareItem // without this method
+	return json.Marshal(struct {
+		*PlainMiddlewareItem
+		Provenance *Provenance `json:"provenance,omitempty"`
+	}{(*PlainMiddlewareItem)(mw), mw.Provenance()})
+}
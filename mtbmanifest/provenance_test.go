@@ -0,0 +1,61 @@
+package mtbmanifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvenance(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	dir := t.TempDir()
+	seed := NewManifestCache(WithDir(dir), WithCacheTTL(time.Hour))
+	defer seed.Close()
+	for u, data := range map[string]string{
+		superURL:                             testSuperXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	} {
+		if err := seed.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm, err := NewSuperManifestFromURL(superURL, WithCacheDir(dir), WithOffline())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	board, _ := sm.GetBoard("CY8CKIT-149")
+	p := board.Provenance()
+	sum := sha256.Sum256([]byte(testBoardsXML))
+	if p == nil || p.SuperManifestURL != superURL || p.ManifestURL != "https://example.com/boards.xml" ||
+		p.SHA256 != hex.EncodeToString(sum[:]) || p.FetchedAt.IsZero() {
+		t.Fatalf("unexpected board provenance %+v", p)
+	}
+	app, _ := sm.GetApp("mtb-example-hal-hello-world")
+	if p := app.Provenance(); p == nil || p.ManifestURL != "https://example.com/apps.xml" {
+		t.Fatalf("unexpected app provenance %+v", p)
+	}
+	mw, _ := sm.GetMiddleware("freertos")
+	if p := mw.Provenance(); p == nil || p.ManifestURL != "https://example.com/middleware.xml" {
+		t.Fatalf("unexpected middleware provenance %+v", p)
+	}
+
+	data, err := json.Marshal(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"provenance":{"superManifestUrl":"`+superURL) || !strings.Contains(string(data), `"ID":"CY8CKIT-149"`) {
+		t.Fatalf("expected the provenance in the JSON, got %s", data)
+	}
+
+	// Trees assembled in memory do not know where their items came from
+	handmade, _ := newTestSuperManifest(t).GetBoard("CY8CKIT-149")
+	if handmade.Provenance() != nil {
+		t.Fatal("expected no provenance")
+	}
+}
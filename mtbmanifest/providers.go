@@ -0,0 +1,70 @@
+package mtbmanifest
+
+// ////////////////////////////////////////////////////////////////////////
+// Dependency and capability providers
+// ////////////////////////////////////////////////////////////////////////
+
+// Boards, apps and middleware always come from the manifests the super manifest lists, but the
+// BSP dependencies and capabilities they refer to are auxiliary data that can live elsewhere:
+// a local database, a snapshot, or a corporate service. A provider supplies them for the
+// dependency-url and capability-url of a manifest, which it may treat as a plain key. Without
+// a provider they are fetched from those URLs like every other manifest.
+
+// DependencyProvider supplies the dependencies manifest referred to by a dependency-url
+type DependencyProvider interface {
+	Dependencies(urlStr string) (*Dependencies, error)
+}
+
+// CapabilityProvider supplies the BSP capabilities manifest referred to by a capability-url
+type CapabilityProvider interface {
+	Capabilities(urlStr string) (*BSPCapabilitiesManifest, error)
+}
+
+// DependencyProviderFunc adapts a function to DependencyProvider
+type DependencyProviderFunc func(urlStr string) (*Dependencies, error)
+
+func (fn DependencyProviderFunc) Dependencies(urlStr string) (*Dependencies, error) {
+	return fn(urlStr)
+}
+
+// CapabilityProviderFunc adapts a function to CapabilityProvider
+type CapabilityProviderFunc func(urlStr string) (*BSPCapabilitiesManifest, error)
+
+func (fn CapabilityProviderFunc) Capabilities(urlStr string) (*BSPCapabilitiesManifest, error) {
+	return fn(urlStr)
+}
+
+// URLProvider reads dependencies and capabilities from their URLs through a cache, as
+// ingestion does by default. It is meant as the fallback of other providers.
+type URLProvider struct {
+	cache *ManifestCache
+}
+
+// NewURLProvider returns a provider reading through cache
+func NewURLProvider(cache *ManifestCache) *URLProvider {
+	return &URLProvider{cache: cache}
+}
+
+func (p *URLProvider) Dependencies(urlStr string) (*Dependencies, error) {
+	data, err := p.cache.Get(urlStr)
+	return UnmarshalManifest(data, err, ReadDependenciesManifest)
+}
+
+func (p *URLProvider) Capabilities(urlStr string) (*BSPCapabilitiesManifest, error) {
+	data, err := p.cache.Get(urlStr)
+	return UnmarshalManifest(data, err, ReadBSPCapabilitiesManifest)
+}
+
+// WithDependencyProvider gets the dependencies manifests from p instead of their URLs
+func WithDependencyProvider(p DependencyProvider) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.dependencyProvider = p
+	}
+}
+
+// WithCapabilityProvider gets the BSP capabilities manifests from p instead of their URLs
+func WithCapabilityProvider(p CapabilityProvider) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.capabilityProvider = p
+	}
+}
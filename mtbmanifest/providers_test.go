@@ -0,0 +1,57 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDependencyProvider(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	const depsURL = "https://example.com/deps.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	superXML := strings.Replace(testSuperXML, "<board-manifest>",
+		`<board-manifest dependency-url="`+depsURL+`" capability-url="https://example.com/caps.xml">`, 1)
+	for u, data := range map[string]string{
+		superURL:                             superXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Neither manifest is cached, so only the providers can supply them
+	requested := []string{}
+	deps := DependencyProviderFunc(func(urlStr string) (*Dependencies, error) {
+		requested = append(requested, urlStr)
+		return &Dependencies{Dependers: []*Depender{{ID: "CY8CKIT-149"}}}, nil
+	})
+	caps := CapabilityProviderFunc(func(urlStr string) (*BSPCapabilitiesManifest, error) {
+		return nil, errors.New("service unavailable")
+	})
+	steps := 0
+	sm, err := NewSuperManifestFromURL(superURL, withIngestCache(cache), WithOffline(),
+		WithDependencyProvider(deps), WithCapabilityProvider(caps),
+		WithProgress(func(done, total int, urlStr string) { steps = total }))
+	if err != nil {
+		t.Fatalf("failed to ingest: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != depsURL {
+		t.Errorf("unexpected provider requests %v", requested)
+	}
+	if steps != 6 {
+		t.Errorf("progress should count the provider calls, got %d steps", steps)
+	}
+	c := sm.Completeness()
+	if len(c.Failed) != 1 || c.Failed[0].Kind != KindCapabilities || !strings.Contains(c.Failed[0].Error, "service unavailable") {
+		t.Errorf("unexpected completeness %+v", c)
+	}
+	if board, _ := sm.GetBoard("CY8CKIT-149"); sm.GetDependencies(depsURL) == nil || board.Dependencies == nil {
+		t.Error("dependencies from the provider are not linked")
+	}
+}
@@ -0,0 +1,101 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Radio awareness
+// ////////////////////////////////////////////////////////////////////////
+
+// Boards list their connectivity chips separately from the MCU, e.g. CYW43012 next to a
+// PSoC 6. The part number tells what the chip provides, so the wifi and bt capability tokens
+// a board claims can be checked against the chips it lists.
+
+// Capability tokens of radios
+const (
+	RadioWiFi = "wifi"
+	RadioBT   = "bt"
+)
+
+// radioPrefixes maps part number prefixes to what the chips provide, longest first where
+// they overlap
+var radioPrefixes = []struct {
+	prefix string
+	caps   []string
+}{
+	{"CYW20829", []string{RadioBT}}, {"CYW89829", []string{RadioBT}},
+	{"CYW43907", []string{RadioWiFi}}, {"CYW54907", []string{RadioWiFi}},
+	{"CYW4390", []string{RadioWiFi}},
+	{"CYW43", []string{RadioWiFi, RadioBT}}, // CYW43012, CYW43439, CYW4343W, ...
+	{"CYW4373", []string{RadioWiFi, RadioBT}},
+	{"CYW55", []string{RadioWiFi, RadioBT}}, // CYW55513, CYW55572
+	{"CYW20", []string{RadioBT}},            // CYW20719, CYW20819, ...
+	{"CYW30", []string{RadioBT}},
+	{"CYW89", []string{RadioBT}},
+}
+
+// RadioCapabilitiesOf returns the radio capability tokens a chip provides, e.g. wifi and bt
+// for CYW43439. Parts that are not radios return none.
+func RadioCapabilitiesOf(part string) []string {
+	part = strings.ToUpper(part)
+	for _, rp := range radioPrefixes {
+		if strings.HasPrefix(part, rp.prefix) {
+			return rp.caps
+		}
+	}
+	return []string{}
+}
+
+// RadioCapabilities returns the radio capability tokens the chips of the board provide. MCUs
+// count as well, since some have a radio built in (CYW20829).
+func (b *Board) RadioCapabilities() []string {
+	ret := []string{}
+	for _, part := range append(slices.Clone(b.Chips.Radio), b.Chips.MCU...) {
+		for _, c := range RadioCapabilitiesOf(part) {
+			if !slices.Contains(ret, c) {
+				ret = append(ret, c)
+			}
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// RadioIssue is a mismatch between the radio capabilities a board claims and its chips
+type RadioIssue struct {
+	BoardID    string `json:"boardId"`
+	Capability string `json:"capability"`
+	// Claimed is set when the board claims the capability without a chip providing it, and
+	// clear when a chip provides it but the board does not claim it
+	Claimed bool `json:"claimed"`
+}
+
+func (issue *RadioIssue) String() string {
+	if issue.Claimed {
+		return fmt.Sprintf("%s claims %s but lists no chip providing it", issue.BoardID, issue.Capability)
+	}
+	return fmt.Sprintf("%s lists a chip providing %s but does not claim it", issue.BoardID, issue.Capability)
+}
+
+// CheckRadios compares the wifi and bt capabilities each board claims with the chips it
+// lists, in board ID order
+func CheckRadios(sm SuperManifestIF) []*RadioIssue {
+	ret := []*RadioIssue{}
+	for _, id := range sm.GetBoardIDs() {
+		b, ok := sm.GetBoard(id)
+		if !ok {
+			continue
+		}
+		claimed := strings.Fields(b.ProvCapabilities)
+		provided := b.RadioCapabilities()
+		for _, c := range []string{RadioWiFi, RadioBT} {
+			if has, claims := slices.Contains(provided, c), slices.Contains(claimed, c); has != claims {
+				ret = append(ret, &RadioIssue{BoardID: b.ID, Capability: c, Claimed: claims})
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,46 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRadioCapabilities(t *testing.T) {
+	tests := map[string][]string{
+		"CYW43012C0WKWBG": {RadioWiFi, RadioBT},
+		"cyw4343wkubg":    {RadioWiFi, RadioBT},
+		"CYW43907KWBG":    {RadioWiFi},
+		"CYW20819A1KFBG":  {RadioBT},
+		"CY8C624ABZI":     {},
+	}
+	for part, want := range tests {
+		if got := RadioCapabilitiesOf(part); !slices.Equal(got, want) {
+			t.Errorf("%s: expected %v, got %v", part, want, got)
+		}
+	}
+	b := &Board{Chips: Chips{MCU: []string{"CYW20829B0LKML"}}}
+	if got := b.RadioCapabilities(); !slices.Equal(got, []string{RadioBT}) {
+		t.Errorf("expected the built-in radio of the MCU, got %v", got)
+	}
+}
+
+func TestCheckRadios(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	if issues := CheckRadios(sm); len(issues) != 0 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	b, _ := sm.GetBoard("CY8CKIT-149")
+	b.ProvCapabilities += " wifi"
+	b2, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	b2.ProvCapabilities = "cat1 cat1a psoc6 hal wifi"
+	issues := CheckRadios(sm)
+	if len(issues) != 2 || issues[0].BoardID != "CY8CKIT-062S2-43012" || issues[0].Claimed || issues[0].Capability != RadioBT ||
+		!issues[1].Claimed || issues[1].String() != "CY8CKIT-149 claims wifi but lists no chip providing it" {
+		t.Errorf("unexpected issues %v", issues)
+	}
+
+	filter, _ := ParseFilter("connectivity=bt")
+	if !filter.MatchBoard(b2) || filter.MatchBoard(b) {
+		t.Error("connectivity should follow the chips, not the claimed capabilities")
+	}
+}
@@ -0,0 +1,211 @@
+package mtbmanifest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Rate limits
+// ////////////////////////////////////////////////////////////////////////
+
+// GitHub limits how many requests a client may send, and answers 429 (or 403 once the quota
+// is used up) with a Retry-After header or X-RateLimit-* headers telling when to come back.
+// The cache reads those headers from every response: a host whose quota is used up is not
+// sent requests until it resets, and a fetch that is turned away fails right away with a
+// RateLimitError saying when to try again. A ManifestFetcher reschedules such URLs: it gives
+// up its concurrency slot, so URLs of other hosts go ahead, waits for the reset and fetches
+// again, unless the wait is longer than the cache accepts (see WithRateLimitWait). What each
+// host said of its quota is in RateLimits and in the IngestReport.
+
+// defaultRateLimitWait is how long a fetch waits for a rate limit by default
+const defaultRateLimitWait = time.Minute
+
+// rateLimitRetries is how many times a fetcher reschedules a URL
+const rateLimitRetries = 3
+
+// WithRateLimitWait sets the longest a fetch waits for a host's rate limit to reset before it
+// fails; 0 means one minute, less than 0 never waits
+func WithRateLimitWait(d time.Duration) CacheOption {
+	return func(c *ManifestCache) {
+		c.rateLimits.maxWait = d
+	}
+}
+
+// RateLimitError is returned for a request a host turned away, or that was not sent because
+// the host's quota is used up
+type RateLimitError struct {
+	URL string
+	// StatusCode is the status of the response, 0 if the request was not sent
+	StatusCode int
+	// RetryAt is when the host accepts requests again
+	RetryAt time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	status := "quota used up"
+	if e.StatusCode != 0 {
+		status = fmt.Sprintf("http status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s: rate limited (%s), retry at %s", e.URL, status, e.RetryAt.Format(time.RFC3339))
+}
+
+// HostRateLimit is what a host said of its request quota
+type HostRateLimit struct {
+	Host string `json:"host"`
+	// Limit and Remaining are the quota and what is left of it, -1 if the host did not say
+	Limit     int `json:"limit"`
+	Remaining int `json:"remaining"`
+	// Reset is when the quota is renewed, zero if the host did not say
+	Reset time.Time `json:"reset,omitzero"`
+	// Limited counts the requests turned away
+	Limited int `json:"limited"`
+	// Waited is the time fetches spent waiting for the host
+	Waited time.Duration `json:"waited"`
+}
+
+// rateLimiter keeps track of the rate limits of the hosts a cache talks to
+type rateLimiter struct {
+	maxWait time.Duration
+	mu      sync.Mutex
+	hosts   map[string]*HostRateLimit
+	// blocked are the hosts not to send requests to before the given time
+	blocked map[string]time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{hosts: map[string]*HostRateLimit{}, blocked: map[string]time.Time{}}
+}
+
+// host returns the record of a host, creating it. Called with mu held.
+func (r *rateLimiter) host(host string) *HostRateLimit {
+	h, ok := r.hosts[host]
+	if !ok {
+		h = &HostRateLimit{Host: host, Limit: -1, Remaining: -1}
+		r.hosts[host] = h
+	}
+	return h
+}
+
+// check returns a RateLimitError if the host of urlStr is not to be sent requests yet
+func (r *rateLimiter) check(urlStr string) error {
+	host := hostOf(urlStr)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if until, ok := r.blocked[host]; ok {
+		if time.Now().Before(until) {
+			return &RateLimitError{URL: urlStr, RetryAt: until}
+		}
+		delete(r.blocked, host)
+	}
+	return nil
+}
+
+// observe records the rate limit headers of a response, and returns a RateLimitError if the
+// response turned the request away
+func (r *rateLimiter) observe(urlStr string, resp *http.Response) error {
+	now := time.Now()
+	host := hostOf(urlStr)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.host(host)
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+		h.Limit = n
+	}
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		h.Remaining = n
+	}
+	if secs, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		h.Reset = time.Unix(secs, 0)
+	}
+	retryAt, hasRetryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	exhausted := resp.Header.Get("X-RateLimit-Remaining") == "0"
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && (exhausted || hasRetryAfter))
+	switch {
+	case hasRetryAfter:
+	case exhausted && h.Reset.After(now):
+		retryAt = h.Reset
+	case limited:
+		retryAt = now.Add(10 * time.Second) // no hint; back off a little
+	}
+	if exhausted || limited {
+		// Even a successful response can use up the quota: hold the next requests back
+		if retryAt.After(r.blocked[host]) {
+			r.blocked[host] = retryAt
+		}
+	}
+	if !limited {
+		return nil
+	}
+	h.Limited++
+	return &RateLimitError{URL: urlStr, StatusCode: resp.StatusCode, RetryAt: retryAt}
+}
+
+// waited adds to the time spent waiting for the host of urlStr
+func (r *rateLimiter) waited(urlStr string, d time.Duration) {
+	r.mu.Lock()
+	r.host(hostOf(urlStr)).Waited += d
+	r.mu.Unlock()
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or an HTTP date
+func parseRetryAfter(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	if secs, err := strconv.Atoi(s); err == nil && secs >= 0 {
+		return now.Add(time.Duration(secs) * time.Second), true
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// RateLimits returns what the hosts the cache talked to said of their quotas, by host
+func (c *ManifestCache) RateLimits() []*HostRateLimit {
+	c.rateLimits.mu.Lock()
+	defer c.rateLimits.mu.Unlock()
+	ret := []*HostRateLimit{}
+	for _, h := range c.rateLimits.hosts {
+		copied := *h
+		ret = append(ret, &copied)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Host < ret[j].Host })
+	return ret
+}
+
+// waitForRateLimit waits until a rate limited URL may be fetched again, and reports whether it
+// may: not if the wait is longer than the cache accepts, or ctx is done first
+func (c *ManifestCache) waitForRateLimit(ctx context.Context, rl *RateLimitError) bool {
+	maxWait := c.rateLimits.maxWait
+	if maxWait == 0 {
+		maxWait = defaultRateLimitWait
+	}
+	wait := time.Until(rl.RetryAt)
+	if wait > maxWait {
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+	c.log().Infof("Rate limited, fetching %s again in %s\n", rl.URL, wait.Round(time.Second))
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	start := time.Now()
+	defer func() { c.rateLimits.waited(rl.URL, time.Since(start)) }()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimits(t *testing.T) {
+	var limitedOnce atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a.xml" && !limitedOnce.Swap(true) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		_, _ = w.Write([]byte("<ok/>"))
+	}))
+	defer srv.Close()
+
+	cache := NewManifestCache(WithStore(NewMemoryStore()))
+	f := NewManifestFetcher(WithCache(cache), WithMaxConcurrent(1))
+	results := f.FetchAll([]string{srv.URL + "/a.xml", srv.URL + "/b.xml"})
+	for u, r := range results {
+		if _, ok := r.([]byte); !ok {
+			t.Errorf("expected %s fetched after the rate limit, got %v", u, r)
+		}
+	}
+	limits := cache.RateLimits()
+	if len(limits) != 1 || limits[0].Limited != 1 || limits[0].Waited < 500*time.Millisecond ||
+		limits[0].Remaining != 10 || limits[0].Limit != 60 {
+		t.Errorf("expected one limited request and the quota, got %+v", limits)
+	}
+
+	var requests atomic.Int32
+	exhausted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer exhausted.Close()
+	cache = NewManifestCache(WithStore(NewMemoryStore()), WithRateLimitWait(time.Second))
+	f = NewManifestFetcher(WithCache(cache))
+	for _, path := range []string{"/a.xml", "/b.xml"} {
+		results = f.FetchAll([]string{exhausted.URL + path})
+		var rl *RateLimitError
+		if err, _ := results[exhausted.URL+path].(error); !errors.As(err, &rl) || rl.RetryAt.Before(time.Now().Add(50*time.Minute)) {
+			t.Errorf("expected a rate limit error until the reset, got %v", results[exhausted.URL+path])
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected no request sent to an exhausted host, got %d", n)
+	}
+	if w := warningFor("u", &RateLimitError{}); w.Code != WarnRateLimited {
+		t.Errorf("expected a rate-limited warning, got %s", w.Code)
+	}
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if at, ok := parseRetryAfter("Sat, 01 Jun 2024 12:00:30 GMT", now); !ok || at.Sub(now) != 30*time.Second {
+		t.Errorf("parseRetryAfter of a date = %v, %v", at, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("parseRetryAfter accepted garbage")
+	}
+}
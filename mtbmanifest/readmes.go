@@ -0,0 +1,312 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Middleware READMEs
+// ////////////////////////////////////////////////////////////////////////
+
+// The description of a library in the manifest is a line or two, so a search for what a
+// library does rarely finds it by anything but its name. Its README says much more. A
+// ReadmeFetcher downloads the README of each middleware repository on GitHub at its newest
+// version, no faster than one request per interval, and keeps them in a CacheStore for a TTL.
+// Fetched READMEs are recorded with the middleware of the tree they were fetched for (see
+// MiddlewareItem.SetReadme): its search index weighs their text below the description, and
+// catalog exports show a snippet of them.
+
+// DefaultReadmeNames are the repository paths a README is looked for at
+var DefaultReadmeNames = []string{"README.md", "readme.md", "README.MD"}
+
+// Readme is the README of a middleware repository
+type Readme struct {
+	MiddlewareID string `json:"middlewareId"`
+	URL          string `json:"url"`
+	// Snippet is the first paragraph of prose, at most 300 characters
+	Snippet string `json:"snippet"`
+	// Text is the whole README as plain text, without code blocks
+	Text string `json:"-"`
+}
+
+// readmeSnippetLen is the length of Readme.Snippet
+const readmeSnippetLen = 300
+
+// ReadmeFetcher finds, downloads and caches middleware READMEs
+type ReadmeFetcher struct {
+	names    []string
+	store    CacheStore
+	client   *http.Client
+	ttl      time.Duration
+	interval time.Duration
+	rawBase  string
+
+	mu      sync.Mutex
+	next    time.Time       // when the next request may be sent
+	missing map[string]bool // URLs known not to exist, for this fetcher's lifetime
+}
+
+// ReadmeOption configures a ReadmeFetcher
+type ReadmeOption func(*ReadmeFetcher)
+
+// WithReadmeNames sets the repository paths READMEs are looked for at. Default
+// DefaultReadmeNames.
+func WithReadmeNames(paths ...string) ReadmeOption {
+	return func(r *ReadmeFetcher) {
+		r.names = paths
+	}
+}
+
+// WithReadmeStore sets where READMEs are cached. Default files in
+// ~/.modustoolbox/mtbmcp/readmes.
+func WithReadmeStore(store CacheStore) ReadmeOption {
+	return func(r *ReadmeFetcher) {
+		r.store = store
+	}
+}
+
+// WithReadmeClient sets the client READMEs are downloaded with. Default a client with a 30s
+// timeout.
+func WithReadmeClient(client *http.Client) ReadmeOption {
+	return func(r *ReadmeFetcher) {
+		r.client = client
+	}
+}
+
+// WithReadmeTTL sets how long a cached README is used before it is downloaded again. Default
+// 7 days.
+func WithReadmeTTL(ttl time.Duration) ReadmeOption {
+	return func(r *ReadmeFetcher) {
+		r.ttl = ttl
+	}
+}
+
+// WithReadmeInterval sets the least time between two requests, however many READMEs are
+// fetched at once. Default 200ms; 0 means no limit.
+func WithReadmeInterval(interval time.Duration) ReadmeOption {
+	return func(r *ReadmeFetcher) {
+		r.interval = interval
+	}
+}
+
+// NewReadmeFetcher creates a fetcher with the given options
+func NewReadmeFetcher(opts ...ReadmeOption) *ReadmeFetcher {
+	home, _ := os.UserHomeDir()
+	r := &ReadmeFetcher{
+		names:    DefaultReadmeNames,
+		store:    NewFileStore(filepath.Join(home, ".modustoolbox", "mtbmcp", "readmes")),
+		client:   &http.Client{Timeout: 30 * time.Second},
+		ttl:      7 * 24 * time.Hour,
+		interval: 200 * time.Millisecond,
+		rawBase:  "https://raw.githubusercontent.com",
+		missing:  make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// candidateURLs returns the URLs the README of a library may be at, best first
+func (r *ReadmeFetcher) candidateURLs(mw *MiddlewareItem) []string {
+	ownerRepo, ok := githubRepo(mw.URI)
+	if !ok {
+		return nil
+	}
+	ref := newestCommit(mw.VersionCommits())
+	if ref == "" {
+		ref = "master"
+	}
+	ret := []string{}
+	for _, p := range r.names {
+		ret = append(ret, r.rawBase+"/"+ownerRepo+"/"+ref+"/"+p)
+	}
+	return ret
+}
+
+// wait blocks until the next request may be sent
+func (r *ReadmeFetcher) wait() {
+	r.mu.Lock()
+	now := time.Now()
+	at := now
+	if r.next.After(now) {
+		at = r.next
+	}
+	r.next = at.Add(r.interval)
+	r.mu.Unlock()
+	time.Sleep(at.Sub(now))
+}
+
+// download returns the content at urlStr from the store while fresh, or else from the network
+// and then stores it. A stale entry is returned when the network fails.
+func (r *ReadmeFetcher) download(urlStr string) ([]byte, error) {
+	cached, cacheErr := r.store.Get(urlStr)
+	if cacheErr == nil {
+		if info, err := r.store.Stat(urlStr); err == nil && time.Since(info.ModTime) < r.ttl {
+			return cached, nil
+		}
+	}
+	r.mu.Lock()
+	missing := r.missing[urlStr]
+	r.mu.Unlock()
+	if missing {
+		return nil, fmt.Errorf("%s: not found", urlStr)
+	}
+	r.wait()
+	data, err := r.get(urlStr)
+	if err != nil {
+		if cacheErr == nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	_ = r.store.Put(urlStr, data, time.Time{})
+	return data, nil
+}
+
+// get downloads urlStr, remembering URLs that do not exist
+func (r *ReadmeFetcher) get(urlStr string) ([]byte, error) {
+	resp, err := r.client.Get(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound {
+			r.mu.Lock()
+			r.missing[urlStr] = true
+			r.mu.Unlock()
+		}
+		return nil, fmt.Errorf("%s: http status %d", urlStr, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Readme returns the README of a library, or an error if none was found
+func (r *ReadmeFetcher) Readme(mw *MiddlewareItem) (*Readme, error) {
+	var lastErr error = fmt.Errorf("no README source for middleware %s", mw.ID)
+	for _, u := range r.candidateURLs(mw) {
+		data, err := r.download(u)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		text, snippet := markdownText(string(data))
+		return &Readme{MiddlewareID: mw.ID, URL: u, Snippet: snippet, Text: text}, nil
+	}
+	return nil, lastErr
+}
+
+// Readmes fetches the READMEs of the middleware of the tree, records them with the middleware
+// for search and exports (see MiddlewareItem.SetReadme) and returns those found, in middleware
+// ID order. Up to maxConcurrent libraries are looked up at once; 0 means 4.
+func (r *ReadmeFetcher) Readmes(sm SuperManifestIF, maxConcurrent int) []*Readme {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 4
+	}
+	ids := sm.GetMiddlewareIDs()
+	found := make([]*Readme, len(ids))
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, maxConcurrent)
+	for i, id := range ids {
+		mw, ok := sm.GetMiddleware(id)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+			if found[i], _ = r.Readme(mw); found[i] != nil {
+				mw.SetReadme(found[i])
+			}
+		}()
+	}
+	wg.Wait()
+	ret := []*Readme{}
+	for _, readme := range found {
+		if readme != nil {
+			ret = append(ret, readme)
+		}
+	}
+	if tree, ok := sm.(*SuperManifest); ok && len(ret) > 0 {
+		tree.searchIndex.reset() // rebuilt with the READMEs on the next search
+	}
+	return ret
+}
+
+// SetReadme records the README of the library, e.g. one saved from an earlier ReadmeFetcher;
+// nil forgets it. Search indexes of its tree built afterwards include it.
+func (mw *MiddlewareItem) SetReadme(readme *Readme) {
+	mw.readme.Store(readme)
+}
+
+// Readme returns the recorded README of the library, or nil if none was fetched
+func (mw *MiddlewareItem) Readme() *Readme {
+	return mw.readme.Load()
+}
+
+var (
+	mdFenceRegex     = regexp.MustCompile("(?s)(```|~~~).*?(```|~~~)")
+	mdImageRegex     = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	mdLinkRegex      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdRefLinkRegex   = regexp.MustCompile(`(?m)^\s*\[[^\]]+\]:\s*\S+.*$`)
+	mdEmphasisRegex  = regexp.MustCompile("[*_`]+")
+	mdParagraphRegex = regexp.MustCompile(`\n\s*\n`)
+)
+
+// markdownText returns a Markdown document as plain text, without code blocks, images and
+// link targets, and its first paragraph of prose, skipping headings, badges and tables, as a
+// snippet
+func markdownText(md string) (text, snippet string) {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	md = mdFenceRegex.ReplaceAllString(md, "\n\n")
+	md = mdImageRegex.ReplaceAllString(md, "")
+	md = mdLinkRegex.ReplaceAllString(md, "$1")
+	md = mdRefLinkRegex.ReplaceAllString(md, "")
+	md = mdEmphasisRegex.ReplaceAllString(md, "")
+	paragraphs := []string{}
+	for _, block := range mdParagraphRegex.Split(md, -1) {
+		lines := []string{}
+		for _, line := range strings.Split(block, "\n") {
+			line = strings.TrimLeft(strings.TrimSpace(line), "#>")
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if plain := PlainText(strings.Join(lines, "\n")); plain != "" {
+			paragraphs = append(paragraphs, plain)
+			if snippet == "" && isProse(block) {
+				snippet = Excerpt(plain, readmeSnippetLen)
+			}
+		}
+	}
+	return strings.Join(paragraphs, "\n\n"), snippet
+}
+
+// isProse reports whether a Markdown block is a paragraph of text, rather than a heading, a
+// table, a list or what is left of a line of badges
+func isProse(block string) bool {
+	block = strings.TrimSpace(block)
+	if block == "" || strings.ContainsAny(block[:1], "#|-*+<=") {
+		return false
+	}
+	return len(strings.Fields(block)) >= 4
+}
+
+// WithReadmes fetches the READMEs of the middleware with r once the manifests are read, so
+// that the search index includes them. Not when ingesting WithOffline.
+func WithReadmes(r *ReadmeFetcher) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.readmes = r
+	}
+}
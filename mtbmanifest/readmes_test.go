@@ -0,0 +1,98 @@
+package mtbmanifest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testFreertosReadme = "# FreeRTOS for Infineon MCUs\n\n" +
+	"[![badge](https://img.shields.io/badge.svg)](https://example.com/ci)\n\n" +
+	"This repo has the **FreeRTOS kernel** with a port for the [Arm Cortex-M](https://example.com) cores " +
+	"and tickless idle support.\n\n" +
+	"## Quick start\n\n" +
+	"```\n#include \"FreeRTOS.h\"\nvTaskStartScheduler();\n```\n\n" +
+	"Heap schemes are configured in FreeRTOSConfig.h.\n"
+
+func TestReadmeFetcher(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path != "/Infineon/freertos/latest-v10.X/README.md" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testFreertosReadme))
+	}))
+	defer srv.Close()
+
+	sm := newTestSuperManifest(t)
+	if hits := sm.Search("tickless", nil); len(hits) != 0 {
+		t.Fatalf("expected no hits before the READMEs are fetched, got %d", len(hits))
+	}
+	store := NewMemoryStore()
+	r := NewReadmeFetcher(WithReadmeStore(store), WithReadmeInterval(time.Millisecond))
+	r.rawBase = srv.URL
+	readmes := r.Readmes(sm, 0)
+	if len(readmes) != 1 || readmes[0].MiddlewareID != "freertos" {
+		t.Fatalf("expected the freertos README, got %+v", readmes)
+	}
+	want := "This repo has the FreeRTOS kernel with a port for the Arm Cortex-M cores and tickless idle support."
+	if readmes[0].Snippet != want {
+		t.Errorf("expected the snippet %q, got %q", want, readmes[0].Snippet)
+	}
+	if hits := sm.Search("tickless", nil); len(hits) != 1 || hits[0].ID != "freertos" {
+		t.Errorf("expected the README to be searched, got %+v", hits)
+	}
+	if hits := sm.Search("vTaskStartScheduler", nil); len(hits) != 0 {
+		t.Error("expected code blocks to be left out of the index")
+	}
+	mw, _ := sm.GetMiddleware("freertos")
+	if mw.Readme() == nil {
+		t.Error("expected the README to be recorded")
+	}
+	// Another tree, e.g. of another channel, has READMEs of its own
+	other := newTestSuperManifest(t)
+	if otherMW, _ := other.GetMiddleware("freertos"); otherMW.Readme() != nil {
+		t.Error("expected the README to be recorded for its own tree only")
+	}
+	if hits := other.Search("tickless", nil); len(hits) != 0 {
+		t.Errorf("expected the README not to be searched in another tree, got %d hits", len(hits))
+	}
+
+	// Cached READMEs are not downloaded again, nor are those known to be missing
+	total := func() (n int) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range hits {
+			n += c
+		}
+		return n
+	}
+	sent := total()
+	r.Readmes(sm, 0)
+	if total() != sent {
+		t.Errorf("expected no new requests, got %v", hits)
+	}
+	// A stale README is downloaded again
+	_ = store.Put(readmes[0].URL, []byte("old"), time.Now().Add(-30*24*time.Hour))
+	if readme, err := r.Readme(mw); err != nil || readme.Text == "old" {
+		t.Errorf("expected the stale README to be refreshed, got %+v, %v", readme, err)
+	}
+}
+
+func TestReadmeInterval(t *testing.T) {
+	r := NewReadmeFetcher(WithReadmeInterval(20 * time.Millisecond))
+	start := time.Now()
+	for range 3 {
+		r.wait()
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected requests to be spaced, 3 took %v", elapsed)
+	}
+}
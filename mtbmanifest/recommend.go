@@ -0,0 +1,228 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Middleware recommendations
+// ////////////////////////////////////////////////////////////////////////
+
+// Someone who picks the Wi-Fi connection manager will also want the lwIP port, secure sockets
+// and mbedTLS, but nothing in the manifests says so outright. The dependency manifests do,
+// in bulk: every BSP and library version lists the libraries it is used with. Recommend
+// mines those lists for libraries that are required together, and suggests for a selection
+// the ones it requires (directly or through others) and the ones that most often come with
+// it, compatible with the board and, all else equal, of the same category.
+
+// RecommendOptions controls Recommend
+type RecommendOptions struct {
+	// Limit is the maximum number of recommendations. 0 means 10, < 0 means no limit.
+	Limit int
+	// MinScore leaves out libraries that come with the selection less often than this, from
+	// 0 to 1. 0 means 0.25.
+	MinScore float64
+	// Scorer boosts the libraries that are not required; nil means the default scorer (see
+	// SetDefaultScorer)
+	Scorer Scorer
+}
+
+// Recommendation is a library suggested for a selection
+type Recommendation struct {
+	Item *MiddlewareItem `json:"-"`
+	ID   string          `json:"id"`
+	// Score is 1 for a required library, or else the share of the dependency lists with a
+	// selected library that also have this one, times the boost of the scorer, plus a little
+	// for a category it shares with the selection, at most 1
+	Score float64 `json:"score"`
+	// RequiredBy are the selected libraries that need this one, directly or through others
+	RequiredBy []string `json:"requiredBy,omitempty"`
+	// CoRequiredWith are the selected libraries this one is listed together with
+	CoRequiredWith []string `json:"coRequiredWith,omitempty"`
+}
+
+// recommendCategoryBonus is added to the score of a library in the category of a selected one
+const recommendCategoryBonus = 0.05
+
+// dependencySets returns, for each BSP and library, itself and the libraries its newest
+// version depends on, keyed by ID
+func dependencySets(sm SuperManifestIF) []map[string]bool {
+	ret := []map[string]bool{}
+	add := func(id string, deps *Depender) {
+		v := newestDependerVersion(deps)
+		if v == nil || len(v.Dependees) == 0 {
+			return
+		}
+		set := map[string]bool{idKey(id): true}
+		for _, dep := range v.Dependees {
+			set[idKey(dep.ID)] = true
+		}
+		ret = append(ret, set)
+	}
+	for _, b := range sm.Boards() {
+		add(b.ID, b.Dependencies)
+	}
+	for _, mw := range sm.Middleware() {
+		add(mw.ID, mw.Dependencies)
+	}
+	return ret
+}
+
+// newestDependerVersion returns the dependencies of the newest version of a depender, or nil
+func newestDependerVersion(d *Depender) *DependerVersion {
+	if d == nil || len(d.Versions) == 0 {
+		return nil
+	}
+	commits := []string{}
+	for _, v := range d.Versions {
+		commits = append(commits, v.Commit)
+	}
+	return dependerVersion(d, newestCommit(commits))
+}
+
+// Recommend suggests libraries to add to the selected middleware IDs for a board: those the
+// selection requires, then those most often required together with it, best first. Only
+// libraries compatible with the board are suggested, none of those the board's BSP already
+// brings in. An empty boardID suggests for any board.
+func Recommend(sm SuperManifestIF, boardID string, selected []string, opts *RecommendOptions) ([]*Recommendation, error) {
+	if opts == nil {
+		opts = &RecommendOptions{}
+	}
+	limit, minScore := opts.Limit, opts.MinScore
+	if limit == 0 {
+		limit = 10
+	}
+	if minScore == 0 {
+		minScore = 0.25
+	}
+	var compatible map[string]bool
+	exclude := map[string]bool{}
+	if boardID != "" {
+		board, ok := sm.GetBoard(boardID)
+		if !ok {
+			return nil, fmt.Errorf("board %s not found", boardID)
+		}
+		compatible = map[string]bool{}
+		for _, mw := range FindMiddlewareForBoard(sm, board) {
+			compatible[idKey(mw.ID)] = true
+		}
+		if v := newestDependerVersion(board.Dependencies); v != nil {
+			for _, dep := range v.Dependees {
+				exclude[idKey(dep.ID)] = true
+			}
+		}
+	}
+	selectedItems := []*MiddlewareItem{}
+	for _, id := range selected {
+		mw, ok := sm.GetMiddleware(id)
+		if !ok {
+			return nil, fmt.Errorf("middleware %s not found", id)
+		}
+		selectedItems = append(selectedItems, mw)
+		exclude[idKey(mw.ID)] = true
+	}
+
+	recs := map[string]*Recommendation{}
+	get := func(id string) *Recommendation {
+		key := idKey(id)
+		if exclude[key] || (compatible != nil && !compatible[key]) {
+			return nil
+		}
+		if r, ok := recs[key]; ok {
+			return r
+		}
+		mw, ok := sm.GetMiddleware(id)
+		if !ok {
+			return nil
+		}
+		r := &Recommendation{Item: mw, ID: mw.ID}
+		recs[key] = r
+		return r
+	}
+
+	// What the selection requires, breadth first through the newest versions
+	for _, sel := range selectedItems {
+		seen := map[string]bool{idKey(sel.ID): true}
+		queue := []*MiddlewareItem{sel}
+		for len(queue) > 0 {
+			mw := queue[0]
+			queue = queue[1:]
+			v := newestDependerVersion(mw.Dependencies)
+			if v == nil {
+				continue
+			}
+			for _, dep := range v.Dependees {
+				if seen[idKey(dep.ID)] {
+					continue
+				}
+				seen[idKey(dep.ID)] = true
+				if r := get(dep.ID); r != nil {
+					r.Score = 1
+					r.RequiredBy = append(r.RequiredBy, sel.ID)
+				}
+				if next, ok := sm.GetMiddleware(dep.ID); ok {
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	// What comes with the selection
+	sets := dependencySets(sm)
+	for _, sel := range selectedItems {
+		with, together := 0, map[string]int{}
+		for _, set := range sets {
+			if !set[idKey(sel.ID)] {
+				continue
+			}
+			with++
+			for key := range set {
+				together[key]++
+			}
+		}
+		for key, n := range together {
+			r := get(key)
+			if r == nil {
+				continue
+			}
+			score := float64(n) / float64(with)
+			r.CoRequiredWith = append(r.CoRequiredWith, sel.ID)
+			r.Score = max(r.Score, score)
+		}
+	}
+
+	categories := map[string]bool{}
+	for _, sel := range selectedItems {
+		if sel.Category != "" {
+			categories[strings.ToLower(sel.Category)] = true
+		}
+	}
+	scorer := scorerOr(opts.Scorer)
+	ret := []*Recommendation{}
+	for _, r := range recs {
+		if len(r.RequiredBy) == 0 {
+			if r.Score < minScore {
+				continue
+			}
+			r.Score = min(r.Score*scorer.Boost(ItemKindMiddleware, r.Item), 1)
+			if categories[strings.ToLower(r.Item.Category)] {
+				r.Score = min(r.Score+recommendCategoryBonus, 1)
+			}
+		}
+		slices.Sort(r.CoRequiredWith)
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Score != ret[j].Score {
+			return ret[i].Score > ret[j].Score
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret, nil
+}
@@ -0,0 +1,82 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestRecommend(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	more, err := ReadMiddlewareManifest([]byte(`<middleware>
+  <middleware req_capabilities_v2="wifi"><n>Wi-Fi core</n><id>wifi-mw-core</id><category>Wi-Fi</category>
+    <versions><version><num>1.0</num><commit>latest-v1.X</commit></version></versions></middleware>
+  <middleware><n>lwIP</n><id>lwip</id><category>Networking</category>
+    <versions><version><num>2.0</num><commit>latest-v2.X</commit></version></versions></middleware>
+  <middleware><n>mbedTLS</n><id>mbedtls</id><category>Security</category>
+    <versions><version><num>3.0</num><commit>latest-v3.X</commit></version></versions></middleware>
+  <middleware><n>Secure sockets</n><id>secure-sockets</id><category>Wi-Fi</category>
+    <versions><version><num>3.0</num><commit>latest-v3.X</commit></version></versions></middleware>
+  <middleware><n>MQTT</n><id>mqtt</id><category>Cloud</category>
+    <versions><version><num>4.0</num><commit>latest-v4.X</commit></version></versions></middleware>
+  <middleware><n>emWin</n><id>emwin</id><category>Graphics</category>
+    <versions><version><num>6.0</num><commit>latest-v6.X</commit></version></versions></middleware>
+</middleware>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.MiddlewareManifestList.MiddlewareManifest = append(sm.MiddlewareManifestList.MiddlewareManifest,
+		&MiddlewareManifest{URI: "https://example.com/more.xml", Middlewares: more})
+	sm.clearMaps()
+	dependsOn := func(id, commit string, ids ...string) *Depender {
+		v := &DependerVersion{Commit: commit}
+		for _, dep := range ids {
+			v.Dependees = append(v.Dependees, &Dependee{ID: dep, Commit: "latest"})
+		}
+		return &Depender{ID: id, Versions: []*DependerVersion{v}}
+	}
+	for id, deps := range map[string][]string{
+		"wifi-connection-manager": {"wifi-mw-core"},
+		"wifi-mw-core":            {"lwip", "mbedtls", "freertos"},
+		"secure-sockets":          {"lwip", "mbedtls"},
+		"mqtt":                    {"secure-sockets", "wifi-connection-manager"},
+	} {
+		mw, _ := sm.GetMiddleware(id)
+		mw.Dependencies = dependsOn(id, mw.VersionCommits()[0], deps...)
+	}
+	board, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	board.Dependencies = dependsOn(board.ID, "latest-v4.X", "freertos")
+
+	recs, err := Recommend(sm, "CY8CKIT-062S2-43012", []string{"wifi-connection-manager"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id    string
+		score float64
+	}{{"lwip", 1}, {"mbedtls", 1}, {"wifi-mw-core", 1}, {"secure-sockets", 0.55}, {"mqtt", 0.5}}
+	if len(recs) != len(want) {
+		t.Fatalf("expected %d recommendations, got %+v", len(want), recs)
+	}
+	for i, w := range want {
+		if recs[i].ID != w.id || recs[i].Score < w.score-1e-9 || recs[i].Score > w.score+1e-9 {
+			t.Errorf("recommendation %d: expected %s at %.2f, got %s at %.2f", i, w.id, w.score, recs[i].ID, recs[i].Score)
+		}
+	}
+	if len(recs[0].RequiredBy) != 1 || len(recs[3].RequiredBy) != 0 || len(recs[3].CoRequiredWith) != 1 {
+		t.Errorf("expected lwip required and secure-sockets co-required, got %+v and %+v", recs[0], recs[3])
+	}
+
+	// The PSoC 4 kit has no Wi-Fi, so the Wi-Fi core is not for it
+	recs, _ = Recommend(sm, "CY8CKIT-149", []string{"wifi-connection-manager"}, &RecommendOptions{MinScore: 0.6})
+	for _, r := range recs {
+		if r.ID == "wifi-mw-core" || r.ID == "mqtt" {
+			t.Errorf("expected %s to be left out, got %+v", r.ID, r)
+		}
+	}
+	if len(recs) != 3 {
+		t.Errorf("expected freertos, lwip and mbedtls, got %+v", recs)
+	}
+
+	if _, err := Recommend(sm, "", []string{"no-such-library"}, nil); err == nil {
+		t.Error("expected an error for an unknown library")
+	}
+}
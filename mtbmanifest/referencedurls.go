@@ -0,0 +1,77 @@
+package mtbmanifest
+
+import (
+	"sort"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Referenced URLs
+// ////////////////////////////////////////////////////////////////////////
+
+// Bundles, mirrors, prefetching, link checks and directory scans all need the files a tree
+// refers to. ReferencedURLs lists them once for all, each with the kind of manifest the super
+// manifest says it is, rather than the guess ManifestKindOf makes from its name. The order is
+// stable: the super manifests, then board, app and middleware manifests as listed, then the
+// dependency and capability manifests sorted by URL. The list can be handed to FetchAndParse
+// as it is.
+
+// ReferencedURLs returns every manifest URL of the tree with its kind, each once. A URL
+// listed as two kinds keeps the first.
+func (sm *SuperManifest) ReferencedURLs() []TypedURL {
+	ret := []TypedURL{}
+	seen := make(map[string]bool)
+	add := func(urlStr string, kind ManifestKind) {
+		if urlStr != "" && !seen[urlStr] {
+			seen[urlStr] = true
+			ret = append(ret, TypedURL{URL: urlStr, Kind: kind})
+		}
+	}
+	for _, u := range sm.SourceUrls {
+		add(u, KindSuper)
+	}
+	// Old super manifests may lack a list
+	var boards []*BoardManifest
+	var apps []*AppManifest
+	var middleware []*MiddlewareManifest
+	if sm.BoardManifestList != nil {
+		boards = sm.BoardManifestList.BoardManifest
+	}
+	if sm.AppManifestList != nil {
+		apps = sm.AppManifestList.AppManifest
+	}
+	if sm.MiddlewareManifestList != nil {
+		middleware = sm.MiddlewareManifestList.MiddlewareManifest
+	}
+	for _, bm := range boards {
+		add(bm.URI, KindBoards)
+	}
+	for _, am := range apps {
+		add(am.URI, KindApps)
+	}
+	for _, mm := range middleware {
+		add(mm.URI, KindMiddleware)
+	}
+	extra := []TypedURL{}
+	for _, bm := range boards {
+		extra = append(extra, TypedURL{bm.DependencyURL, KindDependencies}, TypedURL{bm.CapabilityURL, KindCapabilities})
+	}
+	for _, mm := range middleware {
+		extra = append(extra, TypedURL{mm.DependencyURL, KindDependencies})
+	}
+	sort.SliceStable(extra, func(i, j int) bool { return extra[i].URL < extra[j].URL })
+	for _, u := range extra {
+		add(u.URL, u.Kind)
+	}
+	return ret
+}
+
+// referencedBy returns the URLs a super manifest refers to, of any kind but super
+func referencedBy(sm *SuperManifest) []string {
+	ret := []string{}
+	for _, ref := range sm.ReferencedURLs() {
+		if ref.Kind != KindSuper {
+			ret = append(ret, ref.URL)
+		}
+	}
+	return ret
+}
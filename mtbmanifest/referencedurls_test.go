@@ -0,0 +1,38 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestReferencedURLs(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	sm.SourceUrls = []string{"https://example.com/super.xml"}
+	bm := sm.BoardManifestList.BoardManifest[0]
+	bm.DependencyURL, bm.CapabilityURL = "https://example.com/deps.xml", "https://example.com/caps.json"
+	// Shared with the board manifest: listed once
+	sm.MiddlewareManifestList.MiddlewareManifest[0].DependencyURL = "https://example.com/deps.xml"
+
+	want := []TypedURL{
+		{"https://example.com/super.xml", KindSuper},
+		{"https://example.com/boards.xml", KindBoards},
+		{"https://example.com/apps.xml", KindApps},
+		{"https://example.com/middleware.xml", KindMiddleware},
+		{"https://example.com/caps.json", KindCapabilities},
+		{"https://example.com/deps.xml", KindDependencies},
+	}
+	got := sm.ReferencedURLs()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d URLs, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("URL %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+	if urls := sm.ManifestURLs(); len(urls) != len(want) || urls[4] != "https://example.com/caps.json" {
+		t.Errorf("expected ManifestURLs in the same order, got %v", urls)
+	}
+	if refs := referencedBy(sm); len(refs) != len(want)-1 || refs[0] != "https://example.com/boards.xml" {
+		t.Errorf("expected the super manifest left out, got %v", refs)
+	}
+}
@@ -0,0 +1,219 @@
+package mtbmanifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Refreshing a tree
+// ////////////////////////////////////////////////////////////////////////
+
+// Servers and IDE back-ends keep one tree for days, while the manifests behind it change.
+// A refresh downloads the manifests of the tree that are past their TTL, ingests a new tree the
+// way the old one was ingested, and reports what changed. A tree is never changed in place:
+// LiveSuperManifest holds the current tree and swaps in the new one atomically, so readers
+// always see one tree or the other.
+
+// ErrNotRefreshable is returned by Refresh for trees that were not ingested from URLs, e.g.
+// assembled in memory or loaded from a pinned snapshot
+var ErrNotRefreshable = errors.New("the tree was not ingested from URLs and cannot be refreshed")
+
+// ItemChange is a board, app or middleware item that was added, removed or changed
+type ItemChange struct {
+	Kind ItemKind `json:"kind"`
+	ID   string   `json:"id"`
+	// AddedVersions and RemovedVersions are the version commits that appeared and disappeared,
+	// for changed items
+	AddedVersions   []string `json:"addedVersions,omitempty"`
+	RemovedVersions []string `json:"removedVersions,omitempty"`
+}
+
+// ChangeSet is what a refresh changed
+type ChangeSet struct {
+	// Refetched are the manifest URLs downloaded again because they were stale
+	Refetched []string `json:"refetched"`
+	// FetchErrors are the stale manifests that could not be downloaded, by URL; the cached
+	// content was used instead
+	FetchErrors map[string]string `json:"fetchErrors,omitempty"`
+	Added       []*ItemChange     `json:"added"`
+	Removed     []*ItemChange     `json:"removed"`
+	Changed     []*ItemChange     `json:"changed"`
+}
+
+// Empty reports whether no item was added, removed or changed
+func (cs *ChangeSet) Empty() bool {
+	return len(cs.Added) == 0 && len(cs.Removed) == 0 && len(cs.Changed) == 0
+}
+
+// DiffTrees returns the items added, removed and changed from one tree to another, boards
+// first, then apps and middleware, each in manifest order
+func DiffTrees(from, to SuperManifestIF) *ChangeSet {
+	cs := &ChangeSet{Refetched: []string{}, Added: []*ItemChange{}, Removed: []*ItemChange{}, Changed: []*ItemChange{}}
+	// Items are compared without their provenance, which changes with every download
+	type plainBoard Board
+	type plainApp App
+	type plainMiddlewareItem MiddlewareItem
+	diffItems(cs, ItemKindBoard, from.GetBoardIDs(), to.GetBoardIDs(), from.GetBoard, to.GetBoard,
+		(*Board).VersionCommits, func(b *Board) any { return (*plainBoard)(b) })
+	diffItems(cs, ItemKindApp, from.GetAppIDs(), to.GetAppIDs(), from.GetApp, to.GetApp,
+		(*App).VersionCommits, func(a *App) any { return (*plainApp)(a) })
+	diffItems(cs, ItemKindMiddleware, from.GetMiddlewareIDs(), to.GetMiddlewareIDs(), from.GetMiddleware,
+		to.GetMiddleware, (*MiddlewareItem).VersionCommits, func(mw *MiddlewareItem) any { return (*plainMiddlewareItem)(mw) })
+	return cs
+}
+
+// diffItems adds the items of one kind that differ between two trees to cs. plain returns
+// what is compared of an item.
+func diffItems[T any](cs *ChangeSet, kind ItemKind, fromIDs, toIDs []string, fromGet, toGet func(string) (T, bool),
+	commits func(T) []string, plain func(T) any) {
+	for _, id := range toIDs {
+		cur, _ := toGet(id)
+		old, ok := fromGet(id)
+		if !ok {
+			cs.Added = append(cs.Added, &ItemChange{Kind: kind, ID: id})
+			continue
+		}
+		a, errA := json.Marshal(plain(old))
+		b, errB := json.Marshal(plain(cur))
+		if errA == nil && errB == nil && string(a) == string(b) {
+			continue
+		}
+		change := &ItemChange{Kind: kind, ID: id}
+		oldCommits, newCommits := commits(old), commits(cur)
+		for _, c := range newCommits {
+			if !slices.Contains(oldCommits, c) {
+				change.AddedVersions = append(change.AddedVersions, c)
+			}
+		}
+		for _, c := range oldCommits {
+			if !slices.Contains(newCommits, c) {
+				change.RemovedVersions = append(change.RemovedVersions, c)
+			}
+		}
+		cs.Changed = append(cs.Changed, change)
+	}
+	for _, id := range fromIDs {
+		if _, ok := toGet(id); !ok {
+			cs.Removed = append(cs.Removed, &ItemChange{Kind: kind, ID: id})
+		}
+	}
+}
+
+// refreshed downloads the stale manifests of the tree and returns a new tree ingested the way
+// this one was, with what changed
+func (sm *SuperManifest) refreshed(ctx context.Context) (*SuperManifest, *ChangeSet, error) {
+	cfg := sm.ingestCfg
+	if cfg == nil || sm.pinnedSnapshot != "" || len(sm.SourceUrls) == 0 {
+		return nil, nil, ErrNotRefreshable
+	}
+	cache := cfg.newCache()
+	refetched, fetchErrors := []string{}, map[string]string{}
+	if !cfg.offline {
+		for _, urlStr := range sm.ManifestURLs() {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			if info, err := cache.Store().Stat(urlStr); err == nil && time.Since(info.ModTime) < cache.TTL(urlStr) {
+				continue
+			}
+			if _, err := cache.fetchAndCache(ctx, urlStr); err != nil {
+				fetchErrors[urlStr] = err.Error()
+				continue
+			}
+			refetched = append(refetched, urlStr)
+		}
+	}
+
+	// Ingest through the same cache, without starting a background refresh of it
+	refreshCfg := *cfg
+	refreshCfg.cache = cache
+	refreshCfg.ctx = ctx
+	var fresh *SuperManifest
+	for _, urlStr := range sm.SourceUrls {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		tree, err := newSuperManifest(urlStr, &refreshCfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("refreshing: %v", err)
+		}
+		if fresh == nil {
+			fresh = tree
+		} else {
+			fresh.AddSuperManifest(tree)
+		}
+	}
+	fresh.ingestCfg = cfg
+	// Build the indexes now, not while readers use the tree
+	fresh.boardsIndex()
+	fresh.appsIndex()
+	fresh.middlewareIndex()
+
+	changes := DiffTrees(sm, fresh)
+	fresh.matrices = updateMatrices(sm, fresh, changes)
+	changes.Refetched = refetched
+	if len(fetchErrors) > 0 {
+		changes.FetchErrors = fetchErrors
+	}
+	return fresh, changes, nil
+}
+
+// LiveSuperManifest holds a tree that goroutines read while it is refreshed. Tree returns the
+// current tree, which a refresh does not change: Refresh ingests a new tree and swaps it in
+// atomically. Readers that need a consistent view across calls get the tree once and keep it.
+type LiveSuperManifest struct {
+	tree atomic.Pointer[SuperManifest]
+	mu   sync.Mutex // serializes refreshes
+}
+
+// NewLiveSuperManifest holds a tree made by NewSuperManifestFromURL
+func NewLiveSuperManifest(sm SuperManifestIF) (*LiveSuperManifest, error) {
+	tree, ok := sm.(*SuperManifest)
+	if !ok {
+		return nil, ErrNotRefreshable
+	}
+	live := &LiveSuperManifest{}
+	live.tree.Store(tree)
+	return live, nil
+}
+
+// Tree returns the current tree
+func (l *LiveSuperManifest) Tree() SuperManifestIF {
+	return l.tree.Load()
+}
+
+// Refresh downloads the stale manifests of the current tree, ingests a new tree and makes it
+// the current one. On error the current tree stays.
+func (l *LiveSuperManifest) Refresh(ctx context.Context) (*ChangeSet, error) {
+	_, changes, err := l.refresh(ctx)
+	return changes, err
+}
+
+// refresh is Refresh, also returning the new tree
+func (l *LiveSuperManifest) refresh(ctx context.Context) (*SuperManifest, *ChangeSet, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fresh, changes, err := l.tree.Load().refreshed(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	l.tree.Store(fresh)
+	return fresh, changes, nil
+}
+
+// replace makes tree the current one, as a refresh would, and returns what changed
+func (l *LiveSuperManifest) replace(tree *SuperManifest) *ChangeSet {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	changes := DiffTrees(l.tree.Load(), tree)
+	tree.matrices = updateMatrices(l.tree.Load(), tree, changes)
+	l.tree.Store(tree)
+	return changes
+}
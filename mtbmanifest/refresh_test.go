@@ -0,0 +1,78 @@
+package mtbmanifest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRefresh(t *testing.T) {
+	srv := newTestTreeServer(t)
+
+	dir := t.TempDir()
+	sm, err := NewSuperManifestFromURL(srv.URL+"/super.xml", WithCacheDir(dir), WithTTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, err := NewLiveSuperManifest(sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := live.Tree()
+
+	// Nothing is stale yet
+	changes, err := live.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Refetched) != 0 || !changes.Empty() {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+
+	// The boards manifest drops a board and adds a version, and its cached copy goes stale
+	boards := strings.Replace(testBoardsXML, `<version flow_version="2.0"><num>4.1.0 release</num>`,
+		`<version flow_version="2.0"><num>4.2.0 release</num><commit>release-v4.2.0</commit></version>
+      <version flow_version="2.0"><num>4.1.0 release</num>`, 1)
+	srv.setFile("/boards.xml", boards[:strings.Index(boards, "  <board>\n    <id>CY8CKIT-149")]+"</boards>")
+	boardsURL := srv.URL + "/boards.xml"
+	if err := NewFileStore(dir).Put(boardsURL, []byte(testBoardsXML), time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	matrix := BuildCompatibilityMatrix(live.Tree(), nil)
+	changes, err = live.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated := live.tree.Load().matrices[MatrixOptions{}]; updated == nil || updated == matrix ||
+		updated.Compatible("CY8CKIT-149", "mtb-example-hal-hello-world") {
+		t.Error("expected the matrix to be updated for the refreshed tree")
+	}
+	if len(changes.Refetched) != 1 || changes.Refetched[0] != boardsURL {
+		t.Fatalf("expected the boards manifest to be refetched, got %v", changes.Refetched)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0].ID != "CY8CKIT-149" || len(changes.Added) != 0 {
+		t.Fatalf("unexpected added/removed %+v %+v", changes.Added, changes.Removed)
+	}
+	if len(changes.Changed) != 1 || changes.Changed[0].ID != "CY8CKIT-062S2-43012" ||
+		len(changes.Changed[0].AddedVersions) != 1 || changes.Changed[0].AddedVersions[0] != "release-v4.2.0" {
+		t.Fatalf("unexpected changes %+v", changes.Changed)
+	}
+	if _, ok := live.Tree().GetBoard("CY8CKIT-149"); ok {
+		t.Fatal("expected the new tree to be current")
+	}
+	if _, ok := held.GetBoard("CY8CKIT-149"); !ok {
+		t.Fatal("expected the tree held by a reader to stay as it was")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := live.Refresh(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the refresh to be canceled, got %v", err)
+	}
+	if _, _, err := newTestSuperManifest(t).refreshed(context.Background()); !errors.Is(err, ErrNotRefreshable) {
+		t.Fatalf("expected ErrNotRefreshable, got %v", err)
+	}
+}
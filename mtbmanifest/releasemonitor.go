@@ -0,0 +1,205 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Latest-release monitor
+// ////////////////////////////////////////////////////////////////////////
+
+// The manifests are maintained by hand, so a repository can publish a release the manifest
+// does not list yet. CheckReleases compares the newest version each item lists with the newest
+// release tag of its repository and flags the items whose manifest lags behind.
+
+// ReleaseStatus is the result of checking one item
+type ReleaseStatus struct {
+	ID   string   `json:"id"`
+	Kind ItemKind `json:"kind"`
+	Repo string   `json:"repo"`
+	// ManifestNewest is the newest version commit the manifest lists, e.g. latest-v4.X
+	ManifestNewest string `json:"manifestNewest"`
+	// LatestTag is the newest release tag of the repository, e.g. release-v5.0.0
+	LatestTag string `json:"latestTag,omitempty"`
+	// Behind is set when LatestTag is newer than anything the manifest lists. A "latest-v4.X"
+	// entry covers every 4.x release.
+	Behind bool   `json:"behind"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReleaseCheckOptions selects what CheckReleases looks at
+type ReleaseCheckOptions struct {
+	// Kinds limits the check to these item kinds. Empty means all.
+	Kinds []ItemKind
+	// IDs limits the check to these items. Empty means all.
+	IDs []string
+	// MaxConcurrent is the number of repositories queried at once. Default 8.
+	MaxConcurrent int
+	// Fetcher queries the repositories. Default NewGitFetcher().
+	Fetcher *GitFetcher
+}
+
+// ListTags returns the tags of a remote repository. Without git, GitHub repositories are
+// queried through the GitHub API.
+func (g *GitFetcher) ListTags(repo string) ([]string, error) {
+	if err := checkGitSource(repo); err != nil {
+		return nil, err
+	}
+	if g.hasGit() {
+		out, err := g.git("", "ls-remote", "--tags", "--refs", "--", repo)
+		if err != nil {
+			return nil, err
+		}
+		ret := []string{}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 {
+				ret = append(ret, strings.TrimPrefix(fields[1], "refs/tags/"))
+			}
+		}
+		return ret, nil
+	}
+	ownerRepo, ok := githubRepo(repo)
+	if !ok {
+		return nil, fmt.Errorf("cannot list the tags of %s without git: not a GitHub repository", repo)
+	}
+	ret := []string{}
+	for page := 1; ; page++ {
+		resp, err := g.client.Get(fmt.Sprintf("%s/repos/%s/tags?per_page=100&page=%d", g.apiBase, ownerRepo, page))
+		if err != nil {
+			return nil, fmt.Errorf("http get: %w", err)
+		}
+		var tags []struct {
+			Name string `json:"name"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&tags)
+		} else {
+			err = fmt.Errorf("listing tags of %s: http status %d", repo, resp.StatusCode)
+		}
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tags {
+			ret = append(ret, t.Name)
+		}
+		if len(tags) < 100 {
+			return ret, nil
+		}
+	}
+}
+
+// newestReleaseTag returns the newest tag that names a release. Pre-releases such as
+// v2.0.0-beta, which carry a suffix, are ignored.
+func newestReleaseTag(tags []string) *SemanticVersion {
+	releases := []string{}
+	for _, t := range tags {
+		if v, err := ParseVersion(t); err == nil && v.Suffix == "" {
+			releases = append(releases, t)
+		}
+	}
+	return NewestVersion(releases)
+}
+
+// checkRelease fills in the repository side of a status
+func checkRelease(g *GitFetcher, status *ReleaseStatus, commits []string) {
+	manifestNewest := NewestVersion(commits)
+	if manifestNewest == nil {
+		status.Error = "the manifest lists no recognizable version"
+		return
+	}
+	status.ManifestNewest = manifestNewest.Raw
+	tags, err := g.ListTags(status.Repo)
+	if err != nil {
+		status.Error = err.Error()
+		return
+	}
+	latest := newestReleaseTag(tags)
+	if latest == nil {
+		status.Error = "the repository has no release tags"
+		return
+	}
+	status.LatestTag = latest.Raw
+	status.Behind = CompareStrict(latest, manifestNewest) > 0
+}
+
+// CheckReleases checks the selected items and returns their status: boards, apps, then
+// middleware, each in manifest order. Items that could not be checked have Error set.
+func CheckReleases(sm SuperManifestIF, opts *ReleaseCheckOptions) []*ReleaseStatus {
+	if opts == nil {
+		opts = &ReleaseCheckOptions{}
+	}
+	g := opts.Fetcher
+	if g == nil {
+		g = NewGitFetcher()
+	}
+	maxConcurrent := opts.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = 8
+	}
+	wantKind := func(kind ItemKind) bool {
+		if len(opts.Kinds) == 0 {
+			return true
+		}
+		for _, k := range opts.Kinds {
+			if k == kind {
+				return true
+			}
+		}
+		return false
+	}
+	wantID := make(map[string]bool)
+	for _, id := range opts.IDs {
+		wantID[idKey(id)] = true
+	}
+
+	type job struct {
+		status  *ReleaseStatus
+		commits []string
+	}
+	jobs := []*job{}
+	add := func(kind ItemKind, id, repo string, commits []string) {
+		if !wantKind(kind) || (len(wantID) > 0 && !wantID[idKey(id)]) {
+			return
+		}
+		jobs = append(jobs, &job{status: &ReleaseStatus{ID: id, Kind: kind, Repo: repo}, commits: commits})
+	}
+	for _, id := range sm.GetBoardIDs() {
+		if b, ok := sm.GetBoard(id); ok {
+			add(ItemKindBoard, b.ID, b.BoardURI, b.VersionCommits())
+		}
+	}
+	for _, id := range sm.GetAppIDs() {
+		if a, ok := sm.GetApp(id); ok {
+			add(ItemKindApp, a.ID, a.URI, a.VersionCommits())
+		}
+	}
+	for _, id := range sm.GetMiddlewareIDs() {
+		if mw, ok := sm.GetMiddleware(id); ok {
+			add(ItemKindMiddleware, mw.ID, mw.URI, mw.VersionCommits())
+		}
+	}
+
+	var wg sync.WaitGroup
+	limiter := make(chan struct{}, maxConcurrent)
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j *job) {
+			defer wg.Done()
+			limiter <- struct{}{}
+			defer func() { <-limiter }()
+			checkRelease(g, j.status, j.commits)
+		}(j)
+	}
+	wg.Wait()
+
+	ret := make([]*ReleaseStatus, len(jobs))
+	for i, j := range jobs {
+		ret[i] = j.status
+	}
+	return ret
+}
@@ -0,0 +1,65 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckReleases(t *testing.T) {
+	repo := newTestGitRepo(t)
+	for _, tag := range []string{"release-v10.5.0", "release-v11.0.0", "release-v12.0.0-beta"} {
+		runGit(t, repo, "tag", tag)
+	}
+	sm := newTestSuperManifest(t)
+	mw, _ := sm.GetMiddleware("freertos")
+	mw.URI = "file://" + repo
+
+	statuses := CheckReleases(sm, &ReleaseCheckOptions{IDs: []string{"freertos"}})
+	if len(statuses) != 1 {
+		t.Fatalf("expected one status, got %d", len(statuses))
+	}
+	s := statuses[0]
+	if s.Error != "" || s.ManifestNewest != "latest-v10.X" || s.LatestTag != "release-v11.0.0" || !s.Behind {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	// latest-v11.X covers every 11.x release
+	mw.Versions.Version = append(mw.Versions.Version, &MWVersion{Commit: "latest-v11.X"})
+	s = CheckReleases(sm, &ReleaseCheckOptions{Kinds: []ItemKind{ItemKindMiddleware}, IDs: []string{"freertos"}})[0]
+	if s.Behind {
+		t.Errorf("expected freertos to be up to date, got %+v", s)
+	}
+}
+
+func TestListTagsGitHubAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/Infineon/freertos/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") == "1" {
+			fmt.Fprint(w, `[{"name":"release-v10.5.0"},{"name":"latest-v10.X"}]`)
+		} else {
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+	g := NewGitFetcher(WithGitBinary(""))
+	g.apiBase = server.URL
+	tags, err := g.ListTags("https://github.com/Infineon/freertos")
+	if err != nil || len(tags) != 2 || tags[0] != "release-v10.5.0" {
+		t.Errorf("unexpected tags %v, %v", tags, err)
+	}
+}
+
+func TestListTagsUnsafeRepo(t *testing.T) {
+	g := NewGitFetcher()
+	for _, repo := range []string{"--upload-pack=touch pwned", "ext::sh -c touch% pwned"} {
+		if _, err := g.ListTags(repo); !errors.Is(err, ErrUnsafeGitSource) {
+			t.Errorf("ListTags(%q) = %v, want ErrUnsafeGitSource", repo, err)
+		}
+	}
+}
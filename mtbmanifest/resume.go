@@ -0,0 +1,132 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Resuming interrupted ingestions
+// ////////////////////////////////////////////////////////////////////////
+
+// An ingestion that is interrupted, by Ctrl-C or a dropped connection, never saves its report.
+// So while it runs, an ingestion with an IngestHistory keeps a checkpoint: its report so far, in
+// a file of the history directory that is removed when the ingestion ends. The checkpoint and
+// the cache together record what was completed: a manifest the checkpoint lists without an error
+// and the cache holds needs no fetching. WithResume reads those from the cache, however stale,
+// and fetches only the rest.
+
+// checkpointExt is the extension of checkpoint files; not .json, so that List skips them
+const checkpointExt = ".checkpoint"
+
+// WithResume resumes an interrupted ingestion of the same URL, if its checkpoint is in the
+// IngestHistory: the manifests it completed are read from the cache without refreshing them,
+// and only the others are fetched. Without a checkpoint the ingestion runs as usual.
+func WithResume() IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.resume = true
+	}
+}
+
+// checkpointFile returns the file the checkpoint of an ingestion of urlStr is kept in
+func (h *IngestHistory) checkpointFile(urlStr string) string {
+	return filepath.Join(h.dir, "in-progress-"+hashContent([]byte(urlStr))[:16]+checkpointExt)
+}
+
+// saveCheckpoint saves the report of an ingestion that is running
+func (h *IngestHistory) saveCheckpoint(r *IngestReport) error {
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	data, err := json.Marshal(r)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(h.checkpointFile(r.URL), data)
+}
+
+// removeCheckpoint removes the checkpoint of an ingestion of urlStr
+func (h *IngestHistory) removeCheckpoint(urlStr string) {
+	_ = os.Remove(h.checkpointFile(urlStr))
+}
+
+// Interrupted returns the report so far of an ingestion of urlStr that did not end, or nil if
+// there is none
+func (h *IngestHistory) Interrupted(urlStr string) (*IngestReport, error) {
+	data, err := os.ReadFile(h.checkpointFile(urlStr))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	r := &IngestReport{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// startCheckpoints makes the report save a checkpoint in history after every manifest, and
+// with WithResume marks the manifests an interrupted ingestion completed as resumed
+func (cfg *ingestConfig) startCheckpoints(history *IngestHistory, report *IngestReport) {
+	if cfg.resume {
+		interrupted, err := history.Interrupted(report.URL)
+		if err != nil {
+			cfg.logger.Warningf("Not resuming, the checkpoint of %s cannot be read: %v\n", report.URL, err)
+		} else if interrupted != nil {
+			report.ResumedFrom = interrupted.StartedAt
+			report.resumed = map[string]bool{}
+			for _, m := range interrupted.Manifests {
+				if m.Error == "" {
+					report.resumed[m.URL] = true
+				}
+			}
+			cfg.logger.Infof("Resuming the ingestion of %s started %s: %d manifests done\n", report.URL,
+				interrupted.StartedAt.Local().Format("2006-01-02 15:04:05"), len(report.resumed))
+		}
+	}
+	report.checkpoint = func() {
+		report.checkpointMu.Lock()
+		defer report.checkpointMu.Unlock()
+		if err := history.saveCheckpoint(report); err != nil {
+			cfg.logger.Debugf("Failed to save the checkpoint of %s: %v\n", report.URL, err)
+		}
+	}
+}
+
+// getResumed returns the cached content of urlStr if an interrupted ingestion completed it,
+// and ok false if the manifest is to be fetched
+func getResumed(cache *ManifestCache, report *IngestReport, urlStr string) (data []byte, ok bool) {
+	if !report.resumed[urlStr] {
+		return nil, false
+	}
+	data, err := cache.readCache(urlStr)
+	if err != nil {
+		delete(report.resumed, urlStr) // completed, but no longer cached
+		return nil, false
+	}
+	return data, true
+}
+
+// resumeFromCache passes the cached content of the items an interrupted ingestion completed to
+// their callbacks, and returns the items left to fetch
+func resumeFromCache(cache *ManifestCache, report *IngestReport, urls []*FetchUrlWithCb) []*FetchUrlWithCb {
+	if len(report.resumed) == 0 {
+		return urls
+	}
+	rest := []*FetchUrlWithCb{}
+	for _, item := range urls {
+		report.mu.Lock()
+		data, ok := getResumed(cache, report, item.Url)
+		report.mu.Unlock()
+		if !ok {
+			rest = append(rest, item)
+		} else if item.Callback != nil {
+			item.Callback(item.Url, data, nil, item.Index)
+		}
+	}
+	return rest
+}
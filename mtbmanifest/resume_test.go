@@ -0,0 +1,121 @@
+package mtbmanifest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResumeIngestion(t *testing.T) {
+	srv := newTestTreeServer(t)
+	superURL := srv.URL + "/super.xml"
+
+	// An earlier run read the super and boards manifests, which are now far too stale, and was
+	// interrupted while reading the apps manifest
+	store := NewMemoryStore()
+	old := time.Now().Add(-3 * time.Hour)
+	for _, path := range []string{"/super.xml", "/boards.xml"} {
+		data, _ := srv.content(path)
+		_ = store.Put(srv.URL+path, []byte(data), old)
+	}
+	history := NewIngestHistory(t.TempDir())
+	interrupted := newIngestReport(superURL)
+	interrupted.record(superURL, KindSuper, []byte("x"), nil)
+	interrupted.record(srv.URL+"/boards.xml", KindBoards, []byte("x"), nil)
+	if err := history.saveCheckpoint(interrupted); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := history.Interrupted(superURL); err != nil || r == nil || len(r.Manifests) != 2 {
+		t.Fatalf("expected the checkpoint, got %+v, %v", r, err)
+	}
+
+	cache := NewManifestCache(WithStore(store), WithCacheTTL(time.Hour), WithMaxStale(time.Minute),
+		WithNoBackgroundRefresh())
+	defer cache.Close()
+	var checkpointed int
+	sm, err := NewSuperManifestFromURL(superURL, withIngestCache(cache), WithIngestHistory(history), WithResume(),
+		WithProgress(func(done, total int, urlStr string) {
+			if done == total {
+				r, _ := history.Interrupted(superURL)
+				checkpointed = len(r.Manifests)
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.GetBoardIDs()) != 2 || len(sm.GetAppIDs()) != 3 {
+		t.Errorf("expected the whole tree, got %d boards and %d apps", len(sm.GetBoardIDs()), len(sm.GetAppIDs()))
+	}
+	hits := []int{srv.hitCount("/super.xml"), srv.hitCount("/boards.xml"), srv.hitCount("/apps.xml"),
+		srv.hitCount("/middleware.xml")}
+	if !slices.Equal(hits, []int{0, 0, 1, 1}) {
+		t.Errorf("expected only the missing manifests to be fetched, got %v", hits)
+	}
+	if checkpointed != 4 {
+		t.Errorf("expected the checkpoint to follow the ingestion, got %d manifests", checkpointed)
+	}
+
+	report := sm.(*SuperManifest).IngestReport()
+	resumed := 0
+	for _, m := range report.Manifests {
+		if m.Resumed {
+			resumed++
+		}
+	}
+	if resumed != 2 || !report.ResumedFrom.Equal(interrupted.StartedAt) {
+		t.Errorf("expected 2 manifests resumed from %v, got %d from %v", interrupted.StartedAt, resumed, report.ResumedFrom)
+	}
+	if r, err := history.Interrupted(superURL); err != nil || r != nil {
+		t.Errorf("expected the checkpoint to be removed, got %+v, %v", r, err)
+	}
+	if reports, _ := history.List(time.Time{}); len(reports) != 1 {
+		t.Errorf("expected the report to be saved, got %d", len(reports))
+	}
+}
+
+func TestInterruptedIngestion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var up atomic.Bool
+	srv := newTestTreeServer(t)
+	srv.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/middleware.xml" && !up.Load() {
+			cancel() // Ctrl-C while the middleware manifest downloads
+			<-r.Context().Done()
+			return true
+		}
+		return false
+	}
+	superURL := srv.URL + "/super.xml"
+
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithNoBackgroundRefresh())
+	defer cache.Close()
+	history := NewIngestHistory(t.TempDir())
+	_, err := NewSuperManifestFromURL(superURL, withIngestCache(cache), WithIngestHistory(history), WithContext(ctx))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the ingestion to be interrupted, got %v", err)
+	}
+	interrupted, err := history.Interrupted(superURL)
+	if err != nil || interrupted == nil {
+		t.Fatalf("expected the checkpoint to be kept, got %v", err)
+	}
+	if reports, _ := history.List(time.Time{}); len(reports) != 0 {
+		t.Errorf("expected no report of the interrupted ingestion, got %d", len(reports))
+	}
+
+	up.Store(true)
+	sm, err := NewSuperManifestFromURL(superURL, withIngestCache(cache), WithIngestHistory(history), WithResume())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.GetMiddlewareIDs()) == 0 {
+		t.Error("expected the resumed ingestion to read the middleware")
+	}
+	if r, _ := history.Interrupted(superURL); r != nil {
+		t.Error("expected the checkpoint to be removed")
+	}
+}
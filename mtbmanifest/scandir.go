@@ -0,0 +1,260 @@
+package mtbmanifest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Manifests in a local directory
+// ////////////////////////////////////////////////////////////////////////
+
+// Vendors keep their manifest repository checked out and edit it locally; to see the result
+// they should not have to push and wait for the CDN. ScanDir finds every manifest file under a
+// directory by its content (see SniffManifestKind), parses it, and assembles a tree from the
+// files. The URLs a super manifest references are resolved to the file whose path relative to
+// the directory ends the URL, so a checkout of the repository the URLs point into resolves as
+// is. Without a super manifest in the directory, one listing every board, app and middleware
+// file stands in; such a tree has no dependency or capability manifests, since nothing says
+// which board manifest they belong to.
+
+// ScannedFile is a manifest file found by ScanDir
+type ScannedFile struct {
+	// Path is relative to the scanned directory, with forward slashes
+	Path string       `json:"path"`
+	Kind ManifestKind `json:"kind"`
+	// Format is the format of board, app and middleware manifests (see DetectManifestFormat)
+	Format int `json:"format,omitempty"`
+	// Items is the number of boards, apps, middleware, dependers or capabilities in the file;
+	// for a super manifest, the number of manifests it lists
+	Items int    `json:"items"`
+	Error string `json:"error,omitempty"`
+
+	url  string
+	data []byte
+	m    *AnyManifest
+	err  error
+}
+
+// DirScan is what ScanDir found in a directory
+type DirScan struct {
+	Dir   string         `json:"dir"`
+	Files []*ScannedFile `json:"files"`
+	// Counts are the number of files of each kind
+	Counts map[ManifestKind]int `json:"counts"`
+	// Unresolved are the URLs referenced by the super manifests with no file in the directory
+	Unresolved []string `json:"unresolved,omitempty"`
+	// Tree is the tree assembled from the files; nil when no file parsed
+	Tree SuperManifestIF `json:"-"`
+
+	// byURL are the files the URLs of the tree were read from
+	byURL map[string]*ScannedFile
+}
+
+// Failed returns the files that did not parse
+func (s *DirScan) Failed() []*ScannedFile {
+	ret := []*ScannedFile{}
+	for _, f := range s.Files {
+		if f.Error != "" {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// ScanDir finds and parses the manifest files under dir and assembles a tree from them.
+// Hidden directories such as .git are skipped, as are files that are no manifest. Ingestion
+// options apply to assembling the tree, which is read from the files only; options that choose
+// a cache are ignored.
+func ScanDir(dir string, opts ...IngestOption) (*DirScan, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := manifestFilePaths(abs)
+	if err != nil {
+		return nil, err
+	}
+	scan := &DirScan{Dir: dir, Files: []*ScannedFile{}, Counts: map[ManifestKind]int{}, byURL: map[string]*ScannedFile{}}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, _ := filepath.Rel(abs, path)
+		if f := scanFile(filepath.ToSlash(rel), (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(), data); f != nil {
+			scan.Files = append(scan.Files, f)
+			scan.Counts[f.Kind]++
+		}
+	}
+	if err := scan.assemble(opts); err != nil {
+		return scan, err
+	}
+	return scan, nil
+}
+
+// manifestFilePaths returns the .xml and .json files under dir, outside hidden directories
+func manifestFilePaths(dir string) ([]string, error) {
+	paths := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".xml", ".json":
+			if d.Type().IsRegular() {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// scanFile parses a file found by ScanDir; nil when it is no manifest
+func scanFile(rel, fileURL string, data []byte) *ScannedFile {
+	kind := SniffManifestKind("", data)
+	if kind == KindUnknown {
+		return nil
+	}
+	f := &ScannedFile{Path: rel, Kind: kind, url: fileURL, data: data}
+	m, err := parseAnyAs(kind, data, newIngestConfig(nil), &IngestReport{}, fileURL)
+	if err != nil {
+		f.Error, f.err = err.Error(), err
+		return f
+	}
+	f.m = m
+	switch kind {
+	case KindSuper:
+		if m.Super.BoardManifestList != nil {
+			f.Items += len(m.Super.BoardManifestList.BoardManifest)
+		}
+		if m.Super.AppManifestList != nil {
+			f.Items += len(m.Super.AppManifestList.AppManifest)
+		}
+		if m.Super.MiddlewareManifestList != nil {
+			f.Items += len(m.Super.MiddlewareManifestList.MiddlewareManifest)
+		}
+	case KindBoards:
+		f.Format, f.Items = m.Boards.Format, len(m.Boards.Boards)
+	case KindApps:
+		f.Format, f.Items = m.Apps.Format, len(m.Apps.App)
+	case KindMiddleware:
+		f.Format, f.Items = m.Middleware.Format, len(m.Middleware.Middlewares)
+	case KindDependencies:
+		f.Items = len(m.Dependencies.Dependers)
+	case KindCapabilities:
+		f.Items = len(m.Capabilities.Capabilities)
+	}
+	return f
+}
+
+// resolve returns the parsed file whose path ends the path of urlStr, the longest such, or nil
+func (s *DirScan) resolve(urlStr string) *ScannedFile {
+	urlPath := urlStr
+	if u, err := url.Parse(urlStr); err == nil {
+		urlPath = u.Path
+	}
+	var best *ScannedFile
+	for _, f := range s.Files {
+		if f.m == nil || f.Kind == KindSuper {
+			continue
+		}
+		if (urlPath == f.Path || strings.HasSuffix(urlPath, "/"+f.Path)) && (best == nil || len(f.Path) > len(best.Path)) {
+			best = f
+		}
+	}
+	return best
+}
+
+// assemble ingests the tree of the scanned files
+func (s *DirScan) assemble(opts []IngestOption) error {
+	contents := map[string][]byte{}
+	rootURLs := []string{}
+	for _, f := range s.Files {
+		if f.Kind != KindSuper || f.m == nil {
+			continue
+		}
+		contents[f.url] = f.data
+		s.byURL[f.url] = f
+		rootURLs = append(rootURLs, f.url)
+		for _, ref := range referencedBy(f.m.Super) {
+			if rf := s.resolve(ref); rf != nil {
+				contents[ref] = rf.data
+				s.byURL[ref] = rf
+			} else if !slices.Contains(s.Unresolved, ref) {
+				s.Unresolved = append(s.Unresolved, ref)
+			}
+		}
+	}
+	sort.Strings(s.Unresolved)
+
+	if len(rootURLs) == 0 {
+		super, err := s.standInSuperManifest()
+		if err != nil || super == nil {
+			return err
+		}
+		const standInURL = "file:///super-manifest.xml"
+		contents[standInURL] = super
+		rootURLs = append(rootURLs, standInURL)
+		for _, f := range s.Files {
+			contents[f.url] = f.data
+			s.byURL[f.url] = f
+		}
+	}
+	tree, err := superManifestFromContents(contents, rootURLs, opts)
+	if err != nil {
+		return err
+	}
+	s.Tree = tree
+	return nil
+}
+
+// standInSuperManifest returns a super manifest listing every parsed board, app and middleware
+// file, or nil when there is none
+func (s *DirScan) standInSuperManifest() ([]byte, error) {
+	super := &SuperManifest{
+		Version:                "2.0",
+		BoardManifestList:      &BoardManifestList{},
+		AppManifestList:        &AppManifestList{},
+		MiddlewareManifestList: &MiddlewareManifestList{},
+	}
+	found := false
+	for _, f := range s.Files {
+		if f.m == nil {
+			continue
+		}
+		switch f.Kind {
+		case KindBoards:
+			super.BoardManifestList.BoardManifest = append(super.BoardManifestList.BoardManifest, &BoardManifest{URI: f.url})
+		case KindApps:
+			super.AppManifestList.AppManifest = append(super.AppManifestList.AppManifest, &AppManifest{URI: f.url})
+		case KindMiddleware:
+			super.MiddlewareManifestList.MiddlewareManifest = append(super.MiddlewareManifestList.MiddlewareManifest, &MiddlewareManifest{URI: f.url})
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	data, err := xml.Marshal(super)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write the stand-in super manifest: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,86 @@
+package mtbmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeScanFiles writes files, keyed by slash-separated path, under dir
+func writeScanFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanDir(t *testing.T) {
+	dir := t.TempDir()
+	writeScanFiles(t, dir, map[string]string{
+		"super-manifest.xml":      testSuperXML,
+		"boards.xml":              testBoardsXML,
+		"work/apps.xml":           testAppsXML,
+		"draft/broken-boards.xml": `<boards><board>`,
+		"package.json":            `{"name": "not a manifest"}`,
+		".git/boards.xml":         testBoardsXML,
+	})
+	scan, err := ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan.Files) != 4 || scan.Counts[KindBoards] != 2 || scan.Counts[KindApps] != 1 || scan.Counts[KindSuper] != 1 {
+		t.Fatalf("unexpected files %+v, counts %v", scan.Files, scan.Counts)
+	}
+	if failed := scan.Failed(); len(failed) != 1 || failed[0].Path != "draft/broken-boards.xml" {
+		t.Errorf("expected the broken manifest to fail, got %+v", failed)
+	}
+	for _, f := range scan.Files {
+		if f.Path == "super-manifest.xml" && f.Items != 3 {
+			t.Errorf("expected the super manifest to list 3 manifests, got %d", f.Items)
+		}
+		if f.Path == "boards.xml" && f.Items != 2 {
+			t.Errorf("expected 2 boards, got %d", f.Items)
+		}
+	}
+	if len(scan.Unresolved) != 2 || scan.Unresolved[0] != "https://example.com/apps.xml" ||
+		scan.Unresolved[1] != "https://example.com/middleware.xml" {
+		t.Errorf("expected apps.xml, which is only in a subdirectory, and middleware.xml to be unresolved, got %v", scan.Unresolved)
+	}
+	if _, ok := scan.Tree.GetBoard("CY8CKIT-149"); !ok {
+		t.Error("expected the tree to have the boards of the local file")
+	}
+	if _, ok := scan.Tree.GetApp("mtb-example-hal-hello-world"); ok {
+		t.Error("expected no apps in the tree")
+	}
+}
+
+func TestScanDirWithoutSuperManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeScanFiles(t, dir, map[string]string{
+		"boards.xml":     testBoardsXML,
+		"apps/apps.xml":  testAppsXML,
+		"middleware.xml": testMiddlewareXML,
+	})
+	scan, err := ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scan.Tree == nil {
+		t.Fatal("expected a tree from a stand-in super manifest")
+	}
+	if len(scan.Tree.GetBoardIDs()) != 2 || len(scan.Tree.GetAppIDs()) != 3 || len(scan.Tree.GetMiddlewareIDs()) == 0 {
+		t.Errorf("expected every item of the files, got %v, %v, %v", scan.Tree.GetBoardIDs(),
+			scan.Tree.GetAppIDs(), scan.Tree.GetMiddlewareIDs())
+	}
+
+	empty, err := ScanDir(t.TempDir())
+	if err != nil || empty.Tree != nil || len(empty.Files) != 0 {
+		t.Errorf("expected nothing in an empty directory, got %+v, %v", empty, err)
+	}
+}
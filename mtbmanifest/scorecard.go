@@ -0,0 +1,270 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Data quality scorecard
+// ////////////////////////////////////////////////////////////////////////
+
+// The manifests are maintained by many teams, and their quality varies: items without a
+// description, versions no tool can order, capability tokens nothing defines, links that lead
+// nowhere, tags the parser does not know. ScoreManifests grades every board, app and middleware
+// manifest on these, so that their maintainers get a cleanup list, worst manifest first.
+
+// QualityIssueKind names a kind of data quality problem
+type QualityIssueKind string
+
+const (
+	IssueMissingDescription QualityIssueKind = "missing-description" // the item has no description
+	IssueBadVersion         QualityIssueKind = "bad-version"         // a version commit has no version number
+	IssueUnknownCapability  QualityIssueKind = "unknown-capability"  // a capability token nothing defines
+	IssueDeadLink           QualityIssueKind = "dead-link"           // a link is dead or unreachable
+	IssueSurprise           QualityIssueKind = "surprise"            // a tag or attribute the parser does not know
+)
+
+// issueWeights are how much each kind of issue costs, per item
+var issueWeights = map[QualityIssueKind]int{
+	IssueMissingDescription: 1,
+	IssueBadVersion:         2,
+	IssueUnknownCapability:  2,
+	IssueDeadLink:           3,
+	IssueSurprise:           1,
+}
+
+// QualityIssue is one problem of one item
+type QualityIssue struct {
+	Kind   QualityIssueKind `json:"kind"`
+	ItemID string           `json:"itemId"`
+	Detail string           `json:"detail,omitempty"`
+}
+
+func (i *QualityIssue) String() string {
+	if i.Detail == "" {
+		return fmt.Sprintf("%s: %s", i.ItemID, i.Kind)
+	}
+	return fmt.Sprintf("%s: %s (%s)", i.ItemID, i.Kind, i.Detail)
+}
+
+// SourceScore is the grade of one manifest
+type SourceScore struct {
+	URL   string       `json:"url"`
+	Kind  ManifestKind `json:"kind"`
+	Items int          `json:"items"`
+	// Score is 100 for a manifest without issues, less 20 points per issue weight per item,
+	// and never below 0. Grade is A (90 and up) to F (below 60).
+	Score  int                      `json:"score"`
+	Grade  string                   `json:"grade"`
+	Counts map[QualityIssueKind]int `json:"counts"`
+	// Issues are ordered by weight, heaviest first, then by item
+	Issues []*QualityIssue `json:"issues"`
+}
+
+// Scorecard grades the manifests of a tree
+type Scorecard struct {
+	// Sources are ordered worst first
+	Sources []*SourceScore `json:"sources"`
+}
+
+// ScorecardOptions controls ScoreManifests
+type ScorecardOptions struct {
+	// Links are the results of CheckURIs; without them links are not graded
+	Links []*LinkStatus
+}
+
+// ScoreManifests grades the board, app and middleware manifests of a tree
+func ScoreManifests(sm SuperManifestIF, opts *ScorecardOptions) *Scorecard {
+	if opts == nil {
+		opts = &ScorecardOptions{}
+	}
+	dead := make(map[string]*LinkStatus)
+	for _, s := range opts.Links {
+		if s.Problem == LinkDead || s.Problem == LinkError || s.Problem == LinkTLS {
+			dead[s.URL] = s
+		}
+	}
+	known := knownCapabilityTokens(sm)
+
+	sources := make(map[string]*SourceScore)
+	order := []string{}
+	source := func(urlStr string, kind ManifestKind) *SourceScore {
+		s := sources[urlStr]
+		if s == nil {
+			s = &SourceScore{URL: urlStr, Kind: kind, Counts: make(map[QualityIssueKind]int), Issues: []*QualityIssue{}}
+			sources[urlStr] = s
+			order = append(order, urlStr)
+		}
+		return s
+	}
+	grade := func(s *SourceScore, id, description string, commits []string, tokens []string, links []string, item any) {
+		s.Items++
+		add := func(kind QualityIssueKind, detail string) {
+			s.Issues = append(s.Issues, &QualityIssue{Kind: kind, ItemID: id, Detail: detail})
+			s.Counts[kind]++
+		}
+		if strings.TrimSpace(description) == "" {
+			add(IssueMissingDescription, "")
+		}
+		for _, commit := range commits {
+			if _, err := ParseVersion(commit); err != nil {
+				add(IssueBadVersion, commit)
+			}
+		}
+		for _, token := range tokens {
+			if known != nil && !known[strings.ToLower(token)] {
+				add(IssueUnknownCapability, token)
+			}
+		}
+		for _, link := range links {
+			if status, ok := dead[link]; ok {
+				add(IssueDeadLink, fmt.Sprintf("%s %s", link, status.Problem))
+			}
+		}
+		if n := countSurprises(reflect.ValueOf(item)); n > 0 {
+			add(IssueSurprise, fmt.Sprintf("%d unknown tags or attributes", n))
+		}
+	}
+
+	for _, b := range sm.Boards() {
+		urlStr := ""
+		if b.Origin != nil {
+			urlStr = b.Origin.URI
+		}
+		tokens := []string{}
+		if b.Capabilities != nil {
+			// Only boards whose capabilities manifest loaded can be checked against it
+			for _, token := range strings.Fields(b.ProvCapabilities) {
+				if !b.Capabilities.ValidateToken(token) {
+					tokens = append(tokens, token)
+				}
+			}
+		}
+		grade(source(urlStr, KindBoards), b.ID, b.Description, b.VersionCommits(), tokens,
+			[]string{b.BoardURI, b.DocumentationURL}, b)
+	}
+	for _, a := range sm.Apps() {
+		urlStr := ""
+		if a.Origin != nil {
+			urlStr = a.Origin.URI
+		}
+		grade(source(urlStr, KindApps), a.ID, a.Description, a.VersionCommits(), requiredTokens(a.GetCapabilities()),
+			[]string{a.URI}, a)
+	}
+	for _, mw := range sm.Middleware() {
+		urlStr := ""
+		if mw.Origin != nil {
+			urlStr = mw.Origin.URI
+		}
+		grade(source(urlStr, KindMiddleware), mw.ID, mw.Description, mw.VersionCommits(),
+			requiredTokens(mw.GetCapabilities()), []string{mw.URI}, mw)
+	}
+
+	card := &Scorecard{Sources: []*SourceScore{}}
+	for _, urlStr := range order {
+		s := sources[urlStr]
+		weight := 0
+		for _, issue := range s.Issues {
+			weight += issueWeights[issue.Kind]
+		}
+		s.Score = max(0, 100-int(math.Round(20*float64(weight)/float64(s.Items))))
+		s.Grade = scoreGrade(s.Score)
+		sort.SliceStable(s.Issues, func(i, j int) bool {
+			return issueWeights[s.Issues[i].Kind] > issueWeights[s.Issues[j].Kind]
+		})
+		card.Sources = append(card.Sources, s)
+	}
+	sort.SliceStable(card.Sources, func(i, j int) bool {
+		a, b := card.Sources[i], card.Sources[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return len(a.Issues) > len(b.Issues)
+	})
+	return card
+}
+
+// scoreGrade turns a score into a letter grade
+func scoreGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
+
+// knownCapabilityTokens returns the tokens, lower-cased, that boards provide or capabilities
+// manifests define. It returns nil when the tree has no boards to tell.
+func knownCapabilityTokens(sm SuperManifestIF) map[string]bool {
+	boards := sm.Boards()
+	if len(boards) == 0 {
+		return nil
+	}
+	known := make(map[string]bool)
+	for _, b := range boards {
+		for _, token := range strings.Fields(b.ProvCapabilities) {
+			known[strings.ToLower(token)] = true
+		}
+		if b.Capabilities != nil {
+			for _, c := range b.Capabilities.Capabilities {
+				known[strings.ToLower(c.Token)] = true
+			}
+		}
+	}
+	return known
+}
+
+// requiredTokens returns the tokens of a capability requirement, each once
+func requiredTokens(req CapabilityRequirement) []string {
+	ret := []string{}
+	for _, group := range req.Groups {
+		for _, token := range group {
+			if !containsFold(ret, token) {
+				ret = append(ret, token)
+			}
+		}
+	}
+	return ret
+}
+
+// countSurprises counts the unknown tags and attributes an item and its parsed parts captured.
+// Fields not read from the XML, such as Origin, are not followed.
+func countSurprises(v reflect.Value) int {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0
+		}
+		v = v.Elem()
+	}
+	n := 0
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("xml") == "-" {
+				continue
+			}
+			if field.Name == "Surprises" || field.Name == "LostAttrs" {
+				n += v.Field(i).Len()
+				continue
+			}
+			n += countSurprises(v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			n += countSurprises(v.Index(i))
+		}
+	}
+	return n
+}
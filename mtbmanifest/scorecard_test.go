@@ -0,0 +1,40 @@
+package mtbmanifest
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestScoreManifests(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	freertos, _ := sm.GetMiddleware("freertos")
+	freertos.Description = ""
+	freertos.Versions.Version[0].Commit = "main"
+	freertos.ReqCapabilitiesV2 = "quantum"
+	kit, _ := sm.GetBoard("CY8CKIT-149")
+	kit.Surprises = append(kit.Surprises, AnyTag{XMLName: xml.Name{Local: "mystery"}})
+
+	card := ScoreManifests(sm, &ScorecardOptions{Links: []*LinkStatus{
+		{URL: "https://github.com/Infineon/TARGET_CY8CKIT-149", StatusCode: 404, Problem: LinkDead},
+		{URL: "https://github.com/Infineon/freertos", Problem: LinkRedirect},
+	}})
+	if len(card.Sources) != 3 {
+		t.Fatalf("expected 3 sources, got %d", len(card.Sources))
+	}
+	mw, boards, apps := card.Sources[0], card.Sources[1], card.Sources[2]
+	if mw.URL != "https://example.com/middleware.xml" || mw.Score != 50 || mw.Grade != "F" {
+		t.Errorf("unexpected middleware score %+v", mw)
+	}
+	if mw.Counts[IssueMissingDescription] != 1 || mw.Counts[IssueBadVersion] != 1 || mw.Counts[IssueUnknownCapability] != 1 {
+		t.Errorf("unexpected middleware issues %v", mw.Counts)
+	}
+	if mw.Issues[len(mw.Issues)-1].Kind != IssueMissingDescription {
+		t.Errorf("expected the lightest issue last, got %v", mw.Issues)
+	}
+	if boards.Kind != KindBoards || boards.Score != 60 || boards.Counts[IssueDeadLink] != 1 || boards.Counts[IssueSurprise] != 1 {
+		t.Errorf("unexpected board score %+v", boards)
+	}
+	if apps.Score != 100 || apps.Grade != "A" || len(apps.Issues) != 0 || apps.Items != 3 {
+		t.Errorf("unexpected app score %+v", apps)
+	}
+}
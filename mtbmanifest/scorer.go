@@ -0,0 +1,165 @@
+package mtbmanifest
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Scoring
+// ////////////////////////////////////////////////////////////////////////
+
+// How Search and Recommend rank is a matter of taste: a catalog site wants new code examples
+// first, a training portal its own categories. A Scorer tells the weight of a hit in each field
+// of an item, and boosts whole items. The index keeps how often each term appears in each
+// field, so the weights apply at search time and a scorer can be passed with each search (see
+// SearchOptions.Scorer) or set as the default (see SetDefaultScorer) without rebuilding
+// anything. WeightedScorer is the scorer of the library; programs wrap or replace it.
+
+// ScoreField is a field of an item that search looks at
+type ScoreField string
+
+const (
+	ScoreFieldID          ScoreField = "id"
+	ScoreFieldName        ScoreField = "name"
+	ScoreFieldKeyword     ScoreField = "keyword" // keywords, capabilities and chips
+	ScoreFieldCategory    ScoreField = "category"
+	ScoreFieldDescription ScoreField = "description"
+	ScoreFieldReadme      ScoreField = "readme"
+)
+
+// scoreFields are the fields in the order of the term frequencies of the index
+var scoreFields = []ScoreField{ScoreFieldID, ScoreFieldName, ScoreFieldKeyword, ScoreFieldCategory,
+	ScoreFieldDescription, ScoreFieldReadme}
+
+// fieldFreqs are the term frequencies of a document, by field in the order of scoreFields
+type fieldFreqs [6]float64
+
+// Scorer ranks search results and recommendations
+type Scorer interface {
+	// FieldWeight returns the weight of a hit in a field
+	FieldWeight(field ScoreField) float64
+	// Boost returns the factor the score of an item is multiplied by; 1 leaves it as is. Item
+	// is a *Board, *App or *MiddlewareItem depending on kind.
+	Boost(kind ItemKind, item any) float64
+}
+
+// WeightedScorer is the default Scorer. Its zero value weighs fields with the built-in
+// weights and boosts beginner code examples over advanced ones (see App.Difficulty).
+type WeightedScorer struct {
+	// FieldWeights replace the built-in weights of the fields they list
+	FieldWeights map[ScoreField]float64 `json:"fieldWeights,omitempty"`
+	// CategoryBoosts multiply the scores of the items of a category, case-insensitively
+	CategoryBoosts map[string]float64 `json:"categoryBoosts,omitempty"`
+	// History, with RecencyBoost, favors items whose newest version came out recently: the
+	// score of an item released now is multiplied by 1+RecencyBoost, falling to 1 for one
+	// released RecencyWindow ago or earlier. A window of 0 means 90 days.
+	History       *ReleaseHistory `json:"-"`
+	RecencyBoost  float64         `json:"recencyBoost,omitempty"`
+	RecencyWindow time.Duration   `json:"-"`
+	// Now is the time recency is measured from; zero means the current time
+	Now time.Time `json:"-"`
+}
+
+// FieldWeight returns the weight of a field: that of FieldWeights, or else the built-in one
+func (s *WeightedScorer) FieldWeight(field ScoreField) float64 {
+	if w, ok := s.FieldWeights[field]; ok {
+		return w
+	}
+	switch field {
+	case ScoreFieldID:
+		return searchWeightID
+	case ScoreFieldName:
+		return searchWeightName
+	case ScoreFieldKeyword:
+		return searchWeightKeyword
+	case ScoreFieldCategory:
+		return searchWeightCategory
+	case ScoreFieldDescription:
+		return searchWeightDescription
+	case ScoreFieldReadme:
+		return searchWeightReadme
+	}
+	return 0
+}
+
+// Boost returns the product of the difficulty factor of a code example, the boost of the
+// category of the item and its recency boost
+func (s *WeightedScorer) Boost(kind ItemKind, item any) float64 {
+	boost := 1.0
+	var id, category string
+	var commits []string
+	switch it := item.(type) {
+	case *Board:
+		id, category, commits = it.ID, it.Category, it.VersionCommits()
+	case *App:
+		id, category, commits = it.ID, it.Category, it.VersionCommits()
+		boost *= searchDifficultyFactors[it.Difficulty()]
+	case *MiddlewareItem:
+		id, category, commits = it.ID, it.Category, it.VersionCommits()
+	default:
+		return boost
+	}
+	if b, ok := s.categoryBoost(category); ok {
+		boost *= b
+	}
+	if s.History != nil && s.RecencyBoost != 0 && len(commits) > 0 {
+		if released, ok := s.History.ReleasedAt(kind, id, newestCommit(commits)); ok {
+			window, now := s.RecencyWindow, s.Now
+			if window <= 0 {
+				window = 90 * 24 * time.Hour
+			}
+			if now.IsZero() {
+				now = time.Now()
+			}
+			if age := now.Sub(released); age < window {
+				boost *= 1 + s.RecencyBoost*(1-max(age, 0).Seconds()/window.Seconds())
+			}
+		}
+	}
+	return boost
+}
+
+func (s *WeightedScorer) categoryBoost(category string) (float64, bool) {
+	if category == "" {
+		return 0, false
+	}
+	for c, b := range s.CategoryBoosts {
+		if strings.EqualFold(c, category) {
+			return b, true
+		}
+	}
+	return 0, false
+}
+
+var (
+	defaultScorerMu sync.RWMutex
+	defaultScorer   Scorer = &WeightedScorer{}
+)
+
+// SetDefaultScorer sets the scorer of searches and recommendations that do not pass one. Pass
+// nil for the zero WeightedScorer.
+func SetDefaultScorer(s Scorer) {
+	if s == nil {
+		s = &WeightedScorer{}
+	}
+	defaultScorerMu.Lock()
+	defer defaultScorerMu.Unlock()
+	defaultScorer = s
+}
+
+// DefaultScorer returns the scorer of searches and recommendations that do not pass one
+func DefaultScorer() Scorer {
+	defaultScorerMu.RLock()
+	defer defaultScorerMu.RUnlock()
+	return defaultScorer
+}
+
+// scorerOr returns s, or the default scorer for nil
+func scorerOr(s Scorer) Scorer {
+	if s == nil {
+		return DefaultScorer()
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package mtbmanifest
+
+import (
+	"testing"
+	"time"
+)
+
+// boardsFirst boosts boards far above everything else
+type boardsFirst struct{ WeightedScorer }
+
+func (s *boardsFirst) Boost(kind ItemKind, item any) float64 {
+	if kind == ItemKindBoard {
+		return 100
+	}
+	return s.WeightedScorer.Boost(kind, item)
+}
+
+func TestScorer(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	first := func(opts *SearchOptions) string {
+		results := sm.Search("wifi", opts)
+		if len(results) == 0 {
+			t.Fatal("expected results")
+		}
+		return results[0].ID
+	}
+	if id := first(&SearchOptions{Scorer: &boardsFirst{}}); id != "CY8CKIT-062S2-43012" {
+		t.Errorf("expected the board first, got %s", id)
+	}
+	if id := first(&SearchOptions{Scorer: &WeightedScorer{
+		FieldWeights:   map[ScoreField]float64{ScoreFieldID: 0, ScoreFieldName: 0},
+		CategoryBoosts: map[string]float64{"wi-fi": 0.01},
+	}}); id != "CY8CKIT-062S2-43012" {
+		t.Errorf("expected the board first with Wi-Fi items pushed down, got %s", id)
+	}
+
+	SetDefaultScorer(&boardsFirst{})
+	defer SetDefaultScorer(nil)
+	if id := first(nil); id != "CY8CKIT-062S2-43012" {
+		t.Errorf("expected the default scorer to be used, got %s", id)
+	}
+	SetDefaultScorer(nil)
+	if _, ok := DefaultScorer().(*WeightedScorer); !ok {
+		t.Error("expected nil to restore the weighted scorer")
+	}
+
+	// Recency, from the time the newest version was first seen
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	history := NewReleaseHistory()
+	history.Since = now.AddDate(-1, 0, 0)
+	history.firstSeen[releaseKey(ItemKindMiddleware, "freertos", "latest-v10.X")] = now.AddDate(0, 0, -45)
+	freertos, _ := sm.GetMiddleware("freertos")
+	wcm, _ := sm.GetMiddleware("wifi-connection-manager")
+	s := &WeightedScorer{History: history, RecencyBoost: 1, Now: now}
+	if boost := s.Boost(ItemKindMiddleware, freertos); boost < 1.49 || boost > 1.51 {
+		t.Errorf("expected half the recency boost halfway through the window, got %v", boost)
+	}
+	if boost := s.Boost(ItemKindMiddleware, wcm); boost != 1 {
+		t.Errorf("expected no boost for a version not seen released, got %v", boost)
+	}
+}
@@ -0,0 +1,327 @@
+package mtbmanifest
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ItemKind identifies which kind of manifest item (board, app or middleware) something refers to
+type ItemKind string
+
+const (
+	ItemKindBoard      ItemKind = "board"
+	ItemKindApp        ItemKind = "app"
+	ItemKindMiddleware ItemKind = "middleware"
+)
+
+// Built-in field weights used when scoring search hits (see WeightedScorer). A hit in an ID or
+// name is worth a lot more than a hit somewhere in a long description.
+const (
+	searchWeightID          = 4.0
+	searchWeightName        = 3.0
+	searchWeightKeyword     = 2.5
+	searchWeightCategory    = 2.0
+	searchWeightDescription = 1.0
+	searchWeightReadme      = 0.3
+
+	// Prefix matches (query "wif" finding "wifi") count for this fraction of an exact match
+	searchPrefixFactor = 0.5
+)
+
+// searchDifficultyFactors scale the scores of apps by difficulty (see App.Difficulty), so that
+// of two equally good hits the getting started example comes first
+var searchDifficultyFactors = map[Difficulty]float64{
+	DifficultyBeginner:     1.25,
+	DifficultyIntermediate: 1.0,
+	DifficultyAdvanced:     0.8,
+}
+
+// SearchOptions controls what Search looks at and how many results it returns
+type SearchOptions struct {
+	// Kinds restricts the search to the given item kinds. Empty means all kinds.
+	Kinds []ItemKind
+	// Limit is the maximum number of results. Zero or negative means no limit.
+	Limit int
+	// MatchAll requires every query term to match (AND). Default is to rank by any term (OR).
+	MatchAll bool
+	// Scorer ranks the results; nil means the default scorer (see SetDefaultScorer)
+	Scorer Scorer
+}
+
+// SearchResult is a single scored hit. Item is a *Board, *App or *MiddlewareItem depending on Kind.
+type SearchResult struct {
+	Kind  ItemKind `json:"kind"`
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Score float64  `json:"score"`
+	Item  any      `json:"-"`
+}
+
+type searchDoc struct {
+	kind ItemKind
+	id   string
+	name string
+	item any
+}
+
+// SearchIndex is an inverted index over the names, descriptions, keywords and categories
+// of every board, app and middleware item in a super manifest, and the READMEs of the
+// middleware that has one recorded (see MiddlewareItem.SetReadme).
+type SearchIndex struct {
+	docs []*searchDoc
+	// postings maps a token to its term frequency in each field of each document (by doc index)
+	postings map[string]map[int]*fieldFreqs
+	// tokens is the sorted list of all tokens, used for prefix matching
+	tokens []string
+}
+
+// NewSearchIndex builds a search index for all items currently in the super manifest
+func NewSearchIndex(sm SuperManifestIF) *SearchIndex {
+	idx := &SearchIndex{
+		postings: make(map[string]map[int]*fieldFreqs),
+	}
+	for _, id := range sm.GetBoardIDs() {
+		board, _ := sm.GetBoard(id)
+		if board == nil {
+			continue
+		}
+		ix := idx.addDoc(ItemKindBoard, board.ID, board.Name, board)
+		idx.addText(ix, board.ID, ScoreFieldID)
+		idx.addText(ix, board.Name, ScoreFieldName)
+		idx.addText(ix, board.Category, ScoreFieldCategory)
+		idx.addText(ix, board.ProvCapabilities, ScoreFieldKeyword)
+		idx.addText(ix, board.Summary, ScoreFieldDescription)
+		idx.addText(ix, board.PlainDescription(), ScoreFieldDescription)
+		idx.addText(ix, strings.Join(board.Chips.MCU, " "), ScoreFieldKeyword)
+		idx.addText(ix, strings.Join(board.Chips.Radio, " "), ScoreFieldKeyword)
+	}
+	for _, id := range sm.GetAppIDs() {
+		app, _ := sm.GetApp(id)
+		if app == nil {
+			continue
+		}
+		ix := idx.addDoc(ItemKindApp, app.ID, app.Name, app)
+		idx.addText(ix, app.ID, ScoreFieldID)
+		idx.addText(ix, app.Name, ScoreFieldName)
+		idx.addText(ix, app.Category, ScoreFieldCategory)
+		idx.addText(ix, strings.Join(app.GetKeywords(), " "), ScoreFieldKeyword)
+		idx.addText(ix, app.PlainDescription(), ScoreFieldDescription)
+	}
+	for _, id := range sm.GetMiddlewareIDs() {
+		mw, _ := sm.GetMiddleware(id)
+		if mw == nil {
+			continue
+		}
+		ix := idx.addDoc(ItemKindMiddleware, mw.ID, mw.Name, mw)
+		idx.addText(ix, mw.ID, ScoreFieldID)
+		idx.addText(ix, mw.Name, ScoreFieldName)
+		idx.addText(ix, mw.Category, ScoreFieldCategory)
+		idx.addText(ix, mw.PlainDescription(), ScoreFieldDescription)
+		if readme := mw.Readme(); readme != nil {
+			idx.addText(ix, readme.Text, ScoreFieldReadme)
+		}
+	}
+
+	idx.tokens = make([]string, 0, len(idx.postings))
+	for token := range idx.postings {
+		idx.tokens = append(idx.tokens, token)
+	}
+	sort.Strings(idx.tokens)
+	return idx
+}
+
+func (idx *SearchIndex) addDoc(kind ItemKind, id, name string, item any) int {
+	idx.docs = append(idx.docs, &searchDoc{kind: kind, id: id, name: name, item: item})
+	return len(idx.docs) - 1
+}
+
+func (idx *SearchIndex) addText(docIx int, text string, field ScoreField) {
+	pos := slices.Index(scoreFields, field)
+	for _, token := range tokenize(text) {
+		postings := idx.postings[token]
+		if postings == nil {
+			postings = make(map[int]*fieldFreqs)
+			idx.postings[token] = postings
+		}
+		freqs := postings[docIx]
+		if freqs == nil {
+			freqs = &fieldFreqs{}
+			postings[docIx] = freqs
+		}
+		freqs[pos]++
+	}
+}
+
+// Len returns the number of documents (items) in the index
+func (idx *SearchIndex) Len() int {
+	return len(idx.docs)
+}
+
+// Search returns items matching the query, best match first. Scoring is a term frequency
+// weighted by field (see Scorer) times the inverse document frequency of the term, so rare
+// terms count for more than common ones like "example" or "psoc", times the boost of the item.
+func (idx *SearchIndex) Search(query string, opts *SearchOptions) []*SearchResult {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return []*SearchResult{}
+	}
+
+	scorer := scorerOr(opts.Scorer)
+	var weights fieldFreqs
+	for pos, field := range scoreFields {
+		weights[pos] = scorer.FieldWeight(field)
+	}
+
+	scores := make(map[int]float64)
+	hits := make(map[int]int) // number of query terms that matched each doc
+	numDocs := float64(len(idx.docs))
+	for _, term := range uniqueStrings(terms) {
+		matched := make(map[int]bool)
+		for _, token := range idx.expandTerm(term) {
+			postings := idx.postings[token]
+			factor := 1.0
+			if token != term {
+				factor = searchPrefixFactor
+			}
+			idf := math.Log(1 + numDocs/float64(len(postings)))
+			for docIx, freqs := range postings {
+				tf := 0.0
+				for pos, n := range freqs {
+					tf += n * weights[pos]
+				}
+				scores[docIx] += tf * idf * factor
+				matched[docIx] = true
+			}
+		}
+		for docIx := range matched {
+			hits[docIx]++
+		}
+	}
+
+	numTerms := len(uniqueStrings(terms))
+	results := make([]*SearchResult, 0, len(scores))
+	for docIx, score := range scores {
+		doc := idx.docs[docIx]
+		if !kindSelected(doc.kind, opts.Kinds) {
+			continue
+		}
+		if opts.MatchAll && hits[docIx] < numTerms {
+			continue
+		}
+		// Favor documents matching more of the query terms
+		score *= float64(hits[docIx]) / float64(numTerms)
+		score *= scorer.Boost(doc.kind, doc.item)
+		results = append(results, &SearchResult{
+			Kind:  doc.kind,
+			ID:    doc.id,
+			Name:  doc.name,
+			Score: score,
+			Item:  doc.item,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Kind != results[j].Kind {
+			return results[i].Kind < results[j].Kind
+		}
+		return results[i].ID < results[j].ID
+	})
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results
+}
+
+// expandTerm returns the indexed tokens a query term matches: the term itself plus any token
+// it is a prefix of (for terms of 3 or more characters).
+func (idx *SearchIndex) expandTerm(term string) []string {
+	ret := []string{}
+	if _, ok := idx.postings[term]; ok {
+		ret = append(ret, term)
+	}
+	if len(term) < 3 {
+		return ret
+	}
+	start := sort.SearchStrings(idx.tokens, term)
+	for i := start; i < len(idx.tokens) && strings.HasPrefix(idx.tokens[i], term); i++ {
+		if idx.tokens[i] != term {
+			ret = append(ret, idx.tokens[i])
+		}
+	}
+	return ret
+}
+
+func kindSelected(kind ItemKind, kinds []ItemKind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize lower-cases text and splits it into words on anything that is not a letter or digit.
+// Words joined by punctuation (like "Wi-Fi") also produce their joined form ("wifi") so either
+// spelling finds them. Single character tokens are dropped; they only add noise.
+func tokenize(text string) []string {
+	isSep := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+	ret := []string{}
+	for _, chunk := range strings.Fields(strings.ToLower(text)) {
+		parts := strings.FieldsFunc(chunk, isSep)
+		for _, part := range parts {
+			if len(part) > 1 {
+				ret = append(ret, part)
+			}
+		}
+		if len(parts) > 1 {
+			ret = append(ret, strings.Join(parts, ""))
+		}
+	}
+	return ret
+}
+
+func uniqueStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	ret := make([]string, 0, len(items))
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// Search searches boards, apps and middleware by name, ID, description, keywords and category.
+// The index is built on first use (or at ingest if search indexing is enabled) and rebuilt
+// after manifests are merged.
+func (sm *SuperManifest) Search(query string, opts *SearchOptions) []*SearchResult {
+	return sm.GetSearchIndex().Search(query, opts)
+}
+
+// GetSearchIndex returns the search index for this super manifest, building it if needed. It
+// is safe to call from several goroutines; the index is built once.
+func (sm *SuperManifest) GetSearchIndex() *SearchIndex {
+	return sm.searchIndex.get(func() *SearchIndex { return NewSearchIndex(sm) })
+}
+
+var doBuildSearchIndex = false
+
+// EnableSearchIndexing enables or disables building the search index at ingest time. When
+// disabled, the index is built lazily on the first Search call.
+func EnableSearchIndexing(enable bool) {
+	doBuildSearchIndex = enable
+}
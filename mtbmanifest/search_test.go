@@ -0,0 +1,121 @@
+package mtbmanifest
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	sm := newTestSuperManifest(t)
+
+	tests := []struct {
+		name    string
+		query   string
+		opts    *SearchOptions
+		firstID string
+		count   int
+	}{
+		{
+			name:    "name match ranks first",
+			query:   "hello world",
+			firstID: "mtb-example-hal-hello-world",
+			count:   1,
+		},
+		{
+			name:    "wifi across kinds",
+			query:   "wifi",
+			firstID: "",
+			count:   3,
+		},
+		{
+			name:    "kind filter",
+			query:   "wifi",
+			opts:    &SearchOptions{Kinds: []ItemKind{ItemKindMiddleware}},
+			firstID: "wifi-connection-manager",
+			count:   1,
+		},
+		{
+			name:    "prefix match",
+			query:   "caps",
+			opts:    &SearchOptions{Kinds: []ItemKind{ItemKindApp}},
+			firstID: "mtb-example-capsense-buttons-slider",
+			count:   1,
+		},
+		{
+			name:    "markup is not indexed",
+			query:   "strong",
+			count:   0,
+			firstID: "",
+		},
+		{
+			name:    "match all",
+			query:   "psoc kit capsense",
+			opts:    &SearchOptions{MatchAll: true},
+			firstID: "CY8CKIT-149",
+			count:   1,
+		},
+		{
+			name:    "limit",
+			query:   "wifi",
+			opts:    &SearchOptions{Limit: 2},
+			firstID: "",
+			count:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := sm.Search(tt.query, tt.opts)
+			if len(results) != tt.count {
+				t.Fatalf("expected %d results, got %d", tt.count, len(results))
+			}
+			if tt.firstID != "" && results[0].ID != tt.firstID {
+				t.Errorf("expected first result %q, got %q", tt.firstID, results[0].ID)
+			}
+			for i := 1; i < len(results); i++ {
+				if results[i].Score > results[i-1].Score {
+					t.Errorf("results not sorted by score at %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("Wi-Fi TCP/IP, CY8CKIT-062S2 a")
+	expected := []string{"wi", "fi", "wifi", "tcp", "ip", "tcpip", "cy8ckit", "062s2", "cy8ckit062s2"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("token %d: expected %q, got %q", i, expected[i], tokens[i])
+		}
+	}
+}
+
+func TestSearchIndexConcurrentBuild(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	var wg sync.WaitGroup
+	searchIndexes := make([]*SearchIndex, 8)
+	keywordIndexes := make([]*KeywordIndex, 8)
+	for i := range searchIndexes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			searchIndexes[i] = sm.GetSearchIndex()
+			keywordIndexes[i] = sm.GetKeywordIndex()
+			_ = sm.Search("wifi", nil)
+		}()
+	}
+	wg.Wait()
+	for i := range searchIndexes {
+		if searchIndexes[i] != searchIndexes[0] || keywordIndexes[i] != keywordIndexes[0] {
+			t.Fatal("expected the indexes to be built once")
+		}
+	}
+	sm.clearMaps()
+	if sm.GetSearchIndex() == searchIndexes[0] {
+		t.Error("expected the search index to be rebuilt after the tree changed")
+	}
+}
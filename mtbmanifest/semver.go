@@ -90,3 +90,57 @@ func (v *SemanticVersion) Compare(other *SemanticVersion) int {
 	}
 	return 0
 }
+
+// CompareStrict is a total ordering of versions, suitable for sorting. Unlike Compare, an "X"
+// is not a wildcard: it sorts above any concrete number, so "latest-v4.X" comes after
+// "release-v4.1.0" but before "release-v5.0.0". A nil version sorts before everything.
+func CompareStrict(a, b *SemanticVersion) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	strictCmp := func(x, y int) int {
+		if x == -1 {
+			x = int(^uint(0) >> 1)
+		}
+		if y == -1 {
+			y = int(^uint(0) >> 1)
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	if c := strictCmp(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := strictCmp(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	return strictCmp(a.Patch, b.Patch)
+}
+
+// NewestVersion parses each of the version strings and returns the newest one according to
+// CompareStrict. Strings without a recognizable version are skipped. Returns nil if none parse.
+func NewestVersion(versions []string) *SemanticVersion {
+	var newest *SemanticVersion
+	for _, s := range versions {
+		v, err := ParseVersion(s)
+		if err != nil {
+			continue
+		}
+		if CompareStrict(v, newest) > 0 {
+			newest = v
+		}
+	}
+	return newest
+}
@@ -0,0 +1,209 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Signed manifests
+// ////////////////////////////////////////////////////////////////////////
+
+// With WithSignatureVerification the cache downloads a signature next to every manifest and
+// refuses manifests that are not signed by one of the configured keys, before anything is
+// cached or parsed. The signature is looked for at the manifest URL plus each of
+// SignatureSuffixes:
+//   - ".sig" is a detached signature, raw or base64, as written by
+//     "openssl pkeyutl -sign" or "cosign sign-blob --key"
+//   - ".sigstore.json" is a sigstore bundle made with a key ("cosign sign-blob --key
+//     --bundle"). Only its message signature is checked against the configured keys; keyless
+//     bundles, whose certificate and transparency log entry would need sigstore's roots, are
+//     not supported.
+//
+// Ed25519 signatures are over the manifest itself, ECDSA and RSA (PKCS #1 v1.5 or PSS) ones
+// over its SHA-256 digest.
+//
+// Content is verified where it enters the cache: when it is downloaded, and when a bundle or
+// an export is imported (see Import), which then needs the network for the signatures. The
+// embedded snapshot is unsigned and is not used by a cache that verifies. What is already in
+// the store is trusted as it is: a store shared with caches that do not verify, or that others
+// can write to, defeats verification.
+
+// SignatureSuffixes are appended to a manifest URL to find its signature, tried in order
+var SignatureSuffixes = []string{".sig", ".sigstore.json"}
+
+var (
+	// ErrSignatureMissing is returned when no signature could be found for a manifest
+	ErrSignatureMissing = errors.New("manifest is not signed")
+	// ErrSignatureInvalid is returned when a signature does not verify with any configured key
+	ErrSignatureInvalid = errors.New("manifest signature does not verify")
+)
+
+// ParsePublicKeys parses the PEM "PUBLIC KEY" blocks of data. Ed25519, ECDSA and RSA keys are
+// supported.
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	ret := []crypto.PublicKey{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %v", err)
+		}
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+			ret = append(ret, key)
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no PEM public keys found")
+	}
+	return ret, nil
+}
+
+// LoadPublicKeys reads the PEM public keys of files, for WithSignatureVerification
+func LoadPublicKeys(files ...string) ([]crypto.PublicKey, error) {
+	ret := []crypto.PublicKey{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public keys: %v", err)
+		}
+		keys, err := ParsePublicKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		ret = append(ret, keys...)
+	}
+	return ret, nil
+}
+
+// WithSignatureVerification makes the cache accept only manifests signed by one of keys
+func WithSignatureVerification(keys ...crypto.PublicKey) CacheOption {
+	return func(c *ManifestCache) {
+		c.verifyKeys = append(c.verifyKeys, keys...)
+	}
+}
+
+// VerifySignature checks a signature of data against keys and returns nil if any of them
+// verifies it
+func VerifySignature(data, sig []byte, keys []crypto.PublicKey) error {
+	digest := sha256.Sum256(data)
+	for _, key := range keys {
+		var ok bool
+		switch k := key.(type) {
+		case ed25519.PublicKey:
+			ok = ed25519.Verify(k, data, sig)
+		case *ecdsa.PublicKey:
+			ok = ecdsa.VerifyASN1(k, digest[:], sig)
+		case *rsa.PublicKey:
+			ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil ||
+				rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) == nil
+		}
+		if ok {
+			return nil
+		}
+	}
+	return ErrSignatureInvalid
+}
+
+// decodeSignature accepts a raw or a base64 signature
+func decodeSignature(data []byte) []byte {
+	if sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data))); err == nil {
+		return sig
+	}
+	return data
+}
+
+// sigstoreBundle is the part of a sigstore bundle that signs a blob with a key
+type sigstoreBundle struct {
+	MessageSignature *struct {
+		MessageDigest struct {
+			Algorithm string `json:"algorithm"`
+			Digest    []byte `json:"digest"`
+		} `json:"messageDigest"`
+		Signature []byte `json:"signature"`
+	} `json:"messageSignature"`
+}
+
+// bundleSignature returns the signature of a sigstore bundle after checking that the bundle
+// is for data
+func bundleSignature(bundle, data []byte) ([]byte, error) {
+	var b sigstoreBundle
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		return nil, fmt.Errorf("corrupt sigstore bundle: %v", err)
+	}
+	if b.MessageSignature == nil {
+		return nil, fmt.Errorf("sigstore bundle has no message signature")
+	}
+	md := b.MessageSignature.MessageDigest
+	digest := sha256.Sum256(data)
+	if len(md.Digest) > 0 && (md.Algorithm != "SHA2_256" || !bytes.Equal(md.Digest, digest[:])) {
+		return nil, fmt.Errorf("%w: sigstore bundle is for other content", ErrSignatureInvalid)
+	}
+	return b.MessageSignature.Signature, nil
+}
+
+// verifyEntries checks the signatures of content about to be put into the cache, by URL, if
+// the cache verifies signatures
+func (c *ManifestCache) verifyEntries(ctx context.Context, contents map[string][]byte) error {
+	if len(c.verifyKeys) == 0 {
+		return nil
+	}
+	urls := slices.Sorted(maps.Keys(contents))
+	for _, urlStr := range urls {
+		if err := c.verifySignature(ctx, urlStr, contents[urlStr]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifySignature downloads the signature of a manifest and checks it
+func (c *ManifestCache) verifySignature(ctx context.Context, urlStr string, data []byte) error {
+	for _, suffix := range SignatureSuffixes {
+		sigData, err := c.httpGet(ctx, urlStr+suffix)
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("fetching signature of %s: %w", urlStr, err)
+		}
+		sig := decodeSignature(sigData)
+		if strings.HasSuffix(suffix, ".json") {
+			if sig, err = bundleSignature(sigData, data); err != nil {
+				return fmt.Errorf("%s: %w", urlStr, err)
+			}
+		}
+		if err := VerifySignature(data, sig, c.verifyKeys); err != nil {
+			return fmt.Errorf("%s: %w", urlStr, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: %w", urlStr, ErrSignatureMissing)
+}
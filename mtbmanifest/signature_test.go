@@ -0,0 +1,140 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignatureVerification(t *testing.T) {
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	ecPriv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	der, _ := x509.MarshalPKIXPublicKey(edPub)
+	ecDer, _ := x509.MarshalPKIXPublicKey(&ecPriv.PublicKey)
+	pemData := append(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecDer})...)
+	keys, err := ParsePublicKeys(pemData)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("expected two keys, got %d, %v", len(keys), err)
+	}
+
+	content := []byte("<super-manifest/>")
+	digest := sha256.Sum256(content)
+	ecSig, _ := ecdsa.SignASN1(rand.Reader, ecPriv, digest[:])
+	bundle, _ := json.Marshal(map[string]any{
+		"messageSignature": map[string]any{
+			"messageDigest": map[string]any{"algorithm": "SHA2_256", "digest": digest[:]},
+			"signature":     ecSig,
+		},
+	})
+	files := map[string][]byte{
+		"/signed.xml":                content,
+		"/signed.xml.sig":            []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(edPriv, content))),
+		"/bundled.xml":               content,
+		"/bundled.xml.sigstore.json": bundle,
+		"/forged.xml":                content,
+		"/forged.xml.sig":            ed25519.Sign(otherPriv, content),
+		"/unsigned.xml":              content,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour), WithSignatureVerification(keys...))
+	defer cache.Close()
+	for _, name := range []string{"/signed.xml", "/bundled.xml"} {
+		if data, err := cache.Get(server.URL + name); err != nil || string(data) != string(content) {
+			t.Errorf("%s: expected the signed manifest, got %q, %v", name, data, err)
+		}
+	}
+	if _, err := cache.Get(server.URL + "/forged.xml"); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("expected ErrSignatureInvalid, got %v", err)
+	}
+	if _, err := cache.Get(server.URL + "/unsigned.xml"); !errors.Is(err, ErrSignatureMissing) {
+		t.Errorf("expected ErrSignatureMissing, got %v", err)
+	}
+	if _, err := cache.store.Get(server.URL + "/forged.xml"); err == nil {
+		t.Error("a manifest that failed verification must not be cached")
+	}
+
+	// Imported content is verified like downloaded content, all of it before any is stored
+	exportOf := func(urls ...string) *bytes.Buffer {
+		src := NewManifestCache(WithStore(NewMemoryStore()))
+		defer src.Close()
+		for _, u := range urls {
+			if err := src.writeCache(u, content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf bytes.Buffer
+		if _, err := src.Export(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+	verifying := NewManifestCache(WithStore(NewMemoryStore()), WithSignatureVerification(keys...))
+	defer verifying.Close()
+	if _, err := verifying.Import(exportOf(server.URL+"/signed.xml", server.URL+"/unsigned.xml")); !errors.Is(err, ErrSignatureMissing) {
+		t.Errorf("expected the unsigned entry to be refused, got %v", err)
+	}
+	if _, err := verifying.store.Get(server.URL + "/signed.xml"); err == nil {
+		t.Error("nothing must be imported when an entry fails verification")
+	}
+	if _, err := verifying.Import(exportOf(server.URL + "/signed.xml")); err != nil {
+		t.Errorf("expected the signed entry to be imported: %v", err)
+	}
+
+	// The embedded snapshot is unsigned and not used by a verifying cache
+	snapshot := &BundleIndex{Format: BundleFormatVersion, Created: time.Now().UTC(), RootURLs: []string{SuperManifestURL},
+		Entries: []*BundleEntry{{URL: SuperManifestURL, Size: len(content), SHA256: hashContent(content)}}}
+	var snapshotData bytes.Buffer
+	if err := writeBundle(&snapshotData, snapshot, map[string][]byte{hashContent(content): content}); err != nil {
+		t.Fatal(err)
+	}
+	saved := readSnapshotData
+	readSnapshotData = func() ([]byte, error) { return snapshotData.Bytes(), nil }
+	defer func() { readSnapshotData = saved }()
+	if seedCacheFromSnapshot(verifying, SuperManifestURL, logger) != nil {
+		t.Error("a verifying cache must not fall back to the unsigned snapshot")
+	}
+
+	// What is in the store is trusted: a store shared with a cache that does not verify is not
+	// checked again (see signature.go)
+	shared := NewMemoryStore()
+	if err := shared.Put(server.URL+"/unsigned.xml", content, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewManifestCache(WithStore(shared), WithCacheTTL(time.Hour), WithSignatureVerification(keys...))
+	defer reader.Close()
+	if data, err := reader.Get(server.URL + "/unsigned.xml"); err != nil || string(data) != string(content) {
+		t.Errorf("expected the shared store's entry to be served as it is, got %q, %v", data, err)
+	}
+
+	if err := VerifySignature([]byte("other"), ecSig, []crypto.PublicKey{&ecPriv.PublicKey}); err == nil {
+		t.Error("expected a signature over other content to fail")
+	}
+	if _, err := ParsePublicKeys([]byte("not a key")); err == nil {
+		t.Error("expected an error without PEM keys")
+	}
+}
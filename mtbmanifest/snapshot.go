@@ -0,0 +1,94 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"io/fs"
+	"slices"
+	"time"
+)
+
+//go:generate go run ../cmd/gomtb-manifest bundle create snapshot/manifests.tar.gz
+
+// snapshotFS holds the offline bundle of the default manifest tree (see snapshot/README.md)
+//
+//go:embed snapshot
+var snapshotFS embed.FS
+
+const snapshotFile = "snapshot/manifests.tar.gz"
+
+// readSnapshotData returns the raw embedded bundle. Tests replace it.
+var readSnapshotData = func() ([]byte, error) {
+	return snapshotFS.ReadFile(snapshotFile)
+}
+
+// ErrNoSnapshot is returned when the library was built without an embedded snapshot
+var ErrNoSnapshot = errors.New("no embedded manifest snapshot")
+
+// WithSnapshotFallback enables or disables falling back to the embedded snapshot for this
+// ingestion: when the super manifest can be neither fetched nor found in the cache, the
+// snapshot is installed into the cache and used instead. On by default.
+func WithSnapshotFallback(enable bool) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.snapshotFallback = enable
+	}
+}
+
+// EmbeddedSnapshot returns the index of the embedded snapshot, which tells when it was
+// created and which super manifest it holds
+func EmbeddedSnapshot() (*BundleIndex, error) {
+	index, _, err := readEmbeddedSnapshot()
+	return index, err
+}
+
+func readEmbeddedSnapshot() (*BundleIndex, map[string][]byte, error) {
+	data, err := readSnapshotData()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNoSnapshot
+	} else if err != nil {
+		return nil, nil, err
+	}
+	return ReadBundle(bytes.NewReader(data))
+}
+
+// seedCacheFromSnapshot installs the entries of the embedded snapshot that are missing from the
+// cache, if the snapshot holds the super manifest at urlStr. Entries are back-dated past the
+// cache TTL so they are refreshed as soon as the network is back. Returns the snapshot index,
+// or nil if the snapshot could not be used. Warnings go to logger.
+func seedCacheFromSnapshot(cache *ManifestCache, urlStr string, logger LoggerIF) *BundleIndex {
+	if len(cache.verifyKeys) > 0 {
+		return nil // unsigned, see signature.go
+	}
+	index, contents, err := readEmbeddedSnapshot()
+	if err != nil {
+		if !errors.Is(err, ErrNoSnapshot) {
+			logger.Warningf("Embedded manifest snapshot is unusable: %v\n", err)
+		}
+		return nil
+	}
+	if !slices.Contains(index.RootURLs, urlStr) {
+		return nil
+	}
+	for _, entry := range index.Entries {
+		if _, err := cache.readCache(entry.URL); err == nil {
+			continue // never overwrite something fetched for real
+		}
+		stale := index.Created.Add(-cache.TTL(entry.URL))
+		if err := cache.store.Put(entry.URL, contents[entry.URL], stale); err != nil {
+			logger.Warningf("Failed to install %s from the embedded snapshot: %v\n", entry.URL, err)
+		}
+	}
+	logger.Warningf("Using the embedded manifest snapshot from %s (%d days old)\n",
+		index.Created.Format("2006-01-02"), int(time.Since(index.Created).Hours()/24))
+	return index
+}
+
+// FromSnapshot reports whether the tree was loaded from the embedded snapshot because the
+// network was unavailable, and when that snapshot was created
+func (sm *SuperManifest) FromSnapshot() (time.Time, bool) {
+	if sm.snapshot == nil {
+		return time.Time{}, false
+	}
+	return sm.snapshot.Created, true
+}
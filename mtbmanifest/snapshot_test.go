@@ -0,0 +1,87 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestEmbeddedSnapshot checks the snapshot when there is one. Generate it with make snapshot.
+func TestEmbeddedSnapshot(t *testing.T) {
+	index, err := EmbeddedSnapshot()
+	if errors.Is(err, ErrNoSnapshot) {
+		t.Skipf("%s is missing, run make snapshot", snapshotFile)
+	} else if err != nil {
+		t.Fatalf("embedded snapshot is broken: %v", err)
+	}
+	if !slices.Contains(index.RootURLs, SuperManifestURL) {
+		t.Errorf("expected the snapshot to hold %s, got %v", SuperManifestURL, index.RootURLs)
+	}
+	if len(index.Entries) < 4 {
+		t.Errorf("expected the snapshot to hold the whole tree, got %d files", len(index.Entries))
+	}
+}
+
+func TestSnapshotFallback(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	superURL := server.URL + "/super.xml"
+	created := time.Now().Add(-48 * time.Hour).UTC()
+	index := &BundleIndex{Format: BundleFormatVersion, Created: created, RootURLs: []string{superURL}}
+	contents := map[string][]byte{}
+	for _, u := range []string{superURL, server.URL + "/boards.xml"} {
+		data := []byte("snapshot of " + u)
+		entry := &BundleEntry{URL: u, Size: len(data), SHA256: hashContent(data)}
+		index.Entries = append(index.Entries, entry)
+		contents[entry.SHA256] = data
+	}
+	var buf bytes.Buffer
+	if err := writeBundle(&buf, index, contents); err != nil {
+		t.Fatal(err)
+	}
+	saved := readSnapshotData
+	readSnapshotData = func() ([]byte, error) { return buf.Bytes(), nil }
+	defer func() { readSnapshotData = saved }()
+
+	cache := NewManifestCache(WithDir(t.TempDir()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	// Something already fetched for real must not be replaced by the snapshot
+	if err := cache.writeCache(server.URL+"/boards.xml", []byte("fresh")); err != nil {
+		t.Fatal(err)
+	}
+
+	if seedCacheFromSnapshot(cache, server.URL+"/other.xml", logger) != nil {
+		t.Error("the snapshot should only be used for the super manifest it holds")
+	}
+	if seedCacheFromSnapshot(cache, superURL, logger) == nil {
+		t.Fatal("expected the snapshot to be used")
+	}
+	if data, _ := cache.readCache(superURL); string(data) != "snapshot of "+superURL {
+		t.Errorf("expected the super manifest from the snapshot, got %q", data)
+	}
+	if data, _ := cache.readCache(server.URL + "/boards.xml"); string(data) != "fresh" {
+		t.Errorf("expected the fresh boards manifest to be kept, got %q", data)
+	}
+	info, err := cache.Store().Stat(superURL)
+	if err != nil || time.Since(info.ModTime) < cache.ttl {
+		t.Error("entries from the snapshot should be stale so they get refreshed")
+	}
+
+	// The super manifest is not served, so an ingestion falls back to the snapshot unless told
+	// not to
+	for _, enable := range []bool{true, false} {
+		dir := t.TempDir()
+		_, _ = NewSuperManifestFromURL(superURL, WithCacheDir(dir), WithSnapshotFallback(enable),
+			WithIngestHistory(nil))
+		check := NewManifestCache(WithDir(dir))
+		_, err := check.readCache(superURL)
+		check.Close()
+		if seeded := err == nil; seeded != enable {
+			t.Errorf("with the fallback enabled %v, expected the snapshot installed %v", enable, seeded)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Pinned snapshots
+// ////////////////////////////////////////////////////////////////////////
+
+// A stored snapshot is an offline bundle of a manifest tree, kept under its ID (see
+// BundleIndex.ID) so that ingestion can be pinned to it: two runs weeks apart that load the
+// same snapshot see identical data, whatever happened upstream in between. The bundle index is
+// the snapshot's metadata: when it was created, the super manifest URLs it was made from and
+// the hash of every file.
+
+// SnapshotStore keeps snapshots as <id>.tar.gz files in a directory
+type SnapshotStore struct {
+	dir string
+}
+
+const snapshotExt = ".tar.gz"
+
+// NewSnapshotStore creates a store in dir. An empty dir means ~/.modustoolbox/mtbmcp/snapshots.
+// The directory is created when the first snapshot is saved.
+func NewSnapshotStore(dir string) *SnapshotStore {
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".modustoolbox", "mtbmcp", "snapshots")
+	}
+	return &SnapshotStore{dir: dir}
+}
+
+// Dir returns the directory the store keeps its snapshots in
+func (ss *SnapshotStore) Dir() string {
+	return ss.dir
+}
+
+func (ss *SnapshotStore) path(id string) string {
+	return filepath.Join(ss.dir, id+snapshotExt)
+}
+
+// Create saves a snapshot of the manifest tree and returns its index. File contents come from
+// the cache; a nil cache means the default cache. Saving the same tree twice yields the same
+// ID and keeps one file.
+func (ss *SnapshotStore) Create(sm SuperManifestIF, cache *ManifestCache) (*BundleIndex, error) {
+	if err := os.MkdirAll(ss.dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(ss.dir, "snapshot-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	index, err := CreateBundle(f, sm, cache)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), ss.path(index.ID()))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+	return index, nil
+}
+
+// List returns the index of every stored snapshot, oldest first
+func (ss *SnapshotStore) List() ([]*BundleIndex, error) {
+	files, err := filepath.Glob(filepath.Join(ss.dir, "*"+snapshotExt))
+	if err != nil {
+		return nil, err
+	}
+	ret := []*BundleIndex{}
+	for _, file := range files {
+		index, _, err := ss.read(file)
+		if err != nil {
+			logger.Warningf("Skipping snapshot %s: %v\n", file, err)
+			continue
+		}
+		ret = append(ret, index)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].Created.Equal(ret[j].Created) {
+			return ret[i].Created.Before(ret[j].Created)
+		}
+		return ret[i].ID() < ret[j].ID()
+	})
+	return ret, nil
+}
+
+// Find returns the full ID of the snapshot whose ID starts with prefix, so short IDs can be
+// used like git commit hashes
+func (ss *SnapshotStore) Find(prefix string) (string, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return "", fmt.Errorf("empty snapshot ID")
+	}
+	files, err := filepath.Glob(filepath.Join(ss.dir, "*"+snapshotExt))
+	if err != nil {
+		return "", err
+	}
+	matches := []string{}
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), snapshotExt)
+		if strings.HasPrefix(id, prefix) {
+			matches = append(matches, id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", notFoundSnapshot(prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("snapshot ID %q is ambiguous, it matches %d snapshots", prefix, len(matches))
+	}
+}
+
+func notFoundSnapshot(id string) error {
+	return fmt.Errorf("snapshot %s: %w", id, os.ErrNotExist)
+}
+
+func (ss *SnapshotStore) read(file string) (*BundleIndex, map[string][]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ReadBundle(f)
+}
+
+// Get returns the index of a snapshot. The ID may be shortened (see Find).
+func (ss *SnapshotStore) Get(id string) (*BundleIndex, error) {
+	full, err := ss.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	index, _, err := ss.read(ss.path(full))
+	return index, err
+}
+
+// Load ingests the manifest tree of a snapshot without touching the network or the manifest
+// cache. The ID may be shortened (see Find).
+func (ss *SnapshotStore) Load(id string) (SuperManifestIF, error) {
+	full, err := ss.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	index, contents, err := ss.read(ss.path(full))
+	if err != nil {
+		return nil, err
+	}
+	if index.IsDelta() {
+		return nil, fmt.Errorf("snapshot %s is a delta bundle and cannot be loaded on its own", full)
+	}
+	if len(index.RootURLs) == 0 {
+		return nil, fmt.Errorf("snapshot %s has no super manifest", full)
+	}
+
+	// Everything the tree needs is in the snapshot
+	ret, err := superManifestFromContents(contents, index.RootURLs, nil)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %v", full, err)
+	}
+	ret.pinnedSnapshot = full
+	return ret, nil
+}
+
+// FindAsOf returns the index of the snapshot that shows the manifests as they were at t: the
+// latest full snapshot created at or before t. With rootURLs, only snapshots made from all of
+// those super manifests are considered.
+func (ss *SnapshotStore) FindAsOf(t time.Time, rootURLs ...string) (*BundleIndex, error) {
+	list, err := ss.List()
+	if err != nil {
+		return nil, err
+	}
+	var found, oldest *BundleIndex
+	for _, index := range list {
+		if index.IsDelta() || !containsAll(index.RootURLs, rootURLs) {
+			continue
+		}
+		if oldest == nil {
+			oldest = index
+		}
+		if !index.Created.After(t) {
+			found = index // List is oldest first
+		}
+	}
+	switch {
+	case found != nil:
+		return found, nil
+	case oldest != nil:
+		return nil, fmt.Errorf("no snapshot as of %s, the oldest is from %s: %w", t.Format(time.RFC3339),
+			oldest.Created.Format(time.RFC3339), os.ErrNotExist)
+	default:
+		return nil, fmt.Errorf("no snapshot as of %s: %w", t.Format(time.RFC3339), os.ErrNotExist)
+	}
+}
+
+// AsOf loads the manifests as they were at t, from the snapshot FindAsOf picks
+func (ss *SnapshotStore) AsOf(t time.Time, rootURLs ...string) (SuperManifestIF, error) {
+	index, err := ss.FindAsOf(t, rootURLs...)
+	if err != nil {
+		return nil, err
+	}
+	return ss.Load(index.ID())
+}
+
+// containsAll reports whether list holds every one of want
+func containsAll(list, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(list, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// PinnedSnapshot returns the ID of the stored snapshot the tree was loaded from, if any
+func (sm *SuperManifest) PinnedSnapshot() string {
+	return sm.pinnedSnapshot
+}
+
+// PinURLToRef rewrites a GitHub URL of a file so it names the given git ref (a tag, branch or
+// commit SHA) instead of its current one, e.g. pinning the default super manifest URL to
+// release-v2.0.0 or to a commit. Both github.com/<owner>/<repo>/raw/<ref>/<path> and
+// raw.githubusercontent.com/<owner>/<repo>/<ref>/<path> forms are understood.
+//
+// Only the file itself is pinned: the manifests a pinned super manifest points to are still
+// read at the refs it names. Use a stored snapshot to pin a whole tree.
+func PinURLToRef(urlStr, ref string) (string, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return "", err
+	}
+	if ref == "" || strings.ContainsAny(ref, "/ ") {
+		return "", fmt.Errorf("invalid git ref %q", ref)
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	refIndex := -1
+	switch strings.ToLower(u.Host) {
+	case "github.com":
+		if len(parts) > 4 && (parts[2] == "raw" || parts[2] == "blob") {
+			refIndex = 3
+		}
+	case "raw.githubusercontent.com":
+		if len(parts) > 3 {
+			refIndex = 2
+		}
+	}
+	if refIndex < 0 {
+		return "", fmt.Errorf("cannot pin %s to a git ref: not a GitHub file URL", urlStr)
+	}
+	parts[refIndex] = ref
+	u.Path = "/" + strings.Join(parts, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
@@ -0,0 +1,159 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+const testSuperXML = `<super-manifest version="2.0">
+  <board-manifest-list><board-manifest><uri>https://example.com/boards.xml</uri></board-manifest></board-manifest-list>
+  <app-manifest-list><app-manifest><uri>https://example.com/apps.xml</uri></app-manifest></app-manifest-list>
+  <middleware-manifest-list><middleware-manifest><uri>https://example.com/middleware.xml</uri></middleware-manifest></middleware-manifest-list>
+</super-manifest>`
+
+func TestSnapshotStore(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	for u, data := range map[string]string{
+		superURL:                             testSuperXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm, err := newSuperManifestFromCache(superURL, cache)
+	if err != nil {
+		t.Fatalf("failed to ingest: %v", err)
+	}
+
+	store := NewSnapshotStore(t.TempDir())
+	index, err := store.Create(sm, cache)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if again, err := store.Create(sm, cache); err != nil || again.ID() != index.ID() {
+		t.Errorf("the same tree should give the same snapshot ID, got %v, %v", again, err)
+	}
+	list, err := store.List()
+	if err != nil || len(list) != 1 || list[0].ID() != index.ID() || len(list[0].Entries) != 4 {
+		t.Fatalf("unexpected snapshot list %v, %v", list, err)
+	}
+
+	// Upstream changes must not affect a pinned load
+	_ = cache.writeCache("https://example.com/boards.xml", []byte("<boards/>"))
+	pinned, err := store.Load(index.ID()[:8])
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if pinned.PinnedSnapshot() != index.ID() {
+		t.Errorf("expected pinned snapshot %s, got %q", index.ID(), pinned.PinnedSnapshot())
+	}
+	_, hasBoard := pinned.GetBoard("CY8CKIT-149")
+	_, hasApp := pinned.GetApp("mtb-example-hal-hello-world")
+	if !hasBoard || !hasApp {
+		t.Error("expected the snapshot's boards and apps")
+	}
+
+	if _, err := store.Load("ffffffff"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-found error, got %v", err)
+	}
+}
+
+func TestPinURLToRef(t *testing.T) {
+	tests := []struct {
+		url, ref, want string
+	}{
+		{SuperManifestURL, "release-v2.0.0",
+			"https://github.com/Infineon/mtb-super-manifest/raw/release-v2.0.0/mtb-super-manifest-fv2.xml"},
+		{"https://raw.githubusercontent.com/Infineon/mtb-super-manifest/v2.X/mtb-super-manifest-fv2.xml", "0123abc",
+			"https://raw.githubusercontent.com/Infineon/mtb-super-manifest/0123abc/mtb-super-manifest-fv2.xml"},
+	}
+	for _, tt := range tests {
+		got, err := PinURLToRef(tt.url, tt.ref)
+		if err != nil || got != tt.want {
+			t.Errorf("PinURLToRef(%s, %s) = %s, %v; want %s", tt.url, tt.ref, got, err, tt.want)
+		}
+	}
+	if _, err := PinURLToRef("https://example.com/super.xml", "v1"); err == nil {
+		t.Error("expected an error for a non-GitHub URL")
+	}
+	if _, err := PinURLToRef(SuperManifestURL, "a/b"); err == nil {
+		t.Error("expected an error for a ref with a slash")
+	}
+}
+
+func TestSnapshotAsOf(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	for u, data := range map[string]string{
+		superURL:                             testSuperXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := NewSnapshotStore(t.TempDir())
+	// snapshot stores the tree as it is in the cache, as if created at the given time
+	snapshot := func(created time.Time) *BundleIndex {
+		sm, err := newSuperManifestFromCache(superURL, cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := store.Create(sm, cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, contents, err := store.read(store.path(index.ID()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		byHash := make(map[string][]byte)
+		for _, e := range index.Entries {
+			byHash[e.SHA256] = contents[e.URL]
+		}
+		index.Created = created
+		f, err := os.Create(store.path(index.ID()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = f.Close() }()
+		if err := writeBundle(f, index, byHash); err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	first := snapshot(june)
+	_ = cache.writeCache("https://example.com/boards.xml", []byte("<boards/>"))
+	second := snapshot(june.AddDate(0, 1, 0))
+
+	if index, err := store.FindAsOf(june.AddDate(0, 0, 10)); err != nil || index.ID() != first.ID() {
+		t.Fatalf("expected the June snapshot, got %v, %v", index, err)
+	}
+	if index, err := store.FindAsOf(june.AddDate(1, 0, 0), superURL); err != nil || index.ID() != second.ID() {
+		t.Fatalf("expected the July snapshot, got %v, %v", index, err)
+	}
+	sm, err := store.AsOf(june)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sm.GetBoard("CY8CKIT-149"); !ok || sm.PinnedSnapshot() != first.ID() {
+		t.Fatal("expected the boards of the June snapshot")
+	}
+	if _, err := store.FindAsOf(june.AddDate(0, 0, -1)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-found error before the oldest snapshot, got %v", err)
+	}
+	if _, err := store.FindAsOf(june, "https://example.com/other.xml"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-found error for another super manifest, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package mtbmanifest
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// A solution is a starting point for a project: a board, the code examples that exercise what
+// the user asked for, and the middleware that fills the gaps the board does not provide itself.
+//
+// Each wanted term (e.g. "ble", "display", "freertos") is satisfied either by a capability the
+// board provides, or by a middleware item compatible with the board whose ID, name or category
+// mentions the term.
+
+// SolutionOptions controls FindSolutions
+type SolutionOptions struct {
+	// Limit is the maximum number of solutions. 0 means 10, < 0 means no limit.
+	Limit int
+	// MaxApps is the maximum number of code examples per solution. 0 means 5, < 0 means no limit.
+	MaxApps int
+	// AllowPartial also returns boards that satisfy only some of the wanted terms
+	AllowPartial bool
+	// Flow, when set, restricts the boards, code examples and middleware to those with a
+	// version for this flow, e.g. FlowMTB2 to leave out BTSDK boards
+	Flow Flow
+	// ToolsVersion, when set, leaves out the code examples and middleware without a version
+	// these tools can build, and the board capabilities they do not support (see
+	// CapabilityContext)
+	ToolsVersion string
+	// Policy, when set, leaves out the boards, code examples and middleware whose newest
+	// version it blocks
+	Policy *Policy
+	// IncludeObsolete also proposes obsolete boards (see Board.Lifecycle)
+	IncludeObsolete bool
+}
+
+// SolutionMiddleware is a middleware item picked for a solution, with the version to use
+type SolutionMiddleware struct {
+	Item *MiddlewareItem `json:"-"`
+	ID   string          `json:"id"`
+	// Version is the commit of the newest listed version
+	Version string `json:"version"`
+	// Provides lists the wanted terms this middleware satisfies
+	Provides []string `json:"provides"`
+}
+
+// Solution is one proposed board + code examples + middleware bundle
+type Solution struct {
+	Board   *Board `json:"-"`
+	BoardID string `json:"board"`
+	// FromBoard lists the wanted terms the board provides as capabilities
+	FromBoard  []string              `json:"fromBoard"`
+	Middleware []*SolutionMiddleware `json:"middleware"`
+	Apps       []*App                `json:"-"`
+	AppIDs     []string              `json:"apps"`
+	// Missing lists wanted terms nothing satisfies. Only non-empty with AllowPartial.
+	Missing []string `json:"missing,omitempty"`
+	// Lifecycle is the status of the board when it is not active
+	Lifecycle Lifecycle `json:"lifecycle,omitempty"`
+}
+
+// Covered returns the number of wanted terms the solution satisfies
+func (s *Solution) Covered() int {
+	n := len(s.FromBoard)
+	for _, mw := range s.Middleware {
+		n += len(mw.Provides)
+	}
+	return n
+}
+
+// splitWanted splits a list of wanted capabilities into lower-case, de-duplicated terms
+func splitWanted(wanted string) []string {
+	ret := []string{}
+	seen := make(map[string]bool)
+	for _, term := range strings.FieldsFunc(strings.ToLower(wanted), func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t'
+	}) {
+		if !seen[term] {
+			seen[term] = true
+			ret = append(ret, term)
+		}
+	}
+	return ret
+}
+
+// middlewareMentions reports whether a middleware item's ID, name or category mentions a term
+func middlewareMentions(mw *MiddlewareItem, term string) bool {
+	for _, field := range []string{mw.ID, mw.Name, mw.Category} {
+		for _, token := range tokenize(field) {
+			if token == term {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appExercises returns how many of the terms an app's keywords, capabilities, ID or category mention
+func appExercises(a *App, terms []string) int {
+	tokens := make(map[string]bool)
+	for _, k := range a.GetKeywords() {
+		for _, token := range tokenize(k) {
+			tokens[token] = true
+		}
+	}
+	for _, token := range capabilityTokens(a.GetCapabilities()) {
+		tokens[strings.ToLower(token)] = true
+	}
+	for _, field := range []string{a.ID, a.Category} {
+		for _, token := range tokenize(field) {
+			tokens[token] = true
+		}
+	}
+	n := 0
+	for _, term := range terms {
+		if tokens[term] {
+			n++
+		}
+	}
+	return n
+}
+
+// newestCommit returns the commit of the newest version in the list, or the first one if
+// none of them parse as a version
+func newestCommit(commits []string) string {
+	if v := NewestVersion(commits); v != nil {
+		return v.Raw
+	}
+	if len(commits) > 0 {
+		return commits[0]
+	}
+	return ""
+}
+
+// FindSolutions proposes board + code example + middleware bundles for a list of wanted
+// capabilities such as "ble display freertos". Solutions that satisfy more of the wanted
+// terms come first, then those with more matching code examples.
+func FindSolutions(sm SuperManifestIF, wanted string, opts *SolutionOptions) []*Solution {
+	if opts == nil {
+		opts = &SolutionOptions{}
+	}
+	limit, maxApps := opts.Limit, opts.MaxApps
+	if limit == 0 {
+		limit = 10
+	}
+	if maxApps == 0 {
+		maxApps = 5
+	}
+	terms := splitWanted(wanted)
+	if len(terms) == 0 {
+		return []*Solution{}
+	}
+
+	solutions := []*Solution{}
+	for _, id := range sm.GetBoardIDs() {
+		board, ok := sm.GetBoard(id)
+		if !ok || (opts.Flow != "" && !slices.Contains(board.Flows(), opts.Flow)) ||
+			(!opts.IncludeObsolete && board.Lifecycle() == LifecycleObsolete) ||
+			!opts.allows(ItemKindBoard, board.ID, board.BoardURI, board.VersionCommits()) {
+			continue
+		}
+		if s := solutionForBoard(sm, board, terms, maxApps, opts); s.Covered() > 0 &&
+			(len(s.Missing) == 0 || opts.AllowPartial) {
+			solutions = append(solutions, s)
+		}
+	}
+
+	sort.SliceStable(solutions, func(i, j int) bool {
+		a, b := solutions[i], solutions[j]
+		if a.Covered() != b.Covered() {
+			return a.Covered() > b.Covered()
+		}
+		// Prefer boards that need fewer extra libraries, then those with more examples
+		if len(a.Middleware) != len(b.Middleware) {
+			return len(a.Middleware) < len(b.Middleware)
+		}
+		return len(a.Apps) > len(b.Apps)
+	})
+	if limit > 0 && len(solutions) > limit {
+		solutions = solutions[:limit]
+	}
+	return solutions
+}
+
+// allows reports whether the policy of the options, if any, allows the newest of the
+// versions of an item
+func (opts *SolutionOptions) allows(kind ItemKind, id, uri string, commits []string) bool {
+	return opts.Policy == nil || opts.Policy.Allows(kind, id, uri, newestCommit(commits))
+}
+
+func solutionForBoard(sm SuperManifestIF, board *Board, terms []string, maxApps int, opts *SolutionOptions) *Solution {
+	s := &Solution{
+		Board:      board,
+		BoardID:    board.ID,
+		FromBoard:  []string{},
+		Middleware: []*SolutionMiddleware{},
+		Apps:       []*App{},
+		AppIDs:     []string{},
+	}
+	if l := board.Lifecycle(); l != LifecycleActive {
+		s.Lifecycle = l
+	}
+	boardCaps := make(map[string]bool)
+	for _, c := range strings.Fields(strings.ToLower(board.ProvCapabilities)) {
+		boardCaps[c] = true
+	}
+	ctx := NewCapabilityContext(strings.Fields(board.ProvCapabilities))
+	ctx.ToolsVersion, ctx.Flow = opts.ToolsVersion, opts.Flow
+	// Successors come first so that a renamed library wins over its old name
+	compatible := PreferSuccessors(FindMiddlewareForContext(sm, ctx))
+	compatible = slices.DeleteFunc(compatible, func(mw *MiddlewareItem) bool {
+		return (opts.Flow != "" && !slices.Contains(mw.Flows(), opts.Flow)) ||
+			!opts.allows(ItemKindMiddleware, mw.ID, mw.URI, mw.VersionCommits())
+	})
+	picked := make(map[string]*SolutionMiddleware)
+
+	for _, term := range terms {
+		if boardCaps[term] {
+			s.FromBoard = append(s.FromBoard, term)
+			continue
+		}
+		found := false
+		for _, mw := range compatible {
+			if !middlewareMentions(mw, term) {
+				continue
+			}
+			if pick, ok := picked[mw.ID]; ok {
+				pick.Provides = append(pick.Provides, term)
+			} else {
+				pick = &SolutionMiddleware{
+					Item: mw, ID: mw.ID, Version: newestCommit(mw.VersionCommits()),
+					Provides: []string{term},
+				}
+				picked[mw.ID] = pick
+				s.Middleware = append(s.Middleware, pick)
+			}
+			found = true
+			break // one library per term is enough
+		}
+		if !found {
+			s.Missing = append(s.Missing, term)
+		}
+	}
+
+	type rankedApp struct {
+		app   *App
+		score int
+	}
+	ranked := []rankedApp{}
+	for _, app := range FindCodeExamplesForContext(sm, ctx) {
+		if (opts.Flow != "" && !slices.Contains(app.Flows(), opts.Flow)) ||
+			!opts.allows(ItemKindApp, app.ID, app.URI, app.VersionCommits()) {
+			continue
+		}
+		if score := appExercises(app, terms); score > 0 {
+			ranked = append(ranked, rankedApp{app, score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	for _, r := range ranked {
+		if maxApps > 0 && len(s.Apps) >= maxApps {
+			break
+		}
+		s.Apps = append(s.Apps, r.app)
+		s.AppIDs = append(s.AppIDs, r.app.ID)
+	}
+	return s
+}
@@ -0,0 +1,36 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestFindSolutions(t *testing.T) {
+	sm := newTestSuperManifest(t)
+
+	// wifi comes from the board; the connection manager also mentions it but is not needed
+	solutions := FindSolutions(sm, "wifi freertos", nil)
+	if len(solutions) != 1 {
+		t.Fatalf("expected 1 solution, got %d", len(solutions))
+	}
+	s := solutions[0]
+	if s.BoardID != "CY8CKIT-062S2-43012" {
+		t.Errorf("expected the Wi-Fi kit, got %s", s.BoardID)
+	}
+	if len(s.FromBoard) != 1 || s.FromBoard[0] != "wifi" {
+		t.Errorf("expected wifi from the board, got %v", s.FromBoard)
+	}
+	if len(s.Middleware) != 1 || s.Middleware[0].ID != "freertos" || s.Middleware[0].Version != "latest-v10.X" {
+		t.Errorf("expected freertos latest-v10.X, got %+v", s.Middleware)
+	}
+	if len(s.AppIDs) != 1 || s.AppIDs[0] != "mtb-example-wifi-tcp-client" {
+		t.Errorf("expected the TCP client example, got %v", s.AppIDs)
+	}
+
+	if got := FindSolutions(sm, "capsense wifi", nil); len(got) != 0 {
+		t.Errorf("no board has both capsense and wifi, got %d solutions", len(got))
+	}
+	partial := FindSolutions(sm, "capsense wifi", &SolutionOptions{AllowPartial: true})
+	if len(partial) != 2 || len(partial[0].Missing) != 1 {
+		t.Errorf("expected 2 partial solutions each missing one term, got %d", len(partial))
+	}
+}
@@ -0,0 +1,292 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Filtered manifest subsets
+// ////////////////////////////////////////////////////////////////////////
+
+// A mirror republishes a tree as it is. A subset republishes part of it for an audience, e.g.
+// a channel of education kits only: the filter picks the boards, and the code examples and
+// middleware that work with at least one of them go along, with every library the kept
+// boards and libraries depend on, so the subset is a complete tree of its own. Each manifest
+// is written again with only the kept items, at the path a mirror would use (see
+// PublishMirror), and the new super manifest refers to them under baseURL.
+
+// SubsetReport describes an exported subset
+type SubsetReport struct {
+	// SuperManifestURL is the URL of the new super manifest under baseURL
+	SuperManifestURL string        `json:"superManifestUrl"`
+	Files            []*MirrorFile `json:"files"`
+	Boards           int           `json:"boards"`
+	Apps             int           `json:"apps"`
+	Middleware       int           `json:"middleware"`
+}
+
+// subsetFile is a manifest of the subset being assembled, keyed by its original URL
+type subsetFile[T any] struct {
+	url   string
+	items []T
+}
+
+// subsetGroups groups items by the manifest they came from, keeping the order of both
+type subsetGroups[T any] struct {
+	files []*subsetFile[T]
+	byURL map[string]*subsetFile[T]
+}
+
+func (g *subsetGroups[T]) add(urlStr string, item T) {
+	if g.byURL == nil {
+		g.byURL = map[string]*subsetFile[T]{}
+	}
+	f, ok := g.byURL[urlStr]
+	if !ok {
+		f = &subsetFile[T]{url: urlStr}
+		g.byURL[urlStr] = f
+		g.files = append(g.files, f)
+	}
+	f.items = append(f.items, item)
+}
+
+// ExportSubset writes the manifests of the part of the tree whose boards match filter into
+// dir, to be served at baseURL. Items whose manifest is not known are written to
+// boards.xml, apps.xml and middleware.xml.
+func ExportSubset(sm SuperManifestIF, filter *ItemFilter, dir, baseURL string) (*SubsetReport, error) {
+	if filter == nil {
+		filter = &ItemFilter{}
+	}
+	report := &SubsetReport{Files: []*MirrorFile{}}
+	boards := []*Board{}
+	for _, b := range sm.Boards() {
+		if filter.MatchBoard(b) {
+			boards = append(boards, b)
+		}
+	}
+	if len(boards) == 0 {
+		return nil, fmt.Errorf("no board matches %q", filter)
+	}
+	// A version of a board may provide other capabilities than the board itself
+	boardCaps := []map[string]bool{}
+	for _, b := range boards {
+		boardCaps = append(boardCaps, NewCapabilityContext(b.EffectiveCapabilities(nil)).Capabilities)
+		if b.Versions != nil {
+			for _, v := range b.Versions.Versions {
+				boardCaps = append(boardCaps, NewCapabilityContext(b.EffectiveCapabilities(v)).Capabilities)
+			}
+		}
+	}
+	worksWithABoard := func(req func(map[string]bool) bool) bool {
+		for _, caps := range boardCaps {
+			if req(caps) {
+				return true
+			}
+		}
+		return false
+	}
+
+	apps := []*App{}
+	for _, a := range sm.Apps() {
+		if worksWithABoard(a.Matches) {
+			apps = append(apps, a)
+		}
+	}
+	kept := map[string]bool{}
+	middleware := []*MiddlewareItem{}
+	for _, mw := range sm.Middleware() {
+		if req := mw.GetCapabilities(); worksWithABoard(req.Matches) {
+			kept[idKey(mw.ID)] = true
+		}
+	}
+	// Every library a kept board or library depends on goes along
+	pending := []*Depender{}
+	for _, b := range boards {
+		pending = append(pending, b.Dependencies)
+	}
+	for _, mw := range sm.Middleware() {
+		if kept[idKey(mw.ID)] {
+			pending = append(pending, mw.Dependencies)
+		}
+	}
+	for len(pending) > 0 {
+		depender := pending[0]
+		pending = pending[1:]
+		if depender == nil {
+			continue
+		}
+		for _, v := range depender.Versions {
+			for _, dependee := range v.Dependees {
+				if mw, ok := sm.GetMiddleware(dependee.ID); ok && !kept[idKey(mw.ID)] {
+					kept[idKey(mw.ID)] = true
+					pending = append(pending, mw.Dependencies)
+				}
+			}
+		}
+	}
+	for _, mw := range sm.Middleware() {
+		if kept[idKey(mw.ID)] {
+			middleware = append(middleware, mw)
+		}
+	}
+	report.Boards, report.Apps, report.Middleware = len(boards), len(apps), len(middleware)
+
+	// A dependency manifest may be shared by several manifests, so it is assembled whole first
+	dependers := map[string][]*Depender{}
+	for _, b := range boards {
+		if b.Origin != nil && b.Origin.DependencyURL != "" && b.Dependencies != nil {
+			dependers[b.Origin.DependencyURL] = append(dependers[b.Origin.DependencyURL], b.Dependencies)
+		}
+	}
+	for _, mw := range middleware {
+		if mw.Origin != nil && mw.Origin.DependencyURL != "" && mw.Dependencies != nil {
+			dependers[mw.Origin.DependencyURL] = append(dependers[mw.Origin.DependencyURL], mw.Dependencies)
+		}
+	}
+
+	var err error
+	w := &subsetWriter{dir: dir, baseURL: strings.TrimRight(baseURL, "/"), report: report}
+	super := &SuperManifest{
+		Version:                "2.0",
+		BoardManifestList:      &BoardManifestList{BoardManifest: []*BoardManifest{}},
+		AppManifestList:        &AppManifestList{AppManifest: []*AppManifest{}},
+		MiddlewareManifestList: &MiddlewareManifestList{MiddlewareManifest: []*MiddlewareManifest{}},
+	}
+
+	var boardFiles subsetGroups[*Board]
+	boardOrigins := map[string]*BoardManifest{}
+	for _, b := range boards {
+		urlStr := "boards.xml"
+		if b.Origin != nil {
+			urlStr = b.Origin.URI
+			boardOrigins[urlStr] = b.Origin
+		}
+		boardFiles.add(urlStr, b)
+	}
+	for _, f := range boardFiles.files {
+		bm := &BoardManifest{}
+		if bm.URI, err = w.writeXML(f.url, KindBoards, &Boards{Boards: f.items}); err != nil {
+			return nil, err
+		}
+		if origin := boardOrigins[f.url]; origin != nil {
+			if deps := dependers[origin.DependencyURL]; len(deps) > 0 {
+				if bm.DependencyURL, err = w.writeXML(origin.DependencyURL, KindDependencies,
+					&Dependencies{Version: "2.0", Dependers: deps}); err != nil {
+					return nil, err
+				}
+			}
+			if caps := f.items[0].Capabilities; caps != nil && origin.CapabilityURL != "" {
+				if bm.CapabilityURL, err = w.writeJSON(origin.CapabilityURL, KindCapabilities, caps); err != nil {
+					return nil, err
+				}
+			}
+		}
+		super.BoardManifestList.BoardManifest = append(super.BoardManifestList.BoardManifest, bm)
+	}
+
+	var appFiles subsetGroups[*App]
+	for _, a := range apps {
+		urlStr := "apps.xml"
+		if a.Origin != nil {
+			urlStr = a.Origin.URI
+		}
+		appFiles.add(urlStr, a)
+	}
+	for _, f := range appFiles.files {
+		am := &AppManifest{}
+		if am.URI, err = w.writeXML(f.url, KindApps, &Apps{Version: "2.0", App: f.items}); err != nil {
+			return nil, err
+		}
+		super.AppManifestList.AppManifest = append(super.AppManifestList.AppManifest, am)
+	}
+
+	var mwFiles subsetGroups[*MiddlewareItem]
+	mwOrigins := map[string]*MiddlewareManifest{}
+	for _, mw := range middleware {
+		urlStr := "middleware.xml"
+		if mw.Origin != nil {
+			urlStr = mw.Origin.URI
+			mwOrigins[urlStr] = mw.Origin
+		}
+		mwFiles.add(urlStr, mw)
+	}
+	for _, f := range mwFiles.files {
+		mm := &MiddlewareManifest{}
+		if mm.URI, err = w.writeXML(f.url, KindMiddleware, &Middleware{Middlewares: f.items}); err != nil {
+			return nil, err
+		}
+		if origin := mwOrigins[f.url]; origin != nil {
+			if deps := dependers[origin.DependencyURL]; len(deps) > 0 {
+				if mm.DependencyURL, err = w.writeXML(origin.DependencyURL, KindDependencies,
+					&Dependencies{Version: "2.0", Dependers: deps}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		super.MiddlewareManifestList.MiddlewareManifest = append(super.MiddlewareManifestList.MiddlewareManifest, mm)
+	}
+
+	superURL := "super-manifest.xml"
+	if sources := sm.GetSourceURLs(); len(sources) > 0 {
+		superURL = sources[0]
+	}
+	if report.SuperManifestURL, err = w.writeXML(superURL, KindSuper, super); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// subsetWriter writes the files of a subset
+type subsetWriter struct {
+	dir, baseURL string
+	report       *SubsetReport
+	// written are the files written so far, by original URL
+	written map[string]string
+}
+
+// writeXML writes a manifest at the path of its original URL and returns its new URL
+func (w *subsetWriter) writeXML(urlStr string, kind ManifestKind, v any) (string, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("cannot write %s: %v", urlStr, err)
+	}
+	return w.write(urlStr, kind, append([]byte(xml.Header), data...))
+}
+
+// writeJSON is writeXML for JSON manifests
+func (w *subsetWriter) writeJSON(urlStr string, kind ManifestKind, v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("cannot write %s: %v", urlStr, err)
+	}
+	return w.write(urlStr, kind, data)
+}
+
+func (w *subsetWriter) write(urlStr string, kind ManifestKind, data []byte) (string, error) {
+	if w.written == nil {
+		w.written = map[string]string{}
+	}
+	if mirrorURL, ok := w.written[urlStr]; ok {
+		return mirrorURL, nil // a file shared by several manifests
+	}
+	rel, err := mirrorPath(urlStr)
+	if err != nil {
+		rel = filepath.ToSlash(filepath.Clean(urlStr)) // a stand-in name, not a URL
+	}
+	file := &MirrorFile{URL: urlStr, Kind: kind, Path: rel, MirrorURL: w.baseURL + "/" + rel}
+	target := filepath.Join(w.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(target, data, 0o644); err != nil {
+		return "", err
+	}
+	w.written[urlStr] = file.MirrorURL
+	w.report.Files = append(w.report.Files, file)
+	return file.MirrorURL, nil
+}
@@ -0,0 +1,73 @@
+package mtbmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportSubset(t *testing.T) {
+	const superURL = "https://example.com/super.xml"
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour))
+	defer cache.Close()
+	for u, data := range map[string]string{
+		superURL:                             testSuperXML,
+		"https://example.com/boards.xml":     testBoardsXML,
+		"https://example.com/apps.xml":       testAppsXML,
+		"https://example.com/middleware.xml": testMiddlewareXML,
+	} {
+		if err := cache.writeCache(u, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm, err := newSuperManifestFromCache(superURL, cache)
+	if err != nil {
+		t.Fatalf("failed to ingest: %v", err)
+	}
+	filter, err := ParseFilter("id=CY8CKIT-149")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	report, err := ExportSubset(sm, filter, dir, "https://edu.example.org/mtb/")
+	if err != nil {
+		t.Fatalf("ExportSubset failed: %v", err)
+	}
+	if report.SuperManifestURL != "https://edu.example.org/mtb/example.com/super.xml" {
+		t.Errorf("unexpected super manifest URL %s", report.SuperManifestURL)
+	}
+	if report.Boards != 1 || report.Apps != 2 || report.Middleware != 1 || len(report.Files) != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// The subset is a tree of its own at its new URLs
+	contents := map[string][]byte{}
+	for _, file := range report.Files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[file.MirrorURL] = data
+	}
+	subset, err := NewSuperManifestFromContents(contents, []string{report.SuperManifestURL})
+	if err != nil {
+		t.Fatalf("cannot read the subset back: %v", err)
+	}
+	if len(subset.Boards()) != 1 || subset.Boards()[0].ID != "CY8CKIT-149" {
+		t.Errorf("expected only the PSoC 4 kit, got %d boards", len(subset.Boards()))
+	}
+	for _, a := range subset.Apps() {
+		if a.ID == "mtb-example-wifi-tcp-client" {
+			t.Error("expected the Wi-Fi example to be left out")
+		}
+	}
+	if _, ok := subset.GetMiddleware("freertos"); !ok || len(subset.Middleware()) != 1 {
+		t.Errorf("expected only FreeRTOS, got %d libraries", len(subset.Middleware()))
+	}
+
+	if _, err := ExportSubset(sm, &ItemFilter{Terms: []FilterTerm{{Key: "category", Pattern: "none"}}}, dir, "https://x"); err == nil {
+		t.Error("expected an error when no board matches")
+	}
+}
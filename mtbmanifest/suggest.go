@@ -0,0 +1,105 @@
+package mtbmanifest
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Suggestion is a candidate ID returned by FindClosest when an exact lookup fails
+type Suggestion struct {
+	Kind ItemKind `json:"kind"`
+	ID   string   `json:"id"`
+	// Distance is the edit distance between the normalized query and the normalized ID.
+	// Zero means they differ only in case or punctuation.
+	Distance int `json:"distance"`
+}
+
+// Maximum number of suggestions returned by FindClosest
+const maxSuggestions = 5
+
+// normalizeID lower-cases an ID and drops everything that is not a letter or digit, so that
+// "cy8ckit_062s2-43012" and "CY8CKIT-062S2-43012" compare equal
+func normalizeID(id string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(id) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// closestIDs ranks candidate IDs by how close they are to id. Candidates that match after
+// normalization come first, then candidates containing the query (or contained by it), then
+// candidates within an edit distance of roughly a third of the query length.
+func closestIDs(kind ItemKind, id string, candidates []string) []Suggestion {
+	query := normalizeID(id)
+	if query == "" {
+		return []Suggestion{}
+	}
+	maxDist := max(1, len(query)/3)
+	ret := []Suggestion{}
+	for _, candidate := range candidates {
+		norm := normalizeID(candidate)
+		dist := editDistance(query, norm)
+		if dist > maxDist && !strings.Contains(norm, query) && !strings.Contains(query, norm) {
+			continue
+		}
+		ret = append(ret, Suggestion{Kind: kind, ID: candidate, Distance: dist})
+	}
+	return ret
+}
+
+// FindClosest returns up to five IDs of boards, apps or middleware that are close to id,
+// nearest first. Use it to offer "did you mean ...?" when GetBoard, GetApp or GetMiddleware
+// come back empty. Pass kinds to restrict which item types are considered.
+func FindClosest(sm SuperManifestIF, id string, kinds ...ItemKind) []Suggestion {
+	ret := []Suggestion{}
+	if kindSelected(ItemKindBoard, kinds) {
+		ret = append(ret, closestIDs(ItemKindBoard, id, sm.GetBoardIDs())...)
+	}
+	if kindSelected(ItemKindApp, kinds) {
+		ret = append(ret, closestIDs(ItemKindApp, id, sm.GetAppIDs())...)
+	}
+	if kindSelected(ItemKindMiddleware, kinds) {
+		ret = append(ret, closestIDs(ItemKindMiddleware, id, sm.GetMiddlewareIDs())...)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Distance != ret[j].Distance {
+			return ret[i].Distance < ret[j].Distance
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	if len(ret) > maxSuggestions {
+		ret = ret[:maxSuggestions]
+	}
+	return ret
+}
+
+// FindClosest is a convenience wrapper around the package level FindClosest
+func (sm *SuperManifest) FindClosest(id string, kinds ...ItemKind) []Suggestion {
+	return FindClosest(sm, id, kinds...)
+}
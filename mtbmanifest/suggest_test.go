@@ -0,0 +1,77 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"cy8ckit062s243012", "cy8ckit062s243012", 0},
+		{"cy8ckit062s243012", "cy8ckit062s243021", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("editDistance(%q, %q): expected %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestFindClosest(t *testing.T) {
+	sm := newTestSuperManifest(t)
+
+	tests := []struct {
+		name    string
+		id      string
+		kinds   []ItemKind
+		firstID string
+		count   int
+	}{
+		{
+			name:    "case and punctuation",
+			id:      "cy8ckit_062s2_43012",
+			firstID: "CY8CKIT-062S2-43012",
+			count:   1,
+		},
+		{
+			name:    "typo",
+			id:      "CY8CKIT-062S2-43021",
+			firstID: "CY8CKIT-062S2-43012",
+			count:   1,
+		},
+		{
+			name:    "substring",
+			id:      "hello-world",
+			firstID: "mtb-example-hal-hello-world",
+			count:   1,
+		},
+		{
+			name:  "kind filter excludes",
+			id:    "freertos",
+			kinds: []ItemKind{ItemKindBoard},
+			count: 0,
+		},
+		{
+			name:  "nothing close",
+			id:    "something-else-entirely",
+			count: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := sm.FindClosest(tt.id, tt.kinds...)
+			if len(suggestions) != tt.count {
+				t.Fatalf("expected %d suggestions, got %v", tt.count, suggestions)
+			}
+			if tt.firstID != "" && suggestions[0].ID != tt.firstID {
+				t.Errorf("expected first suggestion %q, got %q", tt.firstID, suggestions[0].ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package mtbmanifest
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Middleware supersession
+// ////////////////////////////////////////////////////////////////////////
+
+// Libraries get renamed and rewritten, and the manifest keeps listing the old asset next to
+// its successor. Which one replaces which comes from an explicit mapping for one tree or for
+// all (see WithMiddlewareSupersessions and SetMiddlewareSupersessions), or else from the
+// description, when it says "superseded by <id>", "replaced by <id>" or "use <id> instead".
+// Resolvers use this to pick the successor when both would do.
+
+var (
+	middlewareSupersessionsMu sync.RWMutex
+	// middlewareSupersessions maps the idKey of an old middleware ID to the ID replacing it
+	middlewareSupersessions = map[string]string{}
+)
+
+// SetMiddlewareSupersessions sets which middleware replaces which, as old ID -> new ID. These
+// take precedence over what descriptions say. Pass nil to rely on descriptions only. Trees
+// ingested WithMiddlewareSupersessions use their own mapping instead.
+func SetMiddlewareSupersessions(supersessions map[string]string) {
+	m := supersessionTable(supersessions)
+	middlewareSupersessionsMu.Lock()
+	defer middlewareSupersessionsMu.Unlock()
+	middlewareSupersessions = m
+}
+
+// WithMiddlewareSupersessions sets which middleware of this tree replaces which, as old ID ->
+// new ID, in place of the mapping of SetMiddlewareSupersessions
+func WithMiddlewareSupersessions(supersessions map[string]string) IngestOption {
+	m := supersessionTable(supersessions)
+	return func(cfg *ingestConfig) {
+		cfg.supersessions = m
+	}
+}
+
+// supersessionTable returns supersessions keyed by the idKey of the old IDs
+func supersessionTable(supersessions map[string]string) map[string]string {
+	m := make(map[string]string, len(supersessions))
+	for oldID, newID := range supersessions {
+		m[idKey(oldID)] = newID
+	}
+	return m
+}
+
+// supersessionTableOf returns the mapping that applies to a library: that of the ingestion of
+// its manifest, or else that of SetMiddlewareSupersessions
+func supersessionTableOf(mw *MiddlewareItem) map[string]string {
+	if mw.Origin != nil && mw.Origin.ingestCfg != nil && mw.Origin.ingestCfg.supersessions != nil {
+		return mw.Origin.ingestCfg.supersessions
+	}
+	middlewareSupersessionsMu.RLock()
+	defer middlewareSupersessionsMu.RUnlock()
+	return middlewareSupersessions
+}
+
+var (
+	// A successor named in a description must look like an asset ID, with a '-' or '_' in
+	// it, so that "use the new API instead" does not name one
+	successorRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:superseded|replaced)\s+by\s+(?:the\s+)?([A-Za-z0-9][A-Za-z0-9_.-]*[-_][A-Za-z0-9_.-]*)`),
+		regexp.MustCompile(`(?i)\buse\s+(?:the\s+)?([A-Za-z0-9][A-Za-z0-9_.-]*[-_][A-Za-z0-9_.-]*)\s+(?:library\s+|middleware\s+)?instead`),
+	}
+	deprecatedRegex = regexp.MustCompile(`(?i)\b(?:deprecated|obsolete|not recommended for new designs)\b`)
+)
+
+// ReplacedBy returns the ID of the middleware that supersedes this one, or "" if there is
+// none
+func (mw *MiddlewareItem) ReplacedBy() string {
+	if newID, ok := supersessionTableOf(mw)[idKey(mw.ID)]; ok {
+		return newID
+	}
+	desc := mw.PlainDescription()
+	for _, re := range successorRegexes {
+		if m := re.FindStringSubmatch(desc); m != nil {
+			if successor := strings.TrimRight(m[1], "."); !SameID(successor, mw.ID) {
+				return successor
+			}
+		}
+	}
+	return ""
+}
+
+// Deprecated reports whether the middleware is superseded or its name or description says
+// it is deprecated
+func (mw *MiddlewareItem) Deprecated() bool {
+	return mw.ReplacedBy() != "" || deprecatedRegex.MatchString(mw.Name) ||
+		deprecatedRegex.MatchString(mw.PlainDescription())
+}
+
+// SuccessorOf follows the ReplacedBy chain of mw through the tree and returns the newest
+// middleware it leads to, or nil if mw is not superseded by anything the tree lists
+func SuccessorOf(sm SuperManifestIF, mw *MiddlewareItem) *MiddlewareItem {
+	var ret *MiddlewareItem
+	seen := map[string]bool{idKey(mw.ID): true}
+	for next := mw.ReplacedBy(); next != "" && !seen[idKey(next)]; {
+		seen[idKey(next)] = true
+		item, ok := sm.GetMiddleware(next)
+		if !ok {
+			break
+		}
+		ret = item
+		next = item.ReplacedBy()
+	}
+	return ret
+}
+
+// PreferSuccessors returns items with the deprecated middleware moved after the others,
+// keeping the order otherwise, so that whoever takes the first match takes a successor when
+// there is one
+func PreferSuccessors(items []*MiddlewareItem) []*MiddlewareItem {
+	ret := slices.Clone(items)
+	slices.SortStableFunc(ret, func(a, b *MiddlewareItem) int {
+		da, db := a.Deprecated(), b.Deprecated()
+		switch {
+		case da == db:
+			return 0
+		case da:
+			return 1
+		}
+		return -1
+	})
+	return ret
+}
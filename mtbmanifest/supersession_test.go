@@ -0,0 +1,71 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestMiddlewareSupersession(t *testing.T) {
+	defer SetMiddlewareSupersessions(nil)
+	sm := newTestSuperManifest(t)
+	wcm, _ := sm.GetMiddleware("wifi-connection-manager")
+	freertos, _ := sm.GetMiddleware("freertos")
+
+	tests := []struct {
+		desc       string
+		replacedBy string
+		deprecated bool
+	}{
+		{"Wi-Fi Connection Manager (WCM) library", "", false},
+		{"This library is superseded by wifi-core-freertos-lwip-mbedtls.", "wifi-core-freertos-lwip-mbedtls", true},
+		{"Deprecated: use <b>wifi_host_driver</b> instead", "wifi_host_driver", true},
+		{"Use the new API instead. This version is obsolete.", "", true},
+	}
+	for _, tt := range tests {
+		wcm.Description = tt.desc
+		if got := wcm.ReplacedBy(); got != tt.replacedBy {
+			t.Errorf("%q: expected successor %q, got %q", tt.desc, tt.replacedBy, got)
+		}
+		if got := wcm.Deprecated(); got != tt.deprecated {
+			t.Errorf("%q: expected deprecated %v, got %v", tt.desc, tt.deprecated, got)
+		}
+	}
+
+	// An explicit mapping wins, and successors are looked up in the tree
+	wcm.Description = "replaced by nothing-we-list"
+	if SuccessorOf(sm, wcm) != nil {
+		t.Error("a successor the tree does not list should not be returned")
+	}
+	SetMiddlewareSupersessions(map[string]string{"wifi-connection-manager": "freertos"})
+	if got := SuccessorOf(sm, wcm); got != freertos {
+		t.Errorf("expected freertos as the successor, got %v", got)
+	}
+	if SuccessorOf(sm, freertos) != nil {
+		t.Error("freertos is not superseded")
+	}
+	if sorted := PreferSuccessors([]*MiddlewareItem{wcm, freertos}); sorted[0] != freertos || sorted[1] != wcm {
+		t.Errorf("expected the deprecated item last, got %s, %s", sorted[0].ID, sorted[1].ID)
+	}
+}
+
+func TestMiddlewareSupersessionsPerTree(t *testing.T) {
+	defer SetMiddlewareSupersessions(nil)
+	srv := newTestTreeServer(t)
+	ingest := func(opts ...IngestOption) *MiddlewareItem {
+		sm, err := NewSuperManifestFromURL(srv.URL+"/super.xml",
+			append(opts, WithCacheDir(t.TempDir()), WithIngestHistory(nil))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mw, _ := sm.GetMiddleware("wifi-connection-manager")
+		return mw
+	}
+	prod := ingest()
+	beta := ingest(WithMiddlewareSupersessions(map[string]string{"wifi-connection-manager": "wifi-core"}))
+	SetMiddlewareSupersessions(map[string]string{"wifi-connection-manager": "freertos"})
+	if got := prod.ReplacedBy(); got != "freertos" {
+		t.Errorf("expected the process-wide mapping for the production tree, got %q", got)
+	}
+	if got := beta.ReplacedBy(); got != "wifi-core" {
+		t.Errorf("expected the mapping of the beta tree, got %q", got)
+	}
+}
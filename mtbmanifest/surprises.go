@@ -23,12 +23,24 @@ func (t AnyTag) String() string {
 // Pass ANY struct (root of your tree) to this function.
 func ReportSurprises(data interface{}) {
 	fmt.Println("🔍 Scanning for hidden XML data...")
-	walk(reflect.ValueOf(data), []string{})
+	walk(reflect.ValueOf(data), []string{}, func(msg string) {
+		fmt.Printf("⚠️  %s\n", msg)
+	})
 	fmt.Println("✅ Scan complete.")
 }
 
-// walk recursively inspects fields
-func walk(v reflect.Value, path []string) {
+// FindXMLSurprises returns the unknown tags and attributes captured while unmarshaling the
+// XML of data, one description each
+func FindXMLSurprises(data interface{}) []string {
+	ret := []string{}
+	walk(reflect.ValueOf(data), []string{}, func(msg string) {
+		ret = append(ret, msg)
+	})
+	return ret
+}
+
+// walk recursively inspects fields, telling found about every surprise
+func walk(v reflect.Value, path []string, found func(msg string)) {
 	// 1. Unwrap Pointers and Interfaces
 	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
 		if v.IsNil() {
@@ -42,7 +54,7 @@ func walk(v reflect.Value, path []string) {
 		for i := 0; i < v.Len(); i++ {
 			// Update path to include index, e.g., Versions[0]
 			itemPath := append(path, fmt.Sprintf("[%d]", i))
-			walk(v.Index(i), itemPath)
+			walk(v.Index(i), itemPath, found)
 		}
 		return
 	}
@@ -54,14 +66,14 @@ func walk(v reflect.Value, path []string) {
 		// A. Check for "Surprises" field (Tags)
 		if f := v.FieldByName("Surprises"); f.IsValid() {
 			if f.Len() > 0 {
-				printSurprises(path, f)
+				describeSurprises(path, f, found)
 			}
 		}
 
 		// B. Check for "LostAttrs" field (Attributes)
 		if f := v.FieldByName("LostAttrs"); f.IsValid() {
 			if f.Len() > 0 {
-				printAttrs(path, f)
+				describeAttrs(path, f, found)
 			}
 		}
 
@@ -70,8 +82,9 @@ func walk(v reflect.Value, path []string) {
 			fieldVal := v.Field(i)
 			fieldType := typ.Field(i)
 
-			// Skip unexported fields (lowercase names)
-			if fieldType.PkgPath != "" {
+			// Skip unexported fields (lowercase names) and those not read from the XML, such
+			// as Origin, which points back up the tree
+			if fieldType.PkgPath != "" || fieldType.Tag.Get("xml") == "-" {
 				continue
 			}
 
@@ -85,29 +98,29 @@ func walk(v reflect.Value, path []string) {
 			if k == reflect.Struct || k == reflect.Slice || k == reflect.Ptr {
 				// Append field name to path, e.g., "Versions"
 				newPath := append(path, fieldType.Name)
-				walk(fieldVal, newPath)
+				walk(fieldVal, newPath, found)
 			}
 		}
 	}
 }
 
-// Helper to print unknown TAGS
-func printSurprises(path []string, f reflect.Value) {
+// Helper to describe unknown TAGS
+func describeSurprises(path []string, f reflect.Value, found func(msg string)) {
 	// We assume f is []AnyTag
 	for i := 0; i < f.Len(); i++ {
 		tag := f.Index(i).Interface().(AnyTag)
 		loc := strings.Join(path, ".")
-		fmt.Printf("⚠️  Tag Surprise @ %s: <%s> %s\n", loc, tag.XMLName.Local, tag.Body)
+		found(fmt.Sprintf("Tag Surprise @ %s: <%s> %s", loc, tag.XMLName.Local, tag.Body))
 	}
 }
 
-// Helper to print unknown ATTRIBUTES
-func printAttrs(path []string, f reflect.Value) {
+// Helper to describe unknown ATTRIBUTES
+func describeAttrs(path []string, f reflect.Value, found func(msg string)) {
 	// We assume f is []xml.Attr
 	for i := 0; i < f.Len(); i++ {
 		attr := f.Index(i).Interface().(xml.Attr)
 		loc := strings.Join(path, ".")
-		fmt.Printf("⚠️  Attr Surprise @ %s: %s=%q\n", loc, attr.Name.Local, attr.Value)
+		found(fmt.Sprintf("Attr Surprise @ %s: %s=%q", loc, attr.Name.Local, attr.Value))
 	}
 }
 
@@ -0,0 +1,269 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// TaxonomyConfig describes how free-form manifest categories are cleaned up and grouped.
+// It is usually loaded from a JSON file like:
+//
+//	{
+//	  "aliases": { "WiFi": "Wi-Fi", "Wireless": "Wi-Fi" },
+//	  "parents": { "Wi-Fi": "Connectivity", "Bluetooth": "Connectivity" }
+//	}
+type TaxonomyConfig struct {
+	// Aliases maps a category as spelled in a manifest to its canonical name.
+	// Matching is case-insensitive.
+	Aliases map[string]string `json:"aliases"`
+
+	// Parents maps a canonical category to its parent category. Parents do not have to
+	// appear in any manifest; they are created as needed.
+	Parents map[string]string `json:"parents"`
+}
+
+// ReadTaxonomyConfig parses a taxonomy config from JSON
+func ReadTaxonomyConfig(jsonData []byte) (*TaxonomyConfig, error) {
+	var config TaxonomyConfig
+	if err := json.Unmarshal(jsonData, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// CategoryNode is one category in the hierarchy
+type CategoryNode struct {
+	Name     string           `json:"name"`
+	Parent   *CategoryNode    `json:"-"`
+	Children []*CategoryNode  `json:"children,omitempty"`
+	Counts   map[ItemKind]int `json:"counts,omitempty"` // items directly in this category
+	Aliases  []string         `json:"aliases,omitempty"`
+	items    []*CategorizedItem
+	aliasSet map[string]bool
+}
+
+// CategorizedItem is a board, app or middleware item filed under a category.
+// Item is a *Board, *App or *MiddlewareItem depending on Kind.
+type CategorizedItem struct {
+	Kind        ItemKind `json:"kind"`
+	ID          string   `json:"id"`
+	RawCategory string   `json:"rawCategory"` // as spelled in the manifest
+	Item        any      `json:"-"`
+}
+
+// Taxonomy is the category hierarchy of all items in a super manifest
+type Taxonomy struct {
+	aliases map[string]string // lower-cased raw category -> canonical
+	parents map[string]string
+	nodes   map[string]*CategoryNode
+}
+
+// Maximum depth of the hierarchy. Anything deeper is assumed to be a cycle in the config.
+const maxTaxonomyDepth = 16
+
+// Category used for items with no category at all
+const UncategorizedCategory = "Uncategorized"
+
+// NewTaxonomy collects the categories of all boards, apps and middleware in sm and arranges
+// them according to config. config may be nil, in which case categories are flat and only
+// differences in case are folded together.
+func NewTaxonomy(sm SuperManifestIF, config *TaxonomyConfig) *Taxonomy {
+	t := &Taxonomy{
+		aliases: make(map[string]string),
+		parents: make(map[string]string),
+		nodes:   make(map[string]*CategoryNode),
+	}
+	if config != nil {
+		// Canonical names from the config always win over whatever spelling a manifest uses
+		for _, canonical := range config.Aliases {
+			t.aliases[strings.ToLower(canonical)] = canonical
+		}
+		for child, parent := range config.Parents {
+			t.aliases[strings.ToLower(child)] = child
+			t.aliases[strings.ToLower(parent)] = parent
+			t.parents[child] = parent
+		}
+		for alias, canonical := range config.Aliases {
+			t.aliases[strings.ToLower(strings.TrimSpace(alias))] = canonical
+		}
+	}
+
+	for _, id := range sm.GetBoardIDs() {
+		if board, ok := sm.GetBoard(id); ok {
+			t.add(ItemKindBoard, board.ID, board.Category, board)
+		}
+	}
+	for _, id := range sm.GetAppIDs() {
+		if app, ok := sm.GetApp(id); ok {
+			t.add(ItemKindApp, app.ID, app.Category, app)
+		}
+	}
+	for _, id := range sm.GetMiddlewareIDs() {
+		if mw, ok := sm.GetMiddleware(id); ok {
+			t.add(ItemKindMiddleware, mw.ID, mw.Category, mw)
+		}
+	}
+	for _, node := range t.nodes {
+		sort.Strings(node.Aliases)
+		sort.Slice(node.Children, func(i, j int) bool {
+			return node.Children[i].Name < node.Children[j].Name
+		})
+	}
+	return t
+}
+
+func (t *Taxonomy) add(kind ItemKind, id, rawCategory string, item any) {
+	name := t.Canonical(rawCategory)
+	node := t.node(name, 0)
+	node.items = append(node.items, &CategorizedItem{Kind: kind, ID: id, RawCategory: rawCategory, Item: item})
+	node.Counts[kind]++
+	raw := strings.TrimSpace(rawCategory)
+	if raw != "" && raw != name && !node.aliasSet[raw] {
+		node.aliasSet[raw] = true
+		node.Aliases = append(node.Aliases, raw)
+	}
+}
+
+// node returns the node for a canonical category, creating it and its ancestors as needed
+func (t *Taxonomy) node(name string, depth int) *CategoryNode {
+	if node, ok := t.nodes[name]; ok {
+		return node
+	}
+	node := &CategoryNode{
+		Name:     name,
+		Counts:   make(map[ItemKind]int),
+		aliasSet: make(map[string]bool),
+	}
+	t.nodes[name] = node
+	if parentName, ok := t.parents[name]; ok && parentName != name && depth < maxTaxonomyDepth {
+		parent := t.node(parentName, depth+1)
+		if !parent.isDescendantOf(node) {
+			node.Parent = parent
+			parent.Children = append(parent.Children, node)
+		} else {
+			logger.Warningf("Taxonomy: ignoring cyclic parent %s for category %s\n", parentName, name)
+		}
+	}
+	return node
+}
+
+func (n *CategoryNode) isDescendantOf(other *CategoryNode) bool {
+	for p := n; p != nil; p = p.Parent {
+		if p == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Canonical returns the canonical name for a category as spelled in a manifest. Aliases from
+// the config are applied first; otherwise categories that differ only in case collapse onto
+// whichever spelling was seen first.
+func (t *Taxonomy) Canonical(category string) string {
+	category = strings.TrimSpace(category)
+	if category == "" {
+		return UncategorizedCategory
+	}
+	key := strings.ToLower(category)
+	if canonical, ok := t.aliases[key]; ok {
+		return canonical
+	}
+	// Remember the first spelling we saw for this case-folded name
+	t.aliases[key] = category
+	return category
+}
+
+// Roots returns the top level categories, sorted by name
+func (t *Taxonomy) Roots() []*CategoryNode {
+	ret := []*CategoryNode{}
+	for _, node := range t.nodes {
+		if node.Parent == nil {
+			ret = append(ret, node)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// GetCategory returns the node for a category (canonical name or alias)
+func (t *Taxonomy) GetCategory(category string) (*CategoryNode, bool) {
+	node, ok := t.nodes[t.lookupName(category)]
+	return node, ok
+}
+
+// lookupName is like Canonical but does not record new spellings
+func (t *Taxonomy) lookupName(category string) string {
+	category = strings.TrimSpace(category)
+	if canonical, ok := t.aliases[strings.ToLower(category)]; ok {
+		return canonical
+	}
+	return category
+}
+
+// Path returns the chain of category names from the root down to the given category
+func (t *Taxonomy) Path(category string) []string {
+	node, ok := t.GetCategory(category)
+	if !ok {
+		return []string{}
+	}
+	ret := []string{}
+	for p := node; p != nil; p = p.Parent {
+		ret = append([]string{p.Name}, ret...)
+	}
+	return ret
+}
+
+// Categories returns the sorted canonical names of all categories that directly hold items
+// of the given kinds (all kinds if none are given)
+func (t *Taxonomy) Categories(kinds ...ItemKind) []string {
+	ret := []string{}
+	for name, node := range t.nodes {
+		for kind, count := range node.Counts {
+			if count > 0 && kindSelected(kind, kinds) {
+				ret = append(ret, name)
+				break
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// GetItemsByCategory returns the boards, apps and middleware filed under a category (canonical
+// name or alias), including everything in its sub-categories. Pass kinds to restrict the result.
+// Items come back in manifest order within each category, parents before children.
+func (t *Taxonomy) GetItemsByCategory(category string, kinds ...ItemKind) []*CategorizedItem {
+	ret := []*CategorizedItem{}
+	node, ok := t.GetCategory(category)
+	if !ok {
+		return ret
+	}
+	var collect func(n *CategoryNode)
+	collect = func(n *CategoryNode) {
+		for _, item := range n.items {
+			if kindSelected(item.Kind, kinds) {
+				ret = append(ret, item)
+			}
+		}
+		for _, child := range n.Children {
+			collect(child)
+		}
+	}
+	collect(node)
+	return ret
+}
+
+// TotalCount returns the number of items in this category and all its sub-categories
+func (n *CategoryNode) TotalCount(kinds ...ItemKind) int {
+	total := 0
+	for kind, count := range n.Counts {
+		if kindSelected(kind, kinds) {
+			total += count
+		}
+	}
+	for _, child := range n.Children {
+		total += child.TotalCount(kinds...)
+	}
+	return total
+}
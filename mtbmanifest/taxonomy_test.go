@@ -0,0 +1,65 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestTaxonomy(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	config, err := ReadTaxonomyConfig([]byte(`{
+		"aliases": { "wifi": "Wi-Fi", "Kit": "Kits" },
+		"parents": { "Wi-Fi": "Connectivity", "Sensing": "Peripherals" }
+	}`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	taxonomy := NewTaxonomy(sm, config)
+
+	path := taxonomy.Path("wifi")
+	if len(path) != 2 || path[0] != "Connectivity" || path[1] != "Wi-Fi" {
+		t.Errorf("unexpected path for wifi: %v", path)
+	}
+
+	items := taxonomy.GetItemsByCategory("Connectivity")
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items under Connectivity, got %d", len(items))
+	}
+	apps := taxonomy.GetItemsByCategory("Connectivity", ItemKindApp)
+	if len(apps) != 1 || apps[0].ID != "mtb-example-wifi-tcp-client" {
+		t.Errorf("unexpected apps under Connectivity: %v", apps)
+	}
+
+	kits := taxonomy.GetItemsByCategory("kit", ItemKindBoard)
+	if len(kits) != 2 {
+		t.Errorf("expected 2 boards under Kits, got %d", len(kits))
+	}
+
+	roots := taxonomy.Roots()
+	names := []string{}
+	for _, root := range roots {
+		names = append(names, root.Name)
+	}
+	expected := []string{"Connectivity", "Getting Started", "Kits", "Peripherals", "RTOS"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected roots %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("root %d: expected %q, got %q", i, expected[i], names[i])
+		}
+	}
+
+	if total := roots[0].TotalCount(); total != 2 {
+		t.Errorf("expected 2 items in Connectivity, got %d", total)
+	}
+}
+
+func TestTaxonomyCycle(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	taxonomy := NewTaxonomy(sm, &TaxonomyConfig{
+		Parents: map[string]string{"Wi-Fi": "RTOS", "RTOS": "Wi-Fi"},
+	})
+	if len(taxonomy.Roots()) == 0 {
+		t.Error("expected at least one root when the config has a cycle")
+	}
+}
@@ -0,0 +1,159 @@
+package mtbmanifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Throttling
+// ////////////////////////////////////////////////////////////////////////
+
+// An ingestion fetches every manifest at once, which saturates a metered or very slow link.
+// Three limits tame it: WithConcurrency (or WithMaxConcurrent of a fetcher) bounds the
+// fetches in flight, WithMaxPerHost the requests a cache sends to one host at a time, and
+// WithMaxBandwidth the bytes per second all downloads of a cache share.
+
+// WithConcurrency limits how many manifests an ingestion fetches at once; 0 or less means
+// runtime.NumCPU(). A fetcher given with WithFetcher keeps its own limit.
+func WithConcurrency(n int) IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.concurrency = n
+	}
+}
+
+// WithMaxPerHost limits the requests of the cache to one host that are in flight at once,
+// downloading the response included; 0 or less means no limit
+func WithMaxPerHost(n int) CacheOption {
+	return func(c *ManifestCache) {
+		c.perHost = nil
+		if n > 0 {
+			c.perHost = &hostLimiter{max: n, hosts: map[string]chan struct{}{}}
+		}
+	}
+}
+
+// WithMaxBandwidth limits the downloads of the cache to bytesPerSecond, shared by all of them;
+// 0 or less means no limit
+func WithMaxBandwidth(bytesPerSecond int64) CacheOption {
+	return func(c *ManifestCache) {
+		c.bandwidth = nil
+		if bytesPerSecond > 0 {
+			c.bandwidth = &bandwidthLimiter{bytesPerSecond: bytesPerSecond}
+		}
+	}
+}
+
+// hostLimiter bounds the requests in flight per host
+type hostLimiter struct {
+	max   int
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+// acquire waits for a slot for the host of urlStr and returns the function that releases it
+func (h *hostLimiter) acquire(urlStr string) func() {
+	host := urlStr
+	if u, err := url.Parse(urlStr); err == nil {
+		host = u.Host
+	}
+	h.mu.Lock()
+	slots, ok := h.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, h.max)
+		h.hosts[host] = slots
+	}
+	h.mu.Unlock()
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// bandwidthLimiter paces reads so that the bytes read, by all readers together, stay within
+// bytesPerSecond
+type bandwidthLimiter struct {
+	bytesPerSecond int64
+	mu             sync.Mutex
+	paidUntil      time.Time // when the bytes read so far are within the limit
+}
+
+// wait records n bytes read and sleeps until they are within the limit
+func (b *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := time.Now()
+	if b.paidUntil.Before(now) {
+		b.paidUntil = now
+	}
+	b.paidUntil = b.paidUntil.Add(time.Duration(int64(n) * int64(time.Second) / b.bytesPerSecond))
+	delay := b.paidUntil.Sub(now)
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reader returns r read at the pace of the limiter, in chunks of a tenth of a second's worth
+// so that the pace is even
+func (b *bandwidthLimiter) reader(ctx context.Context, r io.Reader) io.Reader {
+	return &throttledReader{r: r, limiter: b, ctx: ctx, chunk: max(512, int(b.bytesPerSecond/10))}
+}
+
+type throttledReader struct {
+	r       io.Reader
+	limiter *bandwidthLimiter
+	ctx     context.Context
+	chunk   int
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > t.chunk {
+		p = p[:t.chunk]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if werr := t.limiter.wait(t.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// ParseBandwidth parses a rate in bytes per second such as 500k, 2M, 1.5MB/s or 256KiB. The
+// units k, M and G are decimal, Ki, Mi and Gi binary; a trailing B and /s are optional.
+func ParseBandwidth(s string) (int64, error) {
+	str := strings.TrimSuffix(strings.TrimSpace(s), "/s")
+	str = strings.TrimSuffix(str, "B")
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		value  float64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30},
+		{"k", 1e3}, {"K", 1e3}, {"M", 1e6}, {"G", 1e9},
+	} {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSuffix(str, unit.suffix)
+			multiplier = unit.value
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	rate := int64(value * multiplier)
+	if err != nil || rate < 1 {
+		return 0, fmt.Errorf("invalid bandwidth %q, expected e.g. 500k or 2MB/s", s)
+	}
+	return rate, nil
+}
@@ -0,0 +1,102 @@
+package mtbmanifest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheMaxPerHost(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("<boards/>"))
+	}))
+	defer srv.Close()
+
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithMaxPerHost(2), WithNoBackgroundRefresh())
+	defer cache.Close()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Get(srv.URL + "/boards" + string(rune('a'+i)) + ".xml"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if p := peak.Load(); p > 2 {
+		t.Errorf("expected at most 2 requests at once, got %d", p)
+	}
+}
+
+func TestCacheMaxBandwidth(t *testing.T) {
+	body := strings.Repeat("x", 3000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	// 3000 bytes at 10000 bytes per second take about 300ms
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithMaxBandwidth(10000), WithNoBackgroundRefresh())
+	defer cache.Close()
+	start := time.Now()
+	data, err := cache.Get(srv.URL + "/apps.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != body {
+		t.Errorf("unexpected body of %d bytes", len(data))
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected the download to be paced, took %v", elapsed)
+	}
+}
+
+func TestBandwidthLimiterCancel(t *testing.T) {
+	limiter := &bandwidthLimiter{bytesPerSecond: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := limiter.reader(ctx, bytes.NewReader(make([]byte, 1000)))
+	if _, err := r.Read(make([]byte, 1000)); err != context.Canceled {
+		t.Errorf("expected the wait to be cancelled, got %v", err)
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	for s, want := range map[string]int64{
+		"500":      500,
+		"500k":     500000,
+		"2M":       2000000,
+		"1.5MB/s":  1500000,
+		"256KiB":   256 * 1024,
+		" 1 GiB ":  1 << 30,
+		"100 KB/s": 100000,
+	} {
+		got, err := ParseBandwidth(s)
+		if err != nil || got != want {
+			t.Errorf("ParseBandwidth(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "fast", "0", "-1k", "0.1"} {
+		if _, err := ParseBandwidth(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Version timelines
+// ////////////////////////////////////////////////////////////////////////
+
+// The manifests list the versions of an item in whatever order they were added. A Timeline
+// puts them in release order, by the version in their commit (or number), so that questions
+// such as "what came out since 3.0" or "how many 4.x releases are there" are easy to answer.
+
+// TimelineEntry is one listed version of an item
+type TimelineEntry struct {
+	Num             string `json:"num"`
+	Commit          string `json:"commit"`
+	FlowVersion     string `json:"flowVersion,omitempty"`
+	ToolsMinVersion string `json:"toolsMinVersion,omitempty"`
+	// Ref is the kind of ref Commit is
+	Ref CommitRef `json:"ref"`
+	// Version is parsed from Commit, or Num if Commit has none. Nil if neither has one.
+	Version *SemanticVersion `json:"-"`
+}
+
+// Timeline is the release history of a board, app or middleware, oldest first. Versions
+// without a recognizable number come first; "latest-v4.X" comes after every 4.x release.
+type Timeline struct {
+	ID      string           `json:"id"`
+	Kind    ItemKind         `json:"kind"`
+	Entries []*TimelineEntry `json:"entries"`
+}
+
+// newTimeline sorts the entries into a timeline
+func newTimeline(id string, kind ItemKind, entries []*TimelineEntry) *Timeline {
+	for _, e := range entries {
+		if v, err := ParseVersion(e.Commit); err == nil {
+			e.Version = v
+		} else if v, err := ParseVersion(e.Num); err == nil {
+			e.Version = v
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return CompareStrict(entries[i].Version, entries[j].Version) < 0
+	})
+	return &Timeline{ID: id, Kind: kind, Entries: entries}
+}
+
+// Timeline returns the release history of the board
+func (b *Board) Timeline() *Timeline {
+	entries := []*TimelineEntry{}
+	if b.Versions != nil {
+		for _, v := range b.Versions.Versions {
+			entries = append(entries, &TimelineEntry{Num: v.Num, Commit: v.Commit, FlowVersion: v.FlowVersion, Ref: v.GetRef()})
+		}
+	}
+	return newTimeline(b.ID, ItemKindBoard, entries)
+}
+
+// Timeline returns the release history of the app
+func (a *App) Timeline() *Timeline {
+	entries := []*TimelineEntry{}
+	for _, v := range a.Versions.Version {
+		entries = append(entries, &TimelineEntry{Num: v.Num, Commit: v.Commit, FlowVersion: v.FlowVersion, ToolsMinVersion: v.ToolsMinVersion,
+			Ref: v.GetRef()})
+	}
+	return newTimeline(a.ID, ItemKindApp, entries)
+}
+
+// Timeline returns the release history of the middleware
+func (mw *MiddlewareItem) Timeline() *Timeline {
+	entries := []*TimelineEntry{}
+	if mw.Versions != nil {
+		for _, v := range mw.Versions.Version {
+			entries = append(entries, &TimelineEntry{Num: v.Num, Commit: v.Commit, FlowVersion: v.FlowVersion, ToolsMinVersion: v.ToolsMinVersion,
+				Ref: v.GetRef()})
+		}
+	}
+	return newTimeline(mw.ID, ItemKindMiddleware, entries)
+}
+
+// VersionTimeline returns the release history of the board, app or middleware with the given
+// ID, looked up in that order
+func VersionTimeline(sm SuperManifestIF, id string) (*Timeline, error) {
+	if b, ok := sm.GetBoard(id); ok {
+		return b.Timeline(), nil
+	}
+	if a, ok := sm.GetApp(id); ok {
+		return a.Timeline(), nil
+	}
+	if mw, ok := sm.GetMiddleware(id); ok {
+		return mw.Timeline(), nil
+	}
+	return nil, fmt.Errorf("%s not found", id)
+}
+
+// Latest returns the newest entry, or nil if there are none
+func (t *Timeline) Latest() *TimelineEntry {
+	if len(t.Entries) == 0 {
+		return nil
+	}
+	return t.Entries[len(t.Entries)-1]
+}
+
+// VersionsSince returns the entries newer than version v, e.g. "3.0.0" or "release-v3.0.0",
+// oldest first
+func (t *Timeline) VersionsSince(v string) ([]*TimelineEntry, error) {
+	since, err := ParseVersion(v)
+	if err != nil {
+		return nil, err
+	}
+	ret := []*TimelineEntry{}
+	for _, e := range t.Entries {
+		if e.Version != nil && CompareStrict(e.Version, since) > 0 {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
+}
+
+// LatestMajor returns the highest major version listed, or -1 if no entry has a version
+func (t *Timeline) LatestMajor() int {
+	ret := -1
+	for _, e := range t.Entries {
+		if e.Version != nil && e.Version.Major > ret {
+			ret = e.Version.Major
+		}
+	}
+	return ret
+}
+
+// CountsByMajor returns the number of entries of each major version. Entries without a
+// version are not counted.
+func (t *Timeline) CountsByMajor() map[int]int {
+	ret := make(map[int]int)
+	for _, e := range t.Entries {
+		if e.Version != nil {
+			ret[e.Version.Major]++
+		}
+	}
+	return ret
+}
@@ -0,0 +1,44 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+func TestTimeline(t *testing.T) {
+	mw := &MiddlewareItem{ID: "mw", Versions: &MWVersions{Version: []*MWVersion{
+		{Num: "Latest 4.X release", Commit: "latest-v4.X"},
+		{Num: "3.1.0", Commit: "release-v3.1.0"},
+		{Num: "master", Commit: "master"},
+		{Num: "4.0.0", Commit: "release-v4.0.0"},
+		{Num: "3.0.0", Commit: "release-v3.0.0"},
+	}}}
+	tl := mw.Timeline()
+	want := []string{"master", "release-v3.0.0", "release-v3.1.0", "release-v4.0.0", "latest-v4.X"}
+	for i, e := range tl.Entries {
+		if e.Commit != want[i] {
+			t.Fatalf("entry %d is %s, want %s", i, e.Commit, want[i])
+		}
+	}
+	if tl.Latest().Commit != "latest-v4.X" || tl.LatestMajor() != 4 {
+		t.Errorf("latest %s, major %d", tl.Latest().Commit, tl.LatestMajor())
+	}
+	since, err := tl.VersionsSince("3.1.0")
+	if err != nil || len(since) != 2 || since[0].Commit != "release-v4.0.0" {
+		t.Errorf("VersionsSince(3.1.0) = %v, %v", since, err)
+	}
+	if _, err := tl.VersionsSince("master"); err == nil {
+		t.Error("VersionsSince accepted a version without a number")
+	}
+	if counts := tl.CountsByMajor(); counts[3] != 2 || counts[4] != 2 || len(counts) != 2 {
+		t.Errorf("counts %v", counts)
+	}
+
+	sm := newTestSuperManifest(t)
+	board, err := VersionTimeline(sm, "CY8CKIT-062S2-43012")
+	if err != nil || board.Kind != ItemKindBoard || board.Entries[0].Commit != "release-v4.1.0" {
+		t.Errorf("board timeline %+v, %v", board, err)
+	}
+	if _, err := VersionTimeline(sm, "no-such-item"); err == nil {
+		t.Error("no error for an unknown item")
+	}
+}
@@ -0,0 +1,79 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Network timeouts
+// ////////////////////////////////////////////////////////////////////////
+
+// http.DefaultClient has no timeout, so a proxy that accepts connections and then says nothing
+// holds a CI job until the job's own limit kills it. Timeouts bound the requests of the cache
+// (WithTimeouts) and of the git fetcher (WithGitTimeouts): Connect the setting up of a
+// connection, Request a whole exchange. For the git executable, which this package cannot
+// interrupt mid-request, Request becomes the time a transfer may stall before git gives up.
+
+// Timeouts limit network requests; a zero field sets no limit
+type Timeouts struct {
+	// Connect limits establishing a connection: the dial, to the server or the proxy, and the
+	// TLS handshake
+	Connect time.Duration `json:"connect,omitempty"`
+	// Request limits one request, from the dial until the response body is read
+	Request time.Duration `json:"request,omitempty"`
+}
+
+// IsZero reports whether no timeout is set
+func (t Timeouts) IsZero() bool {
+	return t.Connect <= 0 && t.Request <= 0
+}
+
+// apply sets the timeouts on a client whose transport is the given one, which it modifies
+func (t Timeouts) apply(client *http.Client, transport *http.Transport) {
+	if t.Connect > 0 {
+		dialer := &net.Dialer{Timeout: t.Connect, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = t.Connect
+	}
+	if t.Request > 0 {
+		client.Timeout = t.Request
+	}
+	client.Transport = transport
+}
+
+// Client returns a client with the timeouts, otherwise like http.DefaultClient
+func (t Timeouts) Client() *http.Client {
+	client := &http.Client{}
+	t.apply(client, http.DefaultTransport.(*http.Transport).Clone())
+	return client
+}
+
+// WithTimeouts limits the requests of the cache. Like the TLS options, it does not apply to a
+// client set with WithHTTPClient.
+func WithTimeouts(t Timeouts) CacheOption {
+	return func(c *ManifestCache) {
+		c.timeouts = t
+	}
+}
+
+// WithGitTimeouts limits the requests of the git fetcher: tarball and API downloads get a
+// client with the timeouts, and the git executable gives up on transfers stalled for longer
+// than t.Request. Give it after WithGitHTTPClient to replace that client.
+func WithGitTimeouts(t Timeouts) GitFetcherOption {
+	return func(g *GitFetcher) {
+		g.client = t.Client()
+		g.timeouts = t
+	}
+}
+
+// gitEnv returns the environment settings that apply the timeouts to the git executable
+func (t Timeouts) gitEnv() []string {
+	if t.Request <= 0 {
+		return nil
+	}
+	// Abort when less than a byte per second arrives for the whole timeout
+	return []string{"GIT_HTTP_LOW_SPEED_LIMIT=1", fmt.Sprintf("GIT_HTTP_LOW_SPEED_TIME=%d", max(1, int(t.Request.Seconds())))}
+}
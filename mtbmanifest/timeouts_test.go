@@ -0,0 +1,62 @@
+package mtbmanifest
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release // a proxy that says nothing
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithTimeouts(Timeouts{Request: 100 * time.Millisecond}))
+	defer cache.Close()
+	start := time.Now()
+	_, err := cache.Get(srv.URL + "/super.xml")
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the request to give up after its timeout, took %v", elapsed)
+	}
+
+	// A listener that never answers: the dial succeeds, the TLS handshake never ends
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	cache = NewManifestCache(WithStore(NewMemoryStore()), WithTimeouts(Timeouts{Connect: 100 * time.Millisecond}))
+	defer cache.Close()
+	start = time.Now()
+	if _, err := cache.Get("https://" + l.Addr().String() + "/super.xml"); err == nil {
+		t.Fatal("expected the handshake to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the connection to give up after its timeout, took %v", elapsed)
+	}
+}
+
+func TestTimeoutsGitEnv(t *testing.T) {
+	if env := (Timeouts{Connect: time.Second}).gitEnv(); env != nil {
+		t.Errorf("expected no settings without a request timeout, got %v", env)
+	}
+	env := Timeouts{Request: 90 * time.Second}.gitEnv()
+	if !slices.Equal(env, []string{"GIT_HTTP_LOW_SPEED_LIMIT=1", "GIT_HTTP_LOW_SPEED_TIME=90"}) {
+		t.Errorf("unexpected settings %v", env)
+	}
+	g := NewGitFetcher(WithGitTimeouts(Timeouts{Request: time.Minute}))
+	if g.client == http.DefaultClient || g.client.Timeout != time.Minute {
+		t.Errorf("expected a client with the timeout, got %+v", g.client)
+	}
+}
@@ -0,0 +1,121 @@
+package mtbmanifest
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// TLS options of the manifest cache
+// ////////////////////////////////////////////////////////////////////////
+
+// Corporate networks often intercept HTTPS with a proxy whose CA is not in the system pool;
+// WithRootCAs trusts it. WithCertificatePins goes the other way and accepts only known server
+// keys for some hosts. WithInsecureSkipVerify turns verification off altogether, which is only
+// meant for isolated lab networks and is logged, through the logger of the cache, when the
+// cache first goes to the network. Pins are matched against the chain verification built, so
+// they cannot be combined with WithInsecureSkipVerify: connections to pinned hosts then fail.
+
+// DefaultPinnedHosts are the hosts the default manifests and assets are served from
+var DefaultPinnedHosts = []string{"github.com", "raw.githubusercontent.com", "codeload.github.com", "api.github.com"}
+
+// CertificatePins maps host names to the accepted pins of their certificate chain. A pin is
+// the base64 SHA-256 of a certificate's SubjectPublicKeyInfo, as computed by SPKIPin, with an
+// optional "sha256/" prefix. A connection to a pinned host succeeds only if some certificate of
+// a verified chain matches one of its pins; certificates the server sends that are not part of
+// the chain do not count. Hosts that are not listed, and servers addressed by IP, are not
+// restricted.
+type CertificatePins map[string][]string
+
+// PinDefaultHosts returns pins for all of DefaultPinnedHosts
+func PinDefaultHosts(pins ...string) CertificatePins {
+	ret := make(CertificatePins)
+	for _, host := range DefaultPinnedHosts {
+		ret[host] = pins
+	}
+	return ret
+}
+
+// SPKIPin returns the pin of a certificate for CertificatePins
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParseCertificatePin parses "HOST=PIN" as given on the command line
+func ParseCertificatePin(s string) (string, string, error) {
+	host, pin, ok := strings.Cut(s, "=")
+	if !ok || host == "" || pin == "" {
+		return "", "", fmt.Errorf("invalid certificate pin '%s', expected HOST=PIN", s)
+	}
+	return strings.ToLower(host), pin, nil
+}
+
+// verify checks the verified chains of a connection against the pins of its host
+func (p CertificatePins) verify(cs tls.ConnectionState) error {
+	pins, ok := p[strings.ToLower(cs.ServerName)]
+	if !ok {
+		return nil
+	}
+	if len(cs.VerifiedChains) == 0 {
+		return fmt.Errorf("certificate of %s is pinned but was not verified; pins cannot be used with insecure mode", cs.ServerName)
+	}
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			pin := SPKIPin(cert)
+			for _, want := range pins {
+				if strings.TrimPrefix(want, "sha256/") == pin {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("certificate of %s does not match any pinned key", cs.ServerName)
+}
+
+// LoadCertPool returns the system certificate pool with the PEM certificates of files added,
+// for WithRootCAs
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// WithRootCAs makes the cache verify servers against pool instead of the system pool
+func WithRootCAs(pool *x509.CertPool) CacheOption {
+	return func(c *ManifestCache) {
+		c.tlsConfig().RootCAs = pool
+	}
+}
+
+// WithCertificatePins restricts the certificates accepted for the hosts of pins. With
+// WithInsecureSkipVerify, connections to these hosts are refused.
+func WithCertificatePins(pins CertificatePins) CacheOption {
+	return func(c *ManifestCache) {
+		c.tlsConfig().VerifyConnection = pins.verify
+	}
+}
+
+// WithInsecureSkipVerify turns off server certificate verification. Only for isolated lab
+// networks; a warning is logged when the cache first goes to the network.
+func WithInsecureSkipVerify() CacheOption {
+	return func(c *ManifestCache) {
+		c.tlsConfig().InsecureSkipVerify = true
+	}
+}
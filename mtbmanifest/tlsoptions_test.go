@@ -0,0 +1,102 @@
+package mtbmanifest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheTLSOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<super-manifest/>"))
+	}))
+	defer server.Close()
+	urlStr := server.URL + "/super.xml"
+	// Pins go by host name, which is not sent for IP addresses
+	byName := strings.Replace(urlStr, "127.0.0.1", "localhost", 1)
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	fetchURL := func(u string, opts ...CacheOption) error {
+		cache := NewManifestCache(append([]CacheOption{WithStore(NewMemoryStore()), WithCacheTTL(time.Hour)}, opts...)...)
+		defer cache.Close()
+		_, err := cache.Get(u)
+		return err
+	}
+	fetch := func(opts ...CacheOption) error { return fetchURL(urlStr, opts...) }
+
+	if err := fetch(); err == nil {
+		t.Error("expected the self-signed server to be rejected")
+	}
+	if err := fetch(WithRootCAs(pool)); err != nil {
+		t.Errorf("expected the custom CA to be trusted: %v", err)
+	}
+	if err := fetch(WithInsecureSkipVerify()); err != nil {
+		t.Errorf("expected insecure mode to accept the server: %v", err)
+	}
+
+	good := CertificatePins{"localhost": {"sha256/" + SPKIPin(server.Certificate())}}
+	if err := fetchURL(byName, WithInsecureSkipVerify(), WithCertificatePins(good)); err == nil {
+		t.Error("expected pins to refuse connections in insecure mode")
+	}
+	warnings := &messageLogger{}
+	if err := fetch(WithInsecureSkipVerify(), WithCacheLogger(warnings)); err != nil || warnings.count("verification is disabled") != 1 {
+		t.Errorf("expected the insecure mode warning to go to the cache logger: %v, %v", err, warnings.messages)
+	}
+	other := CertificatePins{"example.com": {"AAAA"}}
+	if err := fetch(WithRootCAs(pool), WithCertificatePins(other)); err != nil {
+		t.Errorf("pins of other hosts should not apply: %v", err)
+	}
+
+	if host, pin, err := ParseCertificatePin("GitHub.com=abc"); err != nil || host != "github.com" || pin != "abc" {
+		t.Errorf("unexpected parse result %q %q %v", host, pin, err)
+	}
+	if _, _, err := ParseCertificatePin("github.com"); err == nil {
+		t.Error("expected an error for a pin without host")
+	}
+	if pins := PinDefaultHosts("abc"); len(pins["raw.githubusercontent.com"]) != 1 {
+		t.Errorf("expected the default hosts to be pinned, got %v", pins)
+	}
+}
+
+func TestCertificatePinsVerifiedChains(t *testing.T) {
+	newCert := func() *x509.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	pinned, other := newCert(), newCert()
+	pins := CertificatePins{"github.com": {SPKIPin(pinned)}}
+
+	if err := pins.verify(tls.ConnectionState{ServerName: "github.com", PeerCertificates: []*x509.Certificate{pinned},
+		VerifiedChains: [][]*x509.Certificate{{pinned}}}); err != nil {
+		t.Errorf("expected the pinned chain to be accepted: %v", err)
+	}
+	// A server verified through another chain that merely sends the pinned certificate along
+	if err := pins.verify(tls.ConnectionState{ServerName: "github.com", PeerCertificates: []*x509.Certificate{other, pinned},
+		VerifiedChains: [][]*x509.Certificate{{other}}}); err == nil {
+		t.Error("expected a pinned certificate outside the verified chain not to count")
+	}
+	if err := pins.verify(tls.ConnectionState{ServerName: "github.com", PeerCertificates: []*x509.Certificate{pinned}}); err == nil {
+		t.Error("expected an unverified connection to a pinned host to be refused")
+	}
+}
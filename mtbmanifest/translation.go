@@ -0,0 +1,146 @@
+package mtbmanifest
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// Translations
+// ////////////////////////////////////////////////////////////////////////
+
+// The manifests are in English. Catalogs for other audiences can present translated names,
+// descriptions and categories next to the English text; a Translator supplies them, keyed by
+// item ID and category name. Languages are BCP 47 tags such as "ja" or "zh-CN".
+
+// ItemText is the translated text of one item. Empty fields have no translation.
+type ItemText struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Translator supplies translated text. Both methods report false when they have none, and
+// callers keep the English text.
+type Translator interface {
+	// ItemText returns the text of the board, app or middleware with the given ID
+	ItemText(lang, id string) (*ItemText, bool)
+	// CategoryName returns the name of a category, as written in the manifests
+	CategoryName(lang, category string) (string, bool)
+}
+
+// TranslationBundle is a Translator backed by tables read from JSON (see
+// ReadTranslationBundle). A region specific language falls back to its base language, e.g.
+// zh-TW to zh.
+type TranslationBundle struct {
+	langs map[string]*bundleLanguage
+}
+
+type bundleLanguage struct {
+	Items      map[string]*ItemText `json:"items"`
+	Categories map[string]string    `json:"categories"`
+}
+
+// ReadTranslationBundle parses a JSON object with one member per language:
+//
+//	{"ja": {"items": {"CY8CKIT-062S2-43012": {"name": "...", "description": "..."}},
+//	        "categories": {"Kit": "キット"}}}
+func ReadTranslationBundle(jsonData []byte) (*TranslationBundle, error) {
+	raw := map[string]*bundleLanguage{}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, err
+	}
+	bundle := &TranslationBundle{langs: make(map[string]*bundleLanguage)}
+	for lang, table := range raw {
+		if table == nil {
+			continue
+		}
+		items := make(map[string]*ItemText, len(table.Items))
+		for id, text := range table.Items {
+			if text != nil {
+				items[idKey(id)] = text
+			}
+		}
+		categories := make(map[string]string, len(table.Categories))
+		for category, name := range table.Categories {
+			categories[strings.ToLower(category)] = name
+		}
+		bundle.langs[strings.ToLower(lang)] = &bundleLanguage{Items: items, Categories: categories}
+	}
+	return bundle, nil
+}
+
+// Languages returns the languages the bundle has tables for, in no particular order
+func (tb *TranslationBundle) Languages() []string {
+	ret := make([]string, 0, len(tb.langs))
+	for lang := range tb.langs {
+		ret = append(ret, lang)
+	}
+	return ret
+}
+
+// lookup calls fn with the table of lang, then that of its base language, until fn finds
+// something
+func (tb *TranslationBundle) lookup(lang string, fn func(*bundleLanguage) bool) bool {
+	lang = strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	for lang != "" {
+		if table, ok := tb.langs[lang]; ok && fn(table) {
+			return true
+		}
+		i := strings.LastIndex(lang, "-")
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return false
+}
+
+// ItemText implements Translator
+func (tb *TranslationBundle) ItemText(lang, id string) (*ItemText, bool) {
+	var ret *ItemText
+	found := tb.lookup(lang, func(table *bundleLanguage) bool {
+		ret = table.Items[idKey(id)]
+		return ret != nil
+	})
+	return ret, found
+}
+
+// CategoryName implements Translator
+func (tb *TranslationBundle) CategoryName(lang, category string) (string, bool) {
+	var ret string
+	found := tb.lookup(lang, func(table *bundleLanguage) bool {
+		ret = table.Categories[strings.ToLower(category)]
+		return ret != ""
+	})
+	return ret, found
+}
+
+// Translation is the translated text of an item in one language, with the English text kept
+// wherever there is no translation
+type Translation struct {
+	Lang        string `json:"lang"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category,omitempty"`
+	// Translated is set when the translator had anything for the item
+	Translated bool `json:"translated"`
+}
+
+// Translate returns the text of an item in lang, given its ID and English text
+func Translate(t Translator, lang, id, name, description, category string) *Translation {
+	ret := &Translation{Lang: lang, Name: name, Description: description, Category: category}
+	if text, ok := t.ItemText(lang, id); ok {
+		if text.Name != "" {
+			ret.Name, ret.Translated = text.Name, true
+		}
+		if text.Description != "" {
+			ret.Description, ret.Translated = text.Description, true
+		}
+	}
+	if category != "" {
+		if c, ok := t.CategoryName(lang, category); ok {
+			ret.Category, ret.Translated = c, true
+		}
+	}
+	return ret
+}
@@ -0,0 +1,40 @@
+package mtbmanifest
+
+import (
+	"testing"
+)
+
+const testTranslations = `{
+  "ja": {
+    "items": {"CY8CKIT-062S2-43012": {"name": "PSoC 62S2 評価キット", "description": "Wi-Fi と Bluetooth 搭載"}},
+    "categories": {"kit": "キット"}
+  },
+  "zh-CN": {"items": {"freertos": {"description": "实时操作系统"}}}
+}`
+
+func TestTranslationBundle(t *testing.T) {
+	bundle, err := ReadTranslationBundle([]byte(testTranslations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Languages()) != 2 {
+		t.Errorf("expected two languages, got %v", bundle.Languages())
+	}
+	if text, ok := bundle.ItemText("ja-JP", "CY8CKIT-062S2-43012"); !ok || text.Name != "PSoC 62S2 評価キット" {
+		t.Errorf("ja-JP did not fall back to ja: %v %v", text, ok)
+	}
+	if name, ok := bundle.CategoryName("JA", "Kit"); !ok || name != "キット" {
+		t.Errorf("category %q %v", name, ok)
+	}
+	if _, ok := bundle.ItemText("zh", "freertos"); ok {
+		t.Error("zh must not use the zh-CN table")
+	}
+
+	tr := Translate(bundle, "zh_CN", "freertos", "FreeRTOS", "Real-time OS", "")
+	if !tr.Translated || tr.Name != "FreeRTOS" || tr.Description != "实时操作系统" {
+		t.Errorf("unexpected translation %+v", tr)
+	}
+	if tr := Translate(bundle, "de", "freertos", "FreeRTOS", "Real-time OS", "RTOS"); tr.Translated || tr.Description != "Real-time OS" {
+		t.Errorf("unexpected translation %+v", tr)
+	}
+}
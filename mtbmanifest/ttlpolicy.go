@@ -0,0 +1,172 @@
+package mtbmanifest
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////
+// TTL policies
+// ////////////////////////////////////////////////////////////////////////
+
+// A TTLPolicy decides how long the cache entry for a URL stays fresh. Super manifests change
+// rarely while code example manifests change often, so one TTL for everything is either too
+// eager for the former or too lazy for the latter. A zero return means the cache's own TTL.
+type TTLPolicy func(urlStr string) time.Duration
+
+// ManifestKind is the kind of a manifest file, as far as it can be told from its URL
+type ManifestKind string
+
+const (
+	KindUnknown      ManifestKind = ""
+	KindSuper        ManifestKind = "super"
+	KindBoards       ManifestKind = "boards"
+	KindApps         ManifestKind = "apps"
+	KindMiddleware   ManifestKind = "middleware"
+	KindDependencies ManifestKind = "dependencies"
+	KindCapabilities ManifestKind = "capabilities"
+)
+
+var manifestKinds = []ManifestKind{KindSuper, KindBoards, KindApps, KindMiddleware, KindDependencies, KindCapabilities}
+
+// ManifestKinds returns the known manifest kinds, from the super manifest down
+func ManifestKinds() []ManifestKind {
+	return append([]ManifestKind{}, manifestKinds...)
+}
+
+// ManifestKindOf guesses the kind of a manifest from the file name in its URL, following the
+// naming of the official manifests (mtb-super-manifest-fv2.xml, mtb-ce-manifest-fv2.xml,
+// mtb-bsp-dependencies-manifest.xml, ...). Returns KindUnknown when the name gives no hint.
+func ManifestKindOf(urlStr string) ManifestKind {
+	name := strings.ToLower(path.Base(strings.SplitN(urlStr, "?", 2)[0]))
+	switch {
+	case strings.Contains(name, "super-manifest"):
+		return KindSuper
+	case strings.Contains(name, "dependencies"):
+		return KindDependencies
+	case strings.Contains(name, "capabilit"):
+		return KindCapabilities
+	case strings.Contains(name, "-ce-") || strings.HasPrefix(name, "ce-") || strings.Contains(name, "app"):
+		return KindApps
+	case strings.Contains(name, "bsp") || strings.Contains(name, "board"):
+		return KindBoards
+	case strings.Contains(name, "mw") || strings.Contains(name, "middleware"):
+		return KindMiddleware
+	}
+	return KindUnknown
+}
+
+// TTLRule gives a TTL to the URLs of one manifest kind, or to the URLs matching a pattern
+type TTLRule struct {
+	Kind    ManifestKind
+	Pattern *regexp.Regexp
+	TTL     time.Duration
+}
+
+func (r *TTLRule) matches(urlStr string) bool {
+	if r.Pattern != nil {
+		return r.Pattern.MatchString(urlStr)
+	}
+	return r.Kind != KindUnknown && ManifestKindOf(urlStr) == r.Kind
+}
+
+// String returns the rule in the form ParseTTLRule accepts
+func (r *TTLRule) String() string {
+	if r.Pattern != nil {
+		return r.Pattern.String() + "=" + r.TTL.String()
+	}
+	return string(r.Kind) + "=" + r.TTL.String()
+}
+
+// NewTTLRulesPolicy returns a policy that applies the first matching rule
+func NewTTLRulesPolicy(rules ...*TTLRule) TTLPolicy {
+	return func(urlStr string) time.Duration {
+		for _, r := range rules {
+			if r.matches(urlStr) {
+				return r.TTL
+			}
+		}
+		return 0
+	}
+}
+
+// ParseTTLRule parses a rule written as KIND=DURATION or REGEX=DURATION, e.g. "super=30d",
+// "apps=1d" or `github\.com/Infineon/mtb-wifi-.*=12h`. KIND is one of super, boards, apps,
+// middleware, dependencies and capabilities; anything else is taken as a regular expression
+// matched against the URL. DURATION is a Go duration and may also use d for days.
+func ParseTTLRule(spec string) (*TTLRule, error) {
+	ix := strings.LastIndex(spec, "=")
+	if ix <= 0 {
+		return nil, fmt.Errorf("invalid TTL rule %q, expected KIND=DURATION or REGEX=DURATION", spec)
+	}
+	key, value := strings.TrimSpace(spec[:ix]), strings.TrimSpace(spec[ix+1:])
+	ttl, err := ParseTTL(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL rule %q: %v", spec, err)
+	}
+	for _, kind := range manifestKinds {
+		if strings.EqualFold(key, string(kind)) {
+			return &TTLRule{Kind: kind, TTL: ttl}, nil
+		}
+	}
+	re, err := regexp.Compile(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTL rule %q: %v", spec, err)
+	}
+	return &TTLRule{Pattern: re, TTL: ttl}, nil
+}
+
+// ParseTTL parses a positive Go duration such as "90m" or "12h", also accepting whole days
+// such as "15d"
+func ParseTTL(s string) (time.Duration, error) {
+	var ttl time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		ttl = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if ttl, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return ttl, nil
+}
+
+// defaultTTLPolicy, when set, is used by every cache the library creates for itself
+var defaultTTLPolicy TTLPolicy
+
+// SetDefaultTTLPolicy sets the policy used by default caches. Pass nil to use the default TTL
+// for every URL.
+func SetDefaultTTLPolicy(policy TTLPolicy) {
+	defaultTTLPolicy = policy
+}
+
+// CacheOption configures a ManifestCache
+type CacheOption func(*ManifestCache)
+
+// WithTTLPolicy gives URLs their own TTL. URLs the policy returns zero for use the cache TTL.
+func WithTTLPolicy(policy TTLPolicy) CacheOption {
+	return func(c *ManifestCache) {
+		c.ttlPolicy = policy
+	}
+}
+
+// TTL returns how long the cache entry for a URL stays fresh
+func (c *ManifestCache) TTL(urlStr string) time.Duration {
+	if c.ttlPolicy != nil {
+		if ttl := c.ttlPolicy(urlStr); ttl > 0 {
+			return ttl
+		}
+	}
+	return c.ttl
+}
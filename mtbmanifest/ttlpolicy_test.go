@@ -0,0 +1,67 @@
+package mtbmanifest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManifestKindOf(t *testing.T) {
+	tests := map[string]ManifestKind{
+		SuperManifestURL: KindSuper,
+		"https://github.com/Infineon/mtb-ce-manifest/raw/v2.X/mtb-ce-manifest-fv2.xml":                         KindApps,
+		"https://github.com/Infineon/mtb-bsp-manifest/raw/v2.X/mtb-bsp-manifest-fv2.xml":                       KindBoards,
+		"https://github.com/Infineon/mtb-mw-manifest/raw/v2.X/mtb-mw-manifest-fv2.xml":                         KindMiddleware,
+		"https://raw.githubusercontent.com/Infineon/mtb-bsp-manifest/v2.X/mtb-bsp-dependencies-manifest.xml":   KindDependencies,
+		"https://raw.githubusercontent.com/Infineon/mtb-bsp-manifest/v2.X/mtb-bsp-capabilities-manifest.xml?x": KindCapabilities,
+		"https://example.com/other.xml": KindUnknown,
+	}
+	for u, want := range tests {
+		if got := ManifestKindOf(u); got != want {
+			t.Errorf("ManifestKindOf(%s) = %q, want %q", u, got, want)
+		}
+	}
+}
+
+func TestTTLPolicy(t *testing.T) {
+	rules := []*TTLRule{}
+	for _, spec := range []string{`example\.com/special=2h`, "super=30d", "apps=1d"} {
+		rule, err := ParseTTLRule(spec)
+		if err != nil {
+			t.Fatalf("ParseTTLRule(%s) failed: %v", spec, err)
+		}
+		rules = append(rules, rule)
+	}
+	for _, bad := range []string{"super", "=1h", "apps=soon", "apps=-1h", "[=1h"} {
+		if _, err := ParseTTLRule(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+
+	cache := NewManifestCache(WithStore(NewMemoryStore()), WithCacheTTL(time.Hour), WithTTLPolicy(NewTTLRulesPolicy(rules...)))
+	defer cache.Close()
+	tests := map[string]time.Duration{
+		SuperManifestURL: 30 * 24 * time.Hour,
+		"https://github.com/Infineon/mtb-ce-manifest/raw/v2.X/mtb-ce-manifest-fv2.xml": 24 * time.Hour,
+		"https://example.com/special/mtb-super-manifest.xml":                           2 * time.Hour,
+		"https://example.com/boards.xml":                                               time.Hour,
+	}
+	for u, want := range tests {
+		if got := cache.TTL(u); got != want {
+			t.Errorf("TTL(%s) = %v, want %v", u, got, want)
+		}
+	}
+
+	// Entries are stale by their own TTL
+	old := time.Now().Add(-3 * time.Hour)
+	_ = cache.store.Put(SuperManifestURL, []byte("super"), old)
+	_ = cache.store.Put("https://example.com/boards.xml", []byte("boards"), old)
+	if err := cache.ClearStale(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.readCache(SuperManifestURL); err != nil {
+		t.Error("the super manifest should still be fresh")
+	}
+	if _, err := cache.readCache("https://example.com/boards.xml"); err == nil {
+		t.Error("the boards manifest should have been cleared as stale")
+	}
+}
@@ -54,7 +54,7 @@ func (m *Dependencies) CreateMaps() map[string]*Depender {
 		m.LibraryMap = make(map[string][]string)
 		for _, depender := range m.Dependers {
 			// depender.ID is the BSP ID
-			m.DependersMap[depender.ID] = depender
+			m.DependersMap[idKey(depender.ID)] = depender
 			depender.VersionsMap = make(map[string]*DependerVersion)
 			for _, v := range depender.Versions {
 				depender.VersionsMap[v.Commit] = v
@@ -62,7 +62,7 @@ func (m *Dependencies) CreateMaps() map[string]*Depender {
 				for _, dependee := range v.Dependees {
 					// dependee.ID is the library ID
 					v.DependeesMap[dependee.ID] = dependee
-					m.LibraryMap[dependee.ID] = append(m.LibraryMap[dependee.ID], depender.ID)
+					m.LibraryMap[idKey(dependee.ID)] = append(m.LibraryMap[idKey(dependee.ID)], depender.ID)
 				}
 			}
 		}
@@ -72,7 +72,7 @@ func (m *Dependencies) CreateMaps() map[string]*Depender {
 
 // Helper function to get dependencies for a specific BSP and version
 func (m *Dependencies) GetDependencies(bspID, version string) ([]*Dependee, bool) {
-	if depender, exists := m.CreateMaps()[bspID]; exists {
+	if depender, exists := m.CreateMaps()[idKey(bspID)]; exists {
 		if versionEntry, exists := depender.VersionsMap[version]; exists {
 			return versionEntry.Dependees, true
 		}
@@ -81,12 +81,12 @@ func (m *Dependencies) GetDependencies(bspID, version string) ([]*Dependee, bool
 }
 
 func (m *Dependencies) GetBSP(bspID string) *Depender {
-	return m.CreateMaps()[bspID]
+	return m.CreateMaps()[idKey(bspID)]
 }
 
 // Helper function to get all versions of a BSP
 func (m *Dependencies) GetBSPVersions(bspID string) ([]*DependerVersion, map[string]*DependerVersion, bool) {
-	if depender, exists := m.CreateMaps()[bspID]; exists {
+	if depender, exists := m.CreateMaps()[idKey(bspID)]; exists {
 		return depender.Versions, depender.VersionsMap, true
 	}
 	return nil, nil, false
@@ -104,7 +104,7 @@ func (m *Dependencies) GetAllBSPs() []string {
 // Helper function to find all BSPs that depend on a specific library
 func (m *Dependencies) FindBSPsUsingLibrary(libraryID string) []string {
 	_ = m.CreateMaps()
-	return m.LibraryMap[libraryID]
+	return m.LibraryMap[idKey(libraryID)]
 }
 
 func ReadBSPDependenciesManifest(data []byte) (*Dependencies, error) {
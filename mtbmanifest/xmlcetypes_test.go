@@ -304,3 +304,37 @@ func TestKeywordsParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestBoardVersionCapabilities(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	board, _ := sm.GetBoard("CY8CKIT-062S2-43012")
+	older, _ := board.Version("release-v4.1.0")
+	older.ProvCapabilitiesPerVersion = "cat1 cat1a psoc6 hal led switch"
+
+	byVersion := board.CapabilitiesByVersion()
+	if len(byVersion["latest-v4.X"]) != 9 || len(byVersion["release-v4.1.0"]) != 6 {
+		t.Fatalf("unexpected capabilities %v", byVersion)
+	}
+	if caps, ok := board.CapabilitiesForVersion("4.1.0 release"); !ok || len(caps) != 6 {
+		t.Errorf("expected the per-version capabilities by num, got %v, %v", caps, ok)
+	}
+	if caps, ok := board.CapabilitiesForVersion("release-v1.0.0"); ok || len(caps) != 9 {
+		t.Errorf("expected the base capabilities for an unknown version, got %v, %v", caps, ok)
+	}
+
+	ids := func(apps []*App, mws []*MiddlewareItem) []string {
+		ret := []string{}
+		for _, a := range apps {
+			ret = append(ret, a.ID)
+		}
+		for _, mw := range mws {
+			ret = append(ret, mw.ID)
+		}
+		return ret
+	}
+	latest := ids(FindCodeExamplesForBoardVersion(sm, board, "latest-v4.X"), FindMiddlewareForBoardVersion(sm, board, "latest-v4.X"))
+	old := ids(FindCodeExamplesForBoardVersion(sm, board, "release-v4.1.0"), FindMiddlewareForBoardVersion(sm, board, "release-v4.1.0"))
+	if len(latest) != 4 || len(old) != 2 {
+		t.Errorf("expected the older version without Wi-Fi to match less, got %v and %v", latest, old)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"encoding/xml"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"reflect"
-	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const SuperManifestURL = "https://github.com/Infineon/mtb-super-manifest/raw/v2.X/mtb-super-manifest-fv2.xml"
@@ -55,8 +57,18 @@ var logger LoggerIF = defLogger
 // BSP capabilities, and merge multiple super manifests.
 type SuperManifestIF interface {
 	// GetBoardsMap returns a map of all boards indexed by their ID
+	//
+	// Deprecated: the map is the tree's own index and must not be modified. Use BoardsByID,
+	// which returns a copy, or Boards.
 	GetBoardsMap() *map[string]*Board
 
+	// Boards returns all boards in manifest order
+	Boards() []*Board
+
+	// BoardsByID returns a copy of the board index, keyed by ID (lower-cased if IDs are
+	// case-insensitive, see EnableCaseInsensitiveIDs)
+	BoardsByID() map[string]*Board
+
 	// Get list of board IDs. Order is according to manifest listing.
 	GetBoardIDs() []string
 
@@ -64,8 +76,17 @@ type SuperManifestIF interface {
 	GetBoard(boardID string) (*Board, bool)
 
 	// GetAppsMap returns a map of all apps indexed by their ID
+	//
+	// Deprecated: the map is the tree's own index and must not be modified. Use AppsByID, which
+	// returns a copy, or Apps.
 	GetAppsMap() *map[string]*App
 
+	// Apps returns all apps in manifest order
+	Apps() []*App
+
+	// AppsByID returns a copy of the app index, keyed like BoardsByID
+	AppsByID() map[string]*App
+
 	// Get list of app IDs. Order is according to manifest listing.
 	GetAppIDs() []string
 
@@ -73,8 +94,17 @@ type SuperManifestIF interface {
 	GetApp(appID string) (*App, bool)
 
 	// GetMiddlewareMap returns a map of all middleware items indexed by their ID
+	//
+	// Deprecated: the map is the tree's own index and must not be modified. Use
+	// MiddlewareByID, which returns a copy, or Middleware.
 	GetMiddlewareMap() *map[string]*MiddlewareItem
 
+	// Middleware returns all middleware items in manifest order
+	Middleware() []*MiddlewareItem
+
+	// MiddlewareByID returns a copy of the middleware index, keyed like BoardsByID
+	MiddlewareByID() map[string]*MiddlewareItem
+
 	// Get list of middleware IDs. Order is according to manifest listing.
 	GetMiddlewareIDs() []string
 
@@ -92,6 +122,50 @@ type SuperManifestIF interface {
 
 	// AddSuperManifestFromURL fetches a super manifest from a URL and merges it into this one
 	AddSuperManifestFromURL(urlStr string) error
+
+	// Search does a full-text search over boards, apps and middleware, best match first
+	Search(query string, opts *SearchOptions) []*SearchResult
+
+	// FindClosest suggests IDs close to one that was not found (case, punctuation, typos)
+	FindClosest(id string, kinds ...ItemKind) []Suggestion
+
+	// GetAppsByKeyword returns the apps carrying a keyword (case-insensitive), in manifest order
+	GetAppsByKeyword(keyword string) []*App
+
+	// GetKeywordIndex returns the app keyword index, with keyword counts and co-occurrence stats
+	GetKeywordIndex() *KeywordIndex
+
+	// ListBoards returns a filtered, sorted page of boards
+	ListBoards(opts *ListOptions) (*ListPage[*Board], error)
+
+	// ListApps returns a filtered, sorted page of apps
+	ListApps(opts *ListOptions) (*ListPage[*App], error)
+
+	// ListMiddleware returns a filtered, sorted page of middleware items
+	ListMiddleware(opts *ListOptions) (*ListPage[*MiddlewareItem], error)
+
+	// GetSourceURLs returns the URLs of the super manifests this tree was ingested from
+	GetSourceURLs() []string
+
+	// ManifestURLs returns the URL of every manifest file in the tree, each once
+	ManifestURLs() []string
+
+	// ReferencedURLs returns the URL of every manifest file in the tree with its kind, each once
+	ReferencedURLs() []TypedURL
+
+	// FromSnapshot reports whether the tree came from the embedded snapshot, and how old it is
+	FromSnapshot() (created time.Time, ok bool)
+
+	// PinnedSnapshot returns the ID of the stored snapshot the tree was loaded from, if any
+	PinnedSnapshot() string
+
+	// IngestReport returns how the tree was loaded and which manifests failed, if it was
+	// loaded from URLs
+	IngestReport() *IngestReport
+
+	// Completeness reports which child manifests loaded, which failed, and whether the tree
+	// is partial as a result
+	Completeness() *Completeness
 }
 
 // Super Manifest structures
@@ -111,10 +185,31 @@ type SuperManifest struct {
 	boardsMap     map[string]*Board
 	appMap        map[string]*App
 	middlewareMap map[string]*MiddlewareItem
+	searchIndex   lazyIndex[SearchIndex]
+	keywordIndex  lazyIndex[KeywordIndex]
+	// matrices are the compatibility matrices built so far, by options (see matrixMu)
+	matrices map[MatrixOptions]*CompatibilityMatrix
+
+	// snapshot is set when the tree was loaded from the embedded snapshot
+	snapshot *BundleIndex
+	// pinnedSnapshot is the ID of the stored snapshot the tree was loaded from (see SnapshotStore)
+	pinnedSnapshot string
+	// ingestReport describes how the tree was loaded (see IngestReport)
+	ingestReport *IngestReport
+	// mergedReports are the ingest reports of the trees merged into this one
+	mergedReports []*IngestReport
+	// mergeWarnings are the warnings of merging trees into this one
+	mergeWarnings []*IngestWarning
+	// logger gets the messages about the tree; nil means the package logger (see WithLogger)
+	logger LoggerIF
+	// ingestCfg is how the tree was ingested; AddSuperManifestFromURL ingests the same way
+	ingestCfg *ingestConfig
 
 	// Following stores downloaded BSP manifests to avoid re-fetching across multiple boards and manifests
 	bspCapabilitiesMap map[string]*BSPCapabilitiesManifest
 	dependenciesMap    map[string]*Dependencies
+	// contentHashes are the SHA-256 of the dependency and capability manifests, by URL
+	contentHashes map[string]string
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -129,145 +224,311 @@ func NewSuperManifest() SuperManifestIF {
 		MiddlewareManifestList: &MiddlewareManifestList{},
 		bspCapabilitiesMap:     make(map[string]*BSPCapabilitiesManifest),
 		dependenciesMap:        make(map[string]*Dependencies),
+		contentHashes:          make(map[string]string),
 	}
 	ret.clearMaps()
 	return ret
 }
 
 // NewSuperManifestFromURL fetches and ingests a complete super manifest tree from the given URL.
-// If urlStr is empty, it uses the default SuperManifestURL.
+// If urlStr is empty, it uses the default SuperManifestURL, or the one of WithFormat or
+// WithFormatNegotiation.
 // This constructor fetches all board, app, and middleware manifests concurrently.
-func NewSuperManifestFromURL(urlStr string) (SuperManifestIF, error) {
-	urlFetcher := NewManifestFetcher(WithMaxConcurrent(runtime.NumCPU()))
+//
+// Without options the default cache is used (see SetDefaultCacheStore); see IngestOption for
+// what can be changed, e.g.
+//
+//	sm, err := NewSuperManifestFromURL("", WithOffline(), WithSections(KindBoards))
+func NewSuperManifestFromURL(urlStr string, opts ...IngestOption) (SuperManifestIF, error) {
+	superManifest, err := newSuperManifest(urlStr, newIngestConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	return superManifest, nil
+}
+
+// newSuperManifestFromCache ingests a super manifest tree through the given cache. A nil
+// cache means the default cache.
+func newSuperManifestFromCache(urlStr string, cache *ManifestCache) (*SuperManifest, error) {
+	return newSuperManifest(urlStr, newIngestConfig([]IngestOption{withIngestCache(cache)}))
+}
+
+// newSuperManifest ingests a super manifest tree as configured
+func newSuperManifest(urlStr string, cfg *ingestConfig) (*SuperManifest, error) {
+	logger := cfg.logger // messages about this ingestion go to the configured logger
+	urlFetcher, done := cfg.newFetcher()
+	defer done()
 	if urlStr == "" {
-		urlStr = SuperManifestURL
+		var err error
+		if urlStr, err = cfg.superManifestURL(urlFetcher.Cache()); err != nil {
+			return nil, err
+		}
 	}
 
+	report := newIngestReport(urlStr)
+	history := cfg.ingestHistory(urlFetcher.Cache())
+	if history != nil {
+		cfg.startCheckpoints(history, report)
+	}
+	if cfg.onReport != nil {
+		defer func() { cfg.onReport(report) }() // once the deferred func below completed it
+	}
+	defer func() {
+		report.Duration = time.Since(report.StartedAt)
+		report.RateLimits = urlFetcher.Cache().RateLimits()
+		if history != nil {
+			report.checkpoint = nil
+			if cfg.ctx.Err() != nil {
+				// Interrupted: the checkpoint stays for resuming, the report is not one of a run
+				if err := history.saveCheckpoint(report); err != nil {
+					logger.Warningf("Failed to save the checkpoint of %s: %v\n", urlStr, err)
+				}
+				return
+			}
+			history.removeCheckpoint(urlStr)
+			if err := history.Save(report); err != nil {
+				report.warn(logger, WarnHistoryNotSaved, urlStr, "Failed to save the ingest report in %s: %v", history.Dir(), err)
+			}
+		}
+	}()
+
 	// logger.Infof("Fetching super manifest...%s\n", urlStr)
-	superData, err := urlFetcher.Cache().Get(urlStr)
+	var err error
+	superData, resumed := getResumed(urlFetcher.Cache(), report, urlStr)
+	if !resumed {
+		superData, err = urlFetcher.Cache().GetContext(cfg.ctx, urlStr)
+	}
+	var snapshot *BundleIndex
+	if err != nil && cfg.ctx.Err() == nil && cfg.snapshotFallback {
+		if snapshot = seedCacheFromSnapshot(urlFetcher.Cache(), urlStr, logger); snapshot != nil {
+			superData, err = urlFetcher.Cache().Get(urlStr)
+		}
+	}
+	report.FromSnapshot = snapshot != nil
+	if snapshot != nil {
+		// seedCacheFromSnapshot logged it
+		report.addWarning(&IngestWarning{Code: WarnSnapshotFallback, URL: urlStr,
+			Message: fmt.Sprintf("using the embedded manifest snapshot from %s", snapshot.Created.Format("2006-01-02"))})
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch super manifest %s: %v", urlStr, err)
+		report.record(urlStr, KindSuper, nil, err)
+		return nil, fmt.Errorf("failed to fetch super manifest %s: %w", urlStr, &manifestFetchError{err})
 	}
-	superManifest, err := UnmarshalManifest(superData, err, ReadSuperManifest)
+	superManifest, err := UnmarshalManifest(superData, err, parser[SuperManifest](cfg, report, urlStr))
+	report.record(urlStr, KindSuper, superData, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse super manifest %s: %v", urlStr, err)
+		return nil, fmt.Errorf("failed to parse super manifest %s: %w", urlStr, err)
+	}
+	if major := formatMajor(superManifest.Version); major > SupportedFormatMajor {
+		report.warn(logger, WarnNewerFormat, urlStr, "super manifest version %s is newer than the fv%d this library understands",
+			superManifest.Version, SupportedFormatMajor)
 	}
 	superManifest.SourceUrls = append(superManifest.SourceUrls, urlStr)
+	superManifest.snapshot = snapshot
+	superManifest.ingestReport = report
+	superManifest.logger = logger
+	superManifest.ingestCfg = cfg
 	superManifest.clearMaps()
+	superURL := urlStr // the callbacks get the URL of their own manifest as urlStr
 
 	urls := []*FetchUrlWithCb{}
 	var mu sync.Mutex
+	memo := newContentMemo() // identical content is parsed once and aliased afterwards
 	depUrls := make(map[string]interface{})
 	capUrls := make(map[string]interface{})
-	for ix, mManifest := range superManifest.BoardManifestList.BoardManifest {
+	boardManifests := superManifest.BoardManifestList.BoardManifest
+	if !cfg.wants(KindBoards) {
+		boardManifests = nil
+	}
+	for ix, mManifest := range boardManifests {
 		item := &FetchUrlWithCb{
 			Url: mManifest.URI, Index: ix,
 			Callback: func(urlStr string, data []byte, err error, index int) {
 				// logger.Infof("Board: %s: len=%d, err=%v, index=%d\n", urlStr, len(data), err, index)
-				boards, err := UnmarshalManifest(data, err, ReadBoardManifest)
+				boards, first, err := parseOnce(memo, data, err, parser[Boards](cfg, report, urlStr))
+				report.record(urlStr, KindBoards, data, err)
 				if err != nil {
 					logger.Errorf("Error fetching %s: %v\n", urlStr, err)
 				} else {
 					mu.Lock()
 					bm := superManifest.BoardManifestList.BoardManifest[index]
-					bm.Boards = boards
-					for _, board := range bm.Boards.Boards {
-						board.Origin = bm
+					bm.provenance = newProvenance(urlFetcher.Cache(), superURL, urlStr, data)
+					bm.ingestCfg = cfg
+					if first {
+						bm.Boards = boards
+						for _, board := range bm.Boards.Boards {
+							board.Origin, board.Channel = bm, cfg.channel
+						}
 					}
 					mu.Unlock()
 				}
 			},
 		}
-		if mManifest.CapabilityURL != "" {
+		if mManifest.CapabilityURL != "" && cfg.wants(KindCapabilities) {
 			capUrls[mManifest.CapabilityURL] = mManifest
 		}
-		if mManifest.DependencyURL != "" {
+		if mManifest.DependencyURL != "" && cfg.wants(KindDependencies) {
 			depUrls[mManifest.DependencyURL] = mManifest
 		}
 		urls = append(urls, item)
 	}
 
-	for ix, aManifest := range superManifest.AppManifestList.AppManifest {
+	appManifests := superManifest.AppManifestList.AppManifest
+	if !cfg.wants(KindApps) {
+		appManifests = nil
+	}
+	for ix, aManifest := range appManifests {
 		item := &FetchUrlWithCb{
 			Url: aManifest.URI, Index: ix,
 			Callback: func(urlStr string, data []byte, err error, index int) {
 				// logger.Infof("App: %s: len=%d, err=%v, index=%d\n", urlStr, len(data), err, index)
-				app, err := UnmarshalManifest(data, err, ReadAppsManifest)
+				app, first, err := parseOnce(memo, data, err, parser[Apps](cfg, report, urlStr))
+				report.record(urlStr, KindApps, data, err)
 				if err != nil {
 					logger.Errorf("Error fetching %s: %v\n", urlStr, err)
 				} else {
 					mu.Lock()
-					superManifest.AppManifestList.AppManifest[index].Apps = app
+					am := superManifest.AppManifestList.AppManifest[index]
+					am.provenance = newProvenance(urlFetcher.Cache(), superURL, urlStr, data)
+					am.ingestCfg = cfg
+					if first {
+						am.Apps = app
+						for _, a := range am.Apps.App {
+							a.Origin, a.Channel = am, cfg.channel
+						}
+					}
 					mu.Unlock()
 				}
 			},
 		}
 		urls = append(urls, item)
 	}
-	for ix, mManifest := range superManifest.MiddlewareManifestList.MiddlewareManifest {
+	middlewareManifests := superManifest.MiddlewareManifestList.MiddlewareManifest
+	if !cfg.wants(KindMiddleware) {
+		middlewareManifests = nil
+	}
+	for ix, mManifest := range middlewareManifests {
 		item := &FetchUrlWithCb{
 			Url: mManifest.URI, Index: ix,
 			Callback: func(urlStr string, data []byte, err error, index int) {
 				// logger.Infof("Middleware: %s: len=%d, err=%v, index=%d\n", urlStr, len(data), err, index)
-				middleware, err := UnmarshalManifest(data, err, ReadMiddlewareManifest)
+				middleware, first, err := parseOnce(memo, data, err, parser[Middleware](cfg, report, urlStr))
+				report.record(urlStr, KindMiddleware, data, err)
 				if err != nil {
 					logger.Errorf("Error fetching file %s: %v\n", urlStr, err)
 				} else {
 					mu.Lock()
 					mwM := superManifest.MiddlewareManifestList.MiddlewareManifest[index]
-					mwM.Middlewares = middleware
-					for _, mw := range mwM.Middlewares.Middlewares {
-						mw.Origin = mwM
+					mwM.provenance = newProvenance(urlFetcher.Cache(), superURL, urlStr, data)
+					mwM.ingestCfg = cfg
+					if first {
+						mwM.Middlewares = middleware
+						for _, mw := range mwM.Middlewares.Middlewares {
+							mw.Origin, mw.Channel = mwM, cfg.channel
+						}
 					}
 					mu.Unlock()
 				}
 			},
 		}
-		if mManifest.DependencyURL != "" {
+		if mManifest.DependencyURL != "" && cfg.wants(KindDependencies) {
 			depUrls[mManifest.DependencyURL] = mManifest
 		}
 		urls = append(urls, item)
 	}
+	// Dependencies and capabilities come from their URLs unless a provider supplies them, in
+	// which case the provider is asked once the fetching is done (see providers.go)
+	providerCalls := []*FetchUrlWithCb{}
 	depMap := make(map[string]*Dependencies)
-	for depUrl := range depUrls {
+	hashes := make(map[string]string)
+	addDependencies := func(urlStr string, deps *Dependencies, data []byte, err error) {
+		report.record(urlStr, KindDependencies, data, err)
+		if err != nil {
+			logger.Errorf("Error fetching dependencies %s: %v\n", urlStr, err)
+		} else {
+			mu.Lock()
+			depMap[urlStr] = deps
+			if data != nil {
+				hashes[urlStr] = hashContent(data)
+			}
+			mu.Unlock()
+		}
+	}
+	for _, depUrl := range orderedKeys(depUrls) {
+		if cfg.dependencyProvider != nil {
+			providerCalls = append(providerCalls, &FetchUrlWithCb{
+				Url: depUrl,
+				Callback: func(urlStr string, _ []byte, _ error, index int) {
+					deps, err := cfg.dependencyProvider.Dependencies(urlStr)
+					if err == nil && deps == nil {
+						err = fmt.Errorf("no dependencies for %s", urlStr)
+					}
+					addDependencies(urlStr, deps, nil, err)
+				},
+			})
+			continue
+		}
 		item := &FetchUrlWithCb{
 			Url: depUrl,
 			Callback: func(urlStr string, data []byte, err error, index int) {
-				// logger.Infof("Dependencies: %s: len=%d, err=%v\n", urlStr, len(data), err)
-				deps, err := UnmarshalManifest(data, err, ReadDependenciesManifest)
-				if err != nil {
-					logger.Errorf("Error fetching dependencies %s: %v\n", urlStr, err)
-				} else {
-					mu.Lock()
-					depMap[urlStr] = deps
-					mu.Unlock()
-				}
+				deps, _, err := parseOnce(memo, data, err, parser[Dependencies](cfg, report, urlStr))
+				addDependencies(urlStr, deps, data, err)
 			},
 		}
 		urls = append(urls, item)
 	}
 	capMap := make(map[string]*BSPCapabilitiesManifest)
-	for capUrl := range capUrls {
+	addCapabilities := func(urlStr string, caps *BSPCapabilitiesManifest, data []byte, err error) {
+		report.record(urlStr, KindCapabilities, data, err)
+		if err != nil {
+			logger.Errorf("Error fetching capabilities %s: %v\n", urlStr, err)
+		} else {
+			mu.Lock()
+			capMap[urlStr] = caps
+			if data != nil {
+				hashes[urlStr] = hashContent(data)
+			}
+			mu.Unlock()
+		}
+	}
+	for _, capUrl := range orderedKeys(capUrls) {
+		if cfg.capabilityProvider != nil {
+			providerCalls = append(providerCalls, &FetchUrlWithCb{
+				Url: capUrl,
+				Callback: func(urlStr string, _ []byte, _ error, index int) {
+					caps, err := cfg.capabilityProvider.Capabilities(urlStr)
+					if err == nil && caps == nil {
+						err = fmt.Errorf("no capabilities for %s", urlStr)
+					}
+					addCapabilities(urlStr, caps, nil, err)
+				},
+			})
+			continue
+		}
 		item := &FetchUrlWithCb{
 			Url: capUrl,
 			Callback: func(urlStr string, data []byte, err error, index int) {
-				// logger.Infof("Capabilities: %s: len=%d, err=%v\n", urlStr, len(data), err)
-				caps, err := UnmarshalManifest(data, err, ReadBSPCapabilitiesManifest)
-				if err != nil {
-					logger.Errorf("Error fetching capabilities %s: %v\n", urlStr, err)
-				} else {
-					mu.Lock()
-					capMap[urlStr] = caps
-					mu.Unlock()
-				}
+				caps, _, err := parseOnce(memo, data, err, ReadBSPCapabilitiesManifest)
+				addCapabilities(urlStr, caps, data, err)
 			},
 		}
 		urls = append(urls, item)
 	}
 
-	urlFetcher.FetchAllWithCb(urls)
+	if cfg.progress != nil {
+		reportProgress(append(urls, providerCalls...), cfg.progress, urlStr)
+	}
+	urlFetcher.fetchAllWithCb(cfg.ctx, resumeFromCache(urlFetcher.Cache(), report, urls))
+	if err := cfg.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("ingestion of %s interrupted: %w", urlStr, err)
+	}
+	for _, item := range providerCalls {
+		item.Callback(item.Url, nil, nil, item.Index)
+	}
 	superManifest.dependenciesMap = depMap
 	superManifest.bspCapabilitiesMap = capMap
+	superManifest.contentHashes = hashes
+	superManifest.aliasDuplicates()
 
 	for _, dep := range depMap {
 		_ = dep.CreateMaps()
@@ -277,46 +538,115 @@ func NewSuperManifestFromURL(urlStr string) (SuperManifestIF, error) {
 		// cap.CreateMaps()
 	}
 
-	for depUrl, manifest := range depUrls {
-		if boardM, ok := manifest.(*BoardManifest); ok {
-			for _, board := range boardM.Boards.Boards {
-				if (board.Origin != manifest) || (board.Origin.DependencyURL != depUrl) {
-					fmt.Printf("Warning: Board %s origin manifest mismatch for dependency URL %s\n", board.ID, depUrl)
-				}
-				board.Dependencies = depMap[depUrl].CreateMaps()[board.ID]
+	// A failed manifest leaves the items it would have been linked to without dependencies or
+	// capabilities, and Completeness reports it. Manifests are linked one by one, as several may
+	// share a dependency or capability URL.
+	for _, boardM := range boardManifests {
+		if boardM.Boards == nil {
+			continue
+		}
+		deps, caps := depMap[boardM.DependencyURL], capMap[boardM.CapabilityURL]
+		for _, board := range boardM.Boards.Boards {
+			if deps != nil {
+				board.Dependencies = deps.CreateMaps()[idKey(board.ID)]
 			}
-		} else if mwM, ok := manifest.(*MiddlewareManifest); ok {
-			for _, mw := range mwM.Middlewares.Middlewares {
-				if (mw.Origin != manifest) || (mw.Origin.DependencyURL != depUrl) {
-					fmt.Printf("Warning: Middleware %s origin manifest mismatch for dependency URL %s\n", mw.ID, depUrl)
-				}
-				mw.Dependencies = depMap[depUrl].CreateMaps()[mw.ID]
+			if caps != nil {
+				board.Capabilities = caps
 			}
 		}
 	}
-	for capUrl, manifest := range capUrls {
-		if boardM, ok := manifest.(*BoardManifest); ok {
-			for _, board := range boardM.Boards.Boards {
-				if (board.Origin != manifest) || (board.Origin.CapabilityURL != capUrl) {
-					fmt.Printf("Warning: Board %s origin manifest mismatch for capability URL %s\n", board.ID, capUrl)
-				}
-				board.Capabilities = capMap[capUrl]
+	for _, mwM := range middlewareManifests {
+		if mwM.Middlewares == nil {
+			continue
+		}
+		if deps := depMap[mwM.DependencyURL]; deps != nil {
+			for _, mw := range mwM.Middlewares.Middlewares {
+				mw.Dependencies = deps.CreateMaps()[idKey(mw.ID)]
 			}
 		}
 	}
 
+	if cfg.readmes != nil && !cfg.offline && cfg.ctx.Err() == nil {
+		readmes := cfg.readmes.Readmes(superManifest, cfg.concurrency)
+		logger.Debugf("Fetched %d of %d middleware READMEs\n", len(readmes), len(superManifest.GetMiddlewareIDs()))
+	}
+	superManifest.GetKeywordIndex()
+	if doBuildSearchIndex {
+		superManifest.GetSearchIndex()
+	}
+
+	report.Boards = len(superManifest.GetBoardIDs())
+	report.Apps = len(superManifest.GetAppIDs())
+	report.Middleware = len(superManifest.GetMiddlewareIDs())
+
 	logger.Infof("Fetched super manifest with %d boards, %d apps, %d middleware\n",
 		len(superManifest.BoardManifestList.BoardManifest),
 		len(superManifest.AppManifestList.AppManifest),
 		len(superManifest.MiddlewareManifestList.MiddlewareManifest))
+	if len(cfg.failOn) > 0 {
+		if warnings := report.WarningsOf(cfg.failOn...); len(warnings) > 0 {
+			return nil, &IngestWarningsError{URL: urlStr, Warnings: warnings}
+		}
+	}
 	return superManifest, err
 }
 
+// reportProgress counts the super manifest as read and wraps the callbacks of urls to count
+// the others
+func reportProgress(urls []*FetchUrlWithCb, progress ProgressFunc, superURL string) {
+	var mu sync.Mutex
+	done, total := 1, len(urls)+1
+	progress(done, total, superURL)
+	for _, item := range urls {
+		callback := item.Callback
+		item.Callback = func(urlStr string, data []byte, err error, index int) {
+			callback(urlStr, data, err, index)
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			progress(done, total, urlStr)
+		}
+	}
+}
+
 // Maps are cleared when manifests are merged or modified so that they can be rebuilt on demand
 func (sm *SuperManifest) clearMaps() {
 	sm.boardsMap = make(map[string]*Board)
 	sm.appMap = make(map[string]*App)
 	sm.middlewareMap = make(map[string]*MiddlewareItem)
+	sm.searchIndex.reset()
+	sm.keywordIndex.reset()
+	matrixMu.Lock()
+	sm.matrices = nil
+	matrixMu.Unlock()
+}
+
+// lazyIndex is an index of a tree, built on first use by whichever goroutine asks first while
+// the others wait for it. A reset makes the next use build it again; readers that got the old
+// index keep it.
+type lazyIndex[T any] struct {
+	built atomic.Pointer[lazyBuild[T]]
+}
+
+type lazyBuild[T any] struct {
+	once  sync.Once
+	index *T
+}
+
+// get returns the index, calling build if it was not built since the last reset
+func (l *lazyIndex[T]) get(build func() *T) *T {
+	b := l.built.Load()
+	for b == nil {
+		l.built.CompareAndSwap(nil, &lazyBuild[T]{})
+		b = l.built.Load()
+	}
+	b.once.Do(func() { b.index = build() })
+	return b.index
+}
+
+// reset makes the next get build the index again
+func (l *lazyIndex[T]) reset() {
+	l.built.Store(nil)
 }
 
 type BoardManifestList struct {
@@ -334,6 +664,14 @@ type BoardManifest struct {
 	CapabilityURL string   `xml:"capability-url,attr,omitempty"`
 	URI           string   `xml:"uri"`
 	Boards        *Boards
+	// AliasOf is the URL of an earlier manifest of the tree with the same content, which holds
+	// the items of both (see aliases.go)
+	AliasOf string `xml:"-" json:"aliasOf,omitempty"`
+
+	// provenance is where the manifest was read from (see provenance.go)
+	provenance *Provenance
+	// ingestCfg is how the manifest was ingested, for the settings of its tree
+	ingestCfg *ingestConfig
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -353,6 +691,15 @@ type AppManifest struct {
 	XMLName xml.Name `xml:"app-manifest"`
 	URI     string   `xml:"uri"`
 	Apps    *Apps
+	// AliasOf is the URL of an earlier manifest of the tree with the same content, which holds
+	// the items of both (see aliases.go)
+	AliasOf string `xml:"-" json:"aliasOf,omitempty"`
+
+	// provenance is where the manifest was read from (see provenance.go)
+	provenance *Provenance
+	// ingestCfg is how the manifest was ingested, for the settings of its tree
+	ingestCfg *ingestConfig
+
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
 	LostAttrs []xml.Attr `xml:",any,attr"`
@@ -372,6 +719,14 @@ type MiddlewareManifest struct {
 	DependencyURL string   `xml:"dependency-url,attr,omitempty"`
 	URI           string   `xml:"uri"`
 	Middlewares   *Middleware
+	// AliasOf is the URL of an earlier manifest of the tree with the same content, which holds
+	// the items of both (see aliases.go)
+	AliasOf string `xml:"-" json:"aliasOf,omitempty"`
+
+	// provenance is where the manifest was read from (see provenance.go)
+	provenance *Provenance
+	// ingestCfg is how the manifest was ingested, for the settings of its tree
+	ingestCfg *ingestConfig
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -381,6 +736,9 @@ type MiddlewareManifest struct {
 type Boards struct {
 	XMLName xml.Name `xml:"boards"`
 	Boards  []*Board `xml:"board"`
+	// Format is the format revision the manifest was read in, 1 or 2; fv1 manifests are
+	// normalized into the fv2 model (see formatdispatch.go)
+	Format int `xml:"-" json:"format,omitempty"`
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -403,6 +761,8 @@ type Board struct {
 
 	//lint:ignore SA5008 Static checker false positive
 	Origin *BoardManifest `json:"-" xml:"-"`
+	// Channel is the channel the item was ingested from, if through a ChannelSet
+	Channel string `json:"channel,omitempty" xml:"-"`
 	//lint:ignore SA5008 Static checker false positive
 	Dependencies *Depender                `xml:"-"`
 	Capabilities *BSPCapabilitiesManifest `xml:"-"`
@@ -436,6 +796,8 @@ type BoardVersion struct {
 	ProvCapabilitiesPerVersion string   `xml:"prov_capabilities_per_version,attr"`
 	Num                        string   `xml:"num"`
 	Commit                     string   `xml:"commit"`
+	// Ref is the kind of ref Commit is, told when the manifest is read
+	Ref CommitRef `xml:"-" json:"ref"`
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -446,6 +808,9 @@ type BoardVersion struct {
 type Middleware struct {
 	XMLName     xml.Name          `xml:"middleware"`
 	Middlewares []*MiddlewareItem `xml:"middleware"`
+	// Format is the format revision the manifest was read in, 1 or 2; fv1 manifests are
+	// normalized into the fv2 model (see formatdispatch.go)
+	Format int `xml:"-" json:"format,omitempty"`
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -467,8 +832,12 @@ type MiddlewareItem struct {
 	Versions          *MWVersions `xml:"versions"`
 	//lint:ignore SA5008 Static checker false positive
 	Origin *MiddlewareManifest `json:"-" xml:"-"`
+	// Channel is the channel the item was ingested from, if through a ChannelSet
+	Channel string `json:"channel,omitempty" xml:"-"`
 	//lint:ignore SA5008 Static checker false positive
 	Dependencies *Depender `xml:"-"`
+	// readme is the README of the library, once fetched (see readmes.go)
+	readme atomic.Pointer[Readme]
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -493,6 +862,8 @@ type MWVersion struct {
 	Num             string   `xml:"num"`
 	Commit          string   `xml:"commit"`
 	Desc            string   `xml:"desc"`
+	// Ref is the kind of ref Commit is, told when the manifest is read
+	Ref CommitRef `xml:"-" json:"ref"`
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -520,6 +891,9 @@ type Apps struct {
 	XMLName xml.Name `xml:"apps"`
 	Version string   `xml:"version,attr,omitempty"` // Only in v2 (fv2): "2.0"
 	App     []*App   `xml:"app"`
+	// Format is the format revision the manifest was read in, 1 or 2; fv1 manifests are
+	// normalized into the fv2 model (see formatdispatch.go)
+	Format int `xml:"-" json:"format,omitempty"`
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -537,8 +911,12 @@ type App struct {
 	URI               string     `xml:"uri"`
 	Description       string     `xml:"description"`
 	Versions          CEVersions `xml:"versions"`
+	// Projects are the per-core projects of a multi-core application (see multicore.go)
+	Projects *AppProjects `xml:"projects,omitempty"`
 	//lint:ignore SA5008 Static checker false positive
 	Origin *AppManifest `json:"-" xml:"-"`
+	// Channel is the channel the item was ingested from, if through a ChannelSet
+	Channel string `json:"channel,omitempty" xml:"-"`
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -563,6 +941,33 @@ type CEVersion struct {
 	ReqCapabilitiesPerVersionV2 string   `xml:"req_capabilities_per_version_v2,attr,omitempty"` // v2: bracketed syntax
 	Num                         string   `xml:"num"`
 	Commit                      string   `xml:"commit"`
+	// Ref is the kind of ref Commit is, told when the manifest is read
+	Ref CommitRef `xml:"-" json:"ref"`
+	// Projects, when set, replace the app's projects for this version
+	Projects *AppProjects `xml:"projects,omitempty"`
+
+	// Capture unknown tags and attributes
+	Surprises []AnyTag   `xml:",any"`
+	LostAttrs []xml.Attr `xml:",any,attr"`
+}
+
+type AppProjects struct {
+	XMLName  xml.Name      `xml:"projects"`
+	Projects []*AppProject `xml:"project"`
+
+	// Capture unknown tags and attributes
+	Surprises []AnyTag   `xml:",any"`
+	LostAttrs []xml.Attr `xml:",any,attr"`
+}
+
+// AppProject is one project of a multi-core application, built for one core
+type AppProject struct {
+	XMLName           xml.Name `xml:"project"`
+	Name              string   `xml:"name,attr"`
+	Core              string   `xml:"core,attr,omitempty"`
+	Path              string   `xml:"path,attr,omitempty"`
+	ReqCapabilities   string   `xml:"req_capabilities,attr,omitempty"`
+	ReqCapabilitiesV2 string   `xml:"req_capabilities_v2,attr,omitempty"`
 
 	// Capture unknown tags and attributes
 	Surprises []AnyTag   `xml:",any"`
@@ -584,6 +989,7 @@ func ReadBoardManifest(xmlData []byte) (*Boards, error) {
 	if err != nil {
 		return nil, err
 	}
+	normalizeManifest(&boards, "", xmlData)
 	return &boards, nil
 }
 
@@ -593,6 +999,7 @@ func ReadMiddlewareManifest(xmlData []byte) (*Middleware, error) {
 	if err != nil {
 		return nil, err
 	}
+	normalizeManifest(&middleware, "", xmlData)
 
 	return &middleware, nil
 }
@@ -622,20 +1029,30 @@ func ReadDependenciesManifest(xmlData []byte) (*Dependencies, error) {
 	return &deps, nil
 }
 
+// GetBoardsMap returns the tree's own index, built on first use.
+//
+// Deprecated: use BoardsByID, which returns a copy.
 func (manifest *SuperManifest) GetBoardsMap() *map[string]*Board {
+	warnDeprecated("(*SuperManifest).GetBoardsMap")
+	manifest.boardsIndex()
+	return &manifest.boardsMap
+}
+
+// boardsIndex returns the tree's own index, building it on first use
+func (manifest *SuperManifest) boardsIndex() map[string]*Board {
 	if len(manifest.boardsMap) > 0 {
-		return &manifest.boardsMap
+		return manifest.boardsMap
 	}
 	manifest.boardsMap = make(map[string]*Board)
 	for _, bm := range manifest.BoardManifestList.BoardManifest {
 		if bm.Boards != nil {
 			for _, board := range bm.Boards.Boards {
 				board.Origin = bm
-				manifest.boardsMap[board.ID] = board
+				manifest.boardsMap[idKey(board.ID)] = board
 			}
 		}
 	}
-	return &manifest.boardsMap
+	return manifest.boardsMap
 }
 
 func (manifest *SuperManifest) GetBoardIDs() []string {
@@ -652,25 +1069,34 @@ func (manifest *SuperManifest) GetBoardIDs() []string {
 }
 
 func (manifest *SuperManifest) GetBoard(boardID string) (*Board, bool) {
-	boardsMap := manifest.GetBoardsMap()
-	board, exists := (*boardsMap)[boardID]
+	board, exists := manifest.boardsIndex()[idKey(boardID)]
 	return board, exists
 }
 
+// GetAppsMap returns the tree's own index, built on first use.
+//
+// Deprecated: use AppsByID, which returns a copy.
 func (manifest *SuperManifest) GetAppsMap() *map[string]*App {
+	warnDeprecated("(*SuperManifest).GetAppsMap")
+	manifest.appsIndex()
+	return &manifest.appMap
+}
+
+// appsIndex returns the tree's own index, building it on first use
+func (manifest *SuperManifest) appsIndex() map[string]*App {
 	if len(manifest.appMap) > 0 {
-		return &manifest.appMap
+		return manifest.appMap
 	}
 	manifest.appMap = make(map[string]*App)
 	for _, am := range manifest.AppManifestList.AppManifest {
 		if am.Apps != nil {
 			for _, app := range am.Apps.App {
 				app.Origin = am
-				manifest.appMap[app.ID] = app
+				manifest.appMap[idKey(app.ID)] = app
 			}
 		}
 	}
-	return &manifest.appMap
+	return manifest.appMap
 }
 
 func (manifest *SuperManifest) GetAppIDs() []string {
@@ -687,25 +1113,34 @@ func (manifest *SuperManifest) GetAppIDs() []string {
 }
 
 func (manifest *SuperManifest) GetApp(appID string) (*App, bool) {
-	appsMap := manifest.GetAppsMap()
-	app, exists := (*appsMap)[appID]
+	app, exists := manifest.appsIndex()[idKey(appID)]
 	return app, exists
 }
 
+// GetMiddlewareMap returns the tree's own index, built on first use.
+//
+// Deprecated: use MiddlewareByID, which returns a copy.
 func (manifest *SuperManifest) GetMiddlewareMap() *map[string]*MiddlewareItem {
+	warnDeprecated("(*SuperManifest).GetMiddlewareMap")
+	manifest.middlewareIndex()
+	return &manifest.middlewareMap
+}
+
+// middlewareIndex returns the tree's own index, building it on first use
+func (manifest *SuperManifest) middlewareIndex() map[string]*MiddlewareItem {
 	if len(manifest.middlewareMap) > 0 {
-		return &manifest.middlewareMap
+		return manifest.middlewareMap
 	}
 	manifest.middlewareMap = make(map[string]*MiddlewareItem)
 	for _, mm := range manifest.MiddlewareManifestList.MiddlewareManifest {
 		if mm.Middlewares != nil {
 			for _, item := range mm.Middlewares.Middlewares {
 				item.Origin = mm
-				manifest.middlewareMap[item.ID] = item
+				manifest.middlewareMap[idKey(item.ID)] = item
 			}
 		}
 	}
-	return &manifest.middlewareMap
+	return manifest.middlewareMap
 }
 
 func (manifest *SuperManifest) GetMiddlewareIDs() []string {
@@ -722,11 +1157,59 @@ func (manifest *SuperManifest) GetMiddlewareIDs() []string {
 }
 
 func (manifest *SuperManifest) GetMiddleware(middlewareID string) (*MiddlewareItem, bool) {
-	middlewareMap := manifest.GetMiddlewareMap()
-	item, exists := (*middlewareMap)[middlewareID]
+	item, exists := manifest.middlewareIndex()[idKey(middlewareID)]
 	return item, exists
 }
 
+// Boards returns all boards in manifest order
+func (manifest *SuperManifest) Boards() []*Board {
+	ret := []*Board{}
+	for _, bm := range manifest.BoardManifestList.BoardManifest {
+		if bm.Boards != nil {
+			ret = append(ret, bm.Boards.Boards...)
+		}
+	}
+	return ret
+}
+
+// BoardsByID returns a copy of the board index. Changes to the map do not affect the tree.
+func (manifest *SuperManifest) BoardsByID() map[string]*Board {
+	return maps.Clone(manifest.boardsIndex())
+}
+
+// Apps returns all apps in manifest order
+func (manifest *SuperManifest) Apps() []*App {
+	ret := []*App{}
+	for _, am := range manifest.AppManifestList.AppManifest {
+		if am.Apps != nil {
+			ret = append(ret, am.Apps.App...)
+		}
+	}
+	return ret
+}
+
+// AppsByID returns a copy of the app index. Changes to the map do not affect the tree.
+func (manifest *SuperManifest) AppsByID() map[string]*App {
+	return maps.Clone(manifest.appsIndex())
+}
+
+// Middleware returns all middleware items in manifest order
+func (manifest *SuperManifest) Middleware() []*MiddlewareItem {
+	ret := []*MiddlewareItem{}
+	for _, mm := range manifest.MiddlewareManifestList.MiddlewareManifest {
+		if mm.Middlewares != nil {
+			ret = append(ret, mm.Middlewares.Middlewares...)
+		}
+	}
+	return ret
+}
+
+// MiddlewareByID returns a copy of the middleware index. Changes to the map do not affect the
+// tree.
+func (manifest *SuperManifest) MiddlewareByID() map[string]*MiddlewareItem {
+	return maps.Clone(manifest.middlewareIndex())
+}
+
 // GetDependencies fetches and caches the BSP/Middleware dependencies manifest from the given URL
 func (sm *SuperManifest) GetDependencies(urlStr string) *Dependencies {
 	if (urlStr == "") || (urlStr == "N/A") {
@@ -756,58 +1239,86 @@ func (sm *SuperManifest) GetDependenciesByID(urlStr string, Id string) *Depender
 
 func UnmarshalManifest[T any](data []byte, err error, parseFunc func([]byte) (*T, error)) (*T, error) {
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch manifest: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrFetchFailed, err)
 	}
 	manifest, err := parseFunc(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+		return nil, &manifestParseError{err}
 	}
 	return manifest, nil
 }
 
+// log returns the logger of the tree
+func (sm *SuperManifest) log() LoggerIF {
+	if sm.logger != nil {
+		return sm.logger
+	}
+	return logger
+}
+
 func (sm *SuperManifest) AddSuperManifest(other *SuperManifest) {
 	if (sm.Version != other.Version) && (other.Version != "") {
 		// Should we error out instead?
-		logger.Warningf("Merging super manifests with different versions: %s vs %s\n", sm.Version, other.Version)
+		sm.warn(WarnVersionMismatch, "", "Merging super manifests with different versions: %s vs %s", sm.Version, other.Version)
 	}
 	sm.SourceUrls = append(sm.SourceUrls, other.SourceUrls...)
-	// Merge Board Manifests
-	sm.BoardManifestList.BoardManifest = append(sm.BoardManifestList.BoardManifest, other.BoardManifestList.BoardManifest...)
-	// Merge App Manifests
-	sm.AppManifestList.AppManifest = append(sm.AppManifestList.AppManifest, other.AppManifestList.AppManifest...)
-	// Merge Middleware Manifests
-	sm.MiddlewareManifestList.MiddlewareManifest = append(sm.MiddlewareManifestList.MiddlewareManifest, other.MiddlewareManifestList.MiddlewareManifest...)
-
-	// If we have duplicate dependency or capability URLs, log a warning. It is possible
-	// that we will have dangling references in board/middleware manifests, but that is up to the user
-	// to resolve. It should not cause a crash. If this is a problem, we can enhance this to track
-	// which manifest the URL came from and only warn if the same URL has different content.
-	for k, v := range other.dependenciesMap {
-		if _, exists := sm.dependenciesMap[k]; exists {
-			logger.Warningf("Merging super manifests with duplicate dependency URL: %s\n", k)
+	if other.ingestReport != nil {
+		sm.mergedReports = append(sm.mergedReports, other.ingestReport)
+	}
+	sm.mergedReports = append(sm.mergedReports, other.mergedReports...)
+	sm.mergeWarnings = append(sm.mergeWarnings, other.mergeWarnings...)
+	// Merge Board, App and Middleware Manifests; copies of ones already in the tree become
+	// aliases (see aliases.go)
+	sm.BoardManifestList.BoardManifest = appendManifests(sm.BoardManifestList.BoardManifest, other.BoardManifestList.BoardManifest)
+	sm.AppManifestList.AppManifest = appendManifests(sm.AppManifestList.AppManifest, other.AppManifestList.AppManifest)
+	sm.MiddlewareManifestList.MiddlewareManifest = appendManifests(sm.MiddlewareManifestList.MiddlewareManifest, other.MiddlewareManifestList.MiddlewareManifest)
+	sm.aliasDuplicates()
+
+	// If we have duplicate dependency or capability URLs with different content, log a warning.
+	// It is possible that we will have dangling references in board/middleware manifests, but
+	// that is up to the user to resolve. It should not cause a crash. The same content under
+	// the same URL is just read twice.
+	if sm.contentHashes == nil {
+		sm.contentHashes = make(map[string]string)
+	}
+	sameContent := func(k string) bool {
+		h := sm.contentHashes[k]
+		return h != "" && h == other.contentHashes[k]
+	}
+	for _, k := range orderedKeys(other.dependenciesMap) {
+		v := other.dependenciesMap[k]
+		if _, exists := sm.dependenciesMap[k]; exists && !sameContent(k) {
+			sm.warn(WarnDuplicateURL, k, "Merging super manifests with duplicate dependency URL")
 		}
 		sm.dependenciesMap[k] = v
 	}
-	for k, v := range other.bspCapabilitiesMap {
-		if _, exists := sm.bspCapabilitiesMap[k]; exists {
-			logger.Warningf("Merging super manifests with duplicate BSP capabilities URL: %s\n", k)
+	for _, k := range orderedKeys(other.bspCapabilitiesMap) {
+		v := other.bspCapabilitiesMap[k]
+		if _, exists := sm.bspCapabilitiesMap[k]; exists && !sameContent(k) {
+			sm.warn(WarnDuplicateURL, k, "Merging super manifests with duplicate BSP capabilities URL")
 		}
 		sm.bspCapabilitiesMap[k] = v
 	}
+	for k, v := range other.contentHashes {
+		sm.contentHashes[k] = v
+	}
 
 	// Following maps will be rebuilt on demand. So, clear them instead of merging
 	sm.clearMaps()
 }
 
+// AddSuperManifestFromURL ingests another super manifest with the options this tree was
+// ingested with, and merges it into this one
 func (sm *SuperManifest) AddSuperManifestFromURL(urlStr string) error {
-	otherManifest, err := NewSuperManifestFromURL(urlStr)
+	cfg := sm.ingestCfg
+	if cfg == nil {
+		cfg = newIngestConfig(nil)
+	}
+	other, err := newSuperManifest(urlStr, cfg)
 	if err != nil {
 		return err
 	}
-	// Type assert to concrete type for internal merge operation
-	if otherConcrete, ok := otherManifest.(*SuperManifest); ok {
-		sm.AddSuperManifest(otherConcrete)
-	}
+	sm.AddSuperManifest(other)
 	return nil
 }
 
@@ -816,11 +1327,34 @@ func (apps *Apps) IsV2() bool {
 	return apps.Version == "2.0"
 }
 
+// UnmarshalXML reads an app. Besides the current layout it accepts the one of early v1
+// manifests, which wrote <n> for <name> and req_capabilities as an element instead of an
+// attribute.
+func (a *App) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type PlainApp App // without this method; exported so that encoding/xml fills it in
+	legacy := struct {
+		*PlainApp
+		ShortName           string `xml:"n"`
+		ReqCapabilitiesElem string `xml:"req_capabilities"`
+	}{PlainApp: (*PlainApp)(a)}
+	if err := d.DecodeElement(&legacy, &start); err != nil {
+		return err
+	}
+	if a.Name == "" {
+		a.Name = legacy.ShortName
+	}
+	if a.ReqCapabilities == "" {
+		a.ReqCapabilities = strings.TrimSpace(legacy.ReqCapabilitiesElem)
+	}
+	return nil
+}
+
 func ReadAppsManifest(data []byte) (*Apps, error) {
 	var apps Apps
 	if err := UnmarshalXMLWithVerification(data, &apps); err != nil {
 		return nil, err
 	}
+	normalizeManifest(&apps, "", data)
 	return &apps, nil
 }
 
@@ -861,17 +1395,27 @@ func EnableXMLUnmarshalVerification(enable bool) {
 	doVerifyXMLUnmarshal = enable
 }
 
+// UnmarshalXMLWithVerification unmarshals data into obj and, if verification is enabled, logs
+// the tags and attributes the types do not know about
 func UnmarshalXMLWithVerification[T any](data []byte, obj *T) error {
+	return unmarshalXML(data, obj, doVerifyXMLUnmarshal, logger, func(path string) {
+		logger.Warningf("⚠️  XML Unmarshal Surprise: %s\n", path)
+	})
+}
+
+// unmarshalXML is UnmarshalXMLWithVerification with its own settings; surprise is called with
+// the path of every unknown tag and attribute
+func unmarshalXML[T any](data []byte, obj *T, verify bool, logger LoggerIF, surprise func(path string)) error {
 	if err := xml.Unmarshal(data, obj); err != nil {
 		return err
 	}
 
-	if doVerifyXMLUnmarshal {
+	if verify {
 		logger.Infof("End Unmarshal of Type %s, Begin Verification\n", reflect.TypeOf(*obj).Name())
-		badPaths := FindDeepSurprisesInStruct(*obj)
+		badPaths := FindXMLSurprises(obj)
 		if len(badPaths) > 0 {
 			for _, path := range badPaths {
-				logger.Warningf("⚠️  XML Unmarshal Surprise: %s\n", path)
+				surprise(path)
 			}
 		}
 	}
@@ -0,0 +1,31 @@
+package mtbmanifest
+
+import "testing"
+
+func TestItemAccessors(t *testing.T) {
+	sm := newTestSuperManifest(t)
+	boards := sm.Boards()
+	if len(boards) != 2 || boards[0].ID != "CY8CKIT-062S2-43012" || boards[1].ID != "CY8CKIT-149" {
+		t.Fatalf("expected the boards in manifest order, got %v", boards)
+	}
+	if apps := sm.Apps(); len(apps) != 3 || apps[0].ID != "mtb-example-hal-hello-world" {
+		t.Errorf("unexpected apps %v", apps)
+	}
+	if mw := sm.Middleware(); len(mw) != len(sm.GetMiddlewareIDs()) {
+		t.Errorf("expected %d middleware items, got %d", len(sm.GetMiddlewareIDs()), len(mw))
+	}
+
+	byID := sm.BoardsByID()
+	delete(byID, idKey("CY8CKIT-149"))
+	if _, ok := sm.GetBoard("CY8CKIT-149"); !ok {
+		t.Error("changing the returned map must not affect the tree")
+	}
+	apps := sm.AppsByID()
+	apps["injected"] = &App{ID: "injected"}
+	if _, ok := sm.GetApp("injected"); ok {
+		t.Error("adding to the returned map must not affect the tree")
+	}
+	if len(sm.MiddlewareByID()) != len(sm.Middleware()) {
+		t.Error("expected the middleware index to cover every item")
+	}
+}
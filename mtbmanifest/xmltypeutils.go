@@ -144,9 +144,23 @@ func (v *CEVersion) GetCapabilities() CapabilityRequirement {
 	return ParseCapabilities(v.ReqCapabilitiesPerVersion)
 }
 
+// GetCapabilities returns the parsed capability requirements for a middleware item
+// Prefers v2 format if available, falls back to v1
+func (mw *MiddlewareItem) GetCapabilities() CapabilityRequirement {
+	if mw.ReqCapabilitiesV2 != "" {
+		return ParseCapabilities(mw.ReqCapabilitiesV2)
+	}
+	return ParseCapabilities(mw.ReqCapabilities)
+}
+
 // Matches checks if a set of available capabilities satisfies this requirement
 // availableCaps should be a set-like structure (use a map for O(1) lookup)
+// A capability is also available when an alias of it is (see SetCapabilityAliases)
 func (cr *CapabilityRequirement) Matches(availableCaps map[string]bool) bool {
+	return cr.MatchesContext(&CapabilityContext{Capabilities: availableCaps})
+}
+
+func (cr *CapabilityRequirement) matches(availableCaps map[string]bool) bool {
 	// All groups must be satisfied (AND logic between groups)
 	for _, group := range cr.Groups {
 		// At least one capability in the group must be available (OR logic within group)
@@ -157,6 +171,15 @@ func (cr *CapabilityRequirement) Matches(availableCaps map[string]bool) bool {
 				break
 			}
 		}
+		if !groupMatched {
+			for _, cap := range group {
+				if provided := aliasedCapability(cap, availableCaps); provided != "" {
+					recordAliasHit(cap, provided)
+					groupMatched = true
+					break
+				}
+			}
+		}
 		if !groupMatched {
 			return false // This group not satisfied
 		}
@@ -181,80 +204,81 @@ func (cr *CapabilityRequirement) String() string {
 	return strings.Join(parts, " AND ")
 }
 
+// FindMiddlewareForBoard returns the middleware whose capability requirements the board meets,
+// judged by its base prov_capabilities
 func FindMiddlewareForBoard(sm SuperManifestIF, board *Board) []*MiddlewareItem {
-	result := make([]*MiddlewareItem, 0)
-	middlewareMap := sm.GetMiddlewareMap()
-	boardsCapabilities := strings.Fields(board.ProvCapabilities)
-	// Check if board's BSP capabilities satisfy middleware requirements
-	boardCaps := make(map[string]bool)
-	for _, cap := range boardsCapabilities {
-		boardCaps[cap] = true
-	}
+	return findMiddlewareForCapabilities(sm, strings.Fields(board.ProvCapabilities))
+}
 
-	for _, mw := range *middlewareMap {
-		// Check if middleware has capability requirements
-		capReqStr := mw.ReqCapabilitiesV2
-		if capReqStr == "" && mw.ReqCapabilities != "" {
-			capReqStr = mw.ReqCapabilities
-		}
-		capReq := ParseCapabilities(capReqStr)
-		if len(capReq.Groups) == 0 {
-			// No requirements, include by default
-			result = append(result, mw)
-			continue
-		}
+// FindMiddlewareForBoardVersion is FindMiddlewareForBoard judged by the capabilities of one
+// version of the board (see Board.CapabilitiesForVersion)
+func FindMiddlewareForBoardVersion(sm SuperManifestIF, board *Board, version string) []*MiddlewareItem {
+	caps, _ := board.CapabilitiesForVersion(version)
+	return findMiddlewareForCapabilities(sm, caps)
+}
+
+func findMiddlewareForCapabilities(sm SuperManifestIF, boardsCapabilities []string) []*MiddlewareItem {
+	return FindMiddlewareForContext(sm, NewCapabilityContext(boardsCapabilities))
+}
 
-		if capReq.Matches(boardCaps) {
-			result = append(result, mw)
+// FindCodeExamplesForBoard returns the code examples with a version whose requirements the
+// board meets (see App.Matches), judged by its base prov_capabilities
+func FindCodeExamplesForBoard(sm SuperManifestIF, board *Board) []*App {
+	return findCodeExamplesForCapabilities(sm, strings.Fields(board.ProvCapabilities))
+}
+
+// FindCodeExamplesForBoardVersion is FindCodeExamplesForBoard judged by the capabilities of one
+// version of the board (see Board.CapabilitiesForVersion)
+func FindCodeExamplesForBoardVersion(sm SuperManifestIF, board *Board, version string) []*App {
+	caps, _ := board.CapabilitiesForVersion(version)
+	return findCodeExamplesForCapabilities(sm, caps)
+}
+
+func findCodeExamplesForCapabilities(sm SuperManifestIF, boardsCapabilities []string) []*App {
+	return FindCodeExamplesForContext(sm, NewCapabilityContext(boardsCapabilities))
+}
+
+// Version returns the version of the board whose commit (e.g. latest-v4.X) or num is version
+func (b *Board) Version(version string) (*BoardVersion, bool) {
+	if b.Versions == nil {
+		return nil, false
+	}
+	for _, v := range b.Versions.Versions {
+		if v.Commit == version || v.Num == version {
+			return v, true
 		}
 	}
-
-	return result
+	return nil, false
 }
 
-func FindCodeExamplesForBoard(sm SuperManifestIF, board *Board) []*App {
-	result := make([]*App, 0)
-	appMap := sm.GetAppsMap()
-	boardsCapabilities := strings.Fields(board.ProvCapabilities)
-	// Check if board's BSP capabilities satisfy middleware requirements
-	boardCaps := make(map[string]bool)
-	for _, cap := range boardsCapabilities {
-		boardCaps[cap] = true
+// EffectiveCapabilities returns the capabilities a version of the board provides. A version
+// that lists prov_capabilities_per_version provides exactly those; the others provide the
+// board's prov_capabilities.
+func (b *Board) EffectiveCapabilities(v *BoardVersion) []string {
+	if v != nil && strings.TrimSpace(v.ProvCapabilitiesPerVersion) != "" {
+		return strings.Fields(v.ProvCapabilitiesPerVersion)
 	}
+	return strings.Fields(b.ProvCapabilities)
+}
 
-	for _, app := range *appMap {
-		// Check if CE has capability requirements
-		capReqStr := app.ReqCapabilitiesV2
-		if capReqStr == "" && app.ReqCapabilities != "" {
-			capReqStr = app.ReqCapabilities
-		}
-		capReq := ParseCapabilities(capReqStr)
-		if len(capReq.Groups) == 0 {
-			// No requirements, check version specific requirements
-			versionCapReqStr := ""
-			for _, version := range app.Versions.Version {
-				versionCapReqStr = version.ReqCapabilitiesPerVersionV2
-				if versionCapReqStr == "" && version.ReqCapabilitiesPerVersion != "" {
-					versionCapReqStr = version.ReqCapabilitiesPerVersion
-				}
-				if versionCapReqStr == "" {
-					continue
-				}
-				capReq = ParseCapabilities(versionCapReqStr)
-				if len(capReq.Groups) > 0 && capReq.Matches(boardCaps) {
-					// Stop looking in versions, we found a match
-					result = append(result, app)
-					break
-				}
-				capReq = CapabilityRequirement{} // reset for next version
-			}
-			continue
-		}
+// CapabilitiesForVersion returns the capabilities of the version of the board given by commit
+// or num. An empty version means the base prov_capabilities. For a version the board does not
+// list, it returns the base capabilities and false.
+func (b *Board) CapabilitiesForVersion(version string) ([]string, bool) {
+	if version == "" {
+		return b.EffectiveCapabilities(nil), true
+	}
+	v, ok := b.Version(version)
+	return b.EffectiveCapabilities(v), ok
+}
 
-		if capReq.Matches(boardCaps) {
-			result = append(result, app)
+// CapabilitiesByVersion maps the commit of every version of the board to its capabilities
+func (b *Board) CapabilitiesByVersion() map[string][]string {
+	ret := make(map[string][]string)
+	if b.Versions != nil {
+		for _, v := range b.Versions.Versions {
+			ret[v.Commit] = b.EffectiveCapabilities(v)
 		}
 	}
-
-	return result
+	return ret
 }